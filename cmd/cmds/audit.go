@@ -0,0 +1,73 @@
+package cmds
+
+import (
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// audited wraps the RunE of a mutating command so that every run is recorded in
+// the audit log (see 'wsm history'). workspaceArg is the index of the positional
+// argument naming the workspace, or -1 when it comes from --workspace, --into or
+// the current directory. Dry runs and plans are not recorded.
+func audited(operation string, workspaceArg int, runE func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if dryRun, err := cmd.Flags().GetBool("dry-run"); err == nil && dryRun {
+			return runE(cmd, args)
+		}
+		if plan, err := cmd.Flags().GetBool("plan"); err == nil && plan {
+			return runE(cmd, args)
+		}
+
+		// Resolved up front: the workspace may not exist anymore afterwards
+		event := wsm.AuditEvent{
+			Time:      time.Now(),
+			Operation: operation,
+			Workspace: auditWorkspace(cmd, args, workspaceArg),
+			Args:      args,
+			Flags:     changedFlags(cmd),
+		}
+
+		runErr := runE(cmd, args)
+
+		event.Duration = time.Since(event.Time)
+		event.Success = runErr == nil
+		if runErr != nil {
+			event.Error = runErr.Error()
+		}
+		if err := wsm.RecordAuditEvent(event); err != nil {
+			log.Warn().Err(err).Str("operation", operation).Msg("Failed to record audit event")
+		}
+		return runErr
+	}
+}
+
+func auditWorkspace(cmd *cobra.Command, args []string, workspaceArg int) string {
+	if workspaceArg >= 0 && workspaceArg < len(args) {
+		return args[workspaceArg]
+	}
+	for _, name := range []string{"workspace", "into"} {
+		if value, err := cmd.Flags().GetString(name); err == nil && value != "" {
+			return value
+		}
+	}
+	if workspace, err := findCurrentWorkspace(); err == nil {
+		return workspace.Name
+	}
+	return ""
+}
+
+// changedFlags returns the flags set on the command line
+func changedFlags(cmd *cobra.Command) map[string]string {
+	flags := map[string]string{}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		flags[flag.Name] = flag.Value.String()
+	})
+	if len(flags) == 0 {
+		return nil
+	}
+	return flags
+}
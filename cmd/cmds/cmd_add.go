@@ -11,15 +11,24 @@ import (
 func NewAddCommand() *cobra.Command {
 	var branchName string
 	var forceOverwrite bool
+	var retryPending bool
+	var sparse []string
+	var pin string
+	var onExisting string
+	var onCheckedOut string
+	var noScripts bool
+	var plan bool
+	var outputFormat string
 
 	cmd := &cobra.Command{
-		Use:   "add <workspace-name> <repo-name>",
+		Use:   "add <workspace-name> [repo-name|path|url]",
 		Short: "Add a repository to an existing workspace",
 		Long: `Add a repository to an existing workspace and create the necessary branch.
 
 This command:
 - Loads the specified workspace configuration
-- Finds the specified repository in the registry
+- Finds the specified repository in the registry; a path to a clone or a
+  remote URL is registered first, and a remote repository is cloned
 - Creates a worktree for the repository using the workspace's branch
 - Updates the workspace configuration to include the new repository
 - Creates or updates go.work file if the workspace has Go repositories
@@ -28,32 +37,114 @@ Examples:
   # Add a repository to an existing workspace
   workspace-manager add my-feature my-new-repo
 
+  # Add a clone that was never discovered
+  workspace-manager add my-feature ~/src/tool
+
   # Add a repository with a different branch name
   workspace-manager add my-feature my-new-repo --branch feature/different-branch
 
   # Force overwrite if the branch already exists
-  workspace-manager add my-feature my-new-repo --force`,
-		Args: cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+  workspace-manager add my-feature my-new-repo --force
+
+  # Use the branch as-is if it already exists, fail otherwise
+  workspace-manager add my-feature my-new-repo --on-existing-branch use
+
+  # Only check out some directories of a large repository
+  workspace-manager add my-feature monorepo --sparse services/api,libs/common
+
+  # Check out a frozen library at a tag instead of the workspace branch
+  workspace-manager add my-feature shared-lib --pin v1.4.2
+
+  # Retry repositories left pending by 'create --partial'
+  workspace-manager add my-feature --retry-pending
+
+  # Preview the branch, go.work changes, setup scripts and conflicts
+  workspace-manager add my-feature my-new-repo --plan
+  workspace-manager add my-feature my-new-repo --plan -o json`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: audited("add", 0, func(cmd *cobra.Command, args []string) error {
 			workspaceName := args[0]
-			repoName := args[1]
+
+			if err := wsm.ValidateExistingBranchPolicy(onExisting); err != nil {
+				return err
+			}
+			if err := wsm.ValidateCheckedOutBranchPolicy(onCheckedOut); err != nil {
+				return err
+			}
+			if forceOverwrite && onExisting != "" && onExisting != wsm.ExistingBranchOverwrite {
+				return errors.Errorf("--force cannot be combined with --on-existing-branch=%s", onExisting)
+			}
 
 			wm, err := wsm.NewWorkspaceManager()
 			if err != nil {
 				return errors.Wrap(err, "failed to create workspace manager")
 			}
+			wm.OnExistingBranch = onExisting
+			wm.OnCheckedOutBranch = onCheckedOut
+			wm.NoScripts = noScripts
 
-			return wm.AddRepositoryToWorkspace(cmd.Context(), workspaceName, repoName, branchName, forceOverwrite)
-		},
+			if retryPending {
+				if plan {
+					return errors.New("--plan cannot be combined with --retry-pending")
+				}
+				if len(args) > 1 {
+					return errors.New("--retry-pending does not take a repository name")
+				}
+				return wm.RetryPendingRepositories(cmd.Context(), workspaceName, forceOverwrite)
+			}
+
+			if len(args) < 2 {
+				return errors.New("repository name is required (or use --retry-pending)")
+			}
+			repoName := args[1]
+
+			var sparsePaths []string
+			if len(sparse) > 0 {
+				if sparsePaths, err = wsm.CleanSparsePaths(sparse); err != nil {
+					return err
+				}
+			}
+
+			if pin != "" && branchName != "" {
+				return errors.New("--pin cannot be combined with --branch")
+			}
+
+			if plan {
+				repoPlan, err := wm.PlanAddRepository(cmd.Context(), workspaceName, repoName, branchName, forceOverwrite, sparsePaths, pin)
+				if err != nil {
+					return err
+				}
+				return emitRepositoryPlan(repoPlan, outputFormat)
+			}
+
+			return wm.AddRepositoryToWorkspace(cmd.Context(), workspaceName, repoName, branchName, forceOverwrite, sparsePaths, pin)
+		}),
 	}
 
 	cmd.Flags().StringVarP(&branchName, "branch", "b", "", "Branch name to use (defaults to workspace's branch)")
 	cmd.Flags().BoolVarP(&forceOverwrite, "force", "f", false, "Force overwrite if branch already exists")
+	cmd.Flags().StringVar(&onExisting, "on-existing-branch", "", "When the branch already exists: overwrite, use or fail (default: ask)")
+	cmd.Flags().StringVar(&onCheckedOut, "on-checked-out-branch", "", "When the branch is checked out in another worktree: reuse that worktree, link (new branch <branch>@<workspace>) or fail (default: ask)")
+	cmd.Flags().StringSliceVar(&sparse, "sparse", nil, "Only check out these directories of the repository (cone mode sparse checkout)")
+	cmd.Flags().StringVar(&pin, "pin", "", "Check out the repository detached at this tag or commit SHA (read-only, skipped by sync)")
+	cmd.Flags().BoolVar(&retryPending, "retry-pending", false, "Retry worktree creation for repositories left pending by 'create --partial'")
+	cmd.Flags().BoolVar(&noScripts, "no-scripts", false, "Do not run the setup scripts of the repository")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Only show what adding the repository would do and what conflicts with it")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format of --plan (table, json)")
 
 	carapace.Gen(cmd).PositionalCompletion(
 		WorkspaceNameCompletion(),
 		RepositoryNameCompletion(),
 	)
 
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"branch":                BranchCompletion(nil),
+			"on-existing-branch":    carapace.ActionValues(wsm.ExistingBranchPolicies...),
+			"on-checked-out-branch": carapace.ActionValues(wsm.CheckedOutBranchPolicies...),
+			"output":                carapace.ActionValues("table", "json"),
+		},
+	)
+
 	return cmd
 }
@@ -0,0 +1,174 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewAgentCommand creates the agent command
+func NewAgentCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Keep a progress journal for agents working in a workspace",
+		Long: `Record the progress of agents working in a workspace, so work spanning several
+sessions can be audited and picked up by the next session.
+
+Notes are appended to .wsm/agent-journal.jsonl with the task, its status,
+blockers and next steps, the actor (WSM_ACTOR) and the HEAD commit of every
+repository. 'wsm agent handoff' renders the journal to HANDOFF.md at the
+workspace root.
+
+Examples:
+  # Record progress
+  wsm agent note --task "migrate auth" --status in-progress \
+    --message "token refresh done" --next "port the session store"
+
+  # Record a blocker
+  wsm agent note --task "migrate auth" --status blocked --blocker "staging credentials expired"
+
+  # Write HANDOFF.md for the next session
+  wsm agent handoff`,
+	}
+
+	cmd.AddCommand(
+		newAgentNoteCommand(),
+		newAgentHandoffCommand(),
+	)
+
+	return cmd
+}
+
+func newAgentNoteCommand() *cobra.Command {
+	var (
+		workspace string
+		note      wsm.AgentNote
+		handoff   bool
+		format    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "note",
+		Short: "Append a progress entry to the agent journal",
+		Args:  cobra.NoArgs,
+		RunE: audited("agent-note", -1, func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return errors.Errorf("unsupported output format: %s", format)
+			}
+			ws, err := namedOrCurrentWorkspace(workspace, "wsm agent note --workspace <workspace-name>")
+			if err != nil {
+				return err
+			}
+			recorded, err := wsm.AppendAgentNote(cmd.Context(), ws, note)
+			if err != nil {
+				return err
+			}
+
+			var handoffPath string
+			if handoff {
+				if handoffPath, err = wsm.WriteHandoff(ws); err != nil {
+					return err
+				}
+			}
+
+			if format == "json" {
+				return wsm.PrintJSON(recorded)
+			}
+			output.PrintSuccess("Noted %s (%s) in workspace '%s'", recorded.Task, recorded.Status, ws.Name)
+			if handoffPath != "" {
+				output.PrintInfo("Updated %s", handoffPath)
+			}
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVarP(&workspace, "workspace", "w", "", "Workspace name (default: the current workspace)")
+	cmd.Flags().StringVar(&note.Task, "task", "", "Task the note is about")
+	cmd.Flags().StringVar(&note.Status, "status", wsm.AgentTaskInProgress, "Task status: "+strings.Join(wsm.AgentTaskStatuses, ", "))
+	cmd.Flags().StringVarP(&note.Message, "message", "m", "", "What was done")
+	cmd.Flags().StringArrayVar(&note.Blockers, "blocker", nil, "Something blocking the task (repeatable)")
+	cmd.Flags().StringArrayVar(&note.Next, "next", nil, "Next step (repeatable)")
+	cmd.Flags().BoolVar(&handoff, "handoff", false, "Also render HANDOFF.md")
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	_ = cmd.MarkFlagRequired("task")
+
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"workspace": WorkspaceNameCompletion(),
+		"status":    carapace.ActionValues(wsm.AgentTaskStatuses...),
+		"output":    carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func newAgentHandoffCommand() *cobra.Command {
+	var printOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "handoff [workspace-name]",
+		Short: "Render the agent journal to HANDOFF.md",
+		Long: `Render the agent journal of a workspace to HANDOFF.md at its root: the latest
+status of every task, open blockers and next steps, and every note, newest
+first.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			ws, err := namedOrCurrentWorkspace(workspaceName, "wsm agent handoff <workspace-name>")
+			if err != nil {
+				return err
+			}
+
+			if printOnly {
+				notes, err := wsm.ReadAgentJournal(ws)
+				if err != nil {
+					return err
+				}
+				fmt.Print(wsm.RenderHandoff(ws, notes))
+				return nil
+			}
+			path, err := wsm.WriteHandoff(ws)
+			if err != nil {
+				return err
+			}
+			output.PrintSuccess("Wrote %s", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&printOnly, "print", false, "Print the handoff instead of writing HANDOFF.md")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+
+	return cmd
+}
+
+// namedOrCurrentWorkspace loads the named workspace, or the current one
+func namedOrCurrentWorkspace(workspaceName, usage string) (*wsm.Workspace, error) {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get current directory")
+		}
+		detected, err := detectWorkspace(cwd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to detect workspace. Use '%s'", usage)
+		}
+		workspaceName = detected
+	}
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+	return workspace, nil
+}
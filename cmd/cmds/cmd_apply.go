@@ -0,0 +1,132 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewApplyCommand creates the apply command
+func NewApplyCommand() *cobra.Command {
+	var (
+		dryRun       bool
+		threeWay     bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply <dir> [workspace-name]",
+		Short: "Apply patches exported by 'wsm diff --export' to a workspace",
+		Long: `Apply the .patch files written by 'wsm diff --export' to the repositories of
+the same names in a workspace, the current one by default. Changes move
+between workspaces this way without pushing a branch.
+
+Every patch is checked first; if one of them does not apply, or its
+repository is not in the workspace, nothing is applied. The changes land in
+the working trees uncommitted, new files untracked.
+
+Examples:
+  wsm diff --export /tmp/changes
+  wsm apply /tmp/changes other-workspace
+
+  # Check that the patches apply
+  wsm apply /tmp/changes --dry-run
+
+  # Merge the patches when the branches diverged
+  wsm apply /tmp/changes --3way`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: audited("apply", 1, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			workspaceName := ""
+			if len(args) > 1 {
+				workspaceName = args[1]
+			}
+			options := wsm.PatchApplyOptions{ThreeWay: threeWay, Check: dryRun}
+			return runApply(cmd.Context(), args[0], workspaceName, options, outputFormat)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only check that every patch applies")
+	cmd.Flags().BoolVar(&threeWay, "3way", false, "Fall back to a three-way merge when a patch does not apply cleanly")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		carapace.ActionDirectories(),
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runApply(ctx context.Context, dir, workspaceName string, options wsm.PatchApplyOptions, outputFormat string) error {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get current directory")
+		}
+		detected, err := detectWorkspace(cwd)
+		if err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm apply <dir> <workspace-name>'")
+		}
+		workspaceName = detected
+	}
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	applies, applyErr := wsm.NewGitOperations(workspace).ApplyPatches(ctx, dir, options)
+	if applies == nil {
+		return applyErr
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(applies); err != nil {
+			return err
+		}
+		return applyErr
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tPATCH\tRESULT")
+	fmt.Fprintln(w, "----------\t-----\t------")
+	for _, apply := range applies {
+		result := "applies"
+		switch {
+		case apply.Problem != "":
+			// git explains the failure on the following lines
+			result = strings.SplitN(apply.Problem, "\n", 2)[0]
+		case apply.Applied:
+			result = "applied"
+		case applyErr != nil:
+			result = "not applied"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", apply.Repository, apply.File, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if applyErr != nil {
+		return applyErr
+	}
+	if options.Check {
+		output.PrintSuccess("All %d patches apply to workspace '%s'", len(applies), workspace.Name)
+		return nil
+	}
+	output.PrintSuccess("Applied %d patches to workspace '%s'", len(applies), workspace.Name)
+	return nil
+}
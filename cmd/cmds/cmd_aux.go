@@ -0,0 +1,174 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewAuxCommand creates the aux command
+func NewAuxCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "aux",
+		Short: "Manage non-git directories of a workspace",
+		Long: `Add data or scratch directories to a workspace next to its repository
+worktrees. An auxiliary path is either a symlink to an existing directory
+(--link) or an empty directory created in the workspace.
+
+Auxiliary paths are recorded in the workspace metadata and skipped by git
+operations. 'wsm delete' removes their symlinks without touching the linked
+directories; created directories are deleted with --remove-files, or when
+empty.
+
+Examples:
+  # Scratch directory in the workspace
+  wsm aux add my-feature scratch
+
+  # Link a shared dataset
+  wsm aux add my-feature data --link ~/datasets/sample
+
+  # List and remove
+  wsm aux list my-feature
+  wsm aux remove my-feature data`,
+	}
+
+	cmd.AddCommand(newAuxAddCommand(), newAuxRemoveCommand(), newAuxListCommand())
+	return cmd
+}
+
+func newAuxAddCommand() *cobra.Command {
+	var link string
+
+	cmd := &cobra.Command{
+		Use:   "add <workspace-name> <name>",
+		Short: "Add a directory, or a symlink with --link",
+		Args:  cobra.ExactArgs(2),
+		RunE: audited("aux-add", 0, func(cmd *cobra.Command, args []string) error {
+			wm, err := wsm.NewWorkspaceManager()
+			if err != nil {
+				return errors.Wrap(err, "failed to create workspace manager")
+			}
+			aux, err := wm.AddAuxiliaryPath(args[0], args[1], link)
+			if err != nil {
+				return err
+			}
+			if aux.Linked() {
+				output.PrintSuccess("Linked %s to %s", aux.Name, aux.Target)
+			} else {
+				output.PrintSuccess("Created %s", aux.Name)
+			}
+			return nil
+		}),
+	}
+
+	cmd.Flags().StringVar(&link, "link", "", "Symlink to this directory instead of creating an empty one")
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"link": carapace.ActionDirectories(),
+	})
+
+	return cmd
+}
+
+func newAuxRemoveCommand() *cobra.Command {
+	var removeFiles bool
+
+	cmd := &cobra.Command{
+		Use:   "remove <workspace-name> <name>",
+		Short: "Remove an auxiliary path",
+		Long: `Remove an auxiliary path from a workspace. Symlinks are removed without
+touching the linked directory. A created directory must be empty unless
+--remove-files is given.`,
+		Args: cobra.ExactArgs(2),
+		RunE: audited("aux-remove", 0, func(cmd *cobra.Command, args []string) error {
+			wm, err := wsm.NewWorkspaceManager()
+			if err != nil {
+				return errors.Wrap(err, "failed to create workspace manager")
+			}
+			if err := wm.RemoveAuxiliaryPath(args[0], args[1], removeFiles); err != nil {
+				return err
+			}
+			output.PrintSuccess("Removed %s from workspace %s", args[1], args[0])
+			return nil
+		}),
+	}
+
+	cmd.Flags().BoolVar(&removeFiles, "remove-files", false, "Delete a created directory with its contents")
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion(), auxiliaryPathCompletion())
+
+	return cmd
+}
+
+func newAuxListCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "list <workspace-name>",
+		Short: "List the auxiliary paths of a workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			workspace, err := loadWorkspace(args[0])
+			if err != nil {
+				return errors.Wrapf(err, "workspace '%s' not found", args[0])
+			}
+
+			if outputFormat == "json" {
+				paths := workspace.AuxiliaryPaths
+				if paths == nil {
+					paths = []wsm.AuxiliaryPath{}
+				}
+				return wsm.PrintJSON(paths)
+			}
+			if len(workspace.AuxiliaryPaths) == 0 {
+				output.PrintInfo("Workspace %s has no auxiliary paths", workspace.Name)
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tTARGET")
+			fmt.Fprintln(w, "----\t------")
+			for _, aux := range workspace.AuxiliaryPaths {
+				target := "(directory)"
+				if aux.Linked() {
+					target = aux.Target
+				}
+				fmt.Fprintf(w, "%s\t%s\n", aux.Name, target)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+// auxiliaryPathCompletion completes the auxiliary paths of the workspace named by the first argument
+func auxiliaryPathCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		if len(ctx.Args) < 1 {
+			return carapace.ActionMessage("workspace name required")
+		}
+		workspace, err := loadWorkspace(ctx.Args[0])
+		if err != nil {
+			return carapace.ActionMessage("workspace not found")
+		}
+		var names []string
+		for _, aux := range workspace.AuxiliaryPaths {
+			names = append(names, aux.Name)
+		}
+		return carapace.ActionValues(names...)
+	})
+}
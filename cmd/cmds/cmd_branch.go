@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"text/tabwriter"
+	"time"
 
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
@@ -14,8 +16,9 @@ import (
 
 func NewBranchCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "branch",
-		Short: "Manage branches across workspace repositories",
+		Use:     "branch",
+		Aliases: []string{"branches"},
+		Short:   "Manage branches across workspace repositories",
 		Long: `Create, switch, and manage branches across all repositories in the workspace.
 This ensures consistent branch operations across your multi-repository development.`,
 	}
@@ -24,13 +27,17 @@ This ensures consistent branch operations across your multi-repository developme
 		NewBranchCreateCommand(),
 		NewBranchSwitchCommand(),
 		NewBranchListCommand(),
+		NewBranchMatrixCommand(),
 	)
 
 	return cmd
 }
 
 func NewBranchCreateCommand() *cobra.Command {
-	var track bool
+	var (
+		track   bool
+		filters repoFilterFlags
+	)
 
 	cmd := &cobra.Command{
 		Use:   "create [branch-name]",
@@ -38,85 +45,158 @@ func NewBranchCreateCommand() *cobra.Command {
 		Long:  "Create a new branch with the same name across all repositories in the workspace.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBranchCreate(cmd.Context(), args[0], track)
+			return runBranchCreate(cmd.Context(), args[0], track, &filters)
 		},
 	}
 
 	cmd.Flags().BoolVar(&track, "track", false, "Set up tracking for the new branch")
+	filters.register(cmd, nil)
 
 	return cmd
 }
 
 func NewBranchSwitchCommand() *cobra.Command {
+	var filters repoFilterFlags
+
 	cmd := &cobra.Command{
 		Use:   "switch [branch-name]",
 		Short: "Switch to a branch across all repositories",
 		Long:  "Switch all repositories in the workspace to the specified branch.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBranchSwitch(cmd.Context(), args[0])
+			return runBranchSwitch(cmd.Context(), args[0], &filters)
 		},
 	}
 
+	filters.register(cmd, nil)
+
+	carapace.Gen(cmd).PositionalCompletion(
+		BranchCompletion(nil),
+	)
+
 	return cmd
 }
 
 func NewBranchListCommand() *cobra.Command {
+	var filters repoFilterFlags
+
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List current branches across repositories",
 		Long:  "Show the current branch for each repository in the workspace.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBranchList(cmd.Context())
+			return runBranchList(cmd.Context(), &filters)
 		},
 	}
 
+	filters.register(cmd, nil)
+
 	return cmd
 }
 
-func runBranchCreate(ctx context.Context, branchName string, track bool) error {
+func NewBranchMatrixCommand() *cobra.Command {
+	var (
+		outputFormat string
+		filters      repoFilterFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "matrix",
+		Short: "Compare each workspace branch with its base branch and remote",
+		Long: `Show, for every repository, the workspace branch against its base branch and
+against its remote counterpart: commits ahead and behind, the date of the last
+commit on each side, and whether one side can be fast-forwarded to the other.
+
+The base is the workspace base branch on the base remote (see 'wsm repos
+remote'), or the remote default branch. The remote is the upstream of the
+branch, or the same branch on origin (the fork remote in fork mode). Remote
+refs are compared as last fetched; run 'wsm bulk fetch' first for current
+numbers.
+
+Examples:
+  wsm branches matrix
+  wsm branch matrix --tag backend --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			return runBranchMatrix(cmd.Context(), outputFormat, &filters)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	filters.register(cmd, nil)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runBranchCreate(ctx context.Context, branchName string, track bool, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 
 	output.PrintHeader("🌿 Creating branch '%s' across workspace: %s", branchName, workspace.Name)
+	printFilterInfo(workspace)
 
 	results, err := syncOps.CreateBranch(ctx, branchName, track)
 	if err != nil {
+		if isInterrupted(err) {
+			_ = printBranchResults(results, "create")
+		}
 		return errors.Wrap(err, "branch creation failed")
 	}
 
 	return printBranchResults(results, "create")
 }
 
-func runBranchSwitch(ctx context.Context, branchName string) error {
+func runBranchSwitch(ctx context.Context, branchName string, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 
 	output.PrintHeader("🔄 Switching to branch '%s' across workspace: %s", branchName, workspace.Name)
+	printFilterInfo(workspace)
 
 	results, err := syncOps.SwitchBranch(ctx, branchName)
 	if err != nil {
+		if isInterrupted(err) {
+			_ = printBranchResults(results, "switch")
+		}
 		return errors.Wrap(err, "branch switch failed")
 	}
 
 	return printBranchResults(results, "switch")
 }
 
-func runBranchList(ctx context.Context) error {
+func runBranchList(ctx context.Context, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	output.PrintHeader("📋 Current branches in workspace: %s", workspace.Name)
+	printFilterInfo(workspace)
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	defer func() {
@@ -154,9 +234,15 @@ func runBranchList(ctx context.Context) error {
 				statusSymbol = "⚠️"
 			}
 
+			branch := repoStatus.CurrentBranch
+			if ref := workspace.PinFor(repo.Name); ref != "" {
+				branch = "@" + ref
+				statusSymbol = "📌"
+			}
+
 			fmt.Fprintf(w, "%s\t%s\t%s\n",
 				repo.Name,
-				repoStatus.CurrentBranch,
+				branch,
 				statusSymbol,
 			)
 		}
@@ -187,10 +273,14 @@ func printBranchResults(results []wsm.SyncResult, operation string) error {
 	fmt.Fprintln(w, "----------\t------\t-----")
 
 	successCount := 0
+	skippedCount := 0
 
 	for _, result := range results {
 		status := "✅"
-		if !result.Success {
+		if result.Skipped {
+			status = "📌"
+			skippedCount++
+		} else if !result.Success {
 			status = "❌"
 		} else {
 			successCount++
@@ -211,11 +301,89 @@ func printBranchResults(results []wsm.SyncResult, operation string) error {
 	fmt.Fprintln(w)
 
 	// Summary
-	output.PrintSuccess("Summary: %d/%d repositories %s successfully", successCount, len(results), operation)
+	output.PrintSuccess("Summary: %d/%d repositories %s successfully", successCount, len(results)-skippedCount, operation)
+	if skippedCount > 0 {
+		output.PrintInfo("%d pinned repositories skipped", skippedCount)
+	}
 
-	if successCount < len(results) {
+	if successCount+skippedCount < len(results) {
 		output.PrintWarning("Some repositories failed. Check errors above and resolve manually.")
 	}
 
 	return nil
 }
+
+func runBranchMatrix(ctx context.Context, outputFormat string, filters *repoFilterFlags) error {
+	workspace, err := detectCurrentWorkspace()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect current workspace")
+	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+
+	rows := wsm.GetBranchMatrix(ctx, workspace)
+	if outputFormat == "json" {
+		return wsm.PrintJSON(rows)
+	}
+
+	output.PrintHeader("Branch matrix of workspace: %s", workspace.Name)
+	printFilterInfo(workspace)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nREPOSITORY\tBRANCH\tLAST COMMIT\tBASE\tVS BASE\tBASE COMMIT\tREMOTE\tVS REMOTE\tREMOTE COMMIT")
+	fmt.Fprintln(w, "----------\t------\t-----------\t----\t-------\t-----------\t------\t---------\t-------------")
+	for _, row := range rows {
+		branch := row.Branch
+		switch {
+		case row.Pinned != "":
+			branch = "@" + row.Pinned
+		case row.Error != "":
+			branch = row.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			row.Repository, branch, matrixDate(row.LastCommit),
+			matrixRef(row.Base), matrixDivergence(row.Base), matrixDate(row.Base.LastCommit),
+			matrixRef(row.Remote), matrixDivergence(row.Remote), matrixDate(row.Remote.LastCommit))
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	fmt.Println()
+	output.PrintInfo("↑ commits only on the workspace branch, ↓ commits only on the other ref; ff: no divergence")
+	return nil
+}
+
+// matrixRef names the ref of a comparison, "-" when there is none
+func matrixRef(c wsm.BranchComparison) string {
+	if c.Ref == "" {
+		return "-"
+	}
+	if !c.Exists {
+		return c.Ref + " (missing)"
+	}
+	return c.Ref
+}
+
+// matrixDivergence renders ahead/behind counts and the fast-forward state
+func matrixDivergence(c wsm.BranchComparison) string {
+	if !c.Exists {
+		return "-"
+	}
+	switch {
+	case c.Ahead == 0 && c.Behind == 0:
+		return "up to date"
+	case c.FastForward:
+		return fmt.Sprintf("↑%d ↓%d ff", c.Ahead, c.Behind)
+	}
+	return fmt.Sprintf("↑%d ↓%d diverged", c.Ahead, c.Behind)
+}
+
+// matrixDate renders a commit date relative to now
+func matrixDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return formatRelativeTime(t)
+}
@@ -0,0 +1,232 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// bulkSelection holds the flags selecting the workspaces of a bulk operation
+type bulkSelection struct {
+	all          bool
+	tags         []string
+	outputFormat string
+}
+
+func (s *bulkSelection) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&s.all, "all", false, "Run on every saved workspace")
+	cmd.Flags().StringSliceVar(&s.tags, "tag", nil, "Only workspaces containing a repository with any of these tags")
+	cmd.Flags().StringVarP(&s.outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"tag":    TagCompletion().UniqueList(","),
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+	carapace.Gen(cmd).PositionalAnyCompletion(WorkspaceNameCompletion())
+}
+
+// workspaces resolves the selected workspaces; patterns are the positional arguments
+func (s *bulkSelection) workspaces(patterns []string) ([]wsm.Workspace, error) {
+	if s.outputFormat != "table" && s.outputFormat != "json" {
+		return nil, errors.Errorf("unsupported output format: %s", s.outputFormat)
+	}
+	if !s.all && len(patterns) == 0 && len(s.tags) == 0 {
+		return nil, errors.New("select workspaces with --all, name patterns or --tag")
+	}
+	if s.all && len(patterns) > 0 {
+		return nil, errors.New("--all cannot be combined with name patterns")
+	}
+
+	workspaces, err := wsm.SelectWorkspaces(patterns, s.tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) == 0 {
+		return nil, errors.New("no workspaces match the selection")
+	}
+	return workspaces, nil
+}
+
+// NewBulkCommand creates the bulk command
+func NewBulkCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bulk",
+		Short: "Run an operation on many workspaces",
+		Long: `Run sync, fetch or status on every saved workspace, or on the workspaces
+whose name matches a glob pattern or that contain a repository with a tag,
+and print one consolidated summary row per workspace.
+
+Workspaces are processed one after another. wsm exits with an error if the
+operation failed in any workspace.
+
+Examples:
+  # Status of every workspace
+  wsm bulk status --all
+
+  # Pull the feature workspaces, without pushing
+  wsm bulk sync 'feature-*' --push=false
+
+  # Fetch the workspaces containing Go repositories
+  wsm bulk fetch --tag go`,
+	}
+
+	cmd.AddCommand(
+		newBulkSyncCommand(),
+		newBulkFetchCommand(),
+		newBulkStatusCommand(),
+	)
+
+	return cmd
+}
+
+func newBulkSyncCommand() *cobra.Command {
+	var selection bulkSelection
+	options := &wsm.SyncOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sync [pattern...]",
+		Short: "Synchronize the repositories of many workspaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaces, err := selection.workspaces(args)
+			if err != nil {
+				return err
+			}
+			if options.DryRun {
+				output.PrintInfo("Dry run mode - no changes will be made")
+			}
+			results := wsm.BulkSync(cmd.Context(), workspaces, options)
+			return printBulkResults(results, "SYNCED", selection.outputFormat)
+		},
+	}
+
+	selection.addFlags(cmd)
+	cmd.Flags().BoolVar(&options.Pull, "pull", true, "Pull latest changes")
+	cmd.Flags().BoolVar(&options.Push, "push", true, "Push local commits")
+	cmd.Flags().BoolVar(&options.Rebase, "rebase", false, "Use rebase when pulling")
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "Show what would be done")
+	cmd.Flags().BoolVar(&options.Confirm, "confirm", false, "Allow pushing protected branches")
+
+	return cmd
+}
+
+func newBulkFetchCommand() *cobra.Command {
+	var selection bulkSelection
+
+	cmd := &cobra.Command{
+		Use:   "fetch [pattern...]",
+		Short: "Fetch the repositories of many workspaces",
+		Long: `Fetch every repository of the selected workspaces once, without touching
+worktrees. Fetch times are recorded and shown by 'wsm status', like the fetches
+of 'wsm daemon fetch'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBulkFetch(cmd.Context(), &selection, args)
+		},
+	}
+
+	selection.addFlags(cmd)
+
+	return cmd
+}
+
+func newBulkStatusCommand() *cobra.Command {
+	var selection bulkSelection
+
+	cmd := &cobra.Command{
+		Use:   "status [pattern...]",
+		Short: "Show the status of many workspaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaces, err := selection.workspaces(args)
+			if err != nil {
+				return err
+			}
+			results := wsm.BulkStatus(cmd.Context(), workspaces)
+			return printBulkResults(results, "CLEAN", selection.outputFormat)
+		},
+	}
+
+	selection.addFlags(cmd)
+
+	return cmd
+}
+
+func runBulkFetch(ctx context.Context, selection *bulkSelection, patterns []string) error {
+	workspaces, err := selection.workspaces(patterns)
+	if err != nil {
+		return err
+	}
+
+	results, err := wsm.BulkFetch(ctx, workspaces)
+	if err != nil {
+		return errors.Wrap(err, "fetch failed")
+	}
+	return printBulkResults(results, "FETCHED", selection.outputFormat)
+}
+
+// printBulkResults prints one row per workspace; doneColumn names the column
+// counting the repositories the operation succeeded in
+func printBulkResults(results []wsm.BulkResult, doneColumn, outputFormat string) error {
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "\nWORKSPACE\tSTATUS\t%s\tFAILED\tMODIFIED\tSYNC\tERROR\n", doneColumn)
+		fmt.Fprintln(w, "---------\t------\t------\t------\t--------\t----\t-----")
+		for _, result := range results {
+			symbol := "✅"
+			if !result.Success {
+				symbol = "❌"
+			} else if result.Status != "clean" && result.Status != "synced" && result.Status != "fetched" {
+				symbol = "🔄"
+			}
+
+			done := fmt.Sprintf("%d/%d", result.Succeeded, result.Repositories-result.Skipped)
+			if doneColumn == "CLEAN" {
+				done = fmt.Sprintf("%d/%d", result.Repositories-result.Modified, result.Repositories)
+			}
+
+			errorMsg := result.Error
+			if len(errorMsg) > 50 {
+				errorMsg = errorMsg[:47] + "..."
+			}
+
+			fmt.Fprintf(w, "%s\t%s %s\t%s\t%d\t%d\t↑%d ↓%d\t%s\n",
+				result.Workspace, symbol, result.Status, done, result.Failed, result.Modified,
+				result.Ahead, result.Behind, errorMsg)
+		}
+		fmt.Fprintln(w)
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "failed to flush table writer")
+		}
+
+		// The table truncates errors; show them in full
+		for _, result := range results {
+			if result.Error != "" {
+				output.PrintError("%s: %s", result.Workspace, result.Error)
+			}
+		}
+		output.PrintSuccess("Summary: %d/%d workspaces succeeded", len(results)-failed, len(results))
+	}
+
+	if failed > 0 {
+		return errors.Errorf("operation failed in %d of %d workspaces", failed, len(results))
+	}
+	return nil
+}
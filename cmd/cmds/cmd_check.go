@@ -0,0 +1,182 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewCheckCommand creates the check command
+func NewCheckCommand() *cobra.Command {
+	var (
+		workspaceName string
+		filters       repoFilterFlags
+		jobs          int
+		outputFormat  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run the configured checks (tests, linters) in every repository",
+		Long: `Run the check commands configured in checks.yaml in the workspace-manager
+config directory, e.g. 'go test ./...' or 'npm test', in every repository of
+the workspace:
+
+  enforce: [push, merge]
+  jobs: 4
+  timeout: 10m
+  tags:
+    go: ["go vet ./...", "go test ./..."]
+    node: ["npm test"]
+  repositories:
+    app: ["make lint", "make test"]
+
+Commands are listed per tag (repository category, see 'wsm repos tag') or per
+repository; a repository entry replaces the commands of its tags. Repositories
+run in parallel (--jobs at a time), the commands of a repository in order until
+one fails. wsm exits with an error if the checks fail in any repository.
+
+With enforce, 'wsm push' and 'wsm merge' run the checks first and stop when
+they fail, unless --skip-checks is given.
+
+Examples:
+  wsm check
+
+  # Check the Go repositories only
+  wsm check --tag go
+
+  # Collect the results as JSON
+  wsm check -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+
+			var workspace *wsm.Workspace
+			var err error
+			if workspaceName == "" {
+				workspace, err = detectCurrentWorkspace()
+			} else {
+				workspace, err = loadWorkspace(workspaceName)
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to find workspace")
+			}
+			if workspace, err = filters.apply(workspace); err != nil {
+				return err
+			}
+			return runCheck(cmd.Context(), workspace, jobs, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&workspaceName, "workspace", "w", "", "Workspace name (default: detect from current directory)")
+	filters.register(cmd, &workspaceName)
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Number of repositories to check in parallel (default: jobs of checks.yaml, else of limits.yaml, or 4)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runCheck(ctx context.Context, workspace *wsm.Workspace, jobs int, outputFormat string) error {
+	config, err := wsm.LoadChecksConfig()
+	if err != nil {
+		return err
+	}
+
+	options := wsm.ChecksOptions{Jobs: jobs}
+	// JSON collects the output per repository instead of streaming it
+	if outputFormat == "table" {
+		options.Stdout = os.Stdout
+		options.Stderr = os.Stderr
+	}
+	results, err := config.RunChecks(ctx, workspace, options)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printCheckResults(results, config)
+	}
+	return wsm.ChecksError(results)
+}
+
+// enforceChecks runs the checks before an operation when checks.yaml enforces
+// them, failing when any repository does not pass
+func enforceChecks(ctx context.Context, workspace *wsm.Workspace, operation string, skip bool) error {
+	config, err := wsm.LoadChecksConfig()
+	if err != nil {
+		return err
+	}
+	if !config.Enforced(operation) {
+		return nil
+	}
+	if skip {
+		output.PrintWarning("Skipping the checks enforced before %s (--skip-checks)", operation)
+		return nil
+	}
+
+	output.PrintInfo("Running checks before %s...", operation)
+	results, err := config.RunChecks(ctx, workspace, wsm.ChecksOptions{Stdout: os.Stdout, Stderr: os.Stderr})
+	if err != nil {
+		return err
+	}
+	printCheckResults(results, config)
+	if err := wsm.ChecksError(results); err != nil {
+		return errors.Wrapf(err, "%s blocked, fix the checks or re-run with --skip-checks", operation)
+	}
+	return nil
+}
+
+func printCheckResults(results []wsm.CheckResult, config *wsm.ChecksConfig) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tRESULT\tDURATION\tDETAILS")
+	fmt.Fprintln(w, "----------\t------\t--------\t-------")
+	checked := 0
+	for _, result := range results {
+		status, details := "pass", strings.Join(result.Commands, " && ")
+		switch {
+		case result.Skipped:
+			status, details = "skip", "no checks configured"
+		case !result.Passed:
+			status = "FAIL"
+			details = result.Error
+			if result.Failed != "" {
+				details = fmt.Sprintf("%s (exit %d)", result.Failed, result.ExitCode)
+				if result.Error != "" {
+					details = fmt.Sprintf("%s (%s)", result.Failed, result.Error)
+				}
+			}
+		}
+		if !result.Skipped {
+			checked++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Repository, status, result.Duration.Round(100*time.Millisecond), details)
+	}
+	_ = w.Flush()
+
+	if checked == 0 {
+		output.PrintWarning("No checks configured for these repositories in %s", config.Path())
+	}
+}
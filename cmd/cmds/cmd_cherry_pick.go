@@ -0,0 +1,184 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewCherryPickCommand creates the cherry-pick command
+func NewCherryPickCommand() *cobra.Command {
+	var (
+		from          string
+		into          string
+		keepConflicts bool
+		dryRun        bool
+		outputFormat  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cherry-pick <change-id | repo=sha...>",
+		Short: "Cherry-pick a change into the repositories of a workspace",
+		Long: `Cherry-pick the commits of a change into the current workspace, or into the
+workspace given with --into, e.g. to backport a fix to a release workspace.
+
+A change is either the Workspace-Change-Id of commits made with
+'wsm commit --change-id', or a list of repo=sha arguments. With a change id,
+matching commits are searched on all branches, or only on the branch of the
+workspace given with --from. Commits are picked oldest first with 'git
+cherry-pick -x'; commits whose cherry-pick is already on the branch are skipped.
+
+Each repository is handled on its own. When a commit conflicts, the conflicting
+files are reported and the repository is restored to its previous state, unless
+--keep-conflicts leaves the cherry-pick in progress for manual resolution.
+
+Examples:
+  # Backport a change made with 'wsm commit --change-id' into this workspace
+  wsm cherry-pick 1b4e28ba-2fa1-41d2-883f-0016d3cca427 --from main-ws
+
+  # Pick specific commits into the release workspace
+  wsm cherry-pick api=3f2a9c1 web=8d0e4b7,91ac2f0 --into release-1.4
+
+  # Show what would be picked
+  wsm cherry-pick 1b4e28ba-2fa1-41d2-883f-0016d3cca427 --dry-run`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: audited("cherry-pick", -1, func(cmd *cobra.Command, args []string) error {
+			return runCherryPick(cmd.Context(), args, from, into, keepConflicts, dryRun, outputFormat)
+		}),
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Only search the change on the branch of this workspace")
+	cmd.Flags().StringVar(&into, "into", "", "Workspace to cherry-pick into (default: current workspace)")
+	cmd.Flags().BoolVar(&keepConflicts, "keep-conflicts", false, "Leave conflicting cherry-picks in progress instead of aborting them")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the commits that would be picked")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"from":   WorkspaceNameCompletion(),
+			"into":   WorkspaceNameCompletion(),
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runCherryPick(ctx context.Context, args []string, from, into string, keepConflicts, dryRun bool, outputFormat string) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	options := &wsm.CherryPickOptions{DryRun: dryRun, KeepConflicts: keepConflicts}
+	if len(args) == 1 && !strings.Contains(args[0], "=") {
+		options.ChangeID = args[0]
+	} else {
+		if from != "" {
+			return errors.New("--from only applies to change ids")
+		}
+		commits, err := wsm.ParseCherryPickSpecs(args)
+		if err != nil {
+			return err
+		}
+		options.Commits = commits
+	}
+
+	var workspace *wsm.Workspace
+	var err error
+	if into != "" {
+		workspace, err = loadWorkspace(into)
+	} else {
+		workspace, err = detectCurrentWorkspace()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to find target workspace")
+	}
+
+	if from != "" {
+		if options.Source, err = loadWorkspace(from); err != nil {
+			return err
+		}
+		if options.Source.Name == workspace.Name {
+			return errors.New("--from and the target workspace are the same")
+		}
+	}
+
+	results, err := wsm.NewSyncOperations(workspace).CherryPick(ctx, options)
+	if err != nil {
+		return errors.Wrap(err, "cherry-pick failed")
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printCherryPickResults(workspace, results, dryRun)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if len(results) == 0 {
+		return errors.New("no commits found for the change")
+	}
+	if failed > 0 {
+		return errors.Errorf("cherry-pick failed in %d of %d repositories", failed, len(results))
+	}
+	return nil
+}
+
+func printCherryPickResults(workspace *wsm.Workspace, results []wsm.CherryPickResult, dryRun bool) {
+	if dryRun {
+		output.PrintHeader("Cherry-pick preview for workspace '%s'", workspace.Name)
+	} else {
+		output.PrintHeader("Cherry-pick into workspace '%s'", workspace.Name)
+	}
+
+	for _, result := range results {
+		switch {
+		case !result.Success && result.Conflicts:
+			fmt.Printf("⚠️  %s: %s\n", result.Repository, result.Error)
+			if result.ConflictCommit != "" {
+				fmt.Printf("    conflicting commit %s\n", shortHash(result.ConflictCommit))
+			}
+			for _, file := range result.ConflictFiles {
+				fmt.Printf("    %s\n", file)
+			}
+		case !result.Success:
+			fmt.Printf("❌ %s: %s\n", result.Repository, result.Error)
+		case result.Skipped:
+			fmt.Printf("⏭️  %s: already applied\n", result.Repository)
+		case dryRun:
+			fmt.Printf("🔍 %s: would pick %d commits\n", result.Repository, len(result.Commits)-len(result.AlreadyApplied))
+		default:
+			fmt.Printf("✅ %s: picked %d commits\n", result.Repository, result.Applied)
+		}
+
+		if dryRun || !result.Success {
+			for _, commit := range result.Commits {
+				note := ""
+				if containsString(result.AlreadyApplied, commit) {
+					note = " (already applied)"
+				}
+				fmt.Printf("    %s%s\n", shortHash(commit), note)
+			}
+		}
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,260 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewCICommand creates the ci command
+func NewCICommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Trigger CI for workspace repositories",
+		Long: `Trigger CI for the repositories of a workspace and follow the runs.
+
+CI is configured in ci.yaml in the workspace-manager config directory. GitHub
+Actions workflows are started with workflow_dispatch through the gh CLI;
+webhook targets receive a JSON POST with the workspace, repository, branch and
+commit. Inputs may use {workspace}, {branch}, {base_branch}, {repo} and {commit}:
+
+  provider: github
+  workflow: ci.yml
+  inputs:
+    workspace: "{workspace}"
+  repositories:
+    legacy:
+      provider: webhook
+      url: https://ci.example.com/hooks/build
+      token-env: CI_TOKEN
+    docs:
+      provider: none
+
+'wsm push --ci' and 'wsm merge --ci' trigger CI after pushing or merging, and
+'wsm status --ci' shows the latest run of every repository.`,
+	}
+
+	cmd.AddCommand(newCITriggerCommand())
+
+	return cmd
+}
+
+func newCITriggerCommand() *cobra.Command {
+	var (
+		workspaceName string
+		ref           string
+		wait          bool
+		interval      time.Duration
+		dryRun        bool
+		outputFormat  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "trigger [repository...]",
+		Short: "Trigger CI for the repositories of a workspace",
+		Long: `Trigger CI for all or the given repositories of the current workspace, on the
+branch checked out in each worktree unless --ref is given. Pinned repositories
+and repositories with provider none are skipped.
+
+Examples:
+  # Run CI for every repository and wait for the results
+  wsm ci trigger --wait
+
+  # Run CI for one repository on main
+  wsm ci trigger api --ref main
+
+  # Show the workflows and inputs that would be dispatched
+  wsm ci trigger --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspace, err := resolveWorkspace(workspaceName)
+			if err != nil {
+				return err
+			}
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			options := &wsm.CITriggerOptions{Repositories: args, Ref: ref, Event: "manual", DryRun: dryRun}
+			return triggerCI(cmd.Context(), workspace, options, wait, interval, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVar(&workspaceName, "workspace", "", "Workspace name (default: current workspace)")
+	cmd.Flags().StringVar(&ref, "ref", "", "Branch to run CI on (default: the checked out branch)")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait until the triggered GitHub Actions runs complete")
+	cmd.Flags().DurationVar(&interval, "interval", 15*time.Second, "Polling interval for --wait")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be triggered")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalAnyCompletion(CurrentWorkspaceRepositoryCompletion(nil))
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+// resolveWorkspace loads the named workspace, or the one containing the current directory
+func resolveWorkspace(workspaceName string) (*wsm.Workspace, error) {
+	if workspaceName != "" {
+		return loadWorkspace(workspaceName)
+	}
+	workspace, err := detectCurrentWorkspace()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to detect current workspace")
+	}
+	return workspace, nil
+}
+
+// triggerCI triggers CI, prints the results and optionally waits for the runs
+func triggerCI(ctx context.Context, workspace *wsm.Workspace, options *wsm.CITriggerOptions, wait bool, interval time.Duration, outputFormat string) error {
+	started := time.Now()
+	results, err := wsm.TriggerCI(ctx, workspace, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to trigger CI")
+	}
+
+	if outputFormat == "json" && !wait {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else if outputFormat != "json" {
+		printCITriggerResults(results, options.DryRun)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to trigger CI in %d of %d repositories", failed, len(results))
+	}
+
+	if !wait || options.DryRun {
+		return nil
+	}
+	return waitForCI(ctx, workspace, started, interval, outputFormat)
+}
+
+func printCITriggerResults(results []wsm.CITriggerResult, dryRun bool) {
+	for _, result := range results {
+		target := result.Provider
+		if result.Workflow != "" && result.Provider == wsm.CIProviderGitHub {
+			target = result.Workflow
+		}
+		switch {
+		case result.Skipped:
+			fmt.Printf("⏭️  %s: skipped\n", result.Repository)
+		case !result.Success:
+			fmt.Printf("❌ %s: %s\n", result.Repository, result.Error)
+		case dryRun:
+			fmt.Printf("🔍 %s: would trigger %s on %s\n", result.Repository, target, result.Ref)
+			for _, key := range sortedStringKeys(result.Inputs) {
+				fmt.Printf("    %s=%s\n", key, result.Inputs[key])
+			}
+		default:
+			fmt.Printf("🚀 %s: triggered %s on %s\n", result.Repository, target, result.Ref)
+		}
+	}
+}
+
+// waitForCI polls until the runs started after since complete and fails if any did not succeed
+func waitForCI(ctx context.Context, workspace *wsm.Workspace, since time.Time, interval time.Duration, outputFormat string) error {
+	if outputFormat != "json" {
+		output.PrintInfo("Waiting for CI runs to complete...")
+	}
+	progress := output.StartProgress("Waiting for CI", 0)
+	runs, err := wsm.WaitForCI(ctx, workspace, since, interval, func(runs []wsm.CIRun) {
+		pending := 0
+		for _, run := range runs {
+			if !run.Completed() {
+				pending++
+			}
+		}
+		progress.Step(fmt.Sprintf("%d runs pending", pending))
+	})
+	progress.Done()
+	if err != nil {
+		return errors.Wrap(err, "failed to wait for CI")
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(runs); err != nil {
+			return err
+		}
+	} else if err := printCIRuns(runs); err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		if run.Provider == wsm.CIProviderGitHub && run.Conclusion != "" && run.Conclusion != "success" && run.Conclusion != "skipped" {
+			return errors.Errorf("CI did not succeed in %s (%s)", run.Repository, run.Conclusion)
+		}
+	}
+	return nil
+}
+
+func printCIRuns(runs []wsm.CIRun) error {
+	if len(runs) == 0 {
+		output.PrintInfo("No CI configured for the workspace repositories")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tBRANCH\tWORKFLOW\tCI\tSTARTED\tURL")
+	fmt.Fprintln(w, "----------\t------\t--------\t--\t-------\t---")
+	for _, run := range runs {
+		started := "-"
+		if !run.Created.IsZero() {
+			started = formatRelativeTime(run.Created)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\t%s\n", run.Repository, run.Branch, run.Workflow, ciSymbol(run), run, started, run.URL)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	for _, run := range runs {
+		if run.Error != "" {
+			output.PrintWarning("%s: %s", run.Repository, run.Error)
+		}
+	}
+	return nil
+}
+
+func ciSymbol(run wsm.CIRun) string {
+	switch {
+	case run.Error != "":
+		return "⚠️"
+	case run.Provider != wsm.CIProviderGitHub || run.Status == "":
+		return "➖"
+	case run.Status != "completed":
+		return "⏳"
+	case run.Conclusion == "success":
+		return "✅"
+	default:
+		return "❌"
+	}
+}
+
+func sortedStringKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,33 +3,135 @@ package cmds
 import (
 	"context"
 	"fmt"
-	"github.com/go-go-golems/workspace-manager/pkg/output"
-	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/carapace-sh/carapace"
+	"github.com/charmbracelet/huh"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 func NewCommitCommand() *cobra.Command {
 	var (
-		message     string
-		interactive bool
-		addAll      bool
-		push        bool
-		dryRun      bool
-		template    string
+		message      string
+		interactive  bool
+		addAll       bool
+		push         bool
+		dryRun       bool
+		template     string
+		conventional bool
+		changeID     bool
+		confirm      bool
+		allowSecrets bool
+		archived     bool
+		signing      commitSigning
+		split        commitSplit
+		filters      repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "commit",
 		Short: "Commit changes across workspace repositories",
 		Long: `Commit related changes across multiple repositories in the workspace.
-Supports interactive file selection and consistent commit messaging.`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCommit(cmd.Context(), message, interactive, addAll, push, dryRun, template)
-		},
+Supports interactive file selection and consistent commit messaging.
+
+With --interactive, pick the repositories and files to commit, preview diffs,
+and enter either one shared message or a separate message per repository.
+Already-staged files that are deselected are unstaged before committing.
+
+With --change-id, every commit gets a shared 'Workspace-Change-Id: <uuid>' trailer.
+Use 'wsm log --change <uuid>' to find all commits belonging to that change.
+
+Unstaged and untracked files matched by a .wsmignore file in the workspace root
+or in a repository are not listed and not staged by --add-all.
+
+With --push, pushing a branch declared protected in policy.yaml requires --confirm.
+
+Changes in archived repositories (see 'wsm repos archive') are not listed or
+committed unless --include-archived is given.
+
+Before committing, the staged changes of every repository are scanned for
+possible secrets: AWS keys, private keys, GitHub and Slack tokens, staged .env
+files, and high-entropy values on lines mentioning a key, token or password.
+Findings block the whole commit with a report per repository unless
+--allow-secrets is given. Rules are configured in secrets.yaml in the
+workspace-manager config directory:
+
+  rules:
+    - name: internal-token
+      pattern: "itk_[A-Za-z0-9]{32}"
+  entropy-threshold: 4.5      # negative disables the entropy check
+  allow: ["EXAMPLE"]          # lines matching these are never flagged
+  ignore-paths: ["testdata/*"]
+
+--signoff adds a Signed-off-by trailer to every commit, for repositories
+requiring the Developer Certificate of Origin. --sign signs every commit with
+the key and format (gpg.format openpgp, ssh or x509) configured in git;
+--signing-key selects another key. --author overrides the commit author, for
+all repositories or per repository with repo=Name <email>. Repositories that
+set commit.gpgsign in their own git config are signed regardless.
+
+Commit messages may contain the placeholders {workspace}, {branch}, {ticket}
+(the ticket ID of the workspace, see branch-naming.yaml) and {repo}, which are
+replaced for each repository. --template picks a built-in template
+(feature, fix, docs, style, refactor, test, chore) or one defined in
+commit-templates.yaml in the workspace-manager config directory:
+
+  templates:
+    bump: "chore({repo}): bump dependencies"
+  types: [feat, fix, docs, chore, security]
+
+--split commits the changes of every repository as one commit per group of
+paths, so a sweeping change becomes reviewable history. Groups are configured in
+the same file, in .wsmignore syntax, and matched in order; files in no group are
+committed last with the plain message:
+
+  groups:
+    - name: docs
+      paths: ["docs/**", "*.md"]
+      message: "docs: {message}"     # default: "{message} ({group})"
+    - name: deps
+      paths: [go.mod, go.sum]
+
+--split-group name=pattern,pattern adds a group, or replaces the paths of a
+configured one, and implies --split.
+
+'wsm commit undo' undoes the commits of the last 'wsm commit', keeping their
+changes staged.
+
+--conventional builds a Conventional Commits message (type, scope, summary,
+body, breaking change) interactively; types come from the same file.
+
+Examples:
+  # Commit every change with a per-repository scope
+  wsm commit --add-all -m "fix({repo}): handle empty config"
+
+  # Use a configured template
+  wsm commit --add-all --template bump
+
+  # Build a conventional commit message, then pick files
+  wsm commit --conventional --interactive
+
+  # Commit in every repository but one
+  wsm commit --add-all -m "chore: bump version" --exclude-repo docs
+
+  # Commit documentation, dependency and code changes separately
+  wsm commit --add-all -m "feat: rename config keys" --split
+
+  # Split by ad hoc groups
+  wsm commit --add-all -m "refactor: new logger" --split-group tests=*_test.go --split-group docs=docs/**
+
+  # Sign off and sign every commit, with a work identity in one repository
+  wsm commit --add-all -m "fix: typo" --signoff --sign --author "api=Jo Doe <jo@corp.example>"`,
+		RunE: audited("commit", -1, func(cmd *cobra.Command, args []string) error {
+			return runCommit(cmd.Context(), message, interactive, addAll, push, dryRun, template, conventional, changeID, confirm, allowSecrets, archived, &signing, &split, &filters)
+		}),
 	}
 
 	cmd.Flags().StringVarP(&message, "message", "m", "", "Commit message")
@@ -38,19 +140,77 @@ Supports interactive file selection and consistent commit messaging.`,
 	cmd.Flags().BoolVar(&push, "push", false, "Push changes after commit")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be committed")
 	cmd.Flags().StringVar(&template, "template", "", "Use commit message template")
+	cmd.Flags().BoolVar(&conventional, "conventional", false, "Build a Conventional Commits message interactively")
+	cmd.Flags().BoolVar(&changeID, "change-id", false, "Add a shared Workspace-Change-Id trailer to every repository's commit")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Allow --push to protected branches")
+	cmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "Commit even if the secret scan reports findings")
+	cmd.Flags().BoolVar(&archived, "include-archived", false, "Also commit in archived (read-only) repositories")
+	cmd.Flags().BoolVarP(&signing.signoff, "signoff", "s", false, "Add a Signed-off-by trailer to every commit")
+	cmd.Flags().BoolVarP(&signing.sign, "sign", "S", false, "Sign every commit (gpg or ssh, as configured in git)")
+	cmd.Flags().StringVar(&signing.signingKey, "signing-key", "", "Key to sign commits with (implies --sign)")
+	cmd.Flags().StringArrayVar(&signing.authors, "author", nil, "Override the commit author: 'Name <email>' or 'repo=Name <email>' (repeatable)")
+	cmd.Flags().BoolVar(&split.enabled, "split", false, "Commit each group of paths configured in commit-templates.yaml separately")
+	cmd.Flags().StringArrayVar(&split.groups, "split-group", nil, "Commit files matching patterns separately, as name=pattern,pattern (repeatable, implies --split)")
+	filters.register(cmd, nil)
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"template": CommitTemplateCompletion(),
+		},
+	)
+
+	cmd.AddCommand(newCommitUndoCommand())
 
 	return cmd
 }
 
-func runCommit(ctx context.Context, message string, interactive, addAll, push, dryRun bool, template string) error {
+// commitSigning holds the sign-off, signing and author flags of the commit command
+type commitSigning struct {
+	signoff    bool
+	sign       bool
+	signingKey string
+	authors    []string
+}
+
+// commitSplit holds the flags splitting commits by path groups
+type commitSplit struct {
+	enabled bool
+	groups  []string
+}
+
+func runCommit(ctx context.Context, message string, interactive, addAll, push, dryRun bool, template string, conventional, changeID, confirm, allowSecrets, includeArchived bool, signing *commitSigning, split *commitSplit, filters *repoFilterFlags) error {
+	if conventional && message != "" {
+		return errors.New("--conventional cannot be combined with --message")
+	}
+	if (interactive || conventional) && wsm.NonInteractive() {
+		return errors.New("--interactive and --conventional cannot be combined with --non-interactive, pass the message with -m")
+	}
+
+	authors, err := wsm.ParseAuthorSpecs(signing.authors)
+	if err != nil {
+		return err
+	}
+
 	// Detect current workspace
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+	for repoName := range authors {
+		if repoName != "" && !workspaceHasRepository(workspace, repoName) {
+			return errors.Errorf("--author: repository '%s' is not part of workspace '%s'", repoName, workspace.Name)
+		}
+	}
 
 	// Initialize git operations
 	gitOps := wsm.NewGitOperations(workspace)
+	gitOps.IncludeArchived = includeArchived
+	if archived := workspace.ArchivedRepositories(); len(archived) > 0 && !includeArchived {
+		output.PrintInfo("Skipping archived (read-only) repositories: %s", strings.Join(archived, ", "))
+	}
 
 	// Get all changes in workspace
 	allChanges, err := gitOps.GetWorkspaceChanges(ctx)
@@ -64,8 +224,26 @@ func runCommit(ctx context.Context, message string, interactive, addAll, push, d
 	}
 
 	// Handle commit message
+	templates, err := wsm.LoadCommitTemplates()
+	if err != nil {
+		return err
+	}
 	if message == "" && template != "" {
-		message = getCommitMessageFromTemplate(template)
+		message = templates.Message(template)
+	}
+	var groups []wsm.CommitGroup
+	if split.enabled || len(split.groups) > 0 {
+		if groups, err = wsm.ParseCommitGroupSpecs(split.groups, templates.Groups); err != nil {
+			return err
+		}
+		if len(groups) == 0 {
+			return errors.New("--split needs commit groups: configure groups in commit-templates.yaml or pass --split-group")
+		}
+	}
+	if conventional {
+		if message, err = buildConventionalCommit(templates.Types, message); err != nil {
+			return err
+		}
 	}
 
 	if message == "" && !interactive {
@@ -74,11 +252,18 @@ func runCommit(ctx context.Context, message string, interactive, addAll, push, d
 
 	// Handle interactive mode
 	var selectedChanges map[string][]wsm.FileChange
+	var messages map[string]string
 	if interactive {
-		selectedChanges, message, err = selectChangesInteractively(allChanges, message)
+		if addAll {
+			output.PrintWarning("--add-all is ignored in interactive mode; only selected files are staged")
+			addAll = false
+		}
+
+		selection, err := selectChangesInteractively(ctx, gitOps, allChanges, message)
 		if err != nil {
 			return errors.Wrap(err, "interactive selection failed")
 		}
+		selectedChanges, message, messages = selection.Changes, selection.Message, selection.Messages
 	} else {
 		selectedChanges = allChanges
 	}
@@ -88,13 +273,38 @@ func runCommit(ctx context.Context, message string, interactive, addAll, push, d
 		return nil
 	}
 
+	// Files that were already staged but deselected must not end up in the commit
+	if interactive && !dryRun {
+		if err := unstageDeselectedChanges(ctx, gitOps, allChanges, selectedChanges); err != nil {
+			return errors.Wrap(err, "failed to unstage deselected files")
+		}
+	}
+
 	// Create commit operation
 	operation := &wsm.CommitOperation{
-		Message: message,
-		Files:   selectedChanges,
-		DryRun:  dryRun,
-		AddAll:  addAll,
-		Push:    push,
+		Message:  message,
+		Files:    selectedChanges,
+		DryRun:   dryRun,
+		AddAll:   addAll,
+		Push:     push,
+		Messages: messages,
+		Confirm:  confirm,
+
+		AllowSecrets: allowSecrets,
+
+		Signoff:    signing.signoff,
+		Sign:       signing.sign,
+		SigningKey: signing.signingKey,
+		Authors:    authors,
+
+		Groups: groups,
+	}
+
+	if changeID {
+		operation.ChangeID, err = wsm.NewChangeID()
+		if err != nil {
+			return err
+		}
 	}
 
 	// Execute commit
@@ -104,6 +314,9 @@ func runCommit(ctx context.Context, message string, interactive, addAll, push, d
 
 	if !dryRun {
 		output.PrintSuccess("Successfully committed changes across %d repositories", len(selectedChanges))
+		if operation.ChangeID != "" {
+			output.PrintInfo("Change ID: %s (find with 'wsm log --change %s')", operation.ChangeID, operation.ChangeID)
+		}
 		if push {
 			output.PrintInfo("Changes pushed to remote repositories")
 		}
@@ -113,86 +326,337 @@ func runCommit(ctx context.Context, message string, interactive, addAll, push, d
 }
 
 // detectCurrentWorkspace detects the current workspace
+// detectCurrentWorkspace finds the workspace of the current directory for a
+// command working on it and records the use for 'wsm suggest-cleanup'
 func detectCurrentWorkspace() (*wsm.Workspace, error) {
-	cwd, err := os.Getwd()
+	workspace, err := findCurrentWorkspace()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get current directory")
+		return nil, err
 	}
+	recordWorkspaceUse(workspace.Name)
+	return workspace, nil
+}
 
-	// Try to find workspace by checking if we're in a workspace directory
-	workspaces, err := wsm.LoadWorkspaces()
+// findCurrentWorkspace finds the workspace of the current directory without
+// recording a use, for prompts and other commands that only look at it
+func findCurrentWorkspace() (*wsm.Workspace, error) {
+	cwd, err := os.Getwd()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load workspaces")
+		return nil, errors.Wrap(err, "failed to get current directory")
 	}
 
-	for _, workspace := range workspaces {
-		if strings.HasPrefix(cwd, workspace.Path) {
-			return &workspace, nil
-		}
+	workspace, err := wsm.NewWorkspaceService("").DetectWorkspace(cwd)
+	if errors.Is(err, wsm.ErrNotInWorkspace) {
+		return nil, errors.New("not in a workspace directory. Run command from within a workspace")
 	}
+	return workspace, err
+}
 
-	return nil, errors.New("not in a workspace directory. Run command from within a workspace")
+// commitSelection is the result of the interactive commit flow
+type commitSelection struct {
+	Changes  map[string][]wsm.FileChange
+	Message  string
+	Messages map[string]string
 }
 
-// selectChangesInteractively allows user to select files interactively
-func selectChangesInteractively(allChanges map[string][]wsm.FileChange, initialMessage string) (map[string][]wsm.FileChange, string, error) {
+const (
+	commitMessageShared  = "shared"
+	commitMessagePerRepo = "per-repo"
+	previewDone          = "__done__"
+)
+
+// selectChangesInteractively lets the user pick repositories and files, preview diffs and enter commit messages
+func selectChangesInteractively(ctx context.Context, gitOps *wsm.GitOperations, allChanges map[string][]wsm.FileChange, initialMessage string) (*commitSelection, error) {
 	output.PrintHeader("Interactive Commit")
 	fmt.Println()
 
-	// Show all changes
-	output.PrintInfo("Changes found:")
-	repoIndex := 0
+	repoNames := make([]string, 0, len(allChanges))
+	for repoName := range allChanges {
+		repoNames = append(repoNames, repoName)
+	}
+	sort.Strings(repoNames)
+
+	// Pick repositories
+	selectedRepos := repoNames
+	if len(repoNames) > 1 {
+		var repoOptions []ux.Option
+		for _, repoName := range repoNames {
+			label := fmt.Sprintf("%s (%d files)", repoName, len(uniqueChangePaths(allChanges[repoName])))
+			repoOptions = append(repoOptions, ux.Option{Label: label, Value: repoName, Selected: true})
+		}
 
-	for repoName, changes := range allChanges {
-		fmt.Printf("\n%d. Repository: %s (%d files)\n", repoIndex+1, repoName, len(changes))
+		var err error
+		if selectedRepos, err = ux.MultiSelect("Choose repositories to commit:", repoOptions); err != nil {
+			return nil, commitPromptError(err)
+		}
+	}
 
-		for i, change := range changes {
-			status := wsm.GetStatusSymbol(change.Status)
-			staged := ""
-			if change.Staged {
-				staged = " (staged)"
+	selection := &commitSelection{
+		Changes:  make(map[string][]wsm.FileChange),
+		Messages: make(map[string]string),
+	}
+
+	// Pick files per repository, with optional diff preview
+	for _, repoName := range selectedRepos {
+		changes := allChanges[repoName]
+
+		if err := previewDiffsInteractively(ctx, gitOps, repoName, changes); err != nil {
+			return nil, err
+		}
+
+		var fileOptions []ux.Option
+		for _, path := range uniqueChangePaths(changes) {
+			fileOptions = append(fileOptions, ux.Option{Label: describeChangePath(changes, path), Value: path, Selected: true})
+		}
+
+		selectedPaths, err := ux.MultiSelect(fmt.Sprintf("Files to commit in %s:", repoName), fileOptions)
+		if err != nil {
+			return nil, commitPromptError(err)
+		}
+
+		selectedSet := make(map[string]bool)
+		for _, path := range selectedPaths {
+			selectedSet[path] = true
+		}
+		for _, change := range changes {
+			if selectedSet[change.Path()] {
+				selection.Changes[repoName] = append(selection.Changes[repoName], change)
 			}
-			fmt.Printf("   %c. %s %s%s\n", 'a'+i, status, change.FilePath, staged)
 		}
-		repoIndex++
 	}
 
-	fmt.Println()
+	if len(selection.Changes) == 0 {
+		return selection, nil
+	}
 
-	// Get commit message if not provided
-	message := initialMessage
-	if message == "" {
-		fmt.Print("Commit message: ")
-		if _, err := fmt.Scanln(&message); err != nil {
-			return nil, "", errors.Wrap(err, "failed to read commit message")
+	// Commit messages: one shared message or one per repository
+	messageMode := commitMessageShared
+	if len(selection.Changes) > 1 {
+		var err error
+		if messageMode, err = ux.Select("Commit message:", []ux.Option{
+			ux.NewOption("One shared message for all repositories", commitMessageShared),
+			ux.NewOption("A separate message per repository", commitMessagePerRepo),
+		}); err != nil {
+			return nil, commitPromptError(err)
 		}
-		if message == "" {
-			return nil, "", errors.New("commit message is required")
+	}
+
+	selection.Message = initialMessage
+	if messageMode == commitMessageShared {
+		if err := runCommitForm(huh.NewText().
+			Title("Commit message").
+			Value(&selection.Message).
+			Validate(requireCommitMessage)); err != nil {
+			return nil, err
+		}
+		return selection, nil
+	}
+
+	for _, repoName := range selectedRepos {
+		if _, ok := selection.Changes[repoName]; !ok {
+			continue
+		}
+		message := initialMessage
+		if err := runCommitForm(huh.NewText().
+			Title(fmt.Sprintf("Commit message for %s", repoName)).
+			Value(&message).
+			Validate(requireCommitMessage)); err != nil {
+			return nil, err
+		}
+		selection.Messages[repoName] = message
+		if selection.Message == "" {
+			selection.Message = message
+		}
+	}
+
+	return selection, nil
+}
+
+// previewDiffsInteractively shows file diffs on request until the user continues
+func previewDiffsInteractively(ctx context.Context, gitOps *wsm.GitOperations, repoName string, changes []wsm.FileChange) error {
+	for {
+		options := []ux.Option{ux.NewOption("Continue to file selection", previewDone)}
+		for i, change := range changes {
+			options = append(options, ux.NewOption(fmt.Sprintf("Preview %s", describeChange(change)), strconv.Itoa(i)))
+		}
+
+		choice, err := ux.Select(fmt.Sprintf("Changes in %s", repoName), options)
+		if err != nil {
+			return commitPromptError(err)
+		}
+		if choice == previewDone {
+			return nil
 		}
+
+		idx, err := strconv.Atoi(choice)
+		if err != nil {
+			return errors.Wrap(err, "invalid selection")
+		}
+		diff, err := gitOps.GetFileDiff(ctx, changes[idx])
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println(diff)
 	}
+}
 
-	// Simple selection - for now, include all changes
-	// TODO: Implement more sophisticated interactive selection
-	output.PrintInfo("Proceeding with all changes...")
+// commitPromptError maps an aborted prompt to a cancellation error
+func commitPromptError(err error) error {
+	if errors.Is(err, ux.ErrAborted) {
+		return errors.New("commit cancelled by user")
+	}
+	return errors.Wrap(err, "interactive form failed")
+}
 
-	return allChanges, message, nil
+// runCommitForm runs a single-field form, mapping aborts to a cancellation error
+func runCommitForm(field huh.Field) error {
+	if err := huh.NewForm(huh.NewGroup(field)).Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return errors.New("commit cancelled by user")
+		}
+		return errors.Wrap(err, "interactive form failed")
+	}
+	return nil
 }
 
-// getCommitMessageFromTemplate gets commit message from template
-func getCommitMessageFromTemplate(template string) string {
-	templates := map[string]string{
-		"feature":  "feat: add new feature",
-		"fix":      "fix: resolve issue",
-		"docs":     "docs: update documentation",
-		"style":    "style: formatting changes",
-		"refactor": "refactor: code restructuring",
-		"test":     "test: add or update tests",
-		"chore":    "chore: maintenance tasks",
+func requireCommitMessage(message string) error {
+	if strings.TrimSpace(message) == "" {
+		return errors.New("commit message is required")
 	}
+	return nil
+}
 
-	if msg, exists := templates[template]; exists {
-		return msg
+// uniqueChangePaths returns the distinct file paths of the changes, in order
+func uniqueChangePaths(changes []wsm.FileChange) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, change := range changes {
+		if !seen[change.Path()] {
+			seen[change.Path()] = true
+			paths = append(paths, change.Path())
+		}
 	}
+	return paths
+}
 
-	return template // Use template as-is if not found in predefined templates
+// describeChangePath labels a path with the status of all its staged and unstaged changes
+func describeChangePath(changes []wsm.FileChange, path string) string {
+	var parts []string
+	for _, change := range changes {
+		if change.Path() == path {
+			parts = append(parts, describeChange(change))
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return fmt.Sprintf("%s [%d changes]", parts[0], len(parts))
+}
+
+func describeChange(change wsm.FileChange) string {
+	staged := ""
+	if change.Staged {
+		staged = " (staged)"
+	}
+	return fmt.Sprintf("%s %s%s", wsm.GetStatusSymbol(change.Status), change.FilePath, staged)
+}
+
+// unstageDeselectedChanges unstages previously staged files the user left out
+// of the selection, in the repositories being committed only
+func unstageDeselectedChanges(ctx context.Context, gitOps *wsm.GitOperations, allChanges, selected map[string][]wsm.FileChange) error {
+	for repoName, changes := range allChanges {
+		if len(selected[repoName]) == 0 {
+			continue
+		}
+		selectedPaths := make(map[string]bool)
+		for _, change := range selected[repoName] {
+			selectedPaths[change.Path()] = true
+		}
+		for _, change := range changes {
+			if change.Staged && !selectedPaths[change.Path()] {
+				if err := gitOps.UnstageFile(ctx, repoName, change.Path()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// buildConventionalCommit asks for the parts of a Conventional Commits message.
+// A template message of the form "type: summary" preselects the type and summary.
+func buildConventionalCommit(types []string, initialMessage string) (string, error) {
+	commit := wsm.ConventionalCommit{Type: types[0]}
+	if header, _, _ := strings.Cut(initialMessage, "\n"); header != "" {
+		if commitType, summary, ok := strings.Cut(header, ": "); ok && containsString(types, commitType) {
+			commit.Type, commit.Summary = commitType, summary
+		} else {
+			commit.Summary = header
+		}
+	}
+
+	var typeOptions []huh.Option[string]
+	for _, commitType := range types {
+		typeOptions = append(typeOptions, huh.NewOption(commitType, commitType))
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Type").
+				Options(typeOptions...).
+				Value(&commit.Type),
+			huh.NewInput().
+				Title("Scope").
+				Description("Optional, e.g. api or {repo} for the repository name").
+				Value(&commit.Scope),
+			huh.NewInput().
+				Title("Summary").
+				Value(&commit.Summary).
+				Validate(requireCommitMessage),
+			huh.NewText().
+				Title("Body").
+				Description("Optional").
+				Value(&commit.Body),
+			huh.NewConfirm().
+				Title("Breaking change?").
+				Value(&commit.Breaking),
+		),
+		huh.NewGroup(
+			huh.NewText().
+				Title("Describe the breaking change").
+				Description("Defaults to the summary").
+				Value(&commit.BreakingNote),
+		).WithHideFunc(func() bool { return !commit.Breaking }),
+	)
+	if err := form.Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return "", errors.New("commit cancelled by user")
+		}
+		return "", errors.Wrap(err, "interactive form failed")
+	}
+
+	if err := commit.Validate(); err != nil {
+		return "", err
+	}
+	return commit.String(), nil
+}
+
+func workspaceHasRepository(workspace *wsm.Workspace, repoName string) bool {
+	for _, repo := range workspace.Repositories {
+		if repo.Name == repoName {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,119 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newCommitUndoCommand() *cobra.Command {
+	var (
+		force        bool
+		dryRun       bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last workspace commit, keeping its changes staged",
+		Long: `Undo the commits made by the last 'wsm commit' of the current workspace, in
+every repository: a multi-repository 'git reset --soft' that leaves the
+committed changes staged. Split commits are undone together.
+
+The commits are identified by the HEADs recorded in .wsm/last-commit.json,
+and by their Workspace-Change-Id trailer when one was added. Nothing is reset
+unless every repository passes the checks: HEAD is still the recorded commit,
+no merge or rebase is in progress and, without --force, no remote branch
+contains the commit yet.
+
+Examples:
+  wsm commit -m "fix: typo" --add-all
+  wsm commit undo
+
+  # Only run the checks
+  wsm commit undo --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: audited("commit-undo", -1, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			return runCommitUndo(cmd.Context(), force, dryRun, outputFormat)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Undo commits that were already pushed")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only check whether the commit can be undone")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runCommitUndo(ctx context.Context, force, dryRun bool, outputFormat string) error {
+	workspace, err := detectCurrentWorkspace()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect current workspace")
+	}
+
+	undos, undoErr := wsm.UndoLastCommit(ctx, workspace, force, dryRun)
+	if outputFormat == "json" {
+		if undos != nil {
+			if err := wsm.PrintJSON(undos); err != nil {
+				return err
+			}
+		}
+		return undoErr
+	}
+
+	if len(undos) > 0 {
+		output.PrintHeader("Last commit of workspace '%s'", workspace.Name)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tCOMMIT\tRESET TO\tSTATUS")
+		fmt.Fprintln(w, "----------\t------\t--------\t------")
+		for _, undo := range undos {
+			status := "can be undone"
+			switch {
+			case undo.Problem != "":
+				status = undo.Problem
+			case undo.Undone:
+				status = "undone"
+			}
+			if undo.Pushed && undo.Problem == "" {
+				status += " (was pushed)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", undo.Repository, shortRef(undo.Head), shortRef(undo.Parent), status)
+		}
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "failed to flush table writer")
+		}
+		fmt.Println()
+	}
+	if undoErr != nil {
+		return undoErr
+	}
+
+	if !dryRun {
+		output.PrintSuccess("Undid the last commit in %d repositories, its changes are staged", len(undos))
+	}
+	return nil
+}
+
+// shortRef abbreviates a commit SHA for tables
+func shortRef(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
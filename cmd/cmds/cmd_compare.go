@@ -0,0 +1,144 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewCompareCommand creates the compare command
+func NewCompareCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "compare <workspace-a> <workspace-b>",
+		Short: "Compare the branches of two workspaces",
+		Long: `Compare the work committed in two workspaces, for instance two forks in which
+different approaches were tried.
+
+For each repository in both workspaces the commits only on either side are
+listed, with the files that differ between the two heads. Repositories in only
+one of the workspaces are listed at the end. Uncommitted changes are not
+compared; worktrees that have some are flagged.
+
+Examples:
+  # Compare two forks
+  wsm compare feature-a feature-b
+
+  # The same as JSON
+  wsm compare feature-a feature-b -o json`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			if args[0] == args[1] {
+				return errors.New("cannot compare a workspace with itself")
+			}
+			return runCompare(cmd.Context(), args[0], args[1], outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runCompare(ctx context.Context, nameA, nameB, outputFormat string) error {
+	a, err := findWorkspace(nameA)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", nameA)
+	}
+	b, err := findWorkspace(nameB)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", nameB)
+	}
+
+	comparison, err := wsm.CompareWorkspaces(ctx, a, b)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" {
+		return wsm.PrintJSON(comparison)
+	}
+	printWorkspaceComparison(comparison)
+	return nil
+}
+
+func printWorkspaceComparison(comparison *wsm.WorkspaceComparison) {
+	output.PrintHeader("Comparing %s with %s", comparison.A, comparison.B)
+
+	for _, repo := range comparison.Repositories {
+		fmt.Printf("\n  %s", repo.Repository)
+		if repo.BranchA != "" || repo.BranchB != "" {
+			fmt.Printf(" (%s ↔ %s)", repo.BranchA, repo.BranchB)
+		}
+		fmt.Println()
+
+		switch {
+		case repo.Error != "":
+			output.PrintError("    %s", repo.Error)
+			continue
+		case repo.Identical:
+			output.PrintSuccess("    Same commit %s", shortHash(repo.HeadA))
+		default:
+			printComparedCommits(comparison.A, repo.AheadA, repo.CommitsA)
+			printComparedCommits(comparison.B, repo.AheadB, repo.CommitsB)
+			if len(repo.Files) > 0 {
+				fmt.Printf("    %d file(s) differ, +%d -%d:\n", len(repo.Files), repo.Insertions, repo.Deletions)
+				for _, file := range repo.Files {
+					if file.Insertions < 0 {
+						fmt.Printf("      %s (binary)\n", file.Path)
+						continue
+					}
+					fmt.Printf("      %s %s %s\n", file.Path,
+						output.SuccessStyle.Render(fmt.Sprintf("+%d", file.Insertions)),
+						output.ErrorStyle.Render(fmt.Sprintf("-%d", file.Deletions)))
+				}
+			}
+		}
+
+		if repo.DirtyA {
+			output.PrintWarning("    %s has uncommitted changes, not compared", comparison.A)
+		}
+		if repo.DirtyB {
+			output.PrintWarning("    %s has uncommitted changes, not compared", comparison.B)
+		}
+	}
+
+	if len(comparison.OnlyInA) > 0 || len(comparison.OnlyInB) > 0 {
+		fmt.Println()
+	}
+	for _, name := range comparison.OnlyInA {
+		output.PrintInfo("%s: only in %s", name, comparison.A)
+	}
+	for _, name := range comparison.OnlyInB {
+		output.PrintInfo("%s: only in %s", name, comparison.B)
+	}
+}
+
+func printComparedCommits(workspace string, count int, commits []string) {
+	if count == 0 {
+		return
+	}
+	fmt.Printf("    %d commit(s) only in %s:\n", count, workspace)
+	for _, commit := range commits {
+		fmt.Printf("      %s\n", commit)
+	}
+	if count > len(commits) {
+		fmt.Printf("      ... and %d more\n", count-len(commits))
+	}
+}
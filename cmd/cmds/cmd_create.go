@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/charmbracelet/huh"
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -15,13 +16,34 @@ import (
 
 func NewCreateCommand() *cobra.Command {
 	var (
-		repos        []string
-		branch       string
-		branchPrefix string
-		baseBranch   string
-		agentSource  string
-		interactive  bool
-		dryRun       bool
+		repos         []string
+		manifest      string
+		cloneDir      string
+		outputFormat  string
+		emitScript    string
+		branch        string
+		branchPrefix  string
+		baseBranch    string
+		agentSource   string
+		scaffold      string
+		mergeStrategy string
+		ticket        string
+		sparse        []string
+		pins          []string
+		gitConfig     []string
+		submodules    string
+		interactive   bool
+		dryRun        bool
+		partial       bool
+		journal       bool
+		resume        bool
+		goReplace     bool
+		sanitize      bool
+		noScripts     bool
+		noHooks       bool
+		nix           bool
+		onExisting    string
+		onCheckedOut  string
 	)
 
 	cmd := &cobra.Command{
@@ -33,6 +55,12 @@ The workspace will contain git worktrees for each repository on the specified br
 If no branch is specified, a branch will be automatically created using the pattern:
   <branch-prefix>/<workspace-name>
 
+The pattern can be changed with a template in branch-naming.yaml in the
+workspace-manager config directory, which may also hold a team policy every
+branch must match:
+  template: "{prefix}/{ticket}-{title}"
+  pattern: '^(task|bug|feature)/[A-Z]+-[0-9]+-'
+
 Examples:
   # Create workspace with automatic branch (task/my-feature)
   workspace-manager create my-feature --repos app,lib
@@ -44,69 +72,253 @@ Examples:
   workspace-manager create my-feature --repos app,lib --branch-prefix bug
 
   # Create workspace from specific base branch
-  workspace-manager create my-feature --repos app,lib --base-branch main`,
+  workspace-manager create my-feature --repos app,lib --base-branch main
+
+  # Repositories the selected ones depend on (see 'wsm graph') are pointed out
+  workspace-manager create my-feature --repos app
+
+  # Use a repository group defined with 'wsm repos group set'
+  workspace-manager create my-feature --repos @backend,docs
+
+  # Use repositories that were never discovered: clones are registered, remote
+  # URLs are registered and cloned into --clone-dir
+  workspace-manager create my-feature --repos /src/app,git@github.com:me/lib.git
+
+  # Create from a manifest, cloning and registering missing repositories
+  workspace-manager create my-feature --manifest ./team-manifest.yaml
+
+  # Print the exact operations as JSON, or write them as a script to audit or run manually
+  workspace-manager create my-feature --repos app,lib --dry-run --output json
+  workspace-manager create my-feature --repos app,lib --dry-run --emit-script plan.sh
+
+  # Keep successfully created repositories if some worktrees fail
+  workspace-manager create my-feature --repos app,lib,shared --partial
+  workspace-manager add my-feature --retry-pending
+
+  # Record progress so an interrupted creation can be finished instead of rolled back
+  workspace-manager create my-feature --repos app,lib,shared --journal
+  workspace-manager create my-feature --resume
+
+  # Only check out some directories of a large repository (cone mode sparse checkout)
+  workspace-manager create my-feature --repos monorepo,lib --sparse monorepo=services/api,libs/common
+
+  # Develop the app against a frozen library release (detached, skipped by sync)
+  workspace-manager create my-feature --repos app,lib --pin lib=v1.4.2
+
+  # Decide up front what happens when the branch already exists (for scripts and CI)
+  workspace-manager create my-feature --repos app,lib --on-existing-branch use --non-interactive
+
+  # The branch is checked out in another workspace: work on feature/x@my-feature instead
+  workspace-manager create my-feature --repos app --branch feature/x --on-checked-out-branch link
+
+  # Commit with a work identity and hooks in every worktree (git config --worktree),
+  # removed again when the workspace is deleted
+  workspace-manager create my-feature --repos app,lib --git-config user.email=me@corp.example --git-config core.hooksPath=.githooks
+
+  # Submodules are initialized in each worktree; include nested ones or skip them
+  workspace-manager create my-feature --repos firmware --submodules recursive
+  workspace-manager create my-feature --repos firmware --submodules none
+
+  # Link the workspace to its ticket: the ID lands in branch names ({ticket} in
+  # branch-naming.yaml), commit messages and pull requests; 'wsm open-ticket' opens it
+  workspace-manager create refund-api --repos api,billing --ticket PAY-42
+  workspace-manager create refund-api --repos api --ticket https://linear.app/acme/issue/PAY-42
+
+  # Scaffold notes/, tickets/ etc. from ~/templates/<name>; files ending in .tmpl
+  # are rendered with {{.Name}}, {{.Branch}}, {{.BaseBranch}}, {{.Repositories}},
+  # {{.Path}} and {{.Date}}. ~/templates/default is used when no --scaffold is given.
+  workspace-manager create my-feature --repos app,lib --scaffold research
+  workspace-manager create my-feature --repos app,lib --scaffold none`,
 		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCreate(cmd.Context(), args[0], repos, branch, branchPrefix, baseBranch, agentSource, interactive, dryRun)
-		},
+		RunE: audited("create", 0, func(cmd *cobra.Command, args []string) error {
+			plan := planOptions{format: outputFormat, script: emitScript}
+			if (plan.format != "" || plan.script != "") && !dryRun {
+				return errors.New("--output and --emit-script require --dry-run")
+			}
+			if plan.format != "" && plan.format != "json" {
+				return errors.Errorf("unsupported output format '%s' (supported: json)", plan.format)
+			}
+			if err := wsm.ValidateExistingBranchPolicy(onExisting); err != nil {
+				return err
+			}
+			if err := wsm.ValidateCheckedOutBranchPolicy(onCheckedOut); err != nil {
+				return err
+			}
+			if interactive && wsm.NonInteractive() {
+				return errors.New("--interactive cannot be combined with --non-interactive, select repositories with --repos")
+			}
+			if resume {
+				if len(repos) > 0 || manifest != "" || interactive || dryRun {
+					return errors.New("--resume cannot be combined with --repos, --manifest, --interactive or --dry-run")
+				}
+				return runResumeCreate(cmd.Context(), args[0], onExisting, onCheckedOut)
+			}
+			if journal && dryRun {
+				return errors.New("--journal cannot be combined with --dry-run")
+			}
+			sparsePaths, err := wsm.ParseSparseSpecs(sparse)
+			if err != nil {
+				return err
+			}
+			pinRefs, err := wsm.ParsePinSpecs(pins)
+			if err != nil {
+				return err
+			}
+			gitConfigs, err := wsm.ParseGitConfig(gitConfig)
+			if err != nil {
+				return err
+			}
+			if err := wsm.ValidateSubmoduleMode(submodules); err != nil {
+				return err
+			}
+			if err := wsm.ValidateMergeStrategy(mergeStrategy); err != nil {
+				return err
+			}
+			opts := wsm.CreateOptions{
+				Name:          args[0],
+				Repositories:  repos,
+				Branch:        branch,
+				BaseBranch:    baseBranch,
+				AgentSource:   agentSource,
+				SparsePaths:   sparsePaths,
+				Pins:          pinRefs,
+				Submodules:    submodules,
+				Scaffold:      scaffold,
+				MergeStrategy: mergeStrategy,
+				GitConfig:     gitConfigs,
+				GoReplaces:    goReplace,
+				Sanitize:      sanitize,
+				NoScripts:     noScripts,
+				NoHooks:       noHooks,
+				DryRun:        dryRun,
+				Partial:       partial,
+				Journaled:     journal,
+			}
+			if cmd.Flags().Changed("nix") {
+				opts.Nix = &nix
+			}
+			if ticket != "" {
+				if opts.Ticket, err = wsm.ResolveTicket(ticket); err != nil {
+					return err
+				}
+			}
+			if manifest != "" && (len(repos) > 0 || interactive) {
+				return errors.New("--manifest cannot be combined with --repos or --interactive")
+			}
+
+			wm, err := wsm.NewWorkspaceManager()
+			if err != nil {
+				return errors.Wrap(err, "failed to create workspace manager")
+			}
+			wm.OnExistingBranch = onExisting
+			wm.OnCheckedOutBranch = onCheckedOut
+			if manifest != "" {
+				return runCreateFromManifest(cmd.Context(), wm, manifest, cloneDir, branchPrefix, opts, plan)
+			}
+			// Repositories registered without a clone (e.g. from a GitHub org) are cloned here
+			wm.CloneDir = cloneDir
+			return runCreate(cmd.Context(), wm, branchPrefix, interactive, opts, plan)
+		}),
 	}
 
-	cmd.Flags().StringSliceVar(&repos, "repos", nil, "Repository names to include (comma-separated)")
+	cmd.Flags().StringSliceVar(&repos, "repos", nil, "Repository names, @groups, paths or remote URLs to include (comma-separated)")
 	cmd.Flags().StringVar(&branch, "branch", "", "Branch name for worktrees (if not specified, uses <branch-prefix>/<workspace-name>)")
 	cmd.Flags().StringVar(&branchPrefix, "branch-prefix", "task", "Prefix for auto-generated branch names")
 	cmd.Flags().StringVar(&baseBranch, "base-branch", "", "Base branch to create new branch from (defaults to current branch)")
 	cmd.Flags().StringVar(&agentSource, "agent-source", "", "Path to AGENT.md template file")
+	cmd.Flags().StringVar(&scaffold, "scaffold", "", "Scaffold template (name in the template directory or a path) copied into the workspace, 'none' to skip (default: 'default' template if present)")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", "", "How 'wsm merge' merges the workspace: merge, squash, rebase or ff-only (default: manifest setting or merge)")
+	cmd.Flags().StringVar(&ticket, "ticket", "", "Ticket the workspace works on, as an ID (PROJ-123) or URL; used in branch names, commit messages and pull requests")
+	cmd.Flags().StringArrayVar(&sparse, "sparse", nil, "Sparse checkout directories for a repository as repo=dir1,dir2 (repeatable)")
+	cmd.Flags().StringArrayVar(&pins, "pin", nil, "Pin a repository to a tag or commit as repo=ref, checked out detached and skipped by sync (repeatable)")
+	cmd.Flags().StringArrayVar(&gitConfig, "git-config", nil, "Git config set in every worktree as key=value, e.g. user.email=me@corp.example (repeatable)")
+	cmd.Flags().StringVar(&submodules, "submodules", "", "How submodules of the worktrees are initialized: init, recursive or none (default: manifest setting or init)")
 	cmd.Flags().BoolVar(&interactive, "interactive", false, "Interactive repository selection")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be created without actually creating")
+	cmd.Flags().StringVar(&manifest, "manifest", "", "Create from a YAML manifest file or URL, cloning missing repositories")
+	cmd.Flags().StringVar(&cloneDir, "clone-dir", "", "Directory to clone missing manifest repositories and repositories not cloned yet into (default: manifest clone-dir or ~/code)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "", "With --dry-run, print the plan in the given format (json)")
+	cmd.Flags().StringVar(&emitScript, "emit-script", "", "With --dry-run, write the plan as an executable shell script to this path")
+	cmd.Flags().BoolVar(&partial, "partial", false, "Keep successfully created repositories when some worktrees fail (failed ones are marked pending)")
+	cmd.Flags().BoolVar(&journal, "journal", false, "Record progress under .wsm/ and keep created worktrees on failure so creation can be resumed")
+	cmd.Flags().BoolVar(&goReplace, "go-replace", false, "Add go.mod replace directives pointing Go consumers at sibling worktrees (see 'wsm go-replace')")
+	cmd.Flags().StringVar(&onExisting, "on-existing-branch", "", "When the branch already exists in a repository: overwrite, use or fail (default: ask)")
+	cmd.Flags().StringVar(&onCheckedOut, "on-checked-out-branch", "", "When the branch is checked out in another worktree: reuse that worktree, link (new branch <branch>@<workspace>) or fail (default: ask)")
+	cmd.Flags().BoolVar(&sanitize, "sanitize", false, "Replace characters not allowed in workspace and branch names instead of failing")
+	cmd.Flags().BoolVar(&noScripts, "no-scripts", false, "Do not run the setup scripts of the workspace and its repositories")
+	cmd.Flags().BoolVar(&noHooks, "no-hooks", false, "Do not deploy the shared git hooks to the worktrees")
+	cmd.Flags().BoolVar(&nix, "nix", false, "Generate a flake.nix dev shell for the toolchains of the repositories; --nix=false skips it (default: enabled in nix.yaml)")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Finish an interrupted journaled creation, skipping worktrees that already exist")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"repos":                 RepositoryOrGroupCompletion().UniqueList(","),
+			"agent-source":          carapace.ActionFiles(".md"),
+			"scaffold":              ScaffoldCompletion(),
+			"manifest":              carapace.ActionFiles(".yaml", ".yml"),
+			"clone-dir":             carapace.ActionDirectories(),
+			"output":                carapace.ActionValues("json"),
+			"emit-script":           carapace.ActionFiles(".sh"),
+			"submodules":            carapace.ActionValues(wsm.SubmoduleModes...),
+			"merge-strategy":        carapace.ActionValues(wsm.MergeStrategies...),
+			"on-existing-branch":    carapace.ActionValues(wsm.ExistingBranchPolicies...),
+			"on-checked-out-branch": carapace.ActionValues(wsm.CheckedOutBranchPolicies...),
+		},
+	)
+
+	carapace.Gen(cmd).PositionalCompletion(
+		carapace.ActionCallback(func(c carapace.Context) carapace.Action {
+			if resume {
+				return InterruptedWorkspaceCompletion()
+			}
+			return carapace.ActionValues()
+		}),
+	)
 
 	return cmd
 }
 
-func runCreate(ctx context.Context, name string, repos []string, branch, branchPrefix, baseBranch, agentSource string, interactive, dryRun bool) error {
-	wm, err := wsm.NewWorkspaceManager()
-	if err != nil {
-		return errors.Wrap(err, "failed to create workspace manager")
-	}
-
+func runCreate(ctx context.Context, wm *wsm.WorkspaceManager, branchPrefix string, interactive bool, opts wsm.CreateOptions, plan planOptions) error {
 	// Handle interactive mode
 	if interactive {
 		selectedRepos, err := selectRepositoriesInteractively(wm)
 		if err != nil {
 			// Check if user cancelled - handle gracefully without error
-			errMsg := strings.ToLower(err.Error())
-			if strings.Contains(errMsg, "cancelled by user") ||
-				strings.Contains(errMsg, "creation cancelled") ||
-				strings.Contains(errMsg, "operation cancelled") {
+			if wsm.IsCancellationError(err) {
 				output.PrintInfo("Operation cancelled.")
 				return nil // Return success to prevent usage help
 			}
 			return errors.Wrap(err, "interactive selection failed")
 		}
-		repos = selectedRepos
+		opts.Repositories = selectedRepos
 	}
 
 	// Validate inputs
-	if len(repos) == 0 {
+	if len(opts.Repositories) == 0 {
 		return errors.New("no repositories specified. Use --repos flag or --interactive mode")
 	}
 
+	if !opts.DryRun || !plan.requested() {
+		suggestMissingDependencies(wm, opts.Repositories)
+	}
+
 	// Generate branch name if not specified
-	finalBranch := branch
-	if finalBranch == "" {
-		finalBranch = fmt.Sprintf("%s/%s", branchPrefix, name)
-		output.PrintInfo("Using auto-generated branch: %s", finalBranch)
-		log.Debug().Str("branch", finalBranch).Str("prefix", branchPrefix).Str("name", name).Msg("Generated branch name")
+	if opts.Branch == "" {
+		var err error
+		opts.Branch, err = wsm.GenerateBranchName(branchPrefix, opts.Name, ticketID(opts.Ticket))
+		if err != nil {
+			return err
+		}
+		output.PrintInfoStderr("Using auto-generated branch: %s", opts.Branch)
+		log.Debug().Str("branch", opts.Branch).Str("prefix", branchPrefix).Str("name", opts.Name).Msg("Generated branch name")
 	}
 
 	// Create workspace
-	log.Debug().Str("name", name).Strs("repos", repos).Str("branch", finalBranch).Str("baseBranch", baseBranch).Bool("dryRun", dryRun).Msg("Creating workspace")
-	workspace, err := wm.CreateWorkspace(ctx, name, repos, finalBranch, baseBranch, agentSource, dryRun)
+	log.Debug().Str("name", opts.Name).Strs("repos", opts.Repositories).Str("branch", opts.Branch).Str("baseBranch", opts.BaseBranch).Bool("dryRun", opts.DryRun).Bool("partial", opts.Partial).Bool("journal", opts.Journaled).Msg("Creating workspace")
+	workspace, err := wm.CreateWorkspace(ctx, opts)
 	if err != nil {
 		// Check if user cancelled - handle gracefully without error
-		errMsg := strings.ToLower(err.Error())
-		if strings.Contains(errMsg, "cancelled by user") ||
-			strings.Contains(errMsg, "creation cancelled") ||
-			strings.Contains(errMsg, "operation cancelled") {
+		if wsm.IsCancellationError(err) {
 			output.PrintInfo("Operation cancelled.")
 			return nil // Return success to prevent usage help
 		}
@@ -114,10 +326,149 @@ func runCreate(ctx context.Context, name string, repos []string, branch, branchP
 	}
 
 	// Show results
-	if dryRun {
+	if opts.DryRun {
+		if plan.requested() {
+			return emitCreatePlan(ctx, wm, workspace, nil, opts.NoScripts, plan)
+		}
 		return showWorkspacePreview(workspace)
 	}
 
+	printCreatedWorkspace(workspace)
+	return nil
+}
+
+// runCreateFromManifest creates a workspace from a manifest file or URL; the
+// sparse directories, pins and submodule mode of opts override the manifest's
+func runCreateFromManifest(ctx context.Context, wm *wsm.WorkspaceManager, manifestSource, cloneDir, branchPrefix string, opts wsm.CreateOptions, plan planOptions) error {
+	manifest, err := wsm.LoadManifest(ctx, manifestSource)
+	if err != nil {
+		return err
+	}
+
+	// --sparse replaces the manifest's sparse directories of a repository
+	for repoName, dirs := range opts.SparsePaths {
+		found := false
+		for i := range manifest.Repositories {
+			if manifest.Repositories[i].Name == repoName {
+				manifest.Repositories[i].Sparse = dirs
+				found = true
+			}
+		}
+		if !found {
+			return errors.Errorf("sparse checkout configured for '%s', which is not in the manifest", repoName)
+		}
+	}
+
+	// --pin replaces the manifest's pin of a repository
+	for repoName, ref := range opts.Pins {
+		found := false
+		for i := range manifest.Repositories {
+			if manifest.Repositories[i].Name == repoName {
+				manifest.Repositories[i].Pin = ref
+				found = true
+			}
+		}
+		if !found {
+			return errors.Errorf("pin configured for '%s', which is not in the manifest", repoName)
+		}
+	}
+	if opts.Submodules != "" {
+		manifest.Submodules = opts.Submodules
+	}
+
+	// The command line branch wins over the manifest's, which wins over the generated one
+	if opts.Branch == "" && manifest.Branch == "" {
+		if opts.Branch, err = wsm.GenerateBranchName(branchPrefix, opts.Name, ticketID(opts.Ticket)); err != nil {
+			return err
+		}
+		output.PrintInfoStderr("Using auto-generated branch: %s", opts.Branch)
+	}
+
+	workspace, resolution, err := wm.CreateWorkspaceFromManifest(ctx, manifest, cloneDir, opts)
+	if err != nil {
+		if wsm.IsCancellationError(err) {
+			output.PrintInfo("Operation cancelled.")
+			return nil
+		}
+		return errors.Wrap(err, "failed to create workspace from manifest")
+	}
+
+	if opts.DryRun {
+		if plan.requested() {
+			return emitCreatePlan(ctx, wm, workspace, wsm.PlanManifestClones(manifest, resolution), opts.NoScripts, plan)
+		}
+		if len(resolution.Cloned) > 0 {
+			output.PrintInfo("Repositories to clone and register: %s", strings.Join(resolution.Cloned, ", "))
+		}
+		return showWorkspacePreview(workspace)
+	}
+
+	if len(resolution.Cloned) > 0 {
+		output.PrintSuccess("Cloned and registered: %s", strings.Join(resolution.Cloned, ", "))
+	}
+	printCreatedWorkspace(workspace)
+	return nil
+}
+
+// runResumeCreate finishes an interrupted journaled workspace creation
+func runResumeCreate(ctx context.Context, name, onExistingBranch, onCheckedOutBranch string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+	wm.OnExistingBranch = onExistingBranch
+	wm.OnCheckedOutBranch = onCheckedOutBranch
+
+	workspace, err := wm.ResumeWorkspaceCreation(ctx, name)
+	if err != nil {
+		if wsm.IsCancellationError(err) {
+			output.PrintInfo("Operation cancelled.")
+			return nil
+		}
+		return errors.Wrap(err, "failed to resume workspace creation")
+	}
+
+	printCreatedWorkspace(workspace)
+	return nil
+}
+
+// planOptions selects machine-readable dry-run output
+type planOptions struct {
+	format string // "json" prints the plan as JSON
+	script string // path to write an executable plan script to
+}
+
+func (p planOptions) requested() bool {
+	return p.format != "" || p.script != ""
+}
+
+// emitCreatePlan prints and/or writes the exact operations of a workspace creation
+func emitCreatePlan(ctx context.Context, wm *wsm.WorkspaceManager, workspace *wsm.Workspace, preSteps []wsm.PlanStep, noScripts bool, opts planOptions) error {
+	plan, err := wm.PlanCreateWorkspace(ctx, workspace, noScripts)
+	if err != nil {
+		return errors.Wrap(err, "failed to build plan")
+	}
+	plan.Steps = append(preSteps, plan.Steps...)
+
+	if opts.script != "" {
+		if err := plan.WriteScript(opts.script); err != nil {
+			return err
+		}
+		if opts.format == "" {
+			output.PrintSuccess("Wrote plan with %d steps to %s", len(plan.Steps), opts.script)
+			output.PrintInfo("Review it, then run: bash %s", opts.script)
+		}
+	}
+
+	if opts.format == "json" {
+		return wsm.PrintJSON(plan)
+	}
+
+	return nil
+}
+
+// printCreatedWorkspace prints the details of a newly created workspace
+func printCreatedWorkspace(workspace *wsm.Workspace) {
 	output.PrintSuccess("Workspace '%s' created successfully!", workspace.Name)
 	fmt.Println()
 
@@ -130,15 +481,37 @@ func runCreate(ctx context.Context, name string, repos []string, branch, branchP
 	if workspace.GoWorkspace {
 		fmt.Printf("  Go workspace: yes (go.work created)\n")
 	}
+	if workspace.GoReplaces {
+		fmt.Printf("  Go replace directives: yes (removed before commits)\n")
+	}
+	if len(workspace.Ecosystems) > 0 {
+		fmt.Printf("  Workspace manifests: %s\n", strings.Join(workspace.Ecosystems, ", "))
+	}
 	if workspace.AgentMD != "" {
 		fmt.Printf("  AGENT.md: copied from %s\n", workspace.AgentMD)
 	}
+	if workspace.Scaffold != "" {
+		fmt.Printf("  Scaffold: copied from %s\n", workspace.Scaffold)
+	}
+	if workspace.NixFlake {
+		fmt.Printf("  Nix: flake.nix dev shell generated\n")
+	}
+	if workspace.MergeStrategy != "" {
+		fmt.Printf("  Merge strategy: %s\n", workspace.MergeStrategy)
+	}
+
+	if len(workspace.PendingRepositories) > 0 {
+		fmt.Println()
+		output.PrintWarning("%d repositories could not be added and are pending:", len(workspace.PendingRepositories))
+		for _, pending := range workspace.PendingRepositories {
+			fmt.Printf("  • %s: %s\n", pending.Repository.Name, pending.Error)
+		}
+		output.PrintInfo("Retry them later with: wsm add %s --retry-pending", workspace.Name)
+	}
 
 	fmt.Println()
 	output.PrintInfo("To start working:")
 	fmt.Printf("  cd %s\n", workspace.Path)
-
-	return nil
 }
 
 func selectRepositoriesInteractively(wm *wsm.WorkspaceManager) ([]string, error) {
@@ -151,31 +524,16 @@ func selectRepositoriesInteractively(wm *wsm.WorkspaceManager) ([]string, error)
 	output.PrintHeader("Select Repositories")
 
 	// Create options for multi-select
-	var options []huh.Option[string]
+	var options []ux.Option
 	for _, repo := range repos {
 		label := fmt.Sprintf("%s (%s)", repo.Name, strings.Join(repo.Categories, ", "))
-		options = append(options, huh.NewOption(label, repo.Name))
+		options = append(options, ux.NewOption(label, repo.Name))
 	}
 
-	var selected []string
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewMultiSelect[string]().
-				Title("Choose repositories to include:").
-				Options(options...).
-				Value(&selected),
-		),
-	)
-
 	log.Debug().Int("repoCount", len(repos)).Msg("Showing interactive repository selection")
-	err := form.Run()
+	selected, err := ux.MultiSelect("Choose repositories to include:", options)
 	if err != nil {
-		// Check if user cancelled/aborted the form
-		errMsg := strings.ToLower(err.Error())
-		if strings.Contains(errMsg, "user aborted") ||
-			strings.Contains(errMsg, "cancelled") ||
-			strings.Contains(errMsg, "aborted") ||
-			strings.Contains(errMsg, "interrupt") {
+		if errors.Is(err, ux.ErrAborted) {
 			return nil, errors.New("workspace creation cancelled by user")
 		}
 		return nil, errors.Wrap(err, "interactive form failed")
@@ -198,11 +556,26 @@ func showWorkspacePreview(workspace *wsm.Workspace) error {
 
 	fmt.Printf("  2. Create worktrees:\n")
 	for _, repo := range workspace.Repositories {
-		if workspace.Branch != "" {
-			fmt.Printf("     git worktree add -B %s %s/%s\n", workspace.Branch, workspace.Path, repo.Name)
+		if pin := workspace.PinFor(repo.Name); pin != "" {
+			fmt.Printf("     git worktree add --detach %s/%s %s\n", workspace.Path, repo.Name, pin)
+		} else if workspace.Branch != "" {
+			fmt.Printf("     git worktree add -B %s %s/%s", workspace.Branch, workspace.Path, repo.Name)
+			if baseBranch := workspace.BaseBranchFor(repo.Name); baseBranch != "" {
+				fmt.Printf(" %s", baseBranch)
+			}
+			fmt.Println()
 		} else {
 			fmt.Printf("     git worktree add %s/%s\n", workspace.Path, repo.Name)
 		}
+		if sparsePaths := workspace.SparsePathsFor(repo.Name); len(sparsePaths) > 0 {
+			fmt.Printf("       sparse checkout: %s\n", strings.Join(sparsePaths, ", "))
+		}
+		if len(repo.Submodules) > 0 && workspace.SubmoduleMode() != wsm.SubmodulesNone {
+			fmt.Printf("       submodules (%s): %d\n", workspace.SubmoduleMode(), len(repo.Submodules))
+		}
+		if repo.LFS {
+			fmt.Printf("       git lfs install --local && git lfs pull\n")
+		}
 	}
 
 	stepNum := 3
@@ -210,11 +583,23 @@ func showWorkspacePreview(workspace *wsm.Workspace) error {
 		fmt.Printf("  %d. Initialize go.work and add modules\n", stepNum)
 		stepNum++
 	}
+	if len(workspace.Ecosystems) > 0 {
+		fmt.Printf("  %d. Create workspace manifests for %s\n", stepNum, strings.Join(workspace.Ecosystems, ", "))
+		stepNum++
+	}
 
 	if workspace.AgentMD != "" {
 		fmt.Printf("  %d. Copy AGENT.md from %s\n", stepNum, workspace.AgentMD)
 		stepNum++
 	}
+	if workspace.Scaffold != "" {
+		fmt.Printf("  %d. Copy scaffold from %s, rendering .tmpl files\n", stepNum, workspace.Scaffold)
+		stepNum++
+	}
+	if workspace.NixFlake {
+		fmt.Printf("  %d. Generate flake.nix (see nix.yaml)\n", stepNum)
+		stepNum++
+	}
 
 	// Show setup scripts preview
 	wm, err := wsm.NewWorkspaceManager()
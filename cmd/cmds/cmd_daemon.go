@@ -0,0 +1,84 @@
+package cmds
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewDaemonCommand creates the daemon command
+func NewDaemonCommand() *cobra.Command {
+	var (
+		interval time.Duration
+		once     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "daemon [workspace-name...]",
+		Short: "Periodically fetch workspace repositories in the background",
+		Long: `Run a fetch loop that periodically runs 'git fetch --all --prune' for every
+repository in all workspaces (or only the named ones).
+
+Last-fetch timestamps are stored in the workspace-manager config directory, so
+'wsm status' can show freshness and ahead/behind counts without fetching itself.
+Each repository is fetched once per cycle, even when used by several workspaces.
+
+The daemon runs in the foreground until interrupted; run it under your service
+manager, tmux, or nohup to keep it in the background.
+
+Examples:
+  # Fetch all workspaces every 5 minutes
+  wsm daemon
+
+  # Fetch two workspaces every minute
+  wsm daemon my-feature other-feature --interval 1m
+
+  # Fetch once and exit (e.g. from cron)
+  wsm daemon --once`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDaemon(cmd.Context(), args, interval, once)
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "Time between fetch cycles")
+	cmd.Flags().BoolVar(&once, "once", false, "Run a single fetch cycle and exit")
+
+	carapace.Gen(cmd).PositionalAnyCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func runDaemon(ctx context.Context, workspaces []string, interval time.Duration, once bool) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !once {
+		output.PrintInfo("Fetching workspace repositories every %s (Ctrl+C to stop)", interval)
+		// A long-running daemon would print a progress line per repository every cycle
+		output.SetProgressFactory(output.NoProgress)
+	}
+
+	err := wsm.RunFetchDaemon(ctx, wsm.FetchDaemonOptions{
+		Interval:   interval,
+		Workspaces: workspaces,
+		Once:       once,
+	})
+	if err != nil {
+		return errors.Wrap(err, "fetch daemon failed")
+	}
+
+	if once {
+		output.PrintSuccess("Fetch completed")
+	} else {
+		output.PrintInfo("Fetch daemon stopped")
+	}
+	return nil
+}
@@ -3,11 +3,11 @@ package cmds
 import (
 	"context"
 	"fmt"
-	"strings"
+	"path/filepath"
 
 	"github.com/carapace-sh/carapace"
-	"github.com/charmbracelet/huh"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -19,6 +19,8 @@ func NewDeleteCommand() *cobra.Command {
 		force          bool
 		forceWorktrees bool
 		removeFiles    bool
+		trash          bool
+		keepWorktrees  bool
 		outputFormat   string
 	)
 
@@ -30,6 +32,20 @@ func NewDeleteCommand() *cobra.Command {
 This command removes the workspace configuration and optionally deletes
 the workspace directory and all its contents. Use with caution.
 
+Before deleting, a preflight report lists unpushed commits, stashes and
+untracked files of every repository. Deletion always needs confirmation
+unless --force is given.
+
+With --trash the workspace directory is moved to ~/workspaces/.trash
+instead, and can be restored with 'wsm undelete' for 7 days. Trashed
+worktrees stay locked in their repositories, so their branches cannot be
+checked out elsewhere until the workspace is restored or purged.
+
+With --keep-worktrees only wsm forgets the workspace: its configuration and
+.wsm directory are removed, but the worktrees stay in place as git worktrees
+of their repositories, to keep working on without wsm. Move them later with
+'git worktree move' or remove them with 'git worktree remove'.
+
 Examples:
   # Delete workspace configuration only
   workspace-manager delete my-workspace
@@ -41,16 +57,31 @@ Examples:
   workspace-manager delete my-workspace --force --remove-files
 
   # Force worktree removal even with uncommitted changes
-  workspace-manager delete my-workspace --force-worktrees --remove-files`,
+  workspace-manager delete my-workspace --force-worktrees --remove-files
+
+  # Move the workspace to the trash, restore it later
+  workspace-manager delete my-workspace --trash
+  workspace-manager undelete my-workspace
+
+  # Stop managing a workspace but keep its checkouts
+  workspace-manager delete my-workspace --keep-worktrees`,
 		Args: cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDelete(cmd.Context(), args[0], force, forceWorktrees, removeFiles, outputFormat)
-		},
+		RunE: audited("delete", 0, func(cmd *cobra.Command, args []string) error {
+			if trash && (removeFiles || forceWorktrees) {
+				return errors.New("--trash cannot be combined with --remove-files or --force-worktrees")
+			}
+			if keepWorktrees && (removeFiles || forceWorktrees || trash) {
+				return errors.New("--keep-worktrees cannot be combined with --remove-files, --force-worktrees or --trash")
+			}
+			return runDelete(cmd.Context(), args[0], force, forceWorktrees, removeFiles, trash, keepWorktrees, outputFormat)
+		}),
 	}
 
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force delete without confirmation")
 	cmd.Flags().BoolVar(&forceWorktrees, "force-worktrees", false, "Force worktree removal even with uncommitted changes")
 	cmd.Flags().BoolVar(&removeFiles, "remove-files", false, "Remove workspace files and directories")
+	cmd.Flags().BoolVar(&trash, "trash", false, "Move the workspace to the trash so it can be restored with 'wsm undelete'")
+	cmd.Flags().BoolVar(&keepWorktrees, "keep-worktrees", false, "Only remove the workspace configuration and .wsm, keeping the worktrees as plain git worktrees")
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
 
 	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
@@ -58,7 +89,7 @@ Examples:
 	return cmd
 }
 
-func runDelete(ctx context.Context, workspaceName string, force bool, forceWorktrees bool, removeFiles bool, outputFormat string) error {
+func runDelete(ctx context.Context, workspaceName string, force bool, forceWorktrees bool, removeFiles bool, trash bool, keepWorktrees bool, outputFormat string) error {
 	manager, err := wsm.NewWorkspaceManager()
 	if err != nil {
 		return errors.Wrap(err, "failed to create workspace manager")
@@ -88,22 +119,37 @@ func runDelete(ctx context.Context, workspaceName string, force bool, forceWorkt
 		return wsm.PrintJSON(workspace)
 	}
 
+	preflight := manager.CheckDeletePreflight(ctx, workspace)
+	printDeletePreflight(preflight)
+
 	output.PrintHeader("Workspace: %s", workspace.Name)
 	fmt.Printf("  Path: %s\n", workspace.Path)
 	fmt.Printf("  Repositories: %d\n", len(workspace.Repositories))
 
 	output.PrintWarning("This will:")
-	if forceWorktrees {
+	switch {
+	case trash:
+		fmt.Printf("  1. Move %s to the trash\n", workspace.Path)
+		fmt.Printf("  2. Remove workspace configuration\n")
+		fmt.Printf("  3. Keep worktrees and branches, restorable with 'wsm undelete %s' for %d days\n",
+			workspace.Name, int(wsm.TrashGracePeriod.Hours()/24))
+	case keepWorktrees:
+		fmt.Printf("  1. Remove workspace configuration and %s\n", filepath.Join(workspace.Path, ".wsm"))
+		fmt.Printf("  2. Keep the worktrees at %s as plain git worktrees, with their branches and work\n", workspace.Path)
+	case forceWorktrees:
 		fmt.Printf("  1. Remove git worktrees (git worktree remove --force)\n")
-	} else {
+	default:
 		fmt.Printf("  1. Remove git worktrees (git worktree remove)\n")
 		output.PrintWarning("     Will fail if there are uncommitted changes")
 	}
 
-	if removeFiles {
+	switch {
+	case trash, keepWorktrees:
+		// Worktrees and files are kept
+	case removeFiles:
 		output.PrintError("  2. DELETE the workspace directory and ALL its contents!")
 		fmt.Printf("     📁 This includes: go.work, AGENT.md, and all repository worktrees\n")
-	} else {
+	default:
 		fmt.Printf("  2. Remove workspace configuration\n")
 		fmt.Printf("  3. Clean up workspace-specific files (go.work, AGENT.md)\n")
 		fmt.Printf("  4. Repository worktrees will remain at: %s\n", workspace.Path)
@@ -111,24 +157,21 @@ func runDelete(ctx context.Context, workspaceName string, force bool, forceWorkt
 
 	// Confirm deletion unless forced
 	if !force {
-		var confirmed bool
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewConfirm().
-					Title(fmt.Sprintf("Are you sure you want to delete workspace '%s'?", workspaceName)).
-					Description("This action cannot be undone.").
-					Value(&confirmed),
-			),
-		)
-
-		err := form.Run()
+		if err := wsm.RequireInteractive(fmt.Sprintf("Deleting workspace '%s'", workspaceName), "--force"); err != nil {
+			return err
+		}
+		description := "This action cannot be undone."
+		if trash {
+			description = "The workspace can be restored with 'wsm undelete'."
+		} else if keepWorktrees {
+			description = "The worktrees and their work are kept."
+		} else if preflight.AtRisk() {
+			description = "Work listed in the preflight report above will be lost. This action cannot be undone."
+		}
+
+		confirmed, err := ux.Confirm(fmt.Sprintf("Are you sure you want to delete workspace '%s'?", workspaceName), description)
 		if err != nil {
-			// Check if user cancelled/aborted the form
-			errMsg := strings.ToLower(err.Error())
-			if strings.Contains(errMsg, "user aborted") ||
-				strings.Contains(errMsg, "cancelled") ||
-				strings.Contains(errMsg, "aborted") ||
-				strings.Contains(errMsg, "interrupt") {
+			if errors.Is(err, ux.ErrAborted) {
 				output.PrintInfo("Operation cancelled.")
 				return nil
 			}
@@ -141,6 +184,40 @@ func runDelete(ctx context.Context, workspaceName string, force bool, forceWorkt
 		}
 	}
 
+	if trash {
+		entry, err := manager.TrashWorkspace(ctx, workspaceName)
+		if err != nil {
+			return errors.Wrap(err, "failed to move workspace to the trash")
+		}
+		output.PrintSuccess("Workspace '%s' moved to the trash: %s", workspaceName, entry.Dir())
+		output.PrintInfo("Restore it with: wsm undelete %s (until %s)", workspaceName, entry.ExpiresAt.Format("2006-01-02 15:04"))
+
+		if purged, err := manager.PurgeTrash(ctx, false); err != nil {
+			output.PrintWarning("Failed to purge expired trash: %v", err)
+		} else if len(purged) > 0 {
+			output.PrintInfo("Purged %d expired workspace(s) from the trash", len(purged))
+		}
+		return nil
+	}
+
+	if keepWorktrees {
+		result, err := manager.UnregisterWorkspace(ctx, workspaceName)
+		if err != nil {
+			return errors.Wrap(err, "failed to unregister workspace")
+		}
+		output.PrintSuccess("Workspace '%s' is no longer managed by wsm", workspaceName)
+		for _, checkout := range result.Checkouts {
+			fmt.Printf("  %s\n", checkout)
+		}
+		for _, link := range result.Links {
+			fmt.Printf("  %s (link to a worktree outside the workspace)\n", link)
+		}
+		for _, message := range result.Errors {
+			output.PrintWarning("%s", message)
+		}
+		return nil
+	}
+
 	// Perform deletion
 	if err := manager.DeleteWorkspace(ctx, workspaceName, removeFiles, forceWorktrees); err != nil {
 		return errors.Wrap(err, "failed to delete workspace")
@@ -155,3 +232,54 @@ func runDelete(ctx context.Context, workspaceName string, force bool, forceWorkt
 
 	return nil
 }
+
+// printDeletePreflight lists work that deleting the workspace could lose
+func printDeletePreflight(preflight *wsm.DeletePreflight) {
+	output.PrintHeader("Preflight check")
+
+	if !preflight.AtRisk() {
+		output.PrintSuccess("No unpushed commits, stashes or untracked files")
+		fmt.Println()
+		return
+	}
+
+	for _, repo := range preflight.Repositories {
+		if repo.Missing {
+			fmt.Printf("  %s: worktree missing\n", repo.Repository)
+			continue
+		}
+		if !repo.AtRisk() {
+			continue
+		}
+
+		fmt.Printf("  %s (%s):\n", repo.Repository, repo.Branch)
+		if repo.UnpushedCount > 0 {
+			output.PrintWarning("    %d unpushed commit(s)", repo.UnpushedCount)
+			for _, commit := range repo.UnpushedCommits {
+				fmt.Printf("      %s\n", commit)
+			}
+			if repo.UnpushedCount > len(repo.UnpushedCommits) {
+				fmt.Printf("      ... and %d more\n", repo.UnpushedCount-len(repo.UnpushedCommits))
+			}
+		}
+		if len(repo.Stashes) > 0 {
+			output.PrintWarning("    %d stash(es)", len(repo.Stashes))
+			for _, stash := range repo.Stashes {
+				fmt.Printf("      %s\n", stash)
+			}
+		}
+		if repo.ModifiedFiles > 0 {
+			output.PrintWarning("    %d file(s) with uncommitted changes", repo.ModifiedFiles)
+		}
+		if len(repo.UntrackedFiles) > 0 {
+			output.PrintWarning("    %d untracked file(s)", len(repo.UntrackedFiles))
+			for _, file := range repo.UntrackedFiles {
+				fmt.Printf("      %s\n", file)
+			}
+		}
+		if repo.Error != "" {
+			output.PrintError("    %s", repo.Error)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,144 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewDevcontainerCommand creates the devcontainer command
+func NewDevcontainerCommand() *cobra.Command {
+	var (
+		compose      bool
+		image        string
+		force        bool
+		dryRun       bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "devcontainer [workspace-name]",
+		Short: "Generate a dev container configuration for a workspace",
+		Long: `Generate .devcontainer/devcontainer.json at the workspace root, so the
+workspace opens in a containerized development environment with the Dev
+Containers extension or 'devcontainer up'.
+
+The workspace is mounted at the same path in the container, together with the
+.git directories of the source clones, which the worktrees refer to by
+absolute path, so git works inside the container.
+
+The image is selected from the Go version of the repositories (go.mod). Node
+(.nvmrc, .node-version, engines of package.json), Python (.python-version)
+and Rust (rust-toolchain) are added as dev container features, with the
+highest version any repository asks for.
+
+With --compose, a docker-compose.yml is generated as well and the
+devcontainer.json uses it.
+
+Examples:
+  wsm devcontainer
+  devcontainer up --workspace-folder ~/workspaces/2025-06-01/my-feature
+
+  # Preview the files
+  wsm devcontainer my-feature --dry-run
+
+  # Use docker compose and a custom image
+  wsm devcontainer --compose --image ghcr.io/acme/dev:latest`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: audited("devcontainer", 0, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			options := wsm.DevcontainerOptions{Compose: compose, Image: image}
+			return runDevcontainer(cmd.Context(), workspaceName, options, force, dryRun, outputFormat)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&compose, "compose", false, "Also generate a docker-compose.yml used by the devcontainer.json")
+	cmd.Flags().StringVar(&image, "image", "", "Use this image instead of the one selected from the toolchains")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace existing files")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the files without writing them")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runDevcontainer(ctx context.Context, workspaceName string, options wsm.DevcontainerOptions, force, dryRun bool, outputFormat string) error {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get current directory")
+		}
+		detected, err := detectWorkspace(cwd)
+		if err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm devcontainer <workspace-name>'")
+		}
+		workspaceName = detected
+	}
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	devcontainer, err := wsm.GenerateDevcontainer(ctx, workspace, options)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err := wsm.WriteDevcontainer(devcontainer, force); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(devcontainer)
+	}
+
+	if dryRun {
+		for _, file := range devcontainer.Files {
+			output.PrintHeader("%s", file.Path)
+			fmt.Print(file.Content)
+			fmt.Println()
+		}
+		return nil
+	}
+
+	var toolchains []string
+	for _, toolchain := range []struct{ name, version string }{
+		{"go", devcontainer.Toolchains.Go},
+		{"node", devcontainer.Toolchains.Node},
+		{"python", devcontainer.Toolchains.Python},
+		{"rust", devcontainer.Toolchains.Rust},
+	} {
+		if toolchain.version != "" {
+			toolchains = append(toolchains, toolchain.name+" "+toolchain.version)
+		}
+	}
+	for _, file := range devcontainer.Files {
+		output.PrintSuccess("Wrote %s", file.Path)
+	}
+	output.PrintInfo("Image: %s", devcontainer.Image)
+	if len(toolchains) > 0 {
+		output.PrintInfo("Toolchains: %s", strings.Join(toolchains, ", "))
+	}
+	output.PrintInfo("Start it with: devcontainer up --workspace-folder %s", workspace.Path)
+	return nil
+}
@@ -3,6 +3,10 @@ package cmds
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 
@@ -12,113 +16,312 @@ import (
 
 func NewDiffCommand() *cobra.Command {
 	var (
-		staged bool
-		repo   string
+		staged        bool
+		sinceCreation bool
+		base          bool
+		stat          bool
+		color         string
+		noPager       bool
+		fold          int
+		export        string
+		filters       repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "diff",
 		Short: "Show diff across workspace repositories",
 		Long: `Show unified diff of changes across all repositories in the workspace.
-This provides a consolidated view of all modifications in your multi-repository development.`,
+This provides a consolidated view of all modifications in your multi-repository development.
+
+Files matched by a .wsmignore file in the workspace root or in a repository
+(same syntax as .gitignore) are left out of the diff.
+
+With --since-creation the diff covers everything done in the workspace: the
+commits and the uncommitted changes since each worktree was created. --base
+does the same from the merge base with each repository's default branch, the
+one detected when the worktree was created, like a pull request would show it.
+
+On a terminal the diff is colored by git and paged through $PAGER (less by
+default, which exits when the diff fits on one screen). When git is configured
+to page diffs through delta or diff-so-fancy (pager.diff, core.pager or
+interactive.diffFilter), the diff of each repository is highlighted by it.
+
+--fold N shows repositories whose diff is longer than N lines as a one-line
+summary; --stat shows a diffstat per repository instead of the patch.
+
+--repo, --exclude-repo and --tag limit the diff to some repositories.
+
+--export DIR writes the diff of each repository to DIR/<repository>.patch
+instead of showing it, untracked files included, so the changes can be moved
+to another workspace with 'wsm apply DIR' without pushing a branch.
+
+Examples:
+  # What changed, file by file
+  wsm diff --stat
+
+  # Fold the diffs of repositories with more than 200 changed lines
+  wsm diff --fold 200
+
+  # Plain output for a file
+  wsm diff --color never > changes.patch
+
+  # Move the uncommitted changes to another workspace
+  wsm diff --export /tmp/changes
+  wsm apply /tmp/changes other-workspace`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDiff(cmd.Context(), staged, repo)
+			if color != "auto" && color != "always" && color != "never" {
+				return errors.Errorf("unsupported color mode '%s' (supported: auto, always, never)", color)
+			}
+			if fold < 0 {
+				return errors.New("--fold must not be negative")
+			}
+			if export != "" {
+				options := wsm.DiffOptions{Staged: staged, SinceCreation: sinceCreation, Base: base}
+				return runDiffExport(cmd.Context(), options, export, &filters)
+			}
+			options := wsm.DiffOptions{
+				Staged:        staged,
+				SinceCreation: sinceCreation,
+				Base:          base,
+				Stat:          stat,
+				Color:         color == "always" || (color == "auto" && output.StdoutIsTerminal() && os.Getenv("NO_COLOR") == ""),
+			}
+			return runDiff(cmd.Context(), options, !noPager, fold, &filters)
 		},
 	}
 
 	cmd.Flags().BoolVar(&staged, "staged", false, "Show staged changes only")
-	cmd.Flags().StringVar(&repo, "repo", "", "Show diff for specific repository only")
+	cmd.Flags().BoolVar(&sinceCreation, "since-creation", false, "Diff against the commit each worktree was created from")
+	cmd.Flags().BoolVar(&base, "base", false, "Diff against the merge base with each repository's default branch")
+	cmd.MarkFlagsMutuallyExclusive("since-creation", "base")
+	cmd.Flags().BoolVar(&stat, "stat", false, "Show a diffstat per repository instead of the patch")
+	cmd.Flags().StringVar(&color, "color", "auto", "Color the diff: auto, always or never")
+	cmd.Flags().BoolVar(&noPager, "no-pager", false, "Do not page the output")
+	cmd.Flags().IntVar(&fold, "fold", 0, "Fold repositories whose diff is longer than this many lines to a summary (0: never)")
+	cmd.Flags().StringVar(&export, "export", "", "Write one .patch file per repository to this directory instead of showing the diff")
+	filters.register(cmd, nil)
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"color":  carapace.ActionValues("auto", "always", "never"),
+			"export": carapace.ActionDirectories(),
+		},
+	)
 
 	return cmd
 }
 
-func runDiff(ctx context.Context, staged bool, repoFilter string) error {
+func runDiff(ctx context.Context, options wsm.DiffOptions, page bool, fold int, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	gitOps := wsm.NewGitOperations(workspace)
 
 	output.PrintHeader("📄 Showing diff for workspace: %s", workspace.Name)
-	if staged {
+	if options.Staged {
 		output.PrintInfo("   (staged changes only)")
 	}
-	if repoFilter != "" {
-		output.PrintInfo("   (repository: %s)", repoFilter)
+	if options.SinceCreation {
+		output.PrintInfo("   (since workspace creation)")
 	}
+	if options.Base {
+		output.PrintInfo("   (since the default branch)")
+	}
+	printFilterInfo(workspace)
 	fmt.Println()
 
-	diff, err := gitOps.GetDiff(ctx, staged, repoFilter)
+	diffs, err := gitOps.GetRepositoryDiffs(ctx, options)
 	if err != nil {
 		return errors.Wrap(err, "failed to get diff")
 	}
 
-	if diff == "" || diff == "No changes found in workspace." {
+	if len(diffs) == 0 {
 		output.PrintInfo("No changes found in workspace.")
 		return nil
 	}
 
-	fmt.Println(diff)
+	sections := make([]output.DiffSection, 0, len(diffs))
+	for _, diff := range diffs {
+		text := diff.Diff
+		if options.Color && !options.Stat {
+			repoPath := filepath.Join(workspace.Path, diff.Repository)
+			if highlighter := wsm.ConfiguredDiffHighlighter(ctx, repoPath); highlighter != "" {
+				highlighted, err := wsm.HighlightDiff(ctx, highlighter, text)
+				if err != nil {
+					output.PrintWarning("%v, showing the git diff", err)
+				} else {
+					text = highlighted
+				}
+			}
+		}
+		sections = append(sections, output.DiffSection{Repository: diff.Repository, Diff: text, Summary: diff.Summary})
+	}
+
+	w, closePager := output.StartPager(page)
+	renderErr := output.RenderDiff(w, sections, output.DiffRenderOptions{
+		Color:     options.Color,
+		FoldLines: fold,
+		FoldHint:  "show it with: wsm diff --repo %s",
+	})
+	if err := closePager(); err != nil && renderErr == nil {
+		return errors.Wrap(err, "pager failed")
+	}
+	return renderErr
+}
+
+func runDiffExport(ctx context.Context, options wsm.DiffOptions, dir string, filters *repoFilterFlags) error {
+	workspace, err := detectCurrentWorkspace()
+	if err != nil {
+		return errors.Wrap(err, "failed to detect current workspace")
+	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+
+	set, err := wsm.NewGitOperations(workspace).ExportPatches(ctx, dir, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to export patches")
+	}
+	if len(set.Patches) == 0 {
+		output.PrintInfo("No changes found in workspace.")
+		return nil
+	}
+	for _, patch := range set.Patches {
+		fmt.Printf("  %s\t%s\n", filepath.Join(dir, patch.File), patch.Summary)
+	}
+	output.PrintSuccess("Exported %d patches to %s; apply them with: wsm apply %s", len(set.Patches), dir, dir)
 	return nil
 }
 
 func NewLogCommand() *cobra.Command {
 	var (
-		since   string
-		oneline bool
-		limit   int
+		since        string
+		author       string
+		grep         string
+		oneline      bool
+		limit        int
+		change       string
+		outputFormat string
+		filters      repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "log",
 		Short: "Show commit history across workspace repositories",
 		Long: `Show commit history spanning multiple repositories in the workspace.
-This provides a unified view of development activity across your projects.`,
+Commits from all repositories are interleaved in chronological order (newest first),
+prefixed with their repository, author and relative date.
+
+Use --change to find every commit tagged with a given Workspace-Change-Id trailer
+(see 'wsm commit --change-id'); it cannot be combined with --grep.
+
+Examples:
+  # Last 10 commits across the workspace
+  wsm log
+
+  # Everything a teammate did this week that mentions "auth"
+  wsm log --since "1 week ago" --author alice --grep auth --limit 0
+
+  # History of the frontend repositories only
+  wsm log --tag frontend
+
+  # Machine-readable history
+  wsm log --output json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runLog(cmd.Context(), since, oneline, limit)
+			opts := wsm.LogOptions{
+				Since:    since,
+				Author:   author,
+				Grep:     grep,
+				ChangeID: change,
+				Limit:    limit,
+			}
+			return runLog(cmd.Context(), opts, oneline, outputFormat, &filters)
 		},
 	}
 
 	cmd.Flags().StringVar(&since, "since", "", "Show commits since date (e.g., '1 week ago')")
-	cmd.Flags().BoolVar(&oneline, "oneline", false, "Show one line per commit")
-	cmd.Flags().IntVar(&limit, "limit", 10, "Limit number of commits per repository")
+	cmd.Flags().StringVar(&author, "author", "", "Only show commits whose author matches the pattern (case-insensitive)")
+	cmd.Flags().StringVar(&grep, "grep", "", "Only show commits whose message matches the pattern (case-insensitive)")
+	cmd.Flags().BoolVar(&oneline, "oneline", false, "Show only hash, repository and subject")
+	cmd.Flags().IntVar(&limit, "limit", 10, "Limit number of commits in the combined history (0 for no limit)")
+	cmd.Flags().StringVar(&change, "change", "", "Only show commits with the given Workspace-Change-Id")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	filters.register(cmd, nil)
+	cmd.MarkFlagsMutuallyExclusive("grep", "change")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
 
 	return cmd
 }
 
-func runLog(ctx context.Context, since string, oneline bool, limit int) error {
+func runLog(ctx context.Context, opts wsm.LogOptions, oneline bool, outputFormat string, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 
-	output.PrintHeader("📜 Commit history for workspace: %s", workspace.Name)
-	if since != "" {
-		output.PrintInfo("   (since: %s)", since)
-	}
-	fmt.Println()
-
-	logs, err := syncOps.GetWorkspaceLog(ctx, since, oneline, limit)
+	entries, err := syncOps.GetWorkspaceCommits(ctx, opts)
 	if err != nil {
 		return errors.Wrap(err, "failed to get workspace log")
 	}
 
-	if len(logs) == 0 {
+	switch outputFormat {
+	case "json":
+		if entries == nil {
+			entries = []wsm.LogEntry{}
+		}
+		return wsm.PrintJSON(entries)
+	case "table":
+	default:
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	output.PrintHeader("📜 Commit history for workspace: %s", workspace.Name)
+	if opts.Since != "" {
+		output.PrintInfo("   (since: %s)", opts.Since)
+	}
+	if opts.Author != "" {
+		output.PrintInfo("   (author: %s)", opts.Author)
+	}
+	if opts.Grep != "" {
+		output.PrintInfo("   (grep: %s)", opts.Grep)
+	}
+	if opts.ChangeID != "" {
+		output.PrintInfo("   (change: %s)", opts.ChangeID)
+	}
+	printFilterInfo(workspace)
+	fmt.Println()
+
+	if len(entries) == 0 {
 		output.PrintInfo("No commits found in workspace.")
 		return nil
 	}
 
-	for repoName, log := range logs {
-		if log == "" {
-			continue
-		}
+	repoWidth := 0
+	for _, entry := range entries {
+		repoWidth = max(repoWidth, len(entry.Repository))
+	}
 
-		output.PrintHeader("=== Repository: %s ===", repoName)
-		fmt.Println(log)
-		fmt.Println()
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s %-*s  %s", entry.ShortHash, repoWidth, entry.Repository, entry.Subject)
+		if !oneline {
+			line += "  " + output.DimStyle.Render(fmt.Sprintf("(%s, %s)", entry.Author, formatRelativeTime(entry.Date)))
+		}
+		fmt.Println(line)
 	}
 
 	return nil
@@ -2,10 +2,12 @@ package cmds
 
 import (
 	"context"
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -15,25 +17,99 @@ func NewDiscoverCommand() *cobra.Command {
 	var (
 		recursive bool
 		maxDepth  int
+		github    wsm.GitHubOrgOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "discover [paths...]",
 		Short: "Discover git repositories in specified directories",
 		Long: `Discover git repositories in the specified directories and add them to the registry.
-If no paths are specified, defaults to current directory.`,
+If no paths are specified, defaults to current directory.
+
+With --github-org, list the repositories of a GitHub organization (through the
+gh CLI) instead and register them by remote URL without cloning them. Their
+topics and primary language become tags. 'wsm create' and 'wsm add' clone them
+on demand into --clone-dir (default ~/code). For very large repositories,
+--filter makes those clones partial (blobless: file contents are fetched when
+checked out; treeless: directories too) and --depth makes them shallow; fetches
+keep them shallow. See 'wsm repos clone-options' to change this later.
+
+Examples:
+  # Scan local directories
+  wsm discover ~/code ~/projects
+
+  # Register the Go services of an organization
+  wsm discover --github-org myorg --language go --topic service
+
+  # Register a monorepo organization with blobless, shallow clones
+  wsm discover --github-org bigcorp --filter blobless --depth 50`,
 		Args: cobra.MinimumNArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if github.Org != "" {
+				if len(args) > 0 {
+					return errors.New("--github-org cannot be combined with paths")
+				}
+				if err := github.Clone.Validate(); err != nil {
+					return err
+				}
+				return runDiscoverGitHubOrg(cmd.Context(), github)
+			}
 			return runDiscover(cmd.Context(), args, recursive, maxDepth)
 		},
 	}
 
 	cmd.Flags().BoolVarP(&recursive, "recursive", "r", true, "Recursively scan subdirectories")
 	cmd.Flags().IntVar(&maxDepth, "max-depth", 3, "Maximum depth for recursive scanning")
+	cmd.Flags().StringVar(&github.Org, "github-org", "", "Register the repositories of a GitHub organization or user instead of scanning paths")
+	cmd.Flags().StringSliceVar(&github.Topics, "topic", nil, "With --github-org, only repositories with one of these topics")
+	cmd.Flags().StringSliceVar(&github.Languages, "language", nil, "With --github-org, only repositories with one of these primary languages")
+	cmd.Flags().BoolVar(&github.IncludeArchived, "include-archived", false, "With --github-org, include archived repositories")
+	cmd.Flags().BoolVar(&github.IncludeForks, "include-forks", false, "With --github-org, include forks")
+	cmd.Flags().BoolVar(&github.SSH, "ssh", false, "With --github-org, register ssh remote URLs instead of https")
+	cmd.Flags().IntVar(&github.Limit, "limit", 1000, "With --github-org, maximum number of repositories to list")
+	cmd.Flags().StringVar(&github.Clone.Filter, "filter", "", "With --github-org, clone partially: blobless or treeless")
+	cmd.Flags().IntVar(&github.Clone.Depth, "depth", 0, "With --github-org, clone and fetch shallowly with this many commits")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"filter": carapace.ActionValues(wsm.CloneFilters...),
+		},
+	)
 
 	return cmd
 }
 
+func runDiscoverGitHubOrg(ctx context.Context, options wsm.GitHubOrgOptions) error {
+	registryPath, err := getRegistryPath()
+	if err != nil {
+		return errors.Wrap(err, "failed to get registry path")
+	}
+	discoverer := wsm.NewRepositoryDiscoverer(registryPath)
+	if err := discoverer.LoadRegistry(); err != nil {
+		return errors.Wrap(err, "failed to load registry")
+	}
+
+	output.PrintInfo("Listing repositories of GitHub organization %s", options.Org)
+	repos, err := wsm.ListGitHubOrgRepositories(ctx, options)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list repositories of %s", options.Org)
+	}
+
+	added, skipped := discoverer.RegisterRemoteRepositories(repos)
+	if err := discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Registered %d repositories from %s", len(added), options.Org)
+	if len(skipped) > 0 {
+		output.PrintInfo("Skipped %d already registered: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	if len(added) > 0 {
+		output.PrintInfo("They are cloned when first used, e.g. 'wsm create my-feature --repos %s'", added[0])
+	}
+	return nil
+}
+
 func runDiscover(ctx context.Context, paths []string, recursive bool, maxDepth int) error {
 	// Default to current directory if no paths specified
 	if len(paths) == 0 {
@@ -92,6 +168,14 @@ func runDiscover(ctx context.Context, paths []string, recursive bool, maxDepth i
 	repos := discoverer.GetRepositories()
 	output.PrintSuccess("Discovery complete! Found %d repositories", len(repos))
 
+	// Workspaces still point at the old location of moved repositories
+	for _, move := range discoverer.DetectedMoves() {
+		output.PrintInfo("Repository %s moved: %s -> %s", move.Name, move.OldPath, move.NewPath)
+		if err := relinkMovedRepository(ctx, move); err != nil {
+			output.PrintWarning("%v", err)
+		}
+	}
+
 	if len(repos) > 0 {
 		output.PrintInfo("Use 'workspace-manager list repos' to see all discovered repositories")
 	}
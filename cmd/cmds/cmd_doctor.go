@@ -0,0 +1,422 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewDoctorCommand creates the doctor command
+func NewDoctorCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment and workspace health",
+		Long: `Check that the external tools used by workspace-manager are available.
+
+Reports the availability and version of:
+  - git (>= 2.38, required)
+  - bash (setup scripts)
+  - tmux (wsm tmux)
+  - gh (wsm pr, GitHub-aware push detection)
+
+Missing optional tools only disable the features that need them.
+
+Examples:
+  # Check tools
+  wsm doctor
+
+  # JSON output
+  wsm doctor --output json
+
+  # Clean up orphaned worktrees and stale workspace configs
+  wsm doctor prune
+
+  # Check the repositories of the current workspace, repairing what is safe
+  wsm doctor workspace --fix`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd.Context(), outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	cmd.AddCommand(NewDoctorPruneCommand())
+	cmd.AddCommand(NewDoctorWorkspaceCommand())
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+// NewDoctorPruneCommand creates the doctor prune command
+func NewDoctorPruneCommand() *cobra.Command {
+	var (
+		dryRun       bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Clean up orphaned worktrees and stale workspace configs",
+		Long: `Scan all registered repositories for worktrees that point into workspaces
+that no longer exist, and clean them up.
+
+A worktree is orphaned when:
+  - its directory was deleted manually (cleaned with 'git worktree prune')
+  - it lives under the workspaces directory but no workspace configuration
+    references it anymore (removed with 'git worktree remove'; worktrees with
+    local changes are kept and reported). Only worktrees in a workspace
+    directory wsm marked with .wsm/wsm.json are removed, others are reported.
+
+Workspace configurations whose directory no longer exists are removed as well.
+
+Examples:
+  # Preview what would be cleaned
+  wsm doctor prune --dry-run
+
+  # Clean up
+  wsm doctor prune`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctorPrune(cmd.Context(), dryRun, outputFormat)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Show what would be cleaned without making changes")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+// NewDoctorWorkspaceCommand creates the doctor workspace command
+func NewDoctorWorkspaceCommand() *cobra.Command {
+	var (
+		all          bool
+		fix          bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "workspace [workspace-name]",
+		Short: "Check the health of workspace repositories",
+		Long: `Check each repository of a workspace (the current one by default) for:
+
+  - missing worktree directories                  (error)
+  - stale index.lock / HEAD.lock files            (error, --fix removes them)
+  - go.work entries pointing at missing dirs      (error, --fix drops them)
+  - detached HEAD                                 (warning, --fix re-attaches when HEAD is the branch tip)
+  - branch differs from the workspace branch      (warning)
+  - locked worktrees                              (warning, --fix unlocks)
+  - missing upstream                              (warning when origin/<branch> exists and --fix can track it, info otherwise)
+  - diverged from upstream                        (warning)
+
+Only repairs that cannot lose work are applied by --fix; other issues come with a hint.
+
+Examples:
+  # Check the current workspace
+  wsm doctor workspace
+
+  # Check and repair all workspaces
+  wsm doctor workspace --all --fix`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			if all && name != "" {
+				return errors.New("--all cannot be combined with a workspace name")
+			}
+			return runDoctorWorkspace(cmd.Context(), name, all, fix, outputFormat)
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Check all workspaces")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Apply safe repairs")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runDoctor(ctx context.Context, outputFormat string) error {
+	statuses := wsm.CheckDependencies(ctx)
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(statuses)
+	}
+
+	output.PrintHeader("External tools")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tSTATUS\tVERSION\tMIN\tUSED FOR")
+	fmt.Fprintln(w, "----\t------\t-------\t---\t--------")
+
+	var problems []wsm.ToolStatus
+	requiredMissing := false
+	for _, status := range statuses {
+		statusStr := "✅"
+		if !status.OK() {
+			statusStr = "⚠️"
+			if status.Required {
+				statusStr = "❌"
+				requiredMissing = true
+			}
+			problems = append(problems, status)
+		}
+
+		version := status.Version
+		if version == "" {
+			version = "-"
+		}
+		minVersion := status.MinVersion
+		if minVersion == "" {
+			minVersion = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Name, statusStr, version, minVersion, status.Purpose)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	if len(problems) == 0 {
+		fmt.Println()
+		output.PrintSuccess("All external tools are available")
+		return nil
+	}
+
+	fmt.Println()
+	for _, status := range problems {
+		if status.Required {
+			output.PrintError("%s", status.Message)
+		} else {
+			output.PrintWarning("%s", status.Message)
+		}
+	}
+
+	if requiredMissing {
+		return errors.New("required tools are missing or outdated")
+	}
+
+	return nil
+}
+
+func runDoctorPrune(ctx context.Context, dryRun bool, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	report, err := wm.PruneOrphans(ctx, dryRun)
+	if err != nil {
+		return errors.Wrap(err, "failed to prune orphans")
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(report)
+	}
+
+	if len(report.OrphanedWorktrees) == 0 && len(report.UnmarkedWorktrees) == 0 && len(report.StaleWorkspaces) == 0 && len(report.Errors) == 0 {
+		output.PrintSuccess("Nothing to clean up - no orphaned worktrees or stale workspaces")
+		return nil
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+
+	if len(report.OrphanedWorktrees) > 0 {
+		output.PrintHeader("Orphaned worktrees")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPOSITORY\tWORKTREE\tBRANCH\tREASON")
+		fmt.Fprintln(w, "----------\t--------\t------\t------")
+		for _, orphan := range report.OrphanedWorktrees {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orphan.Repository, orphan.WorktreePath, orphan.Branch, orphan.Reason)
+		}
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "failed to flush table writer")
+		}
+		fmt.Println()
+	}
+
+	if len(report.UnmarkedWorktrees) > 0 {
+		output.PrintHeader("Worktrees left alone")
+		output.PrintWarning("These worktrees have no workspace configuration, but no wsm.json marker either; remove them with 'git worktree remove' if they are not needed")
+		for _, worktree := range report.UnmarkedWorktrees {
+			fmt.Printf("  %s %s\n", worktree.Repository, output.DimStyle.Render(worktree.WorktreePath))
+		}
+		fmt.Println()
+	}
+
+	if len(report.StaleWorkspaces) > 0 {
+		output.PrintHeader("Stale workspace configurations")
+		for _, stale := range report.StaleWorkspaces {
+			fmt.Printf("  %s %s\n", stale.Name, output.DimStyle.Render(stale.Path))
+		}
+		fmt.Println()
+	}
+
+	output.PrintInfo("%s %d orphaned worktrees and %d stale workspace configurations", verb, len(report.OrphanedWorktrees), len(report.StaleWorkspaces))
+	if len(report.PrunedRepositories) > 0 {
+		if dryRun {
+			output.PrintInfo("Would run 'git worktree prune' in: %s", strings.Join(report.PrunedRepositories, ", "))
+		} else {
+			output.PrintInfo("Ran 'git worktree prune' in: %s", strings.Join(report.PrunedRepositories, ", "))
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		for _, e := range report.Errors {
+			output.PrintWarning("%s", e)
+		}
+		return errors.Errorf("%d cleanup steps failed", len(report.Errors))
+	}
+
+	return nil
+}
+
+func runDoctorWorkspace(ctx context.Context, name string, all, fix bool, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	var workspaces []wsm.Workspace
+	switch {
+	case all:
+		workspaces, err = wsm.LoadWorkspaces()
+		if err != nil {
+			return errors.Wrap(err, "failed to load workspaces")
+		}
+	case name != "":
+		workspace, err := wm.LoadWorkspace(name)
+		if err != nil {
+			return err
+		}
+		workspaces = append(workspaces, *workspace)
+	default:
+		workspace, err := findCurrentWorkspace()
+		if err != nil {
+			return errors.Wrap(err, "failed to detect current workspace. Specify a workspace name or use --all")
+		}
+		workspaces = append(workspaces, *workspace)
+	}
+
+	var reports []*wsm.HealthReport
+	for i := range workspaces {
+		report, err := wm.CheckWorkspaceHealth(ctx, &workspaces[i], fix)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check workspace '%s'", workspaces[i].Name)
+		}
+		reports = append(reports, report)
+	}
+
+	hasErrors := false
+	for _, report := range reports {
+		hasErrors = hasErrors || report.HasErrors()
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(reports); err != nil {
+			return err
+		}
+		if hasErrors {
+			return errors.New("workspace health check found errors")
+		}
+		return nil
+	}
+
+	fixable := 0
+	for _, report := range reports {
+		output.PrintHeader("Workspace: %s", report.Workspace)
+
+		if len(report.Issues) == 0 {
+			output.PrintSuccess("All repositories are healthy")
+			fmt.Println()
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tREPOSITORY\tCHECK\tMESSAGE\tFIX")
+		fmt.Fprintln(w, "--------\t----------\t-----\t-------\t---")
+		var hints []string
+		for _, issue := range report.Issues {
+			repository := issue.Repository
+			if repository == "" {
+				repository = "-"
+			}
+
+			fixStatus := "-"
+			switch {
+			case issue.Fixed:
+				fixStatus = "✅ fixed"
+			case issue.FixError != "":
+				fixStatus = "❌ " + issue.FixError
+			case issue.Fixable:
+				fixStatus = "--fix"
+				fixable++
+			case issue.Hint != "":
+				hints = append(hints, fmt.Sprintf("%s: %s", repository, issue.Hint))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", getSeverityString(issue.Severity), repository, issue.Check, issue.Message, fixStatus)
+		}
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "failed to flush table writer")
+		}
+
+		for _, hint := range hints {
+			fmt.Printf("  %s\n", output.DimStyle.Render(hint))
+		}
+		fmt.Println()
+	}
+
+	if fixable > 0 {
+		output.PrintInfo("%d issues can be repaired with --fix", fixable)
+	}
+	if hasErrors {
+		return errors.New("workspace health check found errors")
+	}
+
+	return nil
+}
+
+func getSeverityString(severity string) string {
+	switch severity {
+	case wsm.HealthSeverityError:
+		return "❌ error"
+	case wsm.HealthSeverityWarning:
+		return "⚠️  warning"
+	default:
+		return "ℹ️  info"
+	}
+}
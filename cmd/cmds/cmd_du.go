@@ -0,0 +1,143 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewDuCommand creates the du command
+func NewDuCommand() *cobra.Command {
+	var (
+		outputFormat string
+		worktrees    bool
+		top          int
+		staleDays    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "du [workspace...]",
+		Short: "Report disk usage of workspaces and their source repositories",
+		Long: `Report the disk space used by each workspace and, with --worktrees, by each
+repository worktree inside it. The .git directories of the source repositories
+are reported separately: all worktrees of a repository share its objects.
+
+Suggestions list workspaces whose files have not changed for --stale-days and
+source repositories that would benefit from 'git gc' (many loose objects or
+packs, or garbage files).
+
+Examples:
+  # Disk usage of every workspace, biggest first
+  wsm du
+
+  # Break a workspace down per worktree
+  wsm du my-feature --worktrees
+
+  # Show the 5 biggest workspaces and suggest ones idle for two weeks
+  wsm du --top 5 --stale-days 14`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDu(cmd.Context(), args, outputFormat, worktrees, top, staleDays)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().BoolVar(&worktrees, "worktrees", false, "Show the size of each worktree")
+	cmd.Flags().IntVar(&top, "top", 0, "Only show the biggest N workspaces and repositories")
+	cmd.Flags().IntVar(&staleDays, "stale-days", 30, "Suggest deleting workspaces without file changes for this many days (0 disables)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+	carapace.Gen(cmd).PositionalAnyCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func runDu(ctx context.Context, workspaceNames []string, outputFormat string, worktrees bool, top, staleDays int) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	report, err := wm.DiskUsage(ctx, workspaceNames, time.Duration(staleDays)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	if top > 0 {
+		if len(report.Workspaces) > top {
+			report.Workspaces = report.Workspaces[:top]
+		}
+		if len(report.Repositories) > top {
+			report.Repositories = report.Repositories[:top]
+		}
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(report)
+	}
+
+	if len(report.Workspaces) == 0 {
+		output.PrintInfo("No workspaces found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tSIZE\tLAST CHANGE\tPATH")
+	fmt.Fprintln(w, "---------\t----\t-----------\t----")
+	for _, workspace := range report.Workspaces {
+		if workspace.Missing {
+			fmt.Fprintf(w, "%s\t-\t-\t%s (missing)\n", workspace.Name, workspace.Path)
+			continue
+		}
+		lastChange := "-"
+		if !workspace.LastModified.IsZero() {
+			lastChange = formatRelativeTime(workspace.LastModified)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", workspace.Name, wsm.FormatBytes(workspace.Size), lastChange, workspace.Path)
+		if worktrees {
+			for _, worktree := range workspace.Worktrees {
+				fmt.Fprintf(w, "  %s\t%s\t\t\n", worktree.Repository, wsm.FormatBytes(worktree.Size))
+			}
+		}
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "SOURCE REPOSITORY\tGIT DIR\tLOOSE\tPACKS\tWORKSPACES")
+	fmt.Fprintln(w, "-----------------\t-------\t-----\t-----\t----------")
+	for _, repo := range report.Repositories {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", repo.Name, wsm.FormatBytes(repo.GitDirSize), repo.LooseObjects, repo.Packs, strings.Join(repo.Workspaces, ", "))
+	}
+	fmt.Fprintln(w)
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	output.PrintInfo("Total: %s", wsm.FormatBytes(report.Total))
+
+	if len(report.Suggestions) > 0 {
+		fmt.Println()
+		output.PrintHeader("Suggestions")
+		for _, suggestion := range report.Suggestions {
+			fmt.Printf("  %s %s (%s, %s)\n", suggestion.Kind, suggestion.Target, wsm.FormatBytes(suggestion.Size), suggestion.Reason)
+			fmt.Printf("    %s\n", output.DimStyle.Render(suggestion.Command))
+		}
+	}
+
+	return nil
+}
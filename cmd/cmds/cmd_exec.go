@@ -0,0 +1,154 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewExecCommand creates the exec command
+func NewExecCommand() *cobra.Command {
+	var (
+		workspaceName string
+		filters       repoFilterFlags
+		jobs          int
+		outputFormat  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "exec [flags] -- <command> [args...]",
+		Short: "Run a command in every repository of the workspace",
+		Long: `Run a shell command in every repository worktree of the workspace, like
+'git submodule foreach' for workspaces.
+
+Repositories run in parallel (--jobs at a time). Output is streamed line by
+line, prefixed with the repository name. A single argument is passed to
+'sh -c' as is, so it may contain pipes; multiple arguments are quoted.
+
+The command sees WSM_REPO_NAME, WSM_REPO_PATH, WSM_WORKSPACE_NAME,
+WSM_WORKSPACE_PATH and WSM_WORKSPACE_BRANCH in its environment.
+wsm exits with an error if the command fails in any repository.
+
+Examples:
+  # Show the short status of every repository
+  wsm exec -- git status -s
+
+  # Run tests in the Go repositories, two at a time
+  wsm exec --tag go --jobs 2 -- go test ./...
+
+  # Use shell features
+  wsm exec --repo app,lib -- 'git log --oneline | head -3'
+
+  # Collect the results as JSON
+  wsm exec -o json -- make lint`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := args[0]
+			if len(args) > 1 {
+				command = wsm.ShellJoin(args)
+			}
+			return runExec(cmd.Context(), workspaceName, command, &filters, jobs, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&workspaceName, "workspace", "w", "", "Workspace name (default: detect from current directory)")
+	filters.register(cmd, &workspaceName)
+	// --repos predates the shared --repo flag
+	cmd.Flags().StringSliceVar(&filters.repos, "repos", nil, "Only run in these repositories (comma-separated)")
+	_ = cmd.Flags().MarkDeprecated("repos", "use --repo instead")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Number of repositories to run in parallel (default: jobs of limits.yaml, or 4)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runExec(ctx context.Context, workspaceName, command string, filters *repoFilterFlags, jobs int, outputFormat string) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	var workspace *wsm.Workspace
+	var err error
+	if workspaceName == "" {
+		workspace, err = detectCurrentWorkspace()
+	} else {
+		workspace, err = loadWorkspace(workspaceName)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to find workspace")
+	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+
+	opts := wsm.ExecOptions{
+		Command:     command,
+		Concurrency: jobs,
+	}
+	// JSON collects the output per repository instead of streaming it
+	if outputFormat == "table" {
+		opts.Stdout = os.Stdout
+		opts.Stderr = os.Stderr
+	}
+
+	results, err := wsm.NewExecOperations(workspace).Exec(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Succeeded() {
+			failed++
+		}
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printExecSummary(results, failed)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("command failed in %d of %d repositories", failed, len(results))
+	}
+	return nil
+}
+
+func printExecSummary(results []wsm.ExecResult, failed int) {
+	fmt.Println()
+	if failed == 0 {
+		output.PrintSuccess("Command succeeded in %d repositories", len(results))
+		return
+	}
+
+	output.PrintWarning("Command failed in %d of %d repositories", failed, len(results))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, result := range results {
+		if result.Succeeded() {
+			continue
+		}
+		reason := fmt.Sprintf("exit %d", result.ExitCode)
+		if result.Error != "" {
+			reason = result.Error
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\n", result.Repository, reason)
+	}
+	_ = w.Flush()
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
@@ -15,11 +16,16 @@ import (
 
 func NewForkCommand() *cobra.Command {
 	var (
-		branch       string
-		branchPrefix string
-		agentSource  string
-		dryRun       bool
-		workspace    string
+		branch        string
+		branchPrefix  string
+		agentSource   string
+		mergeStrategy string
+		gitConfig     []string
+		dryRun        bool
+		sanitize      bool
+		carryChanges  bool
+		nix           bool
+		workspace     string
 	)
 
 	cmd := &cobra.Command{
@@ -34,6 +40,10 @@ If no source workspace is specified, attempts to detect the current workspace.
 The source workspace's current branch will be used as the base branch for 
 the new workspace's branch.
 
+With --carry-changes, the uncommitted changes of the source repositories,
+untracked files included, are copied into the fork as unstaged changes, so an
+experiment can be forked midway. The source workspace keeps its changes.
+
 Examples:
   # Fork current workspace to create "my-feature"
   workspace-manager fork my-feature
@@ -44,29 +54,66 @@ Examples:
   # Fork with custom branch name
   workspace-manager fork my-feature --branch feature/new-api
 
+  # Fork mid-experiment, taking the uncommitted work along
+  workspace-manager fork my-experiment --carry-changes
+
   # Fork with custom branch prefix (bug/my-feature)
-  workspace-manager fork my-feature --branch-prefix bug`,
+  workspace-manager fork my-feature --branch-prefix bug
+
+  # Squash the fork into one commit per repository when merging it back
+  workspace-manager fork my-feature --merge-strategy squash
+
+  # The fork keeps the git config of the source workspace; override some of it
+  workspace-manager fork my-feature --git-config user.email=me@example.org`,
 		Args: cobra.RangeArgs(1, 2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("fork", 0, func(cmd *cobra.Command, args []string) error {
 			newWorkspaceName := args[0]
 			sourceWorkspaceName := workspace
 			if len(args) > 1 {
 				sourceWorkspaceName = args[1]
 			}
-			return runFork(cmd.Context(), newWorkspaceName, sourceWorkspaceName, branch, branchPrefix, agentSource, dryRun)
-		},
+			if err := wsm.ValidateMergeStrategy(mergeStrategy); err != nil {
+				return err
+			}
+			gitConfigs, err := wsm.ParseGitConfig(gitConfig)
+			if err != nil {
+				return err
+			}
+			var nixFlake *bool
+			if cmd.Flags().Changed("nix") {
+				nixFlake = &nix
+			}
+			return runFork(cmd.Context(), newWorkspaceName, sourceWorkspaceName, branch, branchPrefix, agentSource, mergeStrategy, gitConfigs, dryRun, sanitize, carryChanges, nixFlake)
+		}),
 	}
 
 	cmd.Flags().StringVar(&branch, "branch", "", "Branch name for the new workspace (if not specified, uses <branch-prefix>/<new-workspace-name>)")
 	cmd.Flags().StringVar(&branchPrefix, "branch-prefix", "task", "Prefix for auto-generated branch names")
 	cmd.Flags().StringVar(&agentSource, "agent-source", "", "Path to AGENT.md template file")
+	cmd.Flags().StringVar(&mergeStrategy, "merge-strategy", "", "How 'wsm merge' merges the fork: merge, squash, rebase or ff-only (default: the source workspace's)")
+	cmd.Flags().StringArrayVar(&gitConfig, "git-config", nil, "Git config set in every worktree as key=value, on top of the source workspace's (repeatable)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be created without actually creating")
+	cmd.Flags().BoolVar(&sanitize, "sanitize", false, "Replace characters not allowed in workspace and branch names instead of failing")
+	cmd.Flags().BoolVar(&carryChanges, "carry-changes", false, "Copy the uncommitted and untracked changes of the source repositories into the fork")
+	cmd.Flags().BoolVar(&nix, "nix", false, "Generate a flake.nix dev shell; --nix=false skips it (default: as the source workspace, or nix.yaml)")
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Source workspace name")
 
+	carapace.Gen(cmd).PositionalCompletion(
+		carapace.ActionValues(),
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace":      WorkspaceNameCompletion(),
+			"agent-source":   carapace.ActionFiles(".md"),
+			"merge-strategy": carapace.ActionValues(wsm.MergeStrategies...),
+		},
+	)
+
 	return cmd
 }
 
-func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch, branchPrefix, agentSource string, dryRun bool) error {
+func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch, branchPrefix, agentSource, mergeStrategy string, gitConfig map[string]string, dryRun, sanitize, carryChanges bool, nixFlake *bool) error {
 	wm, err := wsm.NewWorkspaceManager()
 	if err != nil {
 		return errors.Wrap(err, "failed to create workspace manager")
@@ -117,10 +164,29 @@ func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch,
 		}
 	}
 
+	// Changes are exported before the fork exists, so a failed export leaves nothing behind
+	var changes *wsm.LocalChanges
+	keepChanges := false
+	if carryChanges && !dryRun {
+		changes, err = wsm.ExportLocalChanges(ctx, sourceWorkspace)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if !keepChanges {
+				_ = changes.Remove()
+			}
+		}()
+		output.PrintInfo("Carrying over the uncommitted changes of %d repositories", len(changes.Set.Patches))
+	}
+
 	// Generate branch name if not specified
 	finalBranch := branch
 	if finalBranch == "" {
-		finalBranch = fmt.Sprintf("%s/%s", branchPrefix, newWorkspaceName)
+		finalBranch, err = wsm.GenerateBranchName(branchPrefix, newWorkspaceName, "")
+		if err != nil {
+			return err
+		}
 		output.PrintInfo("Using auto-generated branch: %s", finalBranch)
 		log.Debug().Str("branch", finalBranch).Str("prefix", branchPrefix).Str("name", newWorkspaceName).Msg("Generated branch name")
 	}
@@ -138,6 +204,31 @@ func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch,
 		output.PrintInfo("Using AGENT.md from source workspace: %s", finalAgentSource)
 	}
 
+	opts := wsm.CreateOptions{
+		Name:          newWorkspaceName,
+		Repositories:  repoNames,
+		Branch:        finalBranch,
+		BaseBranch:    baseBranch,
+		AgentSource:   finalAgentSource,
+		SparsePaths:   sourceWorkspace.RepositorySparsePaths,
+		Pins:          sourceWorkspace.RepositoryPins,
+		Submodules:    sourceWorkspace.Submodules,
+		MergeStrategy: mergeStrategy,
+		GitConfig:     wsm.MergeGitConfig(sourceWorkspace.TrustedGitConfig(), gitConfig),
+		Nix:           nixFlake,
+		Sanitize:      sanitize,
+		NoHooks:       sourceWorkspace.NoHooks,
+		DryRun:        dryRun,
+	}
+	// A fork of a workspace with a flake gets one too
+	if opts.Nix == nil && sourceWorkspace.NixFlake {
+		opts.Nix = &sourceWorkspace.NixFlake
+	}
+	// Merge the fork back the way the source workspace is merged unless told otherwise
+	if opts.MergeStrategy == "" {
+		opts.MergeStrategy = sourceWorkspace.MergeStrategy
+	}
+
 	// Create the new workspace
 	log.Debug().
 		Str("newName", newWorkspaceName).
@@ -148,13 +239,10 @@ func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch,
 		Bool("dryRun", dryRun).
 		Msg("Forking workspace")
 
-	workspace, err := wm.CreateWorkspace(ctx, newWorkspaceName, repoNames, finalBranch, baseBranch, finalAgentSource, dryRun)
+	workspace, err := wm.CreateWorkspace(ctx, opts)
 	if err != nil {
 		// Check if user cancelled - handle gracefully without error
-		errMsg := strings.ToLower(err.Error())
-		if strings.Contains(errMsg, "cancelled by user") ||
-			strings.Contains(errMsg, "creation cancelled") ||
-			strings.Contains(errMsg, "operation cancelled") {
+		if wsm.IsCancellationError(err) {
 			output.PrintInfo("Operation cancelled.")
 			return nil // Return success to prevent usage help
 		}
@@ -169,10 +257,37 @@ func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch,
 		fmt.Printf("  Name: %s\n", sourceWorkspace.Name)
 		fmt.Printf("  Path: %s\n", sourceWorkspace.Path)
 		fmt.Printf("  Current branch: %s\n", baseBranch)
+		if carryChanges {
+			var changed []string
+			for _, repoStatus := range status.Repositories {
+				if repoStatus.HasChanges || len(repoStatus.UntrackedFiles) > 0 {
+					changed = append(changed, repoStatus.Repository.Name)
+				}
+			}
+			if len(changed) > 0 {
+				fmt.Printf("  Changes carried over: %s\n", strings.Join(changed, ", "))
+			} else {
+				fmt.Printf("  Changes carried over: none\n")
+			}
+		}
 		fmt.Println()
 		return showWorkspacePreview(workspace)
 	}
 
+	if changes != nil {
+		applies, err := changes.ApplyTo(ctx, workspace)
+		if err != nil {
+			keepChanges = true
+			for _, apply := range applies {
+				if apply.Problem != "" {
+					output.PrintWarning("%s: %s", apply.Repository, apply.Problem)
+				}
+			}
+			return errors.Wrapf(err, "workspace '%s' was created without the changes of '%s'; apply them with 'wsm apply %s %s --3way'",
+				workspace.Name, sourceWorkspace.Name, changes.Dir, workspace.Name)
+		}
+	}
+
 	output.PrintSuccess("Workspace '%s' forked successfully from '%s'!", workspace.Name, sourceWorkspace.Name)
 	fmt.Println()
 
@@ -183,12 +298,27 @@ func runFork(ctx context.Context, newWorkspaceName, sourceWorkspaceName, branch,
 	fmt.Printf("  Repositories: %s\n", strings.Join(getRepositoryNames(workspace.Repositories), ", "))
 	fmt.Printf("  New branch: %s\n", workspace.Branch)
 	fmt.Printf("  Base branch: %s\n", workspace.BaseBranch)
+	if workspace.MergeStrategy != "" {
+		fmt.Printf("  Merge strategy: %s\n", workspace.MergeStrategy)
+	}
 	if workspace.GoWorkspace {
 		fmt.Printf("  Go workspace: yes (go.work created)\n")
 	}
+	if len(workspace.Ecosystems) > 0 {
+		fmt.Printf("  Workspace manifests: %s\n", strings.Join(workspace.Ecosystems, ", "))
+	}
 	if workspace.AgentMD != "" {
 		fmt.Printf("  AGENT.md: copied from %s\n", workspace.AgentMD)
 	}
+	if changes != nil {
+		var carried []string
+		for _, patch := range changes.Set.Patches {
+			carried = append(carried, fmt.Sprintf("%s (%s)", patch.Repository, patch.Summary))
+		}
+		if len(carried) > 0 {
+			fmt.Printf("  Changes carried over: %s\n", strings.Join(carried, "; "))
+		}
+	}
 
 	fmt.Println()
 	output.PrintInfo("To start working:")
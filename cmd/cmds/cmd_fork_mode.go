@@ -0,0 +1,115 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewForkModeCommand creates the fork-mode command
+func NewForkModeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fork-mode",
+		Short: "Push to personal forks and open pull requests upstream",
+		Long: `Configure the fork-based contribution workflow of a workspace, for projects you
+cannot push to.
+
+In fork mode, 'wsm commit --push', 'wsm sync push' and 'wsm pr' push branches
+to a fork remote (named 'fork' by default) in every repository. When a
+repository has no such remote, it is forked on GitHub with 'gh repo fork' and
+the remote is added. 'wsm pr' then opens pull requests against the repository
+of the base remote (origin, or the remote set with 'wsm repos remote'), with
+the fork branch as head. 'wsm push fork' creates missing forks the same way.
+
+Examples:
+  # Contribute from forks in your account
+  wsm fork-mode enable my-feature
+
+  # Fork into an organization, under a custom remote name
+  wsm fork-mode enable my-feature --org my-org --remote mine
+
+  # Push to origin again
+  wsm fork-mode disable my-feature`,
+	}
+
+	cmd.AddCommand(
+		newForkModeEnableCommand(),
+		newForkModeDisableCommand(),
+	)
+
+	return cmd
+}
+
+func newForkModeEnableCommand() *cobra.Command {
+	var config wsm.ForkConfig
+
+	cmd := &cobra.Command{
+		Use:   "enable [workspace-name]",
+		Short: "Push workspace branches to forks",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runForkMode(args, &config)
+		},
+	}
+
+	cmd.Flags().StringVar(&config.Remote, "remote", wsm.DefaultForkRemote, "Name of the fork remote")
+	cmd.Flags().StringVar(&config.Organization, "org", "", "Create forks in this organization instead of your account")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func newForkModeDisableCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "disable [workspace-name]",
+		Short: "Push workspace branches to origin again",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runForkMode(args, nil)
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func runForkMode(args []string, config *wsm.ForkConfig) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	var workspace *wsm.Workspace
+	if len(args) > 0 {
+		workspace, err = wm.LoadWorkspace(args[0])
+	} else {
+		workspace, err = detectCurrentWorkspace()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load workspace")
+	}
+
+	if config != nil && config.Remote == wsm.DefaultForkRemote {
+		config.Remote = ""
+	}
+	workspace.Fork = config
+	if err := wm.SaveWorkspace(workspace); err != nil {
+		return errors.Wrap(err, "failed to save workspace configuration")
+	}
+
+	if config == nil {
+		output.PrintSuccess("Workspace '%s' pushes to its primary remotes again", workspace.Name)
+		return nil
+	}
+	output.PrintSuccess("Workspace '%s' pushes to the '%s' remote", workspace.Name, config.RemoteName())
+	if config.Organization != "" {
+		fmt.Printf("  Missing forks are created in %s\n", config.Organization)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewGCCommand creates the gc command
+func NewGCCommand() *cobra.Command {
+	var (
+		olderThan    string
+		dryRun       bool
+		force        bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete idle workspaces that hold no unsaved work",
+		Long: `Find workspaces not used for --older-than, by a wsm command or a change to
+their files, and delete them: worktrees are removed with 'git worktree remove', then the workspace
+directory and configuration. Dated workspace directories (~/workspaces/2006-01-02)
+left empty are removed as well.
+
+Workspaces with unpushed commits, stashes, uncommitted or untracked files are
+never collected; the report lists them with the work they still hold.
+'wsm suggest-cleanup' shows the same workspaces without deleting anything.
+
+Examples:
+  # Report and delete workspaces idle for 30 days, after confirmation
+  wsm gc
+
+  # Only report workspaces idle for two weeks
+  wsm gc --older-than 2w --dry-run
+
+  # Delete without confirmation
+  wsm gc --older-than 60d --force`,
+		Args: cobra.NoArgs,
+		RunE: audited("gc", -1, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			age, err := parseAge(olderThan)
+			if err != nil {
+				return err
+			}
+			return runGC(cmd.Context(), age, dryRun, force, outputFormat)
+		}),
+	}
+
+	cmd.Flags().StringVar(&olderThan, "older-than", "30d", "Collect workspaces unused for this long (e.g. 30d, 2w, 36h)")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Only report what would be deleted")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Delete without confirmation")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"older-than": carapace.ActionValues("7d", "14d", "30d", "60d", "90d"),
+			"output":     carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+// parseAge parses a duration that may also be given in days (30d) or weeks (2w)
+func parseAge(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) && n >= 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, nil
+	}
+	return 0, errors.Errorf("invalid age %q, expected e.g. 30d, 2w or 36h", value)
+}
+
+func runGC(ctx context.Context, olderThan time.Duration, dryRun, force bool, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	report, err := wm.PlanGC(ctx, olderThan)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" && (dryRun || len(report.Collectable) == 0) {
+		return wsm.PrintJSON(report)
+	}
+	if outputFormat == "table" {
+		if err := printGCReport(report); err != nil {
+			return err
+		}
+	}
+	if len(report.Collectable) == 0 || dryRun {
+		return nil
+	}
+
+	if !force {
+		if err := wsm.RequireInteractive("Deleting idle workspaces", "--force"); err != nil {
+			return err
+		}
+		confirmed, err := ux.Confirm(
+			fmt.Sprintf("Delete %d workspace(s) and their files?", len(report.Collectable)),
+			"Worktrees, workspace directories and configurations are removed. This action cannot be undone.",
+		)
+		if err != nil && !errors.Is(err, ux.ErrAborted) {
+			return errors.Wrap(err, "confirmation failed")
+		}
+		if !confirmed {
+			output.PrintInfo("Operation cancelled.")
+			return nil
+		}
+	}
+
+	result := wm.CollectGarbage(ctx, report)
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(result); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range result.Deleted {
+			output.PrintSuccess("Deleted workspace %s", name)
+		}
+		for _, dir := range result.RemovedDirectories {
+			output.PrintInfo("Removed empty directory %s", dir)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return errors.Errorf("failed to delete some workspaces: %s", strings.Join(result.Errors, "; "))
+	}
+	return nil
+}
+
+func printGCReport(report *wsm.GCReport) error {
+	if len(report.Collectable) == 0 && len(report.Kept) == 0 {
+		output.PrintSuccess("No workspace idle for %s", formatAge(report.OlderThan))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(report.Collectable) > 0 {
+		output.PrintHeader("Idle workspaces to delete")
+		fmt.Fprintln(w, "WORKSPACE\tSIZE\tLAST USED\tPATH")
+		fmt.Fprintln(w, "---------\t----\t---------\t----")
+		for _, candidate := range report.Collectable {
+			path := candidate.Path
+			if candidate.Missing {
+				path += " (missing)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", candidate.Workspace, wsm.FormatBytes(candidate.Size), formatRelativeTime(candidate.LastUsed), path)
+		}
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "failed to flush table writer")
+		}
+		output.PrintInfo("Reclaimable: %s", wsm.FormatBytes(report.Reclaimable))
+		fmt.Println()
+	}
+
+	if len(report.Kept) > 0 {
+		output.PrintHeader("Idle workspaces kept because they hold work")
+		for _, candidate := range report.Kept {
+			fmt.Printf("  %s (last used %s)\n", candidate.Workspace, formatRelativeTime(candidate.LastUsed))
+			for _, reason := range candidate.KeptBecause {
+				output.PrintWarning("    %s", reason)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// formatAge renders a duration in days when it is a whole number of them
+func formatAge(d time.Duration) string {
+	if d > 0 && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	return d.String()
+}
@@ -0,0 +1,125 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewGoReplaceCommand creates the go-replace command
+func NewGoReplaceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "go-replace",
+		Short: "Point Go consumers at sibling worktrees with go.mod replace directives",
+		Long: `Manage replace directives in the go.mod of workspace repositories, for modules
+that cannot use go.work (e.g. built with Go older than 1.18).
+
+'apply' adds "replace <module> => ../<repo> // wsm:replace" to every workspace
+repository requiring the module of another workspace repository (see 'wsm
+graph'), and keeps them up to date as repositories are added and removed.
+Replace directives written by hand are never changed.
+
+The directives are local only: 'wsm commit' removes them before committing and
+restores them afterwards, and pushes are refused while a committed go.mod
+still contains one.
+
+Examples:
+  # Enable replace directives in the current workspace
+  wsm go-replace apply
+
+  # Create a workspace using them from the start
+  wsm create my-feature --repos app,lib --go-replace
+
+  # Remove them and stop maintaining them
+  wsm go-replace remove my-feature`,
+	}
+
+	cmd.AddCommand(
+		newGoReplaceApplyCommand(),
+		newGoReplaceRemoveCommand(),
+	)
+
+	return cmd
+}
+
+func newGoReplaceApplyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply [workspace-name]",
+		Short: "Add replace directives and keep them up to date",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGoReplace(args, true)
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func newGoReplaceRemoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove [workspace-name]",
+		Short: "Remove the replace directives added by wsm",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGoReplace(args, false)
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+func runGoReplace(args []string, enable bool) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	var workspace *wsm.Workspace
+	if len(args) > 0 {
+		workspace, err = wm.LoadWorkspace(args[0])
+	} else {
+		workspace, err = detectCurrentWorkspace()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to load workspace")
+	}
+
+	if !enable {
+		if err := wsm.RemoveGoReplaces(workspace); err != nil {
+			return err
+		}
+		workspace.GoReplaces = false
+		if err := wm.SaveWorkspace(workspace); err != nil {
+			return errors.Wrap(err, "failed to save workspace configuration")
+		}
+		output.PrintSuccess("Removed replace directives from workspace '%s'", workspace.Name)
+		return nil
+	}
+
+	replaces, err := wsm.ApplyGoReplaces(workspace)
+	if err != nil {
+		return err
+	}
+	workspace.GoReplaces = true
+	if err := wm.SaveWorkspace(workspace); err != nil {
+		return errors.Wrap(err, "failed to save workspace configuration")
+	}
+
+	if len(replaces) == 0 {
+		output.PrintInfo("No workspace repository requires the module of another one")
+		return nil
+	}
+	output.PrintSuccess("Applied %d replace directives in workspace '%s'", len(replaces), workspace.Name)
+	for _, replace := range replaces {
+		fmt.Printf("  %s: %s => %s\n", replace.Repository, replace.Module, replace.Path)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package cmds
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewGraphCommand creates the graph command
+func NewGraphCommand() *cobra.Command {
+	var (
+		outputFormat string
+		registry     bool
+		node         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "graph [workspace-name]",
+		Short: "Show the dependency graph between repositories",
+		Long: `Build the dependency graph between the repositories of a workspace from the
+go.mod files at their roots: a repository depends on another when it requires
+the module the other one declares. With --node, package.json dependencies on
+the packages of other repositories are included as well.
+
+With --registry, the graph covers every registered repository instead of a
+workspace. 'wsm create' uses the same graph to point out dependencies missing
+from a new workspace.
+
+Examples:
+  # Dependencies inside the current workspace
+  wsm graph
+
+  # Render with Graphviz
+  wsm graph my-feature --output dot | dot -Tsvg > graph.svg
+
+  # Mermaid flowchart of all registered repositories, including npm packages
+  wsm graph --registry --node --output mermaid`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			if registry && workspaceName != "" {
+				return errors.New("--registry cannot be combined with a workspace name")
+			}
+			return runGraph(workspaceName, registry, wsm.GraphOptions{Node: node}, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, dot, mermaid, json)")
+	cmd.Flags().BoolVar(&registry, "registry", false, "Graph all registered repositories instead of a workspace")
+	cmd.Flags().BoolVar(&node, "node", false, "Include package.json dependencies")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("text", "dot", "mermaid", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runGraph(workspaceName string, registry bool, opts wsm.GraphOptions, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	var graph *wsm.DependencyGraph
+	if registry {
+		graph, err = wsm.BuildDependencyGraph(wm.Discoverer.GetRepositories(), func(repo wsm.Repository) string {
+			return repo.Path
+		}, opts)
+	} else {
+		var workspace *wsm.Workspace
+		if workspaceName == "" {
+			workspace, err = detectCurrentWorkspace()
+		} else {
+			workspace, err = wm.LoadWorkspace(workspaceName)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to load workspace")
+		}
+		// Worktrees may be on branches whose dependencies differ from the source repositories
+		graph, err = wsm.BuildDependencyGraph(workspace.Repositories, func(repo wsm.Repository) string {
+			return filepath.Join(workspace.Path, repo.Name)
+		}, opts)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to build dependency graph")
+	}
+
+	switch outputFormat {
+	case "json":
+		return wsm.PrintJSON(graph)
+	case "dot":
+		fmt.Print(graph.DOT())
+	case "mermaid":
+		fmt.Print(graph.Mermaid())
+	case "text":
+		printDependencyGraph(graph)
+	default:
+		return errors.Errorf("unsupported output format '%s' (text, dot, mermaid, json)", outputFormat)
+	}
+	return nil
+}
+
+func printDependencyGraph(graph *wsm.DependencyGraph) {
+	if len(graph.Edges) == 0 {
+		output.PrintInfo("No dependencies between the %d repositories.", len(graph.Repositories))
+		return
+	}
+
+	output.PrintHeader("Dependencies")
+	for _, repo := range graph.Repositories {
+		var deps []string
+		for _, edge := range graph.Edges {
+			if edge.From == repo {
+				deps = append(deps, fmt.Sprintf("%s (%s)", edge.To, edge.Via))
+			}
+		}
+		if len(deps) == 0 {
+			continue
+		}
+		fmt.Printf("  %s → %s\n", repo, strings.Join(deps, ", "))
+	}
+}
+
+// suggestMissingDependencies points out registered repositories the selected
+// ones depend on but that are not part of the new workspace
+func suggestMissingDependencies(wm *wsm.WorkspaceManager, repoNames []string) {
+	repos, err := wm.FindRepositories(repoNames)
+	if err != nil {
+		// CreateWorkspace reports unknown repositories
+		return
+	}
+	missing, err := wm.MissingDependencies(repos)
+	if err != nil || len(missing) == 0 {
+		return
+	}
+
+	var all []string
+	for _, repo := range repos {
+		deps, ok := missing[repo.Name]
+		if !ok {
+			continue
+		}
+		output.PrintInfo("%s needs %s (not selected)", repo.Name, strings.Join(deps, " and "))
+		for _, dep := range deps {
+			if !containsString(all, dep) {
+				all = append(all, dep)
+			}
+		}
+	}
+	output.PrintInfo("Include them with --repos %s", strings.Join(append(append([]string{}, repoNames...), all...), ","))
+}
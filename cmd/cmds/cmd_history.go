@@ -0,0 +1,177 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewHistoryCommand creates the history command
+func NewHistoryCommand() *cobra.Command {
+	var (
+		outputFormat string
+		operations   []string
+		since        string
+		failed       bool
+		limit        int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history [workspace]",
+		Short: "Show the audit log of mutating operations",
+		Long: `Show the operations recorded in the audit log. Every create, delete, add,
+remove, fork, commit, push, merge, rebase, cherry-pick and release run is
+appended to audit.jsonl in the wsm config directory with its arguments, flags,
+result and duration. Set WSM_ACTOR to record who drives wsm, e.g. an agent name.
+
+Examples:
+  # Everything that happened to a workspace
+  wsm history my-feature
+
+  # Failed pushes and merges of the last day
+  wsm history --operation push --operation merge --since 24h --failed
+
+  # Raw events for scripts
+  wsm history --output json --limit 0`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := wsm.AuditFilter{
+				Operations: operations,
+				FailedOnly: failed,
+				Limit:      limit,
+			}
+			if len(args) > 0 {
+				filter.Workspace = args[0]
+			}
+			if since != "" {
+				sinceTime, err := parseHistorySince(since)
+				if err != nil {
+					return err
+				}
+				filter.Since = sinceTime
+			}
+			return runHistory(filter, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().StringSliceVar(&operations, "operation", nil, "Only show these operations (repeatable)")
+	cmd.Flags().StringVar(&since, "since", "", "Only show events newer than a duration (e.g. 24h) or date (YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&failed, "failed", false, "Only show failed operations")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Show only the newest N events (0 for all)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output":    carapace.ActionValues("table", "json"),
+			"operation": carapace.ActionValues("create", "delete", "add", "remove", "fork", "commit", "push", "merge", "rebase", "cherry-pick", "release"),
+		},
+	)
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+// parseHistorySince accepts a duration relative to now or an absolute date
+func parseHistorySince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("invalid --since value %q, expected a duration like 24h or a date like 2006-01-02", value)
+}
+
+func runHistory(filter wsm.AuditFilter, outputFormat string) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	events, err := wsm.ReadAuditEvents(filter)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		if events == nil {
+			events = []wsm.AuditEvent{}
+		}
+		return wsm.PrintJSON(events)
+	}
+
+	if len(events) == 0 {
+		output.PrintInfo("No recorded operations found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOPERATION\tWORKSPACE\tRESULT\tDURATION\tACTOR\tARGS")
+	fmt.Fprintln(w, "----\t---------\t---------\t------\t--------\t-----\t----")
+	for _, event := range events {
+		result := "ok"
+		if !event.Success {
+			result = "failed"
+		}
+		actor := event.Actor
+		if actor == "" {
+			actor = event.User
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.Time.Local().Format("2006-01-02 15:04:05"),
+			event.Operation,
+			valueOrDash(event.Workspace),
+			result,
+			event.Duration.Round(time.Millisecond),
+			valueOrDash(actor),
+			formatAuditArgs(event))
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	for _, event := range events {
+		if event.Error != "" {
+			fmt.Println()
+			output.PrintHeader("Errors")
+			break
+		}
+	}
+	for _, event := range events {
+		if event.Error != "" {
+			fmt.Printf("  %s %s: %s\n", event.Time.Local().Format("2006-01-02 15:04:05"), event.Operation, event.Error)
+		}
+	}
+
+	return nil
+}
+
+func formatAuditArgs(event wsm.AuditEvent) string {
+	parts := append([]string{}, event.Args...)
+	names := make([]string, 0, len(event.Flags))
+	for name := range event.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("--%s=%s", name, event.Flags[name]))
+	}
+	return valueOrDash(strings.Join(parts, " "))
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,142 @@
+package cmds
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewHooksCommand creates the hooks command
+func NewHooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage the git hooks shared by the repositories of a workspace",
+		Long: `Git hooks are deployed to every worktree of a workspace, so lint and
+commit-msg hooks apply to all of its repositories alike. They come from the
+githooks directory in the workspace-manager config directory
+(~/.config/workspace-manager/githooks) and from the .wsm/githooks directory of
+each repository, whose hooks win over the shared ones of the same name.
+
+Hooks are copied, together with the hooks the repository already has (e.g.
+those of Git LFS), to .wsm/githooks/<repo> of the workspace, and the worktree's
+core.hooksPath points there. Repositories added to the workspace get them too.
+Create a workspace with --no-hooks to skip them.
+
+Examples:
+  # Show the hooks of the current workspace
+  wsm hooks list
+
+  # Deploy the hooks again after changing them
+  wsm hooks sync`,
+	}
+
+	cmd.AddCommand(
+		newHooksListCommand(),
+		newHooksSyncCommand(),
+	)
+
+	return cmd
+}
+
+func newHooksListCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "list [workspace-name]",
+		Short: "List the git hooks deployed to a workspace",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return errors.Errorf("unsupported output format: %s", format)
+			}
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			workspace, err := namedOrCurrentWorkspace(workspaceName, "wsm hooks list <workspace-name>")
+			if err != nil {
+				return err
+			}
+
+			deployments := workspace.DeployedHooks()
+			if format == "json" {
+				return wsm.PrintJSON(deployments)
+			}
+			if workspace.NoHooks {
+				output.PrintInfo("Workspace '%s' was created with --no-hooks", workspace.Name)
+				return nil
+			}
+			if len(deployments) == 0 {
+				templateDir, _ := wsm.GetHooksTemplateDir()
+				output.PrintInfo("No hooks are deployed to workspace '%s' (add hooks to %s or .wsm/githooks of a repository)", workspace.Name, templateDir)
+				return nil
+			}
+			printHookDeployments(workspace, deployments)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func newHooksSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [workspace-name]",
+		Short: "Deploy the current git hooks to every worktree of a workspace",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: audited("hooks-sync", 0, func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			workspace, err := namedOrCurrentWorkspace(workspaceName, "wsm hooks sync <workspace-name>")
+			if err != nil {
+				return err
+			}
+			wm, err := wsm.NewWorkspaceManager()
+			if err != nil {
+				return errors.Wrap(err, "failed to create workspace manager")
+			}
+
+			deployments, err := wm.SyncHooks(cmd.Context(), workspace)
+			if err != nil {
+				return err
+			}
+			if len(deployments) == 0 {
+				output.PrintInfo("No hooks to deploy to workspace '%s'", workspace.Name)
+				return nil
+			}
+			printHookDeployments(workspace, deployments)
+			output.PrintSuccess("Synced the hooks of workspace '%s'", workspace.Name)
+			return nil
+		}),
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+// printHookDeployments lists the hooks of every repository
+func printHookDeployments(workspace *wsm.Workspace, deployments []wsm.HookDeployment) {
+	output.PrintHeader("Workspace: %s", workspace.Name)
+	for _, deployment := range deployments {
+		if deployment.Removed {
+			fmt.Printf("  %s: hooks removed\n", deployment.Repository)
+			continue
+		}
+		fmt.Printf("  %s: %s\n", deployment.Repository, strings.Join(deployment.Hooks, ", "))
+	}
+}
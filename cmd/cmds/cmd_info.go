@@ -59,11 +59,19 @@ Examples:
 	}
 
 	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
-	cmd.Flags().StringVar(&outputField, "field", "", "Output specific field only (path, name, branch, repositories, created, date, time)")
+	cmd.Flags().StringVar(&outputField, "field", "", "Output specific field only (path, name, branch, ticket, repositories, created, date, time)")
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
 
 	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
 
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+			"field":     carapace.ActionValues("path", "name", "branch", "ticket", "repositories", "created", "date", "time"),
+		},
+	)
+
 	return cmd
 }
 
@@ -110,6 +118,8 @@ func printField(workspace *wsm.Workspace, field string) error {
 		fmt.Println(workspace.Name)
 	case "branch":
 		fmt.Println(workspace.Branch)
+	case "ticket":
+		fmt.Println(workspace.Ticket)
 	case "repositories":
 		fmt.Println(len(workspace.Repositories))
 	case "created":
@@ -119,7 +129,7 @@ func printField(workspace *wsm.Workspace, field string) error {
 	case "time":
 		fmt.Println(workspace.Created.Format("15:04:05"))
 	default:
-		return errors.Errorf("unknown field: %s. Available fields: path, name, branch, repositories, created, date, time", field)
+		return errors.Errorf("unknown field: %s. Available fields: path, name, branch, ticket, repositories, created, date, time", field)
 	}
 	return nil
 }
@@ -129,14 +139,67 @@ func printInfoTable(workspace *wsm.Workspace) error {
 	fmt.Printf("  Name:         %s\n", workspace.Name)
 	fmt.Printf("  Path:         %s\n", workspace.Path)
 	fmt.Printf("  Branch:       %s\n", workspace.Branch)
+	if workspace.Ticket != "" {
+		ticket := workspace.Ticket
+		if workspace.TicketURL != "" {
+			ticket += " (" + workspace.TicketURL + ")"
+		}
+		fmt.Printf("  Ticket:       %s\n", ticket)
+	}
 	fmt.Printf("  Repositories: %d\n", len(workspace.Repositories))
 	fmt.Printf("  Created:      %s\n", workspace.Created.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Go Workspace: %t\n", workspace.GoWorkspace)
+	if len(workspace.GitConfig) > 0 {
+		fmt.Printf("  Git Config:\n")
+		for _, key := range workspace.GitConfigKeys() {
+			fmt.Printf("    %s=%s\n", key, workspace.GitConfig[key])
+		}
+	}
 
 	if len(workspace.Repositories) > 0 {
 		output.PrintHeader("\nRepositories")
 		for _, repo := range workspace.Repositories {
 			fmt.Printf("  - %s (%s)\n", repo.Name, repo.RemoteURL)
+			if origin, ok := workspace.OriginFor(repo.Name); ok {
+				from := origin.BaseSHA
+				if len(from) > 7 {
+					from = from[:7]
+				}
+				if origin.BaseBranch != "" {
+					from = fmt.Sprintf("%s (%s)", origin.BaseBranch, from)
+				}
+				fmt.Printf("      created from %s on %s\n", from, origin.Created.Format("2006-01-02 15:04"))
+			}
+		}
+	}
+
+	if len(workspace.AuxiliaryPaths) > 0 {
+		output.PrintHeader("\nAuxiliary Paths")
+		for _, aux := range workspace.AuxiliaryPaths {
+			if aux.Linked() {
+				fmt.Printf("  - %s -> %s\n", aux.Name, aux.Target)
+			} else {
+				fmt.Printf("  - %s\n", aux.Name)
+			}
+		}
+	}
+
+	if len(workspace.PendingRepositories) > 0 {
+		output.PrintHeader("\nPending Repositories")
+		for _, pending := range workspace.PendingRepositories {
+			fmt.Printf("  - %s (%s)\n", pending.Repository.Name, pending.Error)
+		}
+		output.PrintInfo("Retry with: wsm add %s --retry-pending", workspace.Name)
+	}
+
+	if len(workspace.Releases) > 0 {
+		output.PrintHeader("\nReleases")
+		for _, release := range workspace.Releases {
+			pushed := ""
+			if !release.Pushed {
+				pushed = ", not pushed"
+			}
+			fmt.Printf("  - %s (%s, %d repositories%s)\n", release.Version, release.Created.Format("2006-01-02 15:04"), len(release.Tags), pushed)
 		}
 	}
 
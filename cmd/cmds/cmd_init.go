@@ -0,0 +1,210 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewInitCommand creates the init command
+func NewInitCommand() *cobra.Command {
+	var (
+		name         string
+		repos        []string
+		all          bool
+		dryRun       bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init [directory]",
+		Short: "Import a directory of existing git worktrees as a workspace",
+		Long: `Turn a directory of hand-made git worktrees into a wsm workspace.
+
+Every subdirectory with a .git file is resolved to the repository it is a
+worktree of and to its branch. After the worktrees to import are confirmed,
+source repositories missing from the registry are registered, and the workspace
+configuration and .wsm/wsm.json are written. Nothing in the worktrees changes.
+
+A worktree directory must be named like its repository, as in workspaces
+created by wsm. The workspace branch is the one most worktrees are on.
+
+Examples:
+  # Import the current directory, choosing the worktrees interactively
+  wsm init
+
+  # Import every worktree under a directory under another name
+  wsm init ~/work/old-feature --name old-feature --all
+
+  # Only show what would be imported
+  wsm init ~/work/old-feature --dry-run --output json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: audited("init", -1, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			if all && len(repos) > 0 {
+				return errors.New("--all cannot be combined with --repos")
+			}
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			return runInit(cmd.Context(), dir, name, repos, all, dryRun, outputFormat)
+		}),
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Workspace name (default: the directory name)")
+	cmd.Flags().StringSliceVar(&repos, "repos", nil, "Worktree directories to import (comma-separated, default: ask)")
+	cmd.Flags().BoolVar(&all, "all", false, "Import every worktree found without asking")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Only show the worktrees that would be imported")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionDirectories())
+
+	return cmd
+}
+
+func runInit(ctx context.Context, dir, name string, repos []string, all, dryRun bool, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	plan, err := wm.ScanImport(ctx, dir, name)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" && dryRun {
+		return wsm.PrintJSON(plan)
+	}
+	if outputFormat == "table" {
+		if err := printImportPlan(plan); err != nil {
+			return err
+		}
+	}
+	importable := plan.Importable()
+	if len(importable) == 0 {
+		return errors.Errorf("no worktrees to import in %s", plan.Path)
+	}
+	if dryRun {
+		return nil
+	}
+
+	selected := repos
+	if len(selected) == 0 {
+		if selected, err = selectImportedWorktrees(importable, all); err != nil {
+			if errors.Is(err, ux.ErrAborted) {
+				output.PrintInfo("Operation cancelled.")
+				return nil
+			}
+			return err
+		}
+	}
+
+	workspace, err := wm.ImportWorkspace(ctx, plan, selected)
+	if err != nil {
+		return errors.Wrap(err, "failed to import workspace")
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(workspace)
+	}
+	output.PrintSuccess("Imported %s as workspace '%s'", workspace.Path, workspace.Name)
+	fmt.Printf("  Repositories: %s\n", strings.Join(getRepositoryNames(workspace.Repositories), ", "))
+	if workspace.Branch != "" {
+		fmt.Printf("  Branch: %s\n", workspace.Branch)
+	}
+	return nil
+}
+
+// selectImportedWorktrees asks which worktrees to import, or takes all of them
+func selectImportedWorktrees(importable []wsm.ImportedWorktree, all bool) ([]string, error) {
+	var names []string
+	for _, worktree := range importable {
+		names = append(names, worktree.Name)
+	}
+	if all {
+		return names, nil
+	}
+	if err := wsm.RequireInteractive("Choosing the worktrees to import", "--all or --repos"); err != nil {
+		return nil, err
+	}
+
+	var options []ux.Option
+	for _, worktree := range importable {
+		label := fmt.Sprintf("%s (%s, %s)", worktree.Name, worktree.Branch, worktree.SourcePath)
+		if worktree.Branch == "" {
+			label = fmt.Sprintf("%s (detached, %s)", worktree.Name, worktree.SourcePath)
+		}
+		options = append(options, ux.NewOption(label, worktree.Name))
+	}
+	selected, err := ux.MultiSelect("Choose worktrees to import:", options)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no worktrees selected")
+	}
+	return selected, nil
+}
+
+func printImportPlan(plan *wsm.ImportPlan) error {
+	output.PrintHeader("Worktrees in %s", plan.Path)
+	if len(plan.Worktrees) == 0 {
+		output.PrintWarning("No git worktrees found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DIRECTORY\tBRANCH\tSOURCE\tREGISTRY")
+	fmt.Fprintln(w, "---------\t------\t------\t--------")
+	for _, worktree := range plan.Worktrees {
+		branch := worktree.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		registry := "registered"
+		if !worktree.Registered {
+			registry = "will be registered"
+		}
+		if worktree.Problem != "" {
+			registry = "skipped"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", worktree.Name, branch, worktree.SourcePath, registry)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	for _, worktree := range plan.Worktrees {
+		if worktree.Problem != "" {
+			output.PrintWarning("%s: %s", worktree.Name, worktree.Problem)
+		}
+	}
+	if len(plan.Other) > 0 {
+		output.PrintInfo("Not worktrees, left as they are: %s (see 'wsm aux')", strings.Join(plan.Other, ", "))
+	}
+	fmt.Printf("\nWorkspace '%s'", plan.Name)
+	if plan.Branch != "" {
+		fmt.Printf(" on branch %s", plan.Branch)
+	}
+	fmt.Println()
+	fmt.Println()
+	return nil
+}
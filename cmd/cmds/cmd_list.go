@@ -1,13 +1,15 @@
 package cmds
 
 import (
+	"context"
 	"fmt"
-	"github.com/go-go-golems/workspace-manager/pkg/output"
-	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
-	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/pkg/errors"
@@ -49,7 +51,8 @@ func NewListReposCommand() *cobra.Command {
 
 	carapace.Gen(cmd).FlagCompletion(
 		carapace.ActionMap{
-			"tags": TagCompletion(),
+			"tags":   TagCompletion().UniqueList(","),
+			"format": carapace.ActionValues("table", "json"),
 		},
 	)
 
@@ -57,18 +60,76 @@ func NewListReposCommand() *cobra.Command {
 }
 
 func NewListWorkspacesCommand() *cobra.Command {
-	var format string
+	var (
+		format     string
+		jsonOutput bool
+		query      wsm.SummaryQuery
+		noDisk     bool
+		idle       string
+	)
 
 	cmd := &cobra.Command{
 		Use:   "workspaces",
 		Short: "List created workspaces",
-		Long:  "List all created workspaces, sorted by creation date (newest first).",
+		Long: `List all created workspaces with their branch, repositories, creation date,
+last activity, dirty repositories and disk usage, newest first.
+
+Dirty counts come from the status cache shared with shell prompts and are
+recomputed when older than --max-age. Measuring the last activity (the newest
+file change) and disk usage walks every workspace; --no-disk skips it.
+
+Examples:
+  # Largest workspaces first
+  wsm list workspaces --sort size
+
+  # Workspaces with uncommitted changes that use app
+  wsm list workspaces --dirty --repo app
+
+  # Workspaces idle for two weeks, oldest activity first
+  wsm list workspaces --idle 2w --sort activity --reverse
+
+  # As JSON
+  wsm list workspaces --json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runListWorkspaces(format)
+			if jsonOutput {
+				format = "json"
+			}
+			if format != "table" && format != "json" {
+				return errors.Errorf("unsupported format: %s", format)
+			}
+			if idle != "" {
+				age, err := parseAge(idle)
+				if err != nil {
+					return err
+				}
+				if noDisk {
+					return errors.New("--idle measures the last activity and cannot be combined with --no-disk")
+				}
+				query.IdleFor = age
+			}
+			query.Disk = !noDisk
+			return runListWorkspaces(cmd.Context(), query, format)
 		},
 	}
 
 	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Shorthand for --format json")
+	cmd.Flags().StringVar(&query.Sort, "sort", wsm.SummarySortCreated, "Sort by "+strings.Join(wsm.SummarySortKeys, ", "))
+	cmd.Flags().BoolVar(&query.Reverse, "reverse", false, "Reverse the sort order")
+	cmd.Flags().BoolVar(&query.DirtyOnly, "dirty", false, "Only list workspaces with uncommitted changes")
+	cmd.Flags().StringVar(&query.Repository, "repo", "", "Only list workspaces using this repository")
+	cmd.Flags().StringVar(&idle, "idle", "", "Only list workspaces without file changes for this long (e.g. 30d, 2w, 36h)")
+	cmd.Flags().BoolVar(&noDisk, "no-disk", false, "Skip measuring disk usage and last activity")
+	cmd.Flags().DurationVar(&query.MaxStatusAge, "max-age", time.Minute, "Recompute cached statuses older than this")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"format": carapace.ActionValues("table", "json"),
+			"sort":   carapace.ActionValues(wsm.SummarySortKeys...),
+			"repo":   RepositoryNameCompletion(),
+			"idle":   carapace.ActionValues("7d", "14d", "30d", "60d"),
+		},
+	)
 
 	return cmd
 }
@@ -107,30 +168,24 @@ func runListRepos(format string, tags []string) error {
 	}
 }
 
-func runListWorkspaces(format string) error {
-	workspaces, err := wsm.LoadWorkspaces()
+func runListWorkspaces(ctx context.Context, query wsm.SummaryQuery, format string) error {
+	summaries, err := wsm.NewWorkspaceService("").SummarizeWorkspaces(ctx, query)
 	if err != nil {
-		return errors.Wrap(err, "failed to load workspaces")
+		return err
 	}
 
-	if len(workspaces) == 0 {
-		output.PrintInfo("No workspaces found. Use 'workspace-manager create' to create a workspace")
-		return nil
+	if format == "json" {
+		return wsm.PrintJSON(summaries)
 	}
-
-	// Sort workspaces by creation date descending (newest first)
-	sort.Slice(workspaces, func(i, j int) bool {
-		return workspaces[i].Created.After(workspaces[j].Created)
-	})
-
-	switch format {
-	case "table":
-		return printWorkspacesTable(workspaces)
-	case "json":
-		return printWorkspacesJSON(workspaces)
-	default:
-		return errors.Errorf("unsupported format: %s", format)
+	if len(summaries) == 0 {
+		if query.Repository != "" || query.DirtyOnly || query.IdleFor > 0 {
+			output.PrintInfo("No workspaces match the filters")
+		} else {
+			output.PrintInfo("No workspaces found. Use 'workspace-manager create' to create a workspace")
+		}
+		return nil
 	}
+	return printWorkspacesTable(summaries, query.Disk)
 }
 
 func printReposTable(repos []wsm.Repository) error {
@@ -159,9 +214,14 @@ func printReposTable(repos []wsm.Repository) error {
 			remote = "..." + remote[len(remote)-47:]
 		}
 
+		path := repo.Path
+		if repo.IsRemoteOnly() {
+			path = "(not cloned)"
+		}
+
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
 			repo.Name,
-			repo.Path,
+			path,
 			repo.CurrentBranch,
 			tags,
 			remote,
@@ -175,7 +235,7 @@ func printReposJSON(repos []wsm.Repository) error {
 	return wsm.PrintJSON(repos)
 }
 
-func printWorkspacesTable(workspaces []wsm.Workspace) error {
+func printWorkspacesTable(summaries []wsm.WorkspaceSummary, disk bool) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	defer func() {
 		if err := w.Flush(); err != nil {
@@ -187,31 +247,39 @@ func printWorkspacesTable(workspaces []wsm.Workspace) error {
 		}
 	}()
 
-	fmt.Fprintln(w, "NAME\tPATH\tREPOS\tBRANCH\tCREATED")
-	fmt.Fprintln(w, "----\t----\t-----\t------\t-------")
+	fmt.Fprintln(w, "NAME\tBRANCH\tREPOS\tCREATED\tACTIVITY\tDIRTY\tSIZE")
+	fmt.Fprintln(w, "----\t------\t-----\t-------\t--------\t-----\t----")
 
-	for _, workspace := range workspaces {
-		repoNames := make([]string, len(workspace.Repositories))
-		for i, repo := range workspace.Repositories {
-			repoNames[i] = repo.Name
+	for _, summary := range summaries {
+		repos := fmt.Sprintf("%d", len(summary.Repositories))
+		if names := strings.Join(summary.Repositories, ","); len(names) <= 30 {
+			repos += " (" + names + ")"
 		}
-		repos := strings.Join(repoNames, ",")
-		if len(repos) > 30 {
-			repos = repos[:27] + "..."
+
+		activity, size := "-", "-"
+		if disk && !summary.Missing {
+			activity = formatRelativeTime(summary.LastActivity)
+			size = wsm.FormatBytes(summary.Size)
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			workspace.Name,
-			workspace.Path,
+		dirty := fmt.Sprintf("%d", summary.Dirty)
+		switch {
+		case summary.Missing:
+			dirty = "missing"
+		case summary.Conflicts > 0:
+			dirty += fmt.Sprintf(" (%d conflicted)", summary.Conflicts)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			summary.Name,
+			summary.Branch,
 			repos,
-			workspace.Branch,
-			workspace.Created.Format("2006-01-02 15:04"),
+			summary.Created.Format("2006-01-02 15:04"),
+			activity,
+			dirty,
+			size,
 		)
 	}
 
 	return nil
 }
-
-func printWorkspacesJSON(workspaces []wsm.Workspace) error {
-	return wsm.PrintJSON(workspaces)
-}
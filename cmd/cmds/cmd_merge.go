@@ -6,10 +6,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
-	"github.com/charmbracelet/huh"
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -22,6 +25,15 @@ func NewMergeCommand() *cobra.Command {
 		force         bool
 		workspace     string
 		keepWorkspace bool
+		confirm       bool
+		ci            bool
+		strategy      string
+		squash        bool
+		rebase        bool
+		ffOnly        bool
+		autoRebase    bool
+		requireChecks bool
+		skipChecks    bool
 	)
 
 	cmd := &cobra.Command{
@@ -33,14 +45,36 @@ This command:
 1. Detects the current workspace (if not specified)
 2. Verifies the workspace is a fork (has a base branch)
 3. Checks if a workspace exists for the base branch and enforces running from within it
-4. Checks that all repositories are clean before merging
+4. Checks that all repositories are clean and that every workspace branch
+   contains the latest base branch (and, with --require-checks, has an open pull
+   request whose checks passed), reporting the readiness of every repository
 5. For each repository:
    - Switches to the base branch
-   - Merges the workspace branch into the base branch
+   - Merges the workspace branch into the base branch using the merge strategy
    - Pushes the merged changes
-6. Optionally deletes the workspace after successful merge
+6. Optionally triggers CI on the base branch (--ci, see 'wsm ci')
+7. Optionally deletes the workspace after successful merge
 
-The command handles merge conflicts gracefully and provides rollback on failure.
+The command handles merge conflicts gracefully. On failure, the base branches
+of the repositories merged so far are reset to the commits they were on before
+the merge, unpushed local commits included.
+
+A branch behind its base branch blocks the merge unless --auto-rebase rebases
+it first; with the rebase strategy it is rebased anyway. Nothing is merged
+unless every repository is ready.
+
+When checks.yaml enforces checks before merge (see 'wsm check'), they run
+before anything is merged and a failure stops the merge, unless --skip-checks.
+
+Merge strategies:
+  merge    A plain git merge (the default)
+  squash   One commit per repository, with a message listing the squashed commits
+  rebase   Rebase the workspace branch onto the base branch, then fast-forward
+  ff-only  Only fast-forward; fails when the base branch moved on
+
+The strategy is taken from --strategy (or --squash, --rebase, --ff-only), then
+from the workspace, set with 'wsm create --merge-strategy', 'wsm fork
+--merge-strategy' or the merge-strategy field of a manifest.
 
 IMPORTANT: If there's an existing workspace for the base branch, you must run this
 command from within that workspace to avoid git worktree conflicts. The command
@@ -60,21 +94,68 @@ Examples:
   workspace-manager merge --force
 
   # Merge but keep the workspace (don't delete)
-  workspace-manager merge --keep-workspace`,
+  workspace-manager merge --keep-workspace
+
+  # Merge into a base branch declared protected in policy.yaml
+  workspace-manager merge --confirm
+
+  # Merge and run CI on the base branch
+  workspace-manager merge --ci
+
+  # Squash the workspace branch into one commit per repository
+  workspace-manager merge --squash
+
+  # Rebase the workspace branch and fast-forward the base branch
+  workspace-manager merge --rebase
+
+  # Rebase stale branches first and require green pull request checks
+  workspace-manager merge --auto-rebase --require-checks`,
 		Args: cobra.MaximumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("merge", 0, func(cmd *cobra.Command, args []string) error {
 			workspaceName := workspace
 			if len(args) > 0 {
 				workspaceName = args[0]
 			}
-			return runMerge(cmd.Context(), workspaceName, dryRun, force, keepWorkspace)
-		},
+			switch {
+			case squash:
+				strategy = wsm.MergeStrategySquash
+			case rebase:
+				strategy = wsm.MergeStrategyRebase
+			case ffOnly:
+				strategy = wsm.MergeStrategyFFOnly
+			}
+			if err := wsm.ValidateMergeStrategy(strategy); err != nil {
+				return err
+			}
+			gate := wsm.MergeGateOptions{Rebase: autoRebase, RequireChecks: requireChecks, DryRun: dryRun}
+			return runMerge(cmd.Context(), workspaceName, strategy, gate, dryRun, force, keepWorkspace, confirm, ci, skipChecks)
+		}),
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be merged without executing")
 	cmd.Flags().BoolVar(&force, "force", false, "Skip confirmation prompts")
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
 	cmd.Flags().BoolVar(&keepWorkspace, "keep-workspace", false, "Keep the workspace after merge (don't delete it)")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Allow merging into protected branches")
+	cmd.Flags().BoolVar(&ci, "ci", false, "Trigger CI on the base branch after merging")
+	cmd.Flags().StringVar(&strategy, "strategy", "", "Merge strategy: merge, squash, rebase or ff-only (default: the workspace's, or merge)")
+	cmd.Flags().BoolVar(&squash, "squash", false, "Squash the workspace branch into one commit per repository (--strategy squash)")
+	cmd.Flags().BoolVar(&rebase, "rebase", false, "Rebase the workspace branch onto the base branch, then fast-forward (--strategy rebase)")
+	cmd.Flags().BoolVar(&ffOnly, "ff-only", false, "Only fast-forward the base branch (--strategy ff-only)")
+	cmd.MarkFlagsMutuallyExclusive("strategy", "squash", "rebase", "ff-only")
+	cmd.Flags().BoolVar(&autoRebase, "auto-rebase", false, "Rebase workspace branches behind their base branch before merging instead of failing")
+	cmd.Flags().BoolVar(&requireChecks, "require-checks", false, "Require an open pull request with passing checks in every repository (needs gh)")
+	cmd.Flags().BoolVar(&skipChecks, "skip-checks", false, "Merge without running the checks checks.yaml enforces before merge")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"strategy":  carapace.ActionValues(wsm.MergeStrategies...),
+		},
+	)
 
 	return cmd
 }
@@ -88,7 +169,7 @@ type MergeCandidate struct {
 	IsClean       bool
 }
 
-func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWorkspace bool) error {
+func runMerge(ctx context.Context, workspaceName, strategy string, gate wsm.MergeGateOptions, dryRun, force, keepWorkspace, confirm, ci, skipChecks bool) error {
 	// Detect workspace if not specified
 	if workspaceName == "" {
 		cwd, err := os.Getwd()
@@ -109,15 +190,31 @@ func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWork
 		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
 	}
 
-	// Verify this is a forked workspace
-	if workspace.BaseBranch == "" {
-		return errors.New("workspace is not a fork (no base branch specified). Only forked workspaces can be merged.")
+	// Every repository needs a branch to merge into: the workspace base branch,
+	// or the branch its worktree was created from or the default branch detected then
+	var untargeted []string
+	for _, repo := range workspace.Repositories {
+		if mergeTargetBranch(workspace, repo.Name) == "" {
+			untargeted = append(untargeted, repo.Name)
+		}
 	}
+	if len(untargeted) > 0 {
+		return errors.Errorf("no base branch specified or detected for %s. Only workspaces with a base branch can be merged.", strings.Join(untargeted, ", "))
+	}
+	targets := mergeTargets(workspace)
 
-	// Check if there's a workspace for the base branch
-	baseWorkspace, err := findWorkspaceByBranch(workspace.BaseBranch)
-	if err != nil {
-		return errors.Wrapf(err, "failed to check for base branch workspace")
+	// Check if there's a workspace for a base branch
+	var baseWorkspace *wsm.Workspace
+	var baseBranch string
+	for _, target := range targets {
+		baseWorkspace, err = findWorkspaceByBranch(target)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for base branch workspace")
+		}
+		if baseWorkspace != nil {
+			baseBranch = target
+			break
+		}
 	}
 
 	// If there's a workspace for the base branch, ensure we're running from within it
@@ -128,15 +225,18 @@ func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWork
 		}
 
 		// Check if current directory is within the base workspace
-		if !strings.HasPrefix(cwd, baseWorkspace.Path) {
+		if !wsm.PathWithin(cwd, baseWorkspace.Path) {
 			return errors.Errorf("found workspace '%s' for base branch '%s'. Please run the merge command from within that workspace (at %s) to avoid git worktree conflicts",
-				baseWorkspace.Name, workspace.BaseBranch, baseWorkspace.Path)
+				baseWorkspace.Name, baseBranch, baseWorkspace.Path)
 		}
 
 		output.PrintInfo("✓ Running merge from base workspace '%s' as required", baseWorkspace.Name)
 	}
 
-	output.PrintInfo("Merging workspace '%s' (branch: %s → %s)", workspace.Name, workspace.Branch, workspace.BaseBranch)
+	strategy = workspace.MergeStrategyOrDefault(strategy)
+	output.PrintInfo("Merging workspace '%s' (branch: %s → %s, strategy: %s)", workspace.Name, workspace.Branch, strings.Join(targets, ", "), strategy)
+
+	refreshStaleRepositories(ctx, workspace, wsm.FreshnessMerge, 0, false)
 
 	// Get workspace status to verify readiness for merge
 	checker := wsm.NewStatusChecker()
@@ -153,7 +253,7 @@ func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWork
 		candidate := MergeCandidate{
 			Repository:    repoStatus.Repository,
 			WorktreePath:  filepath.Join(workspace.Path, repoStatus.Repository.Name),
-			BaseBranch:    workspace.BaseBranch,
+			BaseBranch:    mergeTargetBranch(workspace, repoStatus.Repository.Name),
 			CurrentBranch: repoStatus.CurrentBranch,
 			HasChanges:    repoStatus.HasChanges,
 			IsClean:       !repoStatus.HasChanges && len(repoStatus.StagedFiles) == 0 && len(repoStatus.UntrackedFiles) == 0,
@@ -173,19 +273,37 @@ func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWork
 
 	// Verify all repositories are on the workspace branch
 	for _, candidate := range candidates {
-		if candidate.CurrentBranch != workspace.Branch {
+		if branch := workspace.BranchFor(candidate.Repository.Name); candidate.CurrentBranch != branch {
 			return errors.Errorf("repository '%s' is on branch '%s', expected '%s'. Switch all repositories to the workspace branch first",
-				candidate.Repository.Name, candidate.CurrentBranch, workspace.Branch)
+				candidate.Repository.Name, candidate.CurrentBranch, branch)
 		}
 	}
 
+	// Enforce the branch policy before touching any repository
+	if err := checkMergePolicy(candidates, confirm); err != nil {
+		return err
+	}
+
+	// The rebase strategy brings branches up to date itself
+	gate.AllowBehind = strategy == wsm.MergeStrategyRebase
+	if err := checkMergeReadiness(ctx, workspace, gate); err != nil {
+		return err
+	}
+
 	if dryRun {
-		return previewMerge(workspace, candidates)
+		return previewMerge(workspace, candidates, strategy)
+	}
+
+	if err := enforceChecks(ctx, workspace, wsm.CheckBeforeMerge, skipChecks); err != nil {
+		return err
 	}
 
 	// Ask for confirmation unless force is set
 	if !force {
-		confirmed, err := confirmMerge(workspace, candidates, keepWorkspace)
+		if err := wsm.RequireInteractive("Merging", "--force"); err != nil {
+			return err
+		}
+		confirmed, err := confirmMerge(workspace, candidates, strategy, keepWorkspace)
 		if err != nil {
 			return errors.Wrap(err, "failed to get user confirmation")
 		}
@@ -196,10 +314,74 @@ func runMerge(ctx context.Context, workspaceName string, dryRun, force, keepWork
 	}
 
 	// Execute merge
-	return executeMerge(ctx, workspace, candidates, keepWorkspace)
+	return executeMerge(ctx, workspace, candidates, strategy, keepWorkspace, ci)
+}
+
+// checkMergePolicy reports every candidate whose merge into the base branch the policy forbids
+func checkMergePolicy(candidates []MergeCandidate, confirm bool) error {
+	policy, err := wsm.LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	var violations []string
+	for _, candidate := range candidates {
+		if err := policy.Check(wsm.PolicyCheck{
+			Action:     wsm.PolicyActionMerge,
+			Repository: candidate.Repository.Name,
+			Branch:     candidate.BaseBranch,
+			Confirmed:  confirm,
+			Clean:      candidate.IsClean,
+		}); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", candidate.Repository.Name, err))
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.Errorf("merge blocked by policy:\n  %s", strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// checkMergeReadiness prints the readiness report of every repository and fails
+// when any of them is not ready
+func checkMergeReadiness(ctx context.Context, workspace *wsm.Workspace, gate wsm.MergeGateOptions) error {
+	output.PrintInfo("Checking merge readiness...")
+	report := wsm.CheckMergeReadiness(ctx, workspace, gate)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tBASE\tBEHIND\tCHECKS\tSTATUS")
+	fmt.Fprintln(w, "----------\t----\t------\t------\t------")
+	var blocked []string
+	for _, readiness := range report {
+		status := "ready"
+		switch {
+		case !readiness.Ready():
+			status = strings.Join(readiness.Problems, "; ")
+			blocked = append(blocked, readiness.Repository)
+		case readiness.Rebased:
+			status = "ready (rebased)"
+		case readiness.Behind > 0 && gate.Rebase && gate.DryRun:
+			status = "ready (will be rebased)"
+		}
+		checks := readiness.Checks
+		if checks == "" {
+			checks = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", readiness.Repository, readiness.BaseRef, readiness.Behind, checks, status)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+	fmt.Println()
+
+	if len(blocked) > 0 {
+		return errors.Errorf("merge blocked, not ready: %s", strings.Join(blocked, ", "))
+	}
+	return nil
 }
 
-func previewMerge(workspace *wsm.Workspace, candidates []MergeCandidate) error {
+func previewMerge(workspace *wsm.Workspace, candidates []MergeCandidate, strategy string) error {
 	output.PrintHeader("📋 Merge Preview: %s", workspace.Name)
 	fmt.Println()
 
@@ -207,7 +389,8 @@ func previewMerge(workspace *wsm.Workspace, candidates []MergeCandidate) error {
 	fmt.Printf("  Name: %s\n", workspace.Name)
 	fmt.Printf("  Path: %s\n", workspace.Path)
 	fmt.Printf("  Current branch: %s\n", workspace.Branch)
-	fmt.Printf("  Base branch: %s\n", workspace.BaseBranch)
+	fmt.Printf("  Base branch: %s\n", strings.Join(mergeTargets(workspace), ", "))
+	fmt.Printf("  Merge strategy: %s\n", strategy)
 	fmt.Println()
 
 	output.PrintInfo("Merge Plan:")
@@ -218,23 +401,44 @@ func previewMerge(workspace *wsm.Workspace, candidates []MergeCandidate) error {
 		}
 
 		fmt.Printf("  %s (%s)\n", candidate.Repository.Name, status)
-		fmt.Printf("    Merge: %s → %s\n", workspace.Branch, workspace.BaseBranch)
-		fmt.Printf("    Push: %s to origin\n", workspace.BaseBranch)
+		fmt.Printf("    Merge (%s): %s → %s\n", strategy, workspace.Branch, candidate.BaseBranch)
+		fmt.Printf("    Push: %s to origin\n", candidate.BaseBranch)
 	}
 
 	fmt.Println()
 	output.PrintInfo("After successful merge:")
-	fmt.Printf("  - All repositories will have %s branch updated\n", workspace.BaseBranch)
+	fmt.Printf("  - All repositories will have %s branch updated\n", strings.Join(mergeTargets(workspace), ", "))
 	fmt.Printf("  - Changes will be pushed to origin\n")
 	fmt.Printf("  - Workspace will be deleted\n")
 
 	return nil
 }
 
-func confirmMerge(workspace *wsm.Workspace, candidates []MergeCandidate, keepWorkspace bool) (bool, error) {
+// mergeTargetBranch returns the branch a repository is merged into: the branch
+// its worktree was created from, or the workspace base branch
+func mergeTargetBranch(workspace *wsm.Workspace, repoName string) string {
+	if target := workspace.TargetBranchFor(repoName); target != "" {
+		return target
+	}
+	return workspace.BaseBranch
+}
+
+// mergeTargets lists the distinct branches the repositories are merged into
+func mergeTargets(workspace *wsm.Workspace) []string {
+	var targets []string
+	for _, repo := range workspace.Repositories {
+		if target := mergeTargetBranch(workspace, repo.Name); target != "" && !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+func confirmMerge(workspace *wsm.Workspace, candidates []MergeCandidate, strategy string, keepWorkspace bool) (bool, error) {
 	fmt.Printf("\n")
 	output.PrintWarning("You are about to merge workspace '%s'", workspace.Name)
-	fmt.Printf("  Branch: %s → %s\n", workspace.Branch, workspace.BaseBranch)
+	fmt.Printf("  Branch: %s → %s\n", workspace.Branch, strings.Join(mergeTargets(workspace), ", "))
+	fmt.Printf("  Strategy: %s\n", strategy)
 	fmt.Printf("  Repositories: %d\n", len(candidates))
 
 	if !keepWorkspace {
@@ -258,16 +462,10 @@ func confirmMerge(workspace *wsm.Workspace, candidates []MergeCandidate, keepWor
 		fmt.Printf("\nThese changes will be included in the merge.\n")
 	}
 
-	var confirmed bool
-	form := huh.NewForm(
-		huh.NewGroup(
-			huh.NewConfirm().
-				Title("Do you want to proceed with the merge?").
-				Value(&confirmed),
-		),
-	)
-
-	err := form.Run()
+	confirmed, err := ux.Confirm("Do you want to proceed with the merge?", "")
+	if errors.Is(err, ux.ErrAborted) {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -275,8 +473,20 @@ func confirmMerge(workspace *wsm.Workspace, candidates []MergeCandidate, keepWor
 	return confirmed, nil
 }
 
-func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []MergeCandidate, keepWorkspace bool) error {
+// mergeOutcome is the merge result sent to webhooks
+type mergeOutcome struct {
+	Strategy   string   `json:"strategy"`
+	BaseBranch string   `json:"base_branch"`
+	Merged     []string `json:"merged"`
+	Failed     string   `json:"failed,omitempty"`
+	// RolledBack is set when the merged repositories were reset after a failure
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []MergeCandidate, strategy string, keepWorkspace, ci bool) error {
 	output.PrintHeader("🔀 Executing Merge: %s", workspace.Name)
+	targets := mergeTargets(workspace)
+	baseBranch := strings.Join(targets, ", ")
 
 	var successfulMerges []string
 
@@ -284,16 +494,33 @@ func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []Me
 	for _, candidate := range candidates {
 		output.PrintInfo("Processing repository: %s", candidate.Repository.Name)
 
-		if err := mergeRepository(ctx, candidate); err != nil {
+		if err := mergeRepository(ctx, candidate, strategy); err != nil {
 			output.PrintError("Failed to merge repository %s: %v", candidate.Repository.Name, err)
 
+			deletePreMergeRef(ctx, candidate.WorktreePath, candidate.CurrentBranch)
+
 			// Rollback successful merges
 			if len(successfulMerges) > 0 {
 				output.PrintWarning("Rolling back successful merges due to failure...")
 				rollbackMerges(ctx, workspace, successfulMerges)
 			}
 
-			return errors.Wrapf(err, "merge failed for repository %s", candidate.Repository.Name)
+			err = errors.Wrapf(err, "merge failed for repository %s", candidate.Repository.Name)
+			wsm.Notify(ctx, wsm.Notification{
+				Event:     wsm.EventMergeFailed,
+				Workspace: workspace.Name,
+				Branch:    workspace.Branch,
+				Summary:   fmt.Sprintf("Merging %s into %s failed in %s", workspace.Branch, baseBranch, candidate.Repository.Name),
+				Error:     err.Error(),
+				Result: mergeOutcome{
+					Strategy:   strategy,
+					BaseBranch: baseBranch,
+					Merged:     successfulMerges,
+					Failed:     candidate.Repository.Name,
+					RolledBack: len(successfulMerges) > 0,
+				},
+			})
+			return err
 		}
 
 		successfulMerges = append(successfulMerges, candidate.Repository.Name)
@@ -301,6 +528,33 @@ func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []Me
 	}
 
 	output.PrintSuccess("All repositories merged successfully!")
+	wsm.Notify(ctx, wsm.Notification{
+		Event:     wsm.EventMergeSucceeded,
+		Workspace: workspace.Name,
+		Branch:    workspace.Branch,
+		Success:   true,
+		Summary:   fmt.Sprintf("Merged %s into %s in %d repositories (%s)", workspace.Branch, baseBranch, len(successfulMerges), strategy),
+		Result: mergeOutcome{
+			Strategy:   strategy,
+			BaseBranch: baseBranch,
+			Merged:     successfulMerges,
+		},
+	})
+	for _, candidate := range candidates {
+		deletePreMergeRef(ctx, candidate.WorktreePath, candidate.CurrentBranch)
+	}
+
+	// The worktrees are gone once the workspace is deleted, so CI is triggered first
+	switch {
+	case !ci:
+	case len(targets) > 1:
+		output.PrintWarning("Not triggering CI: the repositories were merged into different branches (%s)", baseBranch)
+	default:
+		output.PrintHeader("Triggering CI on %s", baseBranch)
+		if err := triggerCI(ctx, workspace, &wsm.CITriggerOptions{Ref: baseBranch, Event: "merge"}, false, 0, "table"); err != nil {
+			output.PrintWarning("Failed to trigger CI: %v", err)
+		}
+	}
 
 	// Delete workspace if requested
 	if !keepWorkspace {
@@ -323,7 +577,7 @@ func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []Me
 	output.PrintSuccess("Merge completed successfully!")
 	output.PrintInfo("Summary:")
 	fmt.Printf("  - Merged %d repositories\n", len(successfulMerges))
-	fmt.Printf("  - Branch %s merged into %s\n", workspace.Branch, workspace.BaseBranch)
+	fmt.Printf("  - Branch %s merged into %s (%s)\n", workspace.Branch, baseBranch, strategy)
 	fmt.Printf("  - Changes pushed to origin\n")
 	if !keepWorkspace {
 		fmt.Printf("  - Workspace deleted\n")
@@ -332,7 +586,7 @@ func executeMerge(ctx context.Context, workspace *wsm.Workspace, candidates []Me
 	return nil
 }
 
-func mergeRepository(ctx context.Context, candidate MergeCandidate) error {
+func mergeRepository(ctx context.Context, candidate MergeCandidate, strategy string) error {
 	repoPath := candidate.WorktreePath
 
 	log.Debug().
@@ -340,11 +594,15 @@ func mergeRepository(ctx context.Context, candidate MergeCandidate) error {
 		Str("repoPath", repoPath).
 		Str("currentBranch", candidate.CurrentBranch).
 		Str("baseBranch", candidate.BaseBranch).
+		Str("strategy", strategy).
 		Msg("Starting repository merge")
 
+	// The base branch comes from the upstream remote of forks, and the primary remote otherwise
+	baseRemote := candidate.Repository.BaseRemote()
+
 	// Step 1: Fetch latest changes
 	output.PrintInfo("  Fetching latest changes...")
-	if err := executeGitCommand(ctx, repoPath, "git", "fetch", "origin"); err != nil {
+	if err := executeGitCommand(ctx, repoPath, "git", "fetch", baseRemote); err != nil {
 		return errors.Wrap(err, "failed to fetch latest changes")
 	}
 
@@ -354,25 +612,26 @@ func mergeRepository(ctx context.Context, candidate MergeCandidate) error {
 		return errors.Wrapf(err, "failed to switch to base branch %s", candidate.BaseBranch)
 	}
 
+	// Record the base branch as it is now, local commits included, so a
+	// rollback restores exactly this commit
+	if err := executeGitCommand(ctx, repoPath, "git", "update-ref", preMergeRef(candidate.CurrentBranch), "HEAD"); err != nil {
+		return errors.Wrap(err, "failed to record the pre-merge base branch")
+	}
+
 	// Step 3: Pull latest base branch changes
-	output.PrintInfo("  Pulling latest base branch changes...")
-	if err := executeGitCommand(ctx, repoPath, "git", "pull", "origin", candidate.BaseBranch); err != nil {
+	output.PrintInfo("  Pulling latest base branch changes from %s...", baseRemote)
+	if err := executeGitCommand(ctx, repoPath, "git", "pull", baseRemote, candidate.BaseBranch); err != nil {
 		return errors.Wrapf(err, "failed to pull latest changes for %s", candidate.BaseBranch)
 	}
 
 	// Step 4: Merge workspace branch
-	output.PrintInfo("  Merging %s into %s...", candidate.CurrentBranch, candidate.BaseBranch)
-	if err := executeGitCommand(ctx, repoPath, "git", "merge", candidate.CurrentBranch); err != nil {
-		// Check if this is a merge conflict
-		if isGitMergeConflict(err) {
-			return errors.Errorf("merge conflict detected in %s. Please resolve conflicts manually and retry", candidate.Repository.Name)
-		}
-		return errors.Wrapf(err, "failed to merge %s into %s", candidate.CurrentBranch, candidate.BaseBranch)
+	if err := mergeWithStrategy(ctx, candidate, strategy); err != nil {
+		return err
 	}
 
 	// Step 5: Push merged changes
 	output.PrintInfo("  Pushing merged changes...")
-	if err := executeGitCommand(ctx, repoPath, "git", "push", "origin", candidate.BaseBranch); err != nil {
+	if err := executeGitCommand(ctx, repoPath, "git", "push", candidate.Repository.RemoteName(), candidate.BaseBranch); err != nil {
 		return errors.Wrapf(err, "failed to push merged changes for %s", candidate.BaseBranch)
 	}
 
@@ -383,7 +642,68 @@ func mergeRepository(ctx context.Context, candidate MergeCandidate) error {
 	return nil
 }
 
+// mergeWithStrategy merges the workspace branch into the checked out base branch
+func mergeWithStrategy(ctx context.Context, candidate MergeCandidate, strategy string) error {
+	repoPath := candidate.WorktreePath
+	branch, base := candidate.CurrentBranch, candidate.BaseBranch
+
+	switch strategy {
+	case wsm.MergeStrategyFFOnly:
+		output.PrintInfo("  Fast-forwarding %s to %s...", base, branch)
+		if err := executeGitCommand(ctx, repoPath, "git", "merge", "--ff-only", branch); err != nil {
+			return errors.Wrapf(err, "cannot fast-forward %s to %s, the branches diverged; merge with --rebase instead", base, branch)
+		}
+
+	case wsm.MergeStrategyRebase:
+		output.PrintInfo("  Rebasing %s onto %s...", branch, base)
+		if err := executeGitCommand(ctx, repoPath, "git", "rebase", base, branch); err != nil {
+			if abortErr := executeGitCommand(ctx, repoPath, "git", "rebase", "--abort"); abortErr != nil {
+				log.Debug().Err(abortErr).Str("repoPath", repoPath).Msg("Failed to abort rebase")
+			}
+			return errors.Wrapf(err, "failed to rebase %s onto %s in %s. Please rebase manually and retry", branch, base, candidate.Repository.Name)
+		}
+		if err := executeGitCommand(ctx, repoPath, "git", "checkout", base); err != nil {
+			return errors.Wrapf(err, "failed to switch back to base branch %s", base)
+		}
+		output.PrintInfo("  Fast-forwarding %s to %s...", base, branch)
+		if err := executeGitCommand(ctx, repoPath, "git", "merge", "--ff-only", branch); err != nil {
+			return errors.Wrapf(err, "failed to fast-forward %s to %s", base, branch)
+		}
+
+	case wsm.MergeStrategySquash:
+		output.PrintInfo("  Squashing %s into %s...", branch, base)
+		message := wsm.SquashMergeMessage(ctx, repoPath, branch, base)
+		if err := executeGitCommand(ctx, repoPath, "git", "merge", "--squash", branch); err != nil {
+			if isGitMergeConflict(err) {
+				return errors.Errorf("merge conflict detected in %s. Please resolve conflicts manually and retry", candidate.Repository.Name)
+			}
+			return errors.Wrapf(err, "failed to squash %s into %s", branch, base)
+		}
+		// Nothing is staged when the branch brings no changes
+		if err := executeGitCommand(ctx, repoPath, "git", "diff", "--cached", "--quiet"); err == nil {
+			output.PrintInfo("  Nothing to squash, %s has no changes", branch)
+			return nil
+		}
+		if err := executeGitCommand(ctx, repoPath, "git", "commit", "-m", message); err != nil {
+			return errors.Wrapf(err, "failed to commit the squashed changes of %s", branch)
+		}
+
+	default:
+		output.PrintInfo("  Merging %s into %s...", branch, base)
+		if err := executeGitCommand(ctx, repoPath, "git", "merge", branch); err != nil {
+			// Check if this is a merge conflict
+			if isGitMergeConflict(err) {
+				return errors.Errorf("merge conflict detected in %s. Please resolve conflicts manually and retry", candidate.Repository.Name)
+			}
+			return errors.Wrapf(err, "failed to merge %s into %s", branch, base)
+		}
+	}
+	return nil
+}
+
 func executeGitCommand(ctx context.Context, repoPath string, args ...string) error {
+	ctx, cancel := wsm.GitContext(ctx, args[1:]...)
+	defer cancel()
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Dir = repoPath
 
@@ -391,6 +711,7 @@ func executeGitCommand(ctx context.Context, repoPath string, args ...string) err
 	log.Debug().Str("command", cmdStr).Str("repoPath", repoPath).Msg("Executing git command")
 
 	cmdOutput, err := cmd.CombinedOutput()
+	err = wsm.GitError(ctx, err)
 	if err != nil {
 		log.Debug().
 			Str("command", cmdStr).
@@ -427,23 +748,27 @@ func rollbackMerges(ctx context.Context, workspace *wsm.Workspace, successfulMer
 
 	for _, repoName := range successfulMerges {
 		repoPath := filepath.Join(workspace.Path, repoName)
-
+		baseBranch := mergeTargetBranch(workspace, repoName)
+		branch := workspace.BranchFor(repoName)
+		ref := preMergeRef(branch)
 		output.PrintInfo("  Rolling back %s...", repoName)
 
-		// Reset base branch to origin state
-		if err := executeGitCommand(ctx, repoPath, "git", "checkout", workspace.BaseBranch); err != nil {
-			output.PrintWarning("    Failed to checkout %s: %v", workspace.BaseBranch, err)
+		// Reset base branch to the commit recorded before the merge
+		if err := executeGitCommand(ctx, repoPath, "git", "checkout", baseBranch); err != nil {
+			output.PrintWarning("    Failed to checkout %s: %v", baseBranch, err)
 			continue
 		}
 
-		if err := executeGitCommand(ctx, repoPath, "git", "reset", "--hard", "origin/"+workspace.BaseBranch); err != nil {
-			output.PrintWarning("    Failed to reset %s: %v", workspace.BaseBranch, err)
+		if err := executeGitCommand(ctx, repoPath, "git", "reset", "--hard", ref); err != nil {
+			output.PrintWarning("    Failed to reset %s to %s: %v", baseBranch, ref, err)
 			continue
 		}
+		deletePreMergeRef(ctx, repoPath, branch)
+		output.PrintWarning("    The merge was already pushed to %s/%s; revert it there if needed", workspace.RemoteOf(repoName), baseBranch)
 
 		// Switch back to workspace branch
-		if err := executeGitCommand(ctx, repoPath, "git", "checkout", workspace.Branch); err != nil {
-			output.PrintWarning("    Failed to checkout %s: %v", workspace.Branch, err)
+		if err := executeGitCommand(ctx, repoPath, "git", "checkout", branch); err != nil {
+			output.PrintWarning("    Failed to checkout %s: %v", branch, err)
 		}
 
 		output.PrintInfo("    ✓ Rolled back %s", repoName)
@@ -452,6 +777,21 @@ func rollbackMerges(ctx context.Context, workspace *wsm.Workspace, successfulMer
 	output.PrintInfo("🔄 Rollback completed")
 }
 
+// preMergeRef is the ref recording the base branch of a repository before the
+// workspace branch is merged into it. Worktrees share refs with their
+// repository, so the ref is named after the workspace branch.
+func preMergeRef(branch string) string {
+	return "refs/wsm/pre-merge/" + branch
+}
+
+// deletePreMergeRef removes the ref recorded by mergeRepository, if any
+func deletePreMergeRef(ctx context.Context, repoPath, branch string) {
+	ref := preMergeRef(branch)
+	if err := executeGitCommand(ctx, repoPath, "git", "update-ref", "-d", ref); err != nil {
+		log.Debug().Err(err).Str("repoPath", repoPath).Str("ref", ref).Msg("Failed to delete pre-merge ref")
+	}
+}
+
 // findWorkspaceByBranch finds a workspace that uses the given branch
 func findWorkspaceByBranch(branchName string) (*wsm.Workspace, error) {
 	workspaces, err := wsm.LoadWorkspaces()
@@ -0,0 +1,242 @@
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewCdCommand creates the cd command
+func NewCdCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cd [workspace] [repo]",
+		Short: "Print the directory of a workspace or one of its repositories",
+		Long: `Print the path of a workspace, or of a repository worktree inside it.
+
+A program cannot change the directory of its shell, so this command only
+prints the path. The function installed by 'wsm shell-init' wraps it so that
+'wsm cd' changes directory. Without arguments, the root of the current
+workspace is printed.
+
+Examples:
+  # With the shell integration
+  wsm cd my-feature app
+
+  # Without it
+  cd "$(wsm cd my-feature app)"`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := resolveCdPath(args)
+			if err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+		WorkspaceRepositoryCompletion(),
+	)
+
+	return cmd
+}
+
+// resolveCdPath returns the directory named by the workspace and repository arguments of 'wsm cd'
+func resolveCdPath(args []string) (string, error) {
+	var workspace *wsm.Workspace
+	if len(args) == 0 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get current directory")
+		}
+		if workspace, err = wsm.NewWorkspaceService("").DetectWorkspace(cwd); err != nil {
+			return "", errors.Wrap(err, "failed to detect workspace. Use 'wsm cd <workspace-name>'")
+		}
+	} else {
+		var err error
+		if workspace, err = loadWorkspace(args[0]); err != nil {
+			return "", errors.Wrapf(err, "workspace '%s' not found", args[0])
+		}
+	}
+
+	if len(args) < 2 {
+		return workspace.Path, nil
+	}
+	for _, repo := range workspace.Repositories {
+		if repo.Name == args[1] {
+			return filepath.Join(workspace.Path, repo.Name), nil
+		}
+	}
+	return "", errors.Errorf("repository '%s' not found in workspace '%s'", args[1], workspace.Name)
+}
+
+// whichContext describes where the current directory is
+type whichContext struct {
+	Workspace     string `json:"workspace"`
+	WorkspacePath string `json:"workspace_path"`
+	Branch        string `json:"branch"`
+	Repository    string `json:"repository,omitempty"`
+	WorktreePath  string `json:"worktree_path,omitempty"`
+	// RelativePath is the current directory inside the worktree
+	RelativePath string `json:"relative_path,omitempty"`
+}
+
+// NewWhichCommand creates the which command
+func NewWhichCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "which",
+		Short: "Show the workspace and repository of the current directory",
+		Long: `Show which workspace the current directory belongs to and, inside a
+repository worktree, which repository and where in it.
+
+Examples:
+  wsm which
+  wsm which --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			return runWhich(outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runWhich(outputFormat string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return errors.Wrap(err, "failed to get current directory")
+	}
+	workspace, err := wsm.NewWorkspaceService("").DetectWorkspace(cwd)
+	if err != nil {
+		return err
+	}
+
+	location := whichContext{
+		Workspace:     workspace.Name,
+		WorkspacePath: workspace.Path,
+		Branch:        workspace.Branch,
+	}
+	if repo, rel, ok := workspace.RepositoryAt(cwd); ok {
+		location.Repository = repo.Name
+		location.WorktreePath = filepath.Join(workspace.Path, repo.Name)
+		location.RelativePath = rel
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(location)
+	}
+
+	fmt.Printf("Workspace:  %s\n", location.Workspace)
+	fmt.Printf("Path:       %s\n", location.WorkspacePath)
+	if location.Branch != "" {
+		fmt.Printf("Branch:     %s\n", location.Branch)
+	}
+	if location.Repository != "" {
+		fmt.Printf("Repository: %s\n", location.Repository)
+		fmt.Printf("Worktree:   %s\n", location.WorktreePath)
+		if location.RelativePath != "." {
+			fmt.Printf("Directory:  %s\n", location.RelativePath)
+		}
+	}
+	return nil
+}
+
+// NewShellInitCommand creates the shell-init command
+func NewShellInitCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell-init <bash|zsh|fish>",
+		Short: "Print shell integration for 'wsm cd' and completion",
+		Long: `Print a snippet that defines a 'wsm' shell function and loads completion.
+
+The function runs 'wsm cd' and changes to the directory it prints; every other
+command is passed to the wsm binary unchanged. Completion is provided by
+carapace, as with 'source <(wsm _carapace)'.
+
+Add it to your shell configuration:
+  # ~/.bashrc
+  eval "$(wsm shell-init bash)"
+
+  # ~/.zshrc
+  eval "$(wsm shell-init zsh)"
+
+  # ~/.config/fish/config.fish
+  wsm shell-init fish | source`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippet, err := shellInitSnippet(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(snippet)
+			return nil
+		},
+	}
+
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionValues("bash", "zsh", "fish"))
+
+	return cmd
+}
+
+const posixShellInit = `# wsm shell integration: 'wsm cd' changes directory
+wsm() {
+  if [ "$1" = "cd" ]; then
+    local dir
+    dir="$(command wsm "$@")" || return
+    if [ -d "$dir" ]; then
+      builtin cd -- "$dir"
+    else
+      printf '%%s\n' "$dir"
+    fi
+  else
+    command wsm "$@"
+  fi
+}
+source <(command wsm _carapace %s)
+`
+
+const fishShellInit = `# wsm shell integration: 'wsm cd' changes directory
+function wsm
+    if test "$argv[1]" = cd
+        set -l dir (command wsm $argv); or return
+        if test -d "$dir"
+            builtin cd $dir
+        else
+            printf '%s\n' $dir
+        end
+    else
+        command wsm $argv
+    end
+end
+command wsm _carapace fish | source
+`
+
+// shellInitSnippet returns the shell integration for a shell
+func shellInitSnippet(shell string) (string, error) {
+	switch shell {
+	case "bash", "zsh":
+		return fmt.Sprintf(posixShellInit, shell), nil
+	case "fish":
+		return fishShellInit, nil
+	}
+	return "", errors.Errorf("unsupported shell '%s' (supported: bash, zsh, fish)", shell)
+}
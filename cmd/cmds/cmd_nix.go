@@ -0,0 +1,118 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewNixCommand creates the nix command
+func NewNixCommand() *cobra.Command {
+	var (
+		force  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "nix [workspace-name]",
+		Short: "Generate a Nix flake dev shell for a workspace",
+		Long: `Generate flake.nix at the workspace root, with a dev shell holding the
+toolchains of the repository categories (go, node, python, rust), and an
+.envrc using it when direnv is enabled.
+
+nix.yaml in the workspace-manager config directory configures the flake:
+
+  enabled: true        # generate it in every new workspace
+  direnv: true         # write .envrc and run 'direnv allow'
+  nixpkgs: github:NixOS/nixpkgs/nixos-24.05
+  packages:            # replace the packages of a category
+    go: [go_1_22, gopls, golangci-lint]
+  extra-packages: [jq]
+  template: ~/templates/flake.nix.tmpl
+
+The template is a Go template with .Name, .Path, .Branch, .Repositories,
+.Categories, .Packages, .Nixpkgs and .Env, and the nixString function quoting
+values for Nix. 'wsm create --nix' and 'wsm fork --nix' generate the flake
+whatever nix.yaml says; --nix=false skips it.
+
+Examples:
+  wsm nix
+  nix develop ~/workspaces/2025-06-01/my-feature
+
+  # Preview the flake
+  wsm nix my-feature --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: audited("nix", 0, func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			return runNix(cmd.Context(), workspaceName, force, dryRun)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Replace existing flake.nix and .envrc files")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the files without writing them")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+
+	return cmd
+}
+
+func runNix(ctx context.Context, workspaceName string, force, dryRun bool) error {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get current directory")
+		}
+		detected, err := detectWorkspace(cwd)
+		if err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm nix <workspace-name>'")
+		}
+		workspaceName = detected
+	}
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	config, err := wsm.LoadNixConfig()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		files, err := config.GenerateNixFlake(workspace)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			output.PrintHeader("%s", file.Path)
+			fmt.Print(file.Content)
+			fmt.Println()
+		}
+		return nil
+	}
+
+	written, err := config.WriteNixFlake(ctx, workspace, force)
+	if err != nil {
+		return err
+	}
+	for _, file := range written {
+		output.PrintSuccess("Wrote %s", file.Path)
+	}
+	if packages, _ := config.PackagesFor(workspace); len(packages) > 0 {
+		output.PrintInfo("Packages: %s", strings.Join(packages, ", "))
+	}
+	output.PrintInfo("Enter the dev shell with: nix develop %s", workspace.Path)
+	return nil
+}
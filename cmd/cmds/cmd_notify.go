@@ -0,0 +1,139 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewNotifyCommand creates the notify command
+func NewNotifyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Check the webhooks notified of workspace events",
+		Long: `Webhooks configured in notifications.yaml in the workspace-manager config
+directory are notified when long operations finish:
+
+  sync.completed, sync.failed     wsm sync, wsm sync pull, wsm sync push
+  merge.succeeded, merge.failed   wsm merge
+  pr.opened                       wsm pr
+
+  webhooks:
+    - name: team
+      type: slack                 # slack, discord or generic (default)
+      url-env: WSM_SLACK_WEBHOOK  # or url: https://hooks.slack.com/...
+      events: [merge.*, pr.opened]
+    - type: generic
+      url: https://ci.acme.dev/hooks/wsm
+      headers:
+        Authorization: Bearer secret
+
+Generic webhooks receive the notification as JSON, with the structured result
+of the operation under "result". Slack and Discord get a message with the
+result as a code block. A failing webhook never fails the operation.
+
+Examples:
+  # Send a test notification to every webhook
+  wsm notify test
+
+  # Only the webhooks subscribed to merge failures
+  wsm notify test --event merge.failed`,
+	}
+
+	cmd.AddCommand(newNotifyTestCommand())
+
+	return cmd
+}
+
+func newNotifyTestCommand() *cobra.Command {
+	var event string
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Send a test notification to the configured webhooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := wsm.LoadNotificationsConfig()
+			if err != nil {
+				return err
+			}
+			if len(config.Webhooks) == 0 {
+				return errors.Errorf("no webhooks configured in %s", config.Path())
+			}
+
+			notification := wsm.Notification{
+				Event:   event,
+				Success: true,
+				Summary: "Test notification from wsm",
+			}
+			if workspace, err := findCurrentWorkspace(); err == nil {
+				notification.Workspace = workspace.Name
+				notification.Branch = workspace.Branch
+			}
+			results := config.Send(cmd.Context(), notification)
+			if len(results) == 0 {
+				output.PrintWarning("No webhook is subscribed to %s", event)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "WEBHOOK\tRESULT")
+			fmt.Fprintln(w, "-------\t------")
+			failed := 0
+			for _, result := range results {
+				status := "sent"
+				if !result.Sent {
+					status = result.Error
+					failed++
+				}
+				fmt.Fprintf(w, "%s\t%s\n", result.Webhook, status)
+			}
+			_ = w.Flush()
+			if failed > 0 {
+				return errors.Errorf("%d of %d webhook(s) failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&event, "event", wsm.EventTest, "Event of the test notification, selecting the subscribed webhooks")
+
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"event": carapace.ActionValues(wsm.NotificationEvents...),
+	})
+
+	return cmd
+}
+
+// notifySync notifies the webhooks of the outcome of a sync
+func notifySync(ctx context.Context, workspace *wsm.Workspace, operation string, results []wsm.SyncResult, syncErr error) {
+	notification := wsm.Notification{
+		Event:     wsm.EventSyncCompleted,
+		Workspace: workspace.Name,
+		Branch:    workspace.Branch,
+		Success:   true,
+		Result:    results,
+	}
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	if syncErr != nil || failed > 0 {
+		notification.Event = wsm.EventSyncFailed
+		notification.Success = false
+	}
+	if syncErr != nil {
+		notification.Error = syncErr.Error()
+	}
+	notification.Summary = fmt.Sprintf("%s of %d repositories: %d succeeded, %d failed", operation, len(results), len(results)-failed, failed)
+	wsm.Notify(ctx, notification)
+}
@@ -0,0 +1,129 @@
+package cmds
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewOpenCommand creates the open command
+func NewOpenCommand() *cobra.Command {
+	var (
+		editor   string
+		command  string
+		noLaunch bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "open [workspace-name]",
+		Short: "Open a workspace in an editor",
+		Long: `Open a workspace in VS Code, Cursor or a JetBrains IDE.
+
+A multi-root project file is generated in the workspace directory and kept
+up to date when repositories are added or removed:
+
+  vscode, cursor  <workspace>/<name>.code-workspace with the workspace root
+                  and one folder per repository (existing settings are kept)
+  jetbrains       <workspace>/.idea/vcs.xml registering each repository as a
+                  Git root; the IDE opens the workspace directory
+
+If no workspace name is provided, the current workspace is detected.
+
+Examples:
+  # Open the current workspace in VS Code
+  wsm open
+
+  # Open a workspace in Cursor
+  wsm open my-feature --editor cursor
+
+  # Use a specific JetBrains launcher
+  wsm open my-feature --editor jetbrains --command goland
+
+  # Only generate the project file
+  wsm open my-feature --no-launch`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			return runOpen(cmd.Context(), workspaceName, editor, command, noLaunch)
+		},
+	}
+
+	cmd.Flags().StringVarP(&editor, "editor", "e", wsm.EditorVSCode, "Editor to open (vscode, cursor, jetbrains)")
+	cmd.Flags().StringVar(&command, "command", "", "Editor launcher to run instead of the default (code, cursor, idea)")
+	cmd.Flags().BoolVar(&noLaunch, "no-launch", false, "Generate the project file without launching the editor")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"editor":  carapace.ActionValues(wsm.Editors...),
+			"command": carapace.ActionExecutables(),
+		},
+	)
+
+	return cmd
+}
+
+func runOpen(ctx context.Context, workspaceName, editor, command string, noLaunch bool) error {
+	launcher, err := wsm.EditorCommand(editor)
+	if err != nil {
+		return err
+	}
+	if command != "" {
+		launcher = command
+	}
+
+	var workspace *wsm.Workspace
+	if workspaceName == "" {
+		workspace, err = detectCurrentWorkspace()
+		if err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm open <workspace-name>'")
+		}
+	} else {
+		workspace, err = loadWorkspace(workspaceName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+		}
+	}
+
+	projectPath, err := wsm.WriteEditorProject(workspace, editor)
+	if err != nil {
+		return errors.Wrap(err, "failed to write editor project file")
+	}
+	output.PrintSuccess("Wrote %s", projectPath)
+
+	if noLaunch {
+		return nil
+	}
+
+	launcherPath, err := exec.LookPath(launcher)
+	if err != nil {
+		return errors.Errorf("editor launcher '%s' not found in PATH (use --command to set it, or --no-launch)", launcher)
+	}
+
+	target, err := wsm.EditorOpenTarget(workspace, editor)
+	if err != nil {
+		return err
+	}
+
+	// Editors detach from the terminal; do not wait for them to exit
+	launchCmd := exec.Command(launcherPath, target)
+	launchCmd.Dir = workspace.Path
+	launchCmd.Stdout = os.Stdout
+	launchCmd.Stderr = os.Stderr
+	if err := launchCmd.Start(); err != nil {
+		return errors.Wrapf(err, "failed to launch %s", launcher)
+	}
+	_ = launchCmd.Process.Release()
+
+	output.PrintInfo("Opening %s in %s", workspace.Name, editor)
+	return nil
+}
@@ -0,0 +1,85 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewOpenTicketCommand creates the open-ticket command
+func NewOpenTicketCommand() *cobra.Command {
+	var printOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "open-ticket [workspace-name]",
+		Short: "Open the ticket of a workspace in the browser",
+		Long: `Open the ticket a workspace works on in the browser.
+
+The ticket is given with 'wsm create --ticket' as an ID or URL, or found in
+the workspace name. IDs are linked with ticket-url in branch-naming.yaml in the
+workspace-manager config directory:
+
+  ticket-url: https://acme.atlassian.net/browse/{ticket}
+
+Examples:
+  # Open the ticket of the current workspace
+  wsm open-ticket
+
+  # Print the URL instead
+  wsm open-ticket refund-api --print`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			workspace, err := namedOrCurrentWorkspace(workspaceName, "wsm open-ticket <workspace-name>")
+			if err != nil {
+				return err
+			}
+			if workspace.Ticket == "" {
+				return errors.Errorf("workspace '%s' has no ticket (create it with --ticket)", workspace.Name)
+			}
+
+			ticketURL := workspace.TicketURL
+			if ticketURL == "" {
+				// ticket-url may have been configured after the workspace was created
+				naming, err := wsm.LoadBranchNaming()
+				if err != nil {
+					return err
+				}
+				if ticketURL = naming.TicketLink(workspace.Ticket); ticketURL == "" {
+					return errors.Errorf("ticket %s of workspace '%s' has no URL (set ticket-url in %s)", workspace.Ticket, workspace.Name, naming.Path())
+				}
+			}
+
+			if printOnly {
+				fmt.Println(ticketURL)
+				return nil
+			}
+			if err := wsm.OpenURL(ticketURL); err != nil {
+				return err
+			}
+			output.PrintSuccess("Opened %s", ticketURL)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&printOnly, "print", false, "Print the ticket URL instead of opening it")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+// ticketID returns the ID of an optional ticket
+func ticketID(ticket *wsm.TicketRef) string {
+	if ticket == nil {
+		return ""
+	}
+	return ticket.ID
+}
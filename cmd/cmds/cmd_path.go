@@ -43,6 +43,12 @@ Examples:
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
 	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
 
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+		},
+	)
+
 	return cmd
 }
 
@@ -0,0 +1,112 @@
+package cmds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewPolicyCommand creates the policy command
+func NewPolicyCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Show the protected branch policy",
+		Long: `Show the branch policy enforced by push, commit --push, sync and merge.
+
+The policy is read from policy.yaml in the workspace-manager config directory
+(e.g. ~/.config/workspace-manager/policy.yaml):
+
+  protected-branches: [main, master, "release/*"]
+  allow-force-push: false     # never force-push protected branches
+  require-clean-merge: true   # no merges into protected branches with uncommitted changes
+  max-file-size: 50MB         # largest file pushed commits may add (default 100MB)
+  lfs-patterns: ["*.psd"]     # files that belong in Git LFS
+  block-large-files: true     # refuse such pushes instead of warning
+  repositories:
+    infra:
+      protected-branches: [production]
+
+Direct pushes and merges to protected branches require --confirm.
+Without a policy file everything is allowed.
+
+Examples:
+  # Show the effective policy
+  wsm policy
+
+  # JSON output
+  wsm policy --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPolicy(outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runPolicy(outputFormat string) error {
+	policy, err := wsm.LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat {
+	case "json":
+		return wsm.PrintJSON(policy)
+	case "table":
+	default:
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	output.PrintHeader("Branch Policy")
+	fmt.Printf("  File:                  %s\n", policy.Path())
+	fmt.Printf("  Max file size:         %s\n", wsm.FormatBytes(policy.MaxFileSizeBytes()))
+	fmt.Printf("  LFS patterns:          %s\n", formatBranchPatterns(policy.LFSPatterns))
+	fmt.Printf("  Block large files:     %t\n", policy.BlockLargeFiles)
+
+	if len(policy.ProtectedBranches) == 0 && len(policy.Repositories) == 0 {
+		output.PrintInfo("No protected branches configured - all pushes and merges are allowed")
+		return nil
+	}
+
+	fmt.Printf("  Protected branches:    %s\n", formatBranchPatterns(policy.ProtectedBranches))
+	fmt.Printf("  Force-push allowed:    %t\n", policy.AllowForcePush)
+	fmt.Printf("  Clean merge required:  %t\n", policy.RequireCleanMerge)
+
+	if len(policy.Repositories) > 0 {
+		fmt.Println()
+		output.PrintHeader("Repository Overrides")
+		repoNames := make([]string, 0, len(policy.Repositories))
+		for name := range policy.Repositories {
+			repoNames = append(repoNames, name)
+		}
+		sort.Strings(repoNames)
+		for _, name := range repoNames {
+			fmt.Printf("  %s: %s\n", name, formatBranchPatterns(policy.Repositories[name].ProtectedBranches))
+		}
+	}
+
+	return nil
+}
+
+func formatBranchPatterns(patterns []string) string {
+	if len(patterns) == 0 {
+		return "-"
+	}
+	return strings.Join(patterns, ", ")
+}
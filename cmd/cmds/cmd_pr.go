@@ -1,10 +1,11 @@
 package cmds
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
 	"os/exec"
@@ -39,10 +40,15 @@ This command will:
 
 A branch is considered to need a PR if:
 - It's not the main/master branch
-- It's not merged to origin/main yet
-- It has commits ahead of origin/main
+- It's not merged to the default branch of its base remote yet (origin, or the
+  upstream remote of a fork, see 'wsm repos remote')
+- It has commits ahead of that branch
 - If the branch doesn't exist on remote, it will be pushed first
 
+In fork mode (see 'wsm fork-mode'), branches are pushed to the fork remote,
+which is created with 'gh repo fork' if needed, and pull requests are opened
+against the repository of the base remote, with the fork branch as head.
+
 Requirements:
 - GitHub CLI (gh) must be installed and authenticated
 - Repositories must be hosted on GitHub
@@ -73,8 +79,17 @@ Examples:
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what PRs would be created without actually creating them")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Create PRs without asking for confirmation")
 	cmd.Flags().BoolVar(&draft, "draft", false, "Create draft pull requests")
-	cmd.Flags().StringVar(&title, "title", "", "Custom title for all PRs (default: use branch name)")
-	cmd.Flags().StringVar(&body, "body", "", "Custom body for all PRs")
+	cmd.Flags().StringVar(&title, "title", "", "Custom title for all PRs, may use {ticket}, {workspace}, {branch} and {repo} (default: use branch name)")
+	cmd.Flags().StringVar(&body, "body", "", "Custom body for all PRs, may use {ticket}, {ticket_url}, {workspace}, {branch} and {repo} (default: links the workspace ticket)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+		},
+	)
 
 	return cmd
 }
@@ -115,7 +130,7 @@ func runPR(ctx context.Context, workspaceName string, dryRun, force, draft bool,
 	// Find branches that need PRs
 	var candidateBranches []PRCandidate
 	for _, repoStatus := range status.Repositories {
-		if candidate, needsPR := checkIfNeedsPR(ctx, repoStatus, workspace.Path); needsPR {
+		if candidate, needsPR := checkIfNeedsPR(ctx, repoStatus, workspace); needsPR {
 			candidateBranches = append(candidateBranches, candidate)
 		}
 	}
@@ -139,6 +154,9 @@ func runPR(ctx context.Context, workspaceName string, dryRun, force, draft bool,
 			output.PrintWarning("   ⚠️  Existing PR: %s", candidate.ExistingPR)
 		}
 		fmt.Printf("   Remote URL: %s\n", candidate.RemoteURL)
+		if candidate.Upstream != "" {
+			fmt.Printf("   Target: %s (from remote '%s')\n", candidate.Upstream, candidate.PushRemote)
+		}
 		fmt.Println()
 	}
 
@@ -147,8 +165,14 @@ func runPR(ctx context.Context, workspaceName string, dryRun, force, draft bool,
 		return nil
 	}
 
+	if !force {
+		if err := wsm.RequireInteractive("Creating pull requests", "--force"); err != nil {
+			return err
+		}
+	}
+
 	// Create PRs
-	reader := bufio.NewReader(os.Stdin)
+	var opened []openedPR
 	for _, candidate := range candidateBranches {
 		if candidate.ExistingPR != "" {
 			output.PrintWarning("Skipping %s/%s - PR already exists: %s", candidate.Repository, candidate.Branch, candidate.ExistingPR)
@@ -157,36 +181,62 @@ func runPR(ctx context.Context, workspaceName string, dryRun, force, draft bool,
 
 		shouldCreate := force
 		if !force {
-			fmt.Printf("Create PR for %s/%s? [y/N]: ", candidate.Repository, candidate.Branch)
-			response, _ := reader.ReadString('\n')
-			response = strings.ToLower(strings.TrimSpace(response))
-			shouldCreate = response == "y" || response == "yes"
+			confirmed, err := ux.Confirm(fmt.Sprintf("Create PR for %s/%s?", candidate.Repository, candidate.Branch), "")
+			if err != nil {
+				if errors.Is(err, ux.ErrAborted) {
+					output.PrintInfo("Operation cancelled.")
+					break
+				}
+				return err
+			}
+			shouldCreate = confirmed
 		}
 
 		if shouldCreate {
 			// Push branch first if needed
 			if candidate.NeedsPush {
-				output.PrintInfo("🚀 Pushing branch %s/%s to remote...", candidate.Repository, candidate.Branch)
-				if err := pushBranchForPR(ctx, candidate); err != nil {
+				output.PrintInfo("🚀 Pushing branch %s/%s to %s...", candidate.Repository, candidate.Branch, candidate.PushRemote)
+				if err := pushBranchForPR(ctx, candidate, workspace.Fork); err != nil {
 					output.PrintError("Failed to push branch %s/%s: %v", candidate.Repository, candidate.Branch, err)
 					continue
 				}
 				output.PrintSuccess("Pushed branch %s/%s", candidate.Repository, candidate.Branch)
 			}
 
-			if err := createPR(ctx, candidate, draft, customTitle, customBody); err != nil {
+			url, err := createPR(ctx, workspace, candidate, draft, customTitle, customBody)
+			if err != nil {
 				output.PrintError("Failed to create PR for %s/%s: %v", candidate.Repository, candidate.Branch, err)
 			} else {
 				output.PrintSuccess("Created PR for %s/%s", candidate.Repository, candidate.Branch)
+				opened = append(opened, openedPR{Repository: candidate.Repository, Branch: candidate.Branch, URL: url, Draft: draft})
 			}
 		} else {
 			output.PrintInfo("Skipped %s/%s", candidate.Repository, candidate.Branch)
 		}
 	}
 
+	if len(opened) > 0 {
+		wsm.Notify(ctx, wsm.Notification{
+			Event:     wsm.EventPROpened,
+			Workspace: workspace.Name,
+			Branch:    workspace.Branch,
+			Success:   true,
+			Summary:   fmt.Sprintf("Opened %d pull request(s)", len(opened)),
+			Result:    opened,
+		})
+	}
+
 	return nil
 }
 
+// openedPR is a pull request sent to webhooks
+type openedPR struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	URL        string `json:"url,omitempty"`
+	Draft      bool   `json:"draft,omitempty"`
+}
+
 type PRCandidate struct {
 	Repository   string
 	Branch       string
@@ -195,16 +245,19 @@ type PRCandidate struct {
 	RemoteURL    string
 	ExistingPR   string // URL if PR already exists
 	NeedsPush    bool   // true if branch needs to be pushed to remote first
+	PushRemote   string // remote the branch is pushed to
+	Upstream     string // fork mode: owner/name of the repository the PR targets
+
+	repository wsm.Repository
 }
 
 func checkGHCLI(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "gh", "--version")
-	if err := cmd.Run(); err != nil {
-		return errors.New("GitHub CLI (gh) is not installed or not in PATH. Please install it from https://cli.github.com/")
+	if err := wsm.RequireTool(ctx, "gh"); err != nil {
+		return err
 	}
 
 	// Check if authenticated
-	cmd = exec.CommandContext(ctx, "gh", "auth", "status")
+	cmd := exec.CommandContext(ctx, "gh", "auth", "status")
 	if err := cmd.Run(); err != nil {
 		return errors.New("GitHub CLI is not authenticated. Please run 'gh auth login' first")
 	}
@@ -212,12 +265,14 @@ func checkGHCLI(ctx context.Context) error {
 	return nil
 }
 
-func checkIfNeedsPR(ctx context.Context, repoStatus wsm.RepositoryStatus, workspacePath string) (PRCandidate, bool) {
+func checkIfNeedsPR(ctx context.Context, repoStatus wsm.RepositoryStatus, workspace *wsm.Workspace) (PRCandidate, bool) {
 	candidate := PRCandidate{
 		Repository: repoStatus.Repository.Name,
 		Branch:     repoStatus.CurrentBranch,
-		RepoPath:   filepath.Join(workspacePath, repoStatus.Repository.Name),
+		RepoPath:   filepath.Join(workspace.Path, repoStatus.Repository.Name),
 		RemoteURL:  repoStatus.Repository.RemoteURL,
+		PushRemote: workspace.PushRemoteFor(repoStatus.Repository),
+		repository: repoStatus.Repository,
 	}
 
 	log.Debug().
@@ -238,31 +293,42 @@ func checkIfNeedsPR(ctx context.Context, repoStatus wsm.RepositoryStatus, worksp
 		return candidate, false
 	}
 
-	// Skip if already merged to origin/main
+	// Skip if already merged to the base branch
 	if repoStatus.IsMerged {
-		log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Msg("Skipping: already merged to origin/main")
+		log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Msg("Skipping: already merged to the base branch")
 		return candidate, false
 	}
 
-	// Get ahead/behind counts against origin/main specifically for PR purposes
-	aheadCount, behindCount, err := getAheadBehindOriginMain(ctx, candidate.RepoPath)
+	// Get ahead/behind counts against the base branch specifically for PR purposes
+	baseRef := workspace.BaseRefFor(ctx, repoStatus.Repository.Name, candidate.RepoPath)
+	aheadCount, behindCount, err := getAheadBehindBase(ctx, candidate.RepoPath, baseRef)
 	if err != nil {
-		log.Debug().Err(err).Str("repository", candidate.Repository).Str("branch", candidate.Branch).Msg("Failed to get ahead/behind counts against origin/main")
+		log.Debug().Err(err).Str("repository", candidate.Repository).Str("branch", candidate.Branch).Str("base", baseRef).Msg("Failed to get ahead/behind counts against the base branch")
 		// Fall back to the status ahead count
 		aheadCount = repoStatus.Ahead
 	}
 
 	candidate.CommitsAhead = aheadCount
-	log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Int("ahead", aheadCount).Int("behind", behindCount).Msg("Repository commits against origin/main")
+	log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Int("ahead", aheadCount).Int("behind", behindCount).Str("base", baseRef).Msg("Repository commits against the base branch")
 
-	// Skip if no commits ahead of origin/main
+	// Skip if no commits ahead of the base branch
 	if aheadCount == 0 {
-		log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Msg("Skipping: no commits ahead of origin/main")
+		log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Str("base", baseRef).Msg("Skipping: no commits ahead of the base branch")
 		return candidate, false
 	}
 
+	// In fork mode the PR targets the repository of the base remote
+	if workspace.Fork != nil {
+		upstream, err := wsm.UpstreamRepository(ctx, repoStatus.Repository, candidate.RepoPath)
+		if err != nil {
+			log.Debug().Err(err).Str("repository", candidate.Repository).Msg("Skipping: cannot determine the upstream repository")
+			return candidate, false
+		}
+		candidate.Upstream = upstream
+	}
+
 	// Check if branch exists on remote
-	branchExists := branchExistsOnRemote(ctx, candidate.RepoPath, repoStatus.CurrentBranch)
+	branchExists := branchExistsOnRemote(ctx, candidate.RepoPath, candidate.PushRemote, repoStatus.CurrentBranch)
 	log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Bool("exists", branchExists).Msg("Checked if branch exists on remote")
 
 	// If branch doesn't exist on remote but has commits ahead, we need to push first
@@ -272,7 +338,7 @@ func checkIfNeedsPR(ctx context.Context, repoStatus wsm.RepositoryStatus, worksp
 	}
 
 	// Check if PR already exists
-	if existingPR := checkExistingPR(ctx, candidate.RepoPath, repoStatus.CurrentBranch); existingPR != "" {
+	if existingPR := checkExistingPR(ctx, candidate.RepoPath, candidate.Upstream, repoStatus.CurrentBranch); existingPR != "" {
 		log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Str("existingPR", existingPR).Msg("Found existing PR")
 		candidate.ExistingPR = existingPR
 	} else {
@@ -283,13 +349,13 @@ func checkIfNeedsPR(ctx context.Context, repoStatus wsm.RepositoryStatus, worksp
 	return candidate, true
 }
 
-func getAheadBehindOriginMain(ctx context.Context, repoPath string) (int, int, error) {
-	// Get ahead/behind counts against origin/main
-	cmd := exec.CommandContext(ctx, "git", "rev-list", "--left-right", "--count", "HEAD...origin/main")
+// getAheadBehindBase counts the commits of HEAD ahead of and behind baseRef, e.g. origin/main
+func getAheadBehindBase(ctx context.Context, repoPath, baseRef string) (int, int, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--left-right", "--count", "HEAD..."+baseRef)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
-		log.Debug().Err(err).Str("repoPath", repoPath).Msg("Failed to get ahead/behind counts against origin/main")
+		log.Debug().Err(err).Str("repoPath", repoPath).Str("base", baseRef).Msg("Failed to get ahead/behind counts against the base branch")
 		return 0, 0, err
 	}
 
@@ -309,19 +375,27 @@ func getAheadBehindOriginMain(ctx context.Context, repoPath string) (int, int, e
 		behind = behindVal
 	}
 
-	log.Debug().Str("repoPath", repoPath).Int("ahead", ahead).Int("behind", behind).Msg("Got ahead/behind counts against origin/main")
+	log.Debug().Str("repoPath", repoPath).Int("ahead", ahead).Int("behind", behind).Str("base", baseRef).Msg("Got ahead/behind counts against the base branch")
 	return ahead, behind, nil
 }
 
-func branchExistsOnRemote(ctx context.Context, repoPath, branch string) bool {
-	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "origin", branch)
+func branchExistsOnRemote(ctx context.Context, repoPath, remoteName, branch string) bool {
+	ctx, cancel := wsm.GitContext(ctx, "ls-remote")
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", remoteName, branch)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	return err == nil && len(strings.TrimSpace(string(output))) > 0
 }
 
-func checkExistingPR(ctx context.Context, repoPath, branch string) string {
-	cmd := exec.CommandContext(ctx, "gh", "pr", "list", "--head", branch, "--json", "url", "--jq", ".[0].url")
+// checkExistingPR returns the URL of the open PR of branch, in upstream
+// (owner/name) when set, otherwise in the repository gh resolves
+func checkExistingPR(ctx context.Context, repoPath, upstream, branch string) string {
+	args := []string{"pr", "list", "--head", branch, "--json", "url", "--jq", ".[0].url"}
+	if upstream != "" {
+		args = append(args, "--repo", upstream)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -330,32 +404,53 @@ func checkExistingPR(ctx context.Context, repoPath, branch string) string {
 	return strings.TrimSpace(string(output))
 }
 
-func pushBranchForPR(ctx context.Context, candidate PRCandidate) error {
-	cmd := exec.CommandContext(ctx, "git", "push", "-u", "origin", candidate.Branch)
+func pushBranchForPR(ctx context.Context, candidate PRCandidate, fork *wsm.ForkConfig) error {
+	candidates := []PushCandidate{{Repository: candidate.Repository, RepoPath: candidate.RepoPath}}
+	if err := checkLargeFiles(ctx, candidates, false, "push the branch with 'wsm push <remote> --allow-large-files' if they are intended"); err != nil {
+		return err
+	}
+	if fork != nil {
+		return wsm.PushToFork(ctx, candidate.repository, candidate.RepoPath, fork)
+	}
+
+	ctx, cancel := wsm.GitContext(ctx, "push")
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "push", "-u", candidate.PushRemote, candidate.Branch)
 	cmd.Dir = candidate.RepoPath
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "git push failed: %s", string(output))
+		return errors.Wrapf(wsm.GitError(ctx, err), "git push failed: %s", string(output))
 	}
 
 	return nil
 }
 
-func createPR(ctx context.Context, candidate PRCandidate, draft bool, customTitle, customBody string) error {
+// createPR opens the pull request of a candidate and returns its URL
+func createPR(ctx context.Context, workspace *wsm.Workspace, candidate PRCandidate, draft bool, customTitle, customBody string) (string, error) {
 	args := []string{"pr", "create"}
 
 	// Add title
-	title := customTitle
+	title := wsm.ExpandCommitMessage(customTitle, workspace, candidate.Repository)
 	if title == "" {
 		title = fmt.Sprintf("Feature: %s", candidate.Branch)
+		if workspace.Ticket != "" && !strings.Contains(candidate.Branch, workspace.Ticket) {
+			title = fmt.Sprintf("%s: %s", workspace.Ticket, title)
+		}
 	}
 	args = append(args, "--title", title)
 
 	// Add body
-	body := customBody
+	body := wsm.ExpandCommitMessage(customBody, workspace, candidate.Repository)
 	if body == "" {
-		body = fmt.Sprintf("Pull request for branch: %s\n\nCreated automatically by workspace-manager.", candidate.Branch)
+		body = fmt.Sprintf("Pull request for branch: %s\n\n", candidate.Branch)
+		switch {
+		case workspace.TicketURL != "":
+			body += fmt.Sprintf("Ticket: [%s](%s)\n\n", workspace.Ticket, workspace.TicketURL)
+		case workspace.Ticket != "":
+			body += fmt.Sprintf("Ticket: %s\n\n", workspace.Ticket)
+		}
+		body += "Created automatically by workspace-manager."
 	}
 	args = append(args, "--body", body)
 
@@ -364,13 +459,34 @@ func createPR(ctx context.Context, candidate PRCandidate, draft bool, customTitl
 		args = append(args, "--draft")
 	}
 
+	// In fork mode open the PR against the upstream repository from the fork branch
+	if candidate.Upstream != "" {
+		forkURL, err := getGitRemoteURL(ctx, candidate.RepoPath, candidate.PushRemote)
+		if err != nil {
+			return "", err
+		}
+		forkRepo, err := wsm.GitHubRepository(forkURL)
+		if err != nil {
+			return "", err
+		}
+		owner, _, _ := strings.Cut(forkRepo, "/")
+		args = append(args, "--repo", candidate.Upstream, "--head", owner+":"+candidate.Branch)
+	}
+
 	cmd := exec.CommandContext(ctx, "gh", args...)
 	cmd.Dir = candidate.RepoPath
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "gh pr create failed: %s", string(output))
+		return "", errors.Wrapf(err, "gh pr create failed: %s", string(output))
 	}
 
-	return nil
+	// gh prints the URL of the new pull request last
+	var url string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "https://") {
+			url = strings.TrimSpace(line)
+		}
+	}
+	return url, nil
 }
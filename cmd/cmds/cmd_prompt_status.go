@@ -0,0 +1,80 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewPromptStatusCommand creates the prompt-status command
+func NewPromptStatusCommand() *cobra.Command {
+	var (
+		maxAge       time.Duration
+		showDate     bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prompt-status",
+		Short: "Print a one-line workspace status for shell prompts",
+		Long: `Print a compact status of the workspace containing the current directory:
+its name, branch, the number of dirty repositories and the aggregate
+ahead/behind counts, e.g.
+
+  my-feature ⎇ task/my-feature ●2 ↑3 ↓1
+
+The status is served from a cache in the workspace-manager config directory and
+recomputed with one local git command per repository once older than
+--max-age, after a background fetch, or when 'wsm status' runs. It never
+fetches. Outside a workspace nothing is printed, so prompts hide the segment.
+
+Examples:
+  # Use in a prompt (see 'wsm starship')
+  wsm prompt-status
+
+  # Include the workspace creation date
+  wsm prompt-status --show-date
+
+  # Machine-readable status
+  wsm prompt-status --output json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPromptStatus(cmd.Context(), maxAge, showDate, outputFormat)
+		},
+	}
+
+	cmd.Flags().DurationVar(&maxAge, "max-age", 10*time.Second, "Recompute the cached status when older than this")
+	cmd.Flags().BoolVar(&showDate, "show-date", false, "Include the workspace creation date")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json)")
+
+	return cmd
+}
+
+func runPromptStatus(ctx context.Context, maxAge time.Duration, showDate bool, outputFormat string) error {
+	workspace, err := findCurrentWorkspace()
+	if err != nil {
+		// Not in a workspace: print nothing so the prompt segment disappears
+		return nil
+	}
+
+	// A stale cache that could not be saved still gives a correct status
+	status, _ := wsm.GetPromptStatus(ctx, workspace, maxAge)
+
+	switch outputFormat {
+	case "json":
+		return wsm.PrintJSON(status)
+	case "text":
+		line := status.String()
+		if showDate && !workspace.Created.IsZero() {
+			line += fmt.Sprintf(" (%s)", workspace.Created.Format("2006-01-02"))
+		}
+		fmt.Println(line)
+		return nil
+	default:
+		return errors.Errorf("unknown output format '%s' (text, json)", outputFormat)
+	}
+}
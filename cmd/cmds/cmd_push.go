@@ -1,11 +1,12 @@
 package cmds
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
 	"os/exec"
@@ -23,6 +24,13 @@ func NewPushCommand() *cobra.Command {
 		dryRun      bool
 		force       bool
 		setUpstream bool
+		confirm     bool
+		forceLease  bool
+		ci          bool
+		allowLarge  bool
+		skipChecks  bool
+		archived    bool
+		filters     repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
@@ -42,10 +50,32 @@ A branch is considered to need pushing if:
 - The repository exists on GitHub
 
 Requirements:
-- GitHub CLI (gh) must be installed and authenticated
+- GitHub CLI (gh) should be installed and authenticated; without it, wsm falls
+  back to git-only detection using the locally configured remote
 - Repositories must be hosted on GitHub
 - The specified remote must exist and be accessible
 
+Branches declared protected in policy.yaml (see 'wsm policy') need --confirm,
+and cannot be force-pushed unless the policy sets allow-force-push.
+
+Archived repositories (see 'wsm repos archive') are read-only and skipped
+unless --include-archived is given.
+
+Before pushing, the commits of every branch are checked for files over the
+max-file-size of policy.yaml (100MB by default) and for files matching its
+lfs-patterns that are not stored in Git LFS. They are reported as warnings, or
+block the push when the policy sets block-large-files, unless
+--allow-large-files is given.
+
+When checks.yaml enforces checks before push (see 'wsm check'), they run
+before anything is pushed and a failure stops the push, unless --skip-checks.
+
+With --ci, CI configured in ci.yaml (see 'wsm ci') is triggered for every
+pushed branch.
+
+In fork mode (see 'wsm fork-mode'), pushing to the fork remote first forks every
+repository that has no fork remote yet with 'gh repo fork'.
+
 Examples:
   # Check what would be pushed (dry run)  
   workspace-manager push fork my-workspace --dry-run
@@ -57,30 +87,78 @@ Examples:
   workspace-manager push fork my-workspace --force
 
   # Push and set upstream tracking
-  workspace-manager push fork my-workspace --set-upstream`,
+  workspace-manager push fork my-workspace --set-upstream
+
+  # Push and start CI for the pushed branches
+  workspace-manager push origin my-workspace --force --ci`,
 		Args: cobra.RangeArgs(1, 2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("push", 1, func(cmd *cobra.Command, args []string) error {
 			remoteName := args[0]
 			workspaceName := workspace
 			if len(args) > 1 {
 				workspaceName = args[1]
 			}
-			return runPush(cmd.Context(), remoteName, workspaceName, dryRun, force, setUpstream)
-		},
+			return runPush(cmd.Context(), remoteName, workspaceName, pushOptions{
+				dryRun:         dryRun,
+				force:          force,
+				setUpstream:    setUpstream,
+				confirm:        confirm,
+				forceWithLease: forceLease,
+				ci:             ci,
+				allowLarge:     allowLarge,
+				skipChecks:     skipChecks,
+				archived:       archived,
+				filter:         filters.filter(),
+			})
+		}),
 	}
 
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be pushed without actually pushing")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Push without asking for confirmation")
 	cmd.Flags().BoolVarP(&setUpstream, "set-upstream", "u", false, "Set upstream tracking for pushed branches")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Allow pushing branches that are protected by policy")
+	cmd.Flags().BoolVar(&forceLease, "force-with-lease", false, "Force-push with lease (e.g. after a rebase); denied for protected branches unless policy allows it")
+	cmd.Flags().BoolVar(&ci, "ci", false, "Trigger CI for the pushed branches")
+	cmd.Flags().BoolVar(&allowLarge, "allow-large-files", false, "Push even if the policy blocks commits adding large files")
+	cmd.Flags().BoolVar(&skipChecks, "skip-checks", false, "Push without running the checks checks.yaml enforces before push")
+	cmd.Flags().BoolVar(&archived, "include-archived", false, "Also push archived (read-only) repositories")
+	filters.register(cmd, &workspace)
+
+	carapace.Gen(cmd).PositionalCompletion(
+		carapace.ActionValues("origin", "upstream"),
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+		},
+	)
 
 	return cmd
 }
 
-func runPush(ctx context.Context, remoteName, workspaceName string, dryRun, force, setUpstream bool) error {
-	// Check if gh CLI is available
+// pushOptions holds the flags of the push command
+type pushOptions struct {
+	dryRun         bool
+	force          bool // skip the per-branch confirmation prompt
+	setUpstream    bool
+	confirm        bool // acknowledge pushes to protected branches
+	forceWithLease bool
+	ci             bool // trigger CI for the pushed branches
+	allowLarge     bool // push large files the policy blocks
+	skipChecks     bool // skip the checks enforced before push
+	archived       bool // push archived (read-only) repositories too
+	filter         wsm.RepositoryFilter
+}
+
+func runPush(ctx context.Context, remoteName, workspaceName string, opts pushOptions) error {
+	// Use gh for remote detection when available, otherwise fall back to plain git
+	useGH := true
 	if err := checkGHCLI(ctx); err != nil {
-		return err
+		output.PrintWarning("%v", err)
+		output.PrintInfo("Falling back to git-only push detection")
+		useGH = false
 	}
 
 	// If no workspace specified, try to detect current workspace
@@ -102,6 +180,23 @@ func runPush(ctx context.Context, remoteName, workspaceName string, dryRun, forc
 	if err != nil {
 		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
 	}
+	if workspace, err = opts.filter.Apply(workspace); err != nil {
+		return err
+	}
+
+	// In fork mode, missing forks are created before pushing to the fork remote
+	forkPush := workspace.Fork != nil && remoteName == workspace.Fork.RemoteName()
+	if forkPush && !opts.dryRun {
+		for _, repo := range workspace.Repositories {
+			if workspace.PinFor(repo.Name) != "" || (repo.Archived && !opts.archived) {
+				continue
+			}
+			repoPath := filepath.Join(workspace.Path, repo.Name)
+			if _, err := wsm.EnsureFork(ctx, repo, repoPath, workspace.Fork); err != nil {
+				output.PrintWarning("Failed to set up the fork of %s: %v", repo.Name, err)
+			}
+		}
+	}
 
 	// Get workspace status
 	checker := wsm.NewStatusChecker()
@@ -113,9 +208,19 @@ func runPush(ctx context.Context, remoteName, workspaceName string, dryRun, forc
 	// Find branches that need pushing
 	var candidateBranches []PushCandidate
 	for _, repoStatus := range status.Repositories {
-		if candidate, needsPush := checkIfNeedsPush(ctx, repoStatus, workspace.Path, remoteName); needsPush {
-			candidateBranches = append(candidateBranches, candidate)
+		if repoStatus.Pinned != "" {
+			continue
+		}
+		// The fork remote is configured locally, so it is checked with git only
+		candidate, needsPush := checkIfNeedsPush(ctx, repoStatus, workspace, remoteName, useGH && !forkPush)
+		if !needsPush {
+			continue
 		}
+		if repoStatus.Repository.Archived && !opts.archived {
+			output.PrintWarning("Skipping %s: it is archived (read-only), push it with --include-archived", repoStatus.Repository.Name)
+			continue
+		}
+		candidateBranches = append(candidateBranches, candidate)
 	}
 
 	if len(candidateBranches) == 0 {
@@ -139,13 +244,31 @@ func runPush(ctx context.Context, remoteName, workspaceName string, dryRun, forc
 		fmt.Println()
 	}
 
-	if dryRun {
+	// Enforce the branch policy before pushing anything
+	if err := checkPushPolicy(candidateBranches, opts); err != nil {
+		return err
+	}
+	if err := checkLargeFiles(ctx, candidateBranches, opts.allowLarge, "re-run with --allow-large-files if they are intended"); err != nil {
+		return err
+	}
+
+	if opts.dryRun {
 		output.PrintInfo("Dry run mode - no branches will be pushed")
 		return nil
 	}
 
+	if err := enforceChecks(ctx, workspace, wsm.CheckBeforePush, opts.skipChecks); err != nil {
+		return err
+	}
+
+	if !opts.force {
+		if err := wsm.RequireInteractive("Pushing branches", "--force"); err != nil {
+			return err
+		}
+	}
+
 	// Push branches
-	reader := bufio.NewReader(os.Stdin)
+	var pushed []string
 	for _, candidate := range candidateBranches {
 		if !candidate.RemoteExists {
 			output.PrintWarning("Skipping %s/%s - remote repository '%s' not found or not accessible",
@@ -153,28 +276,90 @@ func runPush(ctx context.Context, remoteName, workspaceName string, dryRun, forc
 			continue
 		}
 
-		shouldPush := force
-		if !force {
-			fmt.Printf("Push %s/%s to %s? [y/N]: ", candidate.Repository, candidate.Branch, remoteName)
-			response, _ := reader.ReadString('\n')
-			response = strings.ToLower(strings.TrimSpace(response))
-			shouldPush = response == "y" || response == "yes"
+		shouldPush := opts.force
+		if !opts.force {
+			confirmed, err := ux.Confirm(fmt.Sprintf("Push %s/%s to %s?", candidate.Repository, candidate.Branch, remoteName), "")
+			if err != nil {
+				if errors.Is(err, ux.ErrAborted) {
+					output.PrintInfo("Operation cancelled.")
+					break
+				}
+				return err
+			}
+			shouldPush = confirmed
 		}
 
 		if shouldPush {
-			if err := pushBranch(ctx, candidate, remoteName, setUpstream); err != nil {
+			if err := pushBranch(ctx, candidate, remoteName, opts.setUpstream, opts.forceWithLease); err != nil {
 				output.PrintError("Failed to push %s/%s: %v", candidate.Repository, candidate.Branch, err)
 			} else {
 				output.PrintSuccess("Pushed %s/%s to %s", candidate.Repository, candidate.Branch, remoteName)
+				pushed = append(pushed, candidate.Repository)
 			}
 		} else {
 			output.PrintInfo("Skipped %s/%s", candidate.Repository, candidate.Branch)
 		}
 	}
 
+	if opts.ci && len(pushed) > 0 {
+		fmt.Println()
+		output.PrintHeader("Triggering CI")
+		return triggerCI(ctx, workspace, &wsm.CITriggerOptions{Repositories: pushed, Event: "push"}, false, 0, "table")
+	}
+
+	return nil
+}
+
+// checkPushPolicy reports every candidate branch the policy does not allow pushing
+func checkPushPolicy(candidates []PushCandidate, opts pushOptions) error {
+	policy, err := wsm.LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	action := wsm.PolicyActionPush
+	if opts.forceWithLease {
+		action = wsm.PolicyActionForcePush
+	}
+
+	var violations []string
+	for _, candidate := range candidates {
+		if err := policy.Check(wsm.PolicyCheck{
+			Action:     action,
+			Repository: candidate.Repository,
+			Branch:     candidate.Branch,
+			Confirmed:  opts.confirm,
+		}); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", candidate.Repository, err))
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.Errorf("push blocked by policy:\n  %s", strings.Join(violations, "\n  "))
+	}
 	return nil
 }
 
+// checkLargeFiles reports the large files added by the commits of every
+// candidate branch, and fails if the policy blocks them
+func checkLargeFiles(ctx context.Context, candidates []PushCandidate, allowed bool, hint string) error {
+	policy, err := wsm.LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	var files []wsm.LargeFile
+	for _, candidate := range candidates {
+		found, err := policy.FindLargeFiles(ctx, candidate.Repository, candidate.RepoPath)
+		if err != nil {
+			output.PrintWarning("Could not check %s for large files: %v", candidate.Repository, err)
+			continue
+		}
+		files = append(files, found...)
+	}
+	return policy.CheckLargeFiles(files, allowed, hint)
+}
+
 type PushCandidate struct {
 	Repository         string
 	Branch             string
@@ -193,11 +378,11 @@ type RepoInfo struct {
 	} `json:"defaultBranchRef"`
 }
 
-func checkIfNeedsPush(ctx context.Context, repoStatus wsm.RepositoryStatus, workspacePath, remoteName string) (PushCandidate, bool) {
+func checkIfNeedsPush(ctx context.Context, repoStatus wsm.RepositoryStatus, workspace *wsm.Workspace, remoteName string, useGH bool) (PushCandidate, bool) {
 	candidate := PushCandidate{
 		Repository: repoStatus.Repository.Name,
 		Branch:     repoStatus.CurrentBranch,
-		RepoPath:   filepath.Join(workspacePath, repoStatus.Repository.Name),
+		RepoPath:   filepath.Join(workspace.Path, repoStatus.Repository.Name),
 	}
 
 	log.Debug().
@@ -212,25 +397,35 @@ func checkIfNeedsPush(ctx context.Context, repoStatus wsm.RepositoryStatus, work
 		return candidate, false
 	}
 
-	// Get repository info from GitHub
-	repoInfo, err := getRepoInfo(ctx, candidate.RepoPath)
-	if err != nil {
-		log.Debug().Err(err).Str("repository", candidate.Repository).Msg("Failed to get repository info")
-		return candidate, false
-	}
+	if useGH {
+		// Get repository info from GitHub
+		repoInfo, err := getRepoInfo(ctx, candidate.RepoPath)
+		if err != nil {
+			log.Debug().Err(err).Str("repository", candidate.Repository).Msg("Failed to get repository info")
+			return candidate, false
+		}
 
-	candidate.RemoteRepo = repoInfo.NameWithOwner
-	candidate.RemoteExists = true
+		candidate.RemoteRepo = repoInfo.NameWithOwner
+		candidate.RemoteExists = true
 
-	// Check if remote repository exists (by trying to access it)
-	if !checkRemoteRepoExists(ctx, remoteName, repoInfo.NameWithOwner) {
-		log.Debug().Str("repository", candidate.Repository).Str("remote", remoteName).Str("remoteRepo", repoInfo.NameWithOwner).Msg("Remote repository not accessible")
-		candidate.RemoteExists = false
-		// Still return as candidate so user can see the issue
+		// Check if remote repository exists (by trying to access it)
+		if !checkRemoteRepoExists(ctx, remoteName, repoInfo.NameWithOwner) {
+			log.Debug().Str("repository", candidate.Repository).Str("remote", remoteName).Str("remoteRepo", repoInfo.NameWithOwner).Msg("Remote repository not accessible")
+			candidate.RemoteExists = false
+			// Still return as candidate so user can see the issue
+		}
+	} else {
+		// Git-only detection: the remote must be configured in the repository
+		remoteURL, err := getGitRemoteURL(ctx, candidate.RepoPath, remoteName)
+		candidate.RemoteRepo = remoteURL
+		candidate.RemoteExists = err == nil
+		if err != nil {
+			log.Debug().Err(err).Str("repository", candidate.Repository).Str("remote", remoteName).Msg("Remote not configured")
+		}
 	}
 
 	// Get local commits that aren't pushed to the remote yet
-	localCommits, err := getLocalCommits(ctx, candidate.RepoPath, remoteName, candidate.Branch)
+	localCommits, err := getLocalCommits(ctx, candidate.RepoPath, remoteName, candidate.Branch, workspace.BaseRefFor(ctx, repoStatus.Repository.Name, candidate.RepoPath))
 	if err != nil {
 		log.Debug().Err(err).Str("repository", candidate.Repository).Str("branch", candidate.Branch).Msg("Failed to get local commits")
 		// If we can't determine local commits, assume there might be some
@@ -276,6 +471,16 @@ func getRepoInfo(ctx context.Context, repoPath string) (*RepoInfo, error) {
 	return &info, nil
 }
 
+func getGitRemoteURL(ctx context.Context, repoPath, remoteName string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", remoteName)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "remote '%s' is not configured", remoteName)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 func checkRemoteRepoExists(ctx context.Context, remoteName, repoFullName string) bool {
 	// The repoFullName is already in "owner/repo" format, so we need to replace the owner with remoteName
 	parts := strings.Split(repoFullName, "/")
@@ -295,7 +500,7 @@ func checkRemoteRepoExists(ctx context.Context, remoteName, repoFullName string)
 	return err == nil
 }
 
-func getLocalCommits(ctx context.Context, repoPath, remoteName, branch string) (int, error) {
+func getLocalCommits(ctx context.Context, repoPath, remoteName, branch, baseRef string) (int, error) {
 	// Check if remote branch exists first
 	remoteRef := fmt.Sprintf("%s/%s", remoteName, branch)
 
@@ -306,11 +511,12 @@ func getLocalCommits(ctx context.Context, repoPath, remoteName, branch string) (
 
 	if err != nil {
 		// Remote branch might not exist, check if we have any commits to push
-		// by comparing against origin/main or just counting local commits
-		log.Debug().Err(err).Str("repoPath", repoPath).Str("remoteRef", remoteRef).Msg("Remote branch not found, checking against origin/main")
+		// by comparing against the base branch (e.g. origin/main, or upstream/main
+		// for forks) or just counting local commits
+		log.Debug().Err(err).Str("repoPath", repoPath).Str("remoteRef", remoteRef).Str("base", baseRef).Msg("Remote branch not found, checking against the base branch")
 
-		// Try to compare against origin/main
-		cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", "origin/main..HEAD")
+		// Try to compare against the base branch
+		cmd = exec.CommandContext(ctx, "git", "rev-list", "--count", baseRef+"..HEAD")
 		cmd.Dir = repoPath
 		output, err = cmd.Output()
 		if err != nil {
@@ -334,27 +540,38 @@ func getLocalCommits(ctx context.Context, repoPath, remoteName, branch string) (
 }
 
 func checkRemoteBranchExists(ctx context.Context, repoPath, remoteName, branch string) bool {
+	ctx, cancel := wsm.GitContext(ctx, "ls-remote")
+	defer cancel()
 	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", remoteName, branch)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	return err == nil && len(strings.TrimSpace(string(output))) > 0
 }
 
-func pushBranch(ctx context.Context, candidate PushCandidate, remoteName string, setUpstream bool) error {
+func pushBranch(ctx context.Context, candidate PushCandidate, remoteName string, setUpstream, forceWithLease bool) error {
+	if err := wsm.CheckGoReplacesNotCommitted(ctx, candidate.RepoPath); err != nil {
+		return err
+	}
+
 	args := []string{"push"}
 
 	if setUpstream {
 		args = append(args, "-u")
 	}
+	if forceWithLease {
+		args = append(args, "--force-with-lease")
+	}
 
 	args = append(args, remoteName, candidate.Branch)
 
+	ctx, cancel := wsm.GitContext(ctx, args...)
+	defer cancel()
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = candidate.RepoPath
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "git push failed: %s", string(output))
+		return errors.Wrapf(wsm.GitError(ctx, err), "git push failed: %s", string(output))
 	}
 
 	log.Debug().Str("repository", candidate.Repository).Str("branch", candidate.Branch).Str("remote", remoteName).Msg("Successfully pushed branch")
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
@@ -46,23 +47,36 @@ Examples:
   # Rebase specific repository against feature/base
   workspace-manager rebase my-repo --target feature/base
 
-  # Interactive rebase
-  workspace-manager rebase my-repo --interactive
+  # Reorder, squash, fixup or drop commits of every repository in one planner,
+  # then replay each repository with the resulting todo list
+  workspace-manager rebase --interactive
+
+  # Plan without rebasing: prints the todo list of each repository
+  workspace-manager rebase --interactive --dry-run
 
   # Dry run to see what would be done
   workspace-manager rebase --dry-run`,
 		Args: cobra.MaximumNArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("rebase", -1, func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				repository = args[0]
 			}
 			return runRebase(cmd.Context(), repository, targetBranch, interactive, dryRun)
-		},
+		}),
 	}
 
-	cmd.Flags().StringVar(&targetBranch, "target", "", "Target branch to rebase onto (defaults to detected default branch)")
+	cmd.Flags().StringVar(&targetBranch, "target", "", "Target branch to rebase onto (defaults to the branch each worktree was created from, then the default branch)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without actually rebasing")
-	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Interactive rebase")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Plan the rebase of every repository in a TUI (reorder, squash, fixup, drop)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		CurrentWorkspaceRepositoryCompletion(nil),
+	)
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"target": BranchCompletion(nil),
+		},
+	)
 
 	return cmd
 }
@@ -80,12 +94,19 @@ type RebaseResult struct {
 }
 
 func runRebase(ctx context.Context, repository, targetBranch string, interactive, dryRun bool) error {
+	if interactive && wsm.NonInteractive() {
+		return errors.New("--interactive cannot be combined with --non-interactive")
+	}
+
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
 
 	if repository != "" {
+		if ref := workspace.PinFor(repository); ref != "" {
+			return errors.Errorf("repository '%s' is pinned to %s and cannot be rebased", repository, ref)
+		}
 		if targetBranch != "" {
 			output.PrintHeader("🔄 Rebasing repository '%s' onto '%s'", repository, targetBranch)
 		} else {
@@ -103,34 +124,134 @@ func runRebase(ctx context.Context, repository, targetBranch string, interactive
 		output.PrintInfo("Dry run mode - no changes will be made")
 	}
 
+	refreshStaleRepositories(ctx, workspace, wsm.FreshnessRebase, 0, false)
+
+	if interactive {
+		return runInteractiveRebase(ctx, workspace, repository, targetBranch, dryRun)
+	}
+
 	var results []RebaseResult
 
 	if repository != "" {
 		// Rebase specific repository
-		result := rebaseRepository(ctx, workspace, repository, targetBranch, interactive, dryRun)
+		result := rebaseRepository(ctx, workspace, repository, targetBranch, dryRun)
 		results = append(results, result)
 	} else {
 		// Rebase all repositories
 		for _, repo := range workspace.Repositories {
-			result := rebaseRepository(ctx, workspace, repo.Name, targetBranch, interactive, dryRun)
+			if ref := workspace.PinFor(repo.Name); ref != "" {
+				output.PrintInfo("Skipping %s (pinned to %s)", repo.Name, ref)
+				continue
+			}
+			result := rebaseRepository(ctx, workspace, repo.Name, targetBranch, dryRun)
+			results = append(results, result)
+		}
+	}
+
+	return printRebaseResults(results, dryRun)
+}
+
+// runInteractiveRebase collects the commits of the repositories, lets the user
+// edit the plan of each one in a single TUI and replays them with git rebase -i,
+// feeding the planned todo lists through GIT_SEQUENCE_EDITOR
+func runInteractiveRebase(ctx context.Context, workspace *wsm.Workspace, repository, targetBranch string, dryRun bool) error {
+	var plans []*wsm.RebasePlan
+	for _, repo := range workspace.Repositories {
+		if repository != "" && repo.Name != repository {
+			continue
+		}
+		if ref := workspace.PinFor(repo.Name); ref != "" {
+			output.PrintInfo("Skipping %s (pinned to %s)", repo.Name, ref)
+			continue
+		}
+
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		onto := targetBranch
+		if onto == "" {
+			onto = workspace.TargetBranchFor(repo.Name)
+		}
+		if onto == "" {
+			onto = workspace.DefaultBranchFor(ctx, repo.Name, repoPath)
+		}
+		if !branchExists(ctx, repoPath, onto) {
+			if err := fetchBranch(ctx, repoPath, workspace.BaseRemoteOf(repo.Name), onto); err != nil {
+				output.PrintWarning("Skipping %s: target branch '%s' not found locally or on remote", repo.Name, onto)
+				continue
+			}
+		}
+
+		plan, err := wsm.LoadRebasePlan(ctx, repo.Name, repoPath, onto)
+		if err != nil {
+			return err
+		}
+		if len(plan.Commits) == 0 {
+			output.PrintInfo("Skipping %s (no commits on top of %s)", repo.Name, onto)
+			continue
+		}
+		plans = append(plans, plan)
+	}
+
+	if len(plans) == 0 {
+		output.PrintInfo("No commits to rebase.")
+		return nil
+	}
+
+	confirmed, err := editRebasePlans(plans)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		output.PrintInfo("Operation cancelled.")
+		return nil
+	}
+
+	var results []RebaseResult
+	for _, plan := range plans {
+		if !plan.Changed() {
+			continue
+		}
+		if dryRun {
+			output.PrintHeader("%s onto %s", plan.Repository, plan.Onto)
+			fmt.Print(plan.Todo())
+			fmt.Println()
+			continue
+		}
+
+		result := RebaseResult{Repository: plan.Repository, Success: true, TargetBranch: plan.Onto}
+		result.CommitsBefore, _ = getCommitsAhead(ctx, plan.Path, plan.Onto)
+		if err := plan.Execute(ctx); err != nil {
+			result.Success = false
+			result.Error = fmt.Sprintf("rebase failed: %v", err)
+			result.Conflicts = hasRebaseConflicts(ctx, plan.Path)
 			results = append(results, result)
+			continue
 		}
+		result.Rebased = true
+		result.CommitsAfter, _ = getCommitsAhead(ctx, plan.Path, plan.Onto)
+		results = append(results, result)
 	}
 
+	if dryRun {
+		return nil
+	}
+	if len(results) == 0 {
+		output.PrintInfo("No plan changes, nothing to rebase.")
+		return nil
+	}
 	return printRebaseResults(results, dryRun)
 }
 
-func rebaseRepository(ctx context.Context, workspace *wsm.Workspace, repoName, targetBranch string, interactive, dryRun bool) RebaseResult {
+func rebaseRepository(ctx context.Context, workspace *wsm.Workspace, repoName, targetBranch string, dryRun bool) RebaseResult {
 	repoPath := filepath.Join(workspace.Path, repoName)
 
-	// If no target branch specified, detect the default branch for this repository
+	// If no target branch specified, use the branch the worktree was created
+	// from, then the default branch of the repository
 	actualTargetBranch := targetBranch
 	if actualTargetBranch == "" {
-		if detectedBranch, err := wsm.GetGitDefaultBranch(ctx, repoPath); err == nil {
-			actualTargetBranch = detectedBranch
-		} else {
-			actualTargetBranch = "main" // fallback
-		}
+		actualTargetBranch = workspace.TargetBranchFor(repoName)
+	}
+	if actualTargetBranch == "" {
+		actualTargetBranch = workspace.DefaultBranchFor(ctx, repoName, repoPath)
 	}
 
 	result := RebaseResult{
@@ -181,7 +302,7 @@ func rebaseRepository(ctx context.Context, workspace *wsm.Workspace, repoName, t
 	// Check if target branch exists
 	if !branchExists(ctx, repoPath, actualTargetBranch) {
 		// Try to fetch it from remote
-		if err := fetchBranch(ctx, repoPath, actualTargetBranch); err != nil {
+		if err := fetchBranch(ctx, repoPath, workspace.BaseRemoteOf(repoName), actualTargetBranch); err != nil {
 			result.Success = false
 			result.Error = fmt.Sprintf("target branch '%s' not found locally or on remote", actualTargetBranch)
 			return result
@@ -189,7 +310,7 @@ func rebaseRepository(ctx context.Context, workspace *wsm.Workspace, repoName, t
 	}
 
 	// Perform rebase
-	if err := performRebase(ctx, repoPath, actualTargetBranch, interactive); err != nil {
+	if err := performRebase(ctx, repoPath, actualTargetBranch); err != nil {
 		result.Success = false
 		result.Error = fmt.Sprintf("rebase failed: %v", err)
 		result.Conflicts = hasRebaseConflicts(ctx, repoPath)
@@ -238,20 +359,17 @@ func branchExists(ctx context.Context, repoPath, branch string) bool {
 	return cmd.Run() == nil
 }
 
-func fetchBranch(ctx context.Context, repoPath, branch string) error {
-	// Try to fetch the branch from origin
-	cmd := exec.CommandContext(ctx, "git", "fetch", "origin", branch+":"+branch)
+func fetchBranch(ctx context.Context, repoPath, remote, branch string) error {
+	// Try to fetch the branch from the remote base branches come from
+	ctx, cancel := wsm.GitContext(ctx, "fetch")
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "fetch", remote, branch+":"+branch)
 	cmd.Dir = repoPath
-	return cmd.Run()
+	return wsm.GitError(ctx, cmd.Run())
 }
 
-func performRebase(ctx context.Context, repoPath, targetBranch string, interactive bool) error {
-	var cmd *exec.Cmd
-	if interactive {
-		cmd = exec.CommandContext(ctx, "git", "rebase", "-i", targetBranch)
-	} else {
-		cmd = exec.CommandContext(ctx, "git", "rebase", targetBranch)
-	}
+func performRebase(ctx context.Context, repoPath, targetBranch string) error {
+	cmd := exec.CommandContext(ctx, "git", "rebase", targetBranch)
 	cmd.Dir = repoPath
 
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,168 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewReleaseCommand creates the release command
+func NewReleaseCommand() *cobra.Command {
+	var (
+		workspaceName string
+		outputFormat  string
+	)
+	options := &wsm.ReleaseOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "release <version>",
+		Short: "Tag every repository of a workspace with a release version",
+		Long: `Create an annotated tag named <version> at the current HEAD of every
+repository of the workspace, push the tags to origin and record the tag set in
+the workspace metadata (see 'wsm info'). Pinned repositories are skipped.
+
+All repositories must be clean and must not have the tag yet. If bumping or
+tagging fails in one repository, the tags and bump commits already created are
+removed again. A failed push keeps the tags; push them with
+'git push origin <version>'.
+
+Before tagging, bump commands from release.yaml in the workspace-manager config
+directory run in each repository, and their changes are committed:
+
+  bump-commands: ["echo {version} > VERSION"]
+  commit-message: "chore(release): {version}"
+  repositories:
+    web:
+      bump-commands: ["npm version --no-git-tag-version {version}"]
+
+With --push, bump commits are pushed too; pushing a protected branch requires
+--confirm.
+
+Examples:
+  # Tag and push all repositories of the current workspace
+  wsm release v1.4.0
+
+  # Signed tags with a custom message, without pushing
+  wsm release v1.4.0 --sign -m "Release {version}: spring cleanup" --push=false
+
+  # Show what would happen
+  wsm release v1.4.0 --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: audited("release", -1, func(cmd *cobra.Command, args []string) error {
+			options.Version = args[0]
+			return runRelease(cmd.Context(), workspaceName, outputFormat, options)
+		}),
+	}
+
+	cmd.Flags().StringVar(&workspaceName, "workspace", "", "Workspace name (default: current workspace)")
+	cmd.Flags().StringVarP(&options.Message, "message", "m", "", "Tag message, {version} is replaced (default \"Release {version}\")")
+	cmd.Flags().BoolVarP(&options.Sign, "sign", "s", false, "Create signed tags (gpg or ssh, as configured in git)")
+	cmd.Flags().StringVar(&options.SigningKey, "signing-key", "", "Key to sign tags with (implies --sign)")
+	cmd.Flags().BoolVar(&options.Bump, "bump", true, "Run the bump commands of release.yaml before tagging")
+	cmd.Flags().BoolVar(&options.Push, "push", true, "Push tags and bump commits to origin")
+	cmd.Flags().BoolVar(&options.Confirm, "confirm", false, "Allow pushing bump commits to protected branches")
+	cmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "Check the repositories and show what would be done")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runRelease(ctx context.Context, workspaceName, outputFormat string, options *wsm.ReleaseOptions) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	var workspace *wsm.Workspace
+	var err error
+	if workspaceName != "" {
+		workspace, err = loadWorkspace(workspaceName)
+	} else {
+		workspace, err = detectCurrentWorkspace()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to find workspace")
+	}
+
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	release, results, releaseErr := wm.Release(ctx, workspace, options)
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(map[string]interface{}{"release": release, "repositories": results}); err != nil {
+			return err
+		}
+	} else {
+		printReleaseResults(options, results)
+	}
+	if releaseErr != nil {
+		return releaseErr
+	}
+
+	if release != nil && outputFormat == "table" {
+		if options.Push && !release.Pushed {
+			output.PrintWarning("Release %s created in %d repositories, but not all pushes succeeded", release.Version, len(release.Tags))
+		} else {
+			output.PrintSuccess("Release %s created in %d repositories", release.Version, len(release.Tags))
+		}
+	}
+	if release != nil && options.Push && !release.Pushed {
+		return errors.New("some pushes failed")
+	}
+	return nil
+}
+
+func printReleaseResults(options *wsm.ReleaseOptions, results []wsm.ReleaseResult) {
+	if options.DryRun {
+		output.PrintHeader("Release %s preview", options.Version)
+	} else {
+		output.PrintHeader("Release %s", options.Version)
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			fmt.Printf("📌 %s: pinned, skipped\n", result.Repository)
+			continue
+		case result.Error != "":
+			fmt.Printf("❌ %s: %s\n", result.Repository, result.Error)
+		case result.RolledBack:
+			fmt.Printf("↩️  %s: rolled back\n", result.Repository)
+		case options.DryRun:
+			fmt.Printf("🔍 %s: would tag %s\n", result.Repository, shortHash(result.Commit))
+		default:
+			var done []string
+			if result.Bumped {
+				done = append(done, "bumped")
+			}
+			if result.Tagged {
+				done = append(done, "tagged "+shortHash(result.Commit))
+			}
+			if result.Pushed {
+				done = append(done, "pushed")
+			}
+			fmt.Printf("✅ %s: %s\n", result.Repository, strings.Join(done, ", "))
+		}
+		if options.DryRun {
+			for _, command := range result.BumpCommands {
+				fmt.Printf("    %s\n", output.DimStyle.Render("$ "+command))
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,105 @@
+package cmds
+
+import (
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewRemoteCommand creates the remote command
+func NewRemoteCommand() *cobra.Command {
+	var (
+		workspaceName string
+		reset         bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "remote [remote]",
+		Short: "Show or override the primary remote of a workspace",
+		Long: `Show the primary remote of every repository of a workspace, or make all of
+them use another one. The primary remote is the one workspace branches are
+created from, pushed to and tracked on by 'wsm add', 'wsm sync push',
+'wsm commit --push', 'wsm pr' and 'wsm merge', and base branches are compared
+with unless a repository has an upstream remote.
+
+Repositories use origin, or the remote set with 'wsm repos remote --primary',
+unless their workspace overrides it. Every repository of the workspace must
+have a remote of that name.
+
+Examples:
+  # Show the remotes of the current workspace
+  wsm remote
+
+  # Push the current workspace to the 'gitlab' remote
+  wsm remote gitlab
+
+  # Back to the remotes of the registry
+  wsm remote --reset --workspace my-feature`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 && reset {
+				return errors.New("--reset cannot be combined with a remote name")
+			}
+			workspace, err := namedOrCurrentWorkspace(workspaceName, "wsm remote --workspace <workspace-name>")
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 && !reset {
+				printWorkspaceRemotes(workspace)
+				return nil
+			}
+
+			wm, err := wsm.NewWorkspaceManager()
+			if err != nil {
+				return errors.Wrap(err, "failed to create workspace manager")
+			}
+			remote := ""
+			if len(args) == 1 {
+				remote = args[0]
+			}
+			if err := wm.SetWorkspaceRemote(cmd.Context(), workspace, remote); err != nil {
+				return err
+			}
+			if remote == "" {
+				output.PrintSuccess("Workspace '%s' uses the remotes of the registry again", workspace.Name)
+			} else {
+				output.PrintSuccess("Workspace '%s' uses the '%s' remote", workspace.Name, remote)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&workspaceName, "workspace", "", "Workspace name")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Use the remotes of the registry again")
+
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionValues("origin", "upstream"))
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+		},
+	)
+
+	return cmd
+}
+
+// printWorkspaceRemotes lists the primary and base remote of every repository
+func printWorkspaceRemotes(workspace *wsm.Workspace) {
+	output.PrintHeader("Workspace: %s", workspace.Name)
+	if workspace.Remote != "" {
+		output.PrintInfo("   (all repositories use '%s')", workspace.Remote)
+	}
+	for _, repo := range workspace.Repositories {
+		fmt.Printf("  %s: %s", repo.Name, repo.RemoteName())
+		if repo.BaseRemote() != repo.RemoteName() {
+			fmt.Printf(" (base branches from %s)", repo.BaseRemote())
+		}
+		if workspace.Fork != nil {
+			fmt.Printf(" (pushes to the fork remote %s)", workspace.Fork.RemoteName())
+		}
+		fmt.Println()
+	}
+}
@@ -11,6 +11,8 @@ import (
 func NewRemoveCommand() *cobra.Command {
 	var force bool
 	var removeFiles bool
+	var plan bool
+	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   "remove <workspace-name> <repo-name>",
@@ -32,9 +34,12 @@ Examples:
   workspace-manager remove my-feature my-old-repo --force
 
   # Remove repository and its directory from workspace
-  workspace-manager remove my-feature my-old-repo --remove-files`,
+  workspace-manager remove my-feature my-old-repo --remove-files
+
+  # Preview the go.work changes, the work at stake and conflicts
+  workspace-manager remove my-feature my-old-repo --plan`,
 		Args: cobra.ExactArgs(2),
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: audited("remove", 0, func(cmd *cobra.Command, args []string) error {
 			workspaceName := args[0]
 			repoName := args[1]
 
@@ -43,17 +48,30 @@ Examples:
 				return errors.Wrap(err, "failed to create workspace manager")
 			}
 
+			if plan {
+				repoPlan, err := wm.PlanRemoveRepository(cmd.Context(), workspaceName, repoName, force, removeFiles)
+				if err != nil {
+					return err
+				}
+				return emitRepositoryPlan(repoPlan, outputFormat)
+			}
+
 			return wm.RemoveRepositoryFromWorkspace(cmd.Context(), workspaceName, repoName, force, removeFiles)
-		},
+		}),
 	}
 
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force remove worktree even with uncommitted changes")
 	cmd.Flags().BoolVar(&removeFiles, "remove-files", false, "Remove the repository directory from workspace")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Only show what removing the repository would do and what conflicts with it")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format of --plan (table, json)")
 
 	carapace.Gen(cmd).PositionalCompletion(
 		WorkspaceNameCompletion(),
 		WorkspaceRepositoryCompletion(),
 	)
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
 
 	return cmd
 }
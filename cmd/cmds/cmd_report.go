@@ -0,0 +1,201 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// reportCommitLimit caps the commits listed by the text and markdown reports
+const reportCommitLimit = 20
+
+// NewReportCommand creates the report command
+func NewReportCommand() *cobra.Command {
+	var (
+		since        string
+		outputFormat string
+		filters      repoFilterFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report [workspace]",
+		Short: "Summarize recent activity across workspace repositories",
+		Long: `Aggregate the commits made in a workspace over a period: commits, authors,
+files changed and lines added or removed, in total and per repository.
+
+The markdown output is meant to be pasted into standup notes or used as a
+progress summary by agents; json contains every commit with its files.
+
+Examples:
+  # Activity of the current workspace in the last week
+  wsm report
+
+  # Yesterday's work in a workspace, as markdown
+  wsm report my-feature --since 1d --output markdown
+
+  # Since a date, for one repository
+  wsm report --since 2024-06-01 --repo app --output json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch outputFormat {
+			case "text", "json", "markdown":
+			default:
+				return errors.Errorf("unsupported output format '%s' (supported: text, json, markdown)", outputFormat)
+			}
+			sinceTime, err := parseReportSince(since)
+			if err != nil {
+				return err
+			}
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			return runReport(cmd.Context(), workspaceName, sinceTime, &filters, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "1w", "Start of the period: an age (1w, 3d, 12h) or a date (2006-01-02)")
+	filters.register(cmd, nil)
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, markdown)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"since":  carapace.ActionValues("1d", "1w", "2w", "30d"),
+			"output": carapace.ActionValues("text", "json", "markdown"),
+		},
+	)
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+// parseReportSince accepts an age like 1w or 3d, or anything --since of 'wsm history' accepts
+func parseReportSince(value string) (time.Time, error) {
+	if age, err := parseAge(value); err == nil {
+		return time.Now().Add(-age), nil
+	}
+	t, err := parseHistorySince(value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid --since value %q, expected an age like 1w or a date like 2006-01-02", value)
+	}
+	return t, nil
+}
+
+func runReport(ctx context.Context, workspaceName string, since time.Time, filters *repoFilterFlags, outputFormat string) error {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get current directory")
+		}
+		if workspaceName, err = detectWorkspace(cwd); err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm report <workspace-name>'")
+		}
+	}
+
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+
+	report, err := wsm.NewSyncOperations(workspace).GetActivityReport(ctx, since, "")
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat {
+	case "json":
+		return wsm.PrintJSON(report)
+	case "markdown":
+		fmt.Print(formatReportMarkdown(report))
+		return nil
+	}
+	return printReport(report)
+}
+
+func printReport(report *wsm.ActivityReport) error {
+	output.PrintHeader("Activity in %s since %s", report.Workspace, report.Since.Format("2006-01-02 15:04"))
+	if report.Filter != nil {
+		output.PrintInfo("   (%s)", report.Filter)
+	}
+	fmt.Printf("  %d commit(s) by %d author(s), %d file(s) changed, +%d -%d\n\n",
+		report.TotalCommits, len(report.Authors), report.FilesChanged, report.Insertions, report.Deletions)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tCOMMITS\tFILES\tLINES\tAUTHORS\tLAST COMMIT")
+	fmt.Fprintln(w, "----------\t-------\t-----\t-----\t-------\t-----------")
+	for _, repo := range report.Repositories {
+		lastCommit := "-"
+		if !repo.LastCommit.IsZero() {
+			lastCommit = formatRelativeTime(repo.LastCommit)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t+%d -%d\t%s\t%s\n",
+			repo.Repository, repo.Commits, repo.FilesChanged, repo.Insertions, repo.Deletions, strings.Join(repo.Authors, ", "), lastCommit)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	if len(report.Authors) > 0 {
+		fmt.Println()
+		output.PrintHeader("Authors")
+		for _, author := range report.Authors {
+			fmt.Printf("  %s <%s>: %d commit(s) in %s\n", author.Name, author.Email, author.Commits, strings.Join(author.Repositories, ", "))
+		}
+	}
+
+	if len(report.Commits) > 0 {
+		fmt.Println()
+		output.PrintHeader("Commits")
+		for i, commit := range report.Commits {
+			if i == reportCommitLimit {
+				fmt.Printf("  ... and %d more (see 'wsm report --output json')\n", len(report.Commits)-reportCommitLimit)
+				break
+			}
+			fmt.Printf("  %s %s  %s  %s\n", commit.ShortHash, commit.Repository, commit.Subject,
+				output.DimStyle.Render(fmt.Sprintf("(%s, %s)", commit.Author, formatRelativeTime(commit.Date))))
+		}
+	}
+	return nil
+}
+
+// formatReportMarkdown renders a report for standup notes and progress summaries
+func formatReportMarkdown(report *wsm.ActivityReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s: activity since %s\n\n", report.Workspace, report.Since.Format("2006-01-02"))
+	if report.Branch != "" {
+		fmt.Fprintf(&b, "Branch `%s`. ", report.Branch)
+	}
+	fmt.Fprintf(&b, "%d commit(s) by %d author(s), %d file(s) changed (+%d -%d).\n\n",
+		report.TotalCommits, len(report.Authors), report.FilesChanged, report.Insertions, report.Deletions)
+
+	b.WriteString("| Repository | Commits | Files | Lines | Authors |\n")
+	b.WriteString("|---|---:|---:|---|---|\n")
+	for _, repo := range report.Repositories {
+		fmt.Fprintf(&b, "| %s | %d | %d | +%d -%d | %s |\n",
+			repo.Repository, repo.Commits, repo.FilesChanged, repo.Insertions, repo.Deletions, strings.Join(repo.Authors, ", "))
+	}
+
+	if len(report.Commits) > 0 {
+		b.WriteString("\n### Commits\n\n")
+		for i, commit := range report.Commits {
+			if i == reportCommitLimit {
+				fmt.Fprintf(&b, "- ... and %d more\n", len(report.Commits)-reportCommitLimit)
+				break
+			}
+			fmt.Fprintf(&b, "- **%s** %s (`%s`, %s)\n", commit.Repository, commit.Subject, commit.ShortHash, commit.Author)
+		}
+	}
+	return b.String()
+}
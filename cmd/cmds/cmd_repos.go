@@ -0,0 +1,646 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewReposCommand creates the repos command
+func NewReposCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repos",
+		Short: "Manage repository tags, groups, locations and remotes",
+		Long: `Manage tags and named groups of repositories in the registry, update the
+registry when a repository moves on disk, configure the remote base branches
+come from and how large repositories are cloned, mark archived repositories
+read-only, see which workspaces use a repository, and export the registry to
+import it on another machine.
+
+Groups can be used wherever repositories are listed by prefixing them with '@':
+  wsm create my-feature --repos @backend,docs`,
+	}
+
+	cmd.AddCommand(
+		NewReposTagCommand(),
+		NewReposGroupCommand(),
+		NewReposMoveCommand(),
+		NewReposRemoteCommand(),
+		NewReposCloneOptionsCommand(),
+		NewReposArchiveCommand(),
+		NewReposUsesCommand(),
+		NewReposExportCommand(),
+		NewReposImportCommand(),
+	)
+
+	return cmd
+}
+
+// NewReposTagCommand creates the repos tag command
+func NewReposTagCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Add or remove repository tags",
+		Long: `Edit the tags (categories) of a registered repository.
+
+Tags are kept across 'wsm discover'. Removing an auto-detected tag keeps it
+removed on the next discovery.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <repo-name> <tag...>",
+		Short: "Add tags to a repository",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposTag(args[0], args[1:], true)
+		},
+	}
+	carapace.Gen(addCmd).PositionalCompletion(RepositoryNameCompletion())
+	carapace.Gen(addCmd).PositionalAnyCompletion(TagCompletion())
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <repo-name> <tag...>",
+		Short: "Remove tags from a repository",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposTag(args[0], args[1:], false)
+		},
+	}
+	carapace.Gen(removeCmd).PositionalCompletion(RepositoryNameCompletion())
+	carapace.Gen(removeCmd).PositionalAnyCompletion(TagCompletion())
+
+	cmd.AddCommand(addCmd, removeCmd)
+
+	return cmd
+}
+
+// NewReposGroupCommand creates the repos group command
+func NewReposGroupCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "group",
+		Short: "Manage named repository groups",
+		Long: `Define named groups of repositories, e.g. "backend" = app, lib, proto.
+
+Use a group with '@name' in 'wsm create --repos'.
+
+Examples:
+  # Define a group
+  wsm repos group set backend app lib proto
+
+  # List groups
+  wsm repos group
+
+  # Delete a group
+  wsm repos group delete backend`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposGroupList(format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json")
+
+	setCmd := &cobra.Command{
+		Use:   "set <group-name> <repo-name...>",
+		Short: "Define or replace a group",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposGroupSet(args[0], args[1:])
+		},
+	}
+	carapace.Gen(setCmd).PositionalCompletion(GroupNameCompletion())
+	carapace.Gen(setCmd).PositionalAnyCompletion(RepositoryNameCompletion())
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <group-name>",
+		Short: "Delete a group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposGroupDelete(args[0])
+		},
+	}
+	carapace.Gen(deleteCmd).PositionalCompletion(GroupNameCompletion())
+
+	cmd.AddCommand(setCmd, deleteCmd)
+
+	return cmd
+}
+
+// NewReposMoveCommand creates the repos move command
+func NewReposMoveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "move <repo-name> <new-path>",
+		Short: "Update the registry after moving a repository on disk",
+		Long: `Point a registered repository at its new location after moving it on disk,
+and relink every workspace using it: the workspace configuration is updated and
+'git worktree repair' rewrites the links between the repository and its
+worktrees, which break when the repository moves.
+
+Move the directory first, then run this command. 'wsm discover' detects moves
+on its own when it finds a repository whose remote URL matches a registered
+repository that no longer exists.
+
+Examples:
+  # After mv ~/code/app ~/src/app
+  wsm repos move app ~/src/app`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposMove(cmd.Context(), args[0], args[1])
+		},
+	}
+	carapace.Gen(cmd).PositionalCompletion(RepositoryNameCompletion(), carapace.ActionDirectories())
+
+	return cmd
+}
+
+// NewReposRemoteCommand creates the repos remote command
+func NewReposRemoteCommand() *cobra.Command {
+	var reset, primary bool
+
+	cmd := &cobra.Command{
+		Use:   "remote <repo-name> [remote]",
+		Short: "Show or set the remote base branches are compared with",
+		Long: `Configure the remote a repository's base branches come from. By default this is
+the primary remote, origin. For a fork, where origin is your fork and the
+original project is another remote (typically 'upstream'), status compares
+branches with upstream/<default branch> to tell whether they are merged or need
+a rebase, 'wsm pr' and 'wsm push' count commits against it, 'wsm sync --pull'
+fetches it and 'wsm merge' pulls the base branch from it. Branches are still
+pushed to the primary remote.
+
+With --primary, configure the primary remote instead: the one workspace
+branches are created from, pushed to and tracked on, and merges are pushed to,
+for clones whose main remote is not named origin. A workspace can override it
+for all its repositories with 'wsm remote'.
+
+The settings are stored in the registry, kept across 'wsm discover', and
+applied to the workspaces already using the repository.
+
+Examples:
+  # Show the configured remote
+  wsm repos remote app
+
+  # Compare with the original project of a fork
+  wsm repos remote app upstream
+
+  # Go back to origin
+  wsm repos remote app --reset
+
+  # Push to and track the 'github' remote
+  wsm repos remote app github --primary`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 && !reset {
+				return runReposShowRemote(args[0], primary)
+			}
+			if len(args) == 2 && reset {
+				return errors.New("--reset cannot be combined with a remote name")
+			}
+			remote := ""
+			if len(args) == 2 {
+				remote = args[1]
+			}
+			return runReposSetRemote(cmd.Context(), args[0], remote, primary)
+		},
+	}
+
+	cmd.Flags().BoolVar(&reset, "reset", false, "Go back to the default remote")
+	cmd.Flags().BoolVar(&primary, "primary", false, "Show or set the primary remote instead of the base remote")
+
+	carapace.Gen(cmd).PositionalCompletion(RepositoryNameCompletion(), carapace.ActionValues("upstream", "origin"))
+
+	return cmd
+}
+
+// NewReposCloneOptionsCommand creates the repos clone-options command
+func NewReposCloneOptionsCommand() *cobra.Command {
+	var (
+		options wsm.CloneOptions
+		reset   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "clone-options <repo-name>",
+		Short: "Show or set partial and shallow clone options of a repository",
+		Long: `Configure how a very large repository is cloned when a workspace needs it and
+it is not cloned yet, and how it is fetched afterwards.
+
+--filter blobless clones without file contents, which git fetches when they
+are checked out; treeless also defers directory trees. --depth clones and
+fetches only that many commits of every branch.
+
+Without flags, the current options are shown.
+
+Examples:
+  # Blobless, shallow clones of a monorepo
+  wsm repos clone-options monorepo --filter blobless --depth 50
+
+  # Back to full clones
+  wsm repos clone-options monorepo --reset`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if reset {
+				if !options.IsZero() {
+					return errors.New("--reset cannot be combined with --filter or --depth")
+				}
+				return runReposSetCloneOptions(args[0], options)
+			}
+			if !cmd.Flags().Changed("filter") && !cmd.Flags().Changed("depth") {
+				return runReposShowCloneOptions(args[0])
+			}
+			return runReposSetCloneOptions(args[0], options)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Filter, "filter", "", "Partial clone filter: blobless or treeless")
+	cmd.Flags().IntVar(&options.Depth, "depth", 0, "Clone and fetch shallowly with this many commits")
+	cmd.Flags().BoolVar(&reset, "reset", false, "Clone and fetch in full again")
+
+	carapace.Gen(cmd).PositionalCompletion(RepositoryNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"filter": carapace.ActionValues(wsm.CloneFilters...),
+		},
+	)
+
+	return cmd
+}
+
+// NewReposArchiveCommand creates the repos archive command
+func NewReposArchiveCommand() *cobra.Command {
+	var unset bool
+
+	cmd := &cobra.Command{
+		Use:   "archive <repo-name>",
+		Short: "Mark a repository as archived (read-only)",
+		Long: `Mark a repository as archived, or active again with --unset. Archived
+repositories are read-only: 'wsm commit', 'wsm push' and 'wsm sync' leave them
+alone unless --include-archived is given, and 'wsm status' shows them as
+read-only, so nothing is pushed to a project by accident.
+
+Repositories discovered with 'wsm discover --github-org --include-archived' are
+marked when GitHub reports them archived. The mark is stored in the registry,
+kept across 'wsm discover', and applied to the workspaces already using the
+repository.
+
+Examples:
+  # Stop committing to and pushing legacy-api
+  wsm repos archive legacy-api
+
+  # Make it writable again
+  wsm repos archive legacy-api --unset`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposArchive(args[0], !unset)
+		},
+	}
+
+	cmd.Flags().BoolVar(&unset, "unset", false, "Mark the repository as active again")
+
+	carapace.Gen(cmd).PositionalCompletion(RepositoryNameCompletion())
+
+	return cmd
+}
+
+// NewReposUsesCommand creates the repos uses command
+func NewReposUsesCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "uses <repo-name>",
+		Short: "Show the workspaces and branches using a repository",
+		Long: `List the worktrees of a repository: the workspace each belongs to and the
+branch checked out in it. Branches are read from git, so a branch switched
+inside a worktree is shown as it is now. Worktrees created outside wsm are
+listed without a workspace.
+
+Examples:
+  # Which workspaces use app?
+  wsm repos uses app
+
+  # As JSON
+  wsm repos uses app --format json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return errors.Errorf("unsupported format '%s' (supported: table, json)", format)
+			}
+			return runReposUses(cmd.Context(), args[0], format)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, json")
+	carapace.Gen(cmd).PositionalCompletion(RepositoryNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"format": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runReposUses(ctx context.Context, repoName, format string) error {
+	uses, err := wsm.NewWorkspaceService("").RepositoryUses(ctx, repoName)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		if uses == nil {
+			uses = []wsm.RepositoryUse{}
+		}
+		return wsm.PrintJSON(uses)
+	}
+
+	if len(uses) == 0 {
+		output.PrintInfo("No workspace uses %s", repoName)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tBRANCH\tWORKTREE")
+	fmt.Fprintln(w, "---------\t------\t--------")
+	for _, use := range uses {
+		workspace := use.Workspace
+		if workspace == "" {
+			workspace = "-"
+		}
+		branch := use.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		worktree := use.WorktreePath
+		if use.Missing {
+			worktree += " (missing)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", workspace, branch, worktree)
+	}
+	return w.Flush()
+}
+
+func runReposShowCloneOptions(repoName string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+	for _, repo := range discoverer.GetRepositories() {
+		if repo.Name != repoName {
+			continue
+		}
+		if repo.CloneOptions == nil {
+			fmt.Println(wsm.CloneOptions{})
+		} else {
+			fmt.Println(*repo.CloneOptions)
+		}
+		return nil
+	}
+	return errors.Errorf("repository '%s' not found in registry", repoName)
+}
+
+func runReposSetCloneOptions(repoName string, options wsm.CloneOptions) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	updated, err := wm.SetCloneOptions(repoName, options)
+	if err != nil {
+		return err
+	}
+	output.PrintSuccess("%s clone options: %s", repoName, options)
+	if len(updated) > 0 {
+		output.PrintInfo("Updated workspaces: %s", strings.Join(updated, ", "))
+	}
+	return nil
+}
+
+func runReposArchive(repoName string, archived bool) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	updated, err := wm.SetArchived(repoName, archived)
+	if err != nil {
+		return err
+	}
+	if archived {
+		output.PrintSuccess("%s is archived: commit and push skip it", repoName)
+	} else {
+		output.PrintSuccess("%s is active again", repoName)
+	}
+	if len(updated) > 0 {
+		output.PrintInfo("Updated workspaces: %s", strings.Join(updated, ", "))
+	}
+	return nil
+}
+
+func runReposShowRemote(repoName string, primary bool) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+	for _, repo := range discoverer.GetRepositories() {
+		if repo.Name != repoName {
+			continue
+		}
+		if primary {
+			fmt.Println(repo.RemoteName())
+		} else {
+			fmt.Println(repo.BaseRemote())
+		}
+		return nil
+	}
+	return errors.Errorf("repository '%s' not found in registry", repoName)
+}
+
+func runReposSetRemote(ctx context.Context, repoName, remote string, primary bool) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	var updated []string
+	if primary {
+		updated, err = wm.SetPrimaryRemote(ctx, repoName, remote)
+	} else {
+		updated, err = wm.SetUpstreamRemote(ctx, repoName, remote)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case primary && remote == "":
+		output.PrintSuccess("%s pushes to %s", repoName, wsm.DefaultRemote)
+	case primary:
+		output.PrintSuccess("%s pushes to %s", repoName, remote)
+	case remote == "":
+		output.PrintSuccess("%s compares base branches with its primary remote", repoName)
+	default:
+		output.PrintSuccess("%s compares base branches with %s", repoName, remote)
+	}
+	if len(updated) > 0 {
+		output.PrintInfo("Updated workspaces: %s", strings.Join(updated, ", "))
+	}
+	return nil
+}
+
+func runReposMove(ctx context.Context, repoName, newPath string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	move, err := discoverer.MoveRepository(repoName, newPath)
+	if err != nil {
+		return err
+	}
+	if err := discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+	output.PrintSuccess("Moved %s: %s -> %s", move.Name, move.OldPath, move.NewPath)
+
+	return relinkMovedRepository(ctx, *move)
+}
+
+// relinkMovedRepository updates the workspaces using a moved repository and reports the result
+func relinkMovedRepository(ctx context.Context, move wsm.RepositoryMove) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	results, err := wm.RelinkMovedRepository(ctx, move)
+	if err != nil {
+		return errors.Wrapf(err, "failed to relink workspaces using %s", move.Name)
+	}
+	if len(results) == 0 {
+		output.PrintInfo("No workspace uses %s", move.Name)
+		return nil
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			output.PrintWarning("Workspace %s: %s", result.Workspace, result.Error)
+			continue
+		}
+		output.PrintSuccess("Relinked workspace %s", result.Workspace)
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to repair worktrees in %d workspace(s); run 'git worktree repair <worktree>' in %s", failed, move.NewPath)
+	}
+	return nil
+}
+
+func loadDiscoverer() (*wsm.RepositoryDiscoverer, error) {
+	registryPath, err := getRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	discoverer := wsm.NewRepositoryDiscoverer(registryPath)
+	if err := discoverer.LoadRegistry(); err != nil {
+		return nil, errors.Wrap(err, "failed to load registry")
+	}
+	return discoverer, nil
+}
+
+func runReposTag(repoName string, tags []string, add bool) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	if add {
+		err = discoverer.AddTags(repoName, tags)
+	} else {
+		err = discoverer.RemoveTags(repoName, tags)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+
+	if add {
+		output.PrintSuccess("Added tags to %s: %s", repoName, strings.Join(tags, ", "))
+	} else {
+		output.PrintSuccess("Removed tags from %s: %s", repoName, strings.Join(tags, ", "))
+	}
+	return nil
+}
+
+func runReposGroupList(format string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	groups := discoverer.GetGroups()
+	if format == "json" {
+		return wsm.PrintJSON(groups)
+	}
+
+	if len(groups) == 0 {
+		output.PrintInfo("No repository groups defined. Use 'wsm repos group set <name> <repos...>' to create one.")
+		return nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tREPOSITORIES")
+	fmt.Fprintln(w, "-----\t------------")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s\t%s\n", wsm.GroupPrefix, name, strings.Join(groups[name], ", "))
+	}
+	return w.Flush()
+}
+
+func runReposGroupSet(name string, repoNames []string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	if err := discoverer.SetGroup(name, repoNames); err != nil {
+		return err
+	}
+	if err := discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Group %s%s: %s", wsm.GroupPrefix, strings.TrimPrefix(name, wsm.GroupPrefix), strings.Join(repoNames, ", "))
+	return nil
+}
+
+func runReposGroupDelete(name string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	if err := discoverer.DeleteGroup(name); err != nil {
+		return err
+	}
+	if err := discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Deleted group %s", name)
+	return nil
+}
@@ -0,0 +1,206 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewReposExportCommand creates the repos export command
+func NewReposExportCommand() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the repository registry as portable YAML",
+		Long: `Write the registered repositories and groups as YAML: names, remote URLs,
+paths, tags, remotes and clone options. Paths under the home directory are
+written with ~, so the file can be checked into dotfiles and imported on
+another machine with 'wsm repos import'.
+
+Examples:
+  wsm repos export > repos.yaml
+
+  # Write to a file
+  wsm repos export --file ~/dotfiles/wsm/repos.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReposExport(file)
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Write to this file instead of standard output")
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"file": carapace.ActionFiles(".yaml", ".yml"),
+	})
+
+	return cmd
+}
+
+// NewReposImportCommand creates the repos import command
+func NewReposImportCommand() *cobra.Command {
+	var (
+		replace      bool
+		dryRun       bool
+		force        bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Register the repositories of a 'wsm repos export' file",
+		Long: `Register the repositories and groups written by 'wsm repos export', e.g. on a
+new machine. Use - to read from standard input.
+
+A repository whose exported path holds a clone on this machine is registered
+as that clone. The others are registered by remote URL only and cloned on
+demand, into the --clone-dir of 'wsm create', when a workspace uses them.
+
+By default the import merges: repositories and groups already registered keep
+their settings. With --replace the registry becomes exactly the imported one.
+
+Examples:
+  wsm repos import repos.yaml
+
+  # Preview the import
+  wsm repos import repos.yaml --dry-run
+
+  # Replace the registry without confirmation
+  wsm repos import ~/dotfiles/wsm/repos.yaml --replace --force`,
+		Args: cobra.ExactArgs(1),
+		RunE: audited("repos-import", -1, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			mode := wsm.RegistryImportMerge
+			if replace {
+				mode = wsm.RegistryImportReplace
+			}
+			return runReposImport(cmd.Context(), args[0], mode, dryRun, force, outputFormat)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&replace, "replace", false, "Replace the registry instead of merging into it")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be imported without saving")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace the registry without confirmation")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(carapace.ActionFiles(".yaml", ".yml"))
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runReposExport(file string) error {
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	data, err := wsm.MarshalRegistryExport(discoverer.ExportRegistry())
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		_, err := os.Stdout.Write(data)
+		return errors.Wrap(err, "failed to write registry export")
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", file)
+	}
+	output.PrintSuccess("Exported the registry to %s", file)
+	return nil
+}
+
+func runReposImport(ctx context.Context, file, mode string, dryRun, force bool, outputFormat string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", file)
+	}
+	export, err := wsm.ParseRegistryExport(data)
+	if err != nil {
+		return err
+	}
+
+	discoverer, err := loadDiscoverer()
+	if err != nil {
+		return err
+	}
+
+	if mode == wsm.RegistryImportReplace && !dryRun && !force {
+		if err := wsm.RequireInteractive("Replacing the registry", "--force"); err != nil {
+			return err
+		}
+		confirmed, err := ux.Confirm(
+			fmt.Sprintf("Replace the registry with the %d repositories of %s?", len(export.Repositories), file),
+			"Registered repositories and groups missing from the file are removed from the registry. Clones on disk are not touched.",
+		)
+		if err != nil && !errors.Is(err, ux.ErrAborted) {
+			return errors.Wrap(err, "confirmation failed")
+		}
+		if !confirmed {
+			output.PrintInfo("Operation cancelled.")
+			return nil
+		}
+	}
+
+	result, err := discoverer.ImportRegistry(ctx, export, mode)
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		if err := discoverer.SaveRegistry(); err != nil {
+			return err
+		}
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(result)
+	}
+
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	if len(result.Added) > 0 {
+		output.PrintSuccess("%s %d local repositories: %s", verb, len(result.Added), strings.Join(result.Added, ", "))
+	}
+	if len(result.RemoteOnly) > 0 {
+		output.PrintSuccess("%s %d repositories by URL, cloned when a workspace uses them: %s", verb, len(result.RemoteOnly), strings.Join(result.RemoteOnly, ", "))
+	}
+	if len(result.Kept) > 0 {
+		output.PrintInfo("Already registered: %s", strings.Join(result.Kept, ", "))
+	}
+	if len(result.Groups) > 0 {
+		output.PrintInfo("Groups: %s", strings.Join(result.Groups, ", "))
+	}
+	skipped := make([]string, 0, len(result.Skipped))
+	for name := range result.Skipped {
+		skipped = append(skipped, name)
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		output.PrintWarning("Skipped %s: %s", name, result.Skipped[name])
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package cmds
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// NewServeCommand creates the serve command
+func NewServeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve workspace operations to agents and other programs",
+		Long: `Expose workspace operations (list, status, create, delete, commit, diff,
+sync) to programs that would otherwise shell out to wsm and parse its text
+output, over MCP for agents or over HTTP for dashboards and remote control.
+Mutating operations are recorded in the audit log (see 'wsm history').`,
+	}
+
+	cmd.AddCommand(
+		NewServeMCPCommand(),
+		NewServeHTTPCommand(),
+	)
+
+	return cmd
+}
+
+// NewServeMCPCommand creates the serve mcp command
+func NewServeMCPCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve workspace operations as Model Context Protocol tools over stdio",
+		Long: `Run a Model Context Protocol server on stdin/stdout. Agents get the tools
+list_workspaces, workspace_status, create_workspace, commit, diff and sync and
+receive JSON results instead of terminal output.
+
+Log and progress output goes to stderr so it never corrupts the protocol.
+Operations are recorded in the audit log with the actor "mcp" unless WSM_ACTOR
+is set.
+
+Example MCP client configuration:
+  {
+    "mcpServers": {
+      "wsm": {"command": "wsm", "args": ["serve", "mcp"]}
+    }
+  }`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServeMCP(cmd.Context(), serverVersion(cmd))
+		},
+	}
+}
+
+func runServeMCP(ctx context.Context, version string) error {
+	// stdout carries the protocol: everything printed by workspace operations goes to stderr
+	protocol := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = protocol }()
+	output.SetProgressFactory(output.NoProgress)
+	// stdin carries the protocol too, nobody can answer a prompt
+	wsm.SetNonInteractive(true)
+
+	server := wsm.NewMCPServer(wsm.NewWorkspaceService("mcp"), version)
+	return server.Serve(ctx, os.Stdin, protocol)
+}
+
+// NewServeHTTPCommand creates the serve http command
+func NewServeHTTPCommand() *cobra.Command {
+	var (
+		addr   string
+		token  string
+		noAuth bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "http",
+		Short: "Serve workspace operations as a REST API",
+		Long: `Run an HTTP server exposing workspaces as a REST API, e.g. for a web dashboard
+or to orchestrate workspaces on a remote dev box.
+
+  GET    /api/workspaces                 list workspaces
+  POST   /api/workspaces                 create {"name", "repositories", "branch", "base_branch"}
+  GET    /api/workspaces/{name}          show a workspace
+  DELETE /api/workspaces/{name}          delete (?trash=true, ?remove_files=true, ?force=true)
+  GET    /api/workspaces/{name}/status   git status of every repository
+  GET    /api/workspaces/{name}/diff     unified diff (?repository=, ?staged=true)
+  POST   /api/workspaces/{name}/commit   commit {"message", "repositories", "push"}
+  POST   /api/workspaces/{name}/sync     sync {"pull", "push", "rebase"}
+
+Requests must send "Authorization: Bearer <token>", and POST requests
+"Content-Type: application/json" (else 415). The token is taken from
+--token or WSM_API_TOKEN; without either a random token is generated and
+printed at startup. Deleting a workspace with unpushed or untracked work fails
+with 409 unless force=true. Operations are recorded in the audit log with the
+actor "http" unless WSM_ACTOR is set.
+
+Examples:
+  # Serve on localhost with a generated token
+  wsm serve http
+
+  # Serve on all interfaces of a dev box
+  WSM_API_TOKEN=secret wsm serve http --addr :7878
+
+  # Query it
+  curl -H "Authorization: Bearer secret" http://devbox:7878/api/workspaces`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if token == "" {
+				token = os.Getenv(wsm.APITokenEnv)
+			}
+			return runServeHTTP(cmd.Context(), addr, token, noAuth)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7878", "Address to listen on")
+	cmd.Flags().StringVar(&token, "token", "", "Bearer token required from clients (default $"+wsm.APITokenEnv+" or a generated one)")
+	cmd.Flags().BoolVar(&noAuth, "no-auth", false, "Accept requests without a token (only for trusted networks)")
+
+	return cmd
+}
+
+// runServeHTTP serves the API until ctx is cancelled, which Ctrl-C does
+func runServeHTTP(ctx context.Context, addr, token string, noAuth bool) error {
+	switch {
+	case noAuth:
+		token = ""
+		output.PrintWarning("Authentication is disabled, anyone reaching %s can manage your workspaces", addr)
+	case token == "":
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return errors.Wrap(err, "failed to generate token")
+		}
+		token = hex.EncodeToString(buf)
+		output.PrintInfo("Generated API token: %s", token)
+	}
+	output.SetProgressFactory(output.NoProgress)
+	wsm.SetNonInteractive(true)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           wsm.NewAPIHandler(wsm.NewWorkspaceService("http"), token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return errors.Wrap(err, "server failed")
+		}
+		return nil
+	})
+	g.Go(func() error {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return errors.Wrap(server.Shutdown(shutdownCtx), "failed to shut down server")
+	})
+	output.PrintInfo("Serving workspace API on http://%s/api (Ctrl+C to stop)", addr)
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	output.PrintInfo("Server stopped")
+	return nil
+}
+
+// serverVersion returns the version reported by servers
+func serverVersion(cmd *cobra.Command) string {
+	if version := cmd.Root().Version; version != "" {
+		return version
+	}
+	return "dev"
+}
@@ -0,0 +1,150 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewSetupCommand creates the setup command
+func NewSetupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Manage the setup scripts of a workspace",
+		Long: `Manage the setup scripts run when a workspace is created, forked or gets a
+repository.
+
+The result of every script run (exit code, duration and log file) is recorded
+in .wsm/setup-results.json, and its output in .wsm/setup-logs/. 'wsm status'
+shows the scripts that failed.
+
+Examples:
+  # Run all setup scripts of the current workspace again
+  wsm setup rerun
+
+  # Only the scripts that failed last time
+  wsm setup rerun my-feature --only-failed`,
+	}
+
+	cmd.AddCommand(newSetupRerunCommand())
+
+	return cmd
+}
+
+func newSetupRerunCommand() *cobra.Command {
+	var (
+		onlyFailed   bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rerun [workspace-name]",
+		Short: "Run the setup scripts of an existing workspace again",
+		Long: `Run the workspace setup scripts again: .wsm/setup.sh and .wsm/setup.d/ of the
+workspace root. With --only-failed, only the scripts whose last run failed are
+run. Results are recorded like on creation.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: audited("setup-rerun", 0, func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			return runSetupRerun(cmd.Context(), workspaceName, onlyFailed, outputFormat)
+		}),
+	}
+
+	cmd.Flags().BoolVar(&onlyFailed, "only-failed", false, "Only run the scripts whose last run failed")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(
+		WorkspaceNameCompletion(),
+	)
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runSetupRerun(ctx context.Context, workspaceName string, onlyFailed bool, outputFormat string) error {
+	if workspaceName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get current directory")
+		}
+		detected, err := detectWorkspace(cwd)
+		if err != nil {
+			return errors.Wrap(err, "failed to detect workspace. Use 'wsm setup rerun <workspace-name>'")
+		}
+		workspaceName = detected
+	}
+	workspace, err := loadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+	results, err := wm.RerunSetupScripts(ctx, workspace, onlyFailed)
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(results); err != nil {
+			return err
+		}
+	} else if len(results) == 0 {
+		if onlyFailed {
+			output.PrintInfo("No failed setup scripts in workspace '%s'", workspace.Name)
+		} else {
+			output.PrintInfo("Workspace '%s' has no setup scripts", workspace.Name)
+		}
+		return nil
+	} else {
+		fmt.Println()
+		printSetupResults(results)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Succeeded() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d setup script(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+func printSetupResults(results []wsm.SetupResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCRIPT\tEXIT\tDURATION\tLOG")
+	fmt.Fprintln(w, "------\t----\t--------\t---")
+	for _, result := range results {
+		exit := fmt.Sprintf("%d", result.ExitCode)
+		if result.Error != "" {
+			exit = result.Error
+		}
+		logFile := result.LogFile
+		if logFile == "" {
+			logFile = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Script, exit, result.Duration.Round(time.Millisecond), logFile)
+	}
+	_ = w.Flush()
+}
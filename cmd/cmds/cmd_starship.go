@@ -5,9 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 
-	"github.com/charmbracelet/huh"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -25,10 +25,11 @@ func NewStarshipCommand() *cobra.Command {
 		Long: `Generate a starship configuration snippet that displays the current workspace name
 in your shell prompt when inside a workspace directory.
 
-The configuration adds a custom module that:
-- Detects when you're in a workspace directory (matching /workspaces/YYYY-MM-DD/)
-- Extracts and displays the workspace name
-- Optionally shows the date as well
+The configuration adds a custom module that runs 'wsm prompt-status', which:
+- Detects when you're in a workspace directory
+- Displays the workspace name, branch, dirty repository count and ahead/behind counts
+- Optionally shows the creation date as well
+- Answers from a status cache, so the prompt stays fast
 
 Examples:
   # Generate default configuration
@@ -73,23 +74,13 @@ func runStarshipCommand(symbol, style string, showDate, force bool) error {
 
 	// Ask for confirmation unless forced
 	if !force {
-		var confirmed bool
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewConfirm().
-					Title(fmt.Sprintf("Append this configuration to %s?", configPath)).
-					Description("This will add the workspace module to your starship configuration.").
-					Value(&confirmed),
-			),
-		)
-
-		err := form.Run()
+		if err := wsm.RequireInteractive(fmt.Sprintf("Changing %s", configPath), "--force"); err != nil {
+			return err
+		}
+		confirmed, err := ux.Confirm(fmt.Sprintf("Append this configuration to %s?", configPath),
+			"This will add the workspace module to your starship configuration.")
 		if err != nil {
-			// Check if user cancelled/aborted the form
-			errMsg := strings.ToLower(err.Error())
-			if strings.Contains(errMsg, "user aborted") ||
-				strings.Contains(errMsg, "cancelled") ||
-				strings.Contains(errMsg, "aborted") {
+			if errors.Is(err, ux.ErrAborted) {
 				fmt.Println("Configuration not added.")
 				return nil
 			}
@@ -113,21 +104,16 @@ func runStarshipCommand(symbol, style string, showDate, force bool) error {
 }
 
 func generateStarshipConfig(symbol, style string, showDate bool) string {
-	var command string
+	command := "wsm prompt-status"
 	if showDate {
-		command = `printf "%s\n" "$PWD" \
-  | sed -E 's|.*/workspaces/([0-9]{4}-[0-9]{2}-[0-9]{2})/([^/]+).*|\2 (\1)|'`
-	} else {
-		command = `printf "%s\n" "$PWD" \
-  | sed -E 's|.*/workspaces/[0-9]{4}-[0-9]{2}-[0-9]{2}/([^/]+).*|\1|'`
+		command += " --show-date"
 	}
 
+	// prompt-status prints nothing outside a workspace, which hides the module
 	return fmt.Sprintf(`[custom.workspace]
-description = "Show current workspaces/YYYY-MM-DD/<name>"
-when   = 'echo "$PWD" | grep -Eq "/workspaces/[0-9]{4}-[0-9]{2}-[0-9]{2}/"'
-command = '''
-  %s
-'''
+description = "Show the current wsm workspace, its branch, dirty repositories and ahead/behind counts"
+command = "%s"
+when    = true
 symbol  = "%s"
 style   = "%s"
 format  = '[ $symbol$output ]($style)'`, command, symbol, style)
@@ -0,0 +1,340 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewStateCommand creates the state command
+func NewStateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "Share the registry and workspace definitions through a remote backend",
+		Long: `Synchronize the repository registry and workspace definitions with a shared
+backend, so a team can work from canonical workspace definitions.
+
+The backend is configured in remote-state.yaml in the workspace-manager config
+directory (e.g. ~/.config/workspace-manager/remote-state.yaml):
+
+  # a git repository; registry.json and workspaces/*.json are stored under dir/
+  backend: git
+  url: git@github.com:acme/wsm-state.git
+  branch: main
+  dir: team
+
+  # a single JSON object in S3 (uses the aws CLI and its credentials)
+  backend: s3
+  url: s3://acme-wsm/state.json
+
+  # a JSON document read with GET and replaced with PUT
+  backend: http
+  url: https://wsm.acme.dev/state
+  token-env: WSM_STATE_TOKEN
+
+Pull merges the remote state into the local one against the state seen at the
+last sync: entries changed on one side take that side's version, entries
+changed on both sides are conflicts. Push replaces the remote state with the
+local one and refuses if the remote changed since the last sync.
+
+Paths are shared relative to the workspace directory and the home directory.
+Pull refuses names that are not valid workspace or repository names and
+workspaces outside the workspace directory.
+
+Only configuration is synchronized; worktrees and workspace directories are
+never created or removed.
+
+Examples:
+  # Compare the local and remote state
+  wsm state status
+
+  # Fetch definitions shared by the team
+  wsm state pull
+
+  # Resolve conflicts in favour of the remote state
+  wsm state pull --prefer remote
+
+  # Publish local definitions
+  wsm state push`,
+	}
+
+	cmd.AddCommand(
+		newStateStatusCommand(),
+		newStatePullCommand(),
+		newStatePushCommand(),
+	)
+
+	return cmd
+}
+
+func newStateStatusCommand() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show incoming, outgoing and conflicting changes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStateStatus(cmd.Context(), outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func newStatePullCommand() *cobra.Command {
+	var (
+		prefer string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Merge the remote state into the local registry and workspaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatePull(cmd.Context(), prefer, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&prefer, "prefer", "", "Resolve conflicts with the local or remote version (local, remote)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without writing anything")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"prefer": carapace.ActionValues(wsm.StatePreferLocal, wsm.StatePreferRemote),
+		},
+	)
+
+	return cmd
+}
+
+func newStatePushCommand() *cobra.Command {
+	var (
+		force  bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Replace the remote state with the local registry and workspaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatePush(cmd.Context(), force, dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Push even if the remote state changed since the last sync")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would change without writing anything")
+
+	return cmd
+}
+
+// stateSync holds everything needed to compare the local and remote state
+type stateSync struct {
+	manager *wsm.WorkspaceManager
+	backend wsm.StateBackend
+	base    *wsm.StateSnapshot
+	local   *wsm.StateSnapshot
+	remote  *wsm.StateSnapshot
+}
+
+func loadStateSync(ctx context.Context) (*stateSync, error) {
+	config, err := wsm.LoadRemoteStateConfig()
+	if err != nil {
+		return nil, err
+	}
+	backend, err := wsm.NewStateBackend(config)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	base, err := wsm.LoadStateBase()
+	if err != nil {
+		return nil, err
+	}
+	local, err := manager.LocalState()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read local state")
+	}
+
+	output.PrintInfoStderr("Fetching remote state from %s", backend)
+	remote, err := backend.Fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch remote state")
+	}
+
+	return &stateSync{
+		manager: manager,
+		backend: backend,
+		base:    base,
+		local:   local,
+		remote:  remote,
+	}, nil
+}
+
+func runStateStatus(ctx context.Context, outputFormat string) error {
+	state, err := loadStateSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	merge, err := wsm.MergeState(state.base, state.local, state.remote, "")
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat {
+	case "json":
+		return wsm.PrintJSON(merge)
+	case "table":
+	default:
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+
+	if !state.remote.UpdatedAt.IsZero() {
+		fmt.Printf("  Last push: %s by %s\n", state.remote.UpdatedAt.Format("2006-01-02 15:04"), state.remote.UpdatedBy)
+	}
+	printStateChanges("Incoming (run 'wsm state pull')", merge.Incoming)
+	printStateChanges("Outgoing (run 'wsm state push')", merge.Outgoing)
+	printStateChanges("Conflicts", merge.Conflicts)
+
+	if len(merge.Incoming) == 0 && len(merge.Outgoing) == 0 && len(merge.Conflicts) == 0 {
+		output.PrintSuccess("Local and remote state are in sync")
+	}
+	return nil
+}
+
+func runStatePull(ctx context.Context, prefer string, dryRun bool) error {
+	switch prefer {
+	case "", wsm.StatePreferLocal, wsm.StatePreferRemote:
+	default:
+		return errors.Errorf("--prefer must be 'local' or 'remote', got '%s'", prefer)
+	}
+
+	state, err := loadStateSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	merge, err := wsm.MergeState(state.base, state.local, state.remote, prefer)
+	if err != nil {
+		return err
+	}
+
+	printStateChanges("Incoming", merge.Incoming)
+	if len(merge.Conflicts) > 0 {
+		printStateChanges("Conflicts", merge.Conflicts)
+		return errors.Errorf("%d conflicting change(s), rerun with --prefer local or --prefer remote", len(merge.Conflicts))
+	}
+	if len(merge.Incoming) == 0 {
+		output.PrintSuccess("Already up to date")
+		if !dryRun {
+			return wsm.SaveStateBase(state.remote)
+		}
+		return nil
+	}
+
+	warnRemovedWorkspaces(state.local, merge.Incoming)
+
+	if dryRun {
+		output.PrintInfo("Dry run: no changes written")
+		return nil
+	}
+
+	if err := state.manager.ApplyState(merge.Result); err != nil {
+		return errors.Wrap(err, "failed to apply remote state")
+	}
+	if err := wsm.SaveStateBase(state.remote); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Pulled %d change(s) from %s", len(merge.Incoming), state.backend)
+	if len(merge.Outgoing) > 0 {
+		output.PrintInfo("%d local change(s) not pushed yet, run 'wsm state push'", len(merge.Outgoing))
+	}
+	return nil
+}
+
+func runStatePush(ctx context.Context, force, dryRun bool) error {
+	state, err := loadStateSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	if remoteChanges := wsm.DiffState(state.base, state.remote); len(remoteChanges) > 0 && !force {
+		printStateChanges("Remote changes since the last sync", remoteChanges)
+		return errors.New("the remote state changed since the last sync, run 'wsm state pull' first or push with --force")
+	}
+
+	changes := wsm.DiffState(state.remote, state.local)
+	printStateChanges("Outgoing", changes)
+	if len(changes) == 0 {
+		output.PrintSuccess("Remote state is up to date")
+		return nil
+	}
+
+	if dryRun {
+		output.PrintInfo("Dry run: no changes written")
+		return nil
+	}
+
+	state.local.Stamp()
+	if err := state.backend.Store(ctx, state.local); err != nil {
+		return errors.Wrap(err, "failed to store remote state")
+	}
+	if err := wsm.SaveStateBase(state.local); err != nil {
+		return err
+	}
+
+	output.PrintSuccess("Pushed %d change(s) to %s", len(changes), state.backend)
+	return nil
+}
+
+func printStateChanges(title string, changes []wsm.StateChange) {
+	if len(changes) == 0 {
+		return
+	}
+	output.PrintHeader("%s", title)
+	for _, change := range changes {
+		fmt.Printf("  %-8s %-10s %s\n", change.Action, change.Kind, change.Name)
+	}
+	fmt.Println()
+}
+
+// warnRemovedWorkspaces points out workspaces whose definition is removed while
+// their directory still exists locally
+func warnRemovedWorkspaces(local *wsm.StateSnapshot, incoming []wsm.StateChange) {
+	for _, change := range incoming {
+		if change.Kind != "workspace" || change.Action != "removed" {
+			continue
+		}
+		for _, workspace := range local.Workspaces {
+			if workspace.Name != change.Name {
+				continue
+			}
+			if _, err := os.Stat(workspace.Path); err == nil {
+				output.PrintWarning("Workspace '%s' was removed remotely; its files remain at %s (see 'wsm prune')", workspace.Name, workspace.Path)
+			}
+		}
+	}
+}
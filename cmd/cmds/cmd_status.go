@@ -6,9 +6,10 @@ import (
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/pkg/errors"
@@ -21,33 +22,81 @@ func NewStatusCommand() *cobra.Command {
 		short     bool
 		untracked bool
 		workspace string
+		ci        bool
+		wait      bool
+		interval  time.Duration
+		format    string
+		jobs      int
+		filters   repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "status [workspace-name]",
 		Short: "Show workspace status",
 		Long: `Show the git status of all repositories in a workspace.
-If no workspace name is provided, attempts to detect the current workspace.`,
+If no workspace name is provided, attempts to detect the current workspace.
+
+Besides changes and sync state, status points out unfinished merges, rebases
+and cherry-picks, detached HEADs, stash entries of the current branch and tags
+on commits no remote has yet.
+
+With --ci, the latest CI run of each repository branch is shown as well (see
+'wsm ci'). --wait keeps polling until the runs in progress complete.
+
+Examples:
+  # Status of the current workspace
+  wsm status
+
+  # Include CI results and wait for running workflows
+  wsm status --ci --wait
+
+  # Only the Go repositories, except one
+  wsm status --tag go --exclude-repo legacy
+
+  # Machine-readable status
+  wsm status --output json`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			workspaceName := workspace
 			if len(args) > 0 {
 				workspaceName = args[0]
 			}
-			return runStatus(cmd.Context(), workspaceName, short, untracked)
+			if wait && !ci {
+				return errors.New("--wait requires --ci")
+			}
+			if format != "table" && format != "json" {
+				return errors.Errorf("unsupported output format '%s' (supported: table, json)", format)
+			}
+			if format == "json" && ci {
+				return errors.New("--ci cannot be combined with --output json, use 'wsm ci --output json'")
+			}
+			return runStatus(cmd.Context(), workspaceName, short, untracked, ci, wait, interval, format, jobs, &filters)
 		},
 	}
 
 	cmd.Flags().BoolVar(&short, "short", false, "Show short status format")
 	cmd.Flags().BoolVar(&untracked, "untracked", false, "Include untracked files")
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
+	cmd.Flags().BoolVar(&ci, "ci", false, "Show the latest CI run of each repository")
+	cmd.Flags().BoolVar(&wait, "wait", false, "With --ci, wait until running CI runs complete")
+	cmd.Flags().DurationVar(&interval, "interval", 15*time.Second, "Polling interval for --wait")
+	cmd.Flags().StringVarP(&format, "output", "o", "table", "Output format (table, json)")
+	cmd.Flags().IntVarP(&jobs, "jobs", "j", 0, "Number of stale repositories to fetch in parallel (default: jobs of limits.yaml, or 4)")
+	filters.register(cmd, &workspace)
 
 	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
 
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"output":    carapace.ActionValues("table", "json"),
+		},
+	)
+
 	return cmd
 }
 
-func runStatus(ctx context.Context, workspaceName string, short, untracked bool) error {
+func runStatus(ctx context.Context, workspaceName string, short, untracked, ci, wait bool, interval time.Duration, format string, jobs int, filters *repoFilterFlags) error {
 	// If no workspace specified, try to detect current workspace
 	if workspaceName == "" {
 		cwd, err := os.Getwd()
@@ -67,6 +116,10 @@ func runStatus(ctx context.Context, workspaceName string, short, untracked bool)
 	if err != nil {
 		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
+	refreshStaleRepositories(ctx, workspace, wsm.FreshnessStatus, jobs, format == "json")
 
 	// Get status
 	checker := wsm.NewStatusChecker()
@@ -74,138 +127,75 @@ func runStatus(ctx context.Context, workspaceName string, short, untracked bool)
 	if err != nil {
 		return errors.Wrap(err, "failed to get workspace status")
 	}
+	// Keep the prompt in step with what status shows; a stale prompt is harmless.
+	// A filtered status does not describe the whole workspace.
+	if workspace.Filter == nil {
+		_ = wsm.RecordPromptStatus(status)
+	}
 
 	// Display status
+	if format == "json" {
+		return wsm.PrintJSON(status)
+	}
 	if short {
-		return printStatusShort(status, untracked)
+		err = printStatusShort(status, untracked)
+	} else {
+		err = printStatusDetailed(status, untracked)
+	}
+	if err != nil || !ci {
+		return err
 	}
 
-	return printStatusDetailed(status, untracked)
+	fmt.Println()
+	output.PrintHeader("CI")
+	if wait {
+		return waitForCI(ctx, workspace, time.Time{}, interval, "table")
+	}
+	runs, err := wsm.GetCIRuns(ctx, workspace)
+	if err != nil {
+		return errors.Wrap(err, "failed to get CI runs")
+	}
+	return printCIRuns(runs)
 }
 
+// detectWorkspace returns the name of the workspace containing cwd
 func detectWorkspace(cwd string) (string, error) {
-	log.Debug().Str("cwd", cwd).Msg("Starting workspace detection")
-
-	// First, try to find a workspace that contains this directory
-	workspaces, err := wsm.LoadWorkspaces()
+	workspace, err := wsm.NewWorkspaceService("").DetectWorkspace(cwd)
 	if err != nil {
-		log.Debug().Err(err).Msg("Failed to load workspaces")
-		return "", errors.Wrap(err, "failed to load workspaces")
+		log.Debug().Err(err).Str("cwd", cwd).Msg("No workspace detected")
+		return "", err
 	}
+	output.LogInfo(
+		fmt.Sprintf("Detected workspace: %s", workspace.Name),
+		"Found workspace containing current directory",
+		"workspaceName", workspace.Name,
+		"workspacePath", workspace.Path,
+		"cwd", cwd,
+	)
+	return workspace.Name, nil
+}
 
-	log.Debug().Int("workspaceCount", len(workspaces)).Msg("Loaded workspaces")
-
-	// Check if current directory is within any workspace path
-	for _, workspace := range workspaces {
-		log.Debug().
-			Str("workspaceName", workspace.Name).
-			Str("workspacePath", workspace.Path).
-			Msg("Checking workspace")
-
-		// Check if current directory is within or matches workspace path
-		if strings.HasPrefix(cwd, workspace.Path) {
-			output.LogInfo(
-				fmt.Sprintf("Detected workspace: %s", workspace.Name),
-				"Found workspace containing current directory",
-				"workspaceName", workspace.Name,
-				"workspacePath", workspace.Path,
-				"cwd", cwd,
-			)
-			return workspace.Name, nil
-		}
-
-		// Also check if any repository in the workspace matches current directory
-		for _, repo := range workspace.Repositories {
-			repoWorktreePath := filepath.Join(workspace.Path, repo.Name)
-			log.Debug().
-				Str("repo", repo.Name).
-				Str("repoWorktreePath", repoWorktreePath).
-				Msg("Checking repository worktree path")
-
-			if strings.HasPrefix(cwd, repoWorktreePath) {
-				output.LogInfo(
-					fmt.Sprintf("Detected workspace: %s (via repo %s)", workspace.Name, repo.Name),
-					"Found workspace via repository worktree path",
-					"workspaceName", workspace.Name,
-					"repo", repo.Name,
-					"repoWorktreePath", repoWorktreePath,
-					"cwd", cwd,
-				)
-				return workspace.Name, nil
-			}
-		}
+// loadWorkspace loads a workspace for a command working on it and records
+// the use for 'wsm suggest-cleanup'
+func loadWorkspace(name string) (*wsm.Workspace, error) {
+	workspace, err := findWorkspace(name)
+	if err != nil {
+		return nil, err
 	}
+	recordWorkspaceUse(name)
+	return workspace, nil
+}
 
-	log.Debug().Msg("No workspace found containing current directory, trying heuristic detection")
-
-	// Fallback: Look for workspace configuration file in current directory or parents
-	dir := cwd
-
-	for {
-		log.Debug().Str("dir", dir).Msg("Checking directory for workspace structure")
-
-		// Check if this directory contains repository worktrees
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			log.Debug().Err(err).Str("dir", dir).Msg("Failed to read directory")
-			return "", err
-		}
-
-		// Look for .git files (worktree indicators) and workspace structure
-		gitDirs := 0
-		var gitRepos []string
-		for _, entry := range entries {
-			if entry.IsDir() {
-				gitFile := filepath.Join(dir, entry.Name(), ".git")
-				if stat, err := os.Stat(gitFile); err == nil && stat.Mode().IsRegular() {
-					gitDirs++
-					gitRepos = append(gitRepos, entry.Name())
-				}
-			}
-		}
-
-		log.Debug().
-			Str("dir", dir).
-			Int("gitDirs", gitDirs).
-			Strs("gitRepos", gitRepos).
-			Msg("Found git repositories in directory")
-
-		// If we found multiple git worktrees, this might be a workspace
-		if gitDirs >= 2 {
-			// Try to find a workspace that matches this path
-			dirName := filepath.Base(dir)
-			log.Debug().Str("dirName", dirName).Msg("Checking if directory name matches any workspace")
-
-			for _, workspace := range workspaces {
-				if workspace.Name == dirName || strings.Contains(workspace.Path, dirName) {
-					output.LogInfo(
-						fmt.Sprintf("Detected workspace: %s", workspace.Name),
-						"Found workspace by directory name match",
-						"workspaceName", workspace.Name,
-						"dirName", dirName,
-					)
-					return workspace.Name, nil
-				}
-			}
-
-			// If no exact match, return the directory name as best guess
-			log.Debug().Str("dirName", dirName).Msg("Using directory name as workspace name")
-			return dirName, nil
-		}
-
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			log.Debug().Msg("Reached filesystem root")
-			break // Reached root
-		}
-		dir = parent
+// recordWorkspaceUse records that a command worked on a workspace; failures
+// only make 'wsm suggest-cleanup' less accurate
+func recordWorkspaceUse(name string) {
+	if err := wsm.RecordWorkspaceUse(name); err != nil {
+		log.Debug().Err(err).Str("workspace", name).Msg("Failed to record workspace use")
 	}
-
-	log.Debug().Msg("No workspace detected")
-	return "", errors.New("not in a workspace directory")
 }
 
-func loadWorkspace(name string) (*wsm.Workspace, error) {
+// findWorkspace loads a workspace without recording a use
+func findWorkspace(name string) (*wsm.Workspace, error) {
 	workspaces, err := wsm.LoadWorkspaces()
 	if err != nil {
 		return nil, err
@@ -222,19 +212,38 @@ func loadWorkspace(name string) (*wsm.Workspace, error) {
 
 func printStatusShort(status *wsm.WorkspaceStatus, includeUntracked bool) error {
 	output.PrintHeader("Workspace: %s (%s)", status.Workspace.Name, status.Overall)
+	printFilterInfo(&status.Workspace)
+	if failed := failedSetupScripts(status); len(failed) > 0 {
+		output.PrintWarning("%d setup script(s) failed, run 'wsm setup rerun --only-failed'", len(failed))
+	}
 
 	for _, repoStatus := range status.Repositories {
 		symbol := getRepositoryStatusSymbol(repoStatus)
 		fmt.Printf("%s %s", symbol, repoStatus.Repository.Name)
 
-		if repoStatus.CurrentBranch != "" {
+		if repoStatus.Pinned != "" {
+			fmt.Printf(" [@%s]", repoStatus.Pinned)
+		} else if repoStatus.CurrentBranch != "" {
 			fmt.Printf(" [%s]", repoStatus.CurrentBranch)
+		} else if repoStatus.Detached {
+			fmt.Printf(" [detached]")
+		}
+
+		if repoStatus.InProgress != "" {
+			fmt.Printf(" (%s in progress)", repoStatus.InProgress)
+		}
+		if repoStatus.Repository.Archived {
+			fmt.Printf(" (read-only)")
 		}
 
 		if repoStatus.Ahead > 0 || repoStatus.Behind > 0 {
 			fmt.Printf(" ↑%d ↓%d", repoStatus.Ahead, repoStatus.Behind)
 		}
 
+		if repoStatus.LastFetch != nil {
+			fmt.Printf(" (fetched %s)", getFetchedString(repoStatus))
+		}
+
 		changes := []string{}
 		if len(repoStatus.StagedFiles) > 0 {
 			changes = append(changes, fmt.Sprintf("S:%d", len(repoStatus.StagedFiles)))
@@ -250,6 +259,10 @@ func printStatusShort(status *wsm.WorkspaceStatus, includeUntracked bool) error
 			fmt.Printf(" [%s]", strings.Join(changes, " "))
 		}
 
+		if notes := getNotesString(repoStatus); notes != "-" {
+			fmt.Printf(" %s", notes)
+		}
+
 		fmt.Println()
 	}
 
@@ -258,8 +271,10 @@ func printStatusShort(status *wsm.WorkspaceStatus, includeUntracked bool) error
 
 func printStatusDetailed(status *wsm.WorkspaceStatus, includeUntracked bool) error {
 	output.PrintHeader("Workspace: %s", status.Workspace.Name)
+	printFilterInfo(&status.Workspace)
 	output.PrintInfo("Path: %s", status.Workspace.Path)
 	output.PrintInfo("Overall Status: %s", status.Overall)
+	printSetupSummary(status)
 	fmt.Println()
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -273,12 +288,18 @@ func printStatusDetailed(status *wsm.WorkspaceStatus, includeUntracked bool) err
 		}
 	}()
 
-	fmt.Fprintln(w, "REPOSITORY\tBRANCH\tSTATUS\tCHANGES\tSYNC\tMERGED\tREBASE")
-	fmt.Fprintln(w, "----------\t------\t------\t-------\t----\t------\t------")
+	fmt.Fprintln(w, "REPOSITORY\tBRANCH\tSTATUS\tCHANGES\tSYNC\tFETCHED\tMERGED\tREBASE\tNOTES")
+	fmt.Fprintln(w, "----------\t------\t------\t-------\t----\t-------\t------\t------\t-----")
 
 	for _, repoStatus := range status.Repositories {
 		repoName := repoStatus.Repository.Name
 		branch := repoStatus.CurrentBranch
+		if repoStatus.Pinned != "" {
+			branch = "@" + repoStatus.Pinned
+		}
+		if branch == "" && repoStatus.Detached {
+			branch = "(detached)"
+		}
 		if branch == "" {
 			branch = "-"
 		}
@@ -288,9 +309,11 @@ func printStatusDetailed(status *wsm.WorkspaceStatus, includeUntracked bool) err
 		syncStr := getSyncString(repoStatus)
 		mergedStr := getMergedString(repoStatus)
 		rebaseStr := getRebaseString(repoStatus)
+		fetchedStr := getFetchedString(repoStatus)
+		notesStr := getNotesString(repoStatus)
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			repoName, branch, statusStr, changesStr, syncStr, mergedStr, rebaseStr)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			repoName, branch, statusStr, changesStr, syncStr, fetchedStr, mergedStr, rebaseStr, notesStr)
 	}
 
 	fmt.Fprintln(w)
@@ -326,13 +349,51 @@ func printStatusDetailed(status *wsm.WorkspaceStatus, includeUntracked bool) err
 	return nil
 }
 
+// failedSetupScripts returns the setup scripts whose last run failed
+func failedSetupScripts(status *wsm.WorkspaceStatus) []wsm.SetupResult {
+	var failed []wsm.SetupResult
+	for _, result := range status.Setup {
+		if !result.Succeeded() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// printSetupSummary shows how the setup scripts last went, with the log of
+// every failed script
+func printSetupSummary(status *wsm.WorkspaceStatus) {
+	if len(status.Setup) == 0 {
+		return
+	}
+	failed := failedSetupScripts(status)
+	if len(failed) == 0 {
+		output.PrintInfo("Setup: %d script(s) succeeded", len(status.Setup))
+		return
+	}
+	output.PrintWarning("Setup: %d of %d script(s) failed, run 'wsm setup rerun --only-failed'", len(failed), len(status.Setup))
+	for _, result := range failed {
+		reason := fmt.Sprintf("exit %d", result.ExitCode)
+		if result.Error != "" {
+			reason = result.Error
+		}
+		fmt.Printf("  ✗ %s (%s)\n", result.Script, reason)
+		if result.LogFile != "" {
+			fmt.Printf("    log: %s\n", result.LogFile)
+		}
+	}
+}
+
 func getRepositoryStatusSymbol(status wsm.RepositoryStatus) string {
-	if status.HasConflicts {
+	if status.HasConflicts || status.InProgress != "" {
 		return "⚠️ "
 	}
 	if status.HasChanges {
 		return "🔄"
 	}
+	if status.Pinned != "" {
+		return "📌"
+	}
 	if status.Ahead > 0 || status.Behind > 0 {
 		return "📤"
 	}
@@ -343,9 +404,18 @@ func getStatusString(status wsm.RepositoryStatus) string {
 	if status.HasConflicts {
 		return "conflict"
 	}
+	if status.InProgress != "" {
+		return status.InProgress + " in progress"
+	}
 	if status.HasChanges {
 		return "modified"
 	}
+	if status.PinDrifted {
+		return "pinned (moved)"
+	}
+	if status.Pinned != "" {
+		return "pinned"
+	}
 	return "clean"
 }
 
@@ -369,6 +439,26 @@ func getChangesString(status wsm.RepositoryStatus, includeUntracked bool) string
 	return strings.Join(parts, " ")
 }
 
+// getNotesString lists stash entries and tags that still need pushing
+func getNotesString(status wsm.RepositoryStatus) string {
+	parts := []string{}
+	if status.Repository.Archived {
+		parts = append(parts, "read-only")
+	}
+	if status.StashCount > 0 {
+		parts = append(parts, fmt.Sprintf("stash:%d", status.StashCount))
+	}
+	if len(status.UnpushedTags) > 0 {
+		parts = append(parts, fmt.Sprintf("unpushed tags: %s", strings.Join(status.UnpushedTags, ",")))
+	}
+
+	if len(parts) == 0 {
+		return "-"
+	}
+
+	return strings.Join(parts, " ")
+}
+
 func getSyncString(status wsm.RepositoryStatus) string {
 	if status.Ahead == 0 && status.Behind == 0 {
 		return "✓"
@@ -376,6 +466,55 @@ func getSyncString(status wsm.RepositoryStatus) string {
 	return fmt.Sprintf("↑%d ↓%d", status.Ahead, status.Behind)
 }
 
+// refreshStaleRepositories fetches the repositories freshness.yaml considers
+// stale before an operation, jobs at a time (0 for the default); quiet keeps
+// warnings out of JSON output
+func refreshStaleRepositories(ctx context.Context, workspace *wsm.Workspace, operation string, jobs int, quiet bool) {
+	result, err := wsm.RefreshStaleRepositories(ctx, workspace, operation, jobs)
+	if quiet {
+		return
+	}
+	if err != nil {
+		output.PrintWarning("Not fetching stale repositories: %v", err)
+	}
+	failed := make([]string, 0, len(result.Failed))
+	for repo := range result.Failed {
+		failed = append(failed, repo)
+	}
+	sort.Strings(failed)
+	for _, repo := range failed {
+		output.PrintWarning("Failed to fetch %s, its remote state may be stale: %s", repo, result.Failed[repo])
+	}
+}
+
+// getFetchedString shows how long ago the repository was last fetched
+func getFetchedString(status wsm.RepositoryStatus) string {
+	if status.LastFetch == nil {
+		return "-"
+	}
+
+	return formatRelativeTime(*status.LastFetch)
+}
+
+// formatRelativeTime renders a past time as a short "3h ago" style string
+func formatRelativeTime(t time.Time) string {
+	age := time.Since(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	case age < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	case age < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(age.Hours()/(24*30)))
+	default:
+		return fmt.Sprintf("%dy ago", int(age.Hours()/(24*365)))
+	}
+}
+
 func getMergedString(status wsm.RepositoryStatus) string {
 	if status.IsMerged {
 		return "✓"
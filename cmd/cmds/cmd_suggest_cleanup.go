@@ -0,0 +1,132 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewSuggestCleanupCommand creates the suggest-cleanup command
+func NewSuggestCleanupCommand() *cobra.Command {
+	var (
+		unusedFor    string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "suggest-cleanup",
+		Short: "List workspaces unused for a while, with their disk usage and unmerged work",
+		Long: `List the workspaces that have not been used for --unused-for, least recently
+used first. A workspace is used when a wsm command works on it (status, exec,
+push, tmux, ...) or a file in it changes, whichever is later; workspaces never
+used count from their creation.
+
+Each workspace comes with its disk usage, the commits of its branches not merged
+into the default branch yet (as of the last fetch) and the work 'wsm gc' would
+refuse to lose. Nothing is deleted: 'wsm gc --older-than' with the same age
+deletes the workspaces that hold no such work.
+
+Examples:
+  # Workspaces unused for 30 days
+  wsm suggest-cleanup
+
+  # Workspaces unused for two weeks, as JSON
+  wsm suggest-cleanup --unused-for 2w -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return errors.Errorf("unsupported output format: %s", outputFormat)
+			}
+			age, err := parseAge(unusedFor)
+			if err != nil {
+				return err
+			}
+			return runSuggestCleanup(cmd.Context(), age, unusedFor, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVar(&unusedFor, "unused-for", "30d", "List workspaces unused for this long (e.g. 30d, 2w, 36h)")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"unused-for": carapace.ActionValues("7d", "14d", "30d", "60d", "90d"),
+			"output":     carapace.ActionValues("table", "json"),
+		},
+	)
+
+	return cmd
+}
+
+func runSuggestCleanup(ctx context.Context, unusedFor time.Duration, age string, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	report, err := wm.SuggestCleanup(ctx, unusedFor)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" {
+		return wsm.PrintJSON(report)
+	}
+	return printCleanupSuggestions(report, age)
+}
+
+func printCleanupSuggestions(report *wsm.CleanupReport, age string) error {
+	if len(report.Suggestions) == 0 {
+		output.PrintSuccess("No workspace unused for %s", formatAge(report.UnusedFor))
+		return nil
+	}
+
+	output.PrintHeader("Workspaces unused for %s", formatAge(report.UnusedFor))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tSIZE\tLAST USED\tLAST COMMAND\tGC")
+	fmt.Fprintln(w, "---------\t----\t---------\t------------\t--")
+	for _, suggestion := range report.Suggestions {
+		lastCommand := "never"
+		if suggestion.LastCommand != nil {
+			lastCommand = formatRelativeTime(*suggestion.LastCommand)
+		}
+		gc := "delete"
+		switch {
+		case suggestion.Missing:
+			gc = "delete (missing)"
+		case len(suggestion.KeptBecause) > 0:
+			gc = "keep"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", suggestion.Workspace, wsm.FormatBytes(suggestion.Size), formatRelativeTime(suggestion.LastUsed), lastCommand, gc)
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+	fmt.Println()
+
+	for _, suggestion := range report.Suggestions {
+		if len(suggestion.UnmergedWork) == 0 && len(suggestion.KeptBecause) == 0 {
+			continue
+		}
+		fmt.Printf("  %s\n", suggestion.Workspace)
+		for _, work := range suggestion.UnmergedWork {
+			output.PrintInfo("    %s", work)
+		}
+		for _, reason := range suggestion.KeptBecause {
+			output.PrintWarning("    %s", reason)
+		}
+	}
+
+	if report.Collectable > 0 {
+		fmt.Println()
+		output.PrintInfo("'wsm gc --older-than %s' would delete %d workspace(s) and reclaim %s", age, report.Collectable, wsm.FormatBytes(report.Reclaimable))
+	}
+	return nil
+}
@@ -31,10 +31,13 @@ Supports pulling latest changes and pushing local commits.`,
 
 func NewSyncAllCommand() *cobra.Command {
 	var (
-		pull   bool
-		push   bool
-		rebase bool
-		dryRun bool
+		pull            bool
+		push            bool
+		rebase          bool
+		dryRun          bool
+		confirm         bool
+		includeArchived bool
+		filters         repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
@@ -42,7 +45,7 @@ func NewSyncAllCommand() *cobra.Command {
 		Short: "Sync all repositories (pull and push)",
 		Long:  "Synchronize all repositories by pulling latest changes and pushing local commits.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSyncAll(cmd.Context(), pull, push, rebase, dryRun)
+			return runSyncAll(cmd.Context(), pull, push, rebase, dryRun, confirm, includeArchived, &filters)
 		},
 	}
 
@@ -50,14 +53,18 @@ func NewSyncAllCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&push, "push", true, "Push local commits")
 	cmd.Flags().BoolVar(&rebase, "rebase", false, "Use rebase when pulling")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Allow pushing protected branches")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Also push archived (read-only) repositories")
+	filters.register(cmd, nil)
 
 	return cmd
 }
 
 func NewSyncPullCommand() *cobra.Command {
 	var (
-		rebase bool
-		dryRun bool
+		rebase  bool
+		dryRun  bool
+		filters repoFilterFlags
 	)
 
 	cmd := &cobra.Command{
@@ -65,65 +72,89 @@ func NewSyncPullCommand() *cobra.Command {
 		Short: "Pull latest changes from all repositories",
 		Long:  "Pull latest changes from remote repositories in the workspace.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSyncPull(cmd.Context(), rebase, dryRun)
+			return runSyncPull(cmd.Context(), rebase, dryRun, &filters)
 		},
 	}
 
 	cmd.Flags().BoolVar(&rebase, "rebase", false, "Use rebase instead of merge")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done")
+	filters.register(cmd, nil)
 
 	return cmd
 }
 
 func NewSyncPushCommand() *cobra.Command {
-	var dryRun bool
+	var (
+		dryRun          bool
+		confirm         bool
+		includeArchived bool
+		filters         repoFilterFlags
+	)
 
 	cmd := &cobra.Command{
 		Use:   "push",
 		Short: "Push local commits from all repositories",
 		Long:  "Push local commits to remote repositories in the workspace.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSyncPush(cmd.Context(), dryRun)
+			return runSyncPush(cmd.Context(), dryRun, confirm, includeArchived, &filters)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Allow pushing protected branches")
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "Also push archived (read-only) repositories")
+	filters.register(cmd, nil)
 
 	return cmd
 }
 
-func runSyncAll(ctx context.Context, pull, push, rebase, dryRun bool) error {
+func runSyncAll(ctx context.Context, pull, push, rebase, dryRun, confirm, includeArchived bool, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 	options := &wsm.SyncOptions{
-		Pull:   pull,
-		Push:   push,
-		Rebase: rebase,
-		DryRun: dryRun,
+		Pull:            pull,
+		Push:            push,
+		Rebase:          rebase,
+		DryRun:          dryRun,
+		Confirm:         confirm,
+		IncludeArchived: includeArchived,
 	}
 
 	output.PrintHeader("Synchronizing workspace: %s", workspace.Name)
+	printFilterInfo(workspace)
 	if dryRun {
 		output.PrintInfo("Dry run mode - no changes will be made")
 	}
 
 	results, err := syncOps.SyncWorkspace(ctx, options)
+	if !dryRun {
+		notifySync(ctx, workspace, "Sync", results, err)
+	}
 	if err != nil {
+		if isInterrupted(err) {
+			_ = printSyncResults(results, dryRun)
+		}
 		return errors.Wrap(err, "sync failed")
 	}
 
 	return printSyncResults(results, dryRun)
 }
 
-func runSyncPull(ctx context.Context, rebase, dryRun bool) error {
+func runSyncPull(ctx context.Context, rebase, dryRun bool, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 	options := &wsm.SyncOptions{
@@ -134,45 +165,71 @@ func runSyncPull(ctx context.Context, rebase, dryRun bool) error {
 	}
 
 	output.PrintHeader("Pulling changes for workspace: %s", workspace.Name)
+	printFilterInfo(workspace)
 	if dryRun {
 		output.PrintInfo("Dry run mode - no changes will be made")
 	}
 
 	results, err := syncOps.SyncWorkspace(ctx, options)
+	if !dryRun {
+		notifySync(ctx, workspace, "Pull", results, err)
+	}
 	if err != nil {
+		if isInterrupted(err) {
+			_ = printSyncResults(results, dryRun)
+		}
 		return errors.Wrap(err, "pull failed")
 	}
 
 	return printSyncResults(results, dryRun)
 }
 
-func runSyncPush(ctx context.Context, dryRun bool) error {
+func runSyncPush(ctx context.Context, dryRun, confirm, includeArchived bool, filters *repoFilterFlags) error {
 	workspace, err := detectCurrentWorkspace()
 	if err != nil {
 		return errors.Wrap(err, "failed to detect current workspace")
 	}
+	if workspace, err = filters.apply(workspace); err != nil {
+		return err
+	}
 
 	syncOps := wsm.NewSyncOperations(workspace)
 	options := &wsm.SyncOptions{
-		Pull:   false,
-		Push:   true,
-		Rebase: false,
-		DryRun: dryRun,
+		Pull:            false,
+		Push:            true,
+		Rebase:          false,
+		DryRun:          dryRun,
+		Confirm:         confirm,
+		IncludeArchived: includeArchived,
 	}
 
 	output.PrintHeader("📤 Pushing changes for workspace: %s", workspace.Name)
+	printFilterInfo(workspace)
 	if dryRun {
 		output.PrintInfo("Dry run mode - no changes will be made")
 	}
 
 	results, err := syncOps.SyncWorkspace(ctx, options)
+	if !dryRun {
+		notifySync(ctx, workspace, "Push", results, err)
+	}
 	if err != nil {
+		if isInterrupted(err) {
+			_ = printSyncResults(results, dryRun)
+		}
 		return errors.Wrap(err, "push failed")
 	}
 
 	return printSyncResults(results, dryRun)
 }
 
+// isInterrupted reports whether a workspace-wide operation stopped early, e.g.
+// on Ctrl-C, so its partial results are worth showing
+func isInterrupted(err error) bool {
+	var interrupted *wsm.InterruptedError
+	return errors.As(err, &interrupted)
+}
+
 func printSyncResults(results []wsm.SyncResult, dryRun bool) error {
 	if len(results) == 0 {
 		output.PrintInfo("No repositories to sync.")
@@ -195,10 +252,14 @@ func printSyncResults(results []wsm.SyncResult, dryRun bool) error {
 
 	successCount := 0
 	conflictCount := 0
+	skippedCount := 0
 
 	for _, result := range results {
 		status := "✅"
-		if !result.Success {
+		if result.Skipped {
+			status = "📌"
+			skippedCount++
+		} else if !result.Success {
 			status = "❌"
 		} else {
 			successCount++
@@ -239,9 +300,22 @@ func printSyncResults(results []wsm.SyncResult, dryRun bool) error {
 	}
 
 	fmt.Fprintln(w)
+	if err := w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush table writer")
+	}
+
+	// The table truncates errors; show failures (e.g. policy violations and their fix) in full
+	for _, result := range results {
+		if !result.Success && !result.Conflicts && result.Error != "" {
+			output.PrintError("%s: %s", result.Repository, result.Error)
+		}
+	}
 
 	// Summary
-	output.PrintSuccess("Summary: %d/%d repositories synced successfully", successCount, len(results))
+	output.PrintSuccess("Summary: %d/%d repositories synced successfully", successCount, len(results)-skippedCount)
+	if skippedCount > 0 {
+		output.PrintInfo("%d pinned repositories skipped", skippedCount)
+	}
 	if conflictCount > 0 {
 		output.PrintWarning("⚠️  %d repositories have conflicts", conflictCount)
 		output.PrintInfo("Resolve conflicts manually and run sync again.")
@@ -3,11 +3,13 @@ package cmds
 import (
 	"bufio"
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
@@ -20,6 +22,9 @@ import (
 func NewTmuxCommand() *cobra.Command {
 	var workspace string
 	var profile string
+	var dryRun bool
+	var noStatus bool
+	var perRepo bool
 
 	cmd := &cobra.Command{
 		Use:   "tmux [workspace-name]",
@@ -32,26 +37,81 @@ The command will:
 2. Execute commands from tmux.conf files based on profile selection:
    - If --profile is specified: .wsm/profiles/PROFILE/tmux.conf
    - Otherwise: .wsm/tmux.conf (fallback to default behavior)
-3. Search both workspace root and all top-level directories`,
+3. Search both workspace root and all top-level directories
+
+Windows and panes can be declared in .wsm/tmux.yaml (or
+.wsm/profiles/PROFILE/tmux.yaml) in the workspace root and in repositories:
+
+  windows:
+    - name: code
+      repo: app                 # start in a workspace repository
+      layout: main-vertical     # any tmux layout
+      panes:
+        - commands: [nvim .]
+        - repo: lib
+          split: horizontal     # side by side; vertical (stacked) is the default
+          size: 30%
+          commands: [go test ./...]
+    - name: server
+      dir: app/cmd/server       # relative to the directory containing .wsm
+      commands: [make run]
+
+Layout windows are created first; tmux.conf lines are then sent to the session.
+
+With --per-repo, or 'per-repo: true' in tmux.yaml, the session gets a root
+window and one window per repository, named after it and started in its
+worktree, and tmux.conf files are not replayed. Windows declared next to
+per-repo replace the generated window of the same name or are added after them.
+
+The workspace status (dirty repositories, commits ahead and behind) is shown in
+front of the session's status-right, refreshed by 'wsm tmux refresh-status'
+every 15 seconds from the status cache shared with shell prompts. Windows tmux
+names automatically are titled after the repository they are in. Use
+#{@wsm-status} in a custom status line, or --no-status to leave it alone.
+
+Examples:
+  # Preview the tmux commands of the review profile
+  wsm tmux my-feature --profile review --dry-run
+
+  # One window per repository
+  wsm tmux my-feature --per-repo`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			workspaceName := workspace
 			if len(args) > 0 {
 				workspaceName = args[0]
 			}
-			return runTmux(cmd.Context(), workspaceName, profile)
+			return runTmux(cmd.Context(), workspaceName, profile, dryRun, noStatus, perRepo)
 		},
 	}
 
+	cmd.AddCommand(newTmuxRefreshStatusCommand())
+
 	cmd.Flags().StringVar(&workspace, "workspace", "", "Workspace name")
-	cmd.Flags().StringVar(&profile, "profile", "", "Tmux profile to use (looks for .wsm/profiles/PROFILE/tmux.conf)")
+	cmd.Flags().StringVar(&profile, "profile", "", "Tmux profile to use (looks for .wsm/profiles/PROFILE/tmux.yaml and tmux.conf)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the tmux commands of the layout instead of creating the session")
+	cmd.Flags().BoolVar(&noStatus, "no-status", false, "Do not show the workspace status in the tmux status line")
+	cmd.Flags().BoolVar(&perRepo, "per-repo", false, "Create a root window and one window per repository instead of replaying tmux.conf")
 
 	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
 
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"workspace": WorkspaceNameCompletion(),
+			"profile":   ProfileCompletion(&workspace),
+		},
+	)
+
 	return cmd
 }
 
-func runTmux(ctx context.Context, workspaceName, profile string) error {
+func runTmux(ctx context.Context, workspaceName, profile string, dryRun, noStatus, perRepo bool) error {
+	if !dryRun {
+		if err := wsm.RequireTool(ctx, "tmux"); err != nil {
+			return errors.Wrap(err, "wsm tmux is unavailable")
+		}
+	}
+
 	// If no workspace specified, try to detect current workspace
 	if workspaceName == "" {
 		cwd, err := os.Getwd()
@@ -74,6 +134,29 @@ func runTmux(ctx context.Context, workspaceName, profile string) error {
 
 	sessionName := workspaceName
 
+	tmuxService := wsm.NewTmuxService(workspace)
+	layout, err := tmuxService.LoadLayout(profile)
+	if err != nil {
+		return err
+	}
+	if perRepo && (layout == nil || !layout.PerRepo) {
+		if layout, err = tmuxService.PerRepoLayout(layout); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		if err := printTmuxLayoutCommands(tmuxService, sessionName, layout); err != nil {
+			return err
+		}
+		if !noStatus {
+			for _, args := range tmuxStatusBarCommands(ctx, tmuxService, sessionName) {
+				fmt.Printf("tmux %s\n", wsm.ShellJoin(args))
+			}
+		}
+		return nil
+	}
+
 	// Check if tmux session already exists
 	checkCmd := exec.CommandContext(ctx, "tmux", "has-session", "-t", sessionName)
 	sessionExists := checkCmd.Run() == nil
@@ -86,21 +169,129 @@ func runTmux(ctx context.Context, workspaceName, profile string) error {
 
 	output.PrintInfo("Creating new tmux session: %s", sessionName)
 
-	// Create new session in detached mode
-	createCmd := exec.CommandContext(ctx, "tmux", "new-session", "-d", "-s", sessionName, "-c", workspace.Path)
-	if err := createCmd.Run(); err != nil {
-		return errors.Wrapf(err, "failed to create tmux session '%s'", sessionName)
+	// Create new session in detached mode, with the declared windows if there is a layout
+	if layout != nil {
+		if err := tmuxService.CreateSession(ctx, sessionName, layout); err != nil {
+			return errors.Wrapf(err, "failed to create tmux session '%s' from layout", sessionName)
+		}
+	} else {
+		createCmd := exec.CommandContext(ctx, "tmux", "new-session", "-d", "-s", sessionName, "-c", workspace.Path)
+		if err := createCmd.Run(); err != nil {
+			return errors.Wrapf(err, "failed to create tmux session '%s'", sessionName)
+		}
 	}
 
-	// Execute tmux.conf files
-	if err := executeTmuxConfFiles(ctx, workspace, sessionName, profile); err != nil {
-		log.Warn().Err(err).Msg("Failed to execute tmux.conf files")
+	// Before tmux.conf, which may override the status line
+	if !noStatus {
+		setupTmuxStatusBar(ctx, tmuxService, sessionName)
+	}
+
+	// Execute tmux.conf files, which per-repo sessions replace
+	if layout == nil || !layout.PerRepo {
+		if err := executeTmuxConfFiles(ctx, workspace, sessionName, profile); err != nil {
+			log.Warn().Err(err).Msg("Failed to execute tmux.conf files")
+		}
 	}
 
 	// Replace current process with tmux attach
 	return execTmux("attach-session", "-t", sessionName)
 }
 
+// tmuxStatusBarCommands returns the tmux commands showing the workspace status
+// in the status line of a session
+func tmuxStatusBarCommands(ctx context.Context, tmuxService *wsm.TmuxService, sessionName string) [][]string {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "wsm"
+	}
+	// New sessions start with the global status-right
+	statusRight, _ := exec.CommandContext(ctx, "tmux", "show-options", "-gv", "status-right").Output()
+	refresh := []string{executable, "tmux", "refresh-status", "--session", sessionName, sessionName}
+	return tmuxService.StatusBarCommands(sessionName, strings.TrimRight(string(statusRight), "\n"), refresh)
+}
+
+// setupTmuxStatusBar shows the workspace status in the status line of a
+// session. Failures only disable the status.
+func setupTmuxStatusBar(ctx context.Context, tmuxService *wsm.TmuxService, sessionName string) {
+	for _, args := range tmuxStatusBarCommands(ctx, tmuxService, sessionName) {
+		if out, err := exec.CommandContext(ctx, "tmux", args...).CombinedOutput(); err != nil {
+			log.Warn().Err(err).Str("output", strings.TrimSpace(string(out))).Msg("Failed to set up the tmux status line")
+			return
+		}
+	}
+	if _, err := tmuxService.RefreshStatus(ctx, sessionName, 10*time.Second); err != nil {
+		log.Debug().Err(err).Msg("Failed to refresh the tmux status")
+	}
+}
+
+func newTmuxRefreshStatusCommand() *cobra.Command {
+	var (
+		session string
+		maxAge  time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "refresh-status [workspace-name]",
+		Short: "Refresh the workspace status shown by tmux",
+		Long: `Print the status segment of a workspace (e.g. "●2 ↑3 ↓1", "✓" when clean),
+store it in the @wsm-status option of its tmux session and title the session's
+windows after the repository they are in.
+
+It is run by tmux from the status line set up by 'wsm tmux' and reads the
+status cache shared with shell prompts, so it is cheap. Errors are logged
+rather than printed, to keep them out of the status line.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			var workspace *wsm.Workspace
+			var err error
+			if workspaceName != "" {
+				// tmux refreshing the status line is no use of the workspace
+				workspace, err = findWorkspace(workspaceName)
+			} else {
+				workspace, err = namedOrCurrentWorkspace("", "wsm tmux refresh-status <workspace-name>")
+			}
+			if err != nil {
+				log.Debug().Err(err).Msg("Failed to load the workspace of the tmux status")
+				return nil
+			}
+			if session == "" {
+				session = workspace.Name
+			}
+
+			segment, err := wsm.NewTmuxService(workspace).RefreshStatus(cmd.Context(), session, maxAge)
+			if err != nil {
+				log.Debug().Err(err).Str("session", session).Msg("Failed to refresh the tmux status")
+			}
+			fmt.Println(segment)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&session, "session", "", "Tmux session (default: the workspace name)")
+	cmd.Flags().DurationVar(&maxAge, "max-age", 10*time.Second, "Recompute the cached status when older than this")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+
+	return cmd
+}
+
+// printTmuxLayoutCommands prints the tmux commands a layout would run
+func printTmuxLayoutCommands(tmuxService *wsm.TmuxService, sessionName string, layout *wsm.TmuxLayout) error {
+	if layout == nil {
+		output.PrintInfo("No tmux.yaml layout found; the session would start with a single window")
+		return nil
+	}
+
+	for _, args := range tmuxService.Commands(sessionName, layout) {
+		fmt.Printf("tmux %s\n", wsm.ShellJoin(args))
+	}
+	return nil
+}
+
 func executeTmuxConfFiles(ctx context.Context, workspace *wsm.Workspace, sessionName, profile string) error {
 	// Determine tmux.conf file paths based on profile
 	var tmuxConfPaths []TmuxConfPath
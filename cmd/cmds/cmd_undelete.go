@@ -0,0 +1,167 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewUndeleteCommand creates the undelete command
+func NewUndeleteCommand() *cobra.Command {
+	var (
+		list         bool
+		purge        bool
+		purgeAll     bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "undelete [workspace-name]",
+		Short: "Restore a workspace deleted with --trash",
+		Long: `Restore a workspace that was moved to the trash with 'wsm delete --trash'.
+
+The workspace directory is moved back to its original location, its
+worktrees are repaired and unlocked, and its configuration is restored.
+Trashed workspaces are kept for 7 days; expired entries are purged the
+next time a workspace is trashed, or with --purge.
+
+Examples:
+  # Restore the most recently trashed workspace named my-workspace
+  wsm undelete my-workspace
+
+  # List the trash
+  wsm undelete --list
+
+  # Permanently delete expired trash entries
+  wsm undelete --purge
+
+  # Empty the trash
+  wsm undelete --purge-all`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case purge || purgeAll:
+				return runPurgeTrash(cmd.Context(), purgeAll)
+			case list || len(args) == 0:
+				return runListTrash(outputFormat)
+			}
+			return runUndelete(cmd.Context(), args[0], outputFormat)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&list, "list", "l", false, "List trashed workspaces")
+	cmd.Flags().BoolVar(&purge, "purge", false, "Permanently delete trashed workspaces whose grace period expired")
+	cmd.Flags().BoolVar(&purgeAll, "purge-all", false, "Permanently delete all trashed workspaces")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"output": carapace.ActionValues("table", "json"),
+		},
+	)
+	carapace.Gen(cmd).PositionalCompletion(TrashedWorkspaceCompletion())
+
+	return cmd
+}
+
+func runUndelete(ctx context.Context, name string, outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	entry, err := wm.FindTrashEntry(name)
+	if err != nil {
+		return err
+	}
+
+	workspace, err := wm.RestoreWorkspace(ctx, entry)
+	if err != nil {
+		return errors.Wrapf(err, "failed to restore workspace '%s'", entry.Workspace.Name)
+	}
+
+	if outputFormat == "json" {
+		return wsm.PrintJSON(workspace)
+	}
+
+	output.PrintSuccess("Workspace '%s' restored to %s", workspace.Name, workspace.Path)
+	return nil
+}
+
+func runListTrash(outputFormat string) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	entries, err := wm.ListTrash()
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		if entries == nil {
+			entries = []*wsm.TrashEntry{}
+		}
+		return wsm.PrintJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		output.PrintInfo("The trash is empty")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tDELETED\tEXPIRES\tREPOS\tPATH")
+	_, _ = fmt.Fprintln(w, "----\t-------\t-------\t-----\t----")
+	for _, entry := range entries {
+		expires := formatTrashExpiry(entry.ExpiresAt)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			entry.Workspace.Name,
+			entry.DeletedAt.Format("2006-01-02 15:04"),
+			expires,
+			len(entry.Workspace.Repositories),
+			entry.Workspace.Path,
+		)
+	}
+	return w.Flush()
+}
+
+func runPurgeTrash(ctx context.Context, all bool) error {
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	purged, err := wm.PurgeTrash(ctx, all)
+	for _, entry := range purged {
+		output.PrintSuccess("Purged '%s' (deleted %s)", entry.Workspace.Name, entry.DeletedAt.Format("2006-01-02 15:04"))
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(purged) == 0 {
+		output.PrintInfo("Nothing to purge")
+	}
+	return nil
+}
+
+func formatTrashExpiry(expiresAt time.Time) string {
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return "expired"
+	}
+	if remaining < 24*time.Hour {
+		return fmt.Sprintf("in %dh", int(remaining.Hours()))
+	}
+	return fmt.Sprintf("in %dd", int(remaining.Hours()/24))
+}
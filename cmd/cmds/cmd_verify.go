@@ -0,0 +1,134 @@
+package cmds
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// NewVerifyCommand creates the verify command
+func NewVerifyCommand() *cobra.Command {
+	var (
+		repair       bool
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify [workspace-name]",
+		Short: "Compare a workspace's saved definition with the disk",
+		Long: `Compare the saved definition of a workspace (the current one by default)
+with what is on disk and report every difference, the saved definition as -
+and the disk as +:
+
+  - missing worktrees, or directories in their place that are not worktrees
+  - worktrees that belong to another repository or lost the link to theirs
+  - repositories whose registered path no longer exists
+  - worktrees on another branch than the workspace's
+  - git checkouts in the workspace directory that are not part of it
+  - go.work missing or not using exactly the Go repositories
+  - .wsm/wsm.json describing another workspace, path or repositories
+
+--repair recreates missing worktrees, repairs worktree links, switches clean
+worktrees back to their branch and rewrites go.work and wsm.json. It never
+deletes anything; other findings come with a hint.
+
+Examples:
+  # Verify the current workspace
+  wsm verify
+
+  # Reconcile a workspace with its definition
+  wsm verify my-feature --repair`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workspaceName := ""
+			if len(args) > 0 {
+				workspaceName = args[0]
+			}
+			return runVerify(cmd.Context(), workspaceName, repair, outputFormat)
+		},
+	}
+
+	cmd.Flags().BoolVar(&repair, "repair", false, "Reconcile the disk with the saved definition where it is safe")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format (table, json)")
+
+	carapace.Gen(cmd).PositionalCompletion(WorkspaceNameCompletion())
+	carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+		"output": carapace.ActionValues("table", "json"),
+	})
+
+	return cmd
+}
+
+func runVerify(ctx context.Context, workspaceName string, repair bool, outputFormat string) error {
+	if outputFormat != "table" && outputFormat != "json" {
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+	workspace, err := namedOrCurrentWorkspace(workspaceName, "wsm verify <workspace-name>")
+	if err != nil {
+		return err
+	}
+	wm, err := wsm.NewWorkspaceManager()
+	if err != nil {
+		return errors.Wrap(err, "failed to create workspace manager")
+	}
+
+	report, err := wm.VerifyWorkspace(ctx, workspace, repair)
+	if err != nil {
+		return errors.Wrapf(err, "failed to verify workspace '%s'", workspace.Name)
+	}
+
+	if outputFormat == "json" {
+		if err := wsm.PrintJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printVerifyReport(report, repair)
+	}
+	if unresolved := report.Unresolved(); unresolved > 0 {
+		return errors.Errorf("workspace '%s' differs from its definition in %d places", workspace.Name, unresolved)
+	}
+	return nil
+}
+
+// printVerifyReport prints the findings as a diff of the saved definition (-)
+// and the disk (+)
+func printVerifyReport(report *wsm.VerifyReport, repair bool) {
+	output.PrintHeader("Workspace: %s", report.Workspace)
+	if len(report.Findings) == 0 {
+		output.PrintSuccess("The workspace matches its definition")
+		return
+	}
+
+	repairable := 0
+	fmt.Println("--- saved definition")
+	fmt.Println("+++ on disk")
+	for _, finding := range report.Findings {
+		title := finding.Kind
+		if finding.Repository != "" {
+			title = fmt.Sprintf("%s: %s", finding.Repository, finding.Kind)
+		}
+		fmt.Printf("@@ %s @@\n", title)
+		fmt.Println(output.ErrorStyle.Render("- " + finding.Expected))
+		fmt.Println(output.SuccessStyle.Render("+ " + finding.Actual))
+		switch {
+		case finding.Repaired:
+			fmt.Println("  ✅ repaired")
+		case finding.RepairError != "":
+			fmt.Printf("  ❌ repair failed: %s\n", finding.RepairError)
+		case finding.Repairable:
+			repairable++
+		case finding.Hint != "":
+			fmt.Printf("  hint: %s\n", finding.Hint)
+		}
+	}
+	fmt.Println()
+
+	if !repair && repairable > 0 {
+		output.PrintInfo("%d of %d differences can be reconciled with 'wsm verify %s --repair'", repairable, len(report.Findings), report.Workspace)
+	}
+}
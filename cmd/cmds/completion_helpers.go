@@ -1,6 +1,11 @@
 package cmds
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
 	"github.com/carapace-sh/carapace"
 	"github.com/go-go-golems/workspace-manager/pkg/wsm"
 )
@@ -20,6 +25,45 @@ func WorkspaceNameCompletion() carapace.Action {
 	})
 }
 
+// InterruptedWorkspaceCompletion returns a carapace.Action that completes names of
+// workspaces whose journaled creation was interrupted.
+func InterruptedWorkspaceCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		wm, err := wsm.NewWorkspaceManager()
+		if err != nil {
+			return carapace.ActionMessage("failed to create workspace manager")
+		}
+		journals, err := wm.FindInterruptedCreations()
+		if err != nil {
+			return carapace.ActionMessage("failed to find interrupted creations")
+		}
+		var values []string
+		for _, journal := range journals {
+			values = append(values, journal.Workspace.Name, journal.Workspace.Path)
+		}
+		return carapace.ActionValuesDescribed(values...)
+	})
+}
+
+// TrashedWorkspaceCompletion returns a carapace.Action that completes workspaces in the trash
+func TrashedWorkspaceCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		wm, err := wsm.NewWorkspaceManager()
+		if err != nil {
+			return carapace.ActionMessage("failed to create workspace manager")
+		}
+		entries, err := wm.ListTrash()
+		if err != nil {
+			return carapace.ActionMessage("failed to list trash")
+		}
+		var values []string
+		for _, entry := range entries {
+			values = append(values, entry.Workspace.Name, "deleted "+entry.DeletedAt.Format("2006-01-02 15:04"))
+		}
+		return carapace.ActionValuesDescribed(values...)
+	})
+}
+
 // RepositoryNameCompletion returns a carapace.Action that completes repository names
 // from the registry for add commands.
 func RepositoryNameCompletion() carapace.Action {
@@ -40,6 +84,33 @@ func RepositoryNameCompletion() carapace.Action {
 	})
 }
 
+// GroupNameCompletion returns a carapace.Action that completes repository group names.
+func GroupNameCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		registryPath, err := getRegistryPath()
+		if err != nil {
+			return carapace.ActionMessage("failed to get registry path")
+		}
+		discoverer := wsm.NewRepositoryDiscoverer(registryPath)
+		if err := discoverer.LoadRegistry(); err != nil {
+			return carapace.ActionMessage("failed to load registry")
+		}
+		var names []string
+		for name := range discoverer.GetGroups() {
+			names = append(names, name)
+		}
+		return carapace.ActionValues(names...)
+	})
+}
+
+// RepositoryOrGroupCompletion completes repository names and "@group" references.
+func RepositoryOrGroupCompletion() carapace.Action {
+	return carapace.Batch(
+		RepositoryNameCompletion(),
+		GroupNameCompletion().Prefix(wsm.GroupPrefix),
+	).ToA()
+}
+
 // WorkspaceRepositoryCompletion returns a carapace.Action that completes repository names
 // that are currently part of the specified workspace (for remove commands).
 func WorkspaceRepositoryCompletion() carapace.Action {
@@ -91,3 +162,155 @@ func TagCompletion() carapace.Action {
 		return carapace.ActionValues(tags...)
 	})
 }
+
+// CommitTemplateCompletion returns a carapace.Action that completes built-in and configured commit templates.
+func CommitTemplateCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		templates, err := wsm.LoadCommitTemplates()
+		if err != nil {
+			return carapace.ActionMessage("failed to load commit templates")
+		}
+		var values []string
+		for _, name := range templates.Names() {
+			values = append(values, name, templates.Templates[name])
+		}
+		return carapace.ActionValuesDescribed(values...)
+	})
+}
+
+// ScaffoldCompletion returns a carapace.Action that completes scaffold templates of the template directory.
+func ScaffoldCompletion() carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		wm, err := wsm.NewWorkspaceManager()
+		if err != nil {
+			return carapace.ActionMessage("failed to load configuration")
+		}
+		return carapace.Batch(
+			carapace.ActionValues(append(wm.ScaffoldNames(), wsm.NoScaffold)...),
+			carapace.ActionDirectories(),
+		).ToA()
+	})
+}
+
+// completionWorkspace resolves the workspace a completion refers to: the explicit name
+// (e.g. a --workspace flag value), the first positional argument if it names a
+// workspace, or the workspace containing the current directory.
+func completionWorkspace(ctx carapace.Context, explicit string) (*wsm.Workspace, error) {
+	workspaces, err := wsm.LoadWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{explicit}
+	if len(ctx.Args) > 0 {
+		candidates = append(candidates, ctx.Args[0])
+	}
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		for i := range workspaces {
+			if workspaces[i].Name == name {
+				return &workspaces[i], nil
+			}
+		}
+	}
+
+	dir := ctx.Dir
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	return wsm.DetectWorkspace(dir, workspaces)
+}
+
+// CurrentWorkspaceRepositoryCompletion returns a carapace.Action that completes repository
+// names of the workspace selected by the --workspace flag value or the current directory.
+func CurrentWorkspaceRepositoryCompletion(workspaceFlag *string) carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		ws, err := completionWorkspace(ctx, flagValue(workspaceFlag))
+		if err != nil {
+			return carapace.ActionMessage("not in a workspace")
+		}
+		var names []string
+		for _, repo := range ws.Repositories {
+			names = append(names, repo.Name)
+		}
+		return carapace.ActionValues(names...)
+	})
+}
+
+// BranchCompletion returns a carapace.Action that completes local branch names across
+// the repositories of the workspace selected by the --workspace flag value or the current directory.
+func BranchCompletion(workspaceFlag *string) carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		ws, err := completionWorkspace(ctx, flagValue(workspaceFlag))
+		if err != nil {
+			return carapace.ActionMessage("not in a workspace")
+		}
+
+		branchSet := make(map[string]struct{})
+		for _, repo := range ws.Repositories {
+			cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "refs/heads")
+			cmd.Dir = filepath.Join(ws.Path, repo.Name)
+			out, err := cmd.Output()
+			if err != nil {
+				continue
+			}
+			for _, branch := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+				if branch != "" {
+					branchSet[branch] = struct{}{}
+				}
+			}
+		}
+
+		var branches []string
+		for branch := range branchSet {
+			branches = append(branches, branch)
+		}
+		return carapace.ActionValues(branches...)
+	})
+}
+
+// ProfileCompletion returns a carapace.Action that completes tmux profiles
+// (.wsm/profiles/<name>) of the workspace and its repositories.
+func ProfileCompletion(workspaceFlag *string) carapace.Action {
+	return carapace.ActionCallback(func(ctx carapace.Context) carapace.Action {
+		ws, err := completionWorkspace(ctx, flagValue(workspaceFlag))
+		if err != nil {
+			return carapace.ActionMessage("not in a workspace")
+		}
+
+		dirs := []string{filepath.Join(ws.Path, ".wsm", "profiles")}
+		for _, repo := range ws.Repositories {
+			dirs = append(dirs, filepath.Join(ws.Path, repo.Name, ".wsm", "profiles"))
+		}
+
+		profileSet := make(map[string]struct{})
+		for _, dir := range dirs {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() {
+					profileSet[entry.Name()] = struct{}{}
+				}
+			}
+		}
+
+		var profiles []string
+		for profile := range profileSet {
+			profiles = append(profiles, profile)
+		}
+		return carapace.ActionValues(profiles...)
+	})
+}
+
+func flagValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,140 @@
+package cmds
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+)
+
+var (
+	plannerCursorStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	plannerDropStyle   = lipgloss.NewStyle().Strikethrough(true).Foreground(lipgloss.Color("8"))
+	plannerMeldStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	plannerErrorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// rebasePlanner is the TUI editing the rebase plans of several repositories
+type rebasePlanner struct {
+	plans     []*wsm.RebasePlan
+	plan      int // repository under the cursor
+	commit    int // commit under the cursor
+	confirmed bool
+	err       string
+}
+
+// editRebasePlans lets the user reorder, squash, fixup and drop the commits of
+// every plan. It returns false when the user aborted.
+func editRebasePlans(plans []*wsm.RebasePlan) (bool, error) {
+	result, err := tea.NewProgram(&rebasePlanner{plans: plans}).Run()
+	if err != nil {
+		return false, errors.Wrap(err, "rebase planner failed")
+	}
+	return result.(*rebasePlanner).confirmed, nil
+}
+
+func (m *rebasePlanner) Init() tea.Cmd {
+	return nil
+}
+
+func (m *rebasePlanner) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	m.err = ""
+	plan := m.plans[m.plan]
+
+	switch key.String() {
+	case "ctrl+c", "q", "esc":
+		return m, tea.Quit
+	case "enter":
+		for _, p := range m.plans {
+			if err := p.Validate(); err != nil {
+				m.err = err.Error()
+				return m, nil
+			}
+		}
+		m.confirmed = true
+		return m, tea.Quit
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case "tab":
+		m.plan = (m.plan + 1) % len(m.plans)
+		m.commit = 0
+	case "shift+up", "K":
+		m.commit = plan.Move(m.commit, -1)
+	case "shift+down", "J":
+		m.commit = plan.Move(m.commit, 1)
+	case "p":
+		plan.Commits[m.commit].Action = wsm.RebasePick
+	case "s":
+		plan.Commits[m.commit].Action = wsm.RebaseSquash
+	case "f":
+		plan.Commits[m.commit].Action = wsm.RebaseFixup
+	case "d":
+		plan.Commits[m.commit].Action = wsm.RebaseDrop
+	}
+	return m, nil
+}
+
+// moveCursor moves to the previous or next commit, crossing repositories
+func (m *rebasePlanner) moveCursor(delta int) {
+	m.commit += delta
+	if m.commit < 0 {
+		if m.plan == 0 {
+			m.commit = 0
+			return
+		}
+		m.plan--
+		m.commit = len(m.plans[m.plan].Commits) - 1
+	} else if m.commit >= len(m.plans[m.plan].Commits) {
+		if m.plan == len(m.plans)-1 {
+			m.commit = len(m.plans[m.plan].Commits) - 1
+			return
+		}
+		m.plan++
+		m.commit = 0
+	}
+}
+
+func (m *rebasePlanner) View() string {
+	var b strings.Builder
+	b.WriteString(output.HeaderStyle.Render("Interactive rebase plan") + "\n")
+	b.WriteString(output.DimStyle.Render("Commits are listed oldest first, in the order they will be replayed.") + "\n\n")
+
+	for i, plan := range m.plans {
+		changed := ""
+		if plan.Changed() {
+			changed = " (modified)"
+		}
+		fmt.Fprintf(&b, "%s onto %s%s\n", output.BoldStyle.Render(plan.Repository), plan.Onto, changed)
+		for j, commit := range plan.Commits {
+			line := fmt.Sprintf("%-6s %s %s", commit.Action, commit.ShortSHA(), commit.Subject)
+			switch commit.Action {
+			case wsm.RebaseDrop:
+				line = plannerDropStyle.Render(line)
+			case wsm.RebaseSquash, wsm.RebaseFixup:
+				line = plannerMeldStyle.Render(line)
+			}
+			if i == m.plan && j == m.commit {
+				b.WriteString(plannerCursorStyle.Render("> ") + line + "\n")
+			} else {
+				b.WriteString("  " + line + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if m.err != "" {
+		b.WriteString(plannerErrorStyle.Render(m.err) + "\n\n")
+	}
+	b.WriteString(output.DimStyle.Render("↑/↓ select • shift+↑/↓ or K/J move • p pick • s squash • f fixup • d drop • tab next repository • enter rebase • q abort") + "\n")
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package cmds
+
+import (
+	"github.com/carapace-sh/carapace"
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/spf13/cobra"
+)
+
+// repoFilterFlags are the --repo, --exclude-repo and --tag flags shared by
+// workspace-wide commands
+type repoFilterFlags struct {
+	repos   []string
+	exclude []string
+	tags    []string
+}
+
+// register adds the filter flags and their completion to cmd. workspaceFlag is
+// the value of the command's --workspace flag, if it has one.
+func (f *repoFilterFlags) register(cmd *cobra.Command, workspaceFlag *string) {
+	cmd.Flags().StringSliceVar(&f.repos, "repo", nil, "Only these repositories (comma-separated, repeatable)")
+	cmd.Flags().StringSliceVar(&f.exclude, "exclude-repo", nil, "Skip these repositories (comma-separated, repeatable)")
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "Only repositories with any of these tags (comma-separated, repeatable)")
+
+	carapace.Gen(cmd).FlagCompletion(
+		carapace.ActionMap{
+			"repo":         CurrentWorkspaceRepositoryCompletion(workspaceFlag).UniqueList(","),
+			"exclude-repo": CurrentWorkspaceRepositoryCompletion(workspaceFlag).UniqueList(","),
+			"tag":          TagCompletion().UniqueList(","),
+		},
+	)
+}
+
+// filter returns the repository filter given on the command line
+func (f *repoFilterFlags) filter() wsm.RepositoryFilter {
+	return wsm.RepositoryFilter{Repositories: f.repos, Exclude: f.exclude, Tags: f.tags}
+}
+
+// apply narrows a workspace to the filtered repositories
+func (f *repoFilterFlags) apply(workspace *wsm.Workspace) (*wsm.Workspace, error) {
+	return f.filter().Apply(workspace)
+}
+
+// printFilterInfo mentions the filter of a narrowed workspace under a header
+func printFilterInfo(workspace *wsm.Workspace) {
+	if workspace.Filter != nil {
+		output.PrintInfo("   (%s)", workspace.Filter)
+	}
+}
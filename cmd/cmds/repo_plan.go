@@ -0,0 +1,101 @@
+package cmds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+)
+
+// emitRepositoryPlan prints the plan of an add or remove, as JSON or as a
+// report. Conflicts fail the command so scripts can check the plan.
+func emitRepositoryPlan(plan *wsm.RepositoryPlan, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		if err := wsm.PrintJSON(plan); err != nil {
+			return err
+		}
+	case "table":
+		printRepositoryPlan(plan)
+	default:
+		return errors.Errorf("unsupported output format: %s", outputFormat)
+	}
+	if len(plan.Conflicts) > 0 {
+		return errors.Errorf("%s of '%s' has %d conflict(s)", plan.Operation, plan.Repository, len(plan.Conflicts))
+	}
+	return nil
+}
+
+func printRepositoryPlan(plan *wsm.RepositoryPlan) {
+	verb := "Adding"
+	if plan.Operation == wsm.RepositoryPlanRemove {
+		verb = "Removing"
+	}
+	output.PrintHeader("Plan: %s %s (workspace %s)", verb, plan.Repository, plan.Workspace)
+	fmt.Printf("  Worktree: %s\n", plan.Path)
+	if plan.Branch != "" || plan.BranchAction != "" {
+		fmt.Printf("  Branch: %s (%s)\n", plan.Branch, plan.BranchAction)
+	}
+
+	if len(plan.Steps) > 0 {
+		fmt.Println("\n  Steps:")
+		for i, step := range plan.Steps {
+			fmt.Printf("    %d. %s\n", i+1, step.Description)
+			if len(step.Command) > 0 {
+				fmt.Printf("       %s\n", wsm.ShellJoin(step.Command))
+			}
+		}
+	}
+
+	for _, manifest := range plan.Manifests {
+		fmt.Printf("\n  %s:\n", manifest.Path)
+		for _, line := range manifest.Diff {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				fmt.Println("    " + output.SuccessStyle.Render(line))
+			case strings.HasPrefix(line, "-"):
+				fmt.Println("    " + output.ErrorStyle.Render(line))
+			default:
+				fmt.Println("    " + line)
+			}
+		}
+	}
+
+	if len(plan.Environment) > 0 {
+		fmt.Println("\n  Environment of setup scripts:")
+		keys := make([]string, 0, len(plan.Environment))
+		for key := range plan.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("    %s=%s\n", key, plan.Environment[key])
+		}
+	}
+
+	if len(plan.SetupScripts) > 0 {
+		fmt.Println("\n  Setup scripts:")
+		for _, script := range plan.SetupScripts {
+			fmt.Printf("    %s (%s)\n", script.Path, script.Approval)
+		}
+	}
+
+	if len(plan.Work) > 0 {
+		fmt.Println("\n  Work in the worktree:")
+		for _, work := range plan.Work {
+			output.PrintWarning("    %s", work)
+		}
+	}
+
+	fmt.Println()
+	if len(plan.Conflicts) == 0 {
+		output.PrintSuccess("No conflicts")
+		return
+	}
+	for _, conflict := range plan.Conflicts {
+		output.PrintError("%s", conflict)
+	}
+}
@@ -1,16 +1,33 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/go-go-golems/glazed/pkg/cmds/logging"
 	"github.com/go-go-golems/workspace-manager/cmd/cmds"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/wsm"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/carapace-sh/carapace"
 	clay "github.com/go-go-golems/clay/pkg"
 )
 
+var (
+	fileLogLevel   string
+	logScopes      []string
+	gitTimeout     time.Duration
+	nonInteractive bool
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "wsm",
 	Short: "A tool for managing multi-repository workspaces",
@@ -39,12 +56,116 @@ Examples:
   # Interactive mode
   `,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		return logging.InitLoggerFromViper()
+		if err := logging.InitLoggerFromViper(); err != nil {
+			return err
+		}
+		if err := configureLogging(); err != nil {
+			return err
+		}
+		if err := configureGitTimeouts(cmd); err != nil {
+			return err
+		}
+		wsm.SetNonInteractive(nonInteractive)
+
+		// Warn early instead of failing deep inside an operation
+		if needsGitCheck(cmd) {
+			if status := wsm.CheckTool(cmd.Context(), "git"); !status.OK() {
+				output.PrintWarningStderr("%s", status.Message)
+				output.PrintInfoStderr("Run 'wsm doctor' for details")
+			}
+		}
+		return nil
 	},
 }
 
+// noGitCheckCommands are the top-level commands that skip the git check: shell
+// completion, the prompt integrations, which run on every prompt, the servers,
+// and doctor, which checks git itself
+var noGitCheckCommands = map[string]bool{
+	"__complete":       true,
+	"__completeNoDesc": true,
+	"_carapace":        true,
+	"completion":       true,
+	"help":             true,
+	"prompt-status":    true,
+	"starship":         true,
+	"shell-init":       true,
+	"serve":            true,
+	"doctor":           true,
+}
+
+// needsGitCheck reports whether a command checks that git is usable before running
+func needsGitCheck(cmd *cobra.Command) bool {
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		if !cmd.Parent().HasParent() {
+			return !noGitCheckCommands[cmd.Name()]
+		}
+	}
+	return false
+}
+
+// configureLogging sends log events to stderr at --log-level and to a rotating
+// file (--log-file, by default under the config dir) at --file-log-level
+func configureLogging() error {
+	consoleLevel, err := zerolog.ParseLevel(viper.GetString("log-level"))
+	if err != nil || viper.GetString("log-level") == "" {
+		consoleLevel = zerolog.InfoLevel
+	}
+
+	fileLevel, err := zerolog.ParseLevel(fileLogLevel)
+	if err != nil {
+		return errors.Wrapf(err, "invalid --file-log-level '%s'", fileLogLevel)
+	}
+
+	logFile := viper.GetString("log-file")
+	if logFile == "" {
+		if logFile, err = output.DefaultLogFile(); err != nil {
+			// Logging to the console only is better than not running at all
+			logFile = ""
+		}
+	}
+
+	scopes, err := output.ParseLogScopes(logScopes)
+	if err != nil {
+		return err
+	}
+
+	return output.ConfigureLogging(output.LogOptions{
+		ConsoleLevel: consoleLevel,
+		JSONConsole:  viper.GetString("log-format") == "json",
+		WithCaller:   viper.GetBool("with-caller"),
+		File:         logFile,
+		FileLevel:    fileLevel,
+		Scopes:       scopes,
+	})
+}
+
 func Execute() error {
-	return rootCmd.Execute()
+	// Ctrl-C cancels the command context so workspace-wide operations stop
+	// between repositories and report what they completed. A second Ctrl-C
+	// kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// configureGitTimeouts applies timeouts.yaml, with --git-timeout overriding the
+// timeout of network operations
+func configureGitTimeouts(cmd *cobra.Command) error {
+	timeouts, err := wsm.LoadGitTimeouts()
+	if err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("git-timeout") {
+		timeouts.Network = gitTimeout
+	}
+	wsm.SetGitTimeouts(timeouts)
+	return nil
 }
 
 func init() {
@@ -54,32 +175,80 @@ func init() {
 		log.Fatal().Err(err).Msg("Failed to initialize Viper")
 	}
 
+	rootCmd.PersistentFlags().StringVar(&fileLogLevel, "file-log-level", "debug", "Minimum level written to the log file (disabled to turn it off)")
+	rootCmd.PersistentFlags().StringSliceVar(&logScopes, "log-scope", nil, "Only log these subsystems, optionally with a level (e.g. worktree=debug,git)")
+	rootCmd.PersistentFlags().DurationVar(&gitTimeout, "git-timeout", wsm.DefaultGitTimeouts.Network, "Timeout of git network operations such as fetch and push (0 to disable)")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt: decisions come from flags such as --force or --on-existing-branch, and commands needing an answer fail")
+	carapace.Gen(rootCmd).FlagCompletion(carapace.ActionMap{
+		"file-log-level": carapace.ActionValues("trace", "debug", "info", "warn", "error", "disabled"),
+		"log-scope":      carapace.ActionValues("worktree", "git", "tmux"),
+	})
+
 	// Add all subcommands
 	rootCmd.AddCommand(
 		cmds.NewDiscoverCommand(),
+		cmds.NewDoctorCommand(),
+		cmds.NewDaemonCommand(),
+		cmds.NewPolicyCommand(),
 		cmds.NewValidateCommand(),
+		cmds.NewVerifyCommand(),
 		cmds.NewPruneCommand(),
+		cmds.NewDuCommand(),
+		cmds.NewGCCommand(),
+		cmds.NewSuggestCleanupCommand(),
 		cmds.NewListCommand(),
+		cmds.NewReposCommand(),
+		cmds.NewStateCommand(),
 		cmds.NewCreateCommand(),
 		cmds.NewForkCommand(),
+		cmds.NewInitCommand(),
 		cmds.NewMergeCommand(),
 		cmds.NewAddCommand(),
 		cmds.NewRemoveCommand(),
+		cmds.NewAuxCommand(),
 		cmds.NewDeleteCommand(),
+		cmds.NewUndeleteCommand(),
+		cmds.NewHistoryCommand(),
 		cmds.NewInfoCommand(),
 		cmds.NewPathCommand(),
+		cmds.NewCdCommand(),
+		cmds.NewWhichCommand(),
+		cmds.NewOpenCommand(),
+		cmds.NewOpenTicketCommand(),
+		cmds.NewDevcontainerCommand(),
+		cmds.NewNixCommand(),
+		cmds.NewSetupCommand(),
+		cmds.NewAgentCommand(),
+		cmds.NewNotifyCommand(),
 		cmds.NewStatusCommand(),
 		cmds.NewPRCommand(),
 		cmds.NewPushCommand(),
+		cmds.NewCICommand(),
 
 		cmds.NewCommitCommand(),
 		cmds.NewSyncCommand(),
+		cmds.NewBulkCommand(),
 		cmds.NewBranchCommand(),
 		cmds.NewRebaseCommand(),
+		cmds.NewCherryPickCommand(),
+		cmds.NewReleaseCommand(),
 		cmds.NewDiffCommand(),
+		cmds.NewCompareCommand(),
+		cmds.NewApplyCommand(),
 		cmds.NewLogCommand(),
+		cmds.NewReportCommand(),
+		cmds.NewGraphCommand(),
+		cmds.NewGoReplaceCommand(),
+		cmds.NewForkModeCommand(),
+		cmds.NewRemoteCommand(),
+		cmds.NewHooksCommand(),
+		cmds.NewExecCommand(),
+		cmds.NewCheckCommand(),
 		cmds.NewTmuxCommand(),
 		cmds.NewStarshipCommand(),
+		cmds.NewShellInitCommand(),
+		cmds.NewPromptStatusCommand(),
+		cmds.NewServeCommand(),
 	)
 
 	carapace.Gen(rootCmd)
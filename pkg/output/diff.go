@@ -0,0 +1,67 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DiffSection is the diff of one repository
+type DiffSection struct {
+	Repository string
+	Diff       string
+	// Summary is shown instead of the diff when the section is folded
+	Summary string
+}
+
+// DiffRenderOptions controls how RenderDiff prints sections
+type DiffRenderOptions struct {
+	Color bool
+	// FoldLines folds sections longer than this many lines to their summary;
+	// 0 never folds
+	FoldLines int
+	// FoldHint is printed below folded sections, with %s replaced by the repository
+	FoldHint string
+}
+
+// RenderDiff prints the diff sections of a workspace, each under a header
+// naming its repository
+func RenderDiff(w io.Writer, sections []DiffSection, options DiffRenderOptions) error {
+	for i, section := range sections {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		diff := strings.TrimRight(section.Diff, "\n")
+		lines := strings.Count(diff, "\n") + 1
+		folded := options.FoldLines > 0 && lines > options.FoldLines
+
+		header := fmt.Sprintf("=== Repository: %s ===", section.Repository)
+		if folded {
+			header = fmt.Sprintf("=== Repository: %s (folded, %d lines) ===", section.Repository, lines)
+		}
+		if options.Color {
+			header = "\x1b[1;35m" + header + "\x1b[0m"
+		}
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return err
+		}
+
+		if folded {
+			hint := ""
+			if options.FoldHint != "" {
+				hint = "\n  " + fmt.Sprintf(options.FoldHint, section.Repository)
+			}
+			if _, err := fmt.Fprintf(w, "  %s%s\n", section.Summary, hint); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(w, diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
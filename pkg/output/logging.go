@@ -0,0 +1,162 @@
+package output
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// LogOptions configures where log events go
+type LogOptions struct {
+	// ConsoleLevel is the minimum level written to stderr
+	ConsoleLevel zerolog.Level
+	// JSONConsole writes JSON lines to stderr instead of human-readable ones
+	JSONConsole bool
+	WithCaller  bool
+	// File receives events at FileLevel and above, rotated by size; empty disables it
+	File      string
+	FileLevel zerolog.Level
+	// Scopes restricts the subsystems that log (see Logger), empty for all
+	Scopes map[string]zerolog.Level
+}
+
+var (
+	loggingMu sync.RWMutex
+	logScopes map[string]zerolog.Level
+	// fileLogger records the structured side of LogInfo/LogWarn/LogError; the
+	// console already shows their user message
+	fileLogger = zerolog.Nop()
+)
+
+// DefaultLogFile returns the path of the rotating log file in the config dir
+func DefaultLogFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "logs", "wsm.log"), nil
+}
+
+// ParseLogScopes parses subsystem scopes such as "worktree=debug" or "git". A
+// subsystem without a level logs at the levels of the outputs.
+func ParseLogScopes(specs []string) (map[string]zerolog.Level, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	scopes := map[string]zerolog.Level{}
+	for _, spec := range specs {
+		name, levelName, hasLevel := strings.Cut(strings.TrimSpace(spec), "=")
+		if name == "" {
+			return nil, errors.Errorf("invalid log scope '%s', expected subsystem[=level]", spec)
+		}
+		level := zerolog.TraceLevel
+		if hasLevel {
+			parsed, err := zerolog.ParseLevel(levelName)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid level in log scope '%s'", spec)
+			}
+			level = parsed
+		}
+		scopes[name] = level
+	}
+	return scopes, nil
+}
+
+// ConfigureLogging replaces the global logger with one writing to stderr and,
+// when configured, to a rotating log file
+func ConfigureLogging(options LogOptions) error {
+	var console io.Writer = os.Stderr
+	if !options.JSONConsole {
+		console = zerolog.ConsoleWriter{Out: os.Stderr}
+	}
+	writers := []io.Writer{levelFilterWriter{w: console, level: options.ConsoleLevel}}
+	minLevel := options.ConsoleLevel
+
+	file := zerolog.Nop()
+	fileEnabled := options.File != "" && options.FileLevel != zerolog.Disabled
+	if fileEnabled {
+		if err := os.MkdirAll(filepath.Dir(options.File), 0755); err != nil {
+			return errors.Wrap(err, "failed to create log directory")
+		}
+		rotating := &lumberjack.Logger{
+			Filename:   options.File,
+			MaxSize:    10, // megabytes
+			MaxBackups: 5,
+			MaxAge:     30, // days
+		}
+		fileWriter := levelFilterWriter{w: rotating, level: options.FileLevel}
+		writers = append(writers, fileWriter)
+		file = zerolog.New(fileWriter).With().Timestamp().Int("pid", os.Getpid()).Logger()
+		if options.FileLevel < minLevel {
+			minLevel = options.FileLevel
+		}
+	}
+
+	logContext := zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp()
+	if fileEnabled {
+		// Tells concurrent wsm processes apart in the shared log file
+		logContext = logContext.Int("pid", os.Getpid())
+	}
+	if options.WithCaller {
+		logContext = logContext.Caller()
+	}
+
+	zerolog.SetGlobalLevel(minLevel)
+	log.Logger = logContext.Logger()
+
+	loggingMu.Lock()
+	defer loggingMu.Unlock()
+	logScopes = options.Scopes
+	fileLogger = file
+	return nil
+}
+
+// Logger returns the logger of a subsystem, e.g. "worktree" or "git". When
+// scopes are configured, subsystems outside them are silenced and the others
+// log at their scope level.
+func Logger(subsystem string) zerolog.Logger {
+	loggingMu.RLock()
+	scopes := logScopes
+	loggingMu.RUnlock()
+
+	logger := log.Logger.With().Str("subsystem", subsystem).Logger()
+	if len(scopes) == 0 {
+		return logger
+	}
+	if level, ok := scopes[subsystem]; ok {
+		return logger.Level(level)
+	}
+	return logger.Level(zerolog.Disabled)
+}
+
+func eventLogger() *zerolog.Logger {
+	loggingMu.RLock()
+	defer loggingMu.RUnlock()
+	logger := fileLogger
+	return &logger
+}
+
+// levelFilterWriter drops events below its level, so each output of a
+// multi-writer logger gets its own verbosity
+type levelFilterWriter struct {
+	w     io.Writer
+	level zerolog.Level
+}
+
+func (f levelFilterWriter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f levelFilterWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < f.level {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
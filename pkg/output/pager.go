@@ -0,0 +1,51 @@
+package output
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// StdoutIsTerminal reports whether standard output is a terminal
+func StdoutIsTerminal() bool {
+	return isTerminal(os.Stdout)
+}
+
+// StartPager returns a writer that pages through $PAGER (less by default)
+// when enabled and standard output is a terminal, and standard output
+// otherwise. less quits on its own when the output fits on one screen. The
+// returned function waits for the pager to exit.
+func StartPager(enabled bool) (io.Writer, func() error) {
+	noPager := func() error { return nil }
+	if !enabled || !isTerminal(os.Stdout) {
+		return os.Stdout, noPager
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	if pager == "cat" {
+		return os.Stdout, noPager
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if os.Getenv("LESS") == "" {
+		// Quit when one screen is enough, keep colors, leave the output on screen
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return os.Stdout, noPager
+	}
+	if err := cmd.Start(); err != nil {
+		return os.Stdout, noPager
+	}
+
+	return stdin, func() error {
+		_ = stdin.Close()
+		return cmd.Wait()
+	}
+}
@@ -0,0 +1,201 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress reports the advance of a long operation, such as creating the
+// worktrees of a workspace or fetching many repositories
+type Progress interface {
+	// Step reports that work on the next item started
+	Step(item string)
+	// Done ends the operation and removes any transient rendering
+	Done()
+}
+
+// ProgressFactory creates the progress reporter of an operation. total is the
+// number of steps, 0 when unknown (e.g. while scanning directories).
+type ProgressFactory func(label string, total int) Progress
+
+var (
+	progressMu      sync.Mutex
+	progressFactory ProgressFactory = defaultProgress
+	// activeLine is the progress currently drawn in place on the terminal
+	activeLine *terminalProgress
+)
+
+// SetProgressFactory replaces how progress is reported, e.g. with NoProgress
+// for background processes. nil restores the default.
+func SetProgressFactory(factory ProgressFactory) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if factory == nil {
+		factory = defaultProgress
+	}
+	progressFactory = factory
+}
+
+// StartProgress starts reporting the progress of an operation
+func StartProgress(label string, total int) Progress {
+	progressMu.Lock()
+	factory := progressFactory
+	progressMu.Unlock()
+	return factory(label, total)
+}
+
+// NoProgress reports nothing
+func NoProgress(string, int) Progress {
+	return noProgress{}
+}
+
+type noProgress struct{}
+
+func (noProgress) Step(string) {}
+func (noProgress) Done()       {}
+
+// defaultProgress draws a spinner and bar in place on a terminal and prints
+// plain lines otherwise, so logs of non-interactive runs stay readable
+func defaultProgress(label string, total int) Progress {
+	if isTerminal(os.Stderr) {
+		return newTerminalProgress(os.Stderr, label, total)
+	}
+	return &lineProgress{w: os.Stderr, label: label, total: total}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// lineProgress prints one line per step
+type lineProgress struct {
+	w     io.Writer
+	label string
+	total int
+	count int
+}
+
+func (p *lineProgress) Step(item string) {
+	p.count++
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "%s [%d/%d] %s\n", p.label, p.count, p.total, item)
+	} else {
+		fmt.Fprintf(p.w, "%s [%d] %s\n", p.label, p.count, item)
+	}
+}
+
+func (p *lineProgress) Done() {}
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const progressBarWidth = 20
+
+// terminalProgress redraws a single status line. The cursor is moved back to
+// the start of the line after each draw, so regular output overwrites it and
+// the next tick draws it again below.
+type terminalProgress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	label string
+	total int
+	count int
+	item  string
+	frame int
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newTerminalProgress(w io.Writer, label string, total int) *terminalProgress {
+	p := &terminalProgress{w: w, label: label, total: total, done: make(chan struct{})}
+
+	progressMu.Lock()
+	activeLine = p
+	progressMu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame++
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+
+	return p
+}
+
+func (p *terminalProgress) Step(item string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count++
+	p.item = item
+	p.draw()
+}
+
+func (p *terminalProgress) Done() {
+	close(p.done)
+	p.wg.Wait()
+
+	progressMu.Lock()
+	if activeLine == p {
+		activeLine = nil
+	}
+	progressMu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+// draw must be called with p.mu held
+func (p *terminalProgress) draw() {
+	var line strings.Builder
+	line.WriteString(InfoStyle.Render(spinnerFrames[p.frame%len(spinnerFrames)]))
+	line.WriteString(" " + p.label)
+	if p.total > 0 {
+		filled := progressBarWidth * p.count / p.total
+		if filled > progressBarWidth {
+			filled = progressBarWidth
+		}
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+		fmt.Fprintf(&line, " %s %d/%d", bar, p.count, p.total)
+	} else if p.count > 0 {
+		fmt.Fprintf(&line, " (%d)", p.count)
+	}
+	if p.item != "" {
+		line.WriteString(" " + DimStyle.Render(p.item))
+	}
+	fmt.Fprintf(p.w, "\r\033[K%s\r", line.String())
+}
+
+// clear must be called with p.mu held
+func (p *terminalProgress) clear() {
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+// clearProgressLine erases the progress line before regular output is printed
+func clearProgressLine() {
+	progressMu.Lock()
+	p := activeLine
+	progressMu.Unlock()
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.clear()
+	p.mu.Unlock()
+}
@@ -38,47 +38,71 @@ var (
 
 // PrintError prints an error message with styling
 func PrintError(format string, args ...interface{}) {
+	clearProgressLine()
 	msg := ErrorStyle.Render("✗ " + fmt.Sprintf(format, args...))
 	fmt.Fprintln(os.Stderr, msg)
 }
 
 // PrintSuccess prints a success message with styling
 func PrintSuccess(format string, args ...interface{}) {
+	clearProgressLine()
 	msg := SuccessStyle.Render("✓ " + fmt.Sprintf(format, args...))
 	fmt.Println(msg)
 }
 
 // PrintInfo prints an info message with styling - replaces log.Info for user-facing output
 func PrintInfo(format string, args ...interface{}) {
+	clearProgressLine()
 	msg := InfoStyle.Render("ℹ " + fmt.Sprintf(format, args...))
 	fmt.Println(msg)
 }
 
 // PrintWarning prints a warning message with styling
 func PrintWarning(format string, args ...interface{}) {
+	clearProgressLine()
 	msg := WarningStyle.Render("⚠ " + fmt.Sprintf(format, args...))
 	fmt.Println(msg)
 }
 
+// PrintInfoStderr prints an info message to stderr, for notes that must stay
+// out of machine-readable output such as JSON or prompt segments
+func PrintInfoStderr(format string, args ...interface{}) {
+	clearProgressLine()
+	msg := InfoStyle.Render("ℹ " + fmt.Sprintf(format, args...))
+	fmt.Fprintln(os.Stderr, msg)
+}
+
+// PrintWarningStderr prints a warning message to stderr, like PrintInfoStderr
+func PrintWarningStderr(format string, args ...interface{}) {
+	clearProgressLine()
+	msg := WarningStyle.Render("⚠ " + fmt.Sprintf(format, args...))
+	fmt.Fprintln(os.Stderr, msg)
+}
+
 // PrintHeader prints a header message with styling
 func PrintHeader(format string, args ...interface{}) {
+	clearProgressLine()
 	msg := HeaderStyle.Render(fmt.Sprintf(format, args...))
 	fmt.Println(msg)
 }
 
-// LogInfo logs at info level while also printing pretty output to user
+// LogInfo logs at info level while also printing pretty output to user.
+// fields are key/value pairs; the event only goes to the log file.
 func LogInfo(userMsg string, logMsg string, fields ...interface{}) {
 	PrintInfo("%s", userMsg)
+	eventLogger().Info().Fields(fields).Msg(logMsg)
 }
 
 // LogError logs at error level while also printing pretty output to user
 func LogError(userMsg string, logMsg string, fields ...interface{}) {
 	PrintError("%s", userMsg)
+	eventLogger().Error().Fields(fields).Msg(logMsg)
 }
 
 // LogWarn logs at warn level while also printing pretty output to user
 func LogWarn(userMsg string, logMsg string, fields ...interface{}) {
 	PrintWarning("%s", userMsg)
+	eventLogger().Warn().Fields(fields).Msg(logMsg)
 }
 
 // Spinner creates a simple text-based spinner for operations
@@ -0,0 +1,63 @@
+package ux
+
+import (
+	"github.com/charmbracelet/huh"
+	"github.com/pkg/errors"
+)
+
+// HuhPrompter prompts with huh forms
+type HuhPrompter struct{}
+
+func (HuhPrompter) Confirm(title, description string) (bool, error) {
+	var confirmed bool
+	field := huh.NewConfirm().Title(title).Value(&confirmed)
+	if description != "" {
+		field.Description(description)
+	}
+	return confirmed, runField(field)
+}
+
+func (HuhPrompter) Select(title string, options []Option) (string, error) {
+	var value string
+	err := runField(huh.NewSelect[string]().
+		Title(title).
+		Options(huhOptions(options)...).
+		Value(&value))
+	return value, err
+}
+
+func (HuhPrompter) MultiSelect(title string, options []Option) ([]string, error) {
+	var values []string
+	err := runField(huh.NewMultiSelect[string]().
+		Title(title).
+		Options(huhOptions(options)...).
+		Value(&values))
+	return values, err
+}
+
+func (HuhPrompter) Input(title, value string, validate func(string) error) (string, error) {
+	field := huh.NewInput().Title(title).Value(&value)
+	if validate != nil {
+		field.Validate(validate)
+	}
+	return value, runField(field)
+}
+
+func huhOptions(options []Option) []huh.Option[string] {
+	result := make([]huh.Option[string], 0, len(options))
+	for _, option := range options {
+		result = append(result, huh.NewOption(option.Label, option.Value).Selected(option.Selected))
+	}
+	return result
+}
+
+// runField runs a single-field form, mapping aborts to ErrAborted
+func runField(field huh.Field) error {
+	if err := huh.NewForm(huh.NewGroup(field)).Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return ErrAborted
+		}
+		return errors.Wrap(err, "prompt failed")
+	}
+	return nil
+}
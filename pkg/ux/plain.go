@@ -0,0 +1,149 @@
+package ux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PlainPrompter prompts with numbered lines, for piped input and dumb terminals
+type PlainPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPlainPrompter creates a prompter reading answers from in
+func NewPlainPrompter(in io.Reader, out io.Writer) *PlainPrompter {
+	return &PlainPrompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *PlainPrompter) Confirm(title, description string) (bool, error) {
+	if description != "" {
+		fmt.Fprintln(p.out, description)
+	}
+	fmt.Fprintf(p.out, "%s (y/N): ", title)
+	answer, err := p.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *PlainPrompter) Select(title string, options []Option) (string, error) {
+	if len(options) == 0 {
+		return "", errors.New("nothing to select")
+	}
+	p.printOptions(title, options, nil)
+	for {
+		fmt.Fprintf(p.out, "Choice [1-%d]: ", len(options))
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			return options[i-1].Value, nil
+		}
+		fmt.Fprintf(p.out, "Enter a number between 1 and %d\n", len(options))
+	}
+}
+
+func (p *PlainPrompter) MultiSelect(title string, options []Option) ([]string, error) {
+	selected := make([]bool, len(options))
+	for i, option := range options {
+		selected[i] = option.Selected
+	}
+	p.printOptions(title, options, selected)
+
+	for {
+		fmt.Fprintf(p.out, "Choices (comma-separated numbers, empty keeps the marked ones): ")
+		answer, err := p.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if answer != "" {
+			if selected, err = parseChoices(answer, len(options)); err != nil {
+				fmt.Fprintln(p.out, err)
+				continue
+			}
+		}
+
+		var values []string
+		for i, option := range options {
+			if selected[i] {
+				values = append(values, option.Value)
+			}
+		}
+		return values, nil
+	}
+}
+
+func (p *PlainPrompter) Input(title, value string, validate func(string) error) (string, error) {
+	for {
+		if value != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", title, value)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", title)
+		}
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = value
+		}
+		if validate != nil {
+			if err := validate(answer); err != nil {
+				fmt.Fprintln(p.out, err)
+				continue
+			}
+		}
+		return answer, nil
+	}
+}
+
+func (p *PlainPrompter) printOptions(title string, options []Option, selected []bool) {
+	fmt.Fprintln(p.out, title)
+	for i, option := range options {
+		mark := ""
+		if selected != nil {
+			mark = "[ ] "
+			if selected[i] {
+				mark = "[x] "
+			}
+		}
+		fmt.Fprintf(p.out, "  %d. %s%s\n", i+1, mark, option.Label)
+	}
+}
+
+// readLine reads an answer, end of input aborting the prompt
+func (p *PlainPrompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", ErrAborted
+		}
+		return "", errors.Wrap(err, "failed to read answer")
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// parseChoices parses "1,3" into the selection of n options
+func parseChoices(answer string, n int) ([]bool, error) {
+	selected := make([]bool, n)
+	for _, field := range strings.Split(answer, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || i < 1 || i > n {
+			return nil, errors.Errorf("'%s' is not a number between 1 and %d", strings.TrimSpace(field), n)
+		}
+		selected[i-1] = true
+	}
+	return selected, nil
+}
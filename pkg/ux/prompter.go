@@ -0,0 +1,90 @@
+package ux
+
+import (
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAborted is returned when the user aborts a prompt (Ctrl-C, Esc or end of input)
+var ErrAborted = errors.New("aborted by user")
+
+// Option is a choice of Select and MultiSelect
+type Option struct {
+	Label string
+	Value string
+	// Selected preselects the option of a MultiSelect
+	Selected bool
+}
+
+// NewOption creates an option
+func NewOption(label, value string) Option {
+	return Option{Label: label, Value: value}
+}
+
+// Prompter asks the user to make decisions. Interactive code paths go through
+// the current prompter so they behave the same on a terminal and with piped input.
+type Prompter interface {
+	// Confirm asks a yes/no question, description may be empty
+	Confirm(title, description string) (bool, error)
+	// Select returns the value of the chosen option
+	Select(title string, options []Option) (string, error)
+	// MultiSelect returns the values of the chosen options
+	MultiSelect(title string, options []Option) ([]string, error)
+	// Input asks for a line of text until validate, which may be nil, accepts it
+	Input(title, value string, validate func(string) error) (string, error)
+}
+
+var (
+	prompterMu sync.Mutex
+	prompter   Prompter
+)
+
+// SetPrompter replaces the prompter, e.g. in tests. nil restores the default.
+func SetPrompter(p Prompter) {
+	prompterMu.Lock()
+	defer prompterMu.Unlock()
+	prompter = p
+}
+
+// CurrentPrompter returns the prompter in use: huh forms on a terminal, plain
+// line-based prompts when stdin is piped
+func CurrentPrompter() Prompter {
+	prompterMu.Lock()
+	defer prompterMu.Unlock()
+	if prompter == nil {
+		// Kept so answers buffered from piped input are not lost between prompts
+		if isTerminal(os.Stdin) {
+			prompter = HuhPrompter{}
+		} else {
+			prompter = NewPlainPrompter(os.Stdin, os.Stdout)
+		}
+	}
+	return prompter
+}
+
+// Confirm asks a yes/no question with the current prompter
+func Confirm(title, description string) (bool, error) {
+	return CurrentPrompter().Confirm(title, description)
+}
+
+// Select asks for one of the options with the current prompter
+func Select(title string, options []Option) (string, error) {
+	return CurrentPrompter().Select(title, options)
+}
+
+// MultiSelect asks for any of the options with the current prompter
+func MultiSelect(title string, options []Option) ([]string, error) {
+	return CurrentPrompter().MultiSelect(title, options)
+}
+
+// Input asks for a line of text with the current prompter
+func Input(title, value string, validate func(string) error) (string, error) {
+	return CurrentPrompter().Input(title, value, validate)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
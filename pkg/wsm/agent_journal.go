@@ -0,0 +1,232 @@
+package wsm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// agentJournalFile holds the progress notes of agents, in .wsm/
+	agentJournalFile = "agent-journal.jsonl"
+	// AgentHandoffFile is rendered from the journal at the workspace root
+	AgentHandoffFile = "HANDOFF.md"
+)
+
+// Statuses of an agent task
+const (
+	AgentTaskInProgress = "in-progress"
+	AgentTaskBlocked    = "blocked"
+	AgentTaskDone       = "done"
+)
+
+// AgentTaskStatuses lists the values of --status
+var AgentTaskStatuses = []string{AgentTaskInProgress, AgentTaskBlocked, AgentTaskDone}
+
+// AgentNote is one progress entry of the agent journal of a workspace
+type AgentNote struct {
+	Time time.Time `json:"time"`
+	// Actor is WSM_ACTOR, naming the agent or person writing the note
+	Actor    string   `json:"actor,omitempty"`
+	Task     string   `json:"task"`
+	Status   string   `json:"status"`
+	Message  string   `json:"message,omitempty"`
+	Blockers []string `json:"blockers,omitempty"`
+	Next     []string `json:"next,omitempty"`
+	// Commits are the HEAD commits of the repositories when the note was written
+	Commits map[string]string `json:"commits,omitempty"`
+}
+
+// AppendAgentNote validates a note, fills in time, actor and commits, and
+// appends it to .wsm/agent-journal.jsonl
+func AppendAgentNote(ctx context.Context, workspace *Workspace, note AgentNote) (*AgentNote, error) {
+	note.Task = strings.TrimSpace(note.Task)
+	if note.Task == "" {
+		return nil, errors.New("a note needs a task")
+	}
+	if note.Status == "" {
+		note.Status = AgentTaskInProgress
+	}
+	if !containsValue(AgentTaskStatuses, note.Status) {
+		return nil, errors.Errorf("unknown task status '%s' (supported: %s)", note.Status, strings.Join(AgentTaskStatuses, ", "))
+	}
+	if note.Time.IsZero() {
+		note.Time = time.Now()
+	}
+	if note.Actor == "" {
+		note.Actor = os.Getenv(AuditActorEnv)
+	}
+	if note.Commits == nil {
+		note.Commits = make(map[string]string)
+		for _, repo := range workspace.Repositories {
+			if commit, err := gitOutput(ctx, filepath.Join(workspace.Path, repo.Name), "rev-parse", "--short", "HEAD"); err == nil {
+				note.Commits[repo.Name] = commit
+			}
+		}
+	}
+
+	data, err := json.Marshal(note)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal agent note")
+	}
+	wsmDir := filepath.Join(workspace.Path, ".wsm")
+	if err := os.MkdirAll(wsmDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", wsmDir)
+	}
+	f, err := os.OpenFile(filepath.Join(wsmDir, agentJournalFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open agent journal")
+	}
+	defer func() { _ = f.Close() }()
+
+	// A single write keeps concurrent agents from interleaving lines
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return nil, errors.Wrap(err, "failed to write agent journal")
+	}
+	return &note, nil
+}
+
+// ReadAgentJournal returns the notes of a workspace, oldest first. Lines that
+// cannot be parsed are skipped.
+func ReadAgentJournal(workspace *Workspace) ([]AgentNote, error) {
+	f, err := os.Open(filepath.Join(workspace.Path, ".wsm", agentJournalFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open agent journal")
+	}
+	defer func() { _ = f.Close() }()
+
+	var notes []AgentNote
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var note AgentNote
+		if err := json.Unmarshal(scanner.Bytes(), &note); err != nil {
+			continue
+		}
+		notes = append(notes, note)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read agent journal")
+	}
+	return notes, nil
+}
+
+// latestAgentTasks returns the latest note of every task, most recent first
+func latestAgentTasks(notes []AgentNote) []AgentNote {
+	latest := make(map[string]AgentNote)
+	for _, note := range notes {
+		latest[note.Task] = note
+	}
+	tasks := make([]AgentNote, 0, len(latest))
+	for _, note := range latest {
+		tasks = append(tasks, note)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Time.After(tasks[j].Time)
+	})
+	return tasks
+}
+
+// RenderHandoff renders the journal of a workspace as Markdown: the state of
+// every task, the open blockers and next steps, then every note, newest first
+func RenderHandoff(workspace *Workspace, notes []AgentNote) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Handoff: %s\n\n", workspace.Name)
+	fmt.Fprintf(&b, "- Branch: `%s`\n", workspace.Branch)
+	fmt.Fprintf(&b, "- Repositories: %s\n", strings.Join(repositoryNames(workspace.Repositories), ", "))
+	if len(notes) == 0 {
+		b.WriteString("\nNo notes yet. Add one with `wsm agent note`.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "- Last note: %s\n", notes[len(notes)-1].Time.Format(time.RFC3339))
+
+	tasks := latestAgentTasks(notes)
+	b.WriteString("\n## Tasks\n\n| Task | Status | Updated | By |\n|------|--------|---------|----|\n")
+	for _, task := range tasks {
+		actor := task.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(task.Task), task.Status, task.Time.Format("2006-01-02 15:04"), markdownCell(actor))
+	}
+
+	var blockers, next []string
+	for _, task := range tasks {
+		if task.Status == AgentTaskDone {
+			continue
+		}
+		for _, blocker := range task.Blockers {
+			blockers = append(blockers, fmt.Sprintf("- **%s**: %s", task.Task, blocker))
+		}
+		for _, step := range task.Next {
+			next = append(next, fmt.Sprintf("- [ ] **%s**: %s", task.Task, step))
+		}
+	}
+	if len(blockers) > 0 {
+		b.WriteString("\n## Blockers\n\n" + strings.Join(blockers, "\n") + "\n")
+	}
+	if len(next) > 0 {
+		b.WriteString("\n## Next Steps\n\n" + strings.Join(next, "\n") + "\n")
+	}
+
+	b.WriteString("\n## Journal\n")
+	for i := len(notes) - 1; i >= 0; i-- {
+		note := notes[i]
+		fmt.Fprintf(&b, "\n### %s: %s (%s)\n\n", note.Time.Format("2006-01-02 15:04"), note.Task, note.Status)
+		if note.Actor != "" {
+			fmt.Fprintf(&b, "By %s.\n\n", note.Actor)
+		}
+		if note.Message != "" {
+			b.WriteString(note.Message + "\n\n")
+		}
+		for _, blocker := range note.Blockers {
+			fmt.Fprintf(&b, "- Blocker: %s\n", blocker)
+		}
+		for _, step := range note.Next {
+			fmt.Fprintf(&b, "- Next: %s\n", step)
+		}
+		if len(note.Commits) > 0 {
+			repos := make([]string, 0, len(note.Commits))
+			for repo := range note.Commits {
+				repos = append(repos, repo)
+			}
+			sort.Strings(repos)
+			commits := make([]string, 0, len(repos))
+			for _, repo := range repos {
+				commits = append(commits, fmt.Sprintf("%s@`%s`", repo, note.Commits[repo]))
+			}
+			fmt.Fprintf(&b, "- Commits: %s\n", strings.Join(commits, ", "))
+		}
+	}
+	return b.String()
+}
+
+// WriteHandoff renders the journal of a workspace to HANDOFF.md at its root
+// and returns the file path
+func WriteHandoff(workspace *Workspace) (string, error) {
+	notes, err := ReadAgentJournal(workspace)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(workspace.Path, AgentHandoffFile)
+	if err := os.WriteFile(path, []byte(RenderHandoff(workspace, notes)), 0644); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", path)
+	}
+	return path, nil
+}
+
+// markdownCell keeps a value from breaking a Markdown table row
+func markdownCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,36 @@
+package wsm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SetArchived marks a repository as archived (read-only) or active again in
+// the registry and in every workspace using it. It returns the names of the
+// updated workspaces.
+func (wm *WorkspaceManager) SetArchived(repoName string, archived bool) ([]string, error) {
+	repo, err := wm.Discoverer.findRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.Archived = archived
+	if err := wm.Discoverer.SaveRegistry(); err != nil {
+		return nil, errors.Wrap(err, "failed to save registry")
+	}
+
+	return wm.updateWorkspaceRepositories(*repo, func(r *Repository) {
+		r.Archived = archived
+	})
+}
+
+// ArchivedRepositories returns the archived repositories of a workspace,
+// which commit and push skip unless told otherwise
+func (w *Workspace) ArchivedRepositories() []string {
+	var names []string
+	for _, repo := range w.Repositories {
+		if repo.Archived {
+			names = append(names, repo.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,144 @@
+package wsm
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuditActorEnv names the environment variable identifying who drives wsm,
+// e.g. the name of an AI agent, recorded with every audit event
+const AuditActorEnv = "WSM_ACTOR"
+
+// AuditEvent is one mutating operation recorded in the audit log
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Workspace string    `json:"workspace,omitempty"`
+	// Args and Flags are the positional arguments and explicitly set flags of the command
+	Args     []string          `json:"args,omitempty"`
+	Flags    map[string]string `json:"flags,omitempty"`
+	Success  bool              `json:"success"`
+	Error    string            `json:"error,omitempty"`
+	Duration time.Duration     `json:"duration_ns"`
+	User     string            `json:"user,omitempty"`
+	Actor    string            `json:"actor,omitempty"`
+	Dir      string            `json:"dir,omitempty"`
+}
+
+// AuditFilter selects events returned by ReadAuditEvents
+type AuditFilter struct {
+	Workspace  string
+	Operations []string
+	Since      time.Time
+	FailedOnly bool
+	// Limit keeps only the newest events, 0 for all
+	Limit int
+}
+
+// GetAuditLogPath returns the path of the append-only audit log
+func GetAuditLogPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "audit.jsonl"), nil
+}
+
+// RecordAuditEvent appends an event to the audit log as one JSON line. User,
+// actor and working directory are filled in when empty.
+func RecordAuditEvent(event AuditEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.User == "" {
+		if current, err := user.Current(); err == nil {
+			event.User = current.Username
+		}
+	}
+	if event.Actor == "" {
+		event.Actor = os.Getenv(AuditActorEnv)
+	}
+	if event.Dir == "" {
+		event.Dir, _ = os.Getwd()
+	}
+
+	logPath, err := GetAuditLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit event")
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open audit log")
+	}
+	defer func() { _ = f.Close() }()
+
+	// A single write keeps concurrent wsm processes from interleaving lines
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write audit log")
+	}
+	return nil
+}
+
+// ReadAuditEvents returns the events of the audit log matching the filter, oldest first.
+// Lines that cannot be parsed are skipped.
+func ReadAuditEvents(filter AuditFilter) ([]AuditEvent, error) {
+	logPath, err := GetAuditLogPath()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(logPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open audit log")
+	}
+	defer func() { _ = f.Close() }()
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if filter.Workspace != "" && event.Workspace != filter.Workspace {
+			continue
+		}
+		if len(filter.Operations) > 0 && !containsValue(filter.Operations, event.Operation) {
+			continue
+		}
+		if !filter.Since.IsZero() && event.Time.Before(filter.Since) {
+			continue
+		}
+		if filter.FailedOnly && event.Success {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read audit log")
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
@@ -0,0 +1,184 @@
+package wsm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// AuxiliaryPath is a non-git directory of a workspace, e.g. for data or scratch
+// files. It is either a symlink to a directory elsewhere or a directory created
+// in the workspace.
+type AuxiliaryPath struct {
+	// Name is the entry in the workspace directory
+	Name string `json:"name"`
+	// Target is the directory the entry links to, empty for a created directory
+	Target string `json:"target,omitempty"`
+}
+
+// Linked reports whether the auxiliary path is a symlink
+func (a AuxiliaryPath) Linked() bool {
+	return a.Target != ""
+}
+
+// AuxiliaryPathFor returns the auxiliary path with the given name
+func (w *Workspace) AuxiliaryPathFor(name string) (AuxiliaryPath, bool) {
+	for _, aux := range w.AuxiliaryPaths {
+		if aux.Name == name {
+			return aux, true
+		}
+	}
+	return AuxiliaryPath{}, false
+}
+
+// validateAuxiliaryName rejects names that are not a single new entry of the workspace directory
+func validateAuxiliaryName(workspace *Workspace, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) || strings.Contains(name, "/") {
+		return errors.Errorf("invalid auxiliary path name '%s' (expected a single directory name)", name)
+	}
+	if name == ".wsm" {
+		return errors.New("'.wsm' is reserved for workspace metadata")
+	}
+	for _, repo := range workspace.Repositories {
+		if repo.Name == name {
+			return errors.Errorf("'%s' is a repository of workspace '%s'", name, workspace.Name)
+		}
+	}
+	if _, ok := workspace.AuxiliaryPathFor(name); ok {
+		return errors.Errorf("auxiliary path '%s' already exists in workspace '%s'", name, workspace.Name)
+	}
+	if _, err := os.Lstat(filepath.Join(workspace.Path, name)); err == nil {
+		return errors.Errorf("'%s' already exists in %s", name, workspace.Path)
+	}
+	return nil
+}
+
+// AddAuxiliaryPath adds a non-git directory to a workspace: a symlink to target,
+// or an empty directory when target is empty
+func (wm *WorkspaceManager) AddAuxiliaryPath(workspaceName, name, target string) (*AuxiliaryPath, error) {
+	workspace, err := wm.LoadWorkspace(workspaceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+	if err := validateAuxiliaryName(workspace, name); err != nil {
+		return nil, err
+	}
+
+	aux := AuxiliaryPath{Name: name}
+	path := filepath.Join(workspace.Path, name)
+	if target != "" {
+		if aux.Target, err = filepath.Abs(expandHome(target)); err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve %s", target)
+		}
+		if info, err := os.Stat(aux.Target); err != nil || !info.IsDir() {
+			return nil, errors.Errorf("'%s' is not a directory", aux.Target)
+		}
+		if err := os.Symlink(aux.Target, path); err != nil {
+			return nil, errors.Wrapf(err, "failed to link %s to %s", path, aux.Target)
+		}
+	} else if err := os.Mkdir(path, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", path)
+	}
+
+	workspace.AuxiliaryPaths = append(workspace.AuxiliaryPaths, aux)
+	if err := wm.saveWorkspaceWithMetadata(workspace); err != nil {
+		return nil, err
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Added auxiliary path %s to workspace %s", name, workspace.Name),
+		"Added auxiliary path",
+		"workspace", workspace.Name,
+		"name", name,
+		"target", aux.Target,
+	)
+	return &aux, nil
+}
+
+// RemoveAuxiliaryPath removes an auxiliary path from a workspace. Symlinks are
+// removed without touching their target; a created directory is only deleted
+// with its contents when removeFiles is set, otherwise only when it is empty.
+func (wm *WorkspaceManager) RemoveAuxiliaryPath(workspaceName, name string, removeFiles bool) error {
+	workspace, err := wm.LoadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+	aux, ok := workspace.AuxiliaryPathFor(name)
+	if !ok {
+		return errors.Errorf("auxiliary path '%s' not found in workspace '%s'", name, workspace.Name)
+	}
+
+	if err := removeAuxiliaryPath(workspace, aux, removeFiles); err != nil {
+		return err
+	}
+
+	var kept []AuxiliaryPath
+	for _, other := range workspace.AuxiliaryPaths {
+		if other.Name != name {
+			kept = append(kept, other)
+		}
+	}
+	workspace.AuxiliaryPaths = kept
+	return wm.saveWorkspaceWithMetadata(workspace)
+}
+
+// removeAuxiliaryPath deletes the entry of an auxiliary path from the workspace directory
+func removeAuxiliaryPath(workspace *Workspace, aux AuxiliaryPath, removeFiles bool) error {
+	path := filepath.Join(workspace.Path, aux.Name)
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to inspect %s", path)
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		// os.Remove deletes the link itself, never the directory it points to
+		err = os.Remove(path)
+	case aux.Linked():
+		return errors.Errorf("%s should be a symlink to %s but is not, remove it manually", path, aux.Target)
+	case removeFiles:
+		err = os.RemoveAll(path)
+	default:
+		if err = os.Remove(path); err != nil {
+			return errors.Errorf("%s is not empty, remove it manually or pass --remove-files", path)
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove %s", path)
+	}
+	return nil
+}
+
+// cleanupAuxiliaryPaths removes the auxiliary paths of a deleted workspace whose
+// files are kept: symlinks and empty directories go, directories with files stay
+func cleanupAuxiliaryPaths(workspace *Workspace) {
+	for _, aux := range workspace.AuxiliaryPaths {
+		if err := removeAuxiliaryPath(workspace, aux, false); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Kept auxiliary path %s: %v", aux.Name, err),
+				"Kept auxiliary path of deleted workspace",
+				"workspace", workspace.Name,
+				"name", aux.Name,
+				"error", err,
+			)
+		}
+	}
+}
+
+// saveWorkspaceWithMetadata saves the workspace configuration and refreshes its wsm.json
+func (wm *WorkspaceManager) saveWorkspaceWithMetadata(workspace *Workspace) error {
+	if err := wm.SaveWorkspace(workspace); err != nil {
+		return errors.Wrap(err, "failed to save workspace configuration")
+	}
+	if err := wm.createWorkspaceMetadata(workspace); err != nil {
+		return errors.Wrap(err, "failed to update workspace metadata")
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package wsm
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BranchComparison compares the workspace branch of a worktree with another ref
+type BranchComparison struct {
+	Ref    string `json:"ref"`
+	Exists bool   `json:"exists"`
+	// Ahead and Behind count the commits only on the workspace branch and only on Ref
+	Ahead      int       `json:"ahead"`
+	Behind     int       `json:"behind"`
+	LastCommit time.Time `json:"last_commit,omitempty"`
+	// FastForward is set when the histories have not diverged, so one side can
+	// be fast-forwarded to the other
+	FastForward bool `json:"fast_forward"`
+}
+
+// BranchMatrixRow shows where the workspace branch of a repository stands
+// against its base branch and its remote counterpart
+type BranchMatrixRow struct {
+	Repository string    `json:"repository"`
+	Branch     string    `json:"branch"`
+	Pinned     string    `json:"pinned,omitempty"`
+	LastCommit time.Time `json:"last_commit,omitempty"`
+	// Base is the branch the workspace branch was created from, preferably on the base remote
+	Base BranchComparison `json:"base"`
+	// Remote is the upstream of the workspace branch, or its counterpart on the push remote
+	Remote BranchComparison `json:"remote"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// GetBranchMatrix compares the workspace branch of every repository with its
+// base branch and its remote. Comparisons use the remote-tracking refs as last
+// fetched; nothing is fetched.
+func GetBranchMatrix(ctx context.Context, workspace *Workspace) []BranchMatrixRow {
+	rows := make([]BranchMatrixRow, 0, len(workspace.Repositories))
+	for _, repo := range workspace.Repositories {
+		rows = append(rows, branchMatrixRow(ctx, workspace, repo))
+	}
+	return rows
+}
+
+func branchMatrixRow(ctx context.Context, workspace *Workspace, repo Repository) BranchMatrixRow {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	row := BranchMatrixRow{Repository: repo.Name, Pinned: workspace.PinFor(repo.Name)}
+
+	branch, err := gitOutput(ctx, worktreePath, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		if row.Pinned == "" {
+			row.Error = "detached HEAD"
+		}
+		row.LastCommit = commitDate(ctx, worktreePath, "HEAD")
+		return row
+	}
+	row.Branch = branch
+	row.LastCommit = commitDate(ctx, worktreePath, "HEAD")
+
+	row.Base = compareBranch(ctx, worktreePath, matrixBaseRef(ctx, workspace, repo, worktreePath))
+	row.Remote = compareBranch(ctx, worktreePath, matrixRemoteRef(ctx, workspace, repo, worktreePath, branch))
+	return row
+}
+
+// matrixBaseRef returns the base branch of a repository on its base remote,
+// falling back to the local branch and then to the remote default branch
+func matrixBaseRef(ctx context.Context, workspace *Workspace, repo Repository, worktreePath string) string {
+	baseBranch := workspace.BaseBranchFor(repo.Name)
+	if baseBranch == "" {
+		return workspace.BaseRefFor(ctx, repo.Name, worktreePath)
+	}
+	remoteRef := repo.BaseRemote() + "/" + baseBranch
+	if refExists(ctx, worktreePath, "refs/remotes/"+remoteRef) {
+		return remoteRef
+	}
+	return baseBranch
+}
+
+// matrixRemoteRef returns the upstream of a branch, or the branch on the push
+// remote (the fork remote in fork mode) when no upstream is configured
+func matrixRemoteRef(ctx context.Context, workspace *Workspace, repo Repository, worktreePath, branch string) string {
+	if upstream, err := gitOutput(ctx, worktreePath, "rev-parse", "--abbrev-ref", branch+"@{upstream}"); err == nil {
+		return upstream
+	}
+	return workspace.PushRemoteFor(repo) + "/" + branch
+}
+
+// compareBranch counts the commits HEAD and ref do not share
+func compareBranch(ctx context.Context, worktreePath, ref string) BranchComparison {
+	comparison := BranchComparison{Ref: ref}
+	if !refExists(ctx, worktreePath, ref) {
+		return comparison
+	}
+	comparison.Exists = true
+	comparison.LastCommit = commitDate(ctx, worktreePath, ref)
+
+	counts, err := gitOutput(ctx, worktreePath, "rev-list", "--left-right", "--count", "HEAD..."+ref)
+	if err != nil {
+		return comparison
+	}
+	if fields := strings.Fields(counts); len(fields) == 2 {
+		comparison.Ahead, _ = strconv.Atoi(fields[0])
+		comparison.Behind, _ = strconv.Atoi(fields[1])
+	}
+	comparison.FastForward = comparison.Ahead == 0 || comparison.Behind == 0
+	return comparison
+}
+
+// refExists reports whether ref resolves to a commit
+func refExists(ctx context.Context, dir, ref string) bool {
+	_, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	return err == nil
+}
+
+// commitDate returns the committer date of ref, zero if it cannot be read
+func commitDate(ctx context.Context, dir, ref string) time.Time {
+	out, err := gitOutput(ctx, dir, "log", "-1", "--format=%cI", ref)
+	if err != nil {
+		return time.Time{}
+	}
+	date, err := time.Parse(time.RFC3339, out)
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
@@ -0,0 +1,174 @@
+package wsm
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultBranchTemplate is how branches are named without branch-naming.yaml
+	DefaultBranchTemplate = "{prefix}/{name}"
+	// defaultTicketBranchTemplate replaces the default template when a ticket
+	// given with --ticket is not part of the workspace name
+	defaultTicketBranchTemplate = "{prefix}/{ticket}-{name}"
+	// DefaultTicketPattern matches Jira style ticket IDs such as PROJ-123
+	DefaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+)
+
+// branchTemplateVariable matches the placeholders of a branch template
+var branchTemplateVariable = regexp.MustCompile(`\{[a-z]+\}`)
+
+// BranchNaming configures how branches of new workspaces are named. It is
+// read from branch-naming.yaml in the workspace-manager config directory:
+//
+//	template: "{user}/{ticket}-{title}"
+//	pattern: '^[a-z]+/[A-Z]+-[0-9]+-[a-z0-9-]+$'
+//	ticket-pattern: '[A-Z]+-[0-9]+'
+//	ticket-url: https://acme.atlassian.net/browse/{ticket}
+//
+// Template variables are {prefix} (--branch-prefix), {name} (the workspace
+// name), {ticket} (the ticket ID found in the workspace name), {title} (the
+// workspace name without the ticket ID), {user} and {date} (YYYY-MM-DD).
+// Pattern is a team policy every workspace branch must match, generated or
+// given with --branch. TicketURL links ticket IDs to the issue tracker.
+type BranchNaming struct {
+	Template      string `yaml:"template,omitempty" json:"template"`
+	Pattern       string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	TicketPattern string `yaml:"ticket-pattern,omitempty" json:"ticket_pattern"`
+	TicketURL     string `yaml:"ticket-url,omitempty" json:"ticket_url,omitempty"`
+
+	path    string
+	pattern *regexp.Regexp
+	ticket  *regexp.Regexp
+}
+
+// GetBranchNamingPath returns the path of the branch naming file
+func GetBranchNamingPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "branch-naming.yaml"), nil
+}
+
+// LoadBranchNaming loads branch-naming.yaml, returning the default naming if none exists
+func LoadBranchNaming() (*BranchNaming, error) {
+	namingPath, err := GetBranchNamingPath()
+	if err != nil {
+		return nil, err
+	}
+
+	naming := &BranchNaming{path: namingPath}
+	data, err := os.ReadFile(namingPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read branch naming")
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, naming); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse branch naming %s", namingPath)
+		}
+	}
+
+	if naming.Template == "" {
+		naming.Template = DefaultBranchTemplate
+	}
+	for _, variable := range branchTemplateVariable.FindAllString(naming.Template, -1) {
+		switch variable {
+		case "{prefix}", "{name}", "{ticket}", "{title}", "{user}", "{date}":
+		default:
+			return nil, errors.Errorf("unknown variable %s in the branch template of %s", variable, namingPath)
+		}
+	}
+	if naming.TicketPattern == "" {
+		naming.TicketPattern = DefaultTicketPattern
+	}
+	if naming.ticket, err = regexp.Compile(naming.TicketPattern); err != nil {
+		return nil, errors.Wrapf(err, "invalid ticket-pattern in %s", namingPath)
+	}
+	if naming.Pattern != "" {
+		if naming.pattern, err = regexp.Compile(naming.Pattern); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern in %s", namingPath)
+		}
+	}
+	return naming, nil
+}
+
+// Path returns the file the naming was loaded from
+func (n *BranchNaming) Path() string {
+	return n.path
+}
+
+// Ticket returns the first ticket ID found in a workspace or branch name
+func (n *BranchNaming) Ticket(name string) string {
+	return n.ticket.FindString(name)
+}
+
+// Generate renders the branch template for a workspace; ticket is the ticket
+// ID given with --ticket, else it is looked for in the workspace name
+func (n *BranchNaming) Generate(prefix, name, ticket string) (string, error) {
+	template := n.Template
+	if ticket == "" {
+		ticket = n.Ticket(name)
+	} else if template == DefaultBranchTemplate && !strings.Contains(name, ticket) {
+		template = defaultTicketBranchTemplate
+	}
+	if ticket == "" && strings.Contains(template, "{ticket}") {
+		return "", errors.Errorf("the branch template of %s needs a ticket ID, but workspace name '%s' has none matching %s (use --ticket or --branch)", n.path, name, n.TicketPattern)
+	}
+	title := name
+	if ticket != "" {
+		// Separators left around an empty title are removed by the sanitizing below
+		title = strings.Trim(collapseDashes(strings.Replace(name, ticket, "", 1)), "-_.")
+		if !strings.Contains(template, "{ticket}") && strings.Contains(template, "{title}") {
+			title = name
+		}
+	}
+
+	branch := strings.NewReplacer(
+		"{prefix}", prefix,
+		"{name}", name,
+		"{ticket}", ticket,
+		"{title}", title,
+		"{user}", branchUser(),
+		"{date}", time.Now().Format("2006-01-02"),
+	).Replace(template)
+	return SanitizeBranchName(branch), nil
+}
+
+// Validate checks a branch name against the team pattern
+func (n *BranchNaming) Validate(branch string) error {
+	if n.pattern == nil || n.pattern.MatchString(branch) {
+		return nil
+	}
+	return errors.Errorf("branch '%s' does not match the naming policy %s of %s", branch, n.Pattern, n.path)
+}
+
+// GenerateBranchName names the branch of a new workspace with the configured
+// template; ticket is the ID given with --ticket, if any
+func GenerateBranchName(prefix, name, ticket string) (string, error) {
+	naming, err := LoadBranchNaming()
+	if err != nil {
+		return "", err
+	}
+	return naming.Generate(prefix, name, ticket)
+}
+
+// branchUser is the {user} of branch templates: the login name, lower case
+func branchUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		// Windows user names are DOMAIN\user
+		name := current.Username
+		if i := strings.LastIndex(name, `\`); i >= 0 {
+			name = name[i+1:]
+		}
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(os.Getenv("USER"))
+}
@@ -0,0 +1,216 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BulkResult summarizes an operation on one workspace of a bulk run
+type BulkResult struct {
+	Workspace    string `json:"workspace"`
+	Success      bool   `json:"success"`
+	Status       string `json:"status"`
+	Repositories int    `json:"repositories"`
+	Succeeded    int    `json:"succeeded"`
+	Failed       int    `json:"failed"`
+	Skipped      int    `json:"skipped,omitempty"`
+	Conflicts    int    `json:"conflicts,omitempty"`
+	Modified     int    `json:"modified,omitempty"`
+	Ahead        int    `json:"ahead,omitempty"`
+	Behind       int    `json:"behind,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// SelectWorkspaces returns the saved workspaces whose name matches any of the
+// glob patterns and that contain a repository with any of the tags. Empty
+// filters match every workspace.
+func SelectWorkspaces(patterns, tags []string) ([]Workspace, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid workspace pattern '%s'", pattern)
+		}
+	}
+
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+
+	var selected []Workspace
+	for _, workspace := range workspaces {
+		if len(patterns) > 0 && !matchesAnyPattern(workspace.Name, patterns) {
+			continue
+		}
+		if len(tags) > 0 && !workspaceHasTag(&workspace, tags) {
+			continue
+		}
+		selected = append(selected, workspace)
+	}
+	return selected, nil
+}
+
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func workspaceHasTag(workspace *Workspace, tags []string) bool {
+	for _, repo := range workspace.Repositories {
+		if hasAnyValue(repo.Categories, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkSync synchronizes the workspaces one after another. Workspaces are not
+// run in parallel because their worktrees share the refs of the same repositories.
+func BulkSync(ctx context.Context, workspaces []Workspace, options *SyncOptions) []BulkResult {
+	var results []BulkResult
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		result := BulkResult{Workspace: workspace.Name, Repositories: len(workspace.Repositories)}
+		if err := ctx.Err(); err != nil {
+			result.Status = "cancelled"
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		syncResults, err := NewSyncOperations(workspace).SyncWorkspace(ctx, options)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		var failures []string
+		for _, syncResult := range syncResults {
+			switch {
+			case syncResult.Skipped:
+				result.Skipped++
+			case syncResult.Conflicts:
+				result.Conflicts++
+				result.Failed++
+				failures = append(failures, syncResult.Repository+": conflicts")
+			case !syncResult.Success:
+				result.Failed++
+				failures = append(failures, fmt.Sprintf("%s: %s", syncResult.Repository, firstLine(syncResult.Error)))
+			default:
+				result.Succeeded++
+			}
+			result.Ahead += syncResult.AheadAfter
+			result.Behind += syncResult.BehindAfter
+		}
+
+		result.Success = result.Failed == 0
+		result.Status = bulkStatus(result.Success, result.Conflicts > 0, "synced")
+		result.Error = strings.Join(failures, "; ")
+		results = append(results, result)
+	}
+	return results
+}
+
+// BulkFetch fetches every repository of the workspaces once, recording freshness
+// like the fetch daemon, and reports the outcome per workspace
+func BulkFetch(ctx context.Context, workspaces []Workspace) ([]BulkResult, error) {
+	var names []string
+	for _, workspace := range workspaces {
+		names = append(names, workspace.Name)
+	}
+
+	state, err := FetchWorkspaces(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BulkResult
+	for _, workspace := range workspaces {
+		result := BulkResult{Workspace: workspace.Name, Repositories: len(workspace.Repositories)}
+		var failures []string
+		for _, repo := range workspace.Repositories {
+			record, ok := state.Repositories[repo.Path]
+			switch {
+			case !ok || record.LastAttempt.IsZero():
+				result.Skipped++
+			case record.Error != "":
+				result.Failed++
+				failures = append(failures, fmt.Sprintf("%s: %s", repo.Name, firstLine(record.Error)))
+			default:
+				result.Succeeded++
+			}
+		}
+		result.Success = result.Failed == 0
+		result.Status = bulkStatus(result.Success, false, "fetched")
+		result.Error = strings.Join(failures, "; ")
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// BulkStatus reports the status of the workspaces
+func BulkStatus(ctx context.Context, workspaces []Workspace) []BulkResult {
+	checker := NewStatusChecker()
+
+	var results []BulkResult
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		result := BulkResult{Workspace: workspace.Name, Repositories: len(workspace.Repositories)}
+
+		status, err := checker.GetWorkspaceStatus(ctx, workspace)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		for _, repoStatus := range status.Repositories {
+			if repoStatus.HasChanges {
+				result.Modified++
+			}
+			if repoStatus.HasConflicts {
+				result.Conflicts++
+			}
+			if repoStatus.Pinned != "" {
+				result.Skipped++
+				continue
+			}
+			result.Ahead += repoStatus.Ahead
+			result.Behind += repoStatus.Behind
+		}
+		result.Success = true
+		result.Succeeded = len(status.Repositories)
+		result.Status = status.Overall
+		results = append(results, result)
+	}
+	return results
+}
+
+func bulkStatus(success, conflicts bool, done string) string {
+	switch {
+	case conflicts:
+		return "conflicts"
+	case success:
+		return done
+	default:
+		return "failed"
+	}
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if line, _, ok := strings.Cut(s, "\n"); ok {
+		return line
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/pkg/errors"
+)
+
+// How a workspace branch already checked out in another worktree is handled.
+// Git checks a branch out in one worktree only.
+const (
+	CheckedOutBranchAsk = "ask"
+	// CheckedOutBranchReuse links the existing worktree into the workspace
+	CheckedOutBranchReuse = "reuse"
+	// CheckedOutBranchLink creates the branch <branch>@<workspace> from the
+	// branch and checks it out instead
+	CheckedOutBranchLink = "link"
+	CheckedOutBranchFail = "fail"
+)
+
+// CheckedOutBranchPolicies lists the values of --on-checked-out-branch
+var CheckedOutBranchPolicies = []string{CheckedOutBranchAsk, CheckedOutBranchReuse, CheckedOutBranchLink, CheckedOutBranchFail}
+
+// ValidateCheckedOutBranchPolicy checks a --on-checked-out-branch value
+func ValidateCheckedOutBranchPolicy(policy string) error {
+	if policy == "" || containsValue(CheckedOutBranchPolicies, policy) {
+		return nil
+	}
+	return errors.Errorf("unknown checked out branch policy '%s' (supported: %s)", policy, strings.Join(CheckedOutBranchPolicies, ", "))
+}
+
+// CheckedOutWorktree is a worktree a branch is checked out in
+type CheckedOutWorktree struct {
+	Path string
+	// Location describes the worktree for messages: a workspace, the main
+	// repository or a plain worktree
+	Location string
+}
+
+// branchCheckedOutElsewhere returns the worktree of a repository, other than
+// the workspace's, where a branch is checked out; nil if there is none
+func branchCheckedOutElsewhere(ctx context.Context, workspace *Workspace, repo Repository, branch string) *CheckedOutWorktree {
+	worktrees, err := ListGitWorktrees(ctx, repo.Path)
+	if err != nil {
+		return nil
+	}
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		workspaces = nil
+	}
+
+	targetPath := resolvePath(filepath.Join(workspace.Path, repo.Name))
+	for _, worktree := range worktrees {
+		if worktree.Branch != branch || resolvePath(worktree.Path) == targetPath {
+			continue
+		}
+		checkedOut := &CheckedOutWorktree{Path: worktree.Path, Location: fmt.Sprintf("the worktree at %s", worktree.Path)}
+		if worktree.Main {
+			checkedOut.Location = fmt.Sprintf("the repository at %s", worktree.Path)
+		} else if other, err := DetectWorkspace(worktree.Path, workspaces); err == nil && other.Name != workspace.Name {
+			checkedOut.Location = fmt.Sprintf("workspace '%s' (%s)", other.Name, worktree.Path)
+		}
+		return checkedOut
+	}
+	return nil
+}
+
+// LinkedBranchName is the branch checked out instead of a branch already
+// checked out elsewhere (CheckedOutBranchLink)
+func LinkedBranchName(branch, workspaceName string) string {
+	return branch + "@" + workspaceName
+}
+
+// checkedOutBranchPolicy returns how to handle a branch checked out
+// elsewhere, CheckedOutBranchAsk meaning the user is prompted
+func (wm *WorkspaceManager) checkedOutBranchPolicy(repoName, branch string, checkedOut *CheckedOutWorktree) (string, error) {
+	policy := wm.OnCheckedOutBranch
+	if policy == "" {
+		policy = CheckedOutBranchAsk
+	}
+	if policy == CheckedOutBranchAsk && nonInteractive {
+		return "", errors.Errorf("branch '%s' of %s is already checked out in %s; pass --on-checked-out-branch=reuse, link or fail", branch, repoName, checkedOut.Location)
+	}
+	return policy, nil
+}
+
+// askCheckedOutBranch asks how to handle a branch checked out elsewhere. An
+// aborted prompt is returned as existingBranchCancel.
+func askCheckedOutBranch(repoName, branch, linkedBranch string, checkedOut *CheckedOutWorktree) (string, error) {
+	output.PrintWarning("Branch '%s' of %s is already checked out in %s", branch, repoName, checkedOut.Location)
+	choice, err := ux.Select("Git checks a branch out in one worktree only. How would you like to continue?", []ux.Option{
+		ux.NewOption(fmt.Sprintf("Reuse that worktree (link %s into the workspace)", checkedOut.Path), CheckedOutBranchReuse),
+		ux.NewOption(fmt.Sprintf("Create branch '%s' from '%s' for this workspace", linkedBranch, branch), CheckedOutBranchLink),
+		ux.NewOption("Cancel", existingBranchCancel),
+	})
+	if errors.Is(err, ux.ErrAborted) {
+		return existingBranchCancel, nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user choice")
+	}
+	return choice, nil
+}
+
+// checkedOutBranchError is returned by the fail policy, with what can be done about it
+func checkedOutBranchError(repoName, branch string, checkedOut *CheckedOutWorktree) error {
+	return errors.Errorf("branch '%s' of %s is already checked out in %s and git checks a branch out in one worktree only. "+
+		"Switch that worktree to another branch ('git -C %s switch --detach'), or pass --on-checked-out-branch=reuse to link it into the workspace "+
+		"or --on-checked-out-branch=link to work on a new branch created from it",
+		branch, repoName, checkedOut.Location, checkedOut.Path)
+}
+
+// addCheckedOutWorktree gives a repository a worktree when its branch is
+// already checked out elsewhere. It returns false when the branch is not
+// checked out elsewhere and the worktree still needs to be created.
+func (wm *WorkspaceManager) addCheckedOutWorktree(ctx context.Context, workspace *Workspace, repo Repository, branch string) (bool, error) {
+	checkedOut := branchCheckedOutElsewhere(ctx, workspace, repo, branch)
+	if checkedOut == nil {
+		return false, nil
+	}
+
+	choice, err := wm.checkedOutBranchPolicy(repo.Name, branch, checkedOut)
+	if err != nil {
+		return true, err
+	}
+	linkedBranch := LinkedBranchName(branch, workspace.Name)
+	if choice == CheckedOutBranchAsk {
+		if choice, err = askCheckedOutBranch(repo.Name, branch, linkedBranch, checkedOut); err != nil {
+			return true, err
+		}
+	}
+
+	targetPath := filepath.Join(workspace.Path, repo.Name)
+	switch choice {
+	case CheckedOutBranchReuse:
+		output.PrintInfo("Linking %s into the workspace for %s", checkedOut.Path, repo.Name)
+		if err := os.Symlink(checkedOut.Path, targetPath); err != nil {
+			return true, errors.Wrapf(err, "failed to link %s", checkedOut.Path)
+		}
+		return true, nil
+	case CheckedOutBranchLink:
+		exists, err := wm.CheckBranchExists(ctx, repo.Path, linkedBranch)
+		if err != nil {
+			return true, err
+		}
+		if exists {
+			output.PrintInfo("Using existing branch '%s'...", linkedBranch)
+			err = wm.addWorktree(ctx, workspace, repo, targetPath, linkedBranch)
+		} else {
+			output.PrintInfo("Creating branch '%s' from '%s'...", linkedBranch, branch)
+			err = wm.addWorktree(ctx, workspace, repo, "-b", linkedBranch, targetPath, branch)
+		}
+		if err != nil {
+			return true, err
+		}
+		if workspace.RepositoryBranches == nil {
+			workspace.RepositoryBranches = make(map[string]string)
+		}
+		workspace.RepositoryBranches[repo.Name] = linkedBranch
+		return true, nil
+	case CheckedOutBranchFail:
+		return true, checkedOutBranchError(repo.Name, branch, checkedOut)
+	case existingBranchCancel:
+		return true, errors.New("workspace creation cancelled by user")
+	default:
+		return true, errors.New("invalid choice, workspace creation cancelled")
+	}
+}
+
+// removeReusedWorktree removes the link of a worktree reused from elsewhere
+// (CheckedOutBranchReuse), leaving the worktree itself alone. It returns false
+// for worktrees of the workspace.
+func removeReusedWorktree(worktreePath string) (bool, error) {
+	info, err := os.Lstat(worktreePath)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false, nil
+	}
+	if err := os.Remove(worktreePath); err != nil {
+		return true, errors.Wrapf(err, "failed to remove the link %s", worktreePath)
+	}
+	output.PrintInfo("Removed the link %s to a worktree outside the workspace", worktreePath)
+	return true, nil
+}
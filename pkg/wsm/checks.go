@@ -0,0 +1,250 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Operations checks can be enforced before
+const (
+	CheckBeforePush  = "push"
+	CheckBeforeMerge = "merge"
+)
+
+// ChecksConfig declares the commands checking the repositories of a workspace,
+// run by 'wsm check'. It is read from checks.yaml in the workspace-manager
+// config directory:
+//
+//	enforce: [push, merge]
+//	jobs: 4
+//	timeout: 10m
+//	tags:
+//	  go: ["go vet ./...", "go test ./..."]
+//	  node: ["npm test"]
+//	repositories:
+//	  app: ["make lint", "make test"]
+//
+// Tags are repository categories; a repository entry replaces the commands of
+// its tags.
+type ChecksConfig struct {
+	// Enforce lists the operations (push, merge) that run the checks first and
+	// stop when one fails
+	Enforce []string `yaml:"enforce,omitempty" json:"enforce,omitempty"`
+	// Jobs bounds the repositories checked at once, the jobs of limits.yaml by default
+	Jobs int `yaml:"jobs,omitempty" json:"jobs,omitempty"`
+	// Timeout bounds the checks of a repository, e.g. "10m"
+	Timeout      string              `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Tags         map[string][]string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Repositories map[string][]string `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+
+	path    string
+	timeout time.Duration
+}
+
+// CheckResult is the outcome of the checks of one repository
+type CheckResult struct {
+	Repository string        `json:"repository"`
+	Commands   []string      `json:"commands"`
+	Passed     bool          `json:"passed"`
+	Skipped    bool          `json:"skipped,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	// Failed is the command that failed, with its exit code and output
+	Failed   string `json:"failed,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ChecksOptions controls RunChecks
+type ChecksOptions struct {
+	// Repositories and Tags restrict the checked repositories
+	Repositories []string
+	Tags         []string
+	// Jobs overrides the jobs of the configuration when > 0
+	Jobs int
+	// Stdout and Stderr receive the output streamed line by line, as for
+	// ExecOptions; when nil, output is captured in the results
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// GetChecksConfigPath returns the path of the checks configuration file
+func GetChecksConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "checks.yaml"), nil
+}
+
+// LoadChecksConfig loads checks.yaml, returning an empty configuration
+// (nothing checked) if none exists
+func LoadChecksConfig() (*ChecksConfig, error) {
+	configPath, err := GetChecksConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ChecksConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read checks configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse checks configuration %s", configPath)
+	}
+
+	for _, operation := range config.Enforce {
+		if operation != CheckBeforePush && operation != CheckBeforeMerge {
+			return nil, errors.Errorf("unknown operation '%s' in enforce of %s (expected push or merge)", operation, configPath)
+		}
+	}
+	if config.Timeout != "" {
+		if config.timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, errors.Wrapf(err, "invalid timeout in %s", configPath)
+		}
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *ChecksConfig) Path() string {
+	return c.path
+}
+
+// Enforced reports whether the checks run before an operation
+func (c *ChecksConfig) Enforced(operation string) bool {
+	return containsValue(c.Enforce, operation)
+}
+
+// CommandsFor returns the check commands of a repository: its own entry, or
+// the commands of its tags in tag order
+func (c *ChecksConfig) CommandsFor(repo Repository) []string {
+	if commands, ok := c.Repositories[repo.Name]; ok {
+		return commands
+	}
+	tags := make([]string, 0, len(c.Tags))
+	for tag := range c.Tags {
+		if containsValue(repo.Categories, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	var commands []string
+	for _, tag := range tags {
+		for _, command := range c.Tags[tag] {
+			commands = appendUnique(commands, command)
+		}
+	}
+	return commands
+}
+
+// RunChecks runs the check commands of the workspace repositories, several
+// repositories at once and the commands of a repository in order, stopping at
+// the first failing one. Repositories without commands are skipped. Results
+// are returned in workspace order; failures are recorded in them, not
+// returned as an error.
+func (c *ChecksConfig) RunChecks(ctx context.Context, workspace *Workspace, options ChecksOptions) ([]CheckResult, error) {
+	repos, err := RepositoryFilter{Repositories: options.Repositories, Tags: options.Tags}.Select(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := options.Jobs
+	if jobs <= 0 {
+		jobs = c.Jobs
+	}
+
+	width := 0
+	for _, repo := range repos {
+		if len(repo.Name) > width {
+			width = len(repo.Name)
+		}
+	}
+
+	eo := NewExecOperations(workspace)
+	var outputMu sync.Mutex
+	results := make([]CheckResult, len(repos))
+	limit := newLimiter(workspace.Name, jobs)
+	var wg sync.WaitGroup
+
+	for i, repo := range repos {
+		results[i] = CheckResult{Repository: repo.Name, Commands: c.CommandsFor(repo)}
+		if len(results[i].Commands) == 0 {
+			results[i].Skipped = true
+			results[i].Passed = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *CheckResult, repo Repository) {
+			defer wg.Done()
+
+			release, err := limit.acquire(ctx)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			defer release()
+
+			repoCtx := ctx
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				repoCtx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+
+			prefix := fmt.Sprintf("[%-*s] ", width, repo.Name)
+			start := time.Now()
+			for _, command := range result.Commands {
+				run := eo.execInRepository(repoCtx, repo, ExecOptions{Command: command, Stdout: options.Stdout, Stderr: options.Stderr}, prefix, &outputMu)
+				if !run.Succeeded() {
+					result.Failed, result.ExitCode, result.Output, result.Error = command, run.ExitCode, run.Output, run.Error
+					if repoCtx.Err() == context.DeadlineExceeded {
+						result.Error = fmt.Sprintf("timed out after %s", c.timeout)
+					}
+					break
+				}
+			}
+			result.Duration = time.Since(start)
+			result.Passed = result.Failed == "" && result.Error == ""
+		}(&results[i], repo)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// ChecksFailed returns the names of the repositories whose checks failed
+func ChecksFailed(results []CheckResult) []string {
+	var failed []string
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, result.Repository)
+		}
+	}
+	return failed
+}
+
+// ChecksError summarizes failed checks as an error, nil when they all passed
+func ChecksError(results []CheckResult) error {
+	failed := ChecksFailed(results)
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("checks failed in %d of %d repositories: %s", len(failed), len(results), strings.Join(failed, ", "))
+}
@@ -0,0 +1,230 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CherryPickOptions describes which commits `wsm cherry-pick` applies to a workspace
+type CherryPickOptions struct {
+	// ChangeID selects the commits carrying a Workspace-Change-Id trailer
+	ChangeID string
+	// Commits lists the commits per repository, oldest first, instead of a ChangeID
+	Commits map[string][]string
+	// Source restricts ChangeID lookups to the branch of another workspace;
+	// without it, all branches of the repositories are searched
+	Source *Workspace
+	DryRun bool
+	// KeepConflicts leaves a conflicting cherry-pick in progress for manual
+	// resolution; by default the repository is restored to its previous HEAD
+	KeepConflicts bool
+}
+
+// CherryPickResult is the outcome of cherry-picking into one repository
+type CherryPickResult struct {
+	Repository string   `json:"repository"`
+	Commits    []string `json:"commits"`
+	// AlreadyApplied lists commits whose cherry-pick is already on the branch
+	AlreadyApplied []string `json:"already_applied,omitempty"`
+	Applied        int      `json:"applied"`
+	Success        bool     `json:"success"`
+	Conflicts      bool     `json:"conflicts"`
+	ConflictCommit string   `json:"conflict_commit,omitempty"`
+	ConflictFiles  []string `json:"conflict_files,omitempty"`
+	Skipped        bool     `json:"skipped,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// ParseCherryPickSpecs parses "repo=sha" specifications; a repository may be
+// given several times to pick several commits, in the given order
+func ParseCherryPickSpecs(specs []string) (map[string][]string, error) {
+	commits := make(map[string][]string)
+	for _, spec := range specs {
+		repoName, shas, ok := strings.Cut(spec, "=")
+		repoName = strings.TrimSpace(repoName)
+		if !ok || repoName == "" || strings.TrimSpace(shas) == "" {
+			return nil, errors.Errorf("invalid commit specification '%s' (expected repo=sha[,sha...])", spec)
+		}
+		for _, sha := range strings.Split(shas, ",") {
+			if sha = strings.TrimSpace(sha); sha != "" {
+				commits[repoName] = append(commits[repoName], sha)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// CherryPick applies commits from other branches to the repositories of the workspace.
+// Repositories are processed independently: a conflict in one does not stop the others.
+func (so *SyncOperations) CherryPick(ctx context.Context, options *CherryPickOptions) ([]CherryPickResult, error) {
+	if options.ChangeID == "" && len(options.Commits) == 0 {
+		return nil, errors.New("a change id or commits are required")
+	}
+	for repoName := range options.Commits {
+		if !so.hasRepository(repoName) {
+			return nil, errors.Errorf("repository '%s' not found in workspace '%s'", repoName, so.workspace.Name)
+		}
+	}
+
+	var results []CherryPickResult
+	for _, repo := range so.workspace.Repositories {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		if options.ChangeID == "" {
+			if _, ok := options.Commits[repo.Name]; !ok {
+				continue
+			}
+		}
+
+		repoPath := filepath.Join(so.workspace.Path, repo.Name)
+		result := CherryPickResult{Repository: repo.Name}
+
+		if ref := so.workspace.PinFor(repo.Name); ref != "" {
+			if len(options.Commits[repo.Name]) > 0 {
+				result.Error = fmt.Sprintf("pinned to %s", ref)
+				results = append(results, result)
+			}
+			continue
+		}
+
+		commits, err := so.cherryPickCommits(ctx, repo.Name, repoPath, options)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if len(commits) == 0 {
+			// The change did not touch this repository
+			continue
+		}
+
+		results = append(results, so.cherryPickRepository(ctx, repoPath, result, commits, options))
+	}
+
+	return results, nil
+}
+
+func (so *SyncOperations) hasRepository(repoName string) bool {
+	for _, repo := range so.workspace.Repositories {
+		if repo.Name == repoName {
+			return true
+		}
+	}
+	return false
+}
+
+// cherryPickCommits resolves the full hashes of the commits to pick in a repository, oldest first
+func (so *SyncOperations) cherryPickCommits(ctx context.Context, repoName, repoPath string, options *CherryPickOptions) ([]string, error) {
+	if options.ChangeID == "" {
+		var commits []string
+		for _, sha := range options.Commits[repoName] {
+			hash, err := gitOutput(ctx, repoPath, "rev-parse", "--verify", "--quiet", sha+"^{commit}")
+			if err != nil {
+				return nil, errors.Errorf("unknown commit %s", sha)
+			}
+			commits = append(commits, hash)
+		}
+		return commits, nil
+	}
+
+	args := []string{"log", "--reverse", "--format=%H", "--fixed-strings",
+		"--grep", fmt.Sprintf("%s: %s", ChangeIDTrailer, options.ChangeID)}
+	if options.Source != nil {
+		if !NewSyncOperations(options.Source).hasRepository(repoName) {
+			return nil, nil
+		}
+		args = append(args, options.Source.Branch)
+	} else {
+		args = append(args, "--all")
+	}
+	// Commits already on the branch, including earlier cherry-picks carrying the same trailer
+	args = append(args, "--not", "HEAD")
+
+	out, err := gitOutput(ctx, repoPath, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search commits")
+	}
+	return splitLines(out), nil
+}
+
+// cherryPickRepository picks the commits not yet applied onto HEAD with -x, so
+// each new commit records where it came from
+func (so *SyncOperations) cherryPickRepository(ctx context.Context, repoPath string, result CherryPickResult, commits []string, options *CherryPickOptions) CherryPickResult {
+	var pending []string
+	for _, commit := range commits {
+		result.Commits = append(result.Commits, commit)
+		applied, err := gitOutput(ctx, repoPath, "log", "HEAD", "--format=%H", "-1", "--fixed-strings",
+			"--grep", fmt.Sprintf("(cherry picked from commit %s)", commit))
+		if err == nil && applied != "" {
+			result.AlreadyApplied = append(result.AlreadyApplied, commit)
+			continue
+		}
+		pending = append(pending, commit)
+	}
+
+	if len(pending) == 0 {
+		result.Success = true
+		result.Skipped = true
+		return result
+	}
+	if options.DryRun {
+		result.Success = true
+		return result
+	}
+
+	if modified, err := gitOutput(ctx, repoPath, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		result.Error = err.Error()
+		return result
+	} else if modified != "" {
+		result.Error = "worktree has uncommitted changes"
+		return result
+	}
+
+	before, _ := gitOutput(ctx, repoPath, "rev-parse", "HEAD")
+	out, err := gitOutput(ctx, repoPath, append([]string{"cherry-pick", "-x"}, pending...)...)
+	result.Applied = so.countCommitsSince(ctx, repoPath, before)
+	if err == nil {
+		result.Success = true
+		return result
+	}
+
+	if so.hasConflicts(ctx, repoPath) {
+		result.Conflicts = true
+		if commit, err := gitOutput(ctx, repoPath, "rev-parse", "CHERRY_PICK_HEAD"); err == nil {
+			result.ConflictCommit = commit
+		}
+		if files, err := gitOutput(ctx, repoPath, "diff", "--name-only", "--diff-filter=U"); err == nil {
+			result.ConflictFiles = splitLines(files)
+		}
+		if options.KeepConflicts {
+			result.Error = fmt.Sprintf("conflict after %d of %d commits, resolve and run 'git cherry-pick --continue'", result.Applied, len(pending))
+			return result
+		}
+		result.Error = fmt.Sprintf("conflict after %d of %d commits, cherry-pick aborted", result.Applied, len(pending))
+	} else {
+		result.Error = firstLine(out)
+	}
+
+	// Aborting restores the branch as it was before the cherry-pick
+	_, _ = gitOutput(ctx, repoPath, "cherry-pick", "--abort")
+	result.Applied = 0
+	return result
+}
+
+func (so *SyncOperations) countCommitsSince(ctx context.Context, repoPath, before string) int {
+	if before == "" {
+		return 0
+	}
+	count, err := gitOutput(ctx, repoPath, "rev-list", "--count", before+"..HEAD")
+	if err != nil {
+		return 0
+	}
+	var n int
+	_, _ = fmt.Sscanf(count, "%d", &n)
+	return n
+}
@@ -0,0 +1,493 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// CI providers
+const (
+	CIProviderGitHub  = "github"
+	CIProviderWebhook = "webhook"
+	CIProviderNone    = "none"
+)
+
+// CIConfig declares how wsm triggers CI for workspace repositories. It is read
+// from ci.yaml in the workspace-manager config directory:
+//
+//	provider: github
+//	workflow: ci.yml
+//	inputs:
+//	  workspace: "{workspace}"
+//	repositories:
+//	  legacy:
+//	    provider: webhook
+//	    url: https://ci.example.com/hooks/build
+//	    token-env: CI_TOKEN
+//	  docs:
+//	    provider: none
+//
+// Repository entries override the top-level settings field by field.
+type CIConfig struct {
+	CITarget     `yaml:",inline"`
+	Repositories map[string]CITarget `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+
+	path string
+}
+
+// CITarget describes the CI of a repository
+type CITarget struct {
+	// Provider is github (workflow_dispatch through the gh CLI), webhook or none
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Workflow is the workflow file or name dispatched on GitHub
+	Workflow string `yaml:"workflow,omitempty" json:"workflow,omitempty"`
+	// Inputs are passed to the workflow or webhook; {workspace}, {branch},
+	// {base_branch}, {repo} and {commit} are replaced
+	Inputs map[string]string `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	// URL receives a JSON POST for the webhook provider
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// TokenEnv names an environment variable holding a bearer token for the webhook
+	TokenEnv string `yaml:"token-env,omitempty" json:"token_env,omitempty"`
+}
+
+// GetCIConfigPath returns the path of the CI configuration file
+func GetCIConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "ci.yaml"), nil
+}
+
+// LoadCIConfig loads ci.yaml. Without the file, CI runs can still be listed
+// from GitHub, but nothing can be triggered.
+func LoadCIConfig() (*CIConfig, error) {
+	configPath, err := GetCIConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &CIConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CI configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CI configuration %s", configPath)
+	}
+
+	for _, target := range append([]CITarget{config.CITarget}, config.targets()...) {
+		switch target.Provider {
+		case "", CIProviderGitHub, CIProviderWebhook, CIProviderNone:
+		default:
+			return nil, errors.Errorf("unknown CI provider '%s' in %s", target.Provider, configPath)
+		}
+	}
+	return config, nil
+}
+
+func (c *CIConfig) targets() []CITarget {
+	targets := make([]CITarget, 0, len(c.Repositories))
+	for _, target := range c.Repositories {
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Path returns the file the configuration was loaded from
+func (c *CIConfig) Path() string {
+	return c.path
+}
+
+// TargetFor returns the CI settings of a repository
+func (c *CIConfig) TargetFor(repoName string) CITarget {
+	target := c.CITarget
+	if override, ok := c.Repositories[repoName]; ok {
+		if override.Provider != "" {
+			target.Provider = override.Provider
+		}
+		if override.Workflow != "" {
+			target.Workflow = override.Workflow
+		}
+		if override.URL != "" {
+			target.URL = override.URL
+		}
+		if override.TokenEnv != "" {
+			target.TokenEnv = override.TokenEnv
+		}
+		if len(override.Inputs) > 0 {
+			inputs := make(map[string]string, len(target.Inputs)+len(override.Inputs))
+			for key, value := range target.Inputs {
+				inputs[key] = value
+			}
+			for key, value := range override.Inputs {
+				inputs[key] = value
+			}
+			target.Inputs = inputs
+		}
+	}
+	if target.Provider == "" {
+		target.Provider = CIProviderGitHub
+	}
+	return target
+}
+
+// CITriggerOptions configures TriggerCI
+type CITriggerOptions struct {
+	// Repositories restricts the trigger; empty means all repositories
+	Repositories []string
+	// Ref is the branch CI runs on; empty means the branch checked out in each worktree
+	Ref string
+	// Event tells webhooks what caused the trigger (manual, push, merge)
+	Event  string
+	DryRun bool
+}
+
+// CITriggerResult is the outcome of triggering CI for one repository
+type CITriggerResult struct {
+	Repository string            `json:"repository"`
+	Provider   string            `json:"provider"`
+	Workflow   string            `json:"workflow,omitempty"`
+	Ref        string            `json:"ref"`
+	Inputs     map[string]string `json:"inputs,omitempty"`
+	Success    bool              `json:"success"`
+	Skipped    bool              `json:"skipped,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// CIRun is the latest CI run of a repository branch
+type CIRun struct {
+	Repository string    `json:"repository"`
+	Provider   string    `json:"provider"`
+	Workflow   string    `json:"workflow,omitempty"`
+	Branch     string    `json:"branch"`
+	Status     string    `json:"status,omitempty"`     // queued, in_progress, completed
+	Conclusion string    `json:"conclusion,omitempty"` // success, failure, cancelled, ...
+	URL        string    `json:"url,omitempty"`
+	Commit     string    `json:"commit,omitempty"`
+	Created    time.Time `json:"created,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Completed reports whether the run finished, or cannot be tracked at all
+func (r CIRun) Completed() bool {
+	return r.Status == "completed" || r.Error != "" || r.Provider != CIProviderGitHub
+}
+
+// TriggerCI dispatches the CI of the workspace repositories with the workspace
+// metadata as inputs. Pinned repositories and repositories with provider none are skipped.
+func TriggerCI(ctx context.Context, workspace *Workspace, options *CITriggerOptions) ([]CITriggerResult, error) {
+	config, err := LoadCIConfig()
+	if err != nil {
+		return nil, err
+	}
+	for _, repoName := range options.Repositories {
+		if !NewSyncOperations(workspace).hasRepository(repoName) {
+			return nil, errors.Errorf("repository '%s' not found in workspace '%s'", repoName, workspace.Name)
+		}
+	}
+
+	var results []CITriggerResult
+	for _, repo := range workspace.Repositories {
+		if len(options.Repositories) > 0 && !containsValue(options.Repositories, repo.Name) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		target := config.TargetFor(repo.Name)
+		result := CITriggerResult{Repository: repo.Name, Provider: target.Provider, Workflow: target.Workflow}
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+
+		if workspace.PinFor(repo.Name) != "" || target.Provider == CIProviderNone {
+			result.Skipped = true
+			result.Success = true
+			results = append(results, result)
+			continue
+		}
+
+		result.Ref = options.Ref
+		if result.Ref == "" {
+			if result.Ref, err = getGitCurrentBranch(ctx, repoPath); err != nil || result.Ref == "" {
+				result.Error = "cannot determine the branch to run CI on"
+				results = append(results, result)
+				continue
+			}
+		}
+		commit, _ := gitOutput(ctx, repoPath, "rev-parse", result.Ref)
+		result.Inputs = expandCIInputs(target.Inputs, workspace, repo.Name, result.Ref, commit)
+
+		if options.DryRun {
+			result.Success = true
+			results = append(results, result)
+			continue
+		}
+
+		switch target.Provider {
+		case CIProviderGitHub:
+			err = dispatchWorkflow(ctx, repoPath, target, result.Ref, result.Inputs)
+		case CIProviderWebhook:
+			err = postCIWebhook(ctx, target, ciWebhookPayload{
+				Event:      options.Event,
+				Workspace:  workspace.Name,
+				Repository: repo.Name,
+				RemoteURL:  repo.RemoteURL,
+				Branch:     result.Ref,
+				BaseBranch: workspace.BaseBranchFor(repo.Name),
+				Commit:     commit,
+				Inputs:     result.Inputs,
+			})
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func expandCIInputs(inputs map[string]string, workspace *Workspace, repoName, branch, commit string) map[string]string {
+	if len(inputs) == 0 {
+		return nil
+	}
+	replacer := strings.NewReplacer(
+		"{workspace}", workspace.Name,
+		"{branch}", branch,
+		"{base_branch}", workspace.BaseBranchFor(repoName),
+		"{repo}", repoName,
+		"{commit}", commit,
+	)
+	expanded := make(map[string]string, len(inputs))
+	for key, value := range inputs {
+		expanded[key] = replacer.Replace(value)
+	}
+	return expanded
+}
+
+// dispatchWorkflow triggers a workflow_dispatch event with the gh CLI, which
+// resolves the GitHub repository from the worktree's remotes
+func dispatchWorkflow(ctx context.Context, repoPath string, target CITarget, ref string, inputs map[string]string) error {
+	if target.Workflow == "" {
+		return errors.New("no workflow configured in ci.yaml")
+	}
+	if err := RequireTool(ctx, "gh"); err != nil {
+		return err
+	}
+
+	args := []string{"workflow", "run", target.Workflow, "--ref", ref}
+	for _, key := range sortedKeys(inputs) {
+		args = append(args, "-f", key+"="+inputs[key])
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = repoPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "gh workflow run failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ciWebhookPayload is the JSON body posted to webhook CI targets
+type ciWebhookPayload struct {
+	Event      string            `json:"event"`
+	Workspace  string            `json:"workspace"`
+	Repository string            `json:"repository"`
+	RemoteURL  string            `json:"remote_url"`
+	Branch     string            `json:"branch"`
+	BaseBranch string            `json:"base_branch,omitempty"`
+	Commit     string            `json:"commit"`
+	Inputs     map[string]string `json:"inputs,omitempty"`
+}
+
+func postCIWebhook(ctx context.Context, target CITarget, payload ciWebhookPayload) error {
+	if target.URL == "" {
+		return errors.New("no webhook url configured in ci.yaml")
+	}
+	if payload.Event == "" {
+		payload.Event = "manual"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode webhook payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook url %s", target.URL)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if target.TokenEnv != "" {
+		token := os.Getenv(target.TokenEnv)
+		if token == "" {
+			return errors.Errorf("environment variable %s holding the webhook token is not set", target.TokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "POST %s failed", target.URL)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("POST %s returned %s", target.URL, resp.Status)
+	}
+	return nil
+}
+
+// GetCIRuns returns the latest CI run of the checked out branch of every
+// repository. Only GitHub Actions runs can be queried; webhook repositories
+// are listed without a status.
+func GetCIRuns(ctx context.Context, workspace *Workspace) ([]CIRun, error) {
+	config, err := LoadCIConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []CIRun
+	for _, repo := range workspace.Repositories {
+		if err := ctx.Err(); err != nil {
+			return runs, err
+		}
+		target := config.TargetFor(repo.Name)
+		if target.Provider == CIProviderNone {
+			continue
+		}
+
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		run := CIRun{Repository: repo.Name, Provider: target.Provider, Workflow: target.Workflow}
+		if run.Branch, err = getGitCurrentBranch(ctx, repoPath); err != nil || run.Branch == "" {
+			// Detached worktrees, e.g. pinned repositories, have no branch runs
+			continue
+		}
+		if target.Provider == CIProviderGitHub {
+			if err := latestWorkflowRun(ctx, repoPath, &run); err != nil {
+				run.Error = err.Error()
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func latestWorkflowRun(ctx context.Context, repoPath string, run *CIRun) error {
+	if err := RequireTool(ctx, "gh"); err != nil {
+		return err
+	}
+
+	args := []string{"run", "list", "--branch", run.Branch, "--limit", "1",
+		"--json", "status,conclusion,workflowName,url,headSha,createdAt"}
+	if run.Workflow != "" {
+		args = append(args, "--workflow", run.Workflow)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return errors.Errorf("gh run list failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return errors.Wrap(err, "gh run list failed")
+	}
+
+	var ghRuns []struct {
+		Status       string    `json:"status"`
+		Conclusion   string    `json:"conclusion"`
+		WorkflowName string    `json:"workflowName"`
+		URL          string    `json:"url"`
+		HeadSha      string    `json:"headSha"`
+		CreatedAt    time.Time `json:"createdAt"`
+	}
+	if err := json.Unmarshal(out, &ghRuns); err != nil {
+		return errors.Wrap(err, "failed to parse gh run list output")
+	}
+	if len(ghRuns) == 0 {
+		return nil
+	}
+
+	latest := ghRuns[0]
+	run.Status = latest.Status
+	run.Conclusion = latest.Conclusion
+	run.URL = latest.URL
+	run.Commit = latest.HeadSha
+	run.Created = latest.CreatedAt
+	if latest.WorkflowName != "" {
+		run.Workflow = latest.WorkflowName
+	}
+	return nil
+}
+
+// WaitForCI polls the CI runs until all runs started after since have
+// completed. update is called with the runs after every poll.
+func WaitForCI(ctx context.Context, workspace *Workspace, since time.Time, interval time.Duration, update func([]CIRun)) ([]CIRun, error) {
+	for {
+		runs, err := GetCIRuns(ctx, workspace)
+		if err != nil {
+			return runs, err
+		}
+		if update != nil {
+			update(runs)
+		}
+
+		done := true
+		for _, run := range runs {
+			// A run older than the trigger means the new one is not listed yet
+			if !run.Completed() || (run.Provider == CIProviderGitHub && run.Error == "" && run.Created.Before(since)) {
+				done = false
+			}
+		}
+		if done {
+			return runs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return runs, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// String summarizes the state of a run
+func (r CIRun) String() string {
+	switch {
+	case r.Error != "":
+		return "error"
+	case r.Provider != CIProviderGitHub:
+		return fmt.Sprintf("n/a (%s)", r.Provider)
+	case r.Status == "":
+		return "no runs"
+	case r.Status == "completed":
+		return r.Conclusion
+	default:
+		return r.Status
+	}
+}
@@ -0,0 +1,116 @@
+package wsm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Partial clone filters
+const (
+	// CloneFilterBlobless fetches file contents on demand (--filter=blob:none)
+	CloneFilterBlobless = "blobless"
+	// CloneFilterTreeless also fetches directory trees on demand (--filter=tree:0)
+	CloneFilterTreeless = "treeless"
+)
+
+// CloneFilters lists the accepted partial clone filters, for flag completion
+var CloneFilters = []string{CloneFilterBlobless, CloneFilterTreeless}
+
+// CloneOptions makes clones and fetches of very large repositories cheaper
+type CloneOptions struct {
+	// Filter makes a partial clone: blobless or treeless
+	Filter string `json:"filter,omitempty"`
+	// Depth makes shallow clones and fetches of that many commits per branch
+	Depth int `json:"depth,omitempty"`
+}
+
+// Validate rejects unknown filters and negative depths
+func (o CloneOptions) Validate() error {
+	if o.Filter != "" && !containsValue(CloneFilters, o.Filter) {
+		return errors.Errorf("invalid clone filter '%s' (expected %s)", o.Filter, strings.Join(CloneFilters, ", "))
+	}
+	if o.Depth < 0 {
+		return errors.Errorf("invalid clone depth %d", o.Depth)
+	}
+	return nil
+}
+
+// IsZero reports whether the options ask for a regular full clone
+func (o CloneOptions) IsZero() bool {
+	return o.Filter == "" && o.Depth == 0
+}
+
+func (o CloneOptions) String() string {
+	var parts []string
+	if o.Filter != "" {
+		parts = append(parts, o.Filter)
+	}
+	if o.Depth > 0 {
+		parts = append(parts, fmt.Sprintf("depth %d", o.Depth))
+	}
+	if len(parts) == 0 {
+		return "full"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cloneArgs returns the git clone arguments of the options. Shallow clones
+// keep every branch, so workspace branches can still start from any of them.
+func (o CloneOptions) cloneArgs() []string {
+	var args []string
+	switch o.Filter {
+	case CloneFilterBlobless:
+		args = append(args, "--filter=blob:none")
+	case CloneFilterTreeless:
+		args = append(args, "--filter=tree:0")
+	}
+	if o.Depth > 0 {
+		args = append(args, "--depth", fmt.Sprint(o.Depth), "--no-single-branch")
+	}
+	return args
+}
+
+// fetchArgs returns the git fetch arguments keeping a shallow clone shallow.
+// Partial clones remember their filter in the remote configuration.
+func (o CloneOptions) fetchArgs() []string {
+	if o.Depth > 0 {
+		return []string{"--depth", fmt.Sprint(o.Depth)}
+	}
+	return nil
+}
+
+// cloneOptions returns the clone options of a repository
+func (r Repository) cloneOptions() CloneOptions {
+	if r.CloneOptions == nil {
+		return CloneOptions{}
+	}
+	return *r.CloneOptions
+}
+
+// SetCloneOptions records how a repository is cloned on demand and fetched, in
+// the registry and in every workspace using it. Zero options reset it to full
+// clones. It returns the names of the updated workspaces.
+func (wm *WorkspaceManager) SetCloneOptions(repoName string, options CloneOptions) ([]string, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	repo, err := wm.Discoverer.findRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored *CloneOptions
+	if !options.IsZero() {
+		stored = &options
+	}
+	repo.CloneOptions = stored
+	if err := wm.Discoverer.SaveRegistry(); err != nil {
+		return nil, errors.Wrap(err, "failed to save registry")
+	}
+
+	return wm.updateWorkspaceRepositories(*repo, func(r *Repository) {
+		r.CloneOptions = stored
+	})
+}
@@ -0,0 +1,151 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommitGroup splits the staged changes of a repository into a commit of its
+// own for the files matching Paths, written in .wsmignore syntax (docs/**,
+// *.md, /go.sum). Message is the message of the group's commit; it may use
+// {message} for the message of the commit command and {group} for Name besides
+// the usual placeholders, and defaults to DefaultCommitGroupMessage.
+type CommitGroup struct {
+	Name    string   `yaml:"name" json:"name"`
+	Paths   []string `yaml:"paths" json:"paths"`
+	Message string   `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// DefaultCommitGroupMessage is the message of commit groups without their own
+const DefaultCommitGroupMessage = "{message} ({group})"
+
+// ParseCommitGroupSpecs parses groups given as name=pattern,pattern. A group
+// named like one of configured replaces its paths and keeps its message.
+func ParseCommitGroupSpecs(specs []string, configured []CommitGroup) ([]CommitGroup, error) {
+	groups := append([]CommitGroup{}, configured...)
+	for _, spec := range specs {
+		name, patterns, ok := strings.Cut(spec, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.TrimSpace(patterns) == "" {
+			return nil, errors.Errorf("invalid commit group '%s' (expected name=pattern,pattern)", spec)
+		}
+		var paths []string
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				paths = append(paths, pattern)
+			}
+		}
+
+		replaced := false
+		for i := range groups {
+			if groups[i].Name == name {
+				groups[i].Paths = paths
+				replaced = true
+			}
+		}
+		if !replaced {
+			groups = append(groups, CommitGroup{Name: name, Paths: paths})
+		}
+	}
+	return groups, nil
+}
+
+// matches reports whether a path relative to the repository root is in the group
+func (g CommitGroup) matches(relPath string) bool {
+	rules := &IgnoreRules{}
+	for _, line := range g.Paths {
+		if pattern, ok := parseIgnorePattern(line); ok {
+			rules.patterns = append(rules.patterns, pattern)
+		}
+	}
+	return rules.Match(relPath)
+}
+
+// messageFor returns the message of the group's commit
+func (g CommitGroup) messageFor(message string) string {
+	template := g.Message
+	if template == "" {
+		template = DefaultCommitGroupMessage
+	}
+	template = strings.ReplaceAll(template, "{group}", g.Name)
+	return strings.ReplaceAll(template, "{message}", strings.TrimRight(message, "\n"))
+}
+
+// CommitGroupFor returns the index of the first group a path belongs to, or
+// len(groups) for paths in no group, which are committed last with the plain message
+func CommitGroupFor(relPath string, groups []CommitGroup) int {
+	for i, group := range groups {
+		if group.matches(relPath) {
+			return i
+		}
+	}
+	return len(groups)
+}
+
+// commitSplit commits the staged changes of a repository as one commit per
+// group, in group order, followed by a commit of the files in no group.
+// message is the expanded message of the repository and trailer is appended
+// to every commit. Partially staged files are committed as staged: the index
+// is saved as a tree and restored before every commit.
+func (gops *GitOperations) commitSplit(ctx context.Context, repoName, repoPath string, operation *CommitOperation, message, trailer string) (int, error) {
+	staged, err := gitOutput(ctx, repoPath, "diff", "--cached", "--name-only", "--no-renames")
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list staged files in %s", repoName)
+	}
+	files := make([][]string, len(operation.Groups)+1)
+	for _, file := range splitLines(staged) {
+		i := CommitGroupFor(file, operation.Groups)
+		files[i] = append(files[i], file)
+	}
+
+	index, err := gitOutput(ctx, repoPath, "write-tree")
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to save the index of %s", repoName)
+	}
+
+	commits := 0
+	for i, groupFiles := range files {
+		if len(groupFiles) == 0 {
+			continue
+		}
+		groupMessage := message
+		if i < len(operation.Groups) {
+			groupMessage = ExpandCommitMessage(operation.Groups[i].messageFor(message), gops.workspace, repoName)
+		}
+
+		// Stage exactly this group: the saved index minus the files of later groups
+		if _, err := gitOutput(ctx, repoPath, "read-tree", index); err != nil {
+			return commits, errors.Wrapf(err, "failed to restore the index of %s", repoName)
+		}
+		var later []string
+		for _, laterFiles := range files[i+1:] {
+			for _, file := range laterFiles {
+				later = append(later, ":(literal)"+file)
+			}
+		}
+		if len(later) > 0 {
+			if _, err := gitOutput(ctx, repoPath, append([]string{"reset", "-q", "--"}, later...)...); err != nil {
+				return commits, errors.Wrapf(err, "failed to stage the %s files of %s", groupName(operation.Groups, i), repoName)
+			}
+		}
+
+		if err := gops.commitRepository(ctx, repoName, repoPath, operation.commitArgs(repoName, groupMessage+trailer)); err != nil {
+			// Leave the remaining changes staged as they were
+			_, _ = gitOutput(ctx, repoPath, "read-tree", index)
+			return commits, err
+		}
+		commits++
+	}
+	return commits, nil
+}
+
+// groupName names a group for messages, the files in no group included
+func groupName(groups []CommitGroup, i int) string {
+	if i < len(groups) {
+		return fmt.Sprintf("'%s'", groups[i].Name)
+	}
+	return "remaining"
+}
@@ -0,0 +1,180 @@
+package wsm
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultCommitTemplates are the built-in templates of `wsm commit --template`
+var DefaultCommitTemplates = map[string]string{
+	"feature":  "feat: add new feature",
+	"fix":      "fix: resolve issue",
+	"docs":     "docs: update documentation",
+	"style":    "style: formatting changes",
+	"refactor": "refactor: code restructuring",
+	"test":     "test: add or update tests",
+	"chore":    "chore: maintenance tasks",
+}
+
+// DefaultConventionalTypes are the commit types offered by the conventional commit builder
+var DefaultConventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// CommitTemplates holds commit message templates, read from commit-templates.yaml
+// in the workspace-manager config directory:
+//
+//	templates:
+//	  bump: "chore({repo}): bump dependencies for {workspace}"
+//	  wip: "wip: {branch}"
+//	  ticket: "{ticket}: {message}"
+//	types: [feat, fix, docs, chore, security]
+//	groups:
+//	  - name: docs
+//	    paths: ["docs/**", "*.md"]
+//	    message: "docs: {message}"
+//
+// Templates extend and override the built-in ones. Types replace the commit
+// types offered by `wsm commit --conventional`. Groups are the path groups
+// `wsm commit --split` commits separately.
+type CommitTemplates struct {
+	Templates map[string]string `yaml:"templates,omitempty"`
+	Types     []string          `yaml:"types,omitempty"`
+	Groups    []CommitGroup     `yaml:"groups,omitempty"`
+}
+
+// GetCommitTemplatesPath returns the path of the commit templates file
+func GetCommitTemplatesPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "commit-templates.yaml"), nil
+}
+
+// LoadCommitTemplates returns the built-in templates merged with the configured ones
+func LoadCommitTemplates() (*CommitTemplates, error) {
+	templates := &CommitTemplates{Templates: map[string]string{}}
+	for name, template := range DefaultCommitTemplates {
+		templates.Templates[name] = template
+	}
+
+	templatesPath, err := GetCommitTemplatesPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(templatesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read commit templates")
+	}
+	if err == nil {
+		var configured CommitTemplates
+		if err := yaml.Unmarshal(data, &configured); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse commit templates %s", templatesPath)
+		}
+		for name, template := range configured.Templates {
+			templates.Templates[name] = template
+		}
+		templates.Types = configured.Types
+		templates.Groups = configured.Groups
+	}
+
+	if len(templates.Types) == 0 {
+		templates.Types = DefaultConventionalTypes
+	}
+	return templates, nil
+}
+
+// Names returns the sorted template names
+func (t *CommitTemplates) Names() []string {
+	names := make([]string, 0, len(t.Templates))
+	for name := range t.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Message returns the message of a template; unknown names are used as the message itself
+func (t *CommitTemplates) Message(name string) string {
+	if template, ok := t.Templates[name]; ok {
+		return template
+	}
+	return name
+}
+
+// ExpandCommitMessage replaces the {workspace}, {branch}, {ticket}, {ticket_url} and {repo} placeholders of a commit message
+func ExpandCommitMessage(message string, workspace *Workspace, repoName string) string {
+	if !strings.Contains(message, "{") {
+		return message
+	}
+	return strings.NewReplacer(
+		"{workspace}", workspace.Name,
+		"{branch}", workspace.Branch,
+		"{ticket}", workspace.Ticket,
+		"{ticket_url}", workspace.TicketURL,
+		"{repo}", repoName,
+	).Replace(message)
+}
+
+// ConventionalCommit is a commit message following the Conventional Commits format:
+//
+//	type(scope)!: summary
+//
+//	body
+//
+//	BREAKING CHANGE: description
+type ConventionalCommit struct {
+	Type     string
+	Scope    string
+	Summary  string
+	Body     string
+	Breaking bool
+	// BreakingNote describes the breaking change; the summary is used when empty
+	BreakingNote string
+}
+
+// Validate checks the fields required for a well-formed header
+func (c *ConventionalCommit) Validate() error {
+	if strings.TrimSpace(c.Type) == "" {
+		return errors.New("commit type is required")
+	}
+	if strings.ContainsAny(c.Type, " ():!") {
+		return errors.Errorf("invalid commit type '%s'", c.Type)
+	}
+	if strings.ContainsAny(c.Scope, "()\n") {
+		return errors.Errorf("invalid commit scope '%s'", c.Scope)
+	}
+	if strings.TrimSpace(c.Summary) == "" {
+		return errors.New("commit summary is required")
+	}
+	return nil
+}
+
+// String renders the commit message
+func (c *ConventionalCommit) String() string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(c.Type))
+	if scope := strings.TrimSpace(c.Scope); scope != "" {
+		b.WriteString("(" + scope + ")")
+	}
+	if c.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": " + strings.TrimSpace(c.Summary))
+
+	if body := strings.TrimSpace(c.Body); body != "" {
+		b.WriteString("\n\n" + body)
+	}
+	if c.Breaking {
+		note := strings.TrimSpace(c.BreakingNote)
+		if note == "" {
+			note = strings.TrimSpace(c.Summary)
+		}
+		b.WriteString("\n\nBREAKING CHANGE: " + note)
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const lastCommitFile = "last-commit.json"
+
+// WorkspaceCommit records the commits of the last `wsm commit` of a workspace
+// in <workspace>/.wsm/, so `wsm commit undo` can find exactly them
+type WorkspaceCommit struct {
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message"`
+	ChangeID string    `json:"change_id,omitempty"`
+	// Repositories maps repository names to their commits
+	Repositories map[string]RecordedCommit `json:"repositories"`
+}
+
+// RecordedCommit is the HEAD of a repository before and after a workspace commit.
+// Split commits make several commits between Parent and Head.
+type RecordedCommit struct {
+	Parent string `json:"parent"`
+	Head   string `json:"head"`
+}
+
+// CommitUndo is the outcome of undoing the last workspace commit in a repository
+type CommitUndo struct {
+	Repository string `json:"repository"`
+	Head       string `json:"head"`
+	Parent     string `json:"parent"`
+	Undone     bool   `json:"undone"`
+	// Pushed is set when a remote branch contains the commit
+	Pushed  bool   `json:"pushed,omitempty"`
+	Problem string `json:"problem,omitempty"`
+}
+
+func lastCommitPath(workspacePath string) string {
+	return filepath.Join(workspacePath, ".wsm", lastCommitFile)
+}
+
+// LoadLastCommit returns the last recorded commit of a workspace
+func LoadLastCommit(workspace *Workspace) (*WorkspaceCommit, error) {
+	data, err := os.ReadFile(lastCommitPath(workspace.Path))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("no commit of workspace '%s' to undo", workspace.Name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the last workspace commit")
+	}
+	var commit WorkspaceCommit
+	if err := json.Unmarshal(data, &commit); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the last workspace commit")
+	}
+	return &commit, nil
+}
+
+// save writes the record atomically
+func (c *WorkspaceCommit) save(workspacePath string) error {
+	recordPath := lastCommitPath(workspacePath)
+	if err := os.MkdirAll(filepath.Dir(recordPath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create .wsm directory")
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the workspace commit")
+	}
+	tmpPath := recordPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write the workspace commit")
+	}
+	return errors.Wrap(os.Rename(tmpPath, recordPath), "failed to replace the workspace commit")
+}
+
+// UndoLastCommit soft-resets every repository to its HEAD before the last
+// workspace commit, leaving the committed changes staged. All repositories are
+// checked first and nothing is reset when any check fails: HEAD must still be
+// the recorded commit and, unless force is set, no remote branch may contain
+// it. With dryRun only the checks run.
+func UndoLastCommit(ctx context.Context, workspace *Workspace, force, dryRun bool) ([]CommitUndo, error) {
+	record, err := LoadLastCommit(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var undos []CommitUndo
+	blocked := false
+	for _, repoName := range sortedCommitRepositories(record) {
+		recorded := record.Repositories[repoName]
+		undo := CommitUndo{Repository: repoName, Head: recorded.Head, Parent: recorded.Parent}
+		undo.Problem, undo.Pushed = checkCommitUndo(ctx, filepath.Join(workspace.Path, repoName), recorded, record.ChangeID)
+		if undo.Problem == "" && undo.Pushed && !force {
+			undo.Problem = "already pushed, undo with --force and force-push afterwards"
+		}
+		if undo.Problem != "" {
+			blocked = true
+		}
+		undos = append(undos, undo)
+	}
+	if blocked {
+		return undos, errors.New("the last workspace commit cannot be undone")
+	}
+	if dryRun {
+		return undos, nil
+	}
+
+	for i := range undos {
+		repoPath := filepath.Join(workspace.Path, undos[i].Repository)
+		if _, err := gitOutput(ctx, repoPath, "reset", "--soft", undos[i].Parent); err != nil {
+			undos[i].Problem = err.Error()
+			return undos, errors.Wrapf(err, "failed to undo the commit in %s", undos[i].Repository)
+		}
+		undos[i].Undone = true
+	}
+
+	if err := os.Remove(lastCommitPath(workspace.Path)); err != nil && !os.IsNotExist(err) {
+		return undos, errors.Wrap(err, "failed to remove the last workspace commit record")
+	}
+	return undos, nil
+}
+
+// checkCommitUndo returns why a repository's commit cannot be undone, if
+// anything prevents it, and whether a remote branch contains the commit
+func checkCommitUndo(ctx context.Context, repoPath string, recorded RecordedCommit, changeID string) (string, bool) {
+	if recorded.Parent == "" {
+		return "the commit is the first of the repository", false
+	}
+	head, err := gitOutput(ctx, repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Sprintf("cannot read HEAD: %v", err), false
+	}
+	if head != recorded.Head {
+		return fmt.Sprintf("HEAD moved to %s since the commit", shortCommit(head)), false
+	}
+	if changeID != "" {
+		body, err := gitOutput(ctx, repoPath, "log", "-1", "--format=%B", head)
+		if err != nil || !strings.Contains(body, ChangeIDTrailer+": "+changeID) {
+			return fmt.Sprintf("HEAD has no %s trailer %s", ChangeIDTrailer, changeID), false
+		}
+	}
+	if operation, _ := NewStatusChecker().getInProgressOperation(ctx, repoPath); operation != "" {
+		return fmt.Sprintf("%s in progress", operation), false
+	}
+	remotes, err := gitOutput(ctx, repoPath, "branch", "-r", "--contains", head)
+	return "", err == nil && remotes != ""
+}
+
+// recordWorkspaceCommit stores the commits just made in the workspace,
+// replacing the previous record
+func recordWorkspaceCommit(ctx context.Context, workspace *Workspace, operation *CommitOperation, parents map[string]string, repoNames []string) error {
+	record := &WorkspaceCommit{
+		Time:         time.Now(),
+		Message:      operation.Message,
+		ChangeID:     operation.ChangeID,
+		Repositories: make(map[string]RecordedCommit),
+	}
+	for _, repoName := range repoNames {
+		head, err := gitOutput(ctx, filepath.Join(workspace.Path, repoName), "rev-parse", "HEAD")
+		if err != nil {
+			return errors.Wrapf(err, "failed to read HEAD of %s", repoName)
+		}
+		record.Repositories[repoName] = RecordedCommit{Parent: parents[repoName], Head: head}
+	}
+	return record.save(workspace.Path)
+}
+
+func sortedCommitRepositories(record *WorkspaceCommit) []string {
+	names := make([]string, 0, len(record.Repositories))
+	for repoName := range record.Repositories {
+		names = append(names, repoName)
+	}
+	sort.Strings(names)
+	return names
+}
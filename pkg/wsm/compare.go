@@ -0,0 +1,185 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxCompareCommits caps the commits listed per side of a repository comparison
+const maxCompareCommits = 20
+
+// FileComparison is the change to one file between two workspaces
+type FileComparison struct {
+	Path string `json:"path"`
+	// Insertions and Deletions are -1 for binary files
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+}
+
+// RepositoryComparison compares the worktrees of a repository present in two workspaces
+type RepositoryComparison struct {
+	Repository string `json:"repository"`
+	BranchA    string `json:"branch_a"`
+	BranchB    string `json:"branch_b"`
+	HeadA      string `json:"head_a"`
+	HeadB      string `json:"head_b"`
+	Identical  bool   `json:"identical"`
+	// AheadA and AheadB count the commits only on each side; CommitsA and
+	// CommitsB list them, newest first, up to maxCompareCommits
+	AheadA   int      `json:"ahead_a"`
+	AheadB   int      `json:"ahead_b"`
+	CommitsA []string `json:"commits_a,omitempty"`
+	CommitsB []string `json:"commits_b,omitempty"`
+	// Files is the diff stat from A's head to B's head
+	Files      []FileComparison `json:"files,omitempty"`
+	Insertions int              `json:"insertions"`
+	Deletions  int              `json:"deletions"`
+	// DirtyA and DirtyB tell that a worktree has uncommitted changes, which the
+	// comparison leaves out
+	DirtyA bool   `json:"dirty_a,omitempty"`
+	DirtyB bool   `json:"dirty_b,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WorkspaceComparison compares the committed work of two workspaces
+type WorkspaceComparison struct {
+	A            string                 `json:"a"`
+	B            string                 `json:"b"`
+	Repositories []RepositoryComparison `json:"repositories"`
+	OnlyInA      []string               `json:"only_in_a"`
+	OnlyInB      []string               `json:"only_in_b"`
+}
+
+// CompareWorkspaces compares the branches checked out in two workspaces, for
+// instance two forks where different approaches were tried. Repositories in
+// both are compared commit by commit and file by file; the others are listed
+// per side. Only committed work is compared.
+func CompareWorkspaces(ctx context.Context, a, b *Workspace) (*WorkspaceComparison, error) {
+	comparison := &WorkspaceComparison{
+		A:            a.Name,
+		B:            b.Name,
+		Repositories: []RepositoryComparison{},
+		OnlyInA:      []string{},
+		OnlyInB:      []string{},
+	}
+
+	inB := map[string]bool{}
+	for _, repo := range b.Repositories {
+		inB[repo.Name] = true
+	}
+	inA := map[string]bool{}
+	for _, repo := range a.Repositories {
+		inA[repo.Name] = true
+		if !inB[repo.Name] {
+			comparison.OnlyInA = append(comparison.OnlyInA, repo.Name)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		comparison.Repositories = append(comparison.Repositories, compareWorktrees(ctx, repo.Name,
+			filepath.Join(a.Path, repo.Name), filepath.Join(b.Path, repo.Name)))
+	}
+	for _, repo := range b.Repositories {
+		if !inA[repo.Name] {
+			comparison.OnlyInB = append(comparison.OnlyInB, repo.Name)
+		}
+	}
+
+	sort.Slice(comparison.Repositories, func(i, j int) bool {
+		return comparison.Repositories[i].Repository < comparison.Repositories[j].Repository
+	})
+	sort.Strings(comparison.OnlyInA)
+	sort.Strings(comparison.OnlyInB)
+	return comparison, nil
+}
+
+// compareWorktrees compares the heads of two worktrees of a repository. Git
+// runs in A's worktree, which shares its objects with B's when both are
+// worktrees of the same clone.
+func compareWorktrees(ctx context.Context, name, pathA, pathB string) RepositoryComparison {
+	result := RepositoryComparison{Repository: name}
+
+	var err error
+	if result.HeadA, err = gitOutput(ctx, pathA, "rev-parse", "HEAD"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.HeadB, err = gitOutput(ctx, pathB, "rev-parse", "HEAD"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.BranchA, _ = gitOutput(ctx, pathA, "rev-parse", "--abbrev-ref", "HEAD")
+	result.BranchB, _ = gitOutput(ctx, pathB, "rev-parse", "--abbrev-ref", "HEAD")
+	if status, err := gitOutput(ctx, pathA, "status", "--porcelain"); err == nil && status != "" {
+		result.DirtyA = true
+	}
+	if status, err := gitOutput(ctx, pathB, "status", "--porcelain"); err == nil && status != "" {
+		result.DirtyB = true
+	}
+
+	if result.HeadA == result.HeadB {
+		result.Identical = true
+		return result
+	}
+	if _, err := gitOutput(ctx, pathA, "cat-file", "-e", result.HeadB+"^{commit}"); err != nil {
+		result.Error = fmt.Sprintf("commit %s of the second workspace is not in the first one's repository", result.HeadB)
+		return result
+	}
+
+	counts, err := gitOutput(ctx, pathA, "rev-list", "--left-right", "--count", result.HeadA+"..."+result.HeadB)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if fields := strings.Fields(counts); len(fields) == 2 {
+		result.AheadA, _ = strconv.Atoi(fields[0])
+		result.AheadB, _ = strconv.Atoi(fields[1])
+	}
+	if result.CommitsA, err = compareCommits(ctx, pathA, result.HeadB, result.HeadA); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if result.CommitsB, err = compareCommits(ctx, pathA, result.HeadA, result.HeadB); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	numstat, err := gitOutput(ctx, pathA, "diff", "--numstat", result.HeadA, result.HeadB)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, line := range splitLines(numstat) {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		file := FileComparison{Path: parts[2], Insertions: -1, Deletions: -1}
+		if added, err := strconv.Atoi(parts[0]); err == nil {
+			file.Insertions = added
+			result.Insertions += added
+		}
+		if deleted, err := strconv.Atoi(parts[1]); err == nil {
+			file.Deletions = deleted
+			result.Deletions += deleted
+		}
+		result.Files = append(result.Files, file)
+	}
+	return result
+}
+
+// compareCommits lists the commits reachable from head but not from base
+func compareCommits(ctx context.Context, dir, base, head string) ([]string, error) {
+	out, err := gitOutput(ctx, dir, "log", "--format=%h %s", fmt.Sprintf("--max-count=%d", maxCompareCommits), base+".."+head)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list commits of %s", head)
+	}
+	return splitLines(out), nil
+}
@@ -0,0 +1,75 @@
+package wsm
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultBranchTimeout bounds the GitHub lookup of a default branch
+const defaultBranchTimeout = 30 * time.Second
+
+// DefaultBranchFor returns the default branch of a repository's base remote:
+// the one detected when its worktree was created, or the one git knows now
+func (w *Workspace) DefaultBranchFor(ctx context.Context, repoName, repoPath string) string {
+	if branch, ok := w.RepositoryDefaultBranches[repoName]; ok && branch != "" {
+		return branch
+	}
+	branch, _ := GetRemoteDefaultBranch(ctx, repoPath, w.BaseRemoteOf(repoName))
+	return branch
+}
+
+// BaseRefFor returns the remote-tracking ref of a repository's default
+// branch, e.g. "upstream/develop"
+func (w *Workspace) BaseRefFor(ctx context.Context, repoName, repoPath string) string {
+	return w.BaseRemoteOf(repoName) + "/" + w.DefaultBranchFor(ctx, repoName, repoPath)
+}
+
+// recordDefaultBranch stores the default branch of a newly created worktree's
+// base remote, so later commands do not have to guess it
+func (wm *WorkspaceManager) recordDefaultBranch(ctx context.Context, workspace *Workspace, repo Repository) {
+	branch := detectDefaultBranch(ctx, repo, filepath.Join(workspace.Path, repo.Name), workspace.BaseRemoteOf(repo.Name))
+	if branch == "" {
+		subsystemLogger(logSubsystemGit).Debug().Str("repo", repo.Name).Msg("Could not detect the default branch")
+		return
+	}
+	if workspace.RepositoryDefaultBranches == nil {
+		workspace.RepositoryDefaultBranches = make(map[string]string)
+	}
+	workspace.RepositoryDefaultBranches[repo.Name] = branch
+}
+
+// detectDefaultBranch finds the default branch of a remote: the remote HEAD
+// git recorded when cloning, then the repository's default branch on GitHub
+// through gh. It is empty when neither is known.
+func detectDefaultBranch(ctx context.Context, repo Repository, repoPath, remote string) string {
+	if ref, err := gitOutput(ctx, repoPath, "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD"); err == nil {
+		if branch, ok := strings.CutPrefix(ref, remote+"/"); ok && branch != "" {
+			return branch
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	url, err := gitOutput(ctx, repoPath, "remote", "get-url", remote)
+	if err != nil {
+		return ""
+	}
+	githubRepo, err := GitHubRepository(url)
+	if err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultBranchTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "gh", "repo", "view", githubRepo, "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name")
+	out, err := cmd.Output()
+	if err != nil {
+		subsystemLogger(logSubsystemGit).Debug().Err(err).Str("repo", repo.Name).Str("github", githubRepo).Msg("gh could not tell the default branch")
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,167 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ToolRequirement describes an external tool used by workspace-manager
+type ToolRequirement struct {
+	Name        string
+	VersionArgs []string
+	MinVersion  string
+	Required    bool
+	Purpose     string
+	InstallHint string
+}
+
+// ToolStatus is the result of checking an external tool
+type ToolStatus struct {
+	Name         string `json:"name"`
+	Path         string `json:"path,omitempty"`
+	Version      string `json:"version,omitempty"`
+	MinVersion   string `json:"min_version,omitempty"`
+	Available    bool   `json:"available"`
+	MeetsMinimum bool   `json:"meets_minimum"`
+	Required     bool   `json:"required"`
+	Purpose      string `json:"purpose"`
+	Message      string `json:"message,omitempty"`
+}
+
+// OK reports whether the tool is installed and recent enough
+func (ts ToolStatus) OK() bool {
+	return ts.Available && ts.MeetsMinimum
+}
+
+// ToolRequirements lists the external tools used by workspace-manager
+var ToolRequirements = []ToolRequirement{
+	{
+		Name:        "git",
+		VersionArgs: []string{"--version"},
+		MinVersion:  "2.38",
+		Required:    true,
+		Purpose:     "worktrees and all repository operations",
+		InstallHint: "install git 2.38 or newer from https://git-scm.com/downloads",
+	},
+	{
+		Name:        "bash",
+		VersionArgs: []string{"--version"},
+		Purpose:     "workspace setup scripts",
+		InstallHint: "install bash to run .wsm/setup.sh and setup.d scripts",
+	},
+	{
+		Name:        "tmux",
+		VersionArgs: []string{"-V"},
+		Purpose:     "wsm tmux sessions",
+		InstallHint: "install tmux (e.g. 'brew install tmux' or 'apt install tmux')",
+	},
+	{
+		Name:        "gh",
+		VersionArgs: []string{"--version"},
+		Purpose:     "wsm pr and GitHub-aware push detection",
+		InstallHint: "install the GitHub CLI from https://cli.github.com/ and run 'gh auth login'",
+	},
+	{
+		Name:        "git-lfs",
+		VersionArgs: []string{"--version"},
+		Purpose:     "checking out Git LFS files in worktrees",
+		InstallHint: "install Git LFS from https://git-lfs.com/",
+	},
+	{
+		Name:        "aws",
+		VersionArgs: []string{"--version"},
+		Purpose:     "s3 remote state backend",
+		InstallHint: "install the AWS CLI from https://aws.amazon.com/cli/ and configure credentials",
+	},
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// CheckDependencies checks all known external tools
+func CheckDependencies(ctx context.Context) []ToolStatus {
+	var statuses []ToolStatus
+	for _, req := range ToolRequirements {
+		statuses = append(statuses, checkTool(ctx, req))
+	}
+	return statuses
+}
+
+// CheckTool checks a single known external tool by name
+func CheckTool(ctx context.Context, name string) ToolStatus {
+	for _, req := range ToolRequirements {
+		if req.Name == name {
+			return checkTool(ctx, req)
+		}
+	}
+	return checkTool(ctx, ToolRequirement{Name: name, VersionArgs: []string{"--version"}})
+}
+
+// RequireTool returns an actionable error if a tool is missing or too old
+func RequireTool(ctx context.Context, name string) error {
+	status := CheckTool(ctx, name)
+	if status.OK() {
+		return nil
+	}
+	return errors.New(status.Message)
+}
+
+func checkTool(ctx context.Context, req ToolRequirement) ToolStatus {
+	status := ToolStatus{
+		Name:       req.Name,
+		MinVersion: req.MinVersion,
+		Required:   req.Required,
+		Purpose:    req.Purpose,
+	}
+
+	path, err := exec.LookPath(req.Name)
+	if err != nil {
+		status.Message = fmt.Sprintf("%s is not installed or not in PATH (needed for %s); %s", req.Name, req.Purpose, req.InstallHint)
+		return status
+	}
+	status.Path = path
+	status.Available = true
+
+	cmd := exec.CommandContext(ctx, req.Name, req.VersionArgs...)
+	versionOutput, err := cmd.Output()
+	if err == nil {
+		if match := versionPattern.FindString(string(versionOutput)); match != "" {
+			status.Version = match
+		}
+	}
+
+	status.MeetsMinimum = true
+	if req.MinVersion != "" && status.Version != "" && compareVersions(status.Version, req.MinVersion) < 0 {
+		status.MeetsMinimum = false
+		status.Message = fmt.Sprintf("%s %s is older than the required %s (needed for %s); %s", req.Name, status.Version, req.MinVersion, req.Purpose, req.InstallHint)
+	}
+
+	return status
+}
+
+// compareVersions compares dotted version strings numerically
+func compareVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var av, bv int
+		if i < len(aParts) {
+			av, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bv, _ = strconv.Atoi(bParts[i])
+		}
+		if av != bv {
+			if av < bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
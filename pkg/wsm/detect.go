@@ -0,0 +1,110 @@
+package wsm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotInWorkspace is returned when a directory belongs to no workspace
+var ErrNotInWorkspace = errors.New("not in a workspace directory")
+
+// resolvePath returns the absolute path of path with symlinks resolved. Paths
+// that do not exist (anymore) are only made absolute.
+func resolvePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// PathWithin reports whether dir is root or one of its subdirectories, once
+// symlinks are resolved. Unlike a string prefix, /ws/foo is not within /ws/fo.
+func PathWithin(dir, root string) bool {
+	return isPathWithin(resolvePath(dir), resolvePath(root))
+}
+
+// FindWorkspaceRoot walks up from dir to the nearest directory holding a
+// .wsm/wsm.json marker
+func FindWorkspaceRoot(dir string) (string, bool) {
+	for current := resolvePath(dir); ; {
+		if info, err := os.Stat(filepath.Join(current, ".wsm", "wsm.json")); err == nil && info.Mode().IsRegular() {
+			return current, true
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", false
+		}
+		current = parent
+	}
+}
+
+// DetectWorkspace returns the workspace containing dir. Ancestors of dir are
+// compared segment by segment with the resolved workspace paths, the nearest
+// one winning for nested workspaces; a wsm.json marker found on the way
+// identifies a workspace whose directory has moved since its configuration
+// was saved.
+func DetectWorkspace(dir string, workspaces []Workspace) (*Workspace, error) {
+	paths := make([]string, len(workspaces))
+	for i := range workspaces {
+		paths[i] = resolvePath(workspaces[i].Path)
+	}
+
+	for current := resolvePath(dir); ; {
+		for i := range workspaces {
+			if paths[i] == current {
+				return &workspaces[i], nil
+			}
+		}
+		if name := markerWorkspaceName(current); name != "" {
+			for i := range workspaces {
+				if workspaces[i].Name == name {
+					return &workspaces[i], nil
+				}
+			}
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, ErrNotInWorkspace
+		}
+		current = parent
+	}
+}
+
+// markerWorkspaceName returns the workspace name recorded in dir/.wsm/wsm.json
+func markerWorkspaceName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".wsm", "wsm.json"))
+	if err != nil {
+		return ""
+	}
+	var metadata WorkspaceMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return ""
+	}
+	return metadata.Name
+}
+
+// RepositoryAt returns the repository whose worktree contains dir and the path
+// of dir relative to the worktree
+func (w *Workspace) RepositoryAt(dir string) (Repository, string, bool) {
+	dir = resolvePath(dir)
+	for _, repo := range w.Repositories {
+		worktreePath := resolvePath(filepath.Join(w.Path, repo.Name))
+		if !isPathWithin(dir, worktreePath) {
+			continue
+		}
+		rel, err := filepath.Rel(worktreePath, dir)
+		if err != nil {
+			continue
+		}
+		return repo, rel, true
+	}
+	return Repository{}, "", false
+}
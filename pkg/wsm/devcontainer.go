@@ -0,0 +1,325 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// devcontainerDir holds the generated files, where editors look for them
+const devcontainerDir = ".devcontainer"
+
+// Images and features of the generated dev containers
+const (
+	devcontainerBaseImage = "mcr.microsoft.com/devcontainers/base:bookworm"
+	devcontainerGoImage   = "mcr.microsoft.com/devcontainers/go:1-%s-bookworm"
+	devcontainerNode      = "ghcr.io/devcontainers/features/node:1"
+	devcontainerPython    = "ghcr.io/devcontainers/features/python:1"
+	devcontainerRust      = "ghcr.io/devcontainers/features/rust:1"
+	devcontainerGo        = "ghcr.io/devcontainers/features/go:1"
+)
+
+// Toolchains are the language versions the repositories of a workspace ask
+// for; an empty version means the language is not used, "latest" that it is
+// used without a pinned version
+type Toolchains struct {
+	Go     string `json:"go,omitempty"`
+	Node   string `json:"node,omitempty"`
+	Python string `json:"python,omitempty"`
+	Rust   string `json:"rust,omitempty"`
+}
+
+// DevcontainerOptions controls GenerateDevcontainer
+type DevcontainerOptions struct {
+	// Compose generates a docker-compose.yml the devcontainer.json points to
+	Compose bool
+	// Image replaces the image selected from the toolchains
+	Image string
+}
+
+// DevcontainerFile is a file generated for a dev container
+type DevcontainerFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Devcontainer is the generated dev container of a workspace
+type Devcontainer struct {
+	Image      string             `json:"image"`
+	Toolchains Toolchains         `json:"toolchains"`
+	Mounts     []string           `json:"mounts"`
+	Files      []DevcontainerFile `json:"files"`
+}
+
+// devcontainerConfig is the subset of devcontainer.json wsm writes
+type devcontainerConfig struct {
+	Name              string                    `json:"name"`
+	Image             string                    `json:"image,omitempty"`
+	DockerComposeFile string                    `json:"dockerComposeFile,omitempty"`
+	Service           string                    `json:"service,omitempty"`
+	Features          map[string]map[string]any `json:"features,omitempty"`
+	WorkspaceMount    string                    `json:"workspaceMount,omitempty"`
+	WorkspaceFolder   string                    `json:"workspaceFolder"`
+	Mounts            []string                  `json:"mounts,omitempty"`
+	ContainerEnv      map[string]string         `json:"containerEnv,omitempty"`
+}
+
+// GenerateDevcontainer builds a dev container for a workspace: a
+// .devcontainer/devcontainer.json, and with options.Compose a
+// docker-compose.yml, mounting the workspace at its own path. The .git
+// directories of the source clones are mounted at their paths as well, since
+// worktrees refer to them by absolute path. The image is selected from the Go
+// version of the repositories, other toolchains are added as features.
+func GenerateDevcontainer(ctx context.Context, workspace *Workspace, options DevcontainerOptions) (*Devcontainer, error) {
+	toolchains := DetectToolchains(workspace)
+	image, features := devcontainerImage(toolchains)
+	if options.Image != "" {
+		image = options.Image
+	}
+
+	gitDirs, err := worktreeGitDirs(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	// The workspace keeps its host path in the container, and so do the variables
+	environment := workspaceEnvironment(workspace)
+
+	config := devcontainerConfig{
+		Name:            "wsm-" + workspace.Name,
+		Features:        features,
+		WorkspaceFolder: workspace.Path,
+		ContainerEnv:    environment,
+	}
+	devcontainer := &Devcontainer{Image: image, Toolchains: toolchains, Mounts: append([]string{workspace.Path}, gitDirs...)}
+	dir := filepath.Join(workspace.Path, devcontainerDir)
+
+	if options.Compose {
+		config.DockerComposeFile = "docker-compose.yml"
+		config.Service = "workspace"
+		volumes := make([]string, 0, len(devcontainer.Mounts))
+		for _, mount := range devcontainer.Mounts {
+			volumes = append(volumes, mount+":"+mount)
+		}
+		compose := map[string]any{
+			"name": "wsm-" + workspace.Name,
+			"services": map[string]any{
+				"workspace": map[string]any{
+					"image":       image,
+					"command":     "sleep infinity",
+					"working_dir": workspace.Path,
+					"volumes":     volumes,
+					"environment": environment,
+				},
+			},
+		}
+		data, err := yaml.Marshal(compose)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal docker-compose.yml")
+		}
+		devcontainer.Files = append(devcontainer.Files, DevcontainerFile{Path: filepath.Join(dir, "docker-compose.yml"), Content: string(data)})
+	} else {
+		config.Image = image
+		config.WorkspaceMount = bindMount(workspace.Path)
+		for _, gitDir := range gitDirs {
+			config.Mounts = append(config.Mounts, bindMount(gitDir))
+		}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal devcontainer.json")
+	}
+	devcontainer.Files = append([]DevcontainerFile{{Path: filepath.Join(dir, "devcontainer.json"), Content: string(data) + "\n"}}, devcontainer.Files...)
+	return devcontainer, nil
+}
+
+// WriteDevcontainer writes the files of a dev container, refusing to replace
+// existing files unless force is set
+func WriteDevcontainer(devcontainer *Devcontainer, force bool) error {
+	if !force {
+		for _, file := range devcontainer.Files {
+			if _, err := os.Stat(file.Path); err == nil {
+				return errors.Errorf("%s already exists, use --force to replace it", file.Path)
+			}
+		}
+	}
+	for _, file := range devcontainer.Files {
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create %s", filepath.Dir(file.Path))
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", file.Path)
+		}
+	}
+	return nil
+}
+
+func bindMount(path string) string {
+	return fmt.Sprintf("source=%s,target=%s,type=bind", path, path)
+}
+
+// worktreeGitDirs returns the .git directories of the clones the worktrees of
+// a workspace belong to
+func worktreeGitDirs(ctx context.Context, workspace *Workspace) ([]string, error) {
+	var dirs []string
+	for _, repo := range workspace.Repositories {
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		commonDir, err := gitOutput(ctx, repoPath, "rev-parse", "--git-common-dir")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find the git directory of %s", repo.Name)
+		}
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(repoPath, commonDir)
+		}
+		// A clone inside the workspace is mounted with it
+		if PathWithin(commonDir, workspace.Path) {
+			continue
+		}
+		dirs = appendUnique(dirs, filepath.Clean(commonDir))
+	}
+	return dirs, nil
+}
+
+// devcontainerImage selects the image for the toolchains, and the features
+// adding the toolchains the image lacks
+func devcontainerImage(toolchains Toolchains) (string, map[string]map[string]any) {
+	features := map[string]map[string]any{}
+	image := devcontainerBaseImage
+	if toolchains.Go != "" {
+		if version := semver.MajorMinor("v" + toolchains.Go); version != "" {
+			image = fmt.Sprintf(devcontainerGoImage, strings.TrimPrefix(version, "v"))
+		} else {
+			features[devcontainerGo] = map[string]any{"version": "latest"}
+		}
+	}
+	if toolchains.Node != "" {
+		features[devcontainerNode] = map[string]any{"version": toolchains.Node}
+	}
+	if toolchains.Python != "" {
+		features[devcontainerPython] = map[string]any{"version": toolchains.Python}
+	}
+	if toolchains.Rust != "" {
+		features[devcontainerRust] = map[string]any{"version": toolchains.Rust}
+	}
+	return image, features
+}
+
+var (
+	// toolchainVersionPattern finds a version number, e.g. in "v20.11.1" or ">=18"
+	toolchainVersionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+	// rustChannelPattern finds the channel of a rust-toolchain.toml
+	rustChannelPattern = regexp.MustCompile(`(?m)^\s*channel\s*=\s*"([^"]+)"`)
+)
+
+// DetectToolchains reads the toolchain versions of the workspace repositories:
+// go.mod, .nvmrc, .node-version, the engines of package.json,
+// .python-version and rust-toolchain(.toml). When repositories disagree the
+// highest version wins.
+func DetectToolchains(workspace *Workspace) Toolchains {
+	var toolchains Toolchains
+	for _, repo := range workspace.Repositories {
+		root := filepath.Join(workspace.Path, repo.Name)
+		toolchains.Go = newerVersion(toolchains.Go, goVersion(root))
+		toolchains.Node = newerVersion(toolchains.Node, nodeVersion(root))
+		toolchains.Python = newerVersion(toolchains.Python, firstVersion(root, ".python-version"))
+		toolchains.Rust = newerVersion(toolchains.Rust, rustVersion(root))
+	}
+	return toolchains
+}
+
+func goVersion(root string) string {
+	goModPath := filepath.Join(root, "go.mod")
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return ""
+	}
+	modFile, err := modfile.ParseLax(goModPath, data, nil)
+	if err != nil || modFile.Go == nil {
+		return "latest"
+	}
+	version := modFile.Go.Version
+	if modFile.Toolchain != nil {
+		version = newerVersion(version, strings.TrimPrefix(modFile.Toolchain.Name, "go"))
+	}
+	return version
+}
+
+func nodeVersion(root string) string {
+	if version := firstVersion(root, ".nvmrc", ".node-version"); version != "" {
+		return version
+	}
+	data, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err == nil {
+		if version := toolchainVersionPattern.FindString(pkg.Engines["node"]); version != "" {
+			// Node features take major versions
+			return strings.Split(version, ".")[0]
+		}
+	}
+	return "lts"
+}
+
+func rustVersion(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, "rust-toolchain.toml")); err == nil {
+		if channel := rustChannelPattern.FindStringSubmatch(string(data)); channel != nil {
+			if version := toolchainVersionPattern.FindString(channel[1]); version != "" {
+				return version
+			}
+		}
+		return "latest"
+	}
+	if version := firstVersion(root, "rust-toolchain"); version != "" {
+		return version
+	}
+	if _, err := os.Stat(filepath.Join(root, "Cargo.toml")); err == nil {
+		return "latest"
+	}
+	return ""
+}
+
+// firstVersion returns the version number in the first of the files that
+// exists in root
+func firstVersion(root string, files ...string) string {
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			continue
+		}
+		if version := toolchainVersionPattern.FindString(string(data)); version != "" {
+			return version
+		}
+		return "latest"
+	}
+	return ""
+}
+
+// newerVersion returns the higher of two versions; a pinned version wins over
+// "latest" and "lts", which win over none
+func newerVersion(a, b string) string {
+	switch {
+	case a == "" || ((a == "latest" || a == "lts") && toolchainVersionPattern.MatchString(b)):
+		return b
+	case b == "" || !toolchainVersionPattern.MatchString(b):
+		return a
+	case !toolchainVersionPattern.MatchString(a):
+		return b
+	case semver.Compare("v"+a, "v"+b) < 0:
+		return b
+	default:
+		return a
+	}
+}
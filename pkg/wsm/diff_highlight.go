@@ -0,0 +1,54 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DiffHighlighters are the external diff highlighters wsm diff delegates to
+// when git is configured to use them
+var DiffHighlighters = []string{"delta", "diff-so-fancy"}
+
+// ConfiguredDiffHighlighter returns the highlighter named in the git pager or
+// diff filter configuration of a repository (pager.diff, core.pager,
+// interactive.diffFilter), or "" when none is configured or installed
+func ConfiguredDiffHighlighter(ctx context.Context, repoPath string) string {
+	for _, key := range []string{"pager.diff", "core.pager", "interactive.diffFilter"} {
+		value, err := gitOutput(ctx, repoPath, "config", "--get", key)
+		if err != nil || value == "" {
+			continue
+		}
+		fields := strings.Fields(value)
+		for _, highlighter := range DiffHighlighters {
+			if fields[0] != highlighter && !strings.HasSuffix(fields[0], "/"+highlighter) {
+				continue
+			}
+			if _, err := exec.LookPath(highlighter); err == nil {
+				return highlighter
+			}
+		}
+	}
+	return ""
+}
+
+// HighlightDiff pipes a colored diff through an external highlighter
+func HighlightDiff(ctx context.Context, highlighter, diff string) (string, error) {
+	args := []string{}
+	if highlighter == "delta" {
+		// wsm pages the whole output itself
+		args = append(args, "--paging=never")
+	}
+	cmd := exec.CommandContext(ctx, highlighter, args...)
+	cmd.Stdin = strings.NewReader(diff)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s failed: %s", highlighter, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
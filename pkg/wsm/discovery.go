@@ -18,6 +18,8 @@ import (
 type RepositoryDiscoverer struct {
 	registry     *RepositoryRegistry
 	registryPath string
+	// moves holds the repositories the last discovery matched to a new path
+	moves []RepositoryMove
 }
 
 // NewRepositoryDiscoverer creates a new repository discoverer
@@ -77,8 +79,11 @@ func (rd *RepositoryDiscoverer) DiscoverRepositories(ctx context.Context, paths
 
 	var allRepos []Repository
 
+	progress := output.StartProgress("Discovering repositories", 0)
+	defer progress.Done()
+
 	for _, path := range paths {
-		repos, err := rd.scanDirectory(ctx, path, recursive, maxDepth, 0)
+		repos, err := rd.scanDirectory(ctx, progress, path, recursive, maxDepth, 0)
 		if err != nil {
 			return errors.Wrapf(err, "failed to scan directory %s", path)
 		}
@@ -99,15 +104,21 @@ func (rd *RepositoryDiscoverer) DiscoverRepositories(ctx context.Context, paths
 }
 
 // scanDirectory recursively scans a directory for git repositories
-func (rd *RepositoryDiscoverer) scanDirectory(ctx context.Context, path string, recursive bool, maxDepth, currentDepth int) ([]Repository, error) {
+func (rd *RepositoryDiscoverer) scanDirectory(ctx context.Context, progress output.Progress, path string, recursive bool, maxDepth, currentDepth int) ([]Repository, error) {
 	if currentDepth > maxDepth {
 		return nil, nil
 	}
 
+	// Submodule checkouts are recorded on their parent repository
+	if isSubmoduleCheckout(path) {
+		return nil, nil
+	}
+
 	var repos []Repository
 
 	// Check if current directory is a git repository
 	if rd.isGitRepository(path) {
+		progress.Step(path)
 		repo, err := rd.analyzeRepository(ctx, path)
 		if err != nil {
 			output.LogWarn(
@@ -146,7 +157,7 @@ func (rd *RepositoryDiscoverer) scanDirectory(ctx context.Context, path string,
 		}
 
 		subPath := filepath.Join(path, name)
-		subRepos, err := rd.scanDirectory(ctx, subPath, recursive, maxDepth, currentDepth+1)
+		subRepos, err := rd.scanDirectory(ctx, progress, subPath, recursive, maxDepth, currentDepth+1)
 		if err != nil {
 			output.LogWarn(
 				fmt.Sprintf("Failed to scan subdirectory %s: %v", subPath, err),
@@ -207,6 +218,13 @@ func (rd *RepositoryDiscoverer) analyzeRepository(ctx context.Context, path stri
 		repo.LastCommit = lastCommit
 	}
 
+	// Get submodules
+	if submodules, err := rd.getGitSubmodules(ctx, path); err == nil {
+		repo.Submodules = submodules
+	}
+
+	repo.LFS = usesLFS(path)
+
 	return repo, nil
 }
 
@@ -342,11 +360,33 @@ func (rd *RepositoryDiscoverer) mergeRepositories(existing, discovered []Reposit
 
 	// Add existing repositories
 	for _, repo := range existing {
-		repoMap[repo.Path] = repo
+		repoMap[repo.registryKey()] = repo
 	}
 
-	// Update with discovered repositories
+	// Update with discovered repositories, keeping user-managed tags and remotes
+	rd.moves = nil
 	for _, repo := range discovered {
+		if _, exists := repoMap[repo.Path]; !exists {
+			// A clone of a repository registered by remote URL only replaces that entry
+			if remote, ok := remoteOnlyMatch(existing, repo); ok {
+				delete(repoMap, remote.registryKey())
+				repo.Name = remote.Name
+				repo.keepUserSettings(remote)
+				repo.Categories = applyUserCategories(append(repo.Categories, remote.Categories...), repo.UserCategories, repo.ExcludedCategories)
+			}
+			// A new path with the remote of a vanished repository is that repository, moved
+			if old, moved := rd.detectMove(existing, repo); moved {
+				delete(repoMap, old.Path)
+				rd.moves = append(rd.moves, RepositoryMove{Name: old.Name, OldPath: old.Path, NewPath: repo.Path})
+				repo.Name = old.Name
+				repo.keepUserSettings(old)
+				repo.Categories = applyUserCategories(repo.Categories, repo.UserCategories, repo.ExcludedCategories)
+			}
+		}
+		if old, exists := repoMap[repo.Path]; exists {
+			repo.keepUserSettings(old)
+			repo.Categories = applyUserCategories(repo.Categories, repo.UserCategories, repo.ExcludedCategories)
+		}
 		repoMap[repo.Path] = repo
 	}
 
@@ -406,7 +446,8 @@ func (rd *RepositoryDiscoverer) ValidateRegistry() *ValidationResult {
 	}
 
 	for _, repo := range rd.registry.Repositories {
-		if rd.isGitRepository(repo.Path) {
+		// Repositories registered from a remote are not expected on disk yet
+		if repo.IsRemoteOnly() || rd.isGitRepository(repo.Path) {
 			result.ValidRepos = append(result.ValidRepos, repo)
 		} else {
 			result.StaleRepos = append(result.StaleRepos, repo)
@@ -420,12 +461,12 @@ func (rd *RepositoryDiscoverer) ValidateRegistry() *ValidationResult {
 func (rd *RepositoryDiscoverer) RemoveRepositories(repos []Repository) {
 	pathsToRemove := make(map[string]bool)
 	for _, repo := range repos {
-		pathsToRemove[repo.Path] = true
+		pathsToRemove[repo.registryKey()] = true
 	}
 
 	var remaining []Repository
 	for _, repo := range rd.registry.Repositories {
-		if !pathsToRemove[repo.Path] {
+		if !pathsToRemove[repo.registryKey()] {
 			remaining = append(remaining, repo)
 		}
 	}
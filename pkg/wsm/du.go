@@ -0,0 +1,298 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Thresholds above which a repository is suggested for `git gc`, matching git's
+// own gc.auto and gc.autoPackLimit defaults
+const (
+	gcLooseObjectThreshold = 6700
+	gcPackThreshold        = 50
+)
+
+// DiskUsageReport describes the disk space used by workspaces and the source
+// repositories their worktrees share
+type DiskUsageReport struct {
+	Workspaces   []WorkspaceUsage  `json:"workspaces"`
+	Repositories []RepositoryUsage `json:"repositories"`
+	// Total counts workspaces and the .git directories of source repositories
+	Total       int64            `json:"total"`
+	Suggestions []DiskSuggestion `json:"suggestions,omitempty"`
+}
+
+// WorkspaceUsage is the disk usage of a workspace directory
+type WorkspaceUsage struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// LastModified is the newest modification of a file in the workspace
+	LastModified time.Time       `json:"last_modified"`
+	Worktrees    []WorktreeUsage `json:"worktrees"`
+	Missing      bool            `json:"missing,omitempty"`
+}
+
+// WorktreeUsage is the disk usage of a repository worktree of a workspace
+type WorktreeUsage struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+}
+
+// RepositoryUsage is the disk usage of the .git directory of a source repository,
+// which holds the objects of all its worktrees
+type RepositoryUsage struct {
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`
+	GitDirSize   int64    `json:"git_dir_size"`
+	LooseObjects int      `json:"loose_objects"`
+	Packs        int      `json:"packs"`
+	Garbage      int64    `json:"garbage"`
+	Workspaces   []string `json:"workspaces"`
+}
+
+// DiskSuggestion is a way to reclaim disk space
+type DiskSuggestion struct {
+	Kind    string `json:"kind"` // delete or gc
+	Target  string `json:"target"`
+	Size    int64  `json:"size"`
+	Reason  string `json:"reason"`
+	Command string `json:"command"`
+}
+
+// DiskUsage measures the given workspaces (all if empty) and the source
+// repositories they use. Workspaces untouched for staleAfter are suggested for deletion.
+func (wm *WorkspaceManager) DiskUsage(ctx context.Context, workspaceNames []string, staleAfter time.Duration) (*DiskUsageReport, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	if len(workspaceNames) > 0 {
+		var selected []Workspace
+		for _, name := range workspaceNames {
+			found := false
+			for _, workspace := range workspaces {
+				if workspace.Name == name {
+					selected = append(selected, workspace)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, errors.Errorf("workspace '%s' not found", name)
+			}
+		}
+		workspaces = selected
+	}
+
+	report := &DiskUsageReport{}
+	repoUsage := map[string]*RepositoryUsage{}
+	var repoOrder []string
+
+	for _, workspace := range workspaces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		usage := WorkspaceUsage{Name: workspace.Name, Path: workspace.Path}
+		if _, err := os.Stat(workspace.Path); os.IsNotExist(err) {
+			usage.Missing = true
+		} else {
+			usage.Size, usage.LastModified, err = directorySize(ctx, workspace.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to measure workspace %s", workspace.Name)
+			}
+			// A workspace without files of its own was last changed when it was created
+			if usage.LastModified.IsZero() {
+				usage.LastModified = workspace.Created
+			}
+		}
+
+		for _, repo := range workspace.Repositories {
+			worktreePath := filepath.Join(workspace.Path, repo.Name)
+			if !usage.Missing {
+				size, _, err := directorySize(ctx, worktreePath)
+				if err != nil && !os.IsNotExist(errors.Cause(err)) {
+					return nil, errors.Wrapf(err, "failed to measure worktree %s", worktreePath)
+				}
+				usage.Worktrees = append(usage.Worktrees, WorktreeUsage{Repository: repo.Name, Path: worktreePath, Size: size})
+			}
+
+			if _, ok := repoUsage[repo.Path]; !ok {
+				repoUsage[repo.Path] = &RepositoryUsage{Name: repo.Name, Path: repo.Path}
+				repoOrder = append(repoOrder, repo.Path)
+			}
+			repoUsage[repo.Path].Workspaces = appendUnique(repoUsage[repo.Path].Workspaces, workspace.Name)
+		}
+		sort.Slice(usage.Worktrees, func(i, j int) bool { return usage.Worktrees[i].Size > usage.Worktrees[j].Size })
+
+		report.Workspaces = append(report.Workspaces, usage)
+		report.Total += usage.Size
+	}
+
+	for _, repoPath := range repoOrder {
+		usage := repoUsage[repoPath]
+		if err := measureGitDir(ctx, usage); err != nil {
+			return nil, err
+		}
+		report.Repositories = append(report.Repositories, *usage)
+		report.Total += usage.GitDirSize
+	}
+
+	sort.Slice(report.Workspaces, func(i, j int) bool { return report.Workspaces[i].Size > report.Workspaces[j].Size })
+	sort.Slice(report.Repositories, func(i, j int) bool {
+		return report.Repositories[i].GitDirSize > report.Repositories[j].GitDirSize
+	})
+	report.Suggestions = diskSuggestions(report, staleAfter)
+
+	return report, nil
+}
+
+// directorySize sums the sizes of the files below a directory and returns the
+// newest modification time of a file outside of .git directories
+func directorySize(ctx context.Context, root string) (int64, time.Time, error) {
+	var size int64
+	var lastModified time.Time
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped rather than failing the whole report
+			if path != root && os.IsPermission(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		size += info.Size()
+		if !isGitMetadata(path) && info.ModTime().After(lastModified) {
+			lastModified = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return size, lastModified, nil
+}
+
+// isGitMetadata reports whether a path is a .git file or lies in a .git directory
+func isGitMetadata(path string) bool {
+	path = filepath.ToSlash(path)
+	return strings.HasSuffix(path, "/.git") || strings.Contains(path, "/.git/")
+}
+
+// measureGitDir records the size of a repository's git directory and the
+// object statistics used to suggest `git gc`
+func measureGitDir(ctx context.Context, usage *RepositoryUsage) error {
+	gitDir, err := gitOutput(ctx, usage.Path, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		// The source repository moved or was deleted; there is nothing to measure
+		return nil
+	}
+
+	if usage.GitDirSize, _, err = directorySize(ctx, gitDir); err != nil {
+		return errors.Wrapf(err, "failed to measure %s", gitDir)
+	}
+
+	stats, err := gitOutput(ctx, usage.Path, "count-objects", "-v")
+	if err != nil {
+		return nil
+	}
+	for _, line := range splitLines(stats) {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		number, _ := strconv.ParseInt(value, 10, 64)
+		switch key {
+		case "count":
+			usage.LooseObjects = int(number)
+		case "packs":
+			usage.Packs = int(number)
+		case "size-garbage":
+			usage.Garbage = number * 1024
+		}
+	}
+	return nil
+}
+
+func diskSuggestions(report *DiskUsageReport, staleAfter time.Duration) []DiskSuggestion {
+	var suggestions []DiskSuggestion
+
+	for _, workspace := range report.Workspaces {
+		if workspace.Missing || workspace.LastModified.IsZero() || staleAfter <= 0 {
+			continue
+		}
+		idle := time.Since(workspace.LastModified)
+		if idle < staleAfter {
+			continue
+		}
+		suggestions = append(suggestions, DiskSuggestion{
+			Kind:    "delete",
+			Target:  workspace.Name,
+			Size:    workspace.Size,
+			Reason:  fmt.Sprintf("no file changed for %d days", int(idle.Hours()/24)),
+			Command: ShellJoin([]string{"wsm", "delete", workspace.Name, "--remove-files"}),
+		})
+	}
+
+	for _, repo := range report.Repositories {
+		var reasons []string
+		if repo.LooseObjects > gcLooseObjectThreshold {
+			reasons = append(reasons, fmt.Sprintf("%d loose objects", repo.LooseObjects))
+		}
+		if repo.Packs > gcPackThreshold {
+			reasons = append(reasons, fmt.Sprintf("%d packs", repo.Packs))
+		}
+		if repo.Garbage > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s of garbage", FormatBytes(repo.Garbage)))
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		suggestions = append(suggestions, DiskSuggestion{
+			Kind:    "gc",
+			Target:  repo.Name,
+			Size:    repo.GitDirSize,
+			Reason:  strings.Join(reasons, ", "),
+			Command: ShellJoin([]string{"git", "-C", repo.Path, "gc"}),
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool { return suggestions[i].Size > suggestions[j].Size })
+	return suggestions
+}
+
+// FormatBytes renders a size with binary units, e.g. "1.5 GiB"
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,173 @@
+package wsm
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Supported editors
+const (
+	EditorVSCode    = "vscode"
+	EditorCursor    = "cursor"
+	EditorJetBrains = "jetbrains"
+)
+
+// Editors lists the supported editors
+var Editors = []string{EditorVSCode, EditorCursor, EditorJetBrains}
+
+// EditorCommand returns the launcher binary of an editor
+func EditorCommand(editor string) (string, error) {
+	switch editor {
+	case EditorVSCode:
+		return "code", nil
+	case EditorCursor:
+		return "cursor", nil
+	case EditorJetBrains:
+		return "idea", nil
+	default:
+		return "", errors.Errorf("unsupported editor '%s' (supported: vscode, cursor, jetbrains)", editor)
+	}
+}
+
+// EditorProjectPath returns the project file generated for an editor.
+// VS Code and Cursor share the <workspace>.code-workspace file; JetBrains IDEs
+// open the workspace directory and pick up its .idea/vcs.xml.
+func EditorProjectPath(workspace *Workspace, editor string) (string, error) {
+	switch editor {
+	case EditorVSCode, EditorCursor:
+		return filepath.Join(workspace.Path, workspace.Name+".code-workspace"), nil
+	case EditorJetBrains:
+		return filepath.Join(workspace.Path, ".idea", "vcs.xml"), nil
+	default:
+		return "", errors.Errorf("unsupported editor '%s' (supported: vscode, cursor, jetbrains)", editor)
+	}
+}
+
+// EditorOpenTarget returns what to pass to the editor launcher: the project file
+// for VS Code and Cursor, the workspace directory for JetBrains IDEs
+func EditorOpenTarget(workspace *Workspace, editor string) (string, error) {
+	if editor == EditorJetBrains {
+		return workspace.Path, nil
+	}
+	return EditorProjectPath(workspace, editor)
+}
+
+// WriteEditorProject generates or refreshes the project file of an editor and returns its path
+func WriteEditorProject(workspace *Workspace, editor string) (string, error) {
+	projectPath, err := EditorProjectPath(workspace, editor)
+	if err != nil {
+		return "", err
+	}
+
+	switch editor {
+	case EditorJetBrains:
+		err = writeJetBrainsVCS(workspace, projectPath)
+	default:
+		err = writeCodeWorkspace(workspace, projectPath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return projectPath, nil
+}
+
+// UpdateEditorProjects refreshes the editor project files that already exist in a
+// workspace, so they keep listing its repositories after add and remove
+func UpdateEditorProjects(workspace *Workspace) error {
+	updated := make(map[string]bool)
+	for _, editor := range Editors {
+		projectPath, err := EditorProjectPath(workspace, editor)
+		if err != nil || updated[projectPath] {
+			continue
+		}
+		if _, err := os.Stat(projectPath); err != nil {
+			continue
+		}
+		if _, err := WriteEditorProject(workspace, editor); err != nil {
+			return err
+		}
+		updated[projectPath] = true
+	}
+	return nil
+}
+
+// writeCodeWorkspace writes a multi-root .code-workspace file with the workspace root
+// and one folder per repository. Settings and other keys of an existing file are kept.
+func writeCodeWorkspace(workspace *Workspace, projectPath string) error {
+	project := map[string]interface{}{}
+	if data, err := os.ReadFile(projectPath); err == nil {
+		if err := json.Unmarshal(data, &project); err != nil {
+			return errors.Wrapf(err, "failed to parse %s", projectPath)
+		}
+	}
+
+	folders := []map[string]string{
+		{"name": fmt.Sprintf("%s (workspace)", workspace.Name), "path": "."},
+	}
+	for _, repo := range workspace.Repositories {
+		folders = append(folders, map[string]string{"name": repo.Name, "path": repo.Name})
+	}
+	project["folders"] = folders
+	if _, ok := project["settings"]; !ok {
+		project["settings"] = map[string]interface{}{}
+	}
+
+	data, err := json.MarshalIndent(project, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal code-workspace file")
+	}
+	if err := os.WriteFile(projectPath, append(data, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", projectPath)
+	}
+	return nil
+}
+
+type jetBrainsProject struct {
+	XMLName   xml.Name           `xml:"project"`
+	Version   string             `xml:"version,attr"`
+	Component jetBrainsComponent `xml:"component"`
+}
+
+type jetBrainsComponent struct {
+	Name     string                `xml:"name,attr"`
+	Mappings []jetBrainsVCSMapping `xml:"mapping"`
+}
+
+type jetBrainsVCSMapping struct {
+	Directory string `xml:"directory,attr"`
+	VCS       string `xml:"vcs,attr"`
+}
+
+// writeJetBrainsVCS registers every repository worktree as a Git root of the project
+func writeJetBrainsVCS(workspace *Workspace, projectPath string) error {
+	if err := os.MkdirAll(filepath.Dir(projectPath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create .idea directory")
+	}
+
+	project := jetBrainsProject{
+		Version:   "4",
+		Component: jetBrainsComponent{Name: "VcsDirectoryMappings"},
+	}
+	for _, repo := range workspace.Repositories {
+		project.Component.Mappings = append(project.Component.Mappings, jetBrainsVCSMapping{
+			Directory: "$PROJECT_DIR$/" + repo.Name,
+			VCS:       "Git",
+		})
+	}
+
+	data, err := xml.MarshalIndent(project, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal vcs.xml")
+	}
+	content := append([]byte(xml.Header), data...)
+	if err := os.WriteFile(projectPath, append(content, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", projectPath)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultExecConcurrency is the number of repositories a command runs in at
+// once without limits.yaml
+const DefaultExecConcurrency = 4
+
+// ExecOptions configures a command run across workspace repositories
+type ExecOptions struct {
+	// Command is run with `sh -c` in each repository worktree
+	Command string
+	// Repositories restricts the run to these repository names
+	Repositories []string
+	// Tags restricts the run to repositories with any of these tags
+	Tags []string
+	// Concurrency bounds the parallel runs; if <= 0 the jobs of limits.yaml apply
+	Concurrency int
+	// Stdout and Stderr receive the output streamed line by line, prefixed with
+	// the repository name. When nil, output is captured in the results instead.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExecResult is the outcome of a command in one repository
+type ExecResult struct {
+	Repository string        `json:"repository"`
+	Path       string        `json:"path"`
+	ExitCode   int           `json:"exit_code"`
+	Duration   time.Duration `json:"duration"`
+	Output     string        `json:"output,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the command exited with status 0
+func (r *ExecResult) Succeeded() bool {
+	return r.ExitCode == 0 && r.Error == ""
+}
+
+// ExecOperations runs commands across the repositories of a workspace
+type ExecOperations struct {
+	workspace *Workspace
+}
+
+// NewExecOperations creates exec operations for a workspace
+func NewExecOperations(workspace *Workspace) *ExecOperations {
+	return &ExecOperations{
+		workspace: workspace,
+	}
+}
+
+// SelectRepositories returns the workspace repositories matching the name and tag filters
+func (eo *ExecOperations) SelectRepositories(names, tags []string) ([]Repository, error) {
+	return RepositoryFilter{Repositories: names, Tags: tags}.Select(eo.workspace)
+}
+
+// Exec runs a shell command in every selected repository worktree, at most
+// opts.Concurrency (or the jobs of limits.yaml) at a time. Results are returned in workspace order; a
+// non-zero exit status is recorded in the result, not returned as an error.
+func (eo *ExecOperations) Exec(ctx context.Context, opts ExecOptions) ([]ExecResult, error) {
+	if opts.Command == "" {
+		return nil, errors.New("no command given")
+	}
+
+	repos, err := eo.SelectRepositories(opts.Repositories, opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) == 0 {
+		return nil, errors.New("no repositories match the given filters")
+	}
+
+	width := 0
+	for _, repo := range repos {
+		if len(repo.Name) > width {
+			width = len(repo.Name)
+		}
+	}
+
+	var outputMu sync.Mutex
+	results := make([]ExecResult, len(repos))
+	limit := newLimiter(eo.workspace.Name, opts.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(i int, repo Repository) {
+			defer wg.Done()
+
+			release, err := limit.acquire(ctx)
+			if err != nil {
+				results[i] = ExecResult{Repository: repo.Name, ExitCode: -1, Error: err.Error()}
+				return
+			}
+			defer release()
+
+			prefix := fmt.Sprintf("[%-*s] ", width, repo.Name)
+			results[i] = eo.execInRepository(ctx, repo, opts, prefix, &outputMu)
+		}(i, repo)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func (eo *ExecOperations) execInRepository(ctx context.Context, repo Repository, opts ExecOptions, prefix string, outputMu *sync.Mutex) ExecResult {
+	worktreePath := filepath.Join(eo.workspace.Path, repo.Name)
+	result := ExecResult{
+		Repository: repo.Name,
+		Path:       worktreePath,
+	}
+
+	if _, err := os.Stat(worktreePath); err != nil {
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("worktree %s does not exist", worktreePath)
+		return result
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", opts.Command)
+	cmd.Dir = worktreePath
+	cmd.Env = os.Environ()
+	for key, value := range workspaceEnvironment(eo.workspace) {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Env = append(cmd.Env,
+		"WSM_REPO_NAME="+repo.Name,
+		"WSM_REPO_PATH="+worktreePath,
+	)
+
+	var captured bytes.Buffer
+	var stdout, stderr *prefixWriter
+	if opts.Stdout != nil {
+		stdout = &prefixWriter{prefix: prefix, out: opts.Stdout, mu: outputMu}
+		cmd.Stdout = stdout
+	} else {
+		cmd.Stdout = &captured
+	}
+	if opts.Stderr != nil {
+		stderr = &prefixWriter{prefix: prefix, out: opts.Stderr, mu: outputMu}
+		cmd.Stderr = stderr
+	} else {
+		cmd.Stderr = &captured
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+
+	if stdout != nil {
+		stdout.Flush()
+	}
+	if stderr != nil {
+		stderr.Flush()
+	}
+	result.Output = captured.String()
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+			result.Error = err.Error()
+		}
+	}
+
+	return result
+}
+
+// prefixWriter writes complete lines prefixed with a repository name, so output of
+// parallel commands does not interleave within a line
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing line without newline
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = io.WriteString(w.out, w.prefix)
+	_, _ = w.out.Write(line)
+}
+
+func hasAnyValue(values, wanted []string) bool {
+	for _, value := range wanted {
+		if containsValue(values, value) {
+			return true
+		}
+	}
+	return false
+}
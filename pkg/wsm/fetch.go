@@ -0,0 +1,243 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// FetchRecord tracks the last background fetch of a repository
+type FetchRecord struct {
+	LastFetch   time.Time `json:"last_fetch,omitempty"`
+	LastAttempt time.Time `json:"last_attempt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// FetchState holds fetch records keyed by repository path.
+// Worktrees share their repository's refs, so one fetch serves every workspace.
+type FetchState struct {
+	Repositories map[string]FetchRecord `json:"repositories"`
+}
+
+// FetchDaemonOptions configures the background fetch loop
+type FetchDaemonOptions struct {
+	Interval   time.Duration
+	Workspaces []string // empty means all workspaces
+	Once       bool
+}
+
+func getFetchStatePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "fetch-state.json"), nil
+}
+
+// LoadFetchState loads the fetch state, returning an empty state if none exists
+func LoadFetchState() (*FetchState, error) {
+	state := &FetchState{Repositories: map[string]FetchRecord{}}
+
+	statePath, err := getFetchStatePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fetch state")
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, "failed to parse fetch state")
+	}
+	if state.Repositories == nil {
+		state.Repositories = map[string]FetchRecord{}
+	}
+
+	return state, nil
+}
+
+// Save writes the fetch state atomically so concurrent readers never see a partial file
+func (fs *FetchState) Save() error {
+	statePath, err := getFetchStatePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+
+	data, err := json.MarshalIndent(fs, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal fetch state")
+	}
+
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write fetch state")
+	}
+	if err := os.Rename(tmpPath, statePath); err != nil {
+		return errors.Wrap(err, "failed to replace fetch state")
+	}
+
+	return nil
+}
+
+// LastFetch returns the time of the last successful fetch of a repository
+func (fs *FetchState) LastFetch(repoPath string) (time.Time, bool) {
+	record, ok := fs.Repositories[repoPath]
+	if !ok || record.LastFetch.IsZero() {
+		return time.Time{}, false
+	}
+	return record.LastFetch, true
+}
+
+// FetchWorkspaces fetches every repository of the given workspaces (all if empty) once
+func FetchWorkspaces(ctx context.Context, workspaceNames []string) (*FetchState, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range workspaceNames {
+		selected[name] = true
+	}
+
+	// Collect unique repositories across the selected workspaces
+	var repos []Repository
+	seen := make(map[string]bool)
+	for _, workspace := range workspaces {
+		if len(selected) > 0 && !selected[workspace.Name] {
+			continue
+		}
+		delete(selected, workspace.Name)
+		for _, repo := range workspace.Repositories {
+			if !seen[repo.Path] {
+				seen[repo.Path] = true
+				repos = append(repos, repo)
+			}
+		}
+	}
+
+	if len(selected) > 0 {
+		var missing []string
+		for name := range selected {
+			missing = append(missing, name)
+		}
+		return nil, errors.Errorf("workspaces not found: %s", strings.Join(missing, ", "))
+	}
+
+	state, err := LoadFetchState()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := output.StartProgress("Fetching", len(repos))
+	defer progress.Done()
+
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			break
+		}
+		progress.Step(repo.Name)
+
+		record := state.Repositories[repo.Path]
+		record.LastAttempt = time.Now()
+
+		if err := fetchRepository(ctx, repo.Path, repo.cloneOptions().fetchArgs()...); err != nil {
+			record.Error = err.Error()
+			output.LogWarn(
+				fmt.Sprintf("Failed to fetch %s: %v", repo.Name, err),
+				"Background fetch failed",
+				"repository", repo.Name,
+				"path", repo.Path,
+				"error", err,
+			)
+		} else {
+			record.LastFetch = record.LastAttempt
+			record.Error = ""
+		}
+
+		state.Repositories[repo.Path] = record
+	}
+
+	if err := state.Save(); err != nil {
+		return nil, err
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Fetched %d repositories", len(repos)),
+		"Background fetch completed",
+		"repositories", len(repos),
+	)
+
+	return state, nil
+}
+
+// RunFetchDaemon fetches workspace repositories on an interval until the context is cancelled
+func RunFetchDaemon(ctx context.Context, opts FetchDaemonOptions) error {
+	if opts.Interval <= 0 {
+		return errors.New("fetch interval must be positive")
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for cycle := 0; ; cycle++ {
+		if _, err := FetchWorkspaces(ctx, opts.Workspaces); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// Fail fast on the first cycle (e.g. unknown workspace names), keep running afterwards
+			if opts.Once || cycle == 0 {
+				return err
+			}
+			output.LogWarn(
+				fmt.Sprintf("Fetch cycle failed: %v", err),
+				"Fetch cycle failed",
+				"error", err,
+			)
+		}
+
+		if opts.Once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchRepository fetches all remotes of a repository without touching the
+// working tree; args keep shallow clones shallow
+func fetchRepository(ctx context.Context, repoPath string, args ...string) error {
+	ctx, cancel := GitContext(ctx, "fetch")
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"fetch", "--all", "--prune", "--quiet"}, args...)...)
+	cmd.Dir = repoPath
+	// Never block on credential prompts in the background
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if cmdOutput, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(GitError(ctx, err), "git fetch failed: %s", strings.TrimSpace(string(cmdOutput)))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// DefaultForkRemote is the remote forks are added as
+const DefaultForkRemote = "fork"
+
+// ForkConfig enables the fork-based contribution workflow of a workspace:
+// branches are pushed to a personal fork of every repository, created with
+// `gh repo fork` when missing, and pull requests target the original repository
+type ForkConfig struct {
+	// Remote is the name of the fork remote in every repository (default "fork")
+	Remote string `json:"remote,omitempty"`
+	// Organization forks into an organization instead of the user account
+	Organization string `json:"organization,omitempty"`
+}
+
+// RemoteName returns the name of the fork remote
+func (f *ForkConfig) RemoteName() string {
+	if f.Remote != "" {
+		return f.Remote
+	}
+	return DefaultForkRemote
+}
+
+// PushRemoteFor returns the remote the workspace branch of a repository is
+// pushed to: the fork remote in fork mode, else its primary remote
+func (w *Workspace) PushRemoteFor(repo Repository) string {
+	if w.Fork != nil {
+		return w.Fork.RemoteName()
+	}
+	return w.RemoteFor(repo)
+}
+
+// GitHubRepository returns the owner/name of a GitHub remote URL
+func GitHubRepository(url string) (string, error) {
+	path, ok := strings.CutPrefix(normalizeRemoteURL(url), "github.com/")
+	if !ok || strings.Count(path, "/") != 1 {
+		return "", errors.Errorf("'%s' is not a GitHub repository URL", url)
+	}
+	return path, nil
+}
+
+// UpstreamRepository returns the owner/name of the repository pull requests
+// target: the repository of the base remote
+func UpstreamRepository(ctx context.Context, repo Repository, repoPath string) (string, error) {
+	url, err := gitOutput(ctx, repoPath, "remote", "get-url", repo.BaseRemote())
+	if err != nil {
+		return "", errors.Wrapf(err, "remote '%s' is not configured in %s", repo.BaseRemote(), repo.Name)
+	}
+	return GitHubRepository(url)
+}
+
+// EnsureFork makes sure a repository has the fork remote, forking the upstream
+// repository on GitHub when it is missing. It returns the fork as owner/name.
+func EnsureFork(ctx context.Context, repo Repository, repoPath string, fork *ForkConfig) (string, error) {
+	remote := fork.RemoteName()
+	if url, err := gitOutput(ctx, repoPath, "remote", "get-url", remote); err == nil {
+		return GitHubRepository(url)
+	}
+
+	if err := RequireTool(ctx, "gh"); err != nil {
+		return "", err
+	}
+	upstream, err := UpstreamRepository(ctx, repo, repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	owner := fork.Organization
+	if owner == "" {
+		cmd := exec.CommandContext(ctx, "gh", "api", "user", "--jq", ".login")
+		login, err := cmd.Output()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get the GitHub user")
+		}
+		owner = strings.TrimSpace(string(login))
+	}
+
+	// gh succeeds without changes when the fork already exists
+	args := []string{"repo", "fork", upstream, "--clone=false"}
+	if fork.Organization != "" {
+		args = append(args, "--org", fork.Organization)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = repoPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", errors.Wrapf(err, "gh repo fork failed: %s", strings.TrimSpace(string(out)))
+	}
+
+	forkRepo := owner + "/" + upstream[strings.Index(upstream, "/")+1:]
+	baseURL, _ := gitOutput(ctx, repoPath, "remote", "get-url", repo.BaseRemote())
+	url := "git@github.com:" + forkRepo + ".git"
+	if strings.HasPrefix(baseURL, "https://") {
+		url = "https://github.com/" + forkRepo + ".git"
+	}
+	if _, err := gitOutput(ctx, repoPath, "remote", "add", remote, url); err != nil {
+		return "", errors.Wrapf(err, "failed to add remote '%s'", remote)
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Forked %s to %s (remote '%s')", upstream, forkRepo, remote),
+		"Created fork",
+		"repository", repo.Name,
+		"fork", forkRepo,
+		"remote", remote,
+	)
+	return forkRepo, nil
+}
+
+// PushToFork pushes the current branch of a repository to its fork, creating
+// the fork first if needed, and makes the fork branch its upstream
+func PushToFork(ctx context.Context, repo Repository, repoPath string, fork *ForkConfig) error {
+	if err := CheckGoReplacesNotCommitted(ctx, repoPath); err != nil {
+		return err
+	}
+	if _, err := EnsureFork(ctx, repo, repoPath, fork); err != nil {
+		return err
+	}
+
+	branch, err := getGitCurrentBranch(ctx, repoPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get current branch of %s", repo.Name)
+	}
+
+	if _, err := gitOutput(ctx, repoPath, "push", "-u", fork.RemoteName(), branch); err != nil {
+		return errors.Wrapf(err, "failed to push %s to %s", repo.Name, fork.RemoteName())
+	}
+	return nil
+}
+
+// repository returns the workspace repository with the given name
+func (w *Workspace) repository(name string) (Repository, bool) {
+	for _, repo := range w.Repositories {
+		if repo.Name == name {
+			return repo, true
+		}
+	}
+	return Repository{}, false
+}
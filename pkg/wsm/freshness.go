@@ -0,0 +1,174 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Operations that fetch stale repositories first
+const (
+	FreshnessStatus = "status"
+	FreshnessMerge  = "merge"
+	FreshnessRebase = "rebase"
+)
+
+// FreshnessOperations lists the operations freshness.yaml can enable
+var FreshnessOperations = []string{FreshnessStatus, FreshnessMerge, FreshnessRebase}
+
+// FreshnessConfig makes status, merge and rebase fetch repositories whose last
+// fetch is older than MaxAge, so ahead/behind counts and merge bases are not
+// stale. It is opt-in, read from freshness.yaml in the workspace-manager
+// config directory:
+//
+//	max-age: 15m
+//	operations: [status, merge]   # default: status, merge and rebase
+type FreshnessConfig struct {
+	MaxAge     time.Duration `yaml:"max-age"`
+	Operations []string      `yaml:"operations,omitempty"`
+
+	path string
+}
+
+// GetFreshnessConfigPath returns the path of the freshness configuration file
+func GetFreshnessConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "freshness.yaml"), nil
+}
+
+// LoadFreshnessConfig loads freshness.yaml, returning a disabled configuration
+// if none exists
+func LoadFreshnessConfig() (*FreshnessConfig, error) {
+	configPath, err := GetFreshnessConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &FreshnessConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read freshness configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse freshness configuration %s", configPath)
+	}
+	if config.MaxAge < 0 {
+		return nil, errors.Errorf("max-age of %s must not be negative", configPath)
+	}
+	for _, operation := range config.Operations {
+		if !containsValue(FreshnessOperations, operation) {
+			return nil, errors.Errorf("unknown operation '%s' in %s (supported: status, merge, rebase)", operation, configPath)
+		}
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *FreshnessConfig) Path() string {
+	return c.path
+}
+
+// Enabled reports whether an operation fetches stale repositories first
+func (c *FreshnessConfig) Enabled(operation string) bool {
+	if c.MaxAge <= 0 {
+		return false
+	}
+	return len(c.Operations) == 0 || containsValue(c.Operations, operation)
+}
+
+// FreshnessResult lists the repositories fetched because they were stale
+type FreshnessResult struct {
+	Fetched []string `json:"fetched,omitempty"`
+	// Failed maps repositories that could not be fetched to the error
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// RefreshStaleRepositories fetches, in parallel, the repositories of a
+// workspace not fetched within the max-age of freshness.yaml, if it enables
+// the operation. jobs bounds the parallel fetches (see Limits), 0 for the default. Fetch failures are reported in the result, not as an error,
+// so an offline machine still gets a (stale) answer.
+func RefreshStaleRepositories(ctx context.Context, workspace *Workspace, operation string, jobs int) (*FreshnessResult, error) {
+	result := &FreshnessResult{}
+
+	config, err := LoadFreshnessConfig()
+	if err != nil {
+		return result, err
+	}
+	if !config.Enabled(operation) {
+		return result, nil
+	}
+
+	state, err := LoadFetchState()
+	if err != nil {
+		return result, err
+	}
+
+	var stale []Repository
+	for _, repo := range workspace.Repositories {
+		if lastFetch, ok := state.LastFetch(repo.Path); ok && time.Since(lastFetch) < config.MaxAge {
+			continue
+		}
+		stale = append(stale, repo)
+	}
+	if len(stale) == 0 {
+		return result, nil
+	}
+
+	progress := output.StartProgress("Fetching stale repositories", len(stale))
+	defer progress.Done()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	limit := newLimiter(workspace.Name, jobs)
+	for _, repo := range stale {
+		wg.Add(1)
+		go func(repo Repository) {
+			defer wg.Done()
+
+			record := FetchRecord{LastAttempt: time.Now()}
+			release, fetchErr := limit.acquire(ctx)
+			if fetchErr == nil {
+				fetchErr = fetchRepository(ctx, repo.Path, repo.cloneOptions().fetchArgs()...)
+				release()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			progress.Step(repo.Name)
+			record.LastFetch = state.Repositories[repo.Path].LastFetch
+			if fetchErr != nil {
+				record.Error = fetchErr.Error()
+				if result.Failed == nil {
+					result.Failed = make(map[string]string)
+				}
+				result.Failed[repo.Name] = fetchErr.Error()
+			} else {
+				record.LastFetch = record.LastAttempt
+				result.Fetched = append(result.Fetched, repo.Name)
+			}
+			state.Repositories[repo.Path] = record
+		}(repo)
+	}
+	wg.Wait()
+	sort.Strings(result.Fetched)
+
+	if err := state.Save(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// GCCandidate is a workspace considered by garbage collection
+type GCCandidate struct {
+	Workspace string `json:"workspace"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	// LastUsed is the later of LastModified and the last wsm command run on the workspace
+	LastUsed time.Time `json:"last_used"`
+	// LastModified is the newest modification of a file in the workspace
+	LastModified time.Time `json:"last_modified"`
+	Missing      bool      `json:"missing,omitempty"`
+	// KeptBecause lists the work that would be lost, empty for collectable workspaces
+	KeptBecause []string         `json:"kept_because,omitempty"`
+	Preflight   *DeletePreflight `json:"preflight,omitempty"`
+}
+
+// GCReport lists the idle workspaces that can be collected and the ones kept
+// because they still hold work
+type GCReport struct {
+	OlderThan   time.Duration `json:"older_than"`
+	Collectable []GCCandidate `json:"collectable"`
+	Kept        []GCCandidate `json:"kept"`
+	// Reclaimable is the size of the collectable workspaces
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// GCResult reports what a garbage collection deleted
+type GCResult struct {
+	Deleted []string `json:"deleted"`
+	// RemovedDirectories are dated workspace directories left empty and removed
+	RemovedDirectories []string `json:"removed_directories,omitempty"`
+	Errors             []string `json:"errors,omitempty"`
+}
+
+// PlanGC finds workspaces neither touched by a wsm command nor changed on disk
+// for olderThan. Workspaces with unpushed commits, stashes, uncommitted or
+// untracked files are kept.
+func (wm *WorkspaceManager) PlanGC(ctx context.Context, olderThan time.Duration) (*GCReport, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	usage, err := LoadUsageLog()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GCReport{
+		OlderThan:   olderThan,
+		Collectable: []GCCandidate{},
+		Kept:        []GCCandidate{},
+	}
+	for i := range workspaces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		workspace := &workspaces[i]
+
+		activity, err := measureActivity(ctx, workspace, usage)
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(activity.lastUsed) < olderThan {
+			continue
+		}
+		candidate := GCCandidate{
+			Workspace:    workspace.Name,
+			Path:         workspace.Path,
+			Size:         activity.size,
+			LastUsed:     activity.lastUsed,
+			LastModified: activity.lastModified,
+			Missing:      activity.missing,
+		}
+		if candidate.LastModified.IsZero() {
+			candidate.LastModified = workspace.Created
+		}
+
+		if !candidate.Missing {
+			candidate.Preflight = wm.CheckDeletePreflight(ctx, workspace)
+			candidate.KeptBecause = gcKeepReasons(candidate.Preflight)
+		}
+		if len(candidate.KeptBecause) > 0 {
+			report.Kept = append(report.Kept, candidate)
+			continue
+		}
+		report.Collectable = append(report.Collectable, candidate)
+		report.Reclaimable += candidate.Size
+	}
+
+	oldestFirst := func(candidates []GCCandidate) {
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+		})
+	}
+	oldestFirst(report.Collectable)
+	oldestFirst(report.Kept)
+
+	return report, nil
+}
+
+// gcKeepReasons summarizes the work a deletion would lose, per repository
+func gcKeepReasons(preflight *DeletePreflight) []string {
+	var reasons []string
+	for _, repo := range preflight.Repositories {
+		var work []string
+		if repo.UnpushedCount > 0 {
+			work = append(work, fmt.Sprintf("%d unpushed commit(s)", repo.UnpushedCount))
+		}
+		if len(repo.Stashes) > 0 {
+			work = append(work, fmt.Sprintf("%d stash(es)", len(repo.Stashes)))
+		}
+		if repo.ModifiedFiles > 0 {
+			work = append(work, fmt.Sprintf("%d modified file(s)", repo.ModifiedFiles))
+		}
+		if len(repo.UntrackedFiles) > 0 {
+			work = append(work, fmt.Sprintf("%d untracked file(s)", len(repo.UntrackedFiles)))
+		}
+		if repo.Error != "" {
+			// Work that cannot be checked is assumed to be at risk
+			work = append(work, "could not be checked: "+repo.Error)
+		}
+		if len(work) > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", repo.Repository, strings.Join(work, ", ")))
+		}
+	}
+	return reasons
+}
+
+// CollectGarbage deletes the collectable workspaces of a report with their
+// worktrees and files, then removes the dated workspace directories
+// (~/workspaces/2006-01-02) left empty
+func (wm *WorkspaceManager) CollectGarbage(ctx context.Context, report *GCReport) *GCResult {
+	result := &GCResult{Deleted: []string{}}
+	parents := make(map[string]bool)
+
+	for _, candidate := range report.Collectable {
+		if err := ctx.Err(); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			break
+		}
+		if err := wm.DeleteWorkspace(ctx, candidate.Workspace, true, false); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", candidate.Workspace, err))
+			continue
+		}
+		result.Deleted = append(result.Deleted, candidate.Workspace)
+		parents[filepath.Dir(candidate.Path)] = true
+	}
+
+	root := filepath.Dir(wm.workspaceDir)
+	for dir := range parents {
+		if filepath.Dir(dir) != root || !isDatedDirectory(dir) {
+			continue
+		}
+		// Only succeeds when the directory is empty
+		if err := os.Remove(dir); err == nil {
+			result.RemovedDirectories = append(result.RemovedDirectories, dir)
+		}
+	}
+	sort.Strings(result.RemovedDirectories)
+
+	output.LogInfo(
+		fmt.Sprintf("Garbage collection deleted %d workspace(s)", len(result.Deleted)),
+		"Garbage collection finished",
+		"deleted", len(result.Deleted),
+		"errors", len(result.Errors),
+	)
+
+	return result
+}
+
+// isDatedDirectory reports whether dir is named after a day, like the default
+// workspace directories
+func isDatedDirectory(dir string) bool {
+	_, err := time.Parse("2006-01-02", filepath.Base(dir))
+	return err == nil
+}
@@ -0,0 +1,332 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/pkg/errors"
+)
+
+// ParseGitConfig parses the key=value overrides of --git-config, e.g.
+// user.email=me@example.com
+func ParseGitConfig(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	config := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, errors.Errorf("invalid git config '%s', expected key=value", spec)
+		}
+		if err := validateGitConfigKey(key); err != nil {
+			return nil, err
+		}
+		config[key] = value
+	}
+	return config, nil
+}
+
+// validateGitConfigKey rejects malformed keys and the ones git does not
+// allow per worktree
+func validateGitConfigKey(key string) error {
+	if !strings.Contains(key, ".") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return errors.Errorf("invalid git config key '%s', expected section.name", key)
+	}
+	if strings.EqualFold(key, "extensions.worktreeConfig") || strings.EqualFold(key, "core.bare") || strings.EqualFold(key, "core.worktree") {
+		return errors.Errorf("git config key '%s' cannot be set per worktree", key)
+	}
+	return nil
+}
+
+// manifestGitConfigAllowed are the git config keys a manifest may set without
+// confirmation; a trailing dot allows a whole section
+var manifestGitConfigAllowed = []string{
+	"user.",
+	"commit.gpgsign",
+	"tag.gpgsign",
+	"pull.rebase",
+	"pull.ff",
+	"push.default",
+	"push.autosetupremote",
+	"rebase.autosquash",
+	"rebase.autostash",
+	"merge.conflictstyle",
+	"merge.ff",
+	"diff.algorithm",
+	"fetch.prune",
+	"core.autocrlf",
+	"core.eol",
+}
+
+// gitConfigExecSections and gitConfigExecNames identify the git config keys
+// whose value is a command git runs, or that pull in such keys (include.path)
+var (
+	gitConfigExecSections = []string{"alias", "pager", "filter", "credential", "include", "includeif", "gpg", "sendemail", "man", "difftool", "mergetool", "browser", "web"}
+	gitConfigExecNames    = []string{"fsmonitor", "sshcommand", "pager", "editor", "hookspath", "askpass", "gitproxy", "alternaterefscommand", "external", "textconv", "command", "driver", "cmd", "program", "helper", "uploadpack", "receivepack", "packobjectshook", "path"}
+)
+
+// gitConfigRunsCommands reports whether a git config key makes git run a command
+func gitConfigRunsCommands(key string) bool {
+	lower := strings.ToLower(key)
+	section := lower[:strings.Index(lower, ".")]
+	name := lower[strings.LastIndex(lower, ".")+1:]
+	return containsValue(gitConfigExecSections, section) || containsValue(gitConfigExecNames, name)
+}
+
+// manifestGitConfigAllows reports whether a manifest may set a git config key
+// without asking the user
+func manifestGitConfigAllows(key string) bool {
+	lower := strings.ToLower(key)
+	for _, allowed := range manifestGitConfigAllowed {
+		if lower == allowed || (strings.HasSuffix(allowed, ".") && strings.HasPrefix(lower, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkManifestGitConfig vets the git-config of a manifest, which may come
+// from a URL. Keys that run commands are rejected, keys outside the allowlist
+// need the user's confirmation (refused in non-interactive mode and only
+// checked with confirm unset, for dry runs). Keys also given with --git-config
+// (overrides) are the user's own.
+func checkManifestGitConfig(config, overrides map[string]string, confirm bool) error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := validateGitConfigKey(key); err != nil {
+			return err
+		}
+		if _, ok := overrides[key]; ok {
+			continue
+		}
+		if gitConfigRunsCommands(key) {
+			return errors.Errorf("git config key '%s' can run commands and cannot be set by a manifest; pass it with --git-config if you trust it", key)
+		}
+		if manifestGitConfigAllows(key) || !confirm {
+			continue
+		}
+		if nonInteractive {
+			return errors.Errorf("the manifest sets git config key '%s', which needs confirmation; pass it with --git-config to accept it", key)
+		}
+		ok, err := ux.Confirm(fmt.Sprintf("The manifest sets %s=%s in every worktree. Allow it?", key, config[key]), "")
+		if err != nil && !errors.Is(err, ux.ErrAborted) {
+			return err
+		}
+		if !ok {
+			return errors.Errorf("git config key '%s' of the manifest was not allowed", key)
+		}
+	}
+	return nil
+}
+
+// MergeGitConfig returns base with overrides applied, nil if both are empty
+func MergeGitConfig(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// GitConfigKeys returns the keys of the workspace's git config overrides, sorted
+func (w *Workspace) GitConfigKeys() []string {
+	return sortedKeys(w.GitConfig)
+}
+
+// gitConfigTrusted reports whether the workspace may set a git config key:
+// keys that run commands must come from the user's --git-config
+func (w *Workspace) gitConfigTrusted(key string) bool {
+	return !gitConfigRunsCommands(key) || containsValue(w.GitConfigUserKeys, key)
+}
+
+// TrustedGitConfig returns the git config of the workspace without the keys
+// that run commands but were not passed with --git-config
+func (w *Workspace) TrustedGitConfig() map[string]string {
+	var config map[string]string
+	for key, value := range w.GitConfig {
+		if !w.gitConfigTrusted(key) {
+			continue
+		}
+		if config == nil {
+			config = make(map[string]string, len(w.GitConfig))
+		}
+		config[key] = value
+	}
+	return config
+}
+
+// localizeGitConfig keeps the git config of a workspace from the shared state
+// that a manifest could set without asking, and the keys the user passed with
+// --git-config for the local definition (local) with the same value
+func localizeGitConfig(workspace *Workspace, local *Workspace) {
+	config := make(map[string]string, len(workspace.GitConfig))
+	var userKeys []string
+	for _, key := range sortedKeys(workspace.GitConfig) {
+		value := workspace.GitConfig[key]
+		if validateGitConfigKey(key) != nil {
+			output.PrintWarning("Ignoring invalid git config key '%s' of workspace '%s' in the shared state", key, workspace.Name)
+			continue
+		}
+		if local != nil && containsValue(local.GitConfigUserKeys, key) {
+			if localValue, ok := local.GitConfig[key]; ok && localValue == value {
+				config[key] = value
+				userKeys = append(userKeys, key)
+				continue
+			}
+		}
+		if gitConfigRunsCommands(key) || !manifestGitConfigAllows(key) {
+			output.PrintWarning("Ignoring git config %s of workspace '%s' in the shared state; pass it with --git-config if you trust it", key, workspace.Name)
+			continue
+		}
+		config[key] = value
+	}
+	if len(config) == 0 {
+		config = nil
+	}
+	workspace.GitConfig = config
+	workspace.GitConfigUserKeys = userKeys
+}
+
+// applyGitConfig writes the workspace's git config overrides to the config of
+// a repository's worktree (git config --worktree), enabling
+// extensions.worktreeConfig in the repository first if needed. Keys that run
+// commands but did not come from --git-config are refused.
+func (wm *WorkspaceManager) applyGitConfig(ctx context.Context, workspace *Workspace, repo Repository) error {
+	if len(workspace.GitConfig) == 0 {
+		return nil
+	}
+
+	if err := wm.enableWorktreeConfig(ctx, workspace, repo); err != nil {
+		return err
+	}
+
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	for _, key := range workspace.GitConfigKeys() {
+		if !workspace.gitConfigTrusted(key) {
+			output.PrintWarning("Not setting git config %s in %s: it can run commands and was not passed with --git-config", key, repo.Name)
+			continue
+		}
+		if _, err := gitOutput(ctx, worktreePath, worktreeConfigArgs(key, workspace.GitConfig[key])...); err != nil {
+			return errors.Wrapf(err, "failed to set %s in %s", key, repo.Name)
+		}
+	}
+	return nil
+}
+
+// enableWorktreeConfigArgs are the git arguments enabling per-worktree config
+var enableWorktreeConfigArgs = []string{"config", "extensions.worktreeConfig", "true"}
+
+// worktreeConfigArgs are the git arguments setting a key in the config of a worktree
+func worktreeConfigArgs(key, value string) []string {
+	return []string{"config", "--worktree", key, value}
+}
+
+// planGitConfig returns the plan steps of applyGitConfig for a repository
+func planGitConfig(ctx context.Context, workspace *Workspace, repo Repository) []PlanStep {
+	if len(workspace.GitConfig) == 0 {
+		return nil
+	}
+
+	steps := planEnableWorktreeConfig(ctx, repo)
+	for _, key := range workspace.GitConfigKeys() {
+		if !workspace.gitConfigTrusted(key) {
+			continue
+		}
+		steps = append(steps, PlanStep{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Set %s in the worktree config of %s", key, repo.Name),
+			Dir:         filepath.Join(workspace.Path, repo.Name),
+			Command:     append([]string{"git"}, worktreeConfigArgs(key, workspace.GitConfig[key])...),
+		})
+	}
+	return steps
+}
+
+// planEnableWorktreeConfig returns the plan step of enableWorktreeConfig, if
+// the repository does not have per-worktree config yet
+func planEnableWorktreeConfig(ctx context.Context, repo Repository) []PlanStep {
+	if enabled, _ := gitOutput(ctx, repo.Path, "config", "--bool", "extensions.worktreeConfig"); enabled == "true" {
+		return nil
+	}
+	return []PlanStep{{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Enable per-worktree config in %s", repo.Name),
+		Dir:         repo.Path,
+		Command:     append([]string{"git"}, enableWorktreeConfigArgs...),
+	}}
+}
+
+// enableWorktreeConfig enables extensions.worktreeConfig in a repository,
+// which 'git config --worktree' needs, recording it if wsm did
+func (wm *WorkspaceManager) enableWorktreeConfig(ctx context.Context, workspace *Workspace, repo Repository) error {
+	if enabled, _ := gitOutput(ctx, repo.Path, "config", "--bool", "extensions.worktreeConfig"); enabled == "true" {
+		return nil
+	}
+	if _, err := gitOutput(ctx, repo.Path, enableWorktreeConfigArgs...); err != nil {
+		return errors.Wrapf(err, "failed to enable per-worktree config in %s", repo.Name)
+	}
+	workspace.GitConfigExtensions = appendUnique(workspace.GitConfigExtensions, repo.Name)
+	return nil
+}
+
+// releaseGitConfig undoes applyGitConfig for a repository whose worktree is
+// removed from a workspace: its overrides are unset, if the worktree is still
+// there, and extensions.worktreeConfig is disabled again where wsm enabled it
+// and no other workspace sets git config or deploys hooks in the repository
+func (wm *WorkspaceManager) releaseGitConfig(ctx context.Context, workspace *Workspace, repo Repository) {
+	// A worktree reused from elsewhere (a link) never got the overrides
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	if info, err := os.Lstat(worktreePath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		for _, key := range workspace.GitConfigKeys() {
+			_, _ = gitOutput(ctx, worktreePath, "config", "--worktree", "--unset-all", key)
+		}
+	}
+
+	if !containsValue(workspace.GitConfigExtensions, repo.Name) {
+		return
+	}
+	var remaining []string
+	for _, name := range workspace.GitConfigExtensions {
+		if name != repo.Name {
+			remaining = append(remaining, name)
+		}
+	}
+	workspace.GitConfigExtensions = remaining
+
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return
+	}
+	for _, other := range workspaces {
+		if other.Name == workspace.Name || (len(other.GitConfig) == 0 && len(other.HookRepositories) == 0) {
+			continue
+		}
+		for _, otherRepo := range other.Repositories {
+			if otherRepo.Path == repo.Path {
+				return
+			}
+		}
+	}
+	if _, err := gitOutput(ctx, repo.Path, "config", "--unset", "extensions.worktreeConfig"); err != nil {
+		output.PrintWarning("Failed to disable per-worktree config in %s: %v", repo.Name, err)
+	}
+}
@@ -0,0 +1,61 @@
+package wsm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", specs: nil, want: nil},
+		{name: "single", specs: []string{"user.email=me@example.com"}, want: map[string]string{"user.email": "me@example.com"}},
+		{name: "value with equals", specs: []string{"alias.lg=log --format=%h"}, want: map[string]string{"alias.lg": "log --format=%h"}},
+		{name: "empty value", specs: []string{"commit.template="}, want: map[string]string{"commit.template": ""}},
+		{name: "key trimmed", specs: []string{" pull.rebase =true"}, want: map[string]string{"pull.rebase": "true"}},
+		{name: "later wins", specs: []string{"pull.ff=only", "pull.ff=false"}, want: map[string]string{"pull.ff": "false"}},
+		{name: "subsection", specs: []string{"remote.origin.prune=true"}, want: map[string]string{"remote.origin.prune": "true"}},
+		{name: "missing equals", specs: []string{"user.email"}, wantErr: true},
+		{name: "empty key", specs: []string{"=value"}, wantErr: true},
+		{name: "no section", specs: []string{"email=me@example.com"}, wantErr: true},
+		{name: "worktree config", specs: []string{"extensions.worktreeConfig=false"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGitConfig(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGitConfig(%q) error = %v, wantErr %v", tt.specs, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGitConfig(%q) = %v, want %v", tt.specs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGitConfigKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"user.email", false},
+		{"remote.origin.url", false},
+		{"core.hooksPath", false},
+		{"email", true},
+		{".email", true},
+		{"user.", true},
+		{"extensions.worktreeConfig", true},
+		{"EXTENSIONS.WORKTREECONFIG", true},
+		{"core.bare", true},
+		{"core.worktree", true},
+	}
+	for _, tt := range tests {
+		if err := validateGitConfigKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("validateGitConfigKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// Git hooks are deployed to every worktree of a workspace from the hooks
+// template directory (githooks/ in the workspace-manager config directory) and
+// the .wsm/githooks directory of each repository. They are copied, on top of
+// the hooks the repository already has, to .wsm/githooks/<repo> of the
+// workspace, which the worktree's core.hooksPath points to. Hooks of a
+// repository are untrusted content: like setup scripts, they are deployed only
+// when the repository is allowed in scripts.yaml or the user approves them.
+const hooksDirName = "githooks"
+
+// HookDeployment lists the hooks deployed to a repository's worktree
+type HookDeployment struct {
+	Repository string   `json:"repository"`
+	Path       string   `json:"path"`
+	Hooks      []string `json:"hooks"`
+	// Removed is set when the repository no longer has hooks to deploy
+	Removed bool `json:"removed,omitempty"`
+}
+
+// GetHooksTemplateDir returns the directory of the hooks deployed to every workspace
+func GetHooksTemplateDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", hooksDirName), nil
+}
+
+// hooksDir is where the hooks of a repository's worktree are deployed
+func (w *Workspace) hooksDir(repoName string) string {
+	return filepath.Join(w.Path, ".wsm", hooksDirName, repoName)
+}
+
+// hookSources returns the directories whose hooks are deployed to a worktree,
+// the later ones overriding hooks of the same name. The first is the hooks
+// directory the repository uses without wsm, so hooks installed there (e.g. by
+// git lfs install) keep running.
+func hookSources(ctx context.Context, repo Repository, worktreePath string) (existing string, sources []string) {
+	// The main worktree does not see the core.hooksPath of workspace worktrees
+	if dir, err := gitOutput(ctx, repo.Path, "config", "core.hooksPath"); err == nil && dir != "" {
+		existing = expandHome(dir)
+		if !filepath.IsAbs(existing) {
+			existing = filepath.Join(worktreePath, existing)
+		}
+	} else if commonDir, err := gitOutput(ctx, worktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil && commonDir != "" {
+		existing = filepath.Join(commonDir, "hooks")
+	}
+	if templateDir, err := GetHooksTemplateDir(); err == nil && isDir(templateDir) {
+		sources = append(sources, templateDir)
+	}
+	if repoDir := filepath.Join(worktreePath, ".wsm", hooksDirName); isDir(repoDir) {
+		sources = append(sources, repoDir)
+	}
+	return existing, sources
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// hookFiles returns the hooks of a directory, sorted by name. Sample hooks,
+// subdirectories and, with allow set, the hooks it refuses are skipped.
+func hookFiles(sourceDir string, allow func(path string) bool) ([]string, error) {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read hooks in %s", sourceDir)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ".sample") {
+			continue
+		}
+		path := filepath.Join(sourceDir, entry.Name())
+		if allow != nil && !allow(path) {
+			continue
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// copyHooks copies the hooks of a directory into dir, executable, as listed
+// by hookFiles
+func copyHooks(sourceDir, dir string, hooks map[string]bool, allow func(path string) bool) error {
+	files, err := hookFiles(sourceDir, allow)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read hook %s", name)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0755); err != nil {
+			return errors.Wrapf(err, "failed to write hook %s", name)
+		}
+		hooks[name] = true
+	}
+	return nil
+}
+
+// deployHooks deploys the template and repository hooks to a repository's
+// worktree. Nothing is done when neither has hooks, when the workspace was
+// created with --no-hooks or sets core.hooksPath itself, and for worktrees
+// reused from elsewhere. The deployment is nil if no hooks were deployed.
+func (wm *WorkspaceManager) deployHooks(ctx context.Context, workspace *Workspace, repo Repository) (*HookDeployment, error) {
+	if workspace.NoHooks || workspace.hooksPathConfigured() {
+		return nil, nil
+	}
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	if info, err := os.Lstat(worktreePath); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		return nil, nil
+	}
+	existing, sources := hookSources(ctx, repo, worktreePath)
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	dir := workspace.hooksDir(repo.Name)
+	staging := dir + ".new"
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, errors.Wrapf(err, "failed to clean %s", staging)
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the hooks directory")
+	}
+	hooks := make(map[string]bool)
+	repoHooks := filepath.Join(worktreePath, ".wsm", hooksDirName)
+	var gate *setupScriptGate
+	for _, source := range append([]string{existing}, sources...) {
+		if source == "" {
+			continue
+		}
+		var allow func(string) bool
+		if source == repoHooks {
+			if gate == nil {
+				gate = newSetupScriptGate()
+			}
+			allow = func(path string) bool { return gate.allowsFile(workspace, path, "git hook") }
+		}
+		if err := copyHooks(source, staging, hooks, allow); err != nil {
+			_ = os.RemoveAll(staging)
+			return nil, err
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, errors.Wrapf(err, "failed to replace %s", dir)
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return nil, errors.Wrapf(err, "failed to replace %s", dir)
+	}
+
+	if err := wm.enableWorktreeConfig(ctx, workspace, repo); err != nil {
+		return nil, err
+	}
+	if _, err := gitOutput(ctx, worktreePath, worktreeConfigArgs("core.hooksPath", dir)...); err != nil {
+		return nil, errors.Wrapf(err, "failed to set core.hooksPath in %s", repo.Name)
+	}
+	workspace.HookRepositories = appendUnique(workspace.HookRepositories, repo.Name)
+
+	deployment := &HookDeployment{Repository: repo.Name, Path: dir}
+	for hook := range hooks {
+		deployment.Hooks = append(deployment.Hooks, hook)
+	}
+	sort.Strings(deployment.Hooks)
+	return deployment, nil
+}
+
+// hooksPathConfigured reports whether the workspace's git config sets
+// core.hooksPath, which takes precedence over deployed hooks
+func (w *Workspace) hooksPathConfigured() bool {
+	for key := range w.GitConfig {
+		if strings.EqualFold(key, "core.hooksPath") {
+			return true
+		}
+	}
+	return false
+}
+
+// planHooks returns the plan steps of deployHooks for a repository. The hooks
+// come from the repository's checkout, since the worktree does not exist yet;
+// its own hooks that the trust gate would ask about are left out.
+func planHooks(ctx context.Context, workspace *Workspace, repo Repository) []PlanStep {
+	if workspace.NoHooks || workspace.hooksPathConfigured() {
+		return nil
+	}
+	existing, sources := hookSources(ctx, repo, repo.Path)
+	if len(sources) == 0 {
+		return nil
+	}
+
+	dir := workspace.hooksDir(repo.Name)
+	repoHooks := filepath.Join(repo.Path, ".wsm", hooksDirName)
+	gate := planScriptGate()
+	var untrusted []string
+	hooks := map[string]string{}
+	for _, source := range append([]string{existing}, sources...) {
+		if source == "" {
+			continue
+		}
+		var allow func(string) bool
+		if source == repoHooks {
+			allow = func(path string) bool {
+				relPath := filepath.ToSlash(filepath.Join(".wsm", hooksDirName, filepath.Base(path)))
+				if approval, _, err := gate.approval(repo, relPath, path); err != nil || approval == ScriptApprovalAsk {
+					untrusted = append(untrusted, filepath.Base(path))
+					return false
+				}
+				return true
+			}
+		}
+		files, _ := hookFiles(source, allow)
+		for _, file := range files {
+			hooks[filepath.Base(file)] = file
+		}
+	}
+
+	mkdir := PlanStep{
+		Action:      PlanActionMkdir,
+		Description: fmt.Sprintf("Create the hooks directory of %s", repo.Name),
+		Path:        dir,
+	}
+	if len(untrusted) > 0 {
+		mkdir.Note = fmt.Sprintf("untrusted hooks of %s are left out; wsm would ask about them: %s", repo.Name, strings.Join(untrusted, ", "))
+	}
+	steps := []PlanStep{mkdir}
+	names := sortedKeys(hooks)
+	for _, name := range names {
+		steps = append(steps, PlanStep{
+			Action:      PlanActionCopyFile,
+			Description: fmt.Sprintf("Copy hook %s of %s", name, repo.Name),
+			Source:      hooks[name],
+			Path:        filepath.Join(dir, name),
+		})
+	}
+	if len(names) > 0 {
+		steps = append(steps, PlanStep{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Make the hooks of %s executable", repo.Name),
+			Dir:         dir,
+			Command:     append([]string{"chmod", "755"}, names...),
+		})
+	}
+	// planGitConfig enables per-worktree config already
+	if len(workspace.GitConfig) == 0 {
+		steps = append(steps, planEnableWorktreeConfig(ctx, repo)...)
+	}
+	return append(steps, PlanStep{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Point core.hooksPath of %s to the deployed hooks", repo.Name),
+		Dir:         filepath.Join(workspace.Path, repo.Name),
+		Command:     append([]string{"git"}, worktreeConfigArgs("core.hooksPath", dir)...),
+	})
+}
+
+// releaseHooks removes the hooks deployed to a repository's worktree. The
+// worktree's core.hooksPath is unset if the worktree is still there.
+func (wm *WorkspaceManager) releaseHooks(ctx context.Context, workspace *Workspace, repo Repository) {
+	if !containsValue(workspace.HookRepositories, repo.Name) {
+		return
+	}
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	if info, err := os.Lstat(worktreePath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		_, _ = gitOutput(ctx, worktreePath, "config", "--worktree", "--unset", "core.hooksPath")
+	}
+	if err := os.RemoveAll(workspace.hooksDir(repo.Name)); err != nil {
+		output.PrintWarning("Failed to remove the hooks of %s: %v", repo.Name, err)
+	}
+
+	var remaining []string
+	for _, name := range workspace.HookRepositories {
+		if name != repo.Name {
+			remaining = append(remaining, name)
+		}
+	}
+	workspace.HookRepositories = remaining
+}
+
+// SyncHooks deploys the current template and repository hooks to every
+// worktree of a workspace again, and removes the hooks of repositories that
+// no longer have any. The workspace is saved.
+func (wm *WorkspaceManager) SyncHooks(ctx context.Context, workspace *Workspace) ([]HookDeployment, error) {
+	if workspace.NoHooks {
+		return nil, errors.Errorf("workspace '%s' was created with --no-hooks", workspace.Name)
+	}
+	if workspace.hooksPathConfigured() {
+		return nil, errors.Errorf("workspace '%s' sets core.hooksPath with --git-config", workspace.Name)
+	}
+
+	var deployments []HookDeployment
+	for _, repo := range workspace.Repositories {
+		deployment, err := wm.deployHooks(ctx, workspace, repo)
+		if err != nil {
+			return deployments, err
+		}
+		if deployment != nil {
+			deployments = append(deployments, *deployment)
+			continue
+		}
+		if containsValue(workspace.HookRepositories, repo.Name) {
+			wm.releaseHooks(ctx, workspace, repo)
+			deployments = append(deployments, HookDeployment{Repository: repo.Name, Removed: true})
+		}
+	}
+	return deployments, wm.SaveWorkspace(workspace)
+}
+
+// DeployedHooks lists the hooks deployed to the worktrees of a workspace
+func (w *Workspace) DeployedHooks() []HookDeployment {
+	var deployments []HookDeployment
+	for _, repo := range w.Repositories {
+		if !containsValue(w.HookRepositories, repo.Name) {
+			continue
+		}
+		deployment := HookDeployment{Repository: repo.Name, Path: w.hooksDir(repo.Name)}
+		if entries, err := os.ReadDir(deployment.Path); err == nil {
+			for _, entry := range entries {
+				deployment.Hooks = append(deployment.Hooks, entry.Name())
+			}
+		}
+		deployments = append(deployments, deployment)
+	}
+	return deployments
+}
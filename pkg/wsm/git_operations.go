@@ -2,9 +2,12 @@ package wsm
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/go-go-golems/workspace-manager/pkg/output"
@@ -14,6 +17,8 @@ import (
 // GitOperations handles git operations across workspace repositories
 type GitOperations struct {
 	workspace *Workspace
+	// IncludeArchived makes archived (read-only) repositories committable
+	IncludeArchived bool
 }
 
 // NewGitOperations creates a new git operations handler
@@ -31,13 +36,123 @@ type FileChange struct {
 	Staged     bool   `json:"staged"`
 }
 
+// Path returns the current path of the file, resolving "old -> new" renames
+func (fc FileChange) Path() string {
+	if idx := strings.Index(fc.FilePath, " -> "); idx >= 0 {
+		return fc.FilePath[idx+len(" -> "):]
+	}
+	return fc.FilePath
+}
+
 // CommitOperation represents a commit operation across repositories
 type CommitOperation struct {
-	Message string                  `json:"message"`
-	Files   map[string][]FileChange `json:"files"` // repo -> files
-	DryRun  bool                    `json:"dry_run"`
-	AddAll  bool                    `json:"add_all"`
-	Push    bool                    `json:"push"`
+	Message  string                  `json:"message"`
+	Files    map[string][]FileChange `json:"files"` // repo -> files
+	DryRun   bool                    `json:"dry_run"`
+	AddAll   bool                    `json:"add_all"`
+	Push     bool                    `json:"push"`
+	ChangeID string                  `json:"change_id,omitempty"` // Added as a ChangeIDTrailer to every commit
+	Messages map[string]string       `json:"messages,omitempty"`  // repo -> message, overrides Message
+	Confirm  bool                    `json:"confirm,omitempty"`   // Acknowledges pushes to protected branches
+
+	// AllowSecrets commits even when the secret scan of the staged changes
+	// reports findings (see SecretsConfig)
+	AllowSecrets bool `json:"allow_secrets,omitempty"`
+
+	// Signoff adds a Signed-off-by trailer (git commit --signoff), e.g. for the DCO
+	Signoff bool `json:"signoff,omitempty"`
+	// Sign signs commits (git commit -S) with the configured gpg.format, so ssh
+	// signing works too. SigningKey selects a key other than user.signingkey.
+	Sign       bool   `json:"sign,omitempty"`
+	SigningKey string `json:"signing_key,omitempty"`
+	// Authors overrides the commit author per repository ("Name <email>");
+	// the "" entry applies to repositories without their own
+	Authors map[string]string `json:"authors,omitempty"`
+
+	// Groups splits the staged changes of every repository into one commit per
+	// group of paths (see CommitGroup)
+	Groups []CommitGroup `json:"groups,omitempty"`
+}
+
+// AuthorFor returns the author override for a repository, empty for the git default
+func (op *CommitOperation) AuthorFor(repoName string) string {
+	if author, ok := op.Authors[repoName]; ok {
+		return author
+	}
+	return op.Authors[""]
+}
+
+// commitArgs returns the git commit arguments for a repository
+func (op *CommitOperation) commitArgs(repoName, message string) []string {
+	args := []string{"commit"}
+	if op.Signoff {
+		args = append(args, "--signoff")
+	}
+	if op.Sign || op.SigningKey != "" {
+		args = append(args, "--gpg-sign"+keyArg(op.SigningKey))
+	}
+	if author := op.AuthorFor(repoName); author != "" {
+		args = append(args, "--author", author)
+	}
+	return append(args, "-m", message)
+}
+
+func keyArg(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "=" + key
+}
+
+// ParseAuthorSpecs parses author overrides of the form "repo=Name <email>", or
+// "Name <email>" for all repositories
+func ParseAuthorSpecs(specs []string) (map[string]string, error) {
+	authors := make(map[string]string)
+	for _, spec := range specs {
+		repoName, author := "", strings.TrimSpace(spec)
+		if before, after, ok := strings.Cut(spec, "="); ok && !strings.Contains(before, "<") {
+			repoName, author = strings.TrimSpace(before), strings.TrimSpace(after)
+		}
+		emailStart, emailEnd := strings.Index(author, "<"), strings.LastIndex(author, ">")
+		if emailStart <= 0 || emailEnd < emailStart+2 || emailEnd != len(author)-1 {
+			return nil, errors.Errorf("invalid author '%s' (expected [repo=]Name <email>)", spec)
+		}
+		if existing, ok := authors[repoName]; ok && existing != author {
+			return nil, errors.Errorf("author of '%s' given twice (%s and %s)", repoNameOrAll(repoName), existing, author)
+		}
+		authors[repoName] = author
+	}
+	return authors, nil
+}
+
+func repoNameOrAll(repoName string) string {
+	if repoName == "" {
+		return "all repositories"
+	}
+	return repoName
+}
+
+// MessageFor returns the commit message to use for a repository
+func (op *CommitOperation) MessageFor(repoName string) string {
+	if msg, ok := op.Messages[repoName]; ok && msg != "" {
+		return msg
+	}
+	return op.Message
+}
+
+// ChangeIDTrailer is the git trailer used to correlate commits belonging to one workspace change
+const ChangeIDTrailer = "Workspace-Change-Id"
+
+// NewChangeID generates a random UUID (v4) to correlate commits across repositories
+func NewChangeID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate change id")
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:]), nil
 }
 
 // GetWorkspaceChanges gets all changes across workspace repositories
@@ -45,6 +160,10 @@ func (gops *GitOperations) GetWorkspaceChanges(ctx context.Context) (map[string]
 	changes := make(map[string][]FileChange)
 
 	for _, repo := range gops.workspace.Repositories {
+		// Pinned and archived repositories are read-only
+		if gops.workspace.PinFor(repo.Name) != "" || (repo.Archived && !gops.IncludeArchived) {
+			continue
+		}
 		repoPath := filepath.Join(gops.workspace.Path, repo.Name)
 		repoChanges, err := gops.getRepositoryChanges(ctx, repo.Name, repoPath)
 		if err != nil {
@@ -68,6 +187,10 @@ func (gops *GitOperations) getRepositoryChanges(ctx context.Context, repoName, r
 		return nil, errors.Wrapf(err, "failed to get git status for %s", repoName)
 	}
 
+	// Unstaged and untracked files matched by .wsmignore are left out; staged
+	// files were added on purpose and are always reported
+	ignoreRules := loadIgnoreRulesOrEmpty(repoPath)
+
 	var changes []FileChange
 	lines := strings.Split(string(output), "\n")
 
@@ -83,6 +206,7 @@ func (gops *GitOperations) getRepositoryChanges(ctx context.Context, repoName, r
 		indexStatus := line[0]
 		workTreeStatus := line[1]
 		filePath := strings.TrimSpace(line[2:])
+		ignored := ignoreRules.Match(FileChange{FilePath: filePath}.Path())
 
 		// Handle staged changes
 		if indexStatus != ' ' && indexStatus != '?' {
@@ -95,7 +219,7 @@ func (gops *GitOperations) getRepositoryChanges(ctx context.Context, repoName, r
 		}
 
 		// Handle unstaged changes
-		if workTreeStatus != ' ' && workTreeStatus != '?' {
+		if workTreeStatus != ' ' && workTreeStatus != '?' && !ignored {
 			changes = append(changes, FileChange{
 				Repository: repoName,
 				FilePath:   filePath,
@@ -105,7 +229,7 @@ func (gops *GitOperations) getRepositoryChanges(ctx context.Context, repoName, r
 		}
 
 		// Handle untracked files
-		if indexStatus == '?' && workTreeStatus == '?' {
+		if indexStatus == '?' && workTreeStatus == '?' && !ignored {
 			changes = append(changes, FileChange{
 				Repository: repoName,
 				FilePath:   filePath,
@@ -166,10 +290,31 @@ func (gops *GitOperations) CommitChanges(ctx context.Context, operation *CommitO
 		return gops.previewCommit(ctx, operation)
 	}
 
+	// Refuse up front rather than after committing if a push would violate the policy
+	if operation.Push {
+		if err := gops.checkPushPolicy(ctx, operation); err != nil {
+			return err
+		}
+	}
+
+	// Local replace directives pointing at sibling worktrees must not be committed
+	if gops.workspace.GoReplaces {
+		restore, err := suspendGoReplaces(ctx, gops.workspace)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
 	var errors []string
 	var successfulRepos []string
+	var processedRepos []string
+	var stagedRepos []string
+	// parents are the HEADs before committing, recorded for `wsm commit undo`
+	parents := make(map[string]string)
 
-	for repoName, files := range operation.Files {
+	for _, repoName := range sortedRepositoryKeys(operation.Files) {
+		files := operation.Files[repoName]
 		repoPath := filepath.Join(gops.workspace.Path, repoName)
 
 		// Stage files if needed
@@ -182,7 +327,7 @@ func (gops *GitOperations) CommitChanges(ctx context.Context, operation *CommitO
 			// Stage only selected files
 			for _, file := range files {
 				if !file.Staged {
-					if err := gops.StageFile(ctx, repoName, file.FilePath); err != nil {
+					if err := gops.StageFile(ctx, repoName, file.Path()); err != nil {
 						errors = append(errors, fmt.Sprintf("%s: %v", repoName, err))
 						continue
 					}
@@ -203,8 +348,39 @@ func (gops *GitOperations) CommitChanges(ctx context.Context, operation *CommitO
 			continue
 		}
 
+		stagedRepos = append(stagedRepos, repoName)
+	}
+
+	// Nothing is committed while any repository stages a possible secret
+	if !operation.AllowSecrets {
+		if err := gops.checkStagedSecrets(ctx, stagedRepos); err != nil {
+			return err
+		}
+	}
+
+	for _, repoName := range stagedRepos {
+		if err := checkInterrupted(ctx, "commit", processedRepos, remainingRepositories(operation.Files, processedRepos)); err != nil {
+			return err
+		}
+		processedRepos = append(processedRepos, repoName)
+		repoPath := filepath.Join(gops.workspace.Path, repoName)
+
+		parents[repoName], _ = gitOutput(ctx, repoPath, "rev-parse", "--verify", "-q", "HEAD")
+
 		// Commit changes
-		if err := gops.commitRepository(ctx, repoName, repoPath, operation.Message); err != nil {
+		message := ExpandCommitMessage(operation.MessageFor(repoName), gops.workspace, repoName)
+		trailer := ""
+		if operation.ChangeID != "" {
+			message = strings.TrimRight(message, "\n")
+			trailer = fmt.Sprintf("\n\n%s: %s", ChangeIDTrailer, operation.ChangeID)
+		}
+		if len(operation.Groups) > 0 {
+			commits, err := gops.commitSplit(ctx, repoName, repoPath, operation, message, trailer)
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("%s: %v (%d commits made)", repoName, err, commits))
+				continue
+			}
+		} else if err := gops.commitRepository(ctx, repoName, repoPath, operation.commitArgs(repoName, message+trailer)); err != nil {
 			errors = append(errors, fmt.Sprintf("%s: %v", repoName, err))
 			continue
 		}
@@ -212,10 +388,28 @@ func (gops *GitOperations) CommitChanges(ctx context.Context, operation *CommitO
 		successfulRepos = append(successfulRepos, repoName)
 	}
 
+	if len(successfulRepos) > 0 {
+		if err := recordWorkspaceCommit(ctx, gops.workspace, operation, parents, successfulRepos); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to record the commit for 'wsm commit undo': %v", err),
+				"Failed to record workspace commit",
+				"workspace", gops.workspace.Name,
+				"error", err,
+			)
+		}
+	}
+
 	// Push changes if requested
 	if operation.Push && len(successfulRepos) > 0 {
-		for _, repoName := range successfulRepos {
+		for i, repoName := range successfulRepos {
+			if err := checkInterrupted(ctx, "push", successfulRepos[:i], successfulRepos[i:]); err != nil {
+				return err
+			}
 			repoPath := filepath.Join(gops.workspace.Path, repoName)
+			if err := checkRepositoryLargeFiles(ctx, repoName, repoPath); err != nil {
+				errors = append(errors, fmt.Sprintf("%s push: %v", repoName, err))
+				continue
+			}
 			if err := gops.pushRepository(ctx, repoName, repoPath); err != nil {
 				errors = append(errors, fmt.Sprintf("%s push: %v", repoName, err))
 			}
@@ -232,24 +426,65 @@ func (gops *GitOperations) CommitChanges(ctx context.Context, operation *CommitO
 		"repositories", successfulRepos,
 		"message", operation.Message,
 		"pushed", operation.Push,
+		"changeID", operation.ChangeID,
 	)
 
 	return nil
 }
 
+// sortedRepositoryKeys returns the repositories of a change set in a stable order
+func sortedRepositoryKeys(files map[string][]FileChange) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// remainingRepositories returns the repositories of a change set not yet processed
+func remainingRepositories(files map[string][]FileChange, processed []string) []string {
+	var remaining []string
+	for _, name := range sortedRepositoryKeys(files) {
+		if !containsValue(processed, name) {
+			remaining = append(remaining, name)
+		}
+	}
+	return remaining
+}
+
 // previewCommit shows what would be committed
 func (gops *GitOperations) previewCommit(ctx context.Context, operation *CommitOperation) error {
 	fmt.Printf("Commit Preview:\n")
-	fmt.Printf("Message: %s\n\n", operation.Message)
+	fmt.Printf("Message: %s\n", operation.Message)
+	if operation.ChangeID != "" {
+		fmt.Printf("Trailer: %s: %s\n", ChangeIDTrailer, operation.ChangeID)
+	}
+	if operation.Signoff {
+		fmt.Printf("Sign-off: yes\n")
+	}
+	if operation.SigningKey != "" {
+		fmt.Printf("Signed: yes (key %s)\n", operation.SigningKey)
+	} else if operation.Sign {
+		fmt.Printf("Signed: yes\n")
+	}
+	fmt.Println()
 
 	for repoName, files := range operation.Files {
 		fmt.Printf("Repository: %s\n", repoName)
+		if msg := ExpandCommitMessage(operation.MessageFor(repoName), gops.workspace, repoName); msg != operation.Message {
+			fmt.Printf("  Message: %s\n", msg)
+		}
+		if author := operation.AuthorFor(repoName); author != "" {
+			fmt.Printf("  Author: %s\n", author)
+		}
+		if len(operation.Groups) > 0 {
+			previewCommitGroups(operation.Groups, files)
+			fmt.Println()
+			continue
+		}
 		for _, file := range files {
-			status := "+"
-			if file.Staged {
-				status = "✓"
-			}
-			fmt.Printf("  %s %s (%s)\n", status, file.FilePath, file.Status)
+			printPreviewFile(file)
 		}
 		fmt.Println()
 	}
@@ -261,9 +496,54 @@ func (gops *GitOperations) previewCommit(ctx context.Context, operation *CommitO
 	return nil
 }
 
-// stageAllFiles stages all changes in a repository
+// previewCommitGroups shows the commits a repository's changes are split into
+func previewCommitGroups(groups []CommitGroup, files []FileChange) {
+	byGroup := make([][]FileChange, len(groups)+1)
+	for _, file := range files {
+		i := CommitGroupFor(file.Path(), groups)
+		byGroup[i] = append(byGroup[i], file)
+	}
+	for i, groupFiles := range byGroup {
+		if len(groupFiles) == 0 {
+			continue
+		}
+		fmt.Printf("  Commit of %s files:\n", groupName(groups, i))
+		for _, file := range groupFiles {
+			fmt.Print("  ")
+			printPreviewFile(file)
+		}
+	}
+}
+
+func printPreviewFile(file FileChange) {
+	status := "+"
+	if file.Staged {
+		status = "✓"
+	}
+	fmt.Printf("  %s %s (%s)\n", status, file.FilePath, file.Status)
+}
+
+// stageAllFiles stages all changes in a repository, except files matched by .wsmignore
 func (gops *GitOperations) stageAllFiles(ctx context.Context, repoName, repoPath string) error {
-	cmd := exec.CommandContext(ctx, "git", "add", ".")
+	ignoreRules, err := LoadIgnoreRules(repoPath)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"add", "."}
+	if !ignoreRules.Empty() {
+		changed, err := gitOutput(ctx, repoPath, "ls-files", "--modified", "--deleted", "--others", "--exclude-standard")
+		if err != nil {
+			return errors.Wrapf(err, "failed to list changed files in %s", repoName)
+		}
+		files := ignoreRules.Filter(splitLines(changed))
+		if len(files) == 0 {
+			return nil
+		}
+		args = append([]string{"add", "-A", "--"}, files...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repoPath
 
 	if output, err := cmd.CombinedOutput(); err != nil {
@@ -292,33 +572,89 @@ func (gops *GitOperations) hasStagedChanges(ctx context.Context, repoPath string
 }
 
 // commitRepository commits changes in a single repository
-func (gops *GitOperations) commitRepository(ctx context.Context, repoName, repoPath, message string) error {
-	cmd := exec.CommandContext(ctx, "git", "commit", "-m", message)
+func (gops *GitOperations) commitRepository(ctx context.Context, repoName, repoPath string, args []string) error {
+	ctx, cancel := GitContext(ctx, args...)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repoPath
 
 	cmdOutput, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "failed to commit in %s: %s", repoName, string(cmdOutput))
+		return errors.Wrapf(GitError(ctx, err), "failed to commit in %s: %s", repoName, string(cmdOutput))
 	}
 
 	output.LogInfo(
 		fmt.Sprintf("Committed changes to %s", repoName),
 		"Repository committed successfully",
 		"repository", repoName,
-		"message", message,
+		"args", args,
 	)
 
 	return nil
 }
 
+// checkPushPolicy validates that pushing the current branch of every committed repository is allowed
+func (gops *GitOperations) checkPushPolicy(ctx context.Context, operation *CommitOperation) error {
+	policy, err := LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	for repoName := range operation.Files {
+		repoPath := filepath.Join(gops.workspace.Path, repoName)
+		branch, err := getGitCurrentBranch(ctx, repoPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get current branch of %s", repoName)
+		}
+
+		if err := policy.Check(PolicyCheck{
+			Action:     PolicyActionPush,
+			Repository: repoName,
+			Branch:     branch,
+			Confirmed:  operation.Confirm,
+		}); err != nil {
+			return errors.Wrap(err, repoName)
+		}
+	}
+
+	return nil
+}
+
 // pushRepository pushes changes in a single repository
 func (gops *GitOperations) pushRepository(ctx context.Context, repoName, repoPath string) error {
-	cmd := exec.CommandContext(ctx, "git", "push")
+	if repo, ok := gops.workspace.repository(repoName); ok && gops.workspace.Fork != nil {
+		if err := PushToFork(ctx, repo, repoPath, gops.workspace.Fork); err != nil {
+			return err
+		}
+		output.LogInfo(
+			fmt.Sprintf("Pushed changes to the fork of %s", repoName),
+			"Repository pushed to fork",
+			"repository", repoName,
+			"remote", gops.workspace.Fork.RemoteName(),
+		)
+		return nil
+	}
+
+	if err := CheckGoReplacesNotCommitted(ctx, repoPath); err != nil {
+		return err
+	}
+
+	// Branches without an upstream are pushed to the primary remote and track it
+	args := []string{"push"}
+	if _, err := gitOutput(ctx, repoPath, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		args = append(args, "-u", gops.workspace.RemoteOf(repoName), "HEAD")
+	}
+
+	ctx, cancel := GitContext(ctx, "push")
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = repoPath
 
 	cmdOutput, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "failed to push %s: %s", repoName, string(cmdOutput))
+		return errors.Wrapf(GitError(ctx, err), "failed to push %s: %s", repoName, string(cmdOutput))
 	}
 
 	output.LogInfo(
@@ -330,48 +666,184 @@ func (gops *GitOperations) pushRepository(ctx context.Context, repoName, repoPat
 	return nil
 }
 
-// GetDiff gets unified diff across repositories
-func (gops *GitOperations) GetDiff(ctx context.Context, staged bool, repoFilter string) (string, error) {
+// DiffOptions selects and formats the diffs of a workspace
+type DiffOptions struct {
+	Staged bool
+	// Repository limits the diff to one repository
+	Repository string
+	// SinceCreation diffs against the commit each worktree was created from,
+	// covering commits and uncommitted changes
+	SinceCreation bool
+	// Base diffs against the merge base with each repository's default branch
+	// (see BaseRefFor), covering commits and uncommitted changes
+	Base bool
+	// Stat produces a diffstat instead of the patch
+	Stat bool
+	// Color asks git for colored output
+	Color bool
+}
+
+// RepositoryDiff is the diff of one repository of a workspace
+type RepositoryDiff struct {
+	Repository string `json:"repository"`
+	Diff       string `json:"diff"`
+	// Summary is the one-line summary of git diff --shortstat
+	Summary string `json:"summary"`
+}
+
+// GetDiff gets unified diff across repositories. With sinceCreation the diff
+// covers everything changed since each worktree was created, commits included.
+func (gops *GitOperations) GetDiff(ctx context.Context, staged bool, repoFilter string, sinceCreation bool) (string, error) {
+	diffs, err := gops.GetRepositoryDiffs(ctx, DiffOptions{Staged: staged, Repository: repoFilter, SinceCreation: sinceCreation})
+	if err != nil {
+		return "", err
+	}
+
 	var allDiffs []string
+	for _, diff := range diffs {
+		header := fmt.Sprintf("=== Repository: %s ===", diff.Repository)
+		allDiffs = append(allDiffs, header, diff.Diff)
+	}
+
+	if len(allDiffs) == 0 {
+		return "No changes found in workspace.", nil
+	}
+
+	return strings.Join(allDiffs, "\n"), nil
+}
+
+// GetRepositoryDiffs returns the diff of every repository with changes, in
+// workspace order
+func (gops *GitOperations) GetRepositoryDiffs(ctx context.Context, options DiffOptions) ([]RepositoryDiff, error) {
+	var diffs []RepositoryDiff
 
 	for _, repo := range gops.workspace.Repositories {
-		if repoFilter != "" && repo.Name != repoFilter {
+		if options.Repository != "" && repo.Name != options.Repository {
 			continue
 		}
 
 		repoPath := filepath.Join(gops.workspace.Path, repo.Name)
-		diff, err := gops.getRepositoryDiff(ctx, repo.Name, repoPath, staged)
+		base, err := gops.diffBase(ctx, repo, repoPath, options)
+		if err != nil {
+			return nil, err
+		}
+		args, err := gops.diffArgs(ctx, repo.Name, repoPath, options.Staged, base)
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to get diff for %s", repo.Name)
+			return nil, errors.Wrapf(err, "failed to get diff for %s", repo.Name)
+		}
+		if args == nil {
+			continue
 		}
 
-		if diff != "" {
-			header := fmt.Sprintf("=== Repository: %s ===", repo.Name)
-			allDiffs = append(allDiffs, header, diff)
+		format := []string{}
+		if options.Stat {
+			format = append(format, "--stat")
+		}
+		if options.Color {
+			format = append(format, "--color=always")
+		}
+		diff, err := gitDiff(ctx, repoPath, args, format...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get diff for %s", repo.Name)
+		}
+		if diff == "" {
+			continue
 		}
-	}
 
-	if len(allDiffs) == 0 {
-		return "No changes found in workspace.", nil
+		summary, err := gitDiff(ctx, repoPath, args, "--shortstat")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get diff summary for %s", repo.Name)
+		}
+		diffs = append(diffs, RepositoryDiff{Repository: repo.Name, Diff: diff, Summary: strings.TrimSpace(summary)})
 	}
 
-	return strings.Join(allDiffs, "\n"), nil
+	return diffs, nil
+}
+
+// diffBase returns the commit the diff of a repository starts from, empty for
+// the changes not committed yet
+func (gops *GitOperations) diffBase(ctx context.Context, repo Repository, repoPath string, options DiffOptions) (string, error) {
+	switch {
+	case options.SinceCreation:
+		return gops.workspace.CreationCommit(ctx, repo)
+	case options.Base:
+		baseRef := gops.workspace.BaseRefFor(ctx, repo.Name, repoPath)
+		commit, err := gitOutput(ctx, repoPath, "merge-base", "HEAD", baseRef)
+		if err != nil {
+			return "", errors.Wrapf(err, "no merge base with %s in %s", baseRef, repo.Name)
+		}
+		return commit, nil
+	default:
+		return "", nil
+	}
 }
 
-// getRepositoryDiff gets diff for a single repository
-func (gops *GitOperations) getRepositoryDiff(ctx context.Context, repoName, repoPath string, staged bool) (string, error) {
+// GetFileDiff gets the diff of a single changed file, including untracked files
+func (gops *GitOperations) GetFileDiff(ctx context.Context, change FileChange) (string, error) {
+	repoPath := filepath.Join(gops.workspace.Path, change.Repository)
+	filePath := change.Path()
+
 	var cmd *exec.Cmd
-	if staged {
-		cmd = exec.CommandContext(ctx, "git", "diff", "--cached")
-	} else {
-		cmd = exec.CommandContext(ctx, "git", "diff")
+	switch {
+	case change.Status == "?":
+		cmd = exec.CommandContext(ctx, "git", "diff", "--no-index", "--", "/dev/null", filePath)
+	case change.Staged:
+		cmd = exec.CommandContext(ctx, "git", "diff", "--cached", "--", filePath)
+	default:
+		cmd = exec.CommandContext(ctx, "git", "diff", "--", filePath)
 	}
 	cmd.Dir = repoPath
 
 	output, err := cmd.Output()
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to get diff for %s", repoName)
+		// git diff --no-index exits with 1 when the files differ
+		if exitError, ok := err.(*exec.ExitError); !ok || exitError.ExitCode() != 1 {
+			return "", errors.Wrapf(err, "failed to get diff for %s in %s", filePath, change.Repository)
+		}
+	}
+
+	return string(output), nil
+}
+
+// diffArgs returns the git diff arguments of a repository, against base if
+// set, leaving out files matched by .wsmignore; nil when every changed file is ignored
+func (gops *GitOperations) diffArgs(ctx context.Context, repoName, repoPath string, staged bool, base string) ([]string, error) {
+	args := []string{"diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if base != "" {
+		args = append(args, base)
+	}
+
+	ignoreRules := loadIgnoreRulesOrEmpty(repoPath)
+	if !ignoreRules.Empty() {
+		changed, err := gitOutput(ctx, repoPath, append(args, "--name-only")...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list changed files in %s", repoName)
+		}
+		files := splitLines(changed)
+		kept := ignoreRules.Filter(files)
+		if len(kept) == 0 {
+			return nil, nil
+		}
+		if len(kept) < len(files) {
+			args = append(append(args, "--"), kept...)
+		}
 	}
+	return args, nil
+}
 
+// gitDiff runs git diff with its formatting options placed before the pathspec
+func gitDiff(ctx context.Context, repoPath string, args []string, format ...string) (string, error) {
+	full := append([]string{args[0]}, format...)
+	full = append(full, args[1:]...)
+
+	cmd := exec.CommandContext(ctx, "git", full...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
 	return string(output), nil
 }
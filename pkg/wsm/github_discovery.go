@@ -0,0 +1,237 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// GitHubOrgOptions selects the repositories of a GitHub organization to register
+type GitHubOrgOptions struct {
+	Org string
+	// Topics keeps repositories with any of these topics, empty for all
+	Topics []string
+	// Languages keeps repositories whose primary language is one of these, empty for all
+	Languages       []string
+	IncludeArchived bool
+	IncludeForks    bool
+	// SSH registers ssh remote URLs instead of https ones
+	SSH   bool
+	Limit int
+	// Clone makes the on-demand clones of the repositories partial or shallow
+	Clone CloneOptions
+}
+
+// IsRemoteOnly reports whether a registry entry is known by its remote URL
+// only and has not been cloned yet
+func (r Repository) IsRemoteOnly() bool {
+	return r.Path == "" && r.RemoteURL != ""
+}
+
+// registryKey identifies a registry entry: its path, or its remote URL when
+// it has not been cloned
+func (r Repository) registryKey() string {
+	if r.IsRemoteOnly() {
+		return "remote:" + normalizeRemoteURL(r.RemoteURL)
+	}
+	return r.Path
+}
+
+// ListGitHubOrgRepositories lists the repositories of a GitHub organization
+// through the gh CLI, which handles authentication
+func ListGitHubOrgRepositories(ctx context.Context, opts GitHubOrgOptions) ([]Repository, error) {
+	if err := RequireTool(ctx, "gh"); err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+	args := []string{"repo", "list", opts.Org, "--limit", fmt.Sprint(limit),
+		"--json", "name,url,sshUrl,primaryLanguage,repositoryTopics,isArchived,isFork"}
+
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, errors.Errorf("gh repo list failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, errors.Wrap(err, "gh repo list failed")
+	}
+
+	var ghRepos []struct {
+		Name            string `json:"name"`
+		URL             string `json:"url"`
+		SSHURL          string `json:"sshUrl"`
+		PrimaryLanguage *struct {
+			Name string `json:"name"`
+		} `json:"primaryLanguage"`
+		RepositoryTopics []struct {
+			Name string `json:"name"`
+		} `json:"repositoryTopics"`
+		IsArchived bool `json:"isArchived"`
+		IsFork     bool `json:"isFork"`
+	}
+	if err := json.Unmarshal(out, &ghRepos); err != nil {
+		return nil, errors.Wrap(err, "failed to parse gh repo list output")
+	}
+
+	var repos []Repository
+	for _, ghRepo := range ghRepos {
+		if (ghRepo.IsArchived && !opts.IncludeArchived) || (ghRepo.IsFork && !opts.IncludeForks) {
+			continue
+		}
+
+		var topics []string
+		for _, topic := range ghRepo.RepositoryTopics {
+			topics = append(topics, topic.Name)
+		}
+		language := ""
+		if ghRepo.PrimaryLanguage != nil {
+			language = strings.ToLower(ghRepo.PrimaryLanguage.Name)
+		}
+		if !matchesAny(topics, opts.Topics) || (len(opts.Languages) > 0 && !matchesAny([]string{language}, opts.Languages)) {
+			continue
+		}
+
+		remoteURL := ghRepo.URL + ".git"
+		if opts.SSH {
+			remoteURL = ghRepo.SSHURL
+		}
+
+		// Topics and the language become tags, so they can be used with --tags and groups
+		categories := append([]string{}, topics...)
+		if language != "" {
+			categories = appendUnique(categories, language)
+		}
+
+		repo := Repository{
+			Name:       ghRepo.Name,
+			RemoteURL:  remoteURL,
+			Categories: categories,
+			Archived:   ghRepo.IsArchived,
+		}
+		if !opts.Clone.IsZero() {
+			cloneOptions := opts.Clone
+			repo.CloneOptions = &cloneOptions
+		}
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+// matchesAny reports whether values contains any of wanted (case-insensitive); an
+// empty wanted matches everything
+func matchesAny(values, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, w := range wanted {
+			if strings.EqualFold(value, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RegisterRemoteRepositories adds repositories known only by their remote URL.
+// Repositories whose remote or name is already registered are skipped.
+func (rd *RepositoryDiscoverer) RegisterRemoteRepositories(repos []Repository) (added []string, skipped []string) {
+	for _, repo := range repos {
+		if rd.isRegistered(repo) {
+			skipped = append(skipped, repo.Name)
+			continue
+		}
+		repo.Path = ""
+		rd.registry.Repositories = append(rd.registry.Repositories, repo)
+		added = append(added, repo.Name)
+	}
+	return added, skipped
+}
+
+func (rd *RepositoryDiscoverer) isRegistered(repo Repository) bool {
+	wanted := normalizeRemoteURL(repo.RemoteURL)
+	for _, existing := range rd.registry.Repositories {
+		if existing.Name == repo.Name {
+			return true
+		}
+		if existing.RemoteURL != "" && normalizeRemoteURL(existing.RemoteURL) == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteOnlyMatch returns the remote-only registry entry a local clone replaces
+func remoteOnlyMatch(existing []Repository, discovered Repository) (Repository, bool) {
+	if discovered.RemoteURL == "" {
+		return Repository{}, false
+	}
+	wanted := normalizeRemoteURL(discovered.RemoteURL)
+	for _, repo := range existing {
+		if repo.IsRemoteOnly() && normalizeRemoteURL(repo.RemoteURL) == wanted {
+			return repo, true
+		}
+	}
+	return Repository{}, false
+}
+
+// EnsureCloned clones the remote-only repositories among repos into the clone
+// directory (CloneDir, by default ~/code) and returns them with their local
+// paths. With dryRun nothing is cloned and the future paths are returned.
+func (wm *WorkspaceManager) EnsureCloned(ctx context.Context, repos []Repository, dryRun bool) ([]Repository, error) {
+	cloneDir, err := resolveCloneDir(wm.CloneDir)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]Repository, 0, len(repos))
+	for _, repo := range repos {
+		if !repo.IsRemoteOnly() {
+			resolved = append(resolved, repo)
+			continue
+		}
+
+		targetPath := filepath.Join(cloneDir, repo.Name)
+		if dryRun {
+			repo.Path = targetPath
+			resolved = append(resolved, repo)
+			continue
+		}
+
+		output.PrintInfo("Repository %s is not cloned yet", repo.Name)
+		options := repo.cloneOptions()
+		entry := ManifestRepository{Name: repo.Name, URL: repo.RemoteURL, Filter: options.Filter, Depth: options.Depth}
+		cloned, err := wm.cloneManifestRepository(ctx, entry, targetPath)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, *cloned)
+	}
+
+	return resolved, nil
+}
+
+// resolveCloneDir returns the directory missing repositories are cloned into
+func resolveCloneDir(cloneDir string) (string, error) {
+	if cloneDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cloneDir = filepath.Join(home, "code")
+	}
+	return expandHome(cloneDir), nil
+}
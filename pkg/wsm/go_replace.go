@@ -0,0 +1,239 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// goReplaceMarker tags the replace directives wsm manages, so hand-written ones are never touched
+const goReplaceMarker = "// wsm:replace"
+
+// GoReplace is a replace directive pointing a consumer at a sibling worktree
+type GoReplace struct {
+	Repository string `json:"repository"`
+	Module     string `json:"module"`
+	Path       string `json:"path"`
+}
+
+// ApplyGoReplaces adds a replace directive to the go.mod of every workspace
+// repository requiring the module of another workspace repository, pointing at
+// its worktree. This is the go.work alternative for modules on Go versions
+// without workspace support. Stale directives from earlier runs are dropped.
+func ApplyGoReplaces(workspace *Workspace) ([]GoReplace, error) {
+	wanted, err := wantedGoReplaces(workspace, func(repo Repository) string {
+		return filepath.Join(workspace.Path, repo.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []GoReplace
+	for _, repo := range workspace.Repositories {
+		goModPath := filepath.Join(workspace.Path, repo.Name, "go.mod")
+		if _, err := os.Stat(goModPath); err != nil {
+			continue
+		}
+		replaces, err := setGoReplaces(goModPath, wanted[repo.Name])
+		if err != nil {
+			return applied, errors.Wrapf(err, "failed to update go.mod of %s", repo.Name)
+		}
+		applied = append(applied, replaces...)
+	}
+	return applied, nil
+}
+
+// wantedGoReplaces returns the replace directives of each repository of a
+// workspace, by repository name, reading the go.mod files in dir(repo)
+func wantedGoReplaces(workspace *Workspace, dir func(Repository) string) (map[string][]GoReplace, error) {
+	graph, err := BuildDependencyGraph(workspace.Repositories, dir, GraphOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string][]GoReplace)
+	for _, edge := range graph.Edges {
+		if edge.Kind != DependencyGo {
+			continue
+		}
+		wanted[edge.From] = append(wanted[edge.From], GoReplace{
+			Repository: edge.From,
+			Module:     edge.Via,
+			Path:       "../" + edge.To,
+		})
+	}
+	return wanted, nil
+}
+
+// planGoReplaces returns the plan steps of ApplyGoReplaces for a new
+// workspace, from the go.mod files of the repositories' checkouts
+func planGoReplaces(workspace *Workspace) ([]PlanStep, error) {
+	if !workspace.GoReplaces {
+		return nil, nil
+	}
+	wanted, err := wantedGoReplaces(workspace, func(repo Repository) string {
+		return repo.Path
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []PlanStep
+	for _, repo := range workspace.Repositories {
+		data, err := os.ReadFile(filepath.Join(repo.Path, "go.mod"))
+		if err != nil {
+			continue
+		}
+		goModPath := filepath.Join(workspace.Path, repo.Name, "go.mod")
+		out, _, err := renderGoReplaces(goModPath, data, wanted[repo.Name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to update go.mod of %s", repo.Name)
+		}
+		if bytes.Equal(out, data) {
+			continue
+		}
+		steps = append(steps, PlanStep{
+			Action:      PlanActionWriteFile,
+			Description: fmt.Sprintf("Add replace directives to the go.mod of %s", repo.Name),
+			Path:        goModPath,
+			Content:     string(out),
+		})
+	}
+	return steps, nil
+}
+
+// RemoveGoReplaces drops the replace directives added by ApplyGoReplaces from
+// the go.mod of every workspace repository
+func RemoveGoReplaces(workspace *Workspace) error {
+	for _, repo := range workspace.Repositories {
+		goModPath := filepath.Join(workspace.Path, repo.Name, "go.mod")
+		if _, err := os.Stat(goModPath); err != nil {
+			continue
+		}
+		if _, err := setGoReplaces(goModPath, nil); err != nil {
+			return errors.Wrapf(err, "failed to update go.mod of %s", repo.Name)
+		}
+	}
+	return nil
+}
+
+// setGoReplaces makes the managed replace directives of a go.mod match replaces
+// and returns the ones it kept or added. A module already replaced by hand is skipped.
+func setGoReplaces(goModPath string, replaces []GoReplace) ([]GoReplace, error) {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	out, applied, err := renderGoReplaces(goModPath, data, replaces)
+	if err != nil || bytes.Equal(out, data) {
+		return applied, err
+	}
+	return applied, os.WriteFile(goModPath, out, 0644)
+}
+
+// renderGoReplaces returns the content of a go.mod, data, with the managed
+// replace directives of setGoReplaces
+func renderGoReplaces(goModPath string, data []byte, replaces []GoReplace) ([]byte, []GoReplace, error) {
+	modFile, err := modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse %s", goModPath)
+	}
+
+	manual := make(map[string]bool)
+	for _, replace := range append([]*modfile.Replace{}, modFile.Replace...) {
+		if !isManagedReplace(replace) {
+			manual[replace.Old.Path] = true
+			continue
+		}
+		if err := modFile.DropReplace(replace.Old.Path, replace.Old.Version); err != nil {
+			return nil, nil, err
+		}
+	}
+	modFile.Cleanup()
+
+	var applied []GoReplace
+	for _, replace := range replaces {
+		if manual[replace.Module] {
+			continue
+		}
+		if err := modFile.AddReplace(replace.Module, "", replace.Path, ""); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to replace %s", replace.Module)
+		}
+		for _, added := range modFile.Replace {
+			if added.Old.Path == replace.Module && added.Old.Version == "" {
+				added.Syntax.Comments.Suffix = append(added.Syntax.Comments.Suffix, modfile.Comment{Token: goReplaceMarker, Suffix: true})
+			}
+		}
+		applied = append(applied, replace)
+	}
+
+	return modfile.Format(modFile.Syntax), applied, nil
+}
+
+func isManagedReplace(replace *modfile.Replace) bool {
+	if replace.Syntax == nil {
+		return false
+	}
+	for _, comment := range replace.Syntax.Comments.Suffix {
+		if strings.TrimSpace(comment.Token) == goReplaceMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckGoReplacesNotCommitted fails when the committed go.mod of a repository
+// still contains replace directives managed by wsm, which must never be pushed
+func CheckGoReplacesNotCommitted(ctx context.Context, repoPath string) error {
+	committed, err := gitOutput(ctx, repoPath, "show", "HEAD:go.mod")
+	if err != nil {
+		// No go.mod in HEAD
+		return nil
+	}
+	if strings.Contains(committed, goReplaceMarker) {
+		return errors.Errorf("the committed go.mod of %s contains local replace directives (%s); amend the commit after 'wsm go-replace remove'",
+			filepath.Base(repoPath), goReplaceMarker)
+	}
+	return nil
+}
+
+// suspendGoReplaces removes the managed replace directives of a workspace before
+// a commit, from the index too when go.mod is staged. The returned function
+// restores them.
+func suspendGoReplaces(ctx context.Context, workspace *Workspace) (func(), error) {
+	if err := RemoveGoReplaces(workspace); err != nil {
+		return nil, errors.Wrap(err, "failed to remove local replace directives before commit")
+	}
+
+	restore := func() {
+		if _, err := ApplyGoReplaces(workspace); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to restore local replace directives: %v", err),
+				"Failed to restore local replace directives, run 'wsm go-replace apply'",
+				"workspace", workspace.Name,
+				"error", err,
+			)
+		}
+	}
+
+	for _, repo := range workspace.Repositories {
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		staged, err := gitOutput(ctx, repoPath, "diff", "--cached", "--", "go.mod")
+		if err != nil || !strings.Contains(staged, goReplaceMarker) {
+			continue
+		}
+		if _, err := gitOutput(ctx, repoPath, "add", "--", "go.mod"); err != nil {
+			restore()
+			return nil, errors.Wrapf(err, "failed to stage go.mod of %s without local replace directives", repo.Name)
+		}
+	}
+
+	return restore, nil
+}
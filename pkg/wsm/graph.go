@@ -0,0 +1,241 @@
+package wsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Dependency kinds of graph edges
+const (
+	DependencyGo   = "go"
+	DependencyNode = "node"
+)
+
+// DependencyEdge records that one repository depends on another
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+	// Via is the go module path or npm package name that creates the dependency
+	Via string `json:"via"`
+}
+
+// DependencyGraph is the dependency graph between a set of repositories
+type DependencyGraph struct {
+	Repositories []string         `json:"repositories"`
+	Edges        []DependencyEdge `json:"edges"`
+}
+
+// GraphOptions selects the manifests a dependency graph is built from
+type GraphOptions struct {
+	// Node also reads package.json dependencies
+	Node bool
+}
+
+// repositoryManifest holds what a repository provides and requires
+type repositoryManifest struct {
+	name     string
+	provides map[string]string // module path or package name -> kind
+	requires map[string]string
+}
+
+// BuildDependencyGraph reads the go.mod (and with opts.Node package.json) at the
+// root of each repository and links repositories requiring a module or package
+// another one provides. dir returns the directory to read a repository from.
+func BuildDependencyGraph(repos []Repository, dir func(Repository) string, opts GraphOptions) (*DependencyGraph, error) {
+	graph := &DependencyGraph{Repositories: []string{}, Edges: []DependencyEdge{}}
+
+	var manifests []repositoryManifest
+	providers := make(map[string]string)
+	for _, repo := range repos {
+		root := dir(repo)
+		if root == "" {
+			continue
+		}
+		manifest, err := readRepositoryManifest(repo.Name, root, opts)
+		if err != nil {
+			return nil, err
+		}
+		graph.Repositories = append(graph.Repositories, repo.Name)
+		manifests = append(manifests, manifest)
+		for provided := range manifest.provides {
+			providers[provided] = repo.Name
+		}
+	}
+
+	for _, manifest := range manifests {
+		for required, kind := range manifest.requires {
+			provider, ok := providers[required]
+			if !ok || provider == manifest.name {
+				continue
+			}
+			graph.Edges = append(graph.Edges, DependencyEdge{From: manifest.name, To: provider, Kind: kind, Via: required})
+		}
+	}
+
+	sort.Strings(graph.Repositories)
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Via < b.Via
+	})
+
+	return graph, nil
+}
+
+func readRepositoryManifest(name, root string, opts GraphOptions) (repositoryManifest, error) {
+	manifest := repositoryManifest{
+		name:     name,
+		provides: make(map[string]string),
+		requires: make(map[string]string),
+	}
+
+	goModPath := filepath.Join(root, "go.mod")
+	if data, err := os.ReadFile(goModPath); err == nil {
+		modFile, err := modfile.ParseLax(goModPath, data, nil)
+		if err != nil {
+			return manifest, errors.Wrapf(err, "failed to parse %s", goModPath)
+		}
+		if modFile.Module != nil {
+			manifest.provides[modFile.Module.Mod.Path] = DependencyGo
+		}
+		for _, require := range modFile.Require {
+			manifest.requires[require.Mod.Path] = DependencyGo
+		}
+	}
+
+	if !opts.Node {
+		return manifest, nil
+	}
+
+	packagePath := filepath.Join(root, "package.json")
+	if data, err := os.ReadFile(packagePath); err == nil {
+		var pkg struct {
+			Name                 string            `json:"name"`
+			Dependencies         map[string]string `json:"dependencies"`
+			DevDependencies      map[string]string `json:"devDependencies"`
+			PeerDependencies     map[string]string `json:"peerDependencies"`
+			OptionalDependencies map[string]string `json:"optionalDependencies"`
+		}
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return manifest, errors.Wrapf(err, "failed to parse %s", packagePath)
+		}
+		if pkg.Name != "" {
+			manifest.provides[pkg.Name] = DependencyNode
+		}
+		for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+			for dep := range deps {
+				manifest.requires[dep] = DependencyNode
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+// Dependencies returns the repositories repoName depends on, directly or not
+func (g *DependencyGraph) Dependencies(repoName string) []string {
+	seen := map[string]bool{repoName: true}
+	queue := []string{repoName}
+	var deps []string
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range g.Edges {
+			if edge.From == current && !seen[edge.To] {
+				seen[edge.To] = true
+				deps = append(deps, edge.To)
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// Dependents returns the repositories depending directly on repoName
+func (g *DependencyGraph) Dependents(repoName string) []string {
+	var dependents []string
+	for _, edge := range g.Edges {
+		if edge.To == repoName {
+			dependents = appendUnique(dependents, edge.From)
+		}
+	}
+	return dependents
+}
+
+// DOT renders the graph in Graphviz format
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, repo := range g.Repositories {
+		fmt.Fprintf(&b, "  %q;\n", repo)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Via)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a mermaid flowchart
+func (g *DependencyGraph) Mermaid() string {
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, repo := range g.Repositories {
+		// Repository names may contain characters mermaid does not accept in ids
+		ids[repo] = fmt.Sprintf("r%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[repo], repo)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+	}
+	return b.String()
+}
+
+// MissingDependencies returns, for each selected repository, the registered
+// repositories it depends on that are not selected
+func (wm *WorkspaceManager) MissingDependencies(selected []Repository) (map[string][]string, error) {
+	opts := GraphOptions{}
+	for _, repo := range selected {
+		if containsValue(repo.Categories, EcosystemNode) {
+			opts.Node = true
+		}
+	}
+
+	graph, err := BuildDependencyGraph(wm.Discoverer.GetRepositories(), func(repo Repository) string {
+		return repo.Path
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	selectedNames := make(map[string]bool)
+	for _, repo := range selected {
+		selectedNames[repo.Name] = true
+	}
+
+	missing := make(map[string][]string)
+	for _, repo := range selected {
+		for _, dep := range graph.Dependencies(repo.Name) {
+			if !selectedNames[dep] {
+				missing[repo.Name] = append(missing[repo.Name], dep)
+			}
+		}
+	}
+	return missing, nil
+}
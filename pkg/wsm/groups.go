@@ -0,0 +1,156 @@
+package wsm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GroupPrefix marks a repository group reference in repository lists, e.g. "@backend"
+const GroupPrefix = "@"
+
+// findRepository returns the registry entry for a repository name
+func (rd *RepositoryDiscoverer) findRepository(name string) (*Repository, error) {
+	for i := range rd.registry.Repositories {
+		if rd.registry.Repositories[i].Name == name {
+			return &rd.registry.Repositories[i], nil
+		}
+	}
+	return nil, errors.Errorf("repository '%s' not found in registry", name)
+}
+
+// AddTags adds user-managed tags to a repository
+func (rd *RepositoryDiscoverer) AddTags(repoName string, tags []string) error {
+	repo, err := rd.findRepository(repoName)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		repo.UserCategories = appendUnique(repo.UserCategories, tag)
+		repo.ExcludedCategories = removeValue(repo.ExcludedCategories, tag)
+	}
+	repo.Categories = applyUserCategories(repo.Categories, repo.UserCategories, repo.ExcludedCategories)
+
+	return nil
+}
+
+// RemoveTags removes tags from a repository, including auto-detected ones
+func (rd *RepositoryDiscoverer) RemoveTags(repoName string, tags []string) error {
+	repo, err := rd.findRepository(repoName)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		repo.UserCategories = removeValue(repo.UserCategories, tag)
+		repo.ExcludedCategories = appendUnique(repo.ExcludedCategories, tag)
+	}
+	repo.Categories = applyUserCategories(repo.Categories, repo.UserCategories, repo.ExcludedCategories)
+
+	return nil
+}
+
+// GetGroups returns all named repository groups
+func (rd *RepositoryDiscoverer) GetGroups() map[string][]string {
+	if rd.registry.Groups == nil {
+		return map[string][]string{}
+	}
+	return rd.registry.Groups
+}
+
+// SetGroup defines (or replaces) a named group of repositories
+func (rd *RepositoryDiscoverer) SetGroup(name string, repoNames []string) error {
+	name = strings.TrimPrefix(name, GroupPrefix)
+	if name == "" {
+		return errors.New("group name is required")
+	}
+	if len(repoNames) == 0 {
+		return errors.New("a group needs at least one repository")
+	}
+
+	var members []string
+	for _, repoName := range repoNames {
+		if _, err := rd.findRepository(repoName); err != nil {
+			return err
+		}
+		members = appendUnique(members, repoName)
+	}
+
+	if rd.registry.Groups == nil {
+		rd.registry.Groups = map[string][]string{}
+	}
+	rd.registry.Groups[name] = members
+
+	return nil
+}
+
+// DeleteGroup removes a named group
+func (rd *RepositoryDiscoverer) DeleteGroup(name string) error {
+	name = strings.TrimPrefix(name, GroupPrefix)
+	if _, exists := rd.registry.Groups[name]; !exists {
+		return errors.Errorf("group '%s' not found", name)
+	}
+	delete(rd.registry.Groups, name)
+	return nil
+}
+
+// ExpandGroups replaces "@group" entries with the group's repositories, removing duplicates
+func (rd *RepositoryDiscoverer) ExpandGroups(names []string) ([]string, error) {
+	var result []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, GroupPrefix) {
+			result = appendUnique(result, name)
+			continue
+		}
+
+		groupName := strings.TrimPrefix(name, GroupPrefix)
+		members, exists := rd.registry.Groups[groupName]
+		if !exists {
+			return nil, errors.Errorf("repository group '%s' not found", groupName)
+		}
+		for _, member := range members {
+			result = appendUnique(result, member)
+		}
+	}
+	return result, nil
+}
+
+// applyUserCategories merges user-added categories into the detected ones and drops excluded ones
+func applyUserCategories(categories, added, excluded []string) []string {
+	var result []string
+	for _, category := range append(append([]string{}, categories...), added...) {
+		if !containsValue(excluded, category) {
+			result = appendUnique(result, category)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsValue(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func removeValue(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
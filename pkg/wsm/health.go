@@ -0,0 +1,301 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Health issue severities
+const (
+	HealthSeverityError   = "error"
+	HealthSeverityWarning = "warning"
+	HealthSeverityInfo    = "info"
+)
+
+// Health checks
+const (
+	HealthCheckMissingWorktree = "missing-worktree"
+	HealthCheckDetachedHead    = "detached-head"
+	HealthCheckBranchMismatch  = "branch-mismatch"
+	HealthCheckMissingUpstream = "missing-upstream"
+	HealthCheckDiverged        = "diverged"
+	HealthCheckWorktreeLocked  = "worktree-locked"
+	HealthCheckStaleLockFile   = "stale-lock-file"
+	HealthCheckGoWorkMissing   = "go-work-missing-dir"
+)
+
+// staleLockAge is how old a git lock file must be before it is considered left over from a crash
+const staleLockAge = 10 * time.Minute
+
+// HealthIssue is a problem found in a workspace repository
+type HealthIssue struct {
+	Workspace  string `json:"workspace"`
+	Repository string `json:"repository,omitempty"`
+	Check      string `json:"check"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+	// Hint tells the user how to repair issues that --fix does not handle
+	Hint     string `json:"hint,omitempty"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed"`
+	FixError string `json:"fix_error,omitempty"`
+
+	fix func(ctx context.Context) error
+}
+
+// HealthReport holds the issues found in a workspace
+type HealthReport struct {
+	Workspace string        `json:"workspace"`
+	Path      string        `json:"path"`
+	Issues    []HealthIssue `json:"issues"`
+}
+
+// HasErrors reports whether an error-level issue remains unfixed
+func (r *HealthReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == HealthSeverityError && !issue.Fixed {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWorkspaceHealth checks every repository of a workspace for common problems.
+// With fix set, safe repairs are applied and recorded on the issues.
+func (wm *WorkspaceManager) CheckWorkspaceHealth(ctx context.Context, workspace *Workspace, fix bool) (*HealthReport, error) {
+	report := &HealthReport{
+		Workspace: workspace.Name,
+		Path:      workspace.Path,
+		Issues:    []HealthIssue{},
+	}
+
+	for _, repo := range workspace.Repositories {
+		report.Issues = append(report.Issues, wm.checkRepositoryHealth(ctx, workspace, repo)...)
+	}
+
+	report.Issues = append(report.Issues, checkGoWorkHealth(workspace)...)
+
+	if fix {
+		for i := range report.Issues {
+			issue := &report.Issues[i]
+			if !issue.Fixable || issue.fix == nil {
+				continue
+			}
+			if err := issue.fix(ctx); err != nil {
+				issue.FixError = err.Error()
+			} else {
+				issue.Fixed = true
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// checkRepositoryHealth runs the per-repository checks
+func (wm *WorkspaceManager) checkRepositoryHealth(ctx context.Context, workspace *Workspace, repo Repository) []HealthIssue {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	newIssue := func(check, severity, message string) HealthIssue {
+		return HealthIssue{
+			Workspace:  workspace.Name,
+			Repository: repo.Name,
+			Check:      check,
+			Severity:   severity,
+			Message:    message,
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(worktreePath, ".git")); err != nil {
+		issue := newIssue(HealthCheckMissingWorktree, HealthSeverityError,
+			fmt.Sprintf("worktree %s does not exist", worktreePath))
+		issue.Hint = fmt.Sprintf("wsm remove %s %s && wsm add %s %s", workspace.Name, repo.Name, workspace.Name, repo.Name)
+		return []HealthIssue{issue}
+	}
+
+	var issues []HealthIssue
+
+	issues = append(issues, wm.checkWorktreeLocks(ctx, workspace, repo, worktreePath)...)
+
+	branch, err := gitOutput(ctx, worktreePath, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		issue := newIssue(HealthCheckDetachedHead, HealthSeverityWarning, "HEAD is detached")
+		issue.Hint = fmt.Sprintf("git -C %s switch %s", worktreePath, workspace.Branch)
+
+		// Re-attaching is safe when HEAD is exactly the tip of the workspace branch
+		head, headErr := gitOutput(ctx, worktreePath, "rev-parse", "HEAD")
+		tip, tipErr := gitOutput(ctx, worktreePath, "rev-parse", "--verify", "--quiet", "refs/heads/"+workspace.Branch)
+		if workspace.Branch != "" && headErr == nil && tipErr == nil && head == tip {
+			issue.Fixable = true
+			issue.Message = fmt.Sprintf("HEAD is detached at the tip of '%s'", workspace.Branch)
+			issue.fix = func(ctx context.Context) error {
+				_, err := gitOutput(ctx, worktreePath, "switch", workspace.Branch)
+				return err
+			}
+		}
+		return append(issues, issue)
+	}
+
+	if workspace.Branch != "" && branch != workspace.Branch {
+		issue := newIssue(HealthCheckBranchMismatch, HealthSeverityWarning,
+			fmt.Sprintf("on branch '%s', workspace expects '%s'", branch, workspace.Branch))
+		issue.Hint = fmt.Sprintf("wsm branch switch %s", workspace.Branch)
+		issues = append(issues, issue)
+	}
+
+	upstream, err := gitOutput(ctx, worktreePath, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if err != nil {
+		issue := newIssue(HealthCheckMissingUpstream, HealthSeverityInfo,
+			fmt.Sprintf("branch '%s' has no upstream", branch))
+		remote := workspace.RemoteFor(repo)
+		issue.Hint = fmt.Sprintf("push with 'wsm push %s --set-upstream'", remote)
+
+		// Tracking an existing remote branch of the same name is safe
+		if _, err := gitOutput(ctx, worktreePath, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch); err == nil {
+			issue.Severity = HealthSeverityWarning
+			issue.Message = fmt.Sprintf("branch '%s' does not track %s/%s", branch, remote, branch)
+			issue.Fixable = true
+			issue.fix = func(ctx context.Context) error {
+				_, err := gitOutput(ctx, worktreePath, "branch", "--set-upstream-to", remote+"/"+branch, branch)
+				return err
+			}
+		}
+		return append(issues, issue)
+	}
+
+	counts, err := gitOutput(ctx, worktreePath, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err == nil {
+		fields := strings.Fields(counts)
+		if len(fields) == 2 {
+			ahead, _ := strconv.Atoi(fields[0])
+			behind, _ := strconv.Atoi(fields[1])
+			if ahead > 0 && behind > 0 {
+				issue := newIssue(HealthCheckDiverged, HealthSeverityWarning,
+					fmt.Sprintf("diverged from %s (%d ahead, %d behind)", upstream, ahead, behind))
+				issue.Hint = "wsm rebase or wsm sync pull --rebase"
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	return issues
+}
+
+// checkWorktreeLocks reports locked worktrees and lock files left behind by crashed git processes
+func (wm *WorkspaceManager) checkWorktreeLocks(ctx context.Context, workspace *Workspace, repo Repository, worktreePath string) []HealthIssue {
+	var issues []HealthIssue
+
+	if worktrees, err := ListGitWorktrees(ctx, repo.Path); err == nil {
+		for _, worktree := range worktrees {
+			if worktree.Locked && filepath.Clean(worktree.Path) == filepath.Clean(worktreePath) {
+				issues = append(issues, HealthIssue{
+					Workspace:  workspace.Name,
+					Repository: repo.Name,
+					Check:      HealthCheckWorktreeLocked,
+					Severity:   HealthSeverityWarning,
+					Message:    "worktree is locked, which blocks 'git worktree remove' and prune",
+					Fixable:    true,
+					fix: func(ctx context.Context) error {
+						_, err := gitOutput(ctx, repo.Path, "worktree", "unlock", worktreePath)
+						return err
+					},
+				})
+			}
+		}
+	}
+
+	gitDir, err := gitOutput(ctx, worktreePath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return issues
+	}
+
+	for _, name := range []string{"index.lock", "HEAD.lock"} {
+		lockPath := filepath.Join(gitDir, name)
+		info, err := os.Stat(lockPath)
+		if err != nil || time.Since(info.ModTime()) < staleLockAge {
+			continue
+		}
+		issues = append(issues, HealthIssue{
+			Workspace:  workspace.Name,
+			Repository: repo.Name,
+			Check:      HealthCheckStaleLockFile,
+			Severity:   HealthSeverityError,
+			Message:    fmt.Sprintf("stale %s from %s blocks git commands", name, info.ModTime().Format("2006-01-02 15:04")),
+			Fixable:    true,
+			fix: func(ctx context.Context) error {
+				return os.Remove(lockPath)
+			},
+		})
+	}
+
+	return issues
+}
+
+// checkGoWorkHealth reports go.work use directives pointing at missing directories
+func checkGoWorkHealth(workspace *Workspace) []HealthIssue {
+	goWorkPath := filepath.Join(workspace.Path, "go.work")
+	data, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return nil
+	}
+
+	var issues []HealthIssue
+	for _, dir := range parseGoWorkUses(string(data)) {
+		if _, err := os.Stat(filepath.Join(workspace.Path, dir)); err == nil {
+			continue
+		}
+		issues = append(issues, HealthIssue{
+			Workspace: workspace.Name,
+			Check:     HealthCheckGoWorkMissing,
+			Severity:  HealthSeverityError,
+			Message:   fmt.Sprintf("go.work uses %s, which does not exist", dir),
+			Fixable:   true,
+			fix: func(ctx context.Context) error {
+				cmd := exec.CommandContext(ctx, "go", "work", "edit", "-dropuse="+dir)
+				cmd.Dir = workspace.Path
+				if out, err := cmd.CombinedOutput(); err != nil {
+					return errors.Wrapf(err, "go work edit failed: %s", strings.TrimSpace(string(out)))
+				}
+				return nil
+			},
+		})
+	}
+
+	return issues
+}
+
+// parseGoWorkUses returns the directories of the use directives of a go.work file
+func parseGoWorkUses(content string) []string {
+	workFile, err := modfile.ParseWork("go.work", []byte(content), nil)
+	if err != nil {
+		return nil
+	}
+
+	uses := make([]string, 0, len(workFile.Use))
+	for _, use := range workFile.Use {
+		uses = append(uses, use.Path)
+	}
+	return uses
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := GitContext(ctx, args...)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(GitError(ctx, err), "git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
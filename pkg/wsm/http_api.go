@@ -0,0 +1,266 @@
+package wsm
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// APITokenEnv names the environment variable holding the token of 'wsm serve http'
+const APITokenEnv = "WSM_API_TOKEN"
+
+// apiHandler serves the workspace service as a REST API under /api
+type apiHandler struct {
+	service *WorkspaceService
+	token   string
+	mux     *http.ServeMux
+	// mutations serializes operations changing workspaces, like the CLI running them one by one
+	mutations sync.Mutex
+}
+
+// apiError is the body of every failed request
+type apiError struct {
+	Error string      `json:"error"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// NewAPIHandler returns the REST API of the workspace service. Requests must
+// carry "Authorization: Bearer <token>" unless token is empty. POST requests
+// must be sent as application/json, which browsers cannot do across sites
+// without a CORS preflight.
+//
+//	GET    /api/workspaces                 list workspaces
+//	POST   /api/workspaces                 create a workspace (CreateWorkspaceRequest)
+//	GET    /api/workspaces/{name}          show a workspace
+//	DELETE /api/workspaces/{name}          delete it (?trash, ?remove_files, ?force_worktrees, ?force)
+//	GET    /api/workspaces/{name}/status   git status of its repositories
+//	GET    /api/workspaces/{name}/diff     unified diff (?repository, ?staged, ?since_creation)
+//	POST   /api/workspaces/{name}/commit   commit all changes (CommitRequest)
+//	POST   /api/workspaces/{name}/sync     pull and push (SyncOptions)
+//
+// status and diff take the repository filter as repeatable ?repo, ?exclude_repo
+// and ?tag parameters, commit and sync as the "filter" object of their body.
+func NewAPIHandler(service *WorkspaceService, token string) http.Handler {
+	h := &apiHandler{service: service, token: token, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /api/workspaces", h.listWorkspaces)
+	h.mux.HandleFunc("POST /api/workspaces", h.createWorkspace)
+	h.mux.HandleFunc("GET /api/workspaces/{name}", h.getWorkspace)
+	h.mux.HandleFunc("DELETE /api/workspaces/{name}", h.deleteWorkspace)
+	h.mux.HandleFunc("GET /api/workspaces/{name}/status", h.status)
+	h.mux.HandleFunc("GET /api/workspaces/{name}/diff", h.diff)
+	h.mux.HandleFunc("POST /api/workspaces/{name}/commit", h.commit)
+	h.mux.HandleFunc("POST /api/workspaces/{name}/sync", h.sync)
+
+	return h
+}
+
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="wsm"`)
+		writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid token"), nil)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *apiHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *apiHandler) listWorkspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.service.ListWorkspaces()
+	if workspaces == nil {
+		workspaces = []Workspace{}
+	}
+	writeAPIResult(w, workspaces, err)
+}
+
+func (h *apiHandler) createWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req CreateWorkspaceRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	h.mutations.Lock()
+	defer h.mutations.Unlock()
+	workspace, err := h.service.CreateWorkspace(r.Context(), req)
+	if err != nil {
+		writeAPIError(w, http.StatusUnprocessableEntity, err, nil)
+		return
+	}
+	writeAPIJSON(w, http.StatusCreated, workspace)
+}
+
+func (h *apiHandler) getWorkspace(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	workspace, err := h.service.GetWorkspace(name)
+	writeAPIResult(w, workspace, err)
+}
+
+func (h *apiHandler) deleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	req := DeleteWorkspaceRequest{
+		Name:           name,
+		Trash:          queryBool(r, "trash"),
+		RemoveFiles:    queryBool(r, "remove_files"),
+		ForceWorktrees: queryBool(r, "force_worktrees"),
+		Force:          queryBool(r, "force"),
+	}
+
+	h.mutations.Lock()
+	defer h.mutations.Unlock()
+	result, err := h.service.DeleteWorkspace(r.Context(), req)
+	if errors.Is(err, ErrWorkAtRisk) {
+		writeAPIError(w, http.StatusConflict, err, result)
+		return
+	}
+	writeAPIResult(w, result, err)
+}
+
+func (h *apiHandler) status(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	status, err := h.service.Status(r.Context(), name, queryFilter(r))
+	writeAPIResult(w, status, err)
+}
+
+func (h *apiHandler) diff(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	diff, err := h.service.Diff(r.Context(), DiffRequest{
+		Workspace:     name,
+		Repository:    r.URL.Query().Get("repository"),
+		Staged:        queryBool(r, "staged"),
+		SinceCreation: queryBool(r, "since_creation"),
+		Filter:        queryFilter(r),
+	})
+	if err != nil {
+		writeAPIResult(w, nil, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+	_, _ = w.Write([]byte(diff))
+}
+
+func (h *apiHandler) commit(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	var req CommitRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	req.Workspace = name
+
+	h.mutations.Lock()
+	defer h.mutations.Unlock()
+	result, err := h.service.Commit(r.Context(), req)
+	writeAPIResult(w, result, err)
+}
+
+func (h *apiHandler) sync(w http.ResponseWriter, r *http.Request) {
+	name, ok := workspaceName(w, r)
+	if !ok {
+		return
+	}
+	var req SyncRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	req.Workspace = name
+
+	h.mutations.Lock()
+	defer h.mutations.Unlock()
+	results, err := h.service.Sync(r.Context(), req)
+	writeAPIResult(w, results, err)
+}
+
+// workspaceName returns the {name} path parameter, answering 400 when it is
+// not a valid workspace name
+func workspaceName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if err := ValidateWorkspaceName(name); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err, nil)
+		return "", false
+	}
+	return name, true
+}
+
+// decodeAPIRequest decodes a JSON body, answering 415 for other content
+// types; an empty body leaves v unchanged
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, errors.New("requests must be sent as application/json"), nil)
+		return false
+	}
+	if r.ContentLength == 0 {
+		return true
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request body"), nil)
+		return false
+	}
+	return true
+}
+
+func queryBool(r *http.Request, name string) bool {
+	value, err := strconv.ParseBool(r.URL.Query().Get(name))
+	return err == nil && value
+}
+
+// queryFilter reads the repository filter from the ?repo, ?exclude_repo and ?tag parameters
+func queryFilter(r *http.Request) RepositoryFilter {
+	query := r.URL.Query()
+	return RepositoryFilter{
+		Repositories: query["repo"],
+		Exclude:      query["exclude_repo"],
+		Tags:         query["tag"],
+	}
+}
+
+// writeAPIResult writes the result, or the error as 404 for unknown workspaces and 500 otherwise
+func writeAPIResult(w http.ResponseWriter, result interface{}, err error) {
+	if err != nil {
+		code := http.StatusInternalServerError
+		var notFound *WorkspaceNotFoundError
+		if errors.As(err, &notFound) {
+			code = http.StatusNotFound
+		}
+		writeAPIError(w, code, err, nil)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+func writeAPIError(w http.ResponseWriter, code int, err error, data interface{}) {
+	writeAPIJSON(w, code, apiError{Error: err.Error(), Data: data})
+}
+
+func writeAPIJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
@@ -0,0 +1,234 @@
+package wsm
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IgnoreFileName is the file listing paths that workspace-wide operations skip
+const IgnoreFileName = ".wsmignore"
+
+// IgnoreRules excludes files from diff, commit --add-all, status and the
+// untracked-file checks of delete. Rules are read from the .wsmignore of the
+// workspace root, which applies to every repository, followed by the
+// .wsmignore of the repository itself. The syntax follows .gitignore:
+//
+//	# build output anywhere in the repository
+//	node_modules/
+//	*.log
+//	# anchored to the repository root
+//	/dist
+//	tmp/**
+//	!tmp/keep.txt
+//
+// A pattern without a slash matches a file or directory name at any depth, a
+// pattern with a slash is relative to the repository root. A trailing slash
+// only matches directories and a leading "!" re-includes a path. The last
+// matching pattern wins.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// LoadIgnoreRules reads the ignore rules that apply to a repository worktree
+// of a workspace (<workspace>/<repo>)
+func LoadIgnoreRules(worktreePath string) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	for _, ignorePath := range []string{
+		filepath.Join(filepath.Dir(worktreePath), IgnoreFileName),
+		filepath.Join(worktreePath, IgnoreFileName),
+	} {
+		if err := rules.load(ignorePath); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+func (r *IgnoreRules) load(ignorePath string) error {
+	file, err := os.Open(ignorePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", ignorePath)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if pattern, ok := parseIgnorePattern(scanner.Text()); ok {
+			r.patterns = append(r.patterns, pattern)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %s", ignorePath)
+	}
+	return nil
+}
+
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	// "dir/**" matches everything inside dir, like "dir/"
+	if strings.HasSuffix(line, "/**") {
+		line = strings.TrimSuffix(line, "**")
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.HasPrefix(line, "**/") {
+		line = strings.TrimPrefix(line, "**/")
+	} else if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	p.pattern = line
+	return p, true
+}
+
+// Empty reports whether there are no rules, so callers can skip filtering
+func (r *IgnoreRules) Empty() bool {
+	return r == nil || len(r.patterns) == 0
+}
+
+// Match reports whether a path relative to the repository root is ignored.
+// Directories reported by git with a trailing slash are matched as directories.
+func (r *IgnoreRules) Match(relPath string) bool {
+	if r.Empty() {
+		return false
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	isDir := strings.HasSuffix(relPath, "/")
+	parts := strings.Split(strings.Trim(relPath, "/"), "/")
+
+	ignored := false
+	for _, p := range r.patterns {
+		if p.matches(parts, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matches tries the pattern against every leading directory of the path and
+// the path itself, so a matching directory ignores all files below it
+func (p ignorePattern) matches(parts []string, isDir bool) bool {
+	depth := strings.Count(p.pattern, "/") + 1
+	for end := depth; end <= len(parts); end++ {
+		if p.anchored && end != depth {
+			break
+		}
+		if p.dirOnly && end == len(parts) && !isDir {
+			continue
+		}
+		if ok, _ := path.Match(p.pattern, strings.Join(parts[end-depth:end], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the paths that are not ignored
+func (r *IgnoreRules) Filter(paths []string) []string {
+	if r.Empty() {
+		return paths
+	}
+	kept := []string{}
+	for _, p := range paths {
+		if !r.Match(p) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// Split separates paths into the ones that are kept and the ones that are ignored
+func (r *IgnoreRules) Split(paths []string) (kept, ignored []string) {
+	kept = []string{}
+	for _, p := range paths {
+		if r.Match(p) {
+			ignored = append(ignored, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	return kept, ignored
+}
+
+// loadIgnoreRulesOrEmpty loads the rules of a worktree; a broken ignore file
+// must not stop status or diff, so errors yield no rules
+func loadIgnoreRulesOrEmpty(worktreePath string) *IgnoreRules {
+	rules, err := LoadIgnoreRules(worktreePath)
+	if err != nil {
+		return &IgnoreRules{}
+	}
+	return rules
+}
+
+// removeIgnoredFiles deletes untracked files matched by .wsmignore so that
+// `git worktree remove` does not refuse to remove the worktree because of them
+func removeIgnoredFiles(ctx context.Context, worktreePath string, files []string) error {
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		target := filepath.Join(worktreePath, filepath.FromSlash(file))
+		if target == filepath.Clean(worktreePath) || !isPathWithin(target, worktreePath) {
+			continue
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return errors.Wrapf(err, "failed to remove ignored file %s", file)
+		}
+	}
+	return nil
+}
+
+// splitUntrackedFiles lists the untracked files of a worktree, separating the
+// ones ignored by .wsmignore
+func (wm *WorkspaceManager) splitUntrackedFiles(ctx context.Context, worktreePath string) (kept, ignored []string, err error) {
+	untracked, err := wm.getUntrackedFiles(ctx, worktreePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := LoadIgnoreRules(worktreePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept, ignored = rules.Split(untracked)
+	return kept, ignored, nil
+}
+
+// splitLines splits git output into lines, returning no lines for empty output
+func splitLines(output string) []string {
+	if output == "" {
+		return []string{}
+	}
+	return strings.Split(output, "\n")
+}
@@ -0,0 +1,254 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// ImportedWorktree is a git worktree found in a directory imported with 'wsm init'
+type ImportedWorktree struct {
+	// Name is the directory of the worktree, which becomes the repository name
+	Name         string `json:"name"`
+	WorktreePath string `json:"worktree_path"`
+	// SourcePath is the main checkout of the repository the worktree belongs to
+	SourcePath string `json:"source_path"`
+	// Branch is empty for a detached HEAD
+	Branch string `json:"branch,omitempty"`
+	// Registered is set when the source repository is in the registry already
+	Registered bool `json:"registered"`
+	// Problem explains why the worktree cannot be imported
+	Problem string `json:"problem,omitempty"`
+}
+
+// ImportPlan describes a directory of worktrees to import as a workspace
+type ImportPlan struct {
+	Name      string             `json:"name"`
+	Path      string             `json:"path"`
+	Branch    string             `json:"branch"`
+	Worktrees []ImportedWorktree `json:"worktrees"`
+	// Other lists the entries of the directory that are not worktrees
+	Other []string `json:"other,omitempty"`
+}
+
+// Importable returns the worktrees without problems
+func (p *ImportPlan) Importable() []ImportedWorktree {
+	var worktrees []ImportedWorktree
+	for _, worktree := range p.Worktrees {
+		if worktree.Problem == "" {
+			worktrees = append(worktrees, worktree)
+		}
+	}
+	return worktrees
+}
+
+// ScanImport inspects a directory of hand-made worktrees, resolving the source
+// repository and branch of each of them. An empty name uses the directory name.
+func (wm *WorkspaceManager) ScanImport(ctx context.Context, dir, name string) (*ImportPlan, error) {
+	path := resolvePath(expandHome(dir))
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil, errors.Errorf("'%s' is not a directory", dir)
+	}
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	if err := wm.checkImportTarget(name, path); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	plan := &ImportPlan{Name: name, Path: path}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		worktreePath := filepath.Join(path, entry.Name())
+		info, err := os.Stat(filepath.Join(worktreePath, ".git"))
+		if err != nil {
+			plan.Other = append(plan.Other, entry.Name())
+			continue
+		}
+		worktree := ImportedWorktree{Name: entry.Name(), WorktreePath: worktreePath}
+		if info.IsDir() {
+			// A .git directory is a clone: its "source" would be the worktree itself
+			worktree.SourcePath = worktreePath
+			worktree.Problem = "a clone, not a worktree; create a worktree of it or register it with 'wsm discover'"
+		} else {
+			wm.resolveImportedWorktree(ctx, &worktree)
+		}
+		plan.Worktrees = append(plan.Worktrees, worktree)
+	}
+
+	plan.Branch = commonImportBranch(plan.Importable())
+	return plan, nil
+}
+
+// checkImportTarget fails when name or path already belong to a workspace
+func (wm *WorkspaceManager) checkImportTarget(name, path string) error {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return errors.Wrap(err, "failed to load workspaces")
+	}
+	for _, workspace := range workspaces {
+		if workspace.Name == name {
+			return errors.Errorf("workspace '%s' already exists, pass another name with --name", name)
+		}
+		if resolvePath(workspace.Path) == path {
+			return errors.Errorf("%s is already workspace '%s'", path, workspace.Name)
+		}
+	}
+	return nil
+}
+
+// resolveImportedWorktree fills in the source repository and branch of a worktree
+func (wm *WorkspaceManager) resolveImportedWorktree(ctx context.Context, worktree *ImportedWorktree) {
+	commonDir, err := gitOutput(ctx, worktree.WorktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		worktree.Problem = fmt.Sprintf("not a valid worktree: %v", err)
+		return
+	}
+	if filepath.Base(commonDir) != ".git" {
+		worktree.SourcePath = commonDir
+		worktree.Problem = "worktree of a bare repository, which cannot be registered"
+		return
+	}
+	worktree.SourcePath = resolvePath(filepath.Dir(commonDir))
+
+	if branch, err := gitOutput(ctx, worktree.WorktreePath, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		worktree.Branch = branch
+	}
+
+	repoName := filepath.Base(worktree.SourcePath)
+	if repo, ok := wm.registeredRepositoryAt(worktree.SourcePath); ok {
+		worktree.Registered = true
+		repoName = repo.Name
+	}
+	// Worktrees live in <workspace>/<repository name>
+	if repoName != worktree.Name {
+		worktree.Problem = fmt.Sprintf("worktree of repository '%s', rename the directory to %s", repoName, repoName)
+	}
+}
+
+// commonImportBranch returns the branch most worktrees are on
+func commonImportBranch(worktrees []ImportedWorktree) string {
+	counts := make(map[string]int)
+	for _, worktree := range worktrees {
+		if worktree.Branch != "" {
+			counts[worktree.Branch]++
+		}
+	}
+	var branches []string
+	for branch := range counts {
+		branches = append(branches, branch)
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if counts[branches[i]] != counts[branches[j]] {
+			return counts[branches[i]] > counts[branches[j]]
+		}
+		return branches[i] < branches[j]
+	})
+	if len(branches) == 0 {
+		return ""
+	}
+	return branches[0]
+}
+
+// ImportWorkspace registers the source repositories of the named worktrees of
+// a plan that are missing from the registry and saves the directory as a
+// workspace, writing both its configuration and .wsm/wsm.json
+func (wm *WorkspaceManager) ImportWorkspace(ctx context.Context, plan *ImportPlan, names []string) (*Workspace, error) {
+	var selected []ImportedWorktree
+	for _, name := range names {
+		found := false
+		for _, worktree := range plan.Worktrees {
+			if worktree.Name != name {
+				continue
+			}
+			if worktree.Problem != "" {
+				return nil, errors.Errorf("cannot import '%s': %s", name, worktree.Problem)
+			}
+			selected = append(selected, worktree)
+			found = true
+		}
+		if !found {
+			return nil, errors.Errorf("no worktree '%s' in %s", name, plan.Path)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no worktrees selected")
+	}
+
+	var unregistered []string
+	for _, worktree := range selected {
+		if !worktree.Registered {
+			unregistered = append(unregistered, worktree.SourcePath)
+		}
+	}
+	if len(unregistered) > 0 {
+		if err := wm.Discoverer.DiscoverRepositories(ctx, unregistered, false, 0); err != nil {
+			return nil, errors.Wrap(err, "failed to register source repositories")
+		}
+	}
+
+	workspace := &Workspace{
+		Name:    plan.Name,
+		Path:    plan.Path,
+		Branch:  commonImportBranch(selected),
+		Created: time.Now(),
+	}
+	for _, worktree := range selected {
+		repo, ok := wm.registeredRepositoryAt(worktree.SourcePath)
+		if !ok {
+			return nil, errors.Errorf("repository %s was not registered", worktree.SourcePath)
+		}
+		workspace.Repositories = append(workspace.Repositories, repo)
+		if worktree.Branch != workspace.Branch {
+			output.PrintWarning("%s is on %s, not on the workspace branch %s", worktree.Name, displayBranch(worktree.Branch), workspace.Branch)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(workspace.Path, "go.work")); err == nil {
+		workspace.GoWorkspace = true
+	}
+
+	if err := wm.saveWorkspaceWithMetadata(workspace); err != nil {
+		return nil, err
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Imported %s as workspace %s with %d repositories", workspace.Path, workspace.Name, len(workspace.Repositories)),
+		"Imported workspace",
+		"workspace", workspace.Name,
+		"path", workspace.Path,
+		"registered", unregistered,
+	)
+	return workspace, nil
+}
+
+// registeredRepositoryAt returns the registered repository checked out at path
+func (wm *WorkspaceManager) registeredRepositoryAt(path string) (Repository, bool) {
+	for _, repo := range wm.Discoverer.GetRepositories() {
+		if repo.Path != "" && resolvePath(repo.Path) == path {
+			return repo, true
+		}
+	}
+	return Repository{}, false
+}
+
+// displayBranch names a branch for messages, a detached HEAD included
+func displayBranch(branch string) string {
+	if branch == "" {
+		return "a detached HEAD"
+	}
+	return "branch " + branch
+}
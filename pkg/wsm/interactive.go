@@ -0,0 +1,101 @@
+package wsm
+
+import (
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/pkg/errors"
+)
+
+// How an existing workspace branch is handled when creating its worktree
+const (
+	ExistingBranchAsk       = "ask"
+	ExistingBranchOverwrite = "overwrite"
+	ExistingBranchUse       = "use"
+	ExistingBranchFail      = "fail"
+)
+
+// ExistingBranchPolicies lists the values of --on-existing-branch
+var ExistingBranchPolicies = []string{ExistingBranchAsk, ExistingBranchOverwrite, ExistingBranchUse, ExistingBranchFail}
+
+// ValidateExistingBranchPolicy checks a --on-existing-branch value
+func ValidateExistingBranchPolicy(policy string) error {
+	if policy == "" || containsValue(ExistingBranchPolicies, policy) {
+		return nil
+	}
+	return errors.Errorf("unknown existing branch policy '%s' (supported: %s)", policy, strings.Join(ExistingBranchPolicies, ", "))
+}
+
+// nonInteractive is set by the global --non-interactive flag
+var nonInteractive bool
+
+// SetNonInteractive disables prompts: decisions must then come from flags, and
+// commands fail instead of waiting for an answer
+func SetNonInteractive(enabled bool) {
+	nonInteractive = enabled
+}
+
+// NonInteractive reports whether prompts are disabled
+func NonInteractive() bool {
+	return nonInteractive
+}
+
+// RequireInteractive fails in non-interactive mode, naming the flag that makes
+// the decision instead of the prompt
+func RequireInteractive(decision, flag string) error {
+	if !nonInteractive {
+		return nil
+	}
+	return errors.Errorf("%s needs confirmation and prompts are disabled by --non-interactive; pass %s", decision, flag)
+}
+
+// confirm asks a yes/no question, an aborted prompt meaning no. In
+// non-interactive mode the answer is yes: the flag that led to the question is
+// the decision.
+func confirm(question string) bool {
+	if nonInteractive {
+		return true
+	}
+	confirmed, err := ux.Confirm(question, "")
+	return err == nil && confirmed
+}
+
+// existingBranchPolicy returns how to handle a workspace branch that already
+// exists in a repository, ExistingBranchAsk meaning the user is prompted
+func (wm *WorkspaceManager) existingBranchPolicy(repoName, branch string) (string, error) {
+	policy := wm.OnExistingBranch
+	if policy == "" {
+		policy = ExistingBranchAsk
+	}
+	if policy == ExistingBranchAsk && nonInteractive {
+		return "", errors.Errorf("branch '%s' already exists in repository '%s'; pass --on-existing-branch=overwrite, use or fail", branch, repoName)
+	}
+	return policy, nil
+}
+
+// existingBranchCancel is the choice of cancelling when asked about an existing branch
+const existingBranchCancel = "cancel"
+
+// askExistingBranch asks how to handle a workspace branch that already exists.
+// An aborted prompt is returned as existingBranchCancel.
+func askExistingBranch(repoName, branch string) (string, error) {
+	output.PrintWarning("Branch '%s' already exists in repository '%s'", branch, repoName)
+	choice, err := ux.Select("How would you like to handle the existing branch?", []ux.Option{
+		ux.NewOption("Overwrite the existing branch (git worktree add -B)", ExistingBranchOverwrite),
+		ux.NewOption("Use the existing branch as-is (git worktree add)", ExistingBranchUse),
+		ux.NewOption("Cancel", existingBranchCancel),
+	})
+	if errors.Is(err, ux.ErrAborted) {
+		return existingBranchCancel, nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get user choice")
+	}
+	return choice, nil
+}
+
+// existingBranchError is returned by the fail policy
+func existingBranchError(repoName, branch string) error {
+	return errors.Errorf("branch '%s' already exists in repository '%s' (--on-existing-branch=fail)", branch, repoName)
+}
@@ -0,0 +1,57 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// InterruptedError reports a workspace-wide operation stopped before all
+// repositories were processed, e.g. by Ctrl-C
+type InterruptedError struct {
+	Operation string
+	Completed []string
+	Remaining []string
+	Cause     error
+}
+
+func (e *InterruptedError) Error() string {
+	completed := "none"
+	if len(e.Completed) > 0 {
+		completed = strings.Join(e.Completed, ", ")
+	}
+	msg := fmt.Sprintf("%s interrupted after %d of %d repositories (completed: %s",
+		e.Operation, len(e.Completed), len(e.Completed)+len(e.Remaining), completed)
+	if len(e.Remaining) > 0 {
+		msg += "; not processed: " + strings.Join(e.Remaining, ", ")
+	}
+	return msg + ")"
+}
+
+// Unwrap returns the context error, so errors.Is(err, context.Canceled) holds
+func (e *InterruptedError) Unwrap() error {
+	return e.Cause
+}
+
+// checkInterrupted returns an InterruptedError when ctx is done. completed and
+// remaining name the repositories processed and still to process.
+func checkInterrupted(ctx context.Context, operation string, completed, remaining []string) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return &InterruptedError{
+		Operation: operation,
+		Completed: append([]string{}, completed...),
+		Remaining: append([]string{}, remaining...),
+		Cause:     ctx.Err(),
+	}
+}
+
+// repositoryNames returns the names of repositories
+func repositoryNames(repos []Repository) []string {
+	names := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	return names
+}
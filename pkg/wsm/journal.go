@@ -0,0 +1,213 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+const createJournalFile = "create-journal.json"
+
+// CreateJournal records the progress of a journaled workspace creation.
+// It lives in <workspace>/.wsm/ until the workspace configuration is saved,
+// so an interrupted creation can be finished with `wsm create --resume`.
+type CreateJournal struct {
+	Workspace Workspace `json:"workspace"`
+	Partial   bool      `json:"partial,omitempty"`
+	// Completed lists the repositories whose worktree has been created
+	Completed []string  `json:"completed"`
+	LastError string    `json:"last_error,omitempty"`
+	Started   time.Time `json:"started"`
+	Updated   time.Time `json:"updated"`
+}
+
+// newCreateJournal starts a journal for a workspace that is about to be created
+func newCreateJournal(workspace *Workspace, partial bool) *CreateJournal {
+	return &CreateJournal{
+		Workspace: *workspace,
+		Partial:   partial,
+		Completed: []string{},
+		Started:   time.Now(),
+	}
+}
+
+func createJournalPath(workspacePath string) string {
+	return filepath.Join(workspacePath, ".wsm", createJournalFile)
+}
+
+// IsCompleted reports whether the worktree of a repository was already created
+func (j *CreateJournal) IsCompleted(repoName string) bool {
+	return containsValue(j.Completed, repoName)
+}
+
+// markCompleted records a created worktree and persists the journal
+func (j *CreateJournal) markCompleted(repoName string) error {
+	j.Completed = appendUnique(j.Completed, repoName)
+	return j.save()
+}
+
+// recordFailure stores the error that interrupted the creation
+func (j *CreateJournal) recordFailure(err error) {
+	j.LastError = err.Error()
+	if saveErr := j.save(); saveErr != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to save creation progress for workspace '%s': %v", j.Workspace.Name, saveErr),
+			"Failed to save create journal",
+			"workspace", j.Workspace.Name,
+			"error", saveErr,
+		)
+	}
+}
+
+// save writes the journal atomically
+func (j *CreateJournal) save() error {
+	journalPath := createJournalPath(j.Workspace.Path)
+	if err := os.MkdirAll(filepath.Dir(journalPath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create .wsm directory")
+	}
+
+	j.Updated = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal create journal")
+	}
+
+	tmpPath := journalPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write create journal")
+	}
+	if err := os.Rename(tmpPath, journalPath); err != nil {
+		return errors.Wrap(err, "failed to replace create journal")
+	}
+
+	return nil
+}
+
+// remove deletes the journal once the workspace has been fully created
+func (j *CreateJournal) remove() {
+	if err := os.Remove(createJournalPath(j.Workspace.Path)); err != nil && !os.IsNotExist(err) {
+		output.LogWarn(
+			fmt.Sprintf("Failed to remove create journal for workspace '%s': %v", j.Workspace.Name, err),
+			"Failed to remove create journal",
+			"workspace", j.Workspace.Name,
+			"error", err,
+		)
+	}
+}
+
+func loadCreateJournal(journalPath string) (*CreateJournal, error) {
+	data, err := os.ReadFile(journalPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read create journal %s", journalPath)
+	}
+
+	var journal CreateJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse create journal %s", journalPath)
+	}
+
+	return &journal, nil
+}
+
+// FindInterruptedCreations returns the journals of all unfinished workspace creations,
+// most recently updated first. Workspaces live in dated directories, so all of them are searched.
+func (wm *WorkspaceManager) FindInterruptedCreations() ([]*CreateJournal, error) {
+	pattern := filepath.Join(filepath.Dir(wm.workspaceDir), "*", "*", ".wsm", createJournalFile)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search for create journals")
+	}
+
+	var journals []*CreateJournal
+	for _, match := range matches {
+		journal, err := loadCreateJournal(match)
+		if err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Ignoring unreadable create journal %s: %v", match, err),
+				"Ignoring unreadable create journal",
+				"path", match,
+				"error", err,
+			)
+			continue
+		}
+		journals = append(journals, journal)
+	}
+
+	sort.Slice(journals, func(i, j int) bool {
+		return journals[i].Updated.After(journals[j].Updated)
+	})
+
+	return journals, nil
+}
+
+// FindCreateJournal returns the most recent unfinished creation of a workspace
+func (wm *WorkspaceManager) FindCreateJournal(name string) (*CreateJournal, error) {
+	journals, err := wm.FindInterruptedCreations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, journal := range journals {
+		if journal.Workspace.Name == name {
+			return journal, nil
+		}
+	}
+
+	return nil, errors.Errorf("no interrupted creation found for workspace '%s'", name)
+}
+
+// ResumeWorkspaceCreation finishes an interrupted journaled creation,
+// skipping worktrees that were already created.
+func (wm *WorkspaceManager) ResumeWorkspaceCreation(ctx context.Context, name string) (*Workspace, error) {
+	if _, err := wm.LoadWorkspace(name); err == nil {
+		return nil, errors.Errorf("workspace '%s' already exists, nothing to resume", name)
+	}
+
+	journal, err := wm.FindCreateJournal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	output.PrintInfo("Resuming creation of workspace '%s' (%d of %d worktrees already created)",
+		name, len(journal.Completed), len(journal.Workspace.Repositories))
+	if journal.LastError != "" {
+		output.PrintInfo("Previous attempt failed with: %s", journal.LastError)
+	}
+
+	workspace := journal.Workspace
+	journal.LastError = ""
+
+	if err := wm.buildWorkspace(ctx, &workspace, CreateOptions{Partial: journal.Partial}, journal); err != nil {
+		return nil, err
+	}
+
+	return &workspace, nil
+}
+
+// isResumableWorktree reports whether a journaled worktree exists and is on the workspace branch.
+// This also covers worktrees created right before an interruption, before the journal was updated.
+func (wm *WorkspaceManager) isResumableWorktree(ctx context.Context, targetPath, branch string) bool {
+	if _, err := os.Stat(filepath.Join(targetPath, ".git")); err != nil {
+		return false
+	}
+	if branch == "" {
+		return true
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = targetPath
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == branch
+}
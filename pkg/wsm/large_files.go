@@ -0,0 +1,173 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxFileSize is the file size limit of GitHub
+const DefaultMaxFileSize = 100 << 20
+
+// lfsPointerMaxSize bounds the size of Git LFS pointer files
+const lfsPointerMaxSize = 1024
+
+// LargeFile is a file added by commits that are about to be pushed
+type LargeFile struct {
+	Repository string `json:"repository"`
+	Commit     string `json:"commit"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	Reason     string `json:"reason"`
+}
+
+// LargeFilesError blocks a push adding large files
+type LargeFilesError struct {
+	Files []LargeFile
+	Hint  string
+}
+
+func (e *LargeFilesError) Error() string {
+	msg := "push blocked by policy, commits add large files:\n" + FormatLargeFiles(e.Files)
+	if e.Hint != "" {
+		msg += "\n" + e.Hint
+	}
+	return msg
+}
+
+// FormatLargeFiles lists large files one per line
+func FormatLargeFiles(files []LargeFile) string {
+	lines := make([]string, 0, len(files))
+	for _, file := range files {
+		lines = append(lines, fmt.Sprintf("  %s: %s (%s, %s) in %s", file.Repository, file.Path, FormatBytes(file.Size), file.Reason, file.Commit))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseSize parses a size such as "512", "500KB", "50MB" or "1.5GB"; units are binary
+func ParseSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.Errorf("invalid size '%s'", size)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// MaxFileSizeBytes returns the file size limit of pushed commits
+func (p *Policy) MaxFileSizeBytes() int64 {
+	if p.maxFileSize == 0 {
+		return DefaultMaxFileSize
+	}
+	return p.maxFileSize
+}
+
+// FindLargeFiles lists the files over the size limit, or matching an LFS
+// pattern without being an LFS pointer, added by the commits of HEAD that are
+// on no remote yet
+func (p *Policy) FindLargeFiles(ctx context.Context, repoName, repoPath string) ([]LargeFile, error) {
+	objects, err := gitOutput(ctx, repoPath, "rev-list", "--objects", "HEAD", "--not", "--remotes")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list unpushed objects of %s", repoName)
+	}
+
+	paths := make(map[string]string)
+	var input strings.Builder
+	for _, line := range splitLines(objects) {
+		sha, filePath, ok := strings.Cut(line, " ")
+		if !ok {
+			continue // commits and the root tree
+		}
+		paths[sha] = filePath
+		input.WriteString(sha + "\n")
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch-check=%(objecttype) %(objectname) %(objectsize)")
+	cmd.Dir = repoPath
+	cmd.Stdin = strings.NewReader(input.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get object sizes of %s", repoName)
+	}
+
+	var files []LargeFile
+	for _, line := range splitLines(string(out)) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "blob" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		file := LargeFile{Repository: repoName, Path: paths[fields[1]], Size: size}
+		switch {
+		case size > p.MaxFileSizeBytes():
+			file.Reason = "over " + FormatBytes(p.MaxFileSizeBytes())
+		case size > lfsPointerMaxSize && p.matchesLFSPattern(file.Path):
+			file.Reason = "should be in Git LFS"
+		default:
+			continue
+		}
+		if commit, err := gitOutput(ctx, repoPath, "log", "-1", "--format=%h", "--find-object="+fields[1], "HEAD", "--not", "--remotes"); err == nil {
+			file.Commit = commit
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func (p *Policy) matchesLFSPattern(filePath string) bool {
+	for _, pattern := range p.LFSPatterns {
+		if matched, _ := path.Match(pattern, filePath); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(filePath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckLargeFiles warns about large files, or returns a *LargeFilesError when
+// the policy blocks them and allowed is not set
+func (p *Policy) CheckLargeFiles(files []LargeFile, allowed bool, hint string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if p.BlockLargeFiles && !allowed {
+		return &LargeFilesError{Files: files, Hint: hint}
+	}
+	output.PrintWarning("Pushing commits that add large files:\n%s", FormatLargeFiles(files))
+	return nil
+}
+
+// checkRepositoryLargeFiles runs the large file preflight for one repository
+// before an automatic push
+func checkRepositoryLargeFiles(ctx context.Context, repoName, repoPath string) error {
+	policy, err := LoadPolicy()
+	if err != nil {
+		return err
+	}
+	files, err := policy.FindLargeFiles(ctx, repoName, repoPath)
+	if err != nil {
+		return err
+	}
+	return policy.CheckLargeFiles(files, false, "push with 'wsm push <remote> --allow-large-files' if they are intended")
+}
@@ -0,0 +1,63 @@
+package wsm
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "512", want: 512},
+		{in: "512B", want: 512},
+		{in: "500KB", want: 500 << 10},
+		{in: "500k", want: 500 << 10},
+		{in: "50MB", want: 50 << 20},
+		{in: " 50 mb ", want: 50 << 20},
+		{in: "1.5GB", want: 3 << 29},
+		{in: "2G", want: 2 << 30},
+		{in: "", wantErr: true},
+		{in: "MB", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "ten", wantErr: true},
+		{in: "10TB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesLFSPattern(t *testing.T) {
+	policy := &Policy{LFSPatterns: []string{"*.psd", "assets/*.bin", "data/**"}}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"design.psd", true},
+		{"art/deep/design.psd", true},
+		{"assets/model.bin", true},
+		{"other/assets/model.bin", false},
+		{"model.bin", false},
+		{"data/x", true},
+		{"data/x/y", false},
+		{"design.psd.txt", false},
+		{"README.md", false},
+	}
+	for _, tt := range tests {
+		if got := policy.matchesLFSPattern(tt.path); got != tt.want {
+			t.Errorf("matchesLFSPattern(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if (&Policy{}).matchesLFSPattern("design.psd") {
+		t.Error("a policy without patterns matched a file")
+	}
+}
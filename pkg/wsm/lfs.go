@@ -0,0 +1,74 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// usesLFS reports whether a checkout tracks files with Git LFS, i.e. its
+// .gitattributes assigns the lfs filter
+func usesLFS(path string) bool {
+	data, err := os.ReadFile(filepath.Join(path, ".gitattributes"))
+	return err == nil && strings.Contains(string(data), "filter=lfs")
+}
+
+// lfsCommands returns the git commands installing the LFS hooks of a worktree
+// and downloading its LFS files, limited to the sparse directories if any
+func lfsCommands(sparsePaths []string) [][]string {
+	pull := []string{"git", "lfs", "pull"}
+	if len(sparsePaths) > 0 {
+		pull = append(pull, "--include", strings.Join(sparsePaths, ","))
+	}
+	return [][]string{{"git", "lfs", "install", "--local"}, pull}
+}
+
+// initLFS installs the Git LFS hooks of a freshly added worktree and replaces
+// its pointer files by their content. The worktree's .gitattributes is checked
+// too, since the registry may predate the repository's use of LFS.
+func (wm *WorkspaceManager) initLFS(ctx context.Context, workspace *Workspace, repo Repository) error {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	if !repo.LFS && !usesLFS(worktreePath) {
+		return nil
+	}
+
+	if err := RequireTool(ctx, "git-lfs"); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("%s uses Git LFS, its LFS files are left as pointers: %v", repo.Name, err),
+			"Git LFS is not available, LFS files are left as pointers",
+			"repository", repo.Name,
+			"error", err,
+		)
+		return nil
+	}
+
+	for _, command := range lfsCommands(workspace.SparsePathsFor(repo.Name)) {
+		if err := wm.ExecuteWorktreeCommand(ctx, worktreePath, command...); err != nil {
+			return errors.Wrapf(err, "failed to fetch the Git LFS files of %s", repo.Name)
+		}
+	}
+	return nil
+}
+
+// planLFS returns the plan steps fetching a repository's Git LFS files
+func planLFS(workspace *Workspace, repo Repository) []PlanStep {
+	if !repo.LFS && !usesLFS(repo.Path) {
+		return nil
+	}
+
+	var steps []PlanStep
+	for _, command := range lfsCommands(workspace.SparsePathsFor(repo.Name)) {
+		steps = append(steps, PlanStep{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Fetch the Git LFS files of %s", repo.Name),
+			Dir:         filepath.Join(workspace.Path, repo.Name),
+			Command:     command,
+		})
+	}
+	return steps
+}
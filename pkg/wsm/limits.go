@@ -0,0 +1,157 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxGitProcesses bounds the git processes wsm runs at once, across
+// all its parallel operations, without limits.yaml
+const DefaultMaxGitProcesses = 8
+
+// Limits bound the parallel operations of wsm (exec, check, fetching stale
+// repositories, prompt status) so they do not saturate the CPU or network.
+// They are read from limits.yaml in the workspace-manager config directory:
+//
+//	max-git-processes: 8   # across all parallel operations of a wsm process
+//	jobs: 4                # repositories an operation works on at once
+//	workspaces:
+//	  monorepo-work: 2     # jobs of a workspace
+//
+// A --jobs flag overrides jobs, but not max-git-processes.
+type Limits struct {
+	MaxGitProcesses int            `yaml:"max-git-processes,omitempty" json:"max_git_processes"`
+	Jobs            int            `yaml:"jobs,omitempty" json:"jobs"`
+	Workspaces      map[string]int `yaml:"workspaces,omitempty" json:"workspaces,omitempty"`
+
+	path string
+}
+
+// GetLimitsPath returns the path of the limits file
+func GetLimitsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "limits.yaml"), nil
+}
+
+// LoadLimits loads limits.yaml, returning the default limits if none exists
+func LoadLimits() (*Limits, error) {
+	limitsPath, err := GetLimitsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &Limits{path: limitsPath}
+	data, err := os.ReadFile(limitsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read limits")
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, limits); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse limits %s", limitsPath)
+		}
+	}
+
+	if limits.MaxGitProcesses < 0 || limits.Jobs < 0 {
+		return nil, errors.Errorf("limits in %s must not be negative", limitsPath)
+	}
+	for name, jobs := range limits.Workspaces {
+		if jobs < 0 {
+			return nil, errors.Errorf("jobs of workspace '%s' in %s must not be negative", name, limitsPath)
+		}
+	}
+	if limits.MaxGitProcesses == 0 {
+		limits.MaxGitProcesses = DefaultMaxGitProcesses
+	}
+	if limits.Jobs == 0 {
+		limits.Jobs = DefaultExecConcurrency
+	}
+	return limits, nil
+}
+
+// loadLimitsOrDefault loads limits.yaml, falling back to the default limits
+// with a warning when it is invalid
+func loadLimitsOrDefault() *Limits {
+	limits, err := LoadLimits()
+	if err != nil {
+		output.PrintWarning("Ignoring limits: %v", err)
+		return &Limits{MaxGitProcesses: DefaultMaxGitProcesses, Jobs: DefaultExecConcurrency}
+	}
+	return limits
+}
+
+// Path returns the file the limits were loaded from
+func (l *Limits) Path() string {
+	return l.path
+}
+
+// JobsFor returns how many repositories of a workspace an operation works on
+// at once: jobs (a --jobs flag) if set, else the workspace's limit, else the
+// default, never more than max-git-processes
+func (l *Limits) JobsFor(workspaceName string, jobs int) int {
+	if jobs <= 0 {
+		jobs = l.Workspaces[workspaceName]
+	}
+	if jobs <= 0 {
+		jobs = l.Jobs
+	}
+	if l.MaxGitProcesses > 0 && jobs > l.MaxGitProcesses {
+		jobs = l.MaxGitProcesses
+	}
+	return jobs
+}
+
+var (
+	gitSlotsOnce sync.Once
+	gitSlots     chan struct{}
+)
+
+// sharedGitSlots returns the semaphore shared by all parallel operations of
+// the process, sized by max-git-processes
+func sharedGitSlots() chan struct{} {
+	gitSlotsOnce.Do(func() {
+		gitSlots = make(chan struct{}, loadLimitsOrDefault().MaxGitProcesses)
+	})
+	return gitSlots
+}
+
+// limiter bounds a parallel operation to its jobs, each of which also holds a
+// slot of the process-wide git process limit while it runs
+type limiter struct {
+	jobs chan struct{}
+}
+
+// newLimiter creates the limiter of an operation on a workspace; jobs is the
+// --jobs flag, 0 if not given
+func newLimiter(workspaceName string, jobs int) *limiter {
+	return &limiter{jobs: make(chan struct{}, loadLimitsOrDefault().JobsFor(workspaceName, jobs))}
+}
+
+// acquire waits for a job and a git process slot. The returned function
+// releases both.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.jobs <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	slots := sharedGitSlots()
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		<-l.jobs
+		return nil, ctx.Err()
+	}
+	return func() {
+		<-slots
+		<-l.jobs
+	}, nil
+}
@@ -0,0 +1,173 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LogOptions filters the commits returned by GetWorkspaceCommits
+type LogOptions struct {
+	Since      string
+	Author     string
+	Grep       string
+	ChangeID   string // Workspace-Change-Id trailer value, cannot be combined with Grep
+	Repository string // restrict to a single repository
+	Limit      int    // maximum number of commits in the combined history, 0 for all
+	Stats      bool   // fill in the files changed by each commit
+}
+
+// LogEntry is a single commit of the combined workspace history
+type LogEntry struct {
+	Repository  string    `json:"repository"`
+	Hash        string    `json:"hash"`
+	ShortHash   string    `json:"short_hash"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"`
+	Subject     string    `json:"subject"`
+	// Files, Insertions and Deletions are only set with LogOptions.Stats
+	Files      []string `json:"files,omitempty"`
+	Insertions int      `json:"insertions,omitempty"`
+	Deletions  int      `json:"deletions,omitempty"`
+}
+
+// Field and record separators for the git log format; they cannot appear in commit metadata
+const (
+	logFieldSeparator  = "\x1f"
+	logRecordSeparator = "\x1e"
+)
+
+// GetWorkspaceCommits returns the commits of all workspace repositories interleaved
+// in reverse chronological order.
+func (so *SyncOperations) GetWorkspaceCommits(ctx context.Context, opts LogOptions) ([]LogEntry, error) {
+	if opts.ChangeID != "" && opts.Grep != "" {
+		return nil, errors.New("a change ID cannot be combined with a message pattern")
+	}
+
+	var entries []LogEntry
+	found := opts.Repository == ""
+
+	for _, repo := range so.workspace.Repositories {
+		if opts.Repository != "" && repo.Name != opts.Repository {
+			continue
+		}
+		found = true
+
+		repoPath := filepath.Join(so.workspace.Path, repo.Name)
+		repoEntries, err := so.getRepositoryCommits(ctx, repo.Name, repoPath, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get log for %s", repo.Name)
+		}
+		entries = append(entries, repoEntries...)
+	}
+
+	if !found {
+		return nil, errors.Errorf("repository '%s' not found in workspace", opts.Repository)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.After(entries[j].Date)
+	})
+
+	if opts.Limit > 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+	}
+
+	return entries, nil
+}
+
+// getRepositoryCommits reads the commit history of a single repository.
+// Each repository is limited to opts.Limit commits, which is enough to fill the combined limit.
+func (so *SyncOperations) getRepositoryCommits(ctx context.Context, repoName, repoPath string, opts LogOptions) ([]LogEntry, error) {
+	// The separator starts each record so --numstat lines end up in the record of their commit
+	format := logRecordSeparator + strings.Join([]string{"%H", "%h", "%an", "%ae", "%at", "%s"}, logFieldSeparator)
+	args := []string{"log", "--format=" + format}
+	if opts.Stats {
+		args = append(args, "--numstat")
+	}
+
+	if opts.ChangeID != "" {
+		args = append(args, "--all", "--fixed-strings", "--grep", fmt.Sprintf("%s: %s", ChangeIDTrailer, opts.ChangeID))
+	} else if opts.Grep != "" {
+		args = append(args, "--grep", opts.Grep)
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	if opts.Author != "" {
+		args = append(args, "--author", opts.Author)
+	}
+	if opts.Grep != "" || opts.Author != "" {
+		args = append(args, "--regexp-ignore-case")
+	}
+	if opts.Limit > 0 {
+		args = append(args, fmt.Sprintf("-%d", opts.Limit))
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	for _, record := range strings.Split(string(out), logRecordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		lines := strings.Split(record, "\n")
+		fields := strings.Split(lines[0], logFieldSeparator)
+		if len(fields) != 6 {
+			continue
+		}
+
+		timestamp, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		entry := LogEntry{
+			Repository:  repoName,
+			Hash:        fields[0],
+			ShortHash:   fields[1],
+			Author:      fields[2],
+			AuthorEmail: fields[3],
+			Date:        time.Unix(timestamp, 0),
+			Subject:     fields[5],
+		}
+		for _, line := range lines[1:] {
+			parseNumstatLine(&entry, line)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseNumstatLine adds a "<added>\t<deleted>\t<path>" line of --numstat to a
+// commit; binary files count as changed without lines
+func parseNumstatLine(entry *LogEntry, line string) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) != 3 {
+		return
+	}
+	entry.Files = append(entry.Files, parts[2])
+	if added, err := strconv.Atoi(parts[0]); err == nil {
+		entry.Insertions += added
+	}
+	if deleted, err := strconv.Atoi(parts[1]); err == nil {
+		entry.Deletions += deleted
+	}
+}
@@ -0,0 +1,392 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes a workspace as a list of remote repositories, similar to repo/west manifests
+type Manifest struct {
+	// Branch is the workspace branch; defaults to the one passed on the command line
+	Branch string `yaml:"branch,omitempty"`
+	// BaseBranch is the default branch new workspace branches are created from
+	BaseBranch string `yaml:"base-branch,omitempty"`
+	// CloneDir is where missing repositories are cloned
+	CloneDir string `yaml:"clone-dir,omitempty"`
+	// Submodules is how submodules are initialized: init (default), recursive or none
+	Submodules string `yaml:"submodules,omitempty"`
+	// Scaffold is the scaffold template (name or path) copied into the workspace
+	Scaffold string `yaml:"scaffold,omitempty"`
+	// MergeStrategy is how 'wsm merge' merges the workspace: merge (default), squash, rebase or ff-only
+	MergeStrategy string `yaml:"merge-strategy,omitempty"`
+	// GitConfig is set in the config of every worktree, e.g. user.email
+	GitConfig    map[string]string    `yaml:"git-config,omitempty"`
+	Repositories []ManifestRepository `yaml:"repositories"`
+}
+
+// ManifestRepository is a repository entry of a manifest
+type ManifestRepository struct {
+	// Name defaults to the last path component of the URL
+	Name string `yaml:"name,omitempty"`
+	URL  string `yaml:"url"`
+	// Branch is checked out when cloning and is the default base branch
+	Branch     string `yaml:"branch,omitempty"`
+	BaseBranch string `yaml:"base-branch,omitempty"`
+	// Sparse restricts the worktree to these directories (cone mode sparse checkout)
+	Sparse []string `yaml:"sparse,omitempty"`
+	// Pin checks the repository out detached at a tag or commit SHA instead of the workspace branch
+	Pin string `yaml:"pin,omitempty"`
+	// Filter (blobless or treeless) and Depth make the clone partial or shallow
+	Filter string `yaml:"filter,omitempty"`
+	Depth  int    `yaml:"depth,omitempty"`
+}
+
+// cloneOptions returns the partial and shallow clone options of the entry
+func (e ManifestRepository) cloneOptions() CloneOptions {
+	return CloneOptions{Filter: e.Filter, Depth: e.Depth}
+}
+
+// ManifestResolution maps manifest entries to registry repositories
+type ManifestResolution struct {
+	Repositories []Repository
+	BaseBranches map[string]string   // repo name -> base branch
+	SparsePaths  map[string][]string // repo name -> sparse checkout directories
+	Pins         map[string]string   // repo name -> pinned tag or commit
+	Cloned       []string            // repositories that were (or would be) cloned
+}
+
+// LoadManifest reads a YAML manifest from a file path or an http(s) URL
+func LoadManifest(ctx context.Context, source string) (*Manifest, error) {
+	var data []byte
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid manifest URL")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to download manifest from %s", source)
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.Errorf("failed to download manifest from %s: %s", source, resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, errors.Wrap(err, "failed to read manifest")
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return nil, errors.Wrapf(err, "failed to read manifest %s", source)
+		}
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to parse manifest")
+	}
+
+	if len(manifest.Repositories) == 0 {
+		return nil, errors.New("manifest lists no repositories")
+	}
+	if err := ValidateSubmoduleMode(manifest.Submodules); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+	if err := ValidateMergeStrategy(manifest.MergeStrategy); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+	for i := range manifest.Repositories {
+		repo := &manifest.Repositories[i]
+		if repo.URL == "" {
+			return nil, errors.Errorf("manifest repository #%d has no url", i+1)
+		}
+		if repo.Name == "" {
+			repo.Name = strings.TrimSuffix(filepath.Base(strings.TrimRight(repo.URL, "/")), ".git")
+		}
+		if err := ValidateRepositoryName(repo.Name); err != nil {
+			return nil, errors.Wrapf(err, "manifest repository #%d", i+1)
+		}
+		if len(repo.Sparse) > 0 {
+			sparse, err := CleanSparsePaths(repo.Sparse)
+			if err != nil {
+				return nil, errors.Wrapf(err, "manifest repository '%s'", repo.Name)
+			}
+			repo.Sparse = sparse
+		}
+		if err := repo.cloneOptions().Validate(); err != nil {
+			return nil, errors.Wrapf(err, "manifest repository '%s'", repo.Name)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// ResolveManifest finds each manifest repository in the registry (by remote URL, then
+// by name), cloning and registering the missing ones into cloneDir.
+func (wm *WorkspaceManager) ResolveManifest(ctx context.Context, manifest *Manifest, cloneDir string, dryRun bool) (*ManifestResolution, error) {
+	if cloneDir == "" {
+		cloneDir = manifest.CloneDir
+	}
+	cloneDir, err := resolveCloneDir(cloneDir)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := &ManifestResolution{
+		BaseBranches: map[string]string{},
+		SparsePaths:  map[string][]string{},
+		Pins:         map[string]string{},
+	}
+
+	for _, entry := range manifest.Repositories {
+		repo, found := wm.findManifestRepository(entry)
+		if !found {
+			targetPath := filepath.Join(cloneDir, entry.Name)
+			resolution.Cloned = append(resolution.Cloned, entry.Name)
+
+			if dryRun {
+				repo = Repository{Name: entry.Name, Path: targetPath, RemoteURL: entry.URL}
+			} else {
+				cloned, err := wm.cloneManifestRepository(ctx, entry, targetPath)
+				if err != nil {
+					return nil, err
+				}
+				repo = *cloned
+			}
+		}
+
+		resolution.Repositories = append(resolution.Repositories, repo)
+
+		baseBranch := entry.BaseBranch
+		if baseBranch == "" {
+			baseBranch = entry.Branch
+		}
+		if baseBranch != "" {
+			resolution.BaseBranches[repo.Name] = baseBranch
+		}
+		if len(entry.Sparse) > 0 {
+			resolution.SparsePaths[repo.Name] = entry.Sparse
+		}
+		if entry.Pin != "" {
+			resolution.Pins[repo.Name] = entry.Pin
+		}
+	}
+
+	return resolution, nil
+}
+
+// CreateWorkspaceFromManifest clones missing repositories and creates a workspace from a manifest.
+// The repositories, sparse directories, pins and submodule mode come from the
+// manifest; the other options override the manifest's values when set.
+func (wm *WorkspaceManager) CreateWorkspaceFromManifest(ctx context.Context, manifest *Manifest, cloneDir string, opts CreateOptions) (*Workspace, *ManifestResolution, error) {
+	if opts.Branch == "" {
+		opts.Branch = manifest.Branch
+	}
+	var err error
+	opts.Name, opts.Branch, err = wm.prepareWorkspaceNames(opts.Name, opts.Branch, opts.Sanitize)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkManifestGitConfig(manifest.GitConfig, opts.GitConfig, !opts.DryRun); err != nil {
+		return nil, nil, errors.Wrap(err, "manifest git-config")
+	}
+
+	resolution, err := wm.ResolveManifest(ctx, manifest, cloneDir, opts.DryRun)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to resolve manifest repositories")
+	}
+
+	if opts.BaseBranch == "" {
+		opts.BaseBranch = manifest.BaseBranch
+	}
+	if opts.Scaffold == "" {
+		opts.Scaffold = manifest.Scaffold
+	}
+	if opts.MergeStrategy == "" {
+		opts.MergeStrategy = manifest.MergeStrategy
+	}
+	opts.SparsePaths = resolution.SparsePaths
+	opts.Pins = resolution.Pins
+	opts.Submodules = manifest.Submodules
+
+	workspace, err := wm.newWorkspace(resolution.Repositories, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the keys of --git-config are the user's own
+	workspace.GitConfig = MergeGitConfig(manifest.GitConfig, opts.GitConfig)
+	workspace.RepositoryBaseBranches = resolution.BaseBranches
+	if err := wm.createNewWorkspace(ctx, workspace, opts); err != nil {
+		return nil, nil, err
+	}
+	return workspace, resolution, nil
+}
+
+// PlanManifestClones returns the clone and registration steps for repositories missing from the registry
+func PlanManifestClones(manifest *Manifest, resolution *ManifestResolution) []PlanStep {
+	cloned := make(map[string]bool)
+	for _, name := range resolution.Cloned {
+		cloned[name] = true
+	}
+
+	paths := make(map[string]string)
+	for _, repo := range resolution.Repositories {
+		paths[repo.Name] = repo.Path
+	}
+
+	var steps []PlanStep
+	for _, entry := range manifest.Repositories {
+		if !cloned[entry.Name] {
+			continue
+		}
+		targetPath := paths[entry.Name]
+
+		command := []string{"git", "clone"}
+		if entry.Branch != "" {
+			command = append(command, "--branch", entry.Branch)
+		}
+		command = append(command, entry.cloneOptions().cloneArgs()...)
+		command = append(command, entry.URL, targetPath)
+
+		steps = append(steps,
+			PlanStep{
+				Action:      PlanActionMkdir,
+				Description: fmt.Sprintf("Create clone directory for %s", entry.Name),
+				Path:        filepath.Dir(targetPath),
+			},
+			PlanStep{
+				Action:      PlanActionCommand,
+				Description: fmt.Sprintf("Clone %s", entry.Name),
+				Dir:         filepath.Dir(targetPath),
+				Command:     command,
+			},
+			PlanStep{
+				Action:      PlanActionCommand,
+				Description: fmt.Sprintf("Register %s in the repository registry", entry.Name),
+				Dir:         targetPath,
+				Command:     []string{"wsm", "discover", targetPath},
+			},
+		)
+	}
+
+	return steps
+}
+
+// findManifestRepository looks up a manifest entry in the registry
+func (wm *WorkspaceManager) findManifestRepository(entry ManifestRepository) (Repository, bool) {
+	repos := wm.Discoverer.GetRepositories()
+	wanted := normalizeRemoteURL(entry.URL)
+
+	for _, repo := range repos {
+		if repo.RemoteURL != "" && normalizeRemoteURL(repo.RemoteURL) == wanted {
+			return repo, true
+		}
+	}
+
+	for _, repo := range repos {
+		if repo.Name == entry.Name {
+			if repo.RemoteURL != "" && normalizeRemoteURL(repo.RemoteURL) != wanted {
+				output.LogWarn(
+					fmt.Sprintf("Registry repository '%s' has remote %s, manifest expects %s", repo.Name, repo.RemoteURL, entry.URL),
+					"Manifest repository matched by name with different remote",
+					"repository", repo.Name,
+					"registryRemote", repo.RemoteURL,
+					"manifestRemote", entry.URL,
+				)
+			}
+			return repo, true
+		}
+	}
+
+	return Repository{}, false
+}
+
+// cloneManifestRepository clones a repository (unless already present) and registers it
+func (wm *WorkspaceManager) cloneManifestRepository(ctx context.Context, entry ManifestRepository, targetPath string) (*Repository, error) {
+	if _, err := os.Stat(targetPath); err == nil {
+		if !wm.Discoverer.isGitRepository(targetPath) {
+			return nil, errors.Errorf("cannot clone %s: %s exists and is not a git repository", entry.Name, targetPath)
+		}
+		output.PrintInfo("Using existing clone of %s at %s", entry.Name, targetPath)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return nil, errors.Wrap(err, "failed to create clone directory")
+		}
+
+		args := []string{"clone"}
+		if entry.Branch != "" {
+			args = append(args, "--branch", entry.Branch)
+		}
+		args = append(args, entry.cloneOptions().cloneArgs()...)
+		args = append(args, entry.URL, targetPath)
+
+		output.PrintInfo("Cloning %s into %s...", entry.URL, targetPath)
+		cmd := exec.CommandContext(ctx, "git", args...)
+		if cmdOutput, err := cmd.CombinedOutput(); err != nil {
+			return nil, errors.Wrapf(err, "failed to clone %s: %s", entry.URL, strings.TrimSpace(string(cmdOutput)))
+		}
+	}
+
+	if err := wm.Discoverer.DiscoverRepositories(ctx, []string{targetPath}, false, 0); err != nil {
+		return nil, errors.Wrapf(err, "failed to register %s", entry.Name)
+	}
+
+	for i, repo := range wm.Discoverer.registry.Repositories {
+		if repo.Path != targetPath {
+			continue
+		}
+		// Later fetches keep the clone partial or shallow
+		if options := entry.cloneOptions(); !options.IsZero() && repo.CloneOptions == nil {
+			repo.CloneOptions = &options
+			wm.Discoverer.registry.Repositories[i] = repo
+			if err := wm.Discoverer.SaveRegistry(); err != nil {
+				return nil, errors.Wrap(err, "failed to save registry")
+			}
+		}
+		return &repo, nil
+	}
+	return nil, errors.Errorf("repository %s was cloned but not registered", entry.Name)
+}
+
+// normalizeRemoteURL reduces ssh/https remote URLs to "host/path" for comparison
+func normalizeRemoteURL(url string) string {
+	url = strings.TrimSpace(url)
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://"} {
+		url = strings.TrimPrefix(url, prefix)
+	}
+	if at := strings.Index(url, "@"); at >= 0 && at < strings.IndexAny(url+"/", ":/") {
+		url = url[at+1:]
+	}
+	// scp-like syntax: host:path
+	if colon := strings.Index(url, ":"); colon >= 0 && !strings.Contains(url[:colon], "/") {
+		url = url[:colon] + "/" + url[colon+1:]
+	}
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	return strings.ToLower(url)
+}
+
+// expandHome expands a leading ~ to the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
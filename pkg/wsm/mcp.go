@@ -0,0 +1,316 @@
+package wsm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// MCPProtocolVersion is the Model Context Protocol revision spoken by MCPServer
+const MCPProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+)
+
+// MCPServer serves workspace operations as Model Context Protocol tools over
+// newline-delimited JSON-RPC, as used by the stdio transport. Requests are
+// handled one at a time, so tools never run concurrently on a workspace.
+type MCPServer struct {
+	service *WorkspaceService
+	version string
+	tools   []mcpTool
+}
+
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	handler     func(ctx context.Context, args json.RawMessage) (interface{}, error)
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      interface{}   `json:"id"`
+	Result  interface{}   `json:"result,omitempty"`
+	Error   *jsonRPCError `json:"error,omitempty"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// mcpContent is a text item of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// NewMCPServer creates an MCP server for the workspace service. version is
+// reported to clients as the server version.
+func NewMCPServer(service *WorkspaceService, version string) *MCPServer {
+	s := &MCPServer{service: service, version: version}
+	s.tools = s.buildTools()
+	return s
+}
+
+func (s *MCPServer) buildTools() []mcpTool {
+	workspaceProperty := map[string]interface{}{"type": "string", "description": "Workspace name"}
+	namesProperty := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	filterProperty := map[string]interface{}{
+		"type":        "object",
+		"description": "Only operate on the repositories passing this filter",
+		"properties": map[string]interface{}{
+			"repositories": namesProperty,
+			"exclude":      namesProperty,
+			"tags":         namesProperty,
+		},
+	}
+
+	return []mcpTool{
+		{
+			Name:        "list_workspaces",
+			Description: "List all workspaces with their path, branch and repositories",
+			InputSchema: objectSchema(map[string]interface{}{}),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				return s.service.ListWorkspaces()
+			},
+		},
+		{
+			Name:        "workspace_status",
+			Description: "Show the git status of every repository of a workspace: branch, staged, modified and untracked files, ahead/behind counts and conflicts",
+			InputSchema: objectSchema(map[string]interface{}{
+				"workspace": workspaceProperty,
+				"filter":    filterProperty,
+			}, "workspace"),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req struct {
+					Workspace string           `json:"workspace"`
+					Filter    RepositoryFilter `json:"filter"`
+				}
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				return s.service.Status(ctx, req.Workspace, req.Filter)
+			},
+		},
+		{
+			Name:        "create_workspace",
+			Description: "Create a workspace with a git worktree per repository on a new branch (task/<name> by default)",
+			InputSchema: objectSchema(map[string]interface{}{
+				"name":         map[string]interface{}{"type": "string", "description": "Workspace name"},
+				"repositories": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Names of registered repositories, @groups, paths to clones or remote URLs"},
+				"branch":       map[string]interface{}{"type": "string", "description": "Branch to create, defaults to task/<name>"},
+				"base_branch":  map[string]interface{}{"type": "string", "description": "Branch to start from, defaults to the current branch of each repository"},
+				"sanitize":     map[string]interface{}{"type": "boolean", "description": "Replace characters not allowed in the name and branch instead of failing"},
+			}, "name", "repositories"),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req CreateWorkspaceRequest
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				return s.service.CreateWorkspace(ctx, req)
+			},
+		},
+		{
+			Name:        "commit",
+			Description: "Stage and commit all changes of a workspace with one message, optionally pushing",
+			InputSchema: objectSchema(map[string]interface{}{
+				"workspace":    workspaceProperty,
+				"message":      map[string]interface{}{"type": "string", "description": "Commit message"},
+				"repositories": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Only commit in these repositories"},
+				"push":         map[string]interface{}{"type": "boolean", "description": "Push after committing"},
+				"change_id":    map[string]interface{}{"type": "boolean", "description": "Add a shared Workspace-Change-Id trailer"},
+				"filter":       filterProperty,
+			}, "workspace", "message"),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req CommitRequest
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				return s.service.Commit(ctx, req)
+			},
+		},
+		{
+			Name:        "diff",
+			Description: "Show the unified diff of a workspace, or of one repository",
+			InputSchema: objectSchema(map[string]interface{}{
+				"workspace":      workspaceProperty,
+				"repository":     map[string]interface{}{"type": "string", "description": "Only show this repository"},
+				"staged":         map[string]interface{}{"type": "boolean", "description": "Show staged changes instead of unstaged ones"},
+				"since_creation": map[string]interface{}{"type": "boolean", "description": "Show everything changed since the worktrees were created, commits included"},
+				"filter":         filterProperty,
+			}, "workspace"),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req DiffRequest
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				return s.service.Diff(ctx, req)
+			},
+		},
+		{
+			Name:        "sync",
+			Description: "Pull and/or push every repository of a workspace and report ahead/behind counts",
+			InputSchema: objectSchema(map[string]interface{}{
+				"workspace": workspaceProperty,
+				"pull":      map[string]interface{}{"type": "boolean", "description": "Pull from the remote"},
+				"push":      map[string]interface{}{"type": "boolean", "description": "Push to the remote"},
+				"rebase":    map[string]interface{}{"type": "boolean", "description": "Rebase instead of merge when pulling"},
+				"filter":    filterProperty,
+			}, "workspace"),
+			handler: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+				var req SyncRequest
+				if err := decodeToolArgs(args, &req); err != nil {
+					return nil, err
+				}
+				return s.service.Sync(ctx, req)
+			},
+		},
+	}
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func decodeToolArgs(args json.RawMessage, v interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return errors.Wrap(err, "invalid tool arguments")
+	}
+	return nil
+}
+
+// Serve reads requests from r and writes responses to w until r is closed or
+// ctx is cancelled
+func (s *MCPServer) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		response := s.handle(ctx, line)
+		if response == nil {
+			continue
+		}
+		if err := encoder.Encode(response); err != nil {
+			return errors.Wrap(err, "failed to write response")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to read request")
+	}
+	return nil
+}
+
+// handle processes one message, returning nil for notifications
+func (s *MCPServer) handle(ctx context.Context, line []byte) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return rpcError(nil, jsonRPCParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcError(req.ID, jsonRPCInvalidRequest, "invalid request")
+	}
+	// Notifications (initialized, cancelled) carry no id and get no response
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return rpcResult(req.ID, map[string]interface{}{
+			"protocolVersion": MCPProtocolVersion,
+			"capabilities": map[string]interface{}{
+				"tools": map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{
+				"name":    "wsm",
+				"version": s.version,
+			},
+		})
+	case "ping":
+		return rpcResult(req.ID, map[string]interface{}{})
+	case "tools/list":
+		return rpcResult(req.ID, map[string]interface{}{"tools": s.tools})
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return rpcError(req.ID, jsonRPCInvalidParams, "invalid params: "+err.Error())
+		}
+		for _, tool := range s.tools {
+			if tool.Name == params.Name {
+				return rpcResult(req.ID, callTool(ctx, tool, params.Arguments))
+			}
+		}
+		return rpcError(req.ID, jsonRPCInvalidParams, "unknown tool: "+params.Name)
+	default:
+		return rpcError(req.ID, jsonRPCMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+// callTool runs a tool; failures are reported in the result so the agent sees them
+func callTool(ctx context.Context, tool mcpTool, args json.RawMessage) mcpToolResult {
+	value, err := tool.handler(ctx, args)
+	if err != nil {
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+
+	if text, ok := value.(string); ok {
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: "failed to encode result: " + err.Error()}}, IsError: true}
+	}
+	return mcpToolResult{Content: []mcpContent{{Type: "text", Text: string(data)}}}
+}
+
+func rpcResult(id json.RawMessage, result interface{}) *jsonRPCResponse {
+	return &jsonRPCResponse{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func rpcError(id json.RawMessage, code int, message string) *jsonRPCResponse {
+	return &jsonRPCResponse{JSONRPC: "2.0", ID: id, Error: &jsonRPCError{Code: code, Message: message}}
+}
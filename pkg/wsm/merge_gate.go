@@ -0,0 +1,209 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// States of the pull request checks of a repository
+const (
+	ChecksPassing = "passing"
+	ChecksFailing = "failing"
+	ChecksPending = "pending"
+	// ChecksNone means the pull request has no checks
+	ChecksNone = "none"
+)
+
+// MergeGateOptions controls the preconditions checked before 'wsm merge'
+type MergeGateOptions struct {
+	// AllowBehind accepts branches behind their base branch, e.g. when the
+	// merge strategy rebases them anyway
+	AllowBehind bool
+	// Rebase rebases branches behind their base branch instead of failing
+	Rebase bool
+	// RequireChecks requires an open pull request whose checks passed on the
+	// commit being merged
+	RequireChecks bool
+	// DryRun reports the rebases Rebase would do without doing them
+	DryRun bool
+}
+
+// MergeReadiness tells whether the workspace branch of a repository can be merged
+type MergeReadiness struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	// BaseRef is the base branch on the base remote, as just fetched
+	BaseRef string `json:"base_ref"`
+	Behind  int    `json:"behind"`
+	Rebased bool   `json:"rebased,omitempty"`
+	// PullRequest and Checks are only filled in when checks are required
+	PullRequest string   `json:"pull_request,omitempty"`
+	Checks      string   `json:"checks,omitempty"`
+	Problems    []string `json:"problems,omitempty"`
+}
+
+// Ready reports whether nothing prevents the merge
+func (r MergeReadiness) Ready() bool {
+	return len(r.Problems) == 0
+}
+
+// CheckMergeReadiness checks every repository of a workspace before a merge:
+// its branch must contain the latest base branch and, optionally, have an open
+// pull request with passing checks. The base remote of every repository is
+// fetched; with options.Rebase branches behind their base are rebased onto it.
+func CheckMergeReadiness(ctx context.Context, workspace *Workspace, options MergeGateOptions) []MergeReadiness {
+	if options.RequireChecks {
+		if err := RequireTool(ctx, "gh"); err != nil {
+			var report []MergeReadiness
+			for _, repo := range workspace.Repositories {
+				report = append(report, MergeReadiness{Repository: repo.Name, Branch: workspace.BranchFor(repo.Name), Problems: []string{err.Error()}})
+			}
+			return report
+		}
+	}
+
+	report := make([]MergeReadiness, 0, len(workspace.Repositories))
+	for _, repo := range workspace.Repositories {
+		report = append(report, checkRepositoryReadiness(ctx, workspace, repo, options))
+	}
+	return report
+}
+
+func checkRepositoryReadiness(ctx context.Context, workspace *Workspace, repo Repository, options MergeGateOptions) MergeReadiness {
+	repoPath := filepath.Join(workspace.Path, repo.Name)
+	readiness := MergeReadiness{Repository: repo.Name, Branch: workspace.BranchFor(repo.Name)}
+
+	base := workspace.TargetBranchFor(repo.Name)
+	if base == "" {
+		base = workspace.BaseBranch
+	}
+	remote := repo.BaseRemote()
+	if _, err := gitOutput(ctx, repoPath, "fetch", remote, base); err != nil {
+		readiness.BaseRef = base
+		readiness.Problems = append(readiness.Problems, fmt.Sprintf("failed to fetch %s from %s: %v", base, remote, err))
+		return readiness
+	}
+	readiness.BaseRef = remote + "/" + base
+	if !refExists(ctx, repoPath, readiness.BaseRef) {
+		readiness.BaseRef = base
+	}
+
+	behind, err := gitOutput(ctx, repoPath, "rev-list", "--count", workspace.BranchFor(repo.Name)+".."+readiness.BaseRef)
+	if err != nil {
+		readiness.Problems = append(readiness.Problems, fmt.Sprintf("failed to compare with %s: %v", readiness.BaseRef, err))
+		return readiness
+	}
+	readiness.Behind, _ = strconv.Atoi(behind)
+
+	if readiness.Behind > 0 && !options.AllowBehind {
+		switch {
+		case !options.Rebase:
+			readiness.Problems = append(readiness.Problems, fmt.Sprintf("%d commits behind %s, rebase the branch or merge with --auto-rebase", readiness.Behind, readiness.BaseRef))
+		case options.DryRun:
+			// The rebase is reported, not done
+		default:
+			if err := rebaseForMerge(ctx, repoPath, workspace.BranchFor(repo.Name), readiness.BaseRef); err != nil {
+				readiness.Problems = append(readiness.Problems, err.Error())
+			} else {
+				readiness.Rebased = true
+			}
+		}
+	}
+
+	if options.RequireChecks {
+		checkPullRequest(ctx, workspace, repo, repoPath, &readiness)
+	}
+	return readiness
+}
+
+// rebaseForMerge rebases branch onto baseRef, aborting on conflicts
+func rebaseForMerge(ctx context.Context, repoPath, branch, baseRef string) error {
+	if _, err := gitOutput(ctx, repoPath, "rebase", baseRef, branch); err != nil {
+		_, _ = gitOutput(ctx, repoPath, "rebase", "--abort")
+		return errors.Errorf("rebase onto %s failed, rebase the branch manually", baseRef)
+	}
+	return nil
+}
+
+// checkPullRequest fills in the pull request of the workspace branch and the
+// state of its checks, which must have run on the local branch head
+func checkPullRequest(ctx context.Context, workspace *Workspace, repo Repository, repoPath string, readiness *MergeReadiness) {
+	args := []string{"pr", "list", "--head", workspace.BranchFor(repo.Name), "--state", "open", "--limit", "1",
+		"--json", "url,headRefOid,statusCheckRollup"}
+	if workspace.Fork != nil {
+		upstream, err := UpstreamRepository(ctx, repo, repoPath)
+		if err != nil {
+			readiness.Problems = append(readiness.Problems, fmt.Sprintf("cannot determine the upstream repository: %v", err))
+			return
+		}
+		args = append(args, "--repo", upstream)
+	}
+
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		readiness.Problems = append(readiness.Problems, fmt.Sprintf("gh pr list failed: %v", err))
+		return
+	}
+
+	var pulls []struct {
+		URL               string `json:"url"`
+		HeadRefOid        string `json:"headRefOid"`
+		StatusCheckRollup []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			State      string `json:"state"`
+		} `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(out, &pulls); err != nil {
+		readiness.Problems = append(readiness.Problems, fmt.Sprintf("failed to parse gh pr list output: %v", err))
+		return
+	}
+	if len(pulls) == 0 {
+		readiness.Problems = append(readiness.Problems, "no open pull request")
+		return
+	}
+	pull := pulls[0]
+	readiness.PullRequest = pull.URL
+
+	readiness.Checks = ChecksNone
+	for _, check := range pull.StatusCheckRollup {
+		// Check runs have a status and a conclusion, commit statuses a state
+		state := strings.ToUpper(check.Conclusion)
+		if state == "" {
+			state = strings.ToUpper(check.State)
+		}
+		switch {
+		case state == "FAILURE" || state == "ERROR" || state == "CANCELLED" || state == "TIMED_OUT" || state == "ACTION_REQUIRED":
+			readiness.Checks = ChecksFailing
+		case readiness.Checks == ChecksFailing:
+		case state == "" || state == "PENDING" || state == "EXPECTED" || (check.Status != "" && strings.ToUpper(check.Status) != "COMPLETED"):
+			readiness.Checks = ChecksPending
+		case readiness.Checks != ChecksPending:
+			readiness.Checks = ChecksPassing
+		}
+	}
+
+	if head, err := gitOutput(ctx, repoPath, "rev-parse", workspace.BranchFor(repo.Name)); err == nil && head != pull.HeadRefOid {
+		readiness.Problems = append(readiness.Problems, "the pull request is not at the local branch head, push the branch and wait for its checks")
+		return
+	}
+	switch readiness.Checks {
+	case ChecksFailing:
+		readiness.Problems = append(readiness.Problems, "pull request checks failing")
+	case ChecksPending:
+		readiness.Problems = append(readiness.Problems, "pull request checks pending")
+	}
+}
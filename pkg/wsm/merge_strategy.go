@@ -0,0 +1,55 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Merge strategies of 'wsm merge'
+const (
+	// MergeStrategyMerge merges the workspace branch with a plain git merge (the default)
+	MergeStrategyMerge = "merge"
+	// MergeStrategySquash squashes the workspace branch into one commit per repository
+	MergeStrategySquash = "squash"
+	// MergeStrategyRebase rebases the workspace branch onto the base branch and fast-forwards
+	MergeStrategyRebase = "rebase"
+	// MergeStrategyFFOnly only fast-forwards the base branch, failing when histories diverged
+	MergeStrategyFFOnly = "ff-only"
+)
+
+// MergeStrategies lists the accepted merge strategies, for flag completion
+var MergeStrategies = []string{MergeStrategyMerge, MergeStrategySquash, MergeStrategyRebase, MergeStrategyFFOnly}
+
+// ValidateMergeStrategy rejects unknown merge strategies; empty means the default
+func ValidateMergeStrategy(strategy string) error {
+	if strategy == "" || containsValue(MergeStrategies, strategy) {
+		return nil
+	}
+	return errors.Errorf("invalid merge strategy '%s' (expected %s)", strategy, strings.Join(MergeStrategies, ", "))
+}
+
+// MergeStrategyOrDefault returns how the workspace is merged into its base
+// branch: override when set, then the workspace setting, then a plain merge
+func (w *Workspace) MergeStrategyOrDefault(override string) string {
+	if override != "" {
+		return override
+	}
+	if w.MergeStrategy != "" {
+		return w.MergeStrategy
+	}
+	return MergeStrategyMerge
+}
+
+// SquashMergeMessage generates the message of the commit squashing branch into
+// base, listing the subjects of the squashed commits
+func SquashMergeMessage(ctx context.Context, dir, branch, base string) string {
+	message := fmt.Sprintf("Squash merge branch '%s' into %s", branch, base)
+	subjects, err := gitOutput(ctx, dir, "log", "--reverse", "--format=* %s", base+".."+branch)
+	if err != nil || subjects == "" {
+		return message
+	}
+	return message + "\n\n" + subjects
+}
@@ -0,0 +1,246 @@
+package wsm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxWorkspaceNameLength keeps <name>.json within the 255 bytes most
+	// filesystems allow for a file name
+	maxWorkspaceNameLength = 250
+	// worktreePathReserve is the room kept below a worktree path for the
+	// files of the repository
+	worktreePathReserve = 100
+)
+
+// workspaceNameReserved are the characters not allowed in workspace names:
+// path separators, characters reserved on Windows, and characters shells and
+// tmux session names treat specially
+const workspaceNameReserved = `/\<>:"|?*$!&;'` + "`"
+
+// windowsReservedNames cannot be used as file names on Windows, with or
+// without an extension
+var windowsReservedNames = regexp.MustCompile(`(?i)^(con|prn|aux|nul|com[0-9]|lpt[0-9])(\..*)?$`)
+
+// ValidateWorkspaceName checks that a workspace name can be used as a
+// directory name, a configuration file name and a tmux session name on every OS
+func ValidateWorkspaceName(name string) error {
+	return validateName("workspace", name, " (use --sanitize)")
+}
+
+// ValidateRepositoryName checks a repository name from a manifest or a remote
+// state with the rules of workspace names, since it becomes a directory of
+// the workspace and of the clone directory
+func ValidateRepositoryName(name string) error {
+	return validateName("repository", name, "")
+}
+
+// validateName applies the workspace name rules to a name of the given kind,
+// appending hint to the errors about characters
+func validateName(kind, name, hint string) error {
+	switch {
+	case name == "":
+		return errors.Errorf("%s name is required", kind)
+	case len(name) > maxWorkspaceNameLength:
+		return errors.Errorf("%s name is %d bytes long, the maximum is %d", kind, len(name), maxWorkspaceNameLength)
+	case name == "." || name == "..":
+		return errors.Errorf("invalid %s name '%s'", kind, name)
+	case strings.HasPrefix(name, "."):
+		return errors.Errorf("invalid %s name '%s': it must not start with '.'", kind, name)
+	case strings.HasPrefix(name, "-"):
+		return errors.Errorf("invalid %s name '%s': it must not start with '-'", kind, name)
+	case strings.HasSuffix(name, "."):
+		return errors.Errorf("invalid %s name '%s': it must not end with '.'", kind, name)
+	case windowsReservedNames.MatchString(name):
+		return errors.Errorf("invalid %s name '%s': the name is reserved on Windows", kind, name)
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || r == ' ' || r == '\t' {
+			return errors.Errorf("invalid %s name '%s': it must not contain whitespace or control characters%s", kind, name, hint)
+		}
+		if strings.ContainsRune(workspaceNameReserved, r) {
+			return errors.Errorf("invalid %s name '%s': it must not contain '%c'%s", kind, name, r, hint)
+		}
+	}
+	return nil
+}
+
+// SanitizeWorkspaceName turns a string into a valid workspace name by
+// replacing reserved characters and whitespace with '-'
+func SanitizeWorkspaceName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || r == ' ' || r == '\t' || strings.ContainsRune(workspaceNameReserved, r) {
+			r = '-'
+		}
+		b.WriteRune(r)
+	}
+	sanitized := collapseDashes(b.String())
+	sanitized = strings.Trim(sanitized, "-.")
+	if len(sanitized) > maxWorkspaceNameLength {
+		sanitized = strings.TrimRight(sanitized[:maxWorkspaceNameLength], "-.")
+	}
+	if windowsReservedNames.MatchString(sanitized) {
+		sanitized = "ws-" + sanitized
+	}
+	return sanitized
+}
+
+// ValidateBranchName checks a branch name with the rules of
+// 'git check-ref-format --branch'
+func ValidateBranchName(branch string) error {
+	if branch == "" {
+		return errors.New("branch name is required")
+	}
+	invalid := func(reason string) error {
+		return errors.Errorf("invalid branch name '%s': %s (use --sanitize)", branch, reason)
+	}
+	switch {
+	case branch == "@":
+		return invalid("'@' is not a valid ref name")
+	case strings.HasPrefix(branch, "-"):
+		return invalid("it must not start with '-'")
+	case strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/"):
+		return invalid("it must not start or end with '/'")
+	case strings.HasSuffix(branch, "."):
+		return invalid("it must not end with '.'")
+	case strings.Contains(branch, ".."):
+		return invalid("it must not contain '..'")
+	case strings.Contains(branch, "//"):
+		return invalid("it must not contain '//'")
+	case strings.Contains(branch, "@{"):
+		return invalid("it must not contain '@{'")
+	}
+	for _, r := range branch {
+		if r < 0x20 || r == 0x7f {
+			return invalid("it must not contain control characters")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", r) {
+			return invalid(fmt.Sprintf("it must not contain '%c'", r))
+		}
+	}
+	for _, component := range strings.Split(branch, "/") {
+		if strings.HasPrefix(component, ".") {
+			return invalid("no part of it may start with '.'")
+		}
+		if strings.HasSuffix(component, ".lock") {
+			return invalid("no part of it may end with '.lock'")
+		}
+	}
+	return nil
+}
+
+// SanitizeBranchName turns a string into a valid branch name: characters
+// git rejects become '-' and invalid sequences are removed
+func SanitizeBranchName(branch string) string {
+	var b strings.Builder
+	for _, r := range branch {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			r = '-'
+		}
+		b.WriteRune(r)
+	}
+	sanitized := strings.ReplaceAll(b.String(), "@{", "-")
+	for strings.Contains(sanitized, "..") {
+		sanitized = strings.ReplaceAll(sanitized, "..", ".")
+	}
+
+	var components []string
+	for _, component := range strings.Split(sanitized, "/") {
+		component = strings.TrimLeft(collapseDashes(component), ".")
+		for strings.HasSuffix(component, ".lock") {
+			component = strings.TrimSuffix(component, ".lock")
+		}
+		if component = strings.Trim(component, "-"); component != "" {
+			components = append(components, component)
+		}
+	}
+	sanitized = strings.TrimRight(strings.Join(components, "/"), ".")
+	if sanitized == "@" {
+		return ""
+	}
+	return sanitized
+}
+
+// collapseDashes replaces runs of '-' with a single one
+func collapseDashes(s string) string {
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "-")
+	}
+	return s
+}
+
+// maxPathLength is the longest path the OS handles reliably
+func maxPathLength() int {
+	switch runtime.GOOS {
+	case "windows":
+		return 260
+	case "darwin":
+		return 1024
+	default:
+		return 4096
+	}
+}
+
+// ValidateWorkspacePath checks that the worktrees of a workspace at path
+// leave room for the files of their repositories within the OS path limit
+func ValidateWorkspacePath(path string, repoNames []string) error {
+	limit := maxPathLength()
+	for _, repoName := range repoNames {
+		worktreePath := filepath.Join(path, repoName)
+		if len(worktreePath)+worktreePathReserve > limit {
+			return errors.Errorf("worktree path %s is %d characters long; with room for the repository files it exceeds the %d characters allowed on %s, use a shorter workspace name or workspace directory",
+				worktreePath, len(worktreePath), limit, runtime.GOOS)
+		}
+	}
+	return nil
+}
+
+// prepareWorkspaceNames validates the name and branch of a new workspace,
+// sanitizing them first when sanitize is set, checks the branch against the
+// naming policy and that no workspace of that name exists
+func (wm *WorkspaceManager) prepareWorkspaceNames(name, branch string, sanitize bool) (string, string, error) {
+	if sanitize {
+		if sanitized := SanitizeWorkspaceName(name); sanitized != name {
+			output.PrintInfoStderr("Using workspace name '%s' for '%s'", sanitized, name)
+			name = sanitized
+		}
+		if sanitized := SanitizeBranchName(branch); branch != "" && sanitized != branch {
+			output.PrintInfoStderr("Using branch '%s' for '%s'", sanitized, branch)
+			branch = sanitized
+		}
+	}
+
+	if err := ValidateWorkspaceName(name); err != nil {
+		return "", "", err
+	}
+	if branch != "" {
+		if err := ValidateBranchName(branch); err != nil {
+			return "", "", err
+		}
+		naming, err := LoadBranchNaming()
+		if err != nil {
+			return "", "", err
+		}
+		if err := naming.Validate(branch); err != nil {
+			return "", "", err
+		}
+	}
+
+	if _, err := wm.LoadWorkspace(name); err == nil {
+		return "", "", errors.Errorf("workspace '%s' already exists", name)
+	}
+	path := filepath.Join(wm.workspaceDir, name)
+	if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+		return "", "", errors.Errorf("directory %s already exists and is not empty", path)
+	}
+	return name, branch, nil
+}
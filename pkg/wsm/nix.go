@@ -0,0 +1,328 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Files written by GenerateNixFlake
+const (
+	nixFlakeFile = "flake.nix"
+	envrcFile    = ".envrc"
+)
+
+// NixWorkspaceFiles are the root files the Nix integration may create
+var NixWorkspaceFiles = []string{nixFlakeFile, "flake.lock", envrcFile}
+
+// defaultNixPackages are the nixpkgs attributes added to the dev shell for
+// each repository category
+var defaultNixPackages = map[string][]string{
+	"go":     {"go", "gopls"},
+	"node":   {"nodejs"},
+	"python": {"python3"},
+	"rust":   {"cargo", "rustc", "rust-analyzer"},
+}
+
+// NixConfig declares the Nix flake generated for new workspaces. It is read
+// from nix.yaml in the workspace-manager config directory:
+//
+//	enabled: true
+//	direnv: true
+//	nixpkgs: github:NixOS/nixpkgs/nixos-24.05
+//	packages:
+//	  go: [go_1_22, gopls, golangci-lint]
+//	  node: [nodejs_20, pnpm]
+//	extra-packages: [jq]
+//	template: ~/templates/flake.nix.tmpl
+//
+// Packages replace the default packages of a category; template replaces the
+// built-in flake and is rendered with NixFlakeVars.
+type NixConfig struct {
+	// Enabled generates a flake.nix in every new workspace
+	Enabled bool `yaml:"enabled,omitempty" json:"enabled"`
+	// Direnv also writes an .envrc loading the flake, and allows it when
+	// direnv is installed
+	Direnv bool `yaml:"direnv,omitempty" json:"direnv"`
+	// Nixpkgs is the flake reference of nixpkgs
+	Nixpkgs       string              `yaml:"nixpkgs,omitempty" json:"nixpkgs,omitempty"`
+	Packages      map[string][]string `yaml:"packages,omitempty" json:"packages,omitempty"`
+	ExtraPackages []string            `yaml:"extra-packages,omitempty" json:"extra_packages,omitempty"`
+	// Template is a Go template file replacing the built-in flake.nix
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+
+	path string
+}
+
+// NixFlakeVars are the variables available to flake templates, which can
+// also quote values with nixString
+type NixFlakeVars struct {
+	Name         string
+	Path         string
+	Branch       string
+	Repositories []string
+	// Categories are the categories of the repositories with Nix packages
+	Categories []string
+	Packages   []string
+	Nixpkgs    string
+	// Env holds the WSM_WORKSPACE_* variables of the workspace
+	Env map[string]string
+}
+
+// NixFlakeFile is a file written by GenerateNixFlake
+type NixFlakeFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// GetNixConfigPath returns the path of the Nix configuration file
+func GetNixConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "nix.yaml"), nil
+}
+
+// LoadNixConfig loads nix.yaml, returning a disabled configuration if none exists
+func LoadNixConfig() (*NixConfig, error) {
+	configPath, err := GetNixConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &NixConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read Nix configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse Nix configuration %s", configPath)
+	}
+	if config.Template != "" {
+		config.Template = expandHome(config.Template)
+		if _, err := os.Stat(config.Template); err != nil {
+			return nil, errors.Wrapf(err, "flake template of %s", configPath)
+		}
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *NixConfig) Path() string {
+	return c.path
+}
+
+// PackagesFor returns the nixpkgs attributes of the dev shell of a workspace
+// and the categories they come from
+func (c *NixConfig) PackagesFor(workspace *Workspace) ([]string, []string) {
+	var categories []string
+	for _, repo := range workspace.Repositories {
+		for _, category := range repo.Categories {
+			if _, ok := c.Packages[category]; ok || defaultNixPackages[category] != nil {
+				categories = appendUnique(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+
+	var packages []string
+	for _, category := range categories {
+		categoryPackages, ok := c.Packages[category]
+		if !ok {
+			categoryPackages = defaultNixPackages[category]
+		}
+		for _, pkg := range categoryPackages {
+			packages = appendUnique(packages, pkg)
+		}
+	}
+	for _, pkg := range c.ExtraPackages {
+		packages = appendUnique(packages, pkg)
+	}
+	return packages, categories
+}
+
+// GenerateNixFlake renders the flake.nix of a workspace, and its .envrc when
+// direnv is enabled
+func (c *NixConfig) GenerateNixFlake(workspace *Workspace) ([]NixFlakeFile, error) {
+	packages, categories := c.PackagesFor(workspace)
+	vars := NixFlakeVars{
+		Name:         workspace.Name,
+		Path:         workspace.Path,
+		Branch:       workspace.Branch,
+		Repositories: repositoryNames(workspace.Repositories),
+		Categories:   categories,
+		Packages:     packages,
+		Nixpkgs:      c.Nixpkgs,
+		Env:          workspaceEnvironment(workspace),
+	}
+	if vars.Nixpkgs == "" {
+		vars.Nixpkgs = "github:NixOS/nixpkgs/nixos-unstable"
+	}
+
+	source, name := defaultNixFlakeTemplate, "flake.nix"
+	if c.Template != "" {
+		data, err := os.ReadFile(c.Template)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read flake template %s", c.Template)
+		}
+		source, name = string(data), c.Template
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"nixString": nixString}).Parse(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse flake template %s", name)
+	}
+	var flake bytes.Buffer
+	if err := tmpl.Execute(&flake, vars); err != nil {
+		return nil, errors.Wrapf(err, "failed to render flake template %s", name)
+	}
+
+	files := []NixFlakeFile{{Path: filepath.Join(workspace.Path, nixFlakeFile), Content: flake.String()}}
+	if c.Direnv {
+		files = append(files, NixFlakeFile{Path: filepath.Join(workspace.Path, envrcFile), Content: "use flake\n"})
+	}
+	return files, nil
+}
+
+// WriteNixFlake generates and writes the Nix files of a workspace. Existing
+// files are kept unless force is set. With direnv enabled and installed, the
+// .envrc is allowed.
+func (c *NixConfig) WriteNixFlake(ctx context.Context, workspace *Workspace, force bool) ([]NixFlakeFile, error) {
+	files, err := c.GenerateNixFlake(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []NixFlakeFile
+	for _, file := range files {
+		if _, err := os.Stat(file.Path); err == nil && !force {
+			output.PrintWarning("%s already exists, kept", file.Path)
+			continue
+		}
+		if err := os.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+			return nil, errors.Wrapf(err, "failed to write %s", file.Path)
+		}
+		written = append(written, file)
+	}
+
+	for _, file := range written {
+		if filepath.Base(file.Path) != envrcFile {
+			continue
+		}
+		if _, err := exec.LookPath("direnv"); err != nil {
+			continue
+		}
+		cmd := exec.CommandContext(ctx, "direnv", "allow", workspace.Path)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			output.PrintWarning("direnv allow failed: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	return written, nil
+}
+
+// nixString quotes a value as a Nix string
+func nixString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// useNixFlake reports whether a new workspace gets a flake.nix: override when
+// set, nix.yaml otherwise
+func useNixFlake(override *bool) (bool, error) {
+	if override != nil {
+		return *override, nil
+	}
+	config, err := LoadNixConfig()
+	if err != nil {
+		return false, err
+	}
+	return config.Enabled, nil
+}
+
+// planNixFlake returns the plan steps of applyNixFlake, rendered by GenerateNixFlake
+func planNixFlake(workspace *Workspace) ([]PlanStep, error) {
+	if !workspace.NixFlake {
+		return nil, nil
+	}
+	config, err := LoadNixConfig()
+	if err != nil {
+		return nil, err
+	}
+	files, err := config.GenerateNixFlake(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []PlanStep
+	for _, file := range files {
+		steps = append(steps, PlanStep{
+			Action:      PlanActionWriteFile,
+			Description: "Create " + filepath.Base(file.Path),
+			Path:        file.Path,
+			Content:     file.Content,
+		})
+		if filepath.Base(file.Path) != envrcFile {
+			continue
+		}
+		if _, err := exec.LookPath("direnv"); err == nil {
+			steps = append(steps, PlanStep{
+				Action:      PlanActionCommand,
+				Description: "Allow the .envrc with direnv",
+				Dir:         workspace.Path,
+				Command:     []string{"direnv", "allow", workspace.Path},
+				Optional:    true,
+			})
+		}
+	}
+	return steps, nil
+}
+
+// applyNixFlake writes the Nix files of a new workspace
+func (wm *WorkspaceManager) applyNixFlake(ctx context.Context, workspace *Workspace) error {
+	config, err := LoadNixConfig()
+	if err != nil {
+		return err
+	}
+	_, err = config.WriteNixFlake(ctx, workspace, false)
+	return err
+}
+
+const defaultNixFlakeTemplate = `{
+  description = "wsm workspace {{.Name}}";
+
+  inputs = {
+    nixpkgs.url = "{{.Nixpkgs}}";
+    flake-utils.url = "github:numtide/flake-utils";
+  };
+
+  outputs = { self, nixpkgs, flake-utils }:
+    flake-utils.lib.eachDefaultSystem (system:
+      let
+        pkgs = nixpkgs.legacyPackages.${system};
+      in
+      {
+        devShells.default = pkgs.mkShell {
+          packages = with pkgs; [
+{{- range .Packages}}
+            {{.}}
+{{- end}}
+          ];
+{{range $key, $value := .Env}}
+          {{$key}} = {{nixString $value}};
+{{- end}}
+        };
+      });
+}
+`
@@ -0,0 +1,323 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace events notifications are sent for
+const (
+	EventSyncCompleted  = "sync.completed"
+	EventSyncFailed     = "sync.failed"
+	EventMergeSucceeded = "merge.succeeded"
+	EventMergeFailed    = "merge.failed"
+	EventPROpened       = "pr.opened"
+	// EventTest is sent by 'wsm notify test'
+	EventTest = "test"
+)
+
+// NotificationEvents lists the events webhooks can subscribe to
+var NotificationEvents = []string{EventSyncCompleted, EventSyncFailed, EventMergeSucceeded, EventMergeFailed, EventPROpened, EventTest}
+
+// Webhook payload formats
+const (
+	WebhookGeneric = "generic"
+	WebhookSlack   = "slack"
+	WebhookDiscord = "discord"
+)
+
+// notificationTimeout bounds each webhook request, notifications must not hold up wsm
+const notificationTimeout = 10 * time.Second
+
+// Chat messages are truncated to what Slack and Discord accept
+const (
+	slackMessageLimit   = 3000
+	discordMessageLimit = 2000
+)
+
+// WebhookConfig is a webhook notified of workspace events
+type WebhookConfig struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Type is the payload format: generic (default), slack or discord
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	URL  string `yaml:"url,omitempty" json:"-"`
+	// URLEnv names an environment variable holding the URL, keeping secrets
+	// out of the file
+	URLEnv string `yaml:"url-env,omitempty" json:"url_env,omitempty"`
+	// Events are event names or patterns such as merge.*; empty means all
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+	// Headers are added to the requests of generic webhooks
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+}
+
+// NotificationsConfig declares the webhooks notified of workspace events. It
+// is read from notifications.yaml in the workspace-manager config directory:
+//
+//	webhooks:
+//	  - name: team
+//	    type: slack
+//	    url-env: WSM_SLACK_WEBHOOK
+//	    events: [merge.*, pr.opened]
+//	  - type: generic
+//	    url: https://ci.acme.dev/hooks/wsm
+//	    headers:
+//	      Authorization: Bearer secret
+type NotificationsConfig struct {
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty" json:"webhooks"`
+
+	path string
+}
+
+// Notification is the payload of generic webhooks; chat webhooks get a
+// message built from it
+type Notification struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Workspace string    `json:"workspace,omitempty"`
+	Branch    string    `json:"branch,omitempty"`
+	Success   bool      `json:"success"`
+	Summary   string    `json:"summary"`
+	Error     string    `json:"error,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	// Result is the structured result of the operation, e.g. []SyncResult
+	Result any `json:"result,omitempty"`
+}
+
+// GetNotificationsConfigPath returns the path of the notifications configuration file
+func GetNotificationsConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "notifications.yaml"), nil
+}
+
+// LoadNotificationsConfig loads notifications.yaml, returning a configuration
+// without webhooks if none exists
+func LoadNotificationsConfig() (*NotificationsConfig, error) {
+	configPath, err := GetNotificationsConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &NotificationsConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read notifications configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse notifications configuration %s", configPath)
+	}
+	for i, webhook := range config.Webhooks {
+		switch webhook.Type {
+		case "":
+			config.Webhooks[i].Type = WebhookGeneric
+		case WebhookGeneric, WebhookSlack, WebhookDiscord:
+		default:
+			return nil, errors.Errorf("webhook %s of %s: unknown type '%s' (supported: generic, slack, discord)", webhook.label(), configPath, webhook.Type)
+		}
+		if webhook.URL == "" && webhook.URLEnv == "" {
+			return nil, errors.Errorf("webhook %s of %s needs url or url-env", webhook.label(), configPath)
+		}
+		for _, pattern := range webhook.Events {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, errors.Errorf("webhook %s of %s: invalid event pattern '%s'", webhook.label(), configPath, pattern)
+			}
+		}
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *NotificationsConfig) Path() string {
+	return c.path
+}
+
+func (w WebhookConfig) label() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	if w.URLEnv != "" {
+		return "$" + w.URLEnv
+	}
+	return w.Type
+}
+
+// Subscribes reports whether the webhook is notified of an event
+func (w WebhookConfig) Subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, pattern := range w.Events {
+		if matched, _ := path.Match(pattern, event); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationResult is the outcome of notifying one webhook
+type NotificationResult struct {
+	Webhook string `json:"webhook"`
+	Sent    bool   `json:"sent"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Notify sends a notification to the webhooks subscribed to its event. A
+// failing webhook is reported as a warning and never fails the operation.
+func Notify(ctx context.Context, notification Notification) []NotificationResult {
+	config, err := LoadNotificationsConfig()
+	if err != nil {
+		output.PrintWarning("Not sending notifications: %v", err)
+		return nil
+	}
+	results := config.Send(ctx, notification)
+	for _, result := range results {
+		if !result.Sent {
+			output.PrintWarning("Failed to notify webhook %s: %s", result.Webhook, result.Error)
+		}
+	}
+	return results
+}
+
+// Send posts a notification to the subscribed webhooks, filling in its time and actor
+func (c *NotificationsConfig) Send(ctx context.Context, notification Notification) []NotificationResult {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+	if notification.Actor == "" {
+		notification.Actor = os.Getenv(AuditActorEnv)
+	}
+
+	var results []NotificationResult
+	for _, webhook := range c.Webhooks {
+		if !webhook.Subscribes(notification.Event) {
+			continue
+		}
+		result := NotificationResult{Webhook: webhook.label(), Sent: true}
+		if err := webhook.send(ctx, notification); err != nil {
+			result.Sent = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (w WebhookConfig) send(ctx context.Context, notification Notification) error {
+	webhookURL := w.URL
+	if w.URLEnv != "" {
+		if webhookURL = os.Getenv(w.URLEnv); webhookURL == "" {
+			return errors.Errorf("environment variable %s holding the webhook url is not set", w.URLEnv)
+		}
+	}
+	payload, err := w.payload(notification)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "invalid webhook url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Type == WebhookGeneric {
+		for key, value := range w.Headers {
+			req.Header.Set(key, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The URL may hold a secret token, only the error without it is shown
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errors.Wrap(err, "request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// payload renders a notification in the format of the webhook
+func (w WebhookConfig) payload(notification Notification) ([]byte, error) {
+	switch w.Type {
+	case WebhookSlack:
+		return json.Marshal(map[string]string{"text": chatMessage(notification, slackMessageLimit)})
+	case WebhookDiscord:
+		return json.Marshal(map[string]string{"content": chatMessage(notification, discordMessageLimit)})
+	default:
+		return json.Marshal(notification)
+	}
+}
+
+// chatMessage renders a notification as a chat message with the result as a
+// JSON code block, truncated to limit characters
+func chatMessage(notification Notification, limit int) string {
+	icon := "✅"
+	if !notification.Success {
+		icon = "❌"
+	}
+	message := fmt.Sprintf("%s *%s*", icon, notification.Event)
+	if notification.Workspace != "" {
+		message += fmt.Sprintf(" in workspace `%s`", notification.Workspace)
+	}
+	if notification.Branch != "" {
+		message += fmt.Sprintf(" (`%s`)", notification.Branch)
+	}
+	message += "\n" + notification.Summary
+	if notification.Error != "" {
+		message += "\nError: " + notification.Error
+	}
+	if notification.Result == nil {
+		return truncateMessage(message, limit)
+	}
+
+	result, err := json.MarshalIndent(notification.Result, "", "  ")
+	if err != nil {
+		return truncateMessage(message, limit)
+	}
+	const fence = "\n```\n"
+	room := limit - len(message) - 2*len(fence)
+	if room < 20 {
+		return truncateMessage(message, limit)
+	}
+	return message + fence + truncateMessage(string(result), room) + fence
+}
+
+func truncateMessage(message string, limit int) string {
+	if len(message) <= limit {
+		return message
+	}
+	// Cut on a rune boundary
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "…"
+}
@@ -0,0 +1,91 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// RepositoryOrigin records where a repository's worktree started
+type RepositoryOrigin struct {
+	// BaseSHA is the commit checked out when the worktree was created
+	BaseSHA string `json:"base_sha"`
+	// BaseBranch is the branch the workspace branch was created from
+	BaseBranch string    `json:"base_branch,omitempty"`
+	Created    time.Time `json:"created"`
+}
+
+// OriginFor returns the recorded origin of a repository's worktree
+func (w *Workspace) OriginFor(repoName string) (RepositoryOrigin, bool) {
+	origin, ok := w.RepositoryOrigins[repoName]
+	return origin, ok && origin.BaseSHA != ""
+}
+
+// TargetBranchFor returns the branch a repository's work is merged or rebased
+// into: the branch its worktree was created from, then the workspace base
+// branch, then the default branch detected at creation. It is empty when none
+// is known.
+func (w *Workspace) TargetBranchFor(repoName string) string {
+	if origin, ok := w.OriginFor(repoName); ok && origin.BaseBranch != "" {
+		return origin.BaseBranch
+	}
+	if baseBranch := w.BaseBranchFor(repoName); baseBranch != "" {
+		return baseBranch
+	}
+	return w.RepositoryDefaultBranches[repoName]
+}
+
+// CreationCommit returns the commit a repository's worktree was created from.
+// Workspaces created before origins were recorded fall back to the merge base
+// of HEAD and the target branch, or the default branch of the base remote.
+func (w *Workspace) CreationCommit(ctx context.Context, repo Repository) (string, error) {
+	if origin, ok := w.OriginFor(repo.Name); ok {
+		return origin.BaseSHA, nil
+	}
+
+	repoPath := filepath.Join(w.Path, repo.Name)
+	target := w.TargetBranchFor(repo.Name)
+	if target == "" {
+		target = w.BaseRefFor(ctx, repo.Name, repoPath)
+	}
+	sha, err := gitOutput(ctx, repoPath, "merge-base", "HEAD", target)
+	if err != nil {
+		return "", errors.Wrapf(err, "no creation commit recorded for %s and no merge base with %s", repo.Name, target)
+	}
+	return sha, nil
+}
+
+// recordOrigin stores the commit and base branch of a newly created worktree.
+// Failures only lose the metadata, so they are logged instead of returned.
+func (wm *WorkspaceManager) recordOrigin(ctx context.Context, workspace *Workspace, repo Repository) {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	sha, err := gitOutput(ctx, worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Could not record the base commit of %s: %v", repo.Name, err),
+			"Could not record worktree origin",
+			"repo", repo.Name,
+			"error", err,
+		)
+		return
+	}
+
+	baseBranch := workspace.BaseBranchFor(repo.Name)
+	if baseBranch == "" && workspace.PinFor(repo.Name) == "" {
+		// Without a base branch, new branches start from the repository's checked out branch
+		baseBranch, _ = getGitCurrentBranch(ctx, repo.Path)
+	}
+
+	if workspace.RepositoryOrigins == nil {
+		workspace.RepositoryOrigins = make(map[string]RepositoryOrigin)
+	}
+	workspace.RepositoryOrigins[repo.Name] = RepositoryOrigin{
+		BaseSHA:    sha,
+		BaseBranch: baseBranch,
+		Created:    time.Now(),
+	}
+}
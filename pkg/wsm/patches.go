@@ -0,0 +1,305 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// patchSetFile describes the patches of an exported directory
+const patchSetFile = "wsm-patches.json"
+
+// PatchSet is written next to the .patch files of `wsm diff --export`
+type PatchSet struct {
+	Workspace string    `json:"workspace"`
+	Branch    string    `json:"branch"`
+	Exported  time.Time `json:"exported"`
+	Patches   []Patch   `json:"patches"`
+}
+
+// Patch is the exported diff of one repository
+type Patch struct {
+	Repository string `json:"repository"`
+	File       string `json:"file"`
+	// Base is the commit the patch applies to
+	Base    string `json:"base"`
+	Summary string `json:"summary"`
+}
+
+// PatchApply is the outcome of applying a patch to a repository
+type PatchApply struct {
+	Repository string `json:"repository"`
+	File       string `json:"file"`
+	Applied    bool   `json:"applied"`
+	Problem    string `json:"problem,omitempty"`
+}
+
+// PatchApplyOptions controls ApplyPatches
+type PatchApplyOptions struct {
+	// ThreeWay falls back to a three-way merge when a patch does not apply
+	// cleanly, which needs the base blobs in the target repository
+	ThreeWay bool
+	// Check only checks that every patch applies
+	Check bool
+}
+
+// ExportPatches writes one binary-safe .patch file per repository with
+// changes to dir, with a wsm-patches.json describing them. The patch holds
+// everything not committed, staged, unstaged and untracked files alike;
+// options.Staged limits it to the index, options.SinceCreation and
+// options.Base extend it to the commits made since the worktree was created or
+// since the default branch. Files matched by .wsmignore are left out.
+func (gops *GitOperations) ExportPatches(ctx context.Context, dir string, options DiffOptions) (*PatchSet, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s", dir)
+	}
+
+	set := &PatchSet{Workspace: gops.workspace.Name, Branch: gops.workspace.Branch, Exported: time.Now(), Patches: []Patch{}}
+	for _, repo := range gops.workspace.Repositories {
+		if options.Repository != "" && repo.Name != options.Repository {
+			continue
+		}
+		patch, err := gops.exportRepositoryPatch(ctx, repo, dir, options)
+		if err != nil {
+			return nil, err
+		}
+		if patch != nil {
+			set.Patches = append(set.Patches, *patch)
+		}
+	}
+
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal patch set")
+	}
+	if err := os.WriteFile(filepath.Join(dir, patchSetFile), data, 0644); err != nil {
+		return nil, errors.Wrap(err, "failed to write patch set")
+	}
+	return set, nil
+}
+
+func (gops *GitOperations) exportRepositoryPatch(ctx context.Context, repo Repository, dir string, options DiffOptions) (*Patch, error) {
+	repoPath := filepath.Join(gops.workspace.Path, repo.Name)
+
+	base, err := gops.diffBase(ctx, repo, repoPath, options)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		base = "HEAD"
+	}
+	baseCommit, err := gitOutput(ctx, repoPath, "rev-parse", base)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve %s in %s", base, repo.Name)
+	}
+
+	if !options.Staged {
+		// Untracked files only show up in the diff once git knows about them
+		untracked, err := gitOutput(ctx, repoPath, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list untracked files in %s", repo.Name)
+		}
+		if files := strings.Split(strings.TrimRight(untracked, "\x00"), "\x00"); untracked != "" {
+			if err := intentToAdd(ctx, repoPath, files); err != nil {
+				return nil, errors.Wrapf(err, "failed to include untracked files of %s", repo.Name)
+			}
+			defer func() { _ = resetPaths(ctx, repoPath, files) }()
+		}
+	}
+
+	args, err := gops.diffArgs(ctx, repo.Name, repoPath, options.Staged, baseCommit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get diff for %s", repo.Name)
+	}
+	if args == nil {
+		return nil, nil
+	}
+	diff, err := gitDiff(ctx, repoPath, args, "--binary", "--full-index", "--no-color")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get diff for %s", repo.Name)
+	}
+	if diff == "" {
+		return nil, nil
+	}
+	summary, err := gitDiff(ctx, repoPath, args, "--shortstat")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get diff summary for %s", repo.Name)
+	}
+
+	patch := &Patch{Repository: repo.Name, File: repo.Name + ".patch", Base: baseCommit, Summary: strings.TrimSpace(summary)}
+	if err := os.WriteFile(filepath.Join(dir, patch.File), []byte(diff), 0644); err != nil {
+		return nil, errors.Wrapf(err, "failed to write %s", patch.File)
+	}
+	return patch, nil
+}
+
+// intentToAdd records untracked files in the index without their content
+func intentToAdd(ctx context.Context, repoPath string, files []string) error {
+	_, err := gitOutput(ctx, repoPath, append([]string{"add", "-N", "--"}, literalPathspecs(files)...)...)
+	return err
+}
+
+// resetPaths removes paths from the index again
+func resetPaths(ctx context.Context, repoPath string, files []string) error {
+	_, err := gitOutput(ctx, repoPath, append([]string{"reset", "-q", "--"}, literalPathspecs(files)...)...)
+	return err
+}
+
+func literalPathspecs(files []string) []string {
+	specs := make([]string, len(files))
+	for i, file := range files {
+		specs[i] = ":(literal)" + file
+	}
+	return specs
+}
+
+// LoadPatchSet reads the patches exported to dir. Without a wsm-patches.json,
+// every <repository>.patch file of dir is a patch.
+func LoadPatchSet(dir string) (*PatchSet, error) {
+	data, err := os.ReadFile(filepath.Join(dir, patchSetFile))
+	if err == nil {
+		var set PatchSet
+		if err := json.Unmarshal(data, &set); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", patchSetFile)
+		}
+		return &set, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %s", patchSetFile)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.patch"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list patches")
+	}
+	if len(matches) == 0 {
+		return nil, errors.Errorf("no patches in %s", dir)
+	}
+	sort.Strings(matches)
+	set := &PatchSet{}
+	for _, match := range matches {
+		file := filepath.Base(match)
+		set.Patches = append(set.Patches, Patch{Repository: strings.TrimSuffix(file, ".patch"), File: file})
+	}
+	return set, nil
+}
+
+// ApplyPatches applies the patches of dir to the repositories of the
+// workspace with the same names. Every patch is checked first and nothing is
+// applied unless all of them apply; patches of repositories missing from the
+// workspace are reported and block the apply as well.
+func (gops *GitOperations) ApplyPatches(ctx context.Context, dir string, options PatchApplyOptions) ([]PatchApply, error) {
+	set, err := LoadPatchSet(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	repoPaths := make(map[string]string)
+	for _, repo := range gops.workspace.Repositories {
+		repoPaths[repo.Name] = filepath.Join(gops.workspace.Path, repo.Name)
+	}
+
+	applies := make([]PatchApply, 0, len(set.Patches))
+	blocked := false
+	for _, patch := range set.Patches {
+		apply := PatchApply{Repository: patch.Repository, File: patch.File}
+		repoPath, ok := repoPaths[patch.Repository]
+		if !ok {
+			apply.Problem = fmt.Sprintf("repository not in workspace '%s'", gops.workspace.Name)
+		} else if err := gitApply(ctx, repoPath, filepath.Join(dir, patch.File), options.ThreeWay, true); err != nil {
+			apply.Problem = err.Error()
+		}
+		if apply.Problem != "" {
+			blocked = true
+		}
+		applies = append(applies, apply)
+	}
+	if blocked {
+		return applies, errors.New("some patches do not apply, nothing was applied")
+	}
+	if options.Check {
+		return applies, nil
+	}
+
+	for i := range applies {
+		repoPath := repoPaths[applies[i].Repository]
+		if err := gitApply(ctx, repoPath, filepath.Join(dir, applies[i].File), options.ThreeWay, false); err != nil {
+			applies[i].Problem = err.Error()
+			return applies, errors.Wrapf(err, "failed to apply the patch of %s", applies[i].Repository)
+		}
+		applies[i].Applied = true
+	}
+	return applies, nil
+}
+
+// LocalChanges are the uncommitted changes of a workspace exported to a
+// temporary directory, to be carried over to another workspace
+type LocalChanges struct {
+	Dir string
+	Set *PatchSet
+}
+
+// ExportLocalChanges exports the uncommitted changes of every repository of a
+// workspace, untracked files included, to a new temporary directory
+func ExportLocalChanges(ctx context.Context, workspace *Workspace) (*LocalChanges, error) {
+	dir, err := os.MkdirTemp("", "wsm-changes-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create a directory for the changes")
+	}
+	set, err := NewGitOperations(workspace).ExportPatches(ctx, dir, DiffOptions{})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, errors.Wrapf(err, "failed to export the changes of '%s'", workspace.Name)
+	}
+	return &LocalChanges{Dir: dir, Set: set}, nil
+}
+
+// ApplyTo applies the changes to the repositories of a workspace with the same
+// names, as unstaged changes. Nothing is applied unless every patch applies.
+func (c *LocalChanges) ApplyTo(ctx context.Context, workspace *Workspace) ([]PatchApply, error) {
+	if len(c.Set.Patches) == 0 {
+		return nil, nil
+	}
+	return NewGitOperations(workspace).ApplyPatches(ctx, c.Dir, PatchApplyOptions{})
+}
+
+// Remove deletes the exported patches
+func (c *LocalChanges) Remove() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// gitApply applies a patch file to the working tree of a repository, or only
+// checks that it applies
+func gitApply(ctx context.Context, repoPath, patchFile string, threeWay, check bool) error {
+	patchFile, err := filepath.Abs(patchFile)
+	if err != nil {
+		return err
+	}
+	args := []string{"apply"}
+	if threeWay {
+		args = append(args, "--3way")
+	}
+	if check {
+		args = append(args, "--check")
+	}
+	args = append(args, patchFile)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		message := strings.TrimSpace(string(out))
+		if message == "" {
+			message = err.Error()
+		}
+		return errors.New(message)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// ParsePinSpecs parses "repo=ref" specifications, where ref is a tag or commit SHA
+func ParsePinSpecs(specs []string) (map[string]string, error) {
+	pins := make(map[string]string)
+	for _, spec := range specs {
+		repoName, ref, ok := strings.Cut(spec, "=")
+		repoName, ref = strings.TrimSpace(repoName), strings.TrimSpace(ref)
+		if !ok || repoName == "" || ref == "" {
+			return nil, errors.Errorf("invalid pin specification '%s' (expected repo=tag or repo=sha)", spec)
+		}
+		if existing, ok := pins[repoName]; ok && existing != ref {
+			return nil, errors.Errorf("repository '%s' is pinned twice (%s and %s)", repoName, existing, ref)
+		}
+		pins[repoName] = ref
+	}
+	return pins, nil
+}
+
+// validatePinnedRepositories rejects pins for repositories that are not part of the workspace
+func validatePinnedRepositories(pins map[string]string, repos []Repository) error {
+	for repoName := range pins {
+		found := false
+		for _, repo := range repos {
+			if repo.Name == repoName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("pin configured for '%s', which is not a workspace repository", repoName)
+		}
+	}
+	return nil
+}
+
+// resolvePin returns the commit a pinned ref points to, fetching it from the
+// remote when the repository does not have it yet
+func resolvePin(ctx context.Context, repoPath, remote, ref string) (string, error) {
+	if commit, err := gitOutput(ctx, repoPath, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+		return commit, nil
+	}
+
+	if _, err := gitOutput(ctx, repoPath, "fetch", "--quiet", remote, ref); err != nil {
+		return "", errors.Wrapf(err, "'%s' is not a tag or commit of %s", ref, repoPath)
+	}
+	commit, err := gitOutput(ctx, repoPath, "rev-parse", "--verify", "--quiet", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve '%s' in %s", ref, repoPath)
+	}
+	return commit, nil
+}
+
+// addPinnedWorktree checks out a pinned repository as a detached worktree at its pinned ref
+func (wm *WorkspaceManager) addPinnedWorktree(ctx context.Context, workspace *Workspace, repo Repository) error {
+	ref := workspace.PinFor(repo.Name)
+	commit, err := resolvePin(ctx, repo.Path, workspace.RemoteFor(repo), ref)
+	if err != nil {
+		return err
+	}
+
+	output.PrintInfo("Pinning %s to %s (%s)", repo.Name, ref, shortCommit(commit))
+	return wm.addWorktree(ctx, workspace, repo, "--detach", filepath.Join(workspace.Path, repo.Name), commit)
+}
+
+// planPinnedWorktree returns the plan step checking out a pinned repository
+func planPinnedWorktree(workspace *Workspace, repo Repository) PlanStep {
+	ref := workspace.PinFor(repo.Name)
+	return PlanStep{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Create worktree for %s pinned to %s", repo.Name, ref),
+		Dir:         repo.Path,
+		Command:     worktreeAddCommand(workspace, repo.Name, "--detach", filepath.Join(workspace.Path, repo.Name), ref),
+		Note:        "pinned repositories are checked out detached and skipped by sync, commit, push and rebase",
+	}
+}
+
+// pinDrifted reports whether a pinned worktree's HEAD moved away from its pinned ref
+func pinDrifted(ctx context.Context, worktreePath, ref string) bool {
+	head, err := gitOutput(ctx, worktreePath, "rev-parse", "HEAD")
+	if err != nil {
+		return false
+	}
+	commit, err := gitOutput(ctx, worktreePath, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return false
+	}
+	return head != commit
+}
+
+// pinnedSyncResult reports a pinned repository skipped by sync and branch operations
+func pinnedSyncResult(repoName, ref string) SyncResult {
+	return SyncResult{
+		Repository: repoName,
+		Success:    true,
+		Skipped:    true,
+		Error:      fmt.Sprintf("pinned to %s", ref),
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
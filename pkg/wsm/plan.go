@@ -0,0 +1,360 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Plan step actions
+const (
+	PlanActionMkdir      = "mkdir"
+	PlanActionCommand    = "command"
+	PlanActionWriteFile  = "write-file"
+	PlanActionCopyFile   = "copy-file"
+	PlanActionRunScripts = "run-setup-scripts"
+)
+
+// PlanStep is a single git or filesystem operation of a plan
+type PlanStep struct {
+	Action      string   `json:"action"`
+	Description string   `json:"description"`
+	Dir         string   `json:"dir,omitempty"`
+	Command     []string `json:"command,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	// Paths lists the workspace's setup.d directories for run-setup-scripts
+	// steps, Scripts the repository scripts the trust gate allows and
+	// Untrusted those it would ask about, which the plan leaves out
+	Paths     []string `json:"paths,omitempty"`
+	Scripts   []string `json:"scripts,omitempty"`
+	Untrusted []string `json:"untrusted,omitempty"`
+	// Optional steps may fail without aborting the plan (setup scripts, direnv)
+	Optional bool   `json:"optional,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// Plan is the exact sequence of operations a workspace command would perform
+type Plan struct {
+	Operation   string            `json:"operation"`
+	Workspace   string            `json:"workspace"`
+	Environment map[string]string `json:"environment,omitempty"`
+	Steps       []PlanStep        `json:"steps"`
+}
+
+// PlanCreateWorkspace builds the plan for creating a workspace without
+// touching disk; noScripts leaves out the setup scripts as --no-scripts does
+func (wm *WorkspaceManager) PlanCreateWorkspace(ctx context.Context, workspace *Workspace, noScripts bool) (*Plan, error) {
+	plan := &Plan{
+		Operation:   "create",
+		Workspace:   workspace.Name,
+		Environment: workspaceEnvironment(workspace),
+	}
+
+	plan.Steps = append(plan.Steps, PlanStep{
+		Action:      PlanActionMkdir,
+		Description: "Create workspace directory",
+		Path:        workspace.Path,
+	})
+
+	for _, repo := range workspace.Repositories {
+		plan.Steps = append(plan.Steps, wm.planWorktree(ctx, workspace, repo))
+		plan.Steps = append(plan.Steps, planSparseCheckout(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planSubmoduleUpdate(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planLFS(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planGitConfig(ctx, workspace, repo)...)
+		plan.Steps = append(plan.Steps, planHooks(ctx, workspace, repo)...)
+	}
+
+	if workspace.GoWorkspace {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:      PlanActionWriteFile,
+			Description: "Create go.work",
+			Path:        filepath.Join(workspace.Path, "go.work"),
+			Content:     wm.planGoWorkContent(ctx, workspace),
+		})
+	}
+
+	replaceSteps, err := planGoReplaces(workspace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update go.mod replace directives")
+	}
+	plan.Steps = append(plan.Steps, replaceSteps...)
+
+	for _, manifest := range workspaceManifests {
+		if !containsValue(workspace.Ecosystems, manifest.Ecosystem) {
+			continue
+		}
+		plan.Steps = append(plan.Steps, planWorkspaceManifest(workspace, manifest))
+	}
+
+	if workspace.AgentMD != "" {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:      PlanActionCopyFile,
+			Description: "Copy AGENT.md",
+			Source:      expandHome(workspace.AgentMD),
+			Path:        filepath.Join(workspace.Path, "AGENT.md"),
+		})
+	}
+	plan.Steps = append(plan.Steps, planScaffold(workspace)...)
+
+	nixSteps, err := planNixFlake(workspace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate flake.nix")
+	}
+	plan.Steps = append(plan.Steps, nixSteps...)
+
+	metadata, err := buildWorkspaceMetadata(workspace)
+	if err != nil {
+		return nil, err
+	}
+	plan.Steps = append(plan.Steps, PlanStep{
+		Action:      PlanActionWriteFile,
+		Description: "Create workspace metadata file",
+		Path:        filepath.Join(workspace.Path, ".wsm", "wsm.json"),
+		Content:     string(metadata) + "\n",
+	})
+
+	if !noScripts {
+		plan.Steps = append(plan.Steps, wm.planSetupScripts(workspace))
+	}
+
+	config, err := json.MarshalIndent(workspace, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal workspace configuration")
+	}
+	plan.Steps = append(plan.Steps, PlanStep{
+		Action:      PlanActionWriteFile,
+		Description: "Save workspace configuration",
+		Path:        filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces", workspace.Name+".json"),
+		Content:     string(config) + "\n",
+	})
+
+	return plan, nil
+}
+
+// planSetupScripts plans the setup scripts of a new workspace. Those of the
+// workspace are the user's own; the setup.d scripts of repositories are
+// listed one by one, as far as the trust gate allows them without asking.
+func (wm *WorkspaceManager) planSetupScripts(workspace *Workspace) PlanStep {
+	step := PlanStep{
+		Action:      PlanActionRunScripts,
+		Description: "Run .wsm/setup.sh and executable setup.d scripts (sorted by name across all locations)",
+		Path:        filepath.Join(workspace.Path, ".wsm", "setup.sh"),
+		Dir:         workspace.Path,
+		Paths:       []string{filepath.Join(workspace.Path, ".wsm", "setup.d")},
+		Optional:    true,
+	}
+	for _, repo := range workspace.Repositories {
+		for _, script := range wm.planAddedRepositoryScripts(repo, filepath.Join(workspace.Path, repo.Name)) {
+			if script.Approval == ScriptApprovalAsk {
+				step.Untrusted = append(step.Untrusted, script.Path)
+			} else {
+				step.Scripts = append(step.Scripts, script.Path)
+			}
+		}
+	}
+	if len(step.Untrusted) > 0 {
+		step.Note = "untrusted repository scripts are left out; wsm create would ask about them"
+	}
+	return step
+}
+
+// planWorktree mirrors createWorktree's branch decisions without prompting.
+// An existing local branch is reused as-is, the non-destructive choice of the interactive prompt.
+func (wm *WorkspaceManager) planWorktree(ctx context.Context, workspace *Workspace, repo Repository) PlanStep {
+	targetPath := filepath.Join(workspace.Path, repo.Name)
+	baseBranch := workspace.BaseBranchFor(repo.Name)
+	step := PlanStep{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Create worktree for %s", repo.Name),
+		Dir:         repo.Path,
+	}
+
+	if workspace.PinFor(repo.Name) != "" {
+		return planPinnedWorktree(workspace, repo)
+	}
+
+	if workspace.Branch == "" {
+		step.Command = worktreeAddCommand(workspace, repo.Name, targetPath)
+		return step
+	}
+
+	branchExists, _ := wm.CheckBranchExists(ctx, repo.Path, workspace.Branch)
+	remote := workspace.RemoteFor(repo)
+	remoteBranchExists, _ := wm.CheckRemoteBranchExists(ctx, repo.Path, remote, workspace.Branch)
+
+	switch {
+	case branchExists:
+		step.Command = worktreeAddCommand(workspace, repo.Name, targetPath, workspace.Branch)
+		step.Note = fmt.Sprintf("branch '%s' already exists; wsm create would ask whether to reuse or overwrite it", workspace.Branch)
+	case remoteBranchExists:
+		step.Command = worktreeAddCommand(workspace, repo.Name, "-b", workspace.Branch, targetPath, remote+"/"+workspace.Branch)
+	case baseBranch != "":
+		step.Command = worktreeAddCommand(workspace, repo.Name, "-b", workspace.Branch, targetPath, baseBranch)
+	default:
+		step.Command = worktreeAddCommand(workspace, repo.Name, "-b", workspace.Branch, targetPath)
+	}
+
+	return step
+}
+
+// planGoWorkContent renders go.work from the source repositories' go.mod files
+func (wm *WorkspaceManager) planGoWorkContent(ctx context.Context, workspace *Workspace) string {
+	goVersion, err := wm.getGoVersion(ctx)
+	if err != nil {
+		goVersion = "1.23"
+	}
+
+	content := fmt.Sprintf("go %s\n\nuse (\n", goVersion)
+	for _, repo := range workspace.Repositories {
+		if _, err := os.Stat(filepath.Join(repo.Path, "go.mod")); err == nil {
+			content += fmt.Sprintf("\t./%s\n", repo.Name)
+		}
+	}
+	return content + ")\n"
+}
+
+// planWorkspaceManifest renders a workspace manifest from the source repositories
+func planWorkspaceManifest(workspace *Workspace, manifest workspaceManifest) PlanStep {
+	var members []string
+	for _, repo := range workspace.Repositories {
+		if _, err := os.Stat(filepath.Join(repo.Path, manifest.MemberFile)); err == nil {
+			members = append(members, repo.Name)
+		}
+	}
+
+	path := manifest.File(workspace)
+	return PlanStep{
+		Action:      PlanActionWriteFile,
+		Description: "Create " + filepath.Base(path),
+		Path:        path,
+		Content:     manifest.Render(path, members),
+	}
+}
+
+// workspaceEnvironment returns the WSM_* variables passed to setup scripts
+func workspaceEnvironment(workspace *Workspace) map[string]string {
+	repoNames := make([]string, len(workspace.Repositories))
+	for i, repo := range workspace.Repositories {
+		repoNames[i] = repo.Name
+	}
+
+	env := map[string]string{
+		"WSM_WORKSPACE_NAME":   workspace.Name,
+		"WSM_WORKSPACE_PATH":   workspace.Path,
+		"WSM_WORKSPACE_BRANCH": workspace.Branch,
+		"WSM_WORKSPACE_REPOS":  strings.Join(repoNames, ","),
+	}
+	if workspace.BaseBranch != "" {
+		env["WSM_WORKSPACE_BASE_BRANCH"] = workspace.BaseBranch
+	}
+	return env
+}
+
+// Script renders the plan as an executable bash script
+func (p *Plan) Script() string {
+	var b strings.Builder
+
+	b.WriteString("#!/usr/bin/env bash\n")
+	fmt.Fprintf(&b, "# wsm %s plan for workspace '%s'\n", p.Operation, p.Workspace)
+	b.WriteString("# Generated by 'wsm create --dry-run --emit-script'. Review before running.\n")
+	b.WriteString("set -euo pipefail\n\n")
+
+	if len(p.Environment) > 0 {
+		keys := make([]string, 0, len(p.Environment))
+		for key := range p.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "export %s=%s\n", key, shellQuote(p.Environment[key]))
+		}
+		b.WriteString("\n")
+	}
+
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "# %d. %s\n", i+1, step.Description)
+		if step.Note != "" {
+			fmt.Fprintf(&b, "# NOTE: %s\n", step.Note)
+		}
+
+		switch step.Action {
+		case PlanActionMkdir:
+			fmt.Fprintf(&b, "mkdir -p %s\n", shellQuote(step.Path))
+		case PlanActionCommand:
+			if step.Optional {
+				fmt.Fprintf(&b, "(cd %s && %s) || echo %s >&2\n", shellQuote(step.Dir), ShellJoin(step.Command), shellQuote(step.Command[0]+" failed"))
+			} else {
+				fmt.Fprintf(&b, "(cd %s && %s)\n", shellQuote(step.Dir), ShellJoin(step.Command))
+			}
+		case PlanActionCopyFile:
+			fmt.Fprintf(&b, "cp %s %s\n", shellQuote(step.Source), shellQuote(step.Path))
+		case PlanActionWriteFile:
+			fmt.Fprintf(&b, "mkdir -p %s\n", shellQuote(filepath.Dir(step.Path)))
+			fmt.Fprintf(&b, "cat > %s <<'WSM_EOF'\n%sWSM_EOF\n", shellQuote(step.Path), step.Content)
+		case PlanActionRunScripts:
+			for _, script := range step.Untrusted {
+				fmt.Fprintf(&b, "# untrusted, not run: %q\n", script)
+			}
+			fmt.Fprintf(&b, "if [ -f %s ]; then (cd %s && bash %s) || echo \"setup.sh failed\" >&2; fi\n",
+				shellQuote(step.Path), shellQuote(step.Dir), shellQuote(step.Path))
+			quotedDirs := make([]string, len(step.Paths))
+			for j, dir := range step.Paths {
+				quotedDirs[j] = shellQuote(dir)
+			}
+			// Missing setup.d directories are expected; don't let find's exit status trip pipefail
+			fmt.Fprintf(&b, "{ find %s -maxdepth 1 -type f -perm -u+x 2>/dev/null || true\n", strings.Join(quotedDirs, " "))
+			if len(step.Scripts) > 0 {
+				fmt.Fprintf(&b, "  printf '%%s\\n' %s\n", ShellJoin(step.Scripts))
+			}
+			b.WriteString("} \\\n")
+			b.WriteString("  | awk -F/ '{print $NF \"\\t\" $0}' | sort | cut -f2- \\\n")
+			b.WriteString("  | while IFS= read -r script; do\n")
+			b.WriteString("      (cd \"${script%/.wsm/setup.d/*}\" && bash \"$script\") || echo \"setup script failed: $script\" >&2\n")
+			b.WriteString("    done\n")
+		default:
+			fmt.Fprintf(&b, "# unsupported action: %s\n", step.Action)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// WriteScript writes the plan as an executable script
+func (p *Plan) WriteScript(path string) error {
+	if err := os.WriteFile(path, []byte(p.Script()), 0755); err != nil {
+		return errors.Wrapf(err, "failed to write plan script %s", path)
+	}
+	return nil
+}
+
+// shellQuote quotes a string for POSIX shells
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellJoin quotes and joins a command line for POSIX shells
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
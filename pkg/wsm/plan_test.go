@@ -0,0 +1,63 @@
+package wsm
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "''"},
+		{"main", "main"},
+		{"/tmp/ws/app-1.2_x", "/tmp/ws/app-1.2_x"},
+		{"user.email=me@example.com", "user.email=me@example.com"},
+		{"a,b+c:d", "a,b+c:d"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a;rm -rf ~", "'a;rm -rf ~'"},
+		{"line\nbreak", "'line\nbreak'"},
+		{"`cmd`", "'`cmd`'"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	for _, in := range []string{"", "plain", "two words", "it's", `"quoted" \ back`, "$HOME $(id) `id`", "line\nbreak", "*?[glob]"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(in)).Output()
+		if err != nil {
+			t.Fatalf("sh failed for %q: %v", in, err)
+		}
+		if string(out) != in {
+			t.Errorf("sh read %q back as %q", in, out)
+		}
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"git", "status"}, "git status"},
+		{[]string{"git", "commit", "-m", "fix: it's done"}, `git commit -m 'fix: it'\''s done'`},
+		{[]string{"git", "worktree", "add", "-b", "task/x", "/tmp/my ws/app"}, "git worktree add -b task/x '/tmp/my ws/app'"},
+		{[]string{"echo", ""}, "echo ''"},
+	}
+	for _, tt := range tests {
+		if got := ShellJoin(tt.args); got != tt.want {
+			t.Errorf("ShellJoin(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
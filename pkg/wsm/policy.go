@@ -0,0 +1,220 @@
+package wsm
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy actions checked by Policy.Check
+const (
+	PolicyActionPush      = "push"
+	PolicyActionForcePush = "force-push"
+	PolicyActionMerge     = "merge"
+)
+
+// Policy declares guard rails for operations on protected branches.
+// It is read from policy.yaml in the workspace-manager config directory:
+//
+//	protected-branches: [main, "release/*"]
+//	allow-force-push: false
+//	require-clean-merge: true
+//	max-file-size: 50MB
+//	lfs-patterns: ["*.psd", "*.mp4"]
+//	block-large-files: true
+//	repositories:
+//	  infra:
+//	    protected-branches: [production]
+type Policy struct {
+	// ProtectedBranches are branch names or glob patterns that need --confirm for direct pushes and merges
+	ProtectedBranches []string `yaml:"protected-branches,omitempty" json:"protected_branches,omitempty"`
+	// AllowForcePush permits force-pushing protected branches
+	AllowForcePush bool `yaml:"allow-force-push,omitempty" json:"allow_force_push"`
+	// RequireCleanMerge refuses merges into protected branches while the repository has uncommitted changes
+	RequireCleanMerge bool `yaml:"require-clean-merge,omitempty" json:"require_clean_merge"`
+	// Repositories adds protected branches for individual repositories
+	Repositories map[string]RepositoryPolicy `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+
+	// MaxFileSize is the largest file pushed commits may add, e.g. "50MB"
+	// (default 100MB, the GitHub limit)
+	MaxFileSize string `yaml:"max-file-size,omitempty" json:"max_file_size,omitempty"`
+	// LFSPatterns are globs of files that belong in Git LFS
+	LFSPatterns []string `yaml:"lfs-patterns,omitempty" json:"lfs_patterns,omitempty"`
+	// BlockLargeFiles refuses pushes adding large files instead of warning
+	BlockLargeFiles bool `yaml:"block-large-files,omitempty" json:"block_large_files"`
+
+	path        string
+	maxFileSize int64
+}
+
+// RepositoryPolicy holds repository specific policy additions
+type RepositoryPolicy struct {
+	ProtectedBranches []string `yaml:"protected-branches,omitempty" json:"protected_branches,omitempty"`
+}
+
+// PolicyCheck describes an operation to validate against the policy
+type PolicyCheck struct {
+	Action     string
+	Repository string
+	Branch     string
+	// Confirmed is set when the user explicitly acknowledged the operation (--confirm)
+	Confirmed bool
+	// Clean reports whether the repository has no uncommitted changes (merges only)
+	Clean bool
+}
+
+// PolicyViolation is returned when an operation is not allowed by the policy
+type PolicyViolation struct {
+	Repository string
+	Branch     string
+	Action     string
+	Reason     string
+	Hint       string
+}
+
+func (v *PolicyViolation) Error() string {
+	msg := "policy: " + v.Reason
+	if v.Hint != "" {
+		msg += " (" + v.Hint + ")"
+	}
+	return msg
+}
+
+// IsPolicyViolation reports whether an error was caused by the policy
+func IsPolicyViolation(err error) bool {
+	var violation *PolicyViolation
+	return errors.As(err, &violation)
+}
+
+// GetPolicyPath returns the path of the policy file
+func GetPolicyPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "policy.yaml"), nil
+}
+
+// LoadPolicy loads the policy, returning an empty policy (everything allowed) if none exists
+func LoadPolicy() (*Policy, error) {
+	policyPath, err := GetPolicyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{path: policyPath, maxFileSize: DefaultMaxFileSize}
+
+	data, err := os.ReadFile(policyPath)
+	if os.IsNotExist(err) {
+		return policy, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read policy")
+	}
+
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse policy %s", policyPath)
+	}
+
+	for _, pattern := range policy.allPatterns() {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("invalid protected branch pattern '%s' in %s", pattern, policyPath)
+		}
+	}
+	for _, pattern := range policy.LFSPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("invalid LFS pattern '%s' in %s", pattern, policyPath)
+		}
+	}
+	if policy.MaxFileSize != "" {
+		if policy.maxFileSize, err = ParseSize(policy.MaxFileSize); err != nil {
+			return nil, errors.Wrapf(err, "invalid max-file-size in %s", policyPath)
+		}
+	}
+
+	return policy, nil
+}
+
+// Path returns the file the policy was loaded from
+func (p *Policy) Path() string {
+	return p.path
+}
+
+// ProtectedBranchesFor returns the protected branch patterns that apply to a repository
+func (p *Policy) ProtectedBranchesFor(repoName string) []string {
+	patterns := append([]string{}, p.ProtectedBranches...)
+	if repoPolicy, ok := p.Repositories[repoName]; ok {
+		patterns = append(patterns, repoPolicy.ProtectedBranches...)
+	}
+	return patterns
+}
+
+// IsProtected reports whether a branch of a repository is protected
+func (p *Policy) IsProtected(repoName, branch string) bool {
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	for _, pattern := range p.ProtectedBranchesFor(repoName) {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates an operation against the policy and returns a *PolicyViolation if it is not allowed
+func (p *Policy) Check(check PolicyCheck) error {
+	if !p.IsProtected(check.Repository, check.Branch) {
+		return nil
+	}
+
+	violation := &PolicyViolation{
+		Repository: check.Repository,
+		Branch:     check.Branch,
+		Action:     check.Action,
+	}
+
+	switch check.Action {
+	case PolicyActionForcePush:
+		if !p.AllowForcePush {
+			violation.Reason = fmt.Sprintf("force-pushing protected branch '%s' is not allowed", check.Branch)
+			violation.Hint = fmt.Sprintf("set allow-force-push in %s to permit it", p.path)
+			return violation
+		}
+		if !check.Confirmed {
+			violation.Reason = fmt.Sprintf("force-pushing protected branch '%s' requires confirmation", check.Branch)
+			violation.Hint = "re-run with --confirm"
+			return violation
+		}
+	case PolicyActionPush:
+		if !check.Confirmed {
+			violation.Reason = fmt.Sprintf("pushing directly to protected branch '%s' requires confirmation", check.Branch)
+			violation.Hint = "re-run with --confirm, or push a feature branch and open a pull request"
+			return violation
+		}
+	case PolicyActionMerge:
+		if p.RequireCleanMerge && !check.Clean {
+			violation.Reason = fmt.Sprintf("merging into protected branch '%s' requires a clean working tree", check.Branch)
+			violation.Hint = "commit or stash your changes first"
+			return violation
+		}
+		if !check.Confirmed {
+			violation.Reason = fmt.Sprintf("merging into protected branch '%s' requires confirmation", check.Branch)
+			violation.Hint = "re-run with --confirm"
+			return violation
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) allPatterns() []string {
+	patterns := append([]string{}, p.ProtectedBranches...)
+	for _, repoPolicy := range p.Repositories {
+		patterns = append(patterns, repoPolicy.ProtectedBranches...)
+	}
+	return patterns
+}
@@ -0,0 +1,130 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxPreflightCommits caps the unpushed commits listed per repository
+const maxPreflightCommits = 20
+
+// RepositoryPreflight describes work in a repository that would be lost or orphaned by deleting it
+type RepositoryPreflight struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Branch     string `json:"branch,omitempty"`
+	Missing    bool   `json:"missing,omitempty"`
+	// UnpushedCommits are "<hash> <subject>" lines of commits not on any remote
+	UnpushedCommits []string `json:"unpushed_commits"`
+	// UnpushedCount is the total number of unpushed commits, UnpushedCommits may be truncated
+	UnpushedCount  int      `json:"unpushed_count"`
+	Stashes        []string `json:"stashes"`
+	UntrackedFiles []string `json:"untracked_files"`
+	ModifiedFiles  int      `json:"modified_files"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// AtRisk reports whether the repository holds work that is not safely stored elsewhere
+func (r *RepositoryPreflight) AtRisk() bool {
+	return r.UnpushedCount > 0 || len(r.Stashes) > 0 || len(r.UntrackedFiles) > 0 || r.ModifiedFiles > 0
+}
+
+// DeletePreflight is the dirty-state report shown before a workspace is deleted
+type DeletePreflight struct {
+	Workspace    string                `json:"workspace"`
+	Path         string                `json:"path"`
+	Repositories []RepositoryPreflight `json:"repositories"`
+}
+
+// AtRisk reports whether deleting the workspace could lose work
+func (p *DeletePreflight) AtRisk() bool {
+	for i := range p.Repositories {
+		if p.Repositories[i].AtRisk() {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDeletePreflight collects unpushed commits, stashes and untracked files of every
+// repository in a workspace.
+func (wm *WorkspaceManager) CheckDeletePreflight(ctx context.Context, workspace *Workspace) *DeletePreflight {
+	report := &DeletePreflight{
+		Workspace:    workspace.Name,
+		Path:         workspace.Path,
+		Repositories: []RepositoryPreflight{},
+	}
+
+	for _, repo := range workspace.Repositories {
+		report.Repositories = append(report.Repositories, wm.checkRepositoryPreflight(ctx, workspace, repo))
+	}
+
+	return report
+}
+
+func (wm *WorkspaceManager) checkRepositoryPreflight(ctx context.Context, workspace *Workspace, repo Repository) RepositoryPreflight {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	result := RepositoryPreflight{
+		Repository:      repo.Name,
+		Path:            worktreePath,
+		UnpushedCommits: []string{},
+		Stashes:         []string{},
+		UntrackedFiles:  []string{},
+	}
+
+	if _, err := os.Stat(filepath.Join(worktreePath, ".git")); err != nil {
+		result.Missing = true
+		return result
+	}
+
+	if branch, err := gitOutput(ctx, worktreePath, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		result.Branch = branch
+	}
+
+	// Commits not reachable from the upstream, or from any remote when there is no upstream.
+	// Repositories without remotes have nowhere to push to and are not reported.
+	rangeArgs := []string{"@{upstream}..HEAD"}
+	if _, err := gitOutput(ctx, worktreePath, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		remotes, err := gitOutput(ctx, worktreePath, "remote")
+		if err != nil || remotes == "" {
+			rangeArgs = nil
+		} else {
+			rangeArgs = []string{"HEAD", "--not", "--remotes"}
+		}
+	}
+	if rangeArgs != nil {
+		commits, err := gitOutput(ctx, worktreePath, append([]string{"log", "--format=%h %s"}, rangeArgs...)...)
+		if err != nil {
+			result.Error = err.Error()
+		} else if commits != "" {
+			lines := strings.Split(commits, "\n")
+			result.UnpushedCount = len(lines)
+			if len(lines) > maxPreflightCommits {
+				lines = lines[:maxPreflightCommits]
+			}
+			result.UnpushedCommits = lines
+		}
+	}
+
+	// Stashes are shared by all worktrees of a repository, only report the ones made on this branch
+	if stashes, err := gitOutput(ctx, worktreePath, "stash", "list"); err == nil && stashes != "" && result.Branch != "" {
+		for _, stash := range strings.Split(stashes, "\n") {
+			if strings.Contains(stash, " on "+result.Branch+": ") || strings.Contains(stash, "On "+result.Branch+": ") {
+				result.Stashes = append(result.Stashes, stash)
+			}
+		}
+	}
+
+	// Untracked files matched by .wsmignore are disposable and removed with the worktree
+	if untracked, _, err := wm.splitUntrackedFiles(ctx, worktreePath); err == nil {
+		result.UntrackedFiles = untracked
+	}
+
+	if modified, err := gitOutput(ctx, worktreePath, "status", "--porcelain", "--untracked-files=no"); err == nil && modified != "" {
+		result.ModifiedFiles = len(strings.Split(modified, "\n"))
+	}
+
+	return result
+}
@@ -0,0 +1,282 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PromptStatus is the compact workspace status shown in shell prompts
+type PromptStatus struct {
+	Workspace    string    `json:"workspace"`
+	Branch       string    `json:"branch"`
+	Repositories int       `json:"repositories"`
+	Dirty        int       `json:"dirty"`
+	Ahead        int       `json:"ahead"`
+	Behind       int       `json:"behind"`
+	Conflicts    int       `json:"conflicts"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// String renders the status on one line, e.g. "my-feature ⎇ task/x ●2 ↑3 ↓1"
+func (ps *PromptStatus) String() string {
+	parts := []string{ps.Workspace}
+	if ps.Branch != "" {
+		parts = append(parts, "⎇ "+ps.Branch)
+	}
+	if ps.Conflicts > 0 {
+		parts = append(parts, fmt.Sprintf("✗%d", ps.Conflicts))
+	}
+	if ps.Dirty > 0 {
+		parts = append(parts, fmt.Sprintf("●%d", ps.Dirty))
+	}
+	if ps.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", ps.Ahead))
+	}
+	if ps.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", ps.Behind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// StatusCache holds the last prompt status of each workspace, keyed by name.
+// Prompts read it instead of running git on every keystroke.
+type StatusCache struct {
+	Workspaces map[string]PromptStatus `json:"workspaces"`
+}
+
+func getStatusCachePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "status-cache.json"), nil
+}
+
+// LoadStatusCache loads the status cache, returning an empty cache if none exists
+func LoadStatusCache() (*StatusCache, error) {
+	cache := &StatusCache{Workspaces: map[string]PromptStatus{}}
+
+	cachePath, err := getStatusCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read status cache")
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, errors.Wrap(err, "failed to parse status cache")
+	}
+	if cache.Workspaces == nil {
+		cache.Workspaces = map[string]PromptStatus{}
+	}
+
+	return cache, nil
+}
+
+// Save writes the status cache atomically so concurrent prompts never see a partial file
+func (sc *StatusCache) Save() error {
+	cachePath, err := getStatusCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal status cache")
+	}
+
+	// Several shells may refresh at once, so each writes its own temporary file
+	tmpPath := fmt.Sprintf("%s.%d.tmp", cachePath, os.Getpid())
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write status cache")
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "failed to replace status cache")
+	}
+
+	return nil
+}
+
+// GetPromptStatus returns the cached prompt status of a workspace when it is
+// younger than maxAge, and recomputes and caches it otherwise
+func GetPromptStatus(ctx context.Context, workspace *Workspace, maxAge time.Duration) (*PromptStatus, error) {
+	cache, err := LoadStatusCache()
+	if err != nil {
+		// A corrupt cache is rebuilt rather than breaking the prompt
+		cache = &StatusCache{Workspaces: map[string]PromptStatus{}}
+	}
+
+	if cached, ok := cache.Workspaces[workspace.Name]; ok && time.Since(cached.UpdatedAt) < maxAge && !fetchedSince(workspace, cached.UpdatedAt) {
+		return &cached, nil
+	}
+
+	status := ComputePromptStatus(ctx, workspace)
+	cache.Workspaces[workspace.Name] = *status
+	if err := cache.Save(); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// fetchedSince reports whether the fetch daemon updated a repository of the
+// workspace after t, which invalidates cached ahead/behind counts
+func fetchedSince(workspace *Workspace, t time.Time) bool {
+	fetchState, err := LoadFetchState()
+	if err != nil {
+		return false
+	}
+	for _, repo := range workspace.Repositories {
+		if lastFetch, ok := fetchState.LastFetch(repo.Path); ok && lastFetch.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputePromptStatus computes the prompt status of a workspace with one
+// local git command per repository, run in parallel within the limits of
+// limits.yaml. It never fetches.
+func ComputePromptStatus(ctx context.Context, workspace *Workspace) *PromptStatus {
+	status := &PromptStatus{
+		Workspace:    workspace.Name,
+		Branch:       workspace.Branch,
+		Repositories: len(workspace.Repositories),
+		UpdatedAt:    time.Now(),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	limit := newLimiter(workspace.Name, 0)
+	for _, repo := range workspace.Repositories {
+		wg.Add(1)
+		go func(repoPath string) {
+			defer wg.Done()
+			release, err := limit.acquire(ctx)
+			if err != nil {
+				return
+			}
+			repoStatus, err := porcelainStatus(ctx, repoPath)
+			release()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if repoStatus.dirty {
+				status.Dirty++
+			}
+			if repoStatus.conflicts {
+				status.Conflicts++
+			}
+			status.Ahead += repoStatus.ahead
+			status.Behind += repoStatus.behind
+		}(filepath.Join(workspace.Path, repo.Name))
+	}
+	wg.Wait()
+
+	return status
+}
+
+// RecordPromptStatus refreshes the cached prompt status from a full workspace status
+func RecordPromptStatus(status *WorkspaceStatus) error {
+	cache, err := LoadStatusCache()
+	if err != nil {
+		cache = &StatusCache{Workspaces: map[string]PromptStatus{}}
+	}
+
+	promptStatus := PromptStatus{
+		Workspace:    status.Workspace.Name,
+		Branch:       status.Workspace.Branch,
+		Repositories: len(status.Repositories),
+		UpdatedAt:    time.Now(),
+	}
+	for _, repo := range status.Repositories {
+		if repo.HasChanges || len(repo.UntrackedFiles) > 0 {
+			promptStatus.Dirty++
+		}
+		if repo.HasConflicts {
+			promptStatus.Conflicts++
+		}
+		promptStatus.Ahead += repo.Ahead
+		promptStatus.Behind += repo.Behind
+	}
+
+	cache.Workspaces[promptStatus.Workspace] = promptStatus
+	return cache.Save()
+}
+
+type repositoryPromptStatus struct {
+	dirty     bool
+	conflicts bool
+	ahead     int
+	behind    int
+}
+
+// porcelainStatus reads branch and change information from a single
+// `git status --porcelain=v2 --branch`
+func porcelainStatus(ctx context.Context, repoPath string) (*repositoryPromptStatus, error) {
+	gitCtx, cancel := GitContext(ctx, "status")
+	defer cancel()
+
+	cmd := exec.CommandContext(gitCtx, "git", "status", "--porcelain=v2", "--branch")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, GitError(gitCtx, err)
+	}
+
+	status := &repositoryPromptStatus{}
+	var changed []string
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(line, "# branch.ab "))
+			if len(fields) == 2 {
+				status.ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				status.behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(line, "u "):
+			status.conflicts = true
+		case strings.HasPrefix(line, "1 "):
+			// 1 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <path>
+			if fields := strings.SplitN(line, " ", 9); len(fields) == 9 {
+				changed = append(changed, fields[8])
+			}
+		case strings.HasPrefix(line, "2 "):
+			// 2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <X><score> <path><tab><origPath>
+			if fields := strings.SplitN(line, " ", 10); len(fields) == 10 {
+				path, _, _ := strings.Cut(fields[9], "\t")
+				changed = append(changed, path)
+			}
+		case strings.HasPrefix(line, "? "):
+			changed = append(changed, strings.TrimPrefix(line, "? "))
+		}
+	}
+
+	// Files matched by .wsmignore do not make the repository dirty, as in wsm status
+	status.dirty = status.conflicts || len(loadIgnoreRulesOrEmpty(repoPath).Filter(changed)) > 0
+	return status, nil
+}
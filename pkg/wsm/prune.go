@@ -0,0 +1,268 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// GitWorktree is an entry of `git worktree list --porcelain`
+type GitWorktree struct {
+	Path     string `json:"path"`
+	Head     string `json:"head,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Main     bool   `json:"main"`
+	Prunable bool   `json:"prunable"`
+	Locked   bool   `json:"locked"`
+}
+
+// OrphanedWorktree is a worktree that points into a workspace that no longer exists
+type OrphanedWorktree struct {
+	Repository     string `json:"repository"`
+	RepositoryPath string `json:"repository_path"`
+	WorktreePath   string `json:"worktree_path"`
+	Branch         string `json:"branch,omitempty"`
+	Reason         string `json:"reason"`
+	Missing        bool   `json:"missing"` // directory is gone, cleaned by git worktree prune
+}
+
+// StaleWorkspace is a workspace configuration whose directory no longer exists
+type StaleWorkspace struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	ConfigPath string `json:"config_path"`
+}
+
+// PruneReport summarizes what was (or would be) cleaned up
+type PruneReport struct {
+	DryRun            bool               `json:"dry_run"`
+	OrphanedWorktrees []OrphanedWorktree `json:"orphaned_worktrees"`
+	// UnmarkedWorktrees would be orphans but have no wsm.json marker in their
+	// workspace directory, so they may not come from wsm and are left alone
+	UnmarkedWorktrees  []OrphanedWorktree `json:"unmarked_worktrees"`
+	StaleWorkspaces    []StaleWorkspace   `json:"stale_workspaces"`
+	PrunedRepositories []string           `json:"pruned_repositories"`
+	Errors             []string           `json:"errors,omitempty"`
+}
+
+// ListGitWorktrees lists the worktrees of a repository
+func ListGitWorktrees(ctx context.Context, repoPath string) ([]GitWorktree, error) {
+	cmd := exec.CommandContext(ctx, "git", "worktree", "list", "--porcelain")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list worktrees in %s", repoPath)
+	}
+
+	var worktrees []GitWorktree
+	var current *GitWorktree
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			if current != nil {
+				worktrees = append(worktrees, *current)
+			}
+			current = &GitWorktree{
+				Path: strings.TrimPrefix(line, "worktree "),
+				Main: len(worktrees) == 0,
+			}
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "HEAD "):
+			current.Head = strings.TrimPrefix(line, "HEAD ")
+		case strings.HasPrefix(line, "branch "):
+			current.Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		case line == "prunable" || strings.HasPrefix(line, "prunable "):
+			current.Prunable = true
+		case line == "locked" || strings.HasPrefix(line, "locked "):
+			current.Locked = true
+		}
+	}
+	if current != nil {
+		worktrees = append(worktrees, *current)
+	}
+
+	return worktrees, nil
+}
+
+// PruneOrphans finds worktrees of registered repositories that point into deleted
+// workspaces, runs `git worktree prune`, and removes stale workspace configurations.
+// Only worktrees in a directory with a .wsm/wsm.json marker are removed.
+func (wm *WorkspaceManager) PruneOrphans(ctx context.Context, dryRun bool) (*PruneReport, error) {
+	report := &PruneReport{
+		DryRun:             dryRun,
+		OrphanedWorktrees:  []OrphanedWorktree{},
+		UnmarkedWorktrees:  []OrphanedWorktree{},
+		StaleWorkspaces:    []StaleWorkspace{},
+		PrunedRepositories: []string{},
+	}
+
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+
+	workspacesDir := filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces")
+
+	// Split workspace configs into live and stale (directory gone)
+	var livePaths []string
+	for _, workspace := range workspaces {
+		if _, err := os.Stat(workspace.Path); os.IsNotExist(err) {
+			report.StaleWorkspaces = append(report.StaleWorkspaces, StaleWorkspace{
+				Name:       workspace.Name,
+				Path:       workspace.Path,
+				ConfigPath: filepath.Join(workspacesDir, workspace.Name+".json"),
+			})
+			continue
+		}
+		livePaths = append(livePaths, workspace.Path)
+	}
+
+	// Interrupted journaled creations have no config yet but can still be resumed
+	journals, err := wm.FindInterruptedCreations()
+	if err != nil {
+		return nil, err
+	}
+	for _, journal := range journals {
+		livePaths = append(livePaths, journal.Workspace.Path)
+	}
+
+	// Trashed workspaces keep their worktrees registered until they are restored or purged
+	trashedPaths := wm.trashedWorkspacePaths()
+
+	// Worktrees under the workspaces root are expected to belong to a workspace config
+	workspacesRoot := filepath.Dir(wm.workspaceDir)
+	prunePaths := make(map[string]string)
+
+	for _, repo := range wm.Discoverer.GetRepositories() {
+		if !wm.Discoverer.isGitRepository(repo.Path) {
+			continue
+		}
+
+		worktrees, err := ListGitWorktrees(ctx, repo.Path)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", repo.Name, err))
+			continue
+		}
+
+		needsPrune := false
+		for _, worktree := range worktrees {
+			if worktree.Main || isPathWithinAny(worktree.Path, trashedPaths) {
+				continue
+			}
+
+			orphan := OrphanedWorktree{
+				Repository:     repo.Name,
+				RepositoryPath: repo.Path,
+				WorktreePath:   worktree.Path,
+				Branch:         worktree.Branch,
+			}
+
+			_, statErr := os.Stat(worktree.Path)
+			switch {
+			case worktree.Prunable || os.IsNotExist(statErr):
+				orphan.Missing = true
+				orphan.Reason = "worktree directory no longer exists"
+				needsPrune = true
+			case isPathWithin(worktree.Path, workspacesRoot) && !isPathWithinAny(worktree.Path, livePaths):
+				orphan.Reason = "workspace configuration no longer exists"
+				if !hasWorkspaceMarker(worktree.Path, workspacesRoot) {
+					orphan.Reason = "no workspace configuration and no wsm.json marker"
+					report.UnmarkedWorktrees = append(report.UnmarkedWorktrees, orphan)
+					continue
+				}
+			default:
+				continue
+			}
+
+			report.OrphanedWorktrees = append(report.OrphanedWorktrees, orphan)
+		}
+
+		if needsPrune {
+			report.PrunedRepositories = append(report.PrunedRepositories, repo.Name)
+			prunePaths[repo.Name] = repo.Path
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	// Remove worktrees whose workspace config is gone; git refuses if they have local changes
+	for _, orphan := range report.OrphanedWorktrees {
+		if orphan.Missing {
+			continue
+		}
+		if err := deinitSubmodules(ctx, orphan.WorktreePath, false); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to remove worktree %s: %v", orphan.Repository, orphan.WorktreePath, err))
+			continue
+		}
+		cmd := exec.CommandContext(ctx, "git", "worktree", "remove", orphan.WorktreePath)
+		cmd.Dir = orphan.RepositoryPath
+		if cmdOutput, err := cmd.CombinedOutput(); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to remove worktree %s: %s", orphan.Repository, orphan.WorktreePath, strings.TrimSpace(string(cmdOutput))))
+			continue
+		}
+		output.LogInfo(
+			fmt.Sprintf("Removed orphaned worktree %s", orphan.WorktreePath),
+			"Removed orphaned worktree",
+			"repository", orphan.Repository,
+			"path", orphan.WorktreePath,
+		)
+	}
+
+	// Clean up administrative files of missing worktrees
+	for _, repoName := range report.PrunedRepositories {
+		cmd := exec.CommandContext(ctx, "git", "worktree", "prune")
+		cmd.Dir = prunePaths[repoName]
+		if cmdOutput, err := cmd.CombinedOutput(); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: git worktree prune failed: %s", repoName, strings.TrimSpace(string(cmdOutput))))
+		}
+	}
+
+	for _, stale := range report.StaleWorkspaces {
+		if err := os.Remove(stale.ConfigPath); err != nil && !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: failed to remove workspace config: %v", stale.Name, err))
+			continue
+		}
+		output.LogInfo(
+			fmt.Sprintf("Removed stale workspace configuration %s", stale.ConfigPath),
+			"Removed stale workspace configuration",
+			"workspace", stale.Name,
+			"config", stale.ConfigPath,
+		)
+	}
+
+	return report, nil
+}
+
+// hasWorkspaceMarker reports whether a worktree lies in a workspace directory
+// below root that wsm marked with .wsm/wsm.json
+func hasWorkspaceMarker(worktreePath, root string) bool {
+	workspacePath, ok := FindWorkspaceRoot(filepath.Dir(worktreePath))
+	return ok && isPathWithin(workspacePath, resolvePath(root))
+}
+
+// isPathWithin reports whether path equals root or is located below it
+func isPathWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func isPathWithinAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if isPathWithin(path, root) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Rebase plan actions, as written in a git rebase todo list
+const (
+	RebasePick   = "pick"
+	RebaseSquash = "squash"
+	RebaseFixup  = "fixup"
+	RebaseDrop   = "drop"
+)
+
+// RebaseActions lists the actions a rebase plan supports
+var RebaseActions = []string{RebasePick, RebaseSquash, RebaseFixup, RebaseDrop}
+
+// RebaseCommit is a commit of a rebase plan and what to do with it
+type RebaseCommit struct {
+	SHA     string `json:"sha"`
+	Subject string `json:"subject"`
+	Action  string `json:"action"`
+}
+
+// ShortSHA returns the abbreviated commit hash
+func (c RebaseCommit) ShortSHA() string {
+	if len(c.SHA) > 7 {
+		return c.SHA[:7]
+	}
+	return c.SHA
+}
+
+// RebasePlan is the interactive rebase of one repository: its commits on top
+// of Onto, in the order they will be replayed
+type RebasePlan struct {
+	Repository string         `json:"repository"`
+	Path       string         `json:"path"`
+	Onto       string         `json:"onto"`
+	Commits    []RebaseCommit `json:"commits"`
+
+	original []string
+}
+
+// LoadRebasePlan lists the commits of a repository on top of onto, oldest
+// first, all picked
+func LoadRebasePlan(ctx context.Context, repoName, repoPath, onto string) (*RebasePlan, error) {
+	out, err := gitOutput(ctx, repoPath, "log", "--reverse", "--format=%H%x09%s", onto+"..HEAD")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list commits of %s", repoName)
+	}
+
+	plan := &RebasePlan{Repository: repoName, Path: repoPath, Onto: onto}
+	for _, line := range splitLines(out) {
+		sha, subject, _ := strings.Cut(line, "\t")
+		plan.Commits = append(plan.Commits, RebaseCommit{SHA: sha, Subject: subject, Action: RebasePick})
+		plan.original = append(plan.original, sha)
+	}
+	return plan, nil
+}
+
+// Changed reports whether the plan differs from replaying every commit as is
+func (p *RebasePlan) Changed() bool {
+	if len(p.Commits) != len(p.original) {
+		return true
+	}
+	for i, commit := range p.Commits {
+		if commit.Action != RebasePick || commit.SHA != p.original[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Move moves the commit at index i by delta positions
+func (p *RebasePlan) Move(i, delta int) int {
+	j := i + delta
+	if i < 0 || i >= len(p.Commits) || j < 0 || j >= len(p.Commits) {
+		return i
+	}
+	p.Commits[i], p.Commits[j] = p.Commits[j], p.Commits[i]
+	return j
+}
+
+// Validate checks that squash and fixup commits have a commit to meld into
+func (p *RebasePlan) Validate() error {
+	for _, commit := range p.Commits {
+		switch commit.Action {
+		case RebaseDrop:
+			continue
+		case RebaseSquash, RebaseFixup:
+			return errors.Errorf("%s: the first kept commit %s cannot be a %s", p.Repository, commit.ShortSHA(), commit.Action)
+		case RebasePick:
+			return nil
+		default:
+			return errors.Errorf("%s: unknown rebase action '%s'", p.Repository, commit.Action)
+		}
+	}
+	return nil
+}
+
+// Todo renders the plan as a git rebase todo list
+func (p *RebasePlan) Todo() string {
+	var b strings.Builder
+	for _, commit := range p.Commits {
+		fmt.Fprintf(&b, "%s %s %s\n", commit.Action, commit.SHA, commit.Subject)
+	}
+	return b.String()
+}
+
+// SequenceEditorScript returns a GIT_SEQUENCE_EDITOR script replacing the todo
+// list git opens with the plan
+func (p *RebasePlan) SequenceEditorScript() string {
+	return "#!/bin/sh\ncat > \"$1\" <<'WSM_REBASE_TODO'\n" + p.Todo() + "WSM_REBASE_TODO\n"
+}
+
+// Execute runs `git rebase -i` with the plan as todo list. Squashed commits
+// keep the combined message git proposes, so no editor is opened.
+func (p *RebasePlan) Execute(ctx context.Context) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "wsm-rebase-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create rebase script directory")
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "sequence-editor.sh")
+	if err := os.WriteFile(script, []byte(p.SequenceEditorScript()), 0755); err != nil {
+		return errors.Wrap(err, "failed to write sequence editor script")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "rebase", "-i", p.Onto)
+	cmd.Dir = p.Path
+	cmd.Env = append(os.Environ(),
+		"GIT_SEQUENCE_EDITOR="+shellQuote(script),
+		"GIT_EDITOR=true",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "git rebase failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// RegistryExport is the portable form of the repository registry written by
+// `wsm repos export`. Paths under the home directory are written with ~, so
+// the file can move between machines or live in dotfiles:
+//
+//	repositories:
+//	  - name: api
+//	    url: git@github.com:acme/api.git
+//	    path: ~/code/api
+//	    tags: [backend, go]
+//	    upstream-remote: upstream
+//	groups:
+//	  backend: [api, worker]
+type RegistryExport struct {
+	Repositories []ExportedRepository `yaml:"repositories"`
+	Groups       map[string][]string  `yaml:"groups,omitempty"`
+}
+
+// ExportedRepository is a registry entry in a RegistryExport
+type ExportedRepository struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url,omitempty"`
+	Path string `yaml:"path,omitempty"`
+	// Tags are all categories; UserTags and ExcludedTags are the ones added and
+	// removed with 'wsm repos tag', which survive rediscovery
+	Tags           []string `yaml:"tags,omitempty"`
+	UserTags       []string `yaml:"user-tags,omitempty"`
+	ExcludedTags   []string `yaml:"excluded-tags,omitempty"`
+	Remote         string   `yaml:"remote,omitempty"`
+	UpstreamRemote string   `yaml:"upstream-remote,omitempty"`
+	Filter         string   `yaml:"filter,omitempty"`
+	Depth          int      `yaml:"depth,omitempty"`
+	Archived       bool     `yaml:"archived,omitempty"`
+}
+
+// Registry import modes
+const (
+	// RegistryImportMerge adds the repositories and groups missing from the registry
+	RegistryImportMerge = "merge"
+	// RegistryImportReplace makes the registry exactly the imported one
+	RegistryImportReplace = "replace"
+)
+
+// RegistryImportResult lists what an import did with every repository
+type RegistryImportResult struct {
+	// Added are local clones found at their exported path
+	Added []string `json:"added,omitempty"`
+	// RemoteOnly are registered by URL and cloned on demand when a workspace uses them
+	RemoteOnly []string `json:"remote_only,omitempty"`
+	// Kept are already registered and left unchanged (merge mode)
+	Kept []string `json:"kept,omitempty"`
+	// Skipped maps repositories that could not be registered to the reason
+	Skipped map[string]string `json:"skipped,omitempty"`
+	Groups  []string          `json:"groups,omitempty"`
+}
+
+// ExportRegistry returns the registry in its portable form, sorted by name
+func (rd *RepositoryDiscoverer) ExportRegistry() *RegistryExport {
+	export := &RegistryExport{Repositories: []ExportedRepository{}}
+	for _, repo := range rd.registry.Repositories {
+		entry := ExportedRepository{
+			Name:           repo.Name,
+			URL:            repo.RemoteURL,
+			Path:           contractHome(repo.Path),
+			Tags:           repo.Categories,
+			UserTags:       repo.UserCategories,
+			ExcludedTags:   repo.ExcludedCategories,
+			Remote:         repo.Remote,
+			UpstreamRemote: repo.UpstreamRemote,
+			Archived:       repo.Archived,
+		}
+		if repo.CloneOptions != nil {
+			entry.Filter, entry.Depth = repo.CloneOptions.Filter, repo.CloneOptions.Depth
+		}
+		export.Repositories = append(export.Repositories, entry)
+	}
+	sort.Slice(export.Repositories, func(i, j int) bool {
+		return export.Repositories[i].Name < export.Repositories[j].Name
+	})
+	if len(rd.registry.Groups) > 0 {
+		export.Groups = rd.registry.Groups
+	}
+	return export
+}
+
+// MarshalRegistryExport renders an export as YAML
+func MarshalRegistryExport(export *RegistryExport) ([]byte, error) {
+	data, err := yaml.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal registry export")
+	}
+	return data, nil
+}
+
+// ParseRegistryExport reads an export written by `wsm repos export`
+func ParseRegistryExport(data []byte) (*RegistryExport, error) {
+	var export RegistryExport
+	if err := yaml.Unmarshal(data, &export); err != nil {
+		return nil, errors.Wrap(err, "failed to parse registry export")
+	}
+	seen := make(map[string]bool)
+	for i, entry := range export.Repositories {
+		if entry.Name == "" {
+			return nil, errors.Errorf("repository #%d has no name", i+1)
+		}
+		if seen[entry.Name] {
+			return nil, errors.Errorf("repository '%s' is listed twice", entry.Name)
+		}
+		seen[entry.Name] = true
+		if err := (CloneOptions{Filter: entry.Filter, Depth: entry.Depth}).Validate(); err != nil {
+			return nil, errors.Wrapf(err, "repository '%s'", entry.Name)
+		}
+	}
+	return &export, nil
+}
+
+// ImportRegistry registers the repositories of an export. Repositories whose
+// exported path is a git repository on this machine are registered as local
+// clones, the others by URL, to be cloned on demand. In merge mode
+// repositories and groups already registered under the same name are kept;
+// in replace mode the registry is cleared first. The registry is not saved.
+func (rd *RepositoryDiscoverer) ImportRegistry(ctx context.Context, export *RegistryExport, mode string) (*RegistryImportResult, error) {
+	if mode != RegistryImportMerge && mode != RegistryImportReplace {
+		return nil, errors.Errorf("invalid import mode '%s' (expected %s or %s)", mode, RegistryImportMerge, RegistryImportReplace)
+	}
+	if mode == RegistryImportReplace {
+		rd.registry.Repositories = []Repository{}
+		rd.registry.Groups = nil
+	}
+
+	result := &RegistryImportResult{Skipped: make(map[string]string)}
+	for _, entry := range export.Repositories {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		repo, local := rd.importedRepository(ctx, entry)
+		if rd.isRegistered(repo) || (local && rd.isRegisteredPath(repo.Path)) {
+			result.Kept = append(result.Kept, entry.Name)
+			continue
+		}
+		switch {
+		case local:
+			result.Added = append(result.Added, entry.Name)
+		case entry.URL != "":
+			result.RemoteOnly = append(result.RemoteOnly, entry.Name)
+		default:
+			result.Skipped[entry.Name] = "no clone at " + entry.Path + " and no url to clone it from"
+			continue
+		}
+		rd.registry.Repositories = append(rd.registry.Repositories, repo)
+	}
+
+	for name, members := range export.Groups {
+		if _, exists := rd.registry.Groups[name]; exists {
+			continue
+		}
+		if rd.registry.Groups == nil {
+			rd.registry.Groups = make(map[string][]string)
+		}
+		rd.registry.Groups[name] = members
+		result.Groups = append(result.Groups, name)
+	}
+	sort.Strings(result.Groups)
+
+	if len(result.Skipped) == 0 {
+		result.Skipped = nil
+	}
+	return result, nil
+}
+
+// importedRepository builds the registry entry of an exported repository,
+// analyzing its clone when the exported path holds one
+func (rd *RepositoryDiscoverer) importedRepository(ctx context.Context, entry ExportedRepository) (Repository, bool) {
+	repo := Repository{
+		Name:               entry.Name,
+		RemoteURL:          entry.URL,
+		Categories:         entry.Tags,
+		UserCategories:     entry.UserTags,
+		ExcludedCategories: entry.ExcludedTags,
+		Remote:             entry.Remote,
+		UpstreamRemote:     entry.UpstreamRemote,
+		Archived:           entry.Archived,
+		LastUpdated:        time.Now(),
+	}
+	if entry.Filter != "" || entry.Depth != 0 {
+		repo.CloneOptions = &CloneOptions{Filter: entry.Filter, Depth: entry.Depth}
+	}
+
+	path := expandHome(entry.Path)
+	if path == "" || !rd.isGitRepository(path) {
+		return repo, false
+	}
+	analyzed, err := rd.analyzeRepository(ctx, path)
+	if err != nil {
+		return repo, false
+	}
+	analyzed.Name = entry.Name
+	analyzed.keepUserSettings(repo)
+	analyzed.Categories = applyUserCategories(analyzed.Categories, analyzed.UserCategories, analyzed.ExcludedCategories)
+	return *analyzed, true
+}
+
+// isRegisteredPath reports whether a clone at path is registered
+func (rd *RepositoryDiscoverer) isRegisteredPath(path string) bool {
+	for _, existing := range rd.registry.Repositories {
+		if existing.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// contractHome writes paths under the home directory with a leading ~
+func contractHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || path == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rel)
+	}
+	return path
+}
@@ -0,0 +1,367 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ReleaseConfig declares how `wsm release` prepares repositories before tagging.
+// It is read from release.yaml in the workspace-manager config directory:
+//
+//	bump-commands: ["echo {version} > VERSION"]
+//	commit-message: "chore(release): {version}"
+//	repositories:
+//	  web:
+//	    bump-commands: ["npm version --no-git-tag-version {version}"]
+//
+// Bump commands run with sh in each worktree, with {version} replaced and
+// WSM_VERSION set. Changes they make are committed before the tag is created.
+type ReleaseConfig struct {
+	// BumpCommands run in every repository without commands of its own
+	BumpCommands []string `yaml:"bump-commands,omitempty" json:"bump_commands,omitempty"`
+	// CommitMessage of bump commits; {version} is replaced
+	CommitMessage string `yaml:"commit-message,omitempty" json:"commit_message,omitempty"`
+	// Repositories replaces the bump commands of individual repositories
+	Repositories map[string]RepositoryReleaseConfig `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+}
+
+// RepositoryReleaseConfig holds repository specific release settings
+type RepositoryReleaseConfig struct {
+	BumpCommands []string `yaml:"bump-commands" json:"bump_commands"`
+}
+
+const defaultReleaseCommitMessage = "chore(release): {version}"
+
+// GetReleaseConfigPath returns the path of the release configuration file
+func GetReleaseConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "release.yaml"), nil
+}
+
+// LoadReleaseConfig loads release.yaml; a missing file means no bump commands
+func LoadReleaseConfig() (*ReleaseConfig, error) {
+	configPath, err := GetReleaseConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ReleaseConfig{}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read release configuration")
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse release configuration %s", configPath)
+		}
+	}
+	if config.CommitMessage == "" {
+		config.CommitMessage = defaultReleaseCommitMessage
+	}
+	return config, nil
+}
+
+// BumpCommandsFor returns the bump commands of a repository with {version} replaced
+func (c *ReleaseConfig) BumpCommandsFor(repoName, version string) []string {
+	commands := c.BumpCommands
+	if repoConfig, ok := c.Repositories[repoName]; ok {
+		commands = repoConfig.BumpCommands
+	}
+	expanded := make([]string, len(commands))
+	for i, command := range commands {
+		expanded[i] = strings.ReplaceAll(command, "{version}", version)
+	}
+	return expanded
+}
+
+// Release is a tag set created across the repositories of a workspace
+type Release struct {
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	// Tags maps repositories to the commit their tag points to
+	Tags   map[string]string `json:"tags"`
+	Signed bool              `json:"signed,omitempty"`
+	Pushed bool              `json:"pushed,omitempty"`
+}
+
+// ReleaseOptions configures WorkspaceManager.Release
+type ReleaseOptions struct {
+	Version string
+	// Message of the annotated tags; {version} is replaced
+	Message    string
+	Sign       bool
+	SigningKey string
+	Bump       bool
+	Push       bool
+	// Confirm allows pushing bump commits to protected branches
+	Confirm bool
+	DryRun  bool
+}
+
+// ReleaseResult is the outcome of a release in one repository
+type ReleaseResult struct {
+	Repository   string   `json:"repository"`
+	Commit       string   `json:"commit,omitempty"`
+	BumpCommands []string `json:"bump_commands,omitempty"`
+	Bumped       bool     `json:"bumped"`
+	Tagged       bool     `json:"tagged"`
+	Pushed       bool     `json:"pushed"`
+	Skipped      bool     `json:"skipped,omitempty"`
+	RolledBack   bool     `json:"rolled_back,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Release bumps versions, creates an annotated tag at HEAD of every repository
+// of the workspace, pushes the tags and records the release in the workspace.
+// Pinned repositories are skipped. Preconditions are checked in all repositories
+// first; if bumping or tagging fails, the repositories already released are
+// restored, so a release is created everywhere or nowhere. Push failures are
+// reported but keep the tags.
+func (wm *WorkspaceManager) Release(ctx context.Context, workspace *Workspace, options *ReleaseOptions) (*Release, []ReleaseResult, error) {
+	version := strings.TrimSpace(options.Version)
+	if version == "" {
+		return nil, nil, errors.New("release version is required")
+	}
+	if _, err := gitOutput(ctx, workspace.Path, "check-ref-format", "refs/tags/"+version); err != nil {
+		return nil, nil, errors.Errorf("'%s' is not a valid tag name", version)
+	}
+	for _, release := range workspace.Releases {
+		if release.Version == version {
+			return nil, nil, errors.Errorf("release %s was already created on %s", version, release.Created.Format("2006-01-02"))
+		}
+	}
+
+	config, err := LoadReleaseConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []ReleaseResult
+	var problems []string
+	for _, repo := range workspace.Repositories {
+		result := ReleaseResult{Repository: repo.Name}
+		if workspace.PinFor(repo.Name) != "" {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+		if options.Bump {
+			result.BumpCommands = config.BumpCommandsFor(repo.Name, version)
+		}
+
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		if err := checkReleasable(ctx, repoPath, workspace.RemoteFor(repo), version, len(result.BumpCommands) > 0, options.Push); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repo.Name, err))
+		}
+		if result.Commit, err = gitOutput(ctx, repoPath, "rev-parse", "HEAD"); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", repo.Name, err))
+		}
+		results = append(results, result)
+	}
+	if len(problems) > 0 {
+		return nil, results, errors.Errorf("cannot release %s:\n%s", version, strings.Join(problems, "\n"))
+	}
+	if options.DryRun {
+		return nil, results, nil
+	}
+
+	message := options.Message
+	if message == "" {
+		message = "Release {version}"
+	}
+	message = strings.ReplaceAll(message, "{version}", version)
+
+	release := &Release{
+		Version: version,
+		Created: time.Now(),
+		Tags:    map[string]string{},
+		Signed:  options.Sign || options.SigningKey != "",
+	}
+
+	for i := range results {
+		result := &results[i]
+		if result.Skipped {
+			continue
+		}
+		repoPath := filepath.Join(workspace.Path, result.Repository)
+
+		err := wm.bumpRepository(ctx, workspace, result, repoPath, version, config.CommitMessage)
+		if err == nil {
+			err = tagRepository(ctx, repoPath, version, message, options)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			rollbackRelease(ctx, workspace, results[:i+1], version)
+			return nil, results, errors.Wrapf(err, "release failed in %s, tags and bump commits were removed", result.Repository)
+		}
+		result.Tagged = true
+		release.Tags[result.Repository] = result.Commit
+
+		output.LogInfo(
+			fmt.Sprintf("Tagged %s in %s", version, result.Repository),
+			"Repository tagged",
+			"repository", result.Repository,
+			"version", version,
+			"commit", result.Commit,
+		)
+	}
+
+	if options.Push {
+		release.Pushed = wm.pushRelease(ctx, workspace, results, version, options.Confirm)
+	}
+
+	workspace.Releases = append(workspace.Releases, *release)
+	if err := wm.SaveWorkspace(workspace); err != nil {
+		return release, results, errors.Wrap(err, "failed to record release")
+	}
+
+	return release, results, nil
+}
+
+// checkReleasable verifies a repository is clean and the tag does not exist yet.
+// Repositories with bump commands must not have untracked files either, as
+// everything the commands leave behind is committed.
+func checkReleasable(ctx context.Context, repoPath, remote, version string, bump, push bool) error {
+	untracked := "--untracked-files=no"
+	if bump {
+		untracked = "--untracked-files=normal"
+	}
+	if modified, err := gitOutput(ctx, repoPath, "status", "--porcelain", untracked); err != nil {
+		return err
+	} else if modified != "" {
+		return errors.New("uncommitted changes")
+	}
+	if _, err := gitOutput(ctx, repoPath, "rev-parse", "--quiet", "--verify", "refs/tags/"+version); err == nil {
+		return errors.Errorf("tag %s already exists", version)
+	}
+	if push {
+		if existing, err := gitOutput(ctx, repoPath, "ls-remote", "--tags", remote, "refs/tags/"+version); err != nil {
+			return errors.Wrapf(err, "failed to query %s", remote)
+		} else if existing != "" {
+			return errors.Errorf("tag %s already exists on %s", version, remote)
+		}
+	}
+	return nil
+}
+
+// bumpRepository runs the bump commands and commits their changes
+func (wm *WorkspaceManager) bumpRepository(ctx context.Context, workspace *Workspace, result *ReleaseResult, repoPath, version, commitMessage string) error {
+	if len(result.BumpCommands) == 0 {
+		return nil
+	}
+	for _, command := range result.BumpCommands {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = repoPath
+		cmd.Env = os.Environ()
+		for key, value := range workspaceEnvironment(workspace) {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+		cmd.Env = append(cmd.Env, "WSM_VERSION="+version, "WSM_REPO_NAME="+result.Repository)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if out := strings.TrimSpace(string(out)); out != "" {
+				return errors.Wrapf(err, "bump command '%s' failed: %s", command, out)
+			}
+			return errors.Wrapf(err, "bump command '%s' failed", command)
+		}
+	}
+
+	changes, err := gitOutput(ctx, repoPath, "status", "--porcelain")
+	if err != nil || changes == "" {
+		return err
+	}
+	if _, err := gitOutput(ctx, repoPath, "add", "-A"); err != nil {
+		return errors.Wrap(err, "failed to stage bump changes")
+	}
+	if out, err := gitOutput(ctx, repoPath, "commit", "-m", strings.ReplaceAll(commitMessage, "{version}", version)); err != nil {
+		return errors.Wrapf(err, "failed to commit bump changes: %s", out)
+	}
+	result.Bumped = true
+	result.Commit, err = gitOutput(ctx, repoPath, "rev-parse", "HEAD")
+	return err
+}
+
+func tagRepository(ctx context.Context, repoPath, version, message string, options *ReleaseOptions) error {
+	args := []string{"tag", "--annotate"}
+	if options.SigningKey != "" {
+		args = append(args, "--local-user", options.SigningKey)
+	} else if options.Sign {
+		args = append(args, "--sign")
+	}
+	args = append(args, "-m", message, version)
+	if out, err := gitOutput(ctx, repoPath, args...); err != nil {
+		return errors.Wrapf(err, "failed to create tag: %s", out)
+	}
+	return nil
+}
+
+// rollbackRelease deletes the tags and bump commits of a failed release
+func rollbackRelease(ctx context.Context, workspace *Workspace, results []ReleaseResult, version string) {
+	for i := range results {
+		result := &results[i]
+		if result.Skipped {
+			continue
+		}
+		repoPath := filepath.Join(workspace.Path, result.Repository)
+		if result.Tagged {
+			_, _ = gitOutput(ctx, repoPath, "tag", "--delete", version)
+			result.Tagged = false
+		}
+		if len(result.BumpCommands) > 0 {
+			// The worktree was clean before the release, so nothing else is lost
+			target := "HEAD"
+			if result.Bumped {
+				target = "HEAD~1"
+				result.Bumped = false
+			}
+			_, _ = gitOutput(ctx, repoPath, "reset", "--hard", target)
+			_, _ = gitOutput(ctx, repoPath, "clean", "-fd")
+		}
+		result.RolledBack = result.Error == ""
+	}
+}
+
+// pushRelease pushes bump commits and tags; it reports whether every push succeeded
+func (wm *WorkspaceManager) pushRelease(ctx context.Context, workspace *Workspace, results []ReleaseResult, version string, confirmed bool) bool {
+	syncOps := NewSyncOperations(workspace)
+	pushed := true
+	for i := range results {
+		result := &results[i]
+		if !result.Tagged {
+			continue
+		}
+		repoPath := filepath.Join(workspace.Path, result.Repository)
+		remote := workspace.RemoteOf(result.Repository)
+
+		if result.Bumped {
+			err := syncOps.checkPushPolicy(ctx, result.Repository, repoPath, confirmed)
+			if err == nil {
+				err = syncOps.pushRepository(ctx, repoPath, remote)
+			}
+			if err != nil {
+				result.Error = fmt.Sprintf("failed to push bump commit: %v", err)
+				pushed = false
+				continue
+			}
+		}
+
+		if out, err := gitOutput(ctx, repoPath, "push", remote, "refs/tags/"+version); err != nil {
+			result.Error = fmt.Sprintf("failed to push tag: %s", firstLine(out))
+			pushed = false
+			continue
+		}
+		result.Pushed = true
+	}
+	return pushed
+}
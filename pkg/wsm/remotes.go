@@ -0,0 +1,200 @@
+package wsm
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRemote is the primary remote of repositories that configure none
+const DefaultRemote = "origin"
+
+// RemoteName returns the primary remote of the repository: the one branches
+// are pushed to and tracked on
+func (r Repository) RemoteName() string {
+	if r.Remote != "" {
+		return r.Remote
+	}
+	return DefaultRemote
+}
+
+// BaseRemote returns the remote base branches come from: the configured
+// upstream remote (e.g. "upstream" for a fork) or the primary remote
+func (r Repository) BaseRemote() string {
+	if r.UpstreamRemote != "" {
+		return r.UpstreamRemote
+	}
+	return r.RemoteName()
+}
+
+// BaseRef returns the remote-tracking ref of the default branch of the
+// repository's base remote, e.g. "upstream/main"
+func (r Repository) BaseRef(ctx context.Context, repoPath string) string {
+	remote := r.BaseRemote()
+	branch, err := GetRemoteDefaultBranch(ctx, repoPath, remote)
+	if err != nil {
+		branch = "main"
+	}
+	return remote + "/" + branch
+}
+
+// keepUserSettings carries the user-managed settings of a registry entry over
+// to its rediscovered version
+func (r *Repository) keepUserSettings(old Repository) {
+	r.UserCategories = old.UserCategories
+	r.ExcludedCategories = old.ExcludedCategories
+	r.Remote = old.Remote
+	r.UpstreamRemote = old.UpstreamRemote
+	r.CloneOptions = old.CloneOptions
+	// A repository found archived on GitHub stays archived
+	r.Archived = r.Archived || old.Archived
+}
+
+// SetUpstreamRemote configures the remote a repository's base branches come
+// from in the registry and in every workspace using it. An empty remote
+// resets it to origin. It returns the names of the updated workspaces.
+func (wm *WorkspaceManager) SetUpstreamRemote(ctx context.Context, repoName, remote string) ([]string, error) {
+	repo, err := wm.Discoverer.findRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if remote == repo.RemoteName() {
+		remote = ""
+	}
+	if err := checkRemoteExists(ctx, *repo, remote); err != nil {
+		return nil, err
+	}
+
+	repo.UpstreamRemote = remote
+	if err := wm.Discoverer.SaveRegistry(); err != nil {
+		return nil, errors.Wrap(err, "failed to save registry")
+	}
+
+	return wm.updateWorkspaceRepositories(*repo, func(r *Repository) {
+		r.UpstreamRemote = remote
+	})
+}
+
+// SetPrimaryRemote configures the primary remote of a repository in the
+// registry and in every workspace using it that does not override it. An
+// empty remote resets it to origin. It returns the names of the updated workspaces.
+func (wm *WorkspaceManager) SetPrimaryRemote(ctx context.Context, repoName, remote string) ([]string, error) {
+	repo, err := wm.Discoverer.findRepository(repoName)
+	if err != nil {
+		return nil, err
+	}
+	if remote == DefaultRemote {
+		remote = ""
+	}
+	if err := checkRemoteExists(ctx, *repo, remote); err != nil {
+		return nil, err
+	}
+
+	repo.Remote = remote
+	if err := wm.Discoverer.SaveRegistry(); err != nil {
+		return nil, errors.Wrap(err, "failed to save registry")
+	}
+
+	return wm.updateWorkspaceRepositories(*repo, func(r *Repository) {
+		r.Remote = remote
+	})
+}
+
+// RemoteFor returns the primary remote of a repository in the workspace
+func (w *Workspace) RemoteFor(repo Repository) string {
+	if w.Remote != "" {
+		return w.Remote
+	}
+	return repo.RemoteName()
+}
+
+// RemoteOf returns the primary remote of a repository of the workspace by name
+func (w *Workspace) RemoteOf(repoName string) string {
+	repo, _ := w.repository(repoName)
+	return w.RemoteFor(repo)
+}
+
+// BaseRemoteOf returns the remote the base branch of a repository of the
+// workspace comes from
+func (w *Workspace) BaseRemoteOf(repoName string) string {
+	if repo, ok := w.repository(repoName); ok && repo.UpstreamRemote != "" {
+		return repo.UpstreamRemote
+	}
+	return w.RemoteOf(repoName)
+}
+
+// SetWorkspaceRemote makes every repository of a workspace use remote as its
+// primary remote; an empty remote goes back to the remotes of the registry
+func (wm *WorkspaceManager) SetWorkspaceRemote(ctx context.Context, workspace *Workspace, remote string) error {
+	for i := range workspace.Repositories {
+		repo := &workspace.Repositories[i]
+		if remote == "" {
+			if registered, err := wm.Discoverer.findRepository(repo.Name); err == nil {
+				repo.Remote = registered.Remote
+			} else {
+				repo.Remote = ""
+			}
+			continue
+		}
+		if err := checkRemoteExists(ctx, *repo, remote); err != nil {
+			return err
+		}
+	}
+
+	workspace.Remote = remote
+	workspace.applyRemote()
+	return wm.SaveWorkspace(workspace)
+}
+
+// applyRemote gives the repositories of a workspace its remote override
+func (w *Workspace) applyRemote() {
+	if w.Remote == "" {
+		return
+	}
+	for i := range w.Repositories {
+		w.Repositories[i].Remote = w.Remote
+	}
+}
+
+// checkRemoteExists fails when a cloned repository has no remote of that name
+func checkRemoteExists(ctx context.Context, repo Repository, remote string) error {
+	if remote == "" || repo.IsRemoteOnly() {
+		return nil
+	}
+	if _, err := gitOutput(ctx, repo.Path, "remote", "get-url", remote); err != nil {
+		return errors.Errorf("repository '%s' has no remote named '%s' (add it with 'git -C %s remote add %s <url>')",
+			repo.Name, remote, repo.Path, remote)
+	}
+	return nil
+}
+
+// updateWorkspaceRepositories applies a registry change to the copy of a
+// repository in every workspace using it and returns the updated workspaces
+func (wm *WorkspaceManager) updateWorkspaceRepositories(repo Repository, update func(*Repository)) ([]string, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+
+	var updated []string
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		changed := false
+		for j := range workspace.Repositories {
+			if workspace.Repositories[j].Name == repo.Name && workspace.Repositories[j].Path == repo.Path {
+				update(&workspace.Repositories[j])
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		workspace.applyRemote()
+		if err := wm.SaveWorkspace(workspace); err != nil {
+			return updated, errors.Wrapf(err, "failed to save workspace '%s'", workspace.Name)
+		}
+		updated = append(updated, workspace.Name)
+	}
+
+	return updated, nil
+}
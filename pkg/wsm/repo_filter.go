@@ -0,0 +1,98 @@
+package wsm
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RepositoryFilter narrows a workspace-wide operation to some of the workspace
+// repositories. A repository matches when it is named in Repositories (or that
+// list is empty), is not named in Exclude, and has one of Tags (or that list is
+// empty).
+type RepositoryFilter struct {
+	Repositories []string `json:"repositories,omitempty"`
+	Exclude      []string `json:"exclude,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// IsZero reports whether the filter matches every repository
+func (f RepositoryFilter) IsZero() bool {
+	return len(f.Repositories) == 0 && len(f.Exclude) == 0 && len(f.Tags) == 0
+}
+
+// Matches reports whether a repository passes the filter
+func (f RepositoryFilter) Matches(repo Repository) bool {
+	if len(f.Repositories) > 0 && !containsValue(f.Repositories, repo.Name) {
+		return false
+	}
+	if containsValue(f.Exclude, repo.Name) {
+		return false
+	}
+	return len(f.Tags) == 0 || hasAnyValue(repo.Categories, f.Tags)
+}
+
+// String describes the filter for headers, e.g. "repo app,lib; tag go"
+func (f RepositoryFilter) String() string {
+	var parts []string
+	if len(f.Repositories) > 0 {
+		parts = append(parts, "repo "+strings.Join(f.Repositories, ","))
+	}
+	if len(f.Exclude) > 0 {
+		parts = append(parts, "excluding "+strings.Join(f.Exclude, ","))
+	}
+	if len(f.Tags) > 0 {
+		parts = append(parts, "tag "+strings.Join(f.Tags, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Select returns the workspace repositories passing the filter, in workspace
+// order. Repositories named in the filter must belong to the workspace.
+func (f RepositoryFilter) Select(workspace *Workspace) ([]Repository, error) {
+	for _, name := range append(append([]string{}, f.Repositories...), f.Exclude...) {
+		if !workspaceHasRepository(workspace, name) {
+			return nil, errors.Errorf("repository '%s' not found in workspace '%s'", name, workspace.Name)
+		}
+	}
+
+	var selected []Repository
+	for _, repo := range workspace.Repositories {
+		if f.Matches(repo) {
+			selected = append(selected, repo)
+		}
+	}
+	return selected, nil
+}
+
+// Apply returns a view of the workspace holding only the repositories passing
+// the filter. The view records the filter in Workspace.Filter, so JSON output
+// shows it, and cannot be saved. A zero filter returns the workspace itself.
+func (f RepositoryFilter) Apply(workspace *Workspace) (*Workspace, error) {
+	if f.IsZero() {
+		return workspace, nil
+	}
+	selected, err := f.Select(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return nil, errors.Errorf("no repository of workspace '%s' matches %s", workspace.Name, f)
+	}
+
+	view := *workspace
+	view.Repositories = selected
+	filter := f
+	view.Filter = &filter
+	return &view, nil
+}
+
+// workspaceHasRepository reports whether a workspace contains the named repository
+func workspaceHasRepository(workspace *Workspace, name string) bool {
+	for _, repo := range workspace.Repositories {
+		if repo.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package wsm
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// IsRepositoryLocation reports whether a repository given to create or add is
+// a path or a remote URL rather than a registered name or @group. Registered
+// names are directory names, so they never contain a separator or a colon.
+func IsRepositoryLocation(entry string) bool {
+	if entry == "." || entry == ".." || entry == "~" {
+		return true
+	}
+	return strings.ContainsAny(entry, `/\:`)
+}
+
+// isRemoteLocation reports whether a repository location is a remote URL,
+// either scheme://host/path or scp-like user@host:path
+func isRemoteLocation(entry string) bool {
+	if strings.Contains(entry, "://") {
+		return true
+	}
+	// A colon after the first path separator, or a drive letter, is part of a path
+	colon := strings.Index(entry, ":")
+	return colon > 1 && !strings.ContainsAny(entry[:colon], `/\`)
+}
+
+// RegisterRepositoryLocations replaces the paths and remote URLs among
+// repository entries with the names of their registry entries, registering
+// them on the fly when needed: a clone is analyzed as 'wsm discover' would,
+// a remote URL is registered to be cloned on demand into the clone directory.
+// Names and @groups are returned unchanged. With dryRun the registry is not saved.
+func (rd *RepositoryDiscoverer) RegisterRepositoryLocations(ctx context.Context, entries []string, dryRun bool) ([]string, error) {
+	names := make([]string, 0, len(entries))
+	registered := false
+	for _, entry := range entries {
+		if !IsRepositoryLocation(entry) {
+			names = append(names, entry)
+			continue
+		}
+
+		var (
+			repo  *Repository
+			added bool
+			err   error
+		)
+		if isRemoteLocation(entry) {
+			repo, added = rd.registerRemoteLocation(entry)
+		} else {
+			repo, added, err = rd.registerLocalLocation(ctx, entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			registered = true
+			output.PrintInfo("Registered %s as repository '%s'", entry, repo.Name)
+		}
+		names = append(names, repo.Name)
+	}
+
+	if registered && !dryRun {
+		if err := rd.SaveRegistry(); err != nil {
+			return nil, errors.Wrap(err, "failed to save registry")
+		}
+	}
+	return names, nil
+}
+
+// registerLocalLocation returns the registry entry of the clone at path,
+// registering it when it is not registered yet
+func (rd *RepositoryDiscoverer) registerLocalLocation(ctx context.Context, entry string) (*Repository, bool, error) {
+	path, err := filepath.Abs(expandHome(entry))
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to get absolute path for %s", entry)
+	}
+	for i := range rd.registry.Repositories {
+		if rd.registry.Repositories[i].Path == path {
+			return &rd.registry.Repositories[i], false, nil
+		}
+	}
+	if !rd.isGitRepository(path) {
+		return nil, false, errors.Errorf("%s is not a git repository", path)
+	}
+
+	repo, err := rd.analyzeRepository(ctx, path)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to analyze %s", path)
+	}
+
+	// A clone of a repository registered by remote URL only replaces that entry
+	if remote, ok := remoteOnlyMatch(rd.registry.Repositories, *repo); ok {
+		repo.Name = remote.Name
+		repo.keepUserSettings(remote)
+		repo.Categories = applyUserCategories(append(repo.Categories, remote.Categories...), repo.UserCategories, repo.ExcludedCategories)
+		for i := range rd.registry.Repositories {
+			if rd.registry.Repositories[i].registryKey() == remote.registryKey() {
+				rd.registry.Repositories[i] = *repo
+				return &rd.registry.Repositories[i], true, nil
+			}
+		}
+	}
+
+	if existing, err := rd.findRepository(repo.Name); err == nil {
+		return nil, false, errors.Errorf("repository name '%s' is already registered for %s; use 'wsm repos move' if it moved to %s", repo.Name, existing.Path, path)
+	}
+	rd.registry.Repositories = append(rd.registry.Repositories, *repo)
+	return &rd.registry.Repositories[len(rd.registry.Repositories)-1], true, nil
+}
+
+// registerRemoteLocation returns the registry entry with the remote URL,
+// registering a remote-only entry named after the URL when there is none
+func (rd *RepositoryDiscoverer) registerRemoteLocation(url string) (*Repository, bool) {
+	wanted := normalizeRemoteURL(url)
+	for i := range rd.registry.Repositories {
+		if existing := rd.registry.Repositories[i].RemoteURL; existing != "" && normalizeRemoteURL(existing) == wanted {
+			return &rd.registry.Repositories[i], false
+		}
+	}
+
+	name := strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	name = name[strings.LastIndexAny(name, "/:")+1:]
+	repo := Repository{Name: name, RemoteURL: url, LastUpdated: time.Now()}
+	// Another repository with the same name, e.g. a fork, keeps its name
+	if _, err := rd.findRepository(name); err == nil {
+		repo.Name = strings.ReplaceAll(wanted, "/", "-")
+	}
+	rd.registry.Repositories = append(rd.registry.Repositories, repo)
+	return &rd.registry.Repositories[len(rd.registry.Repositories)-1], true
+}
@@ -0,0 +1,160 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// RepositoryMove records a registered repository that moved on disk
+type RepositoryMove struct {
+	Name    string `json:"name"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+// RelinkResult reports how a moved repository was relinked in one workspace
+type RelinkResult struct {
+	Workspace string `json:"workspace"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MoveRepository points the registry entry of a repository at its new location
+func (rd *RepositoryDiscoverer) MoveRepository(name, newPath string) (*RepositoryMove, error) {
+	repo, err := rd.findRepository(name)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(newPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get absolute path for %s", newPath)
+	}
+	if !rd.isGitRepository(absPath) {
+		return nil, errors.Errorf("%s is not a git repository", absPath)
+	}
+	if absPath == repo.Path {
+		return nil, errors.Errorf("repository '%s' is already registered at %s", name, absPath)
+	}
+	for _, other := range rd.registry.Repositories {
+		if other.Path == absPath {
+			return nil, errors.Errorf("%s is already registered as '%s'", absPath, other.Name)
+		}
+	}
+
+	move := &RepositoryMove{Name: repo.Name, OldPath: repo.Path, NewPath: absPath}
+	repo.Path = absPath
+	return move, nil
+}
+
+// DetectedMoves returns the repositories the last discovery found at a new
+// path, matched to stale registry entries by remote URL
+func (rd *RepositoryDiscoverer) DetectedMoves() []RepositoryMove {
+	return rd.moves
+}
+
+// detectMove returns the stale registry entry a newly discovered repository
+// replaces: the only entry with the same remote URL whose path is gone
+func (rd *RepositoryDiscoverer) detectMove(existing []Repository, discovered Repository) (Repository, bool) {
+	if discovered.RemoteURL == "" {
+		return Repository{}, false
+	}
+
+	var candidates []Repository
+	for _, repo := range existing {
+		if repo.IsRemoteOnly() || rd.alreadyMoved(repo.Path) {
+			continue
+		}
+		if repo.RemoteURL == discovered.RemoteURL && repo.Path != discovered.Path && !rd.isGitRepository(repo.Path) {
+			candidates = append(candidates, repo)
+		}
+	}
+	if len(candidates) != 1 {
+		// Several clones of the same remote went missing: moving any of them would be a guess
+		return Repository{}, false
+	}
+	return candidates[0], true
+}
+
+func (rd *RepositoryDiscoverer) alreadyMoved(oldPath string) bool {
+	for _, move := range rd.moves {
+		if move.OldPath == oldPath {
+			return true
+		}
+	}
+	return false
+}
+
+// RelinkMovedRepository updates every workspace using a moved repository and
+// repairs the administrative links between the repository and its worktrees
+func (wm *WorkspaceManager) RelinkMovedRepository(ctx context.Context, move RepositoryMove) ([]RelinkResult, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+
+	var results []RelinkResult
+	for i := range workspaces {
+		workspace := &workspaces[i]
+		var worktrees []string
+		for j := range workspace.Repositories {
+			if workspace.Repositories[j].Path == move.OldPath {
+				workspace.Repositories[j].Path = move.NewPath
+				worktrees = append(worktrees, filepath.Join(workspace.Path, workspace.Repositories[j].Name))
+			}
+		}
+		pendingMoved := false
+		for j := range workspace.PendingRepositories {
+			if workspace.PendingRepositories[j].Repository.Path == move.OldPath {
+				workspace.PendingRepositories[j].Repository.Path = move.NewPath
+				pendingMoved = true
+			}
+		}
+		if len(worktrees) == 0 && !pendingMoved {
+			continue
+		}
+
+		result := RelinkResult{Workspace: workspace.Name}
+		if err := repairWorktrees(ctx, move.NewPath, worktrees); err != nil {
+			result.Error = err.Error()
+		}
+		if err := wm.SaveWorkspace(workspace); err != nil {
+			return results, errors.Wrapf(err, "failed to save workspace '%s'", workspace.Name)
+		}
+		if _, err := os.Stat(filepath.Join(workspace.Path, ".wsm")); err == nil {
+			if err := wm.createWorkspaceMetadata(workspace); err != nil {
+				output.LogWarn(
+					fmt.Sprintf("Failed to update metadata of workspace '%s'", workspace.Name),
+					"Failed to update workspace metadata after repository move",
+					"workspace", workspace.Name,
+					"error", err,
+				)
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// repairWorktrees runs `git worktree repair` from the moved repository, which
+// rewrites both the repository's pointers to the worktrees and the .git files
+// of the worktrees pointing back at it
+func repairWorktrees(ctx context.Context, repoPath string, worktrees []string) error {
+	var existing []string
+	for _, worktree := range worktrees {
+		if _, err := os.Stat(worktree); err == nil {
+			existing = append(existing, worktree)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	_, err := gitOutput(ctx, repoPath, append([]string{"worktree", "repair"}, existing...)...)
+	return err
+}
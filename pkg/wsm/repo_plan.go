@@ -0,0 +1,455 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Operations of a RepositoryPlan
+const (
+	RepositoryPlanAdd    = "add"
+	RepositoryPlanRemove = "remove"
+)
+
+// Approvals of a PlannedScript
+const (
+	ScriptApprovalAllowed = "allowed"
+	ScriptApprovalTrusted = "trusted"
+	ScriptApprovalAsk     = "ask"
+)
+
+// ManifestChange is the change of go.work or another workspace manifest, as
+// diff lines prefixed with " ", "-" or "+"
+type ManifestChange struct {
+	Path string   `json:"path"`
+	Diff []string `json:"diff"`
+}
+
+// PlannedScript is a setup script adding a repository would run. Approval is
+// "allowed" (the repository is in scripts.yaml), "trusted" (this content was
+// approved) or "ask" (the user is asked; skipped with --non-interactive).
+type PlannedScript struct {
+	Path     string `json:"path"`
+	Approval string `json:"approval"`
+}
+
+// RepositoryPlan previews adding a repository to or removing one from a
+// workspace, without changing anything
+type RepositoryPlan struct {
+	Operation  string `json:"operation"`
+	Workspace  string `json:"workspace"`
+	Repository string `json:"repository"`
+	// Path is the worktree of the repository in the workspace
+	Path   string `json:"path"`
+	Branch string `json:"branch,omitempty"`
+	// BranchAction tells what happens to Branch, e.g. "create from origin/feature"
+	BranchAction string     `json:"branch_action,omitempty"`
+	Steps        []PlanStep `json:"steps"`
+	// Manifests are the changes to go.work and the other workspace manifests
+	Manifests []ManifestChange `json:"manifests,omitempty"`
+	// Environment holds the WSM_* variables of setup scripts that are new or change
+	Environment  map[string]string `json:"environment,omitempty"`
+	SetupScripts []PlannedScript   `json:"setup_scripts,omitempty"`
+	// Work lists the work in the worktree a removal affects
+	Work []string `json:"work,omitempty"`
+	// Conflicts would make the operation fail or ask
+	Conflicts []string `json:"conflicts"`
+}
+
+// PlanAddRepository previews AddRepositoryToWorkspace with the same arguments.
+// The worktree decisions follow OnExistingBranch, OnCheckedOutBranch and
+// NoScripts of the manager; what the user would be asked is a conflict.
+func (wm *WorkspaceManager) PlanAddRepository(ctx context.Context, workspaceName, repoName, branchName string, forceOverwrite bool, sparsePaths []string, pin string) (*RepositoryPlan, error) {
+	workspace, err := wm.LoadWorkspace(workspaceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+	if IsRepositoryLocation(repoName) {
+		return nil, errors.Errorf("a plan needs the name of a registered repository, not '%s'", repoName)
+	}
+
+	plan := &RepositoryPlan{
+		Operation:  RepositoryPlanAdd,
+		Workspace:  workspace.Name,
+		Repository: repoName,
+		Path:       filepath.Join(workspace.Path, repoName),
+		Steps:      []PlanStep{},
+		Conflicts:  []string{},
+	}
+	for _, repo := range workspace.Repositories {
+		if repo.Name == repoName {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("repository '%s' is already in workspace '%s'", repoName, workspace.Name))
+			return plan, nil
+		}
+	}
+
+	repos, err := wm.FindRepositories([]string{repoName})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find repository '%s'", repoName)
+	}
+	if len(repos) == 0 {
+		return nil, errors.Errorf("repository '%s' not found in registry", repoName)
+	}
+	repo := repos[0]
+	if repo.IsRemoteOnly() {
+		resolved, err := wm.EnsureCloned(ctx, repos, true)
+		if err != nil {
+			return nil, err
+		}
+		repo = resolved[0]
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Clone %s", repo.Name),
+			Dir:         filepath.Dir(repo.Path),
+			Command:     []string{"git", "clone", repo.RemoteURL, repo.Path},
+		})
+	}
+
+	plan.Branch = branchName
+	if plan.Branch == "" {
+		plan.Branch = workspace.Branch
+	}
+	if len(sparsePaths) > 0 {
+		if workspace.RepositorySparsePaths == nil {
+			workspace.RepositorySparsePaths = map[string][]string{}
+		}
+		workspace.RepositorySparsePaths[repo.Name] = sparsePaths
+	}
+	if pin != "" {
+		if workspace.RepositoryPins == nil {
+			workspace.RepositoryPins = map[string]string{}
+		}
+		workspace.RepositoryPins[repo.Name] = pin
+	}
+
+	if _, err := os.Lstat(plan.Path); err == nil {
+		plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("target path '%s' already exists", plan.Path))
+	}
+	if isDir(repo.Path) {
+		plan.Steps = append(plan.Steps, wm.planAddWorktree(ctx, workspace, repo, plan, forceOverwrite))
+		plan.Steps = append(plan.Steps, planSparseCheckout(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planSubmoduleUpdate(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planLFS(workspace, repo)...)
+		plan.Steps = append(plan.Steps, planGitConfig(ctx, workspace, repo)...)
+		plan.Steps = append(plan.Steps, planHooks(ctx, workspace, repo)...)
+	} else {
+		plan.BranchAction = "decided once the repository is cloned"
+	}
+
+	after := *workspace
+	after.Repositories = append(append([]Repository{}, workspace.Repositories...), repo)
+	after.Ecosystems = append([]string{}, workspace.Ecosystems...)
+	for _, ecosystem := range detectEcosystems([]Repository{repo}) {
+		after.Ecosystems = appendUnique(after.Ecosystems, ecosystem)
+	}
+	moduleDir := func(r Repository) string {
+		if r.Name == repo.Name {
+			return repo.Path
+		}
+		return filepath.Join(workspace.Path, r.Name)
+	}
+	if plan.Manifests, err = wm.planManifestChanges(&after, moduleDir); err != nil {
+		return nil, err
+	}
+
+	plan.Environment = changedEnvironment(workspace, &after)
+	plan.Environment["WSM_ADDED_REPO"] = repo.Name
+	if !wm.NoScripts {
+		plan.SetupScripts = wm.planAddedRepositoryScripts(repo, plan.Path)
+	}
+
+	return plan, nil
+}
+
+// planAddWorktree mirrors CreateWorktreeForAdd, filling in the branch action
+// and the conflicts of the plan
+func (wm *WorkspaceManager) planAddWorktree(ctx context.Context, workspace *Workspace, repo Repository, plan *RepositoryPlan, forceOverwrite bool) PlanStep {
+	if workspace.PinFor(repo.Name) != "" {
+		plan.Branch = ""
+		plan.BranchAction = fmt.Sprintf("check out %s detached (pinned)", workspace.PinFor(repo.Name))
+		return planPinnedWorktree(workspace, repo)
+	}
+
+	branch := plan.Branch
+	step := PlanStep{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Create worktree for %s", repo.Name),
+		Dir:         repo.Path,
+	}
+	if branch == "" {
+		plan.BranchAction = "check out the current commit of the repository"
+		step.Command = worktreeAddCommand(workspace, repo.Name, plan.Path)
+		return step
+	}
+
+	branchExists, _ := wm.CheckBranchExists(ctx, repo.Path, branch)
+	remote := workspace.RemoteFor(repo)
+	remoteBranchExists, _ := wm.CheckRemoteBranchExists(ctx, repo.Path, remote, branch)
+	remoteBranch := remote + "/" + branch
+
+	if !branchExists {
+		if remoteBranchExists {
+			plan.BranchAction = "create from " + remoteBranch
+			step.Command = worktreeAddCommand(workspace, repo.Name, "-b", branch, plan.Path, remoteBranch)
+			return step
+		}
+		head, err := gitOutput(ctx, repo.Path, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			head = "HEAD"
+		}
+		plan.BranchAction = fmt.Sprintf("create from %s of %s", head, repo.Path)
+		step.Command = worktreeAddCommand(workspace, repo.Name, "-b", branch, plan.Path)
+		return step
+	}
+
+	if checkedOut := branchCheckedOutElsewhere(ctx, workspace, repo, branch); checkedOut != nil {
+		linkedBranch := LinkedBranchName(branch, workspace.Name)
+		switch wm.OnCheckedOutBranch {
+		case CheckedOutBranchReuse:
+			plan.BranchAction = fmt.Sprintf("reuse the worktree at %s", checkedOut.Path)
+			step.Description = fmt.Sprintf("Link the worktree of %s into the workspace", repo.Name)
+			step.Command = []string{"ln", "-s", checkedOut.Path, plan.Path}
+			return step
+		case CheckedOutBranchLink:
+			plan.Branch = linkedBranch
+			if exists, _ := wm.CheckBranchExists(ctx, repo.Path, linkedBranch); exists {
+				plan.BranchAction = "use the existing branch"
+				step.Command = worktreeAddCommand(workspace, repo.Name, plan.Path, linkedBranch)
+			} else {
+				plan.BranchAction = "create from " + branch
+				step.Command = worktreeAddCommand(workspace, repo.Name, "-b", linkedBranch, plan.Path, branch)
+			}
+			return step
+		}
+		plan.BranchAction = "checked out elsewhere"
+		plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("branch '%s' is already checked out in %s; pass --on-checked-out-branch=reuse or link", branch, checkedOut.Location))
+		return step
+	}
+
+	policy := wm.OnExistingBranch
+	if forceOverwrite {
+		policy = ExistingBranchOverwrite
+	}
+	switch policy {
+	case ExistingBranchOverwrite:
+		if remoteBranchExists {
+			plan.BranchAction = "reset the existing branch to " + remoteBranch
+			step.Command = worktreeAddCommand(workspace, repo.Name, "-B", branch, plan.Path, remoteBranch)
+		} else {
+			plan.BranchAction = "reset the existing branch to the current commit of the repository"
+			step.Command = worktreeAddCommand(workspace, repo.Name, "-B", branch, plan.Path)
+		}
+	case ExistingBranchUse:
+		plan.BranchAction = "use the existing branch"
+		step.Command = worktreeAddCommand(workspace, repo.Name, plan.Path, branch)
+	default:
+		plan.BranchAction = "exists already"
+		plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("branch '%s' already exists in %s; pass --force or --on-existing-branch=overwrite or use", branch, repo.Name))
+	}
+	return step
+}
+
+// planAddedRepositoryScripts lists the setup.d scripts of a repository as
+// they are in its checkout, with the path they get in the worktree
+func (wm *WorkspaceManager) planAddedRepositoryScripts(repo Repository, worktreePath string) []PlannedScript {
+	scripts, err := wm.getSetupDScripts(filepath.Join(repo.Path, ".wsm", "setup.d"), repo.Path)
+	if err != nil || len(scripts) == 0 {
+		return nil
+	}
+	sort.Slice(scripts, func(i, j int) bool {
+		return scripts[i].Name < scripts[j].Name
+	})
+
+	gate := planScriptGate()
+
+	planned := make([]PlannedScript, 0, len(scripts))
+	for _, script := range scripts {
+		relPath := filepath.ToSlash(filepath.Join(".wsm", "setup.d", script.Name))
+		approval, _, _ := gate.approval(repo, relPath, script.Path)
+		planned = append(planned, PlannedScript{Path: filepath.Join(worktreePath, relPath), Approval: approval})
+	}
+	return planned
+}
+
+// PlanRemoveRepository previews RemoveRepositoryFromWorkspace with the same arguments
+func (wm *WorkspaceManager) PlanRemoveRepository(ctx context.Context, workspaceName, repoName string, force, removeFiles bool) (*RepositoryPlan, error) {
+	workspace, err := wm.LoadWorkspace(workspaceName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	repoIndex := -1
+	for i, repo := range workspace.Repositories {
+		if repo.Name == repoName {
+			repoIndex = i
+			break
+		}
+	}
+	if repoIndex == -1 {
+		return nil, errors.Errorf("repository '%s' not found in workspace '%s'", repoName, workspaceName)
+	}
+	repo := workspace.Repositories[repoIndex]
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+
+	plan := &RepositoryPlan{
+		Operation:    RepositoryPlanRemove,
+		Workspace:    workspace.Name,
+		Repository:   repo.Name,
+		Path:         worktreePath,
+		Branch:       workspace.BranchFor(repo.Name),
+		BranchAction: "keep the branch",
+		Steps:        []PlanStep{},
+		Conflicts:    []string{},
+	}
+
+	command := []string{"git", "worktree", "remove", worktreePath}
+	if force {
+		command = []string{"git", "worktree", "remove", "--force", worktreePath}
+	}
+	plan.Steps = append(plan.Steps, PlanStep{
+		Action:      PlanActionCommand,
+		Description: fmt.Sprintf("Remove the worktree of %s", repo.Name),
+		Dir:         repo.Path,
+		Command:     command,
+	})
+	if removeFiles {
+		plan.Steps = append(plan.Steps, PlanStep{
+			Action:      PlanActionCommand,
+			Description: "Remove the repository directory",
+			Dir:         workspace.Path,
+			Command:     []string{"rm", "-rf", worktreePath},
+		})
+	}
+
+	preflight := wm.checkRepositoryPreflight(ctx, workspace, repo)
+	plan.Work = gcKeepReasons(&DeletePreflight{Repositories: []RepositoryPreflight{preflight}})
+	if !preflight.Missing && !force {
+		if untracked, _, err := wm.splitUntrackedFiles(ctx, worktreePath); err == nil && len(untracked) > 0 {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("%d untracked file(s) block the removal; pass --force to delete them", len(untracked)))
+		}
+		if preflight.ModifiedFiles > 0 {
+			plan.Conflicts = append(plan.Conflicts, fmt.Sprintf("%d modified file(s) block the removal; pass --force to discard them", preflight.ModifiedFiles))
+		}
+	}
+
+	after := *workspace
+	after.Repositories = append(append([]Repository{}, workspace.Repositories[:repoIndex]...), workspace.Repositories[repoIndex+1:]...)
+	if plan.Manifests, err = wm.planManifestChanges(&after, func(r Repository) string {
+		return filepath.Join(workspace.Path, r.Name)
+	}, repo.Name); err != nil {
+		return nil, err
+	}
+	plan.Environment = changedEnvironment(workspace, &after)
+
+	return plan, nil
+}
+
+// planManifestChanges diffs go.work and the other workspace manifests on disk
+// against their content for the repositories of after. moduleDir is where a
+// repository's go.mod, package.json or Cargo.toml is looked for.
+func (wm *WorkspaceManager) planManifestChanges(after *Workspace, moduleDir func(repo Repository) string, removedRepos ...string) ([]ManifestChange, error) {
+	var changes []ManifestChange
+	addChange := func(path string, content []byte) {
+		current, _ := os.ReadFile(path)
+		if string(current) != string(content) {
+			changes = append(changes, ManifestChange{Path: path, Diff: diffLines(string(current), string(content))})
+		}
+	}
+
+	if after.GoWorkspace {
+		content, err := wm.renderGoWork(after, moduleDir, removedRepos...)
+		if err != nil {
+			return nil, err
+		}
+		addChange(filepath.Join(after.Path, "go.work"), content)
+	}
+
+	for _, manifest := range workspaceManifests {
+		if !containsValue(after.Ecosystems, manifest.Ecosystem) {
+			continue
+		}
+		var members []string
+		for _, repo := range after.Repositories {
+			if _, err := os.Stat(filepath.Join(moduleDir(repo), manifest.MemberFile)); err == nil {
+				members = append(members, repo.Name)
+			}
+		}
+
+		path := manifest.File(after)
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			if len(members) > 0 {
+				addChange(path, []byte(manifest.Render(path, members)))
+			}
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to read %s", path)
+		default:
+			updated, err := manifest.Update(data, members, removedRepos)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to update %s", filepath.Base(path))
+			}
+			addChange(path, updated)
+		}
+	}
+	return changes, nil
+}
+
+// changedEnvironment returns the WSM_* variables of after that are new or
+// differ from before
+func changedEnvironment(before, after *Workspace) map[string]string {
+	previous := workspaceEnvironment(before)
+	changed := map[string]string{}
+	for key, value := range workspaceEnvironment(after) {
+		if previous[key] != value {
+			changed[key] = value
+		}
+	}
+	return changed
+}
+
+// diffLines is a line diff of two texts, each line prefixed with " " when
+// kept, "-" when removed or "+" when added
+func diffLines(before, after string) []string {
+	a := splitLines(strings.TrimSuffix(before, "\n"))
+	b := splitLines(strings.TrimSuffix(after, "\n"))
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff = append(diff, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	return diff
+}
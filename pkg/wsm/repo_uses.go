@@ -0,0 +1,110 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// RepositoryUse is a worktree of a repository and the workspace it belongs to
+type RepositoryUse struct {
+	Repository string `json:"repository"`
+	// Workspace is empty for worktrees created outside wsm
+	Workspace    string `json:"workspace,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	WorktreePath string `json:"worktree_path"`
+	// Missing is set when the worktree directory no longer exists
+	Missing bool `json:"missing,omitempty"`
+}
+
+// RepositoryUsageIndex lists the worktrees of each repository, keyed by repository name
+type RepositoryUsageIndex map[string][]RepositoryUse
+
+// BuildRepositoryUsageIndex indexes the worktrees of the repositories used by
+// workspaces. Branches are read from git, so branches switched inside a
+// worktree show up; worktrees git no longer knows fall back to the workspace
+// configuration.
+func BuildRepositoryUsageIndex(ctx context.Context, workspaces []Workspace) RepositoryUsageIndex {
+	// Repository name -> source repository paths, usually one
+	sources := make(map[string][]string)
+	for _, workspace := range workspaces {
+		for _, repo := range workspace.Repositories {
+			sources[repo.Name] = appendUnique(sources[repo.Name], repo.Path)
+		}
+	}
+
+	index := make(RepositoryUsageIndex)
+	for name, paths := range sources {
+		seen := make(map[string]bool)
+		for _, repoPath := range paths {
+			worktrees, err := ListGitWorktrees(ctx, repoPath)
+			if err != nil {
+				continue
+			}
+			for _, worktree := range worktrees {
+				if worktree.Main {
+					continue
+				}
+				use := RepositoryUse{
+					Repository:   name,
+					Branch:       worktree.Branch,
+					WorktreePath: worktree.Path,
+					Missing:      worktree.Prunable,
+				}
+				if workspace, err := DetectWorkspace(worktree.Path, workspaces); err == nil {
+					use.Workspace = workspace.Name
+				}
+				seen[resolvePath(worktree.Path)] = true
+				index[name] = append(index[name], use)
+			}
+		}
+
+		for _, workspace := range workspaces {
+			for _, repo := range workspace.Repositories {
+				worktreePath := filepath.Join(workspace.Path, repo.Name)
+				if repo.Name != name || seen[resolvePath(worktreePath)] {
+					continue
+				}
+				_, err := os.Stat(worktreePath)
+				index[name] = append(index[name], RepositoryUse{
+					Repository:   name,
+					Workspace:    workspace.Name,
+					Branch:       workspace.Branch,
+					WorktreePath: worktreePath,
+					Missing:      os.IsNotExist(err),
+				})
+			}
+		}
+
+		uses := index[name]
+		sort.Slice(uses, func(i, j int) bool {
+			if uses[i].Workspace != uses[j].Workspace {
+				return uses[i].Workspace < uses[j].Workspace
+			}
+			return uses[i].WorktreePath < uses[j].WorktreePath
+		})
+	}
+
+	return index
+}
+
+// RepositoryUsage returns the worktrees of every repository used by a workspace
+func (s *WorkspaceService) RepositoryUsage(ctx context.Context) (RepositoryUsageIndex, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	return BuildRepositoryUsageIndex(ctx, workspaces), nil
+}
+
+// RepositoryUses returns the worktrees of one repository
+func (s *WorkspaceService) RepositoryUses(ctx context.Context, repoName string) ([]RepositoryUse, error) {
+	index, err := s.RepositoryUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index[repoName], nil
+}
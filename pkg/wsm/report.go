@@ -0,0 +1,137 @@
+package wsm
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ActivityReport aggregates the commits made in a workspace over a period,
+// e.g. for standups or progress summaries
+type ActivityReport struct {
+	Workspace string    `json:"workspace"`
+	Branch    string    `json:"branch"`
+	Since     time.Time `json:"since"`
+	// Filter is the repository filter the workspace was narrowed to, if any
+	Filter       *RepositoryFilter    `json:"filter,omitempty"`
+	TotalCommits int                  `json:"total_commits"`
+	FilesChanged int                  `json:"files_changed"`
+	Insertions   int                  `json:"insertions"`
+	Deletions    int                  `json:"deletions"`
+	Authors      []AuthorActivity     `json:"authors"`
+	Repositories []RepositoryActivity `json:"repositories"`
+	// Commits are listed newest first
+	Commits []LogEntry `json:"commits"`
+}
+
+// AuthorActivity is the activity of one author in a workspace
+type AuthorActivity struct {
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	Commits      int      `json:"commits"`
+	Repositories []string `json:"repositories"`
+}
+
+// RepositoryActivity is the activity in one repository of a workspace
+type RepositoryActivity struct {
+	Repository string   `json:"repository"`
+	Commits    int      `json:"commits"`
+	Authors    []string `json:"authors"`
+	// FilesChanged counts distinct files, a file changed by several commits counts once
+	FilesChanged int       `json:"files_changed"`
+	Insertions   int       `json:"insertions"`
+	Deletions    int       `json:"deletions"`
+	LastCommit   time.Time `json:"last_commit,omitempty"`
+}
+
+// GetActivityReport aggregates the commits of the workspace repositories (or of
+// one repository) since a point in time. Repositories without commits are
+// listed too, so idle ones show up in the report.
+func (so *SyncOperations) GetActivityReport(ctx context.Context, since time.Time, repository string) (*ActivityReport, error) {
+	entries, err := so.GetWorkspaceCommits(ctx, LogOptions{
+		Since:      since.Format(time.RFC3339),
+		Repository: repository,
+		Stats:      true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read workspace history")
+	}
+	if entries == nil {
+		entries = []LogEntry{}
+	}
+
+	report := &ActivityReport{
+		Workspace:    so.workspace.Name,
+		Branch:       so.workspace.Branch,
+		Since:        since,
+		Filter:       so.workspace.Filter,
+		TotalCommits: len(entries),
+		Authors:      []AuthorActivity{},
+		Repositories: []RepositoryActivity{},
+		Commits:      entries,
+	}
+
+	repos := make(map[string]*RepositoryActivity)
+	repoFiles := make(map[string]map[string]bool)
+	for _, repo := range so.workspace.Repositories {
+		if repository != "" && repo.Name != repository {
+			continue
+		}
+		repos[repo.Name] = &RepositoryActivity{Repository: repo.Name, Authors: []string{}}
+		repoFiles[repo.Name] = make(map[string]bool)
+	}
+
+	authors := make(map[string]*AuthorActivity)
+	for _, entry := range entries {
+		author, ok := authors[entry.AuthorEmail]
+		if !ok {
+			author = &AuthorActivity{Name: entry.Author, Email: entry.AuthorEmail}
+			authors[entry.AuthorEmail] = author
+		}
+		author.Commits++
+		author.Repositories = appendUnique(author.Repositories, entry.Repository)
+
+		repo := repos[entry.Repository]
+		repo.Commits++
+		repo.Authors = appendUnique(repo.Authors, entry.Author)
+		repo.Insertions += entry.Insertions
+		repo.Deletions += entry.Deletions
+		if entry.Date.After(repo.LastCommit) {
+			repo.LastCommit = entry.Date
+		}
+		for _, file := range entry.Files {
+			repoFiles[entry.Repository][file] = true
+		}
+	}
+
+	for name, repo := range repos {
+		repo.FilesChanged = len(repoFiles[name])
+		report.FilesChanged += repo.FilesChanged
+		report.Insertions += repo.Insertions
+		report.Deletions += repo.Deletions
+		report.Repositories = append(report.Repositories, *repo)
+	}
+	sort.Slice(report.Repositories, func(i, j int) bool {
+		a, b := report.Repositories[i], report.Repositories[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Repository < b.Repository
+	})
+
+	for _, author := range authors {
+		sort.Strings(author.Repositories)
+		report.Authors = append(report.Authors, *author)
+	}
+	sort.Slice(report.Authors, func(i, j int) bool {
+		a, b := report.Authors[i], report.Authors[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Name < b.Name
+	})
+
+	return report, nil
+}
@@ -0,0 +1,237 @@
+package wsm
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// Scaffold templates are directories of files copied into new workspaces, e.g.
+// notes/, tickets/ and design.md next to the repository worktrees. They live in
+// the template directory (~/templates/<name>); "default" is applied to every new
+// workspace when it exists.
+const (
+	DefaultScaffold = "default"
+	// NoScaffold disables scaffolding, including the default template
+	NoScaffold = "none"
+	// scaffoldTemplateSuffix marks files rendered with the workspace variables
+	scaffoldTemplateSuffix = ".tmpl"
+)
+
+// ScaffoldVars are the variables available to .tmpl files of a scaffold template
+type ScaffoldVars struct {
+	Name         string
+	Path         string
+	Branch       string
+	BaseBranch   string
+	Repositories []string
+	Date         string
+}
+
+// ResolveScaffold returns the directory of a scaffold template given by name
+// (looked up in the template directory) or path. An empty spec resolves to the
+// default template if there is one, NoScaffold to nothing.
+func (wm *WorkspaceManager) ResolveScaffold(spec string) (string, error) {
+	switch {
+	case spec == NoScaffold:
+		return "", nil
+	case spec == "":
+		dir := filepath.Join(wm.config.TemplateDir, DefaultScaffold)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+		return "", nil
+	}
+
+	dir := filepath.Join(wm.config.TemplateDir, spec)
+	if strings.HasPrefix(spec, "~") || strings.HasPrefix(spec, ".") || filepath.IsAbs(spec) || strings.ContainsRune(spec, filepath.Separator) {
+		abs, err := filepath.Abs(expandHome(spec))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve scaffold %s", spec)
+		}
+		dir = abs
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", errors.Errorf("scaffold template '%s' not found (looked for directory %s)", spec, dir)
+	}
+	return dir, nil
+}
+
+// scaffoldVars returns the template variables of a workspace
+func scaffoldVars(workspace *Workspace) ScaffoldVars {
+	vars := ScaffoldVars{
+		Name:       workspace.Name,
+		Path:       workspace.Path,
+		Branch:     workspace.Branch,
+		BaseBranch: workspace.BaseBranch,
+		Date:       workspace.Created.Format("2006-01-02"),
+	}
+	if workspace.Created.IsZero() {
+		vars.Date = time.Now().Format("2006-01-02")
+	}
+	for _, repo := range workspace.Repositories {
+		vars.Repositories = append(vars.Repositories, repo.Name)
+	}
+	return vars
+}
+
+// applyScaffold copies the scaffold template of a workspace into its directory.
+// Files ending in .tmpl are rendered with ScaffoldVars and lose the suffix.
+// Existing files and repository worktrees are never overwritten.
+func (wm *WorkspaceManager) applyScaffold(workspace *Workspace) error {
+	source := workspace.Scaffold
+	vars := scaffoldVars(workspace)
+	repoNames := make(map[string]bool)
+	for _, repo := range workspace.Repositories {
+		repoNames[repo.Name] = true
+	}
+
+	var created []string
+	err := filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if repoNames[strings.Split(filepath.ToSlash(rel), "/")[0]] {
+			output.PrintWarning("Scaffold entry %s clashes with a repository, skipped", rel)
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(workspace.Path, strings.TrimSuffix(rel, scaffoldTemplateSuffix))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if _, err := os.Lstat(target); err == nil {
+			output.LogInfo(
+				fmt.Sprintf("Scaffold file %s already exists, kept", target),
+				"Scaffold file exists, not overwritten",
+				"target", target,
+			)
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		if strings.HasSuffix(rel, scaffoldTemplateSuffix) {
+			if data, err = renderScaffoldFile(rel, data, vars); err != nil {
+				return err
+			}
+		}
+		mode := os.FileMode(0644)
+		if info, err := entry.Info(); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(target, data, mode); err != nil {
+			return errors.Wrapf(err, "failed to write %s", target)
+		}
+		created = append(created, rel)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to apply scaffold %s", source)
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Scaffolded %d file(s) from %s", len(created), source),
+		"Applied scaffold template",
+		"workspace", workspace.Name,
+		"scaffold", source,
+		"files", created,
+	)
+	return nil
+}
+
+// renderScaffoldFile renders a .tmpl file of a scaffold template
+func renderScaffoldFile(name string, data []byte, vars ScaffoldVars) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse scaffold template %s", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, errors.Wrapf(err, "failed to render scaffold template %s", name)
+	}
+	return buf.Bytes(), nil
+}
+
+// planScaffold returns the steps copying the scaffold template of a workspace
+func planScaffold(workspace *Workspace) []PlanStep {
+	if workspace.Scaffold == "" {
+		return nil
+	}
+	vars := scaffoldVars(workspace)
+
+	var steps []PlanStep
+	_ = filepath.WalkDir(workspace.Scaffold, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(workspace.Scaffold, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		target := filepath.Join(workspace.Path, strings.TrimSuffix(rel, scaffoldTemplateSuffix))
+		switch {
+		case entry.IsDir():
+			steps = append(steps, PlanStep{
+				Action:      PlanActionMkdir,
+				Description: fmt.Sprintf("Create scaffold directory %s", rel),
+				Path:        target,
+			})
+		case strings.HasSuffix(rel, scaffoldTemplateSuffix):
+			step := PlanStep{
+				Action:      PlanActionWriteFile,
+				Description: fmt.Sprintf("Render scaffold template %s", rel),
+				Path:        target,
+			}
+			if data, err := os.ReadFile(path); err != nil {
+				step.Note = err.Error()
+			} else if rendered, err := renderScaffoldFile(rel, data, vars); err != nil {
+				step.Note = err.Error()
+			} else {
+				step.Content = string(rendered)
+			}
+			steps = append(steps, step)
+		default:
+			steps = append(steps, PlanStep{
+				Action:      PlanActionCopyFile,
+				Description: fmt.Sprintf("Copy scaffold file %s", rel),
+				Source:      path,
+				Path:        target,
+			})
+		}
+		return nil
+	})
+	return steps
+}
+
+// ScaffoldNames returns the scaffold templates of the template directory
+func (wm *WorkspaceManager) ScaffoldNames() []string {
+	entries, err := os.ReadDir(wm.config.TemplateDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
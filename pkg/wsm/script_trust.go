@@ -0,0 +1,300 @@
+package wsm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/go-go-golems/workspace-manager/pkg/ux"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Answers to the question of running an untrusted setup script
+const (
+	scriptTrustAlways = "trust"
+	scriptTrustOnce   = "once"
+	scriptTrustSkip   = "skip"
+)
+
+// ScriptsConfig lists the repositories whose setup scripts and git hooks run
+// without confirmation. It is read from scripts.yaml in the workspace-manager config
+// directory:
+//
+//	allow:
+//	  - github.com/acme/my-app
+//	  - github.com/acme/*
+//	  - scratch
+//
+// Entries with a slash match the origin URL of a repository, reduced by
+// normalizeRemoteURL. Bare names only match repositories without an origin,
+// since any clone can take the name of a trusted repository.
+type ScriptsConfig struct {
+	// Allow holds origin URLs, repository names or glob patterns of either
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+
+	path string
+}
+
+// GetScriptsConfigPath returns the path of the setup script configuration file
+func GetScriptsConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "scripts.yaml"), nil
+}
+
+// LoadScriptsConfig loads scripts.yaml, returning an empty allowlist if none exists
+func LoadScriptsConfig() (*ScriptsConfig, error) {
+	configPath, err := GetScriptsConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ScriptsConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read setup script configuration")
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse setup script configuration %s", configPath)
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *ScriptsConfig) Path() string {
+	return c.path
+}
+
+// Allows reports whether the setup scripts and git hooks of a repository are trusted
+func (c *ScriptsConfig) Allows(repo Repository) bool {
+	origin := normalizeRemoteURL(repo.RemoteURL)
+	for _, pattern := range c.Allow {
+		if strings.Contains(pattern, "/") {
+			if origin == "" {
+				continue
+			}
+			if matched, _ := path.Match(normalizeRemoteURL(pattern), origin); matched {
+				return true
+			}
+			continue
+		}
+		if origin != "" {
+			continue
+		}
+		if matched, _ := filepath.Match(pattern, repo.Name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedScript is a setup script or git hook the user approved, pinned to its content
+type TrustedScript struct {
+	SHA256    string    `json:"sha256"`
+	TrustedAt time.Time `json:"trusted_at"`
+}
+
+// ScriptTrust holds the approved setup scripts and git hooks by repository and
+// path in the repository. A script whose content changed is asked about again.
+type ScriptTrust struct {
+	Scripts map[string]TrustedScript `json:"scripts"`
+
+	path string
+}
+
+func getScriptTrustPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "script-trust.json"), nil
+}
+
+// LoadScriptTrust loads the approved setup scripts
+func LoadScriptTrust() (*ScriptTrust, error) {
+	trustPath, err := getScriptTrustPath()
+	if err != nil {
+		return nil, err
+	}
+
+	trust := &ScriptTrust{Scripts: map[string]TrustedScript{}, path: trustPath}
+	data, err := os.ReadFile(trustPath)
+	if os.IsNotExist(err) {
+		return trust, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read setup script trust")
+	}
+	if err := json.Unmarshal(data, trust); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse setup script trust %s", trustPath)
+	}
+	if trust.Scripts == nil {
+		trust.Scripts = map[string]TrustedScript{}
+	}
+	return trust, nil
+}
+
+func (t *ScriptTrust) save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal setup script trust")
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+	return errors.Wrap(os.WriteFile(t.path, data, 0644), "failed to write setup script trust")
+}
+
+// setupScriptRepository returns the repository a setup script comes from and
+// its path in the repository; no repository means a workspace script
+func setupScriptRepository(workspace *Workspace, script SetupScript) (*Repository, string) {
+	for i := range workspace.Repositories {
+		repo := &workspace.Repositories[i]
+		repoPath := filepath.Join(workspace.Path, repo.Name)
+		if !PathWithin(script.Path, repoPath) {
+			continue
+		}
+		relPath, err := filepath.Rel(repoPath, script.Path)
+		if err != nil {
+			relPath = script.Path
+		}
+		return repo, filepath.ToSlash(relPath)
+	}
+	return nil, ""
+}
+
+// setupScriptGate decides which setup scripts of a run may execute and which
+// git hooks of repositories may be deployed
+type setupScriptGate struct {
+	config *ScriptsConfig
+	trust  *ScriptTrust
+}
+
+// planScriptGate returns the gate of plans, which ignores an unreadable
+// allowlist or trust file without warning
+func planScriptGate() *setupScriptGate {
+	config, err := LoadScriptsConfig()
+	if err != nil {
+		config = &ScriptsConfig{}
+	}
+	trust, err := LoadScriptTrust()
+	if err != nil {
+		trust = &ScriptTrust{Scripts: map[string]TrustedScript{}}
+	}
+	return &setupScriptGate{config: config, trust: trust}
+}
+
+func newSetupScriptGate() *setupScriptGate {
+	config, err := LoadScriptsConfig()
+	if err != nil {
+		output.PrintWarning("Ignoring setup script allowlist: %v", err)
+		config = &ScriptsConfig{}
+	}
+	trust, err := LoadScriptTrust()
+	if err != nil {
+		// Without a path, approvals are not saved over the unreadable file
+		output.PrintWarning("Ignoring approved setup scripts: %v", err)
+		trust = &ScriptTrust{Scripts: map[string]TrustedScript{}}
+	}
+	return &setupScriptGate{config: config, trust: trust}
+}
+
+// approval decides without asking whether the file at path, relPath in repo,
+// may run: ScriptApprovalAllowed when the repository is in the allowlist,
+// ScriptApprovalTrusted when this content was approved and ScriptApprovalAsk
+// otherwise. The content hash is returned for pinning.
+func (g *setupScriptGate) approval(repo Repository, relPath, path string) (string, string, error) {
+	if g.config.Allows(repo) {
+		return ScriptApprovalAllowed, "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ScriptApprovalAsk, "", err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if pinned, ok := g.trust.Scripts[repo.Name+":"+relPath]; ok && pinned.SHA256 == hash {
+		return ScriptApprovalTrusted, hash, nil
+	}
+	return ScriptApprovalAsk, hash, nil
+}
+
+// allows reports whether a setup script may run. Workspace scripts are the
+// user's own. Repository scripts run when the repository's origin is in the
+// allowlist or this content was approved; otherwise the user is asked, and in
+// non-interactive mode the script is skipped.
+func (g *setupScriptGate) allows(workspace *Workspace, script SetupScript) bool {
+	return g.allowsFile(workspace, script.Path, "setup script")
+}
+
+// allowsFile applies the rules of allows to any file of a repository that
+// runs as a command, kind naming it in messages
+func (g *setupScriptGate) allowsFile(workspace *Workspace, path, kind string) bool {
+	repo, relPath := setupScriptRepository(workspace, SetupScript{Path: path})
+	if repo == nil {
+		return true
+	}
+	repoName := repo.Name
+
+	approval, hash, err := g.approval(*repo, relPath, path)
+	if os.IsNotExist(err) {
+		// Nothing runs, executeSetupScript skips it
+		return true
+	}
+	if err != nil {
+		output.PrintWarning("Skipping %s %s: %v", kind, path, err)
+		return false
+	}
+	if approval != ScriptApprovalAsk {
+		return true
+	}
+
+	key := repoName + ":" + relPath
+	_, known := g.trust.Scripts[key]
+
+	if nonInteractive {
+		output.PrintWarning("Skipping untrusted %s %s of %s; allow the repository in scripts.yaml or run it interactively", kind, relPath, repoName)
+		return false
+	}
+	if known {
+		output.PrintWarning("The %s %s of %s changed since it was trusted", kind, relPath, repoName)
+	}
+	output.PrintInfo("Review it before running it: %s (sha256 %s)", path, hash[:12])
+	choice, err := ux.Select(fmt.Sprintf("Run %s %s of repository %s?", kind, relPath, repoName), []ux.Option{
+		ux.NewOption("Run it and trust this version", scriptTrustAlways),
+		ux.NewOption("Run it once", scriptTrustOnce),
+		ux.NewOption("Skip it", scriptTrustSkip),
+	})
+	if err != nil {
+		choice = scriptTrustSkip
+	}
+
+	switch choice {
+	case scriptTrustAlways:
+		g.trust.Scripts[key] = TrustedScript{SHA256: hash, TrustedAt: time.Now()}
+		if g.trust.path != "" {
+			if err := g.trust.save(); err != nil {
+				output.PrintWarning("Failed to remember the approval of %s: %v", relPath, err)
+			}
+		}
+		return true
+	case scriptTrustOnce:
+		return true
+	default:
+		output.PrintInfo("Skipped %s %s of %s", kind, relPath, repoName)
+		return false
+	}
+}
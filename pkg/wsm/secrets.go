@@ -0,0 +1,319 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of the entropy rule
+const (
+	DefaultSecretEntropyThreshold = 4.5
+	DefaultSecretEntropyMinLength = 20
+)
+
+// SecretRule flags added lines matching a regular expression
+type SecretRule struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// defaultSecretRules are checked unless secrets.yaml sets disable-default-rules
+var defaultSecretRules = []SecretRule{
+	{Name: "aws-access-key-id", Pattern: `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-access-key", Pattern: `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`},
+	{Name: "private-key", Pattern: `-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`},
+	{Name: "github-token", Pattern: `\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`},
+	{Name: "slack-token", Pattern: `\bxox[abposr]-[A-Za-z0-9-]{10,}`},
+}
+
+// defaultSecretIgnorePaths are lock and checksum files full of high-entropy hashes
+var defaultSecretIgnorePaths = []string{"go.sum", "*.lock", "package-lock.json", "pnpm-lock.yaml"}
+
+// secretKeywordPattern selects the lines the entropy rule looks at
+var secretKeywordPattern = regexp.MustCompile(`(?i)(key|secret|token|passw(or)?d|credential|auth)`)
+
+// secretTokenPattern extracts candidate values for the entropy rule
+var secretTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_\-]+`)
+
+// SecretsConfig configures the secret scan run before commits.
+// It is read from secrets.yaml in the workspace-manager config directory:
+//
+//	rules:
+//	  - name: internal-token
+//	    pattern: "itk_[A-Za-z0-9]{32}"
+//	entropy-threshold: 4.5   # negative disables the entropy rule
+//	entropy-min-length: 20
+//	allow: ["EXAMPLE", "dummy"]
+//	ignore-paths: ["testdata/*"]
+type SecretsConfig struct {
+	// Rules are checked in addition to the built-in rules
+	Rules []SecretRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// DisableDefaultRules drops the built-in rules
+	DisableDefaultRules bool `yaml:"disable-default-rules,omitempty" json:"disable_default_rules"`
+	// EntropyThreshold flags values of lines mentioning a key, token, password...
+	// whose Shannon entropy in bits per character reaches it
+	EntropyThreshold float64 `yaml:"entropy-threshold,omitempty" json:"entropy_threshold"`
+	// EntropyMinLength is the minimum length of values checked for entropy
+	EntropyMinLength int `yaml:"entropy-min-length,omitempty" json:"entropy_min_length"`
+	// Allow lists regular expressions; matching lines are never flagged
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// IgnorePaths lists file name or path globs that are not scanned
+	IgnorePaths []string `yaml:"ignore-paths,omitempty" json:"ignore_paths,omitempty"`
+
+	allow []*regexp.Regexp
+	path  string
+}
+
+// SecretFinding is a possible secret in a staged change
+type SecretFinding struct {
+	Repository string `json:"repository"`
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Rule       string `json:"rule"`
+	Match      string `json:"match"` // redacted
+}
+
+// SecretsFoundError blocks a commit whose staged changes contain possible secrets
+type SecretsFoundError struct {
+	Findings []SecretFinding
+}
+
+func (e *SecretsFoundError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "possible secrets in staged changes (commit with --allow-secrets if these are false positives):")
+	repo := ""
+	for _, finding := range e.Findings {
+		if finding.Repository != repo {
+			repo = finding.Repository
+			fmt.Fprintf(&b, "\n  %s:", repo)
+		}
+		location := finding.File
+		if finding.Line > 0 {
+			location += ":" + strconv.Itoa(finding.Line)
+		}
+		fmt.Fprintf(&b, "\n    %s  %s  %s", location, finding.Rule, finding.Match)
+	}
+	return b.String()
+}
+
+// GetSecretsConfigPath returns the path of the secret scan configuration
+func GetSecretsConfigPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "secrets.yaml"), nil
+}
+
+// LoadSecretsConfig loads the secret scan configuration, using the built-in
+// rules if none exists
+func LoadSecretsConfig() (*SecretsConfig, error) {
+	configPath, err := GetSecretsConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &SecretsConfig{path: configPath}
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read secrets configuration")
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", configPath)
+		}
+	}
+
+	if !config.DisableDefaultRules {
+		config.Rules = append(append([]SecretRule{}, defaultSecretRules...), config.Rules...)
+	}
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.re, err = regexp.Compile(rule.Pattern); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern of secret rule '%s' in %s", rule.Name, configPath)
+		}
+	}
+	for _, pattern := range config.Allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid allow pattern '%s' in %s", pattern, configPath)
+		}
+		config.allow = append(config.allow, re)
+	}
+	if config.EntropyThreshold == 0 {
+		config.EntropyThreshold = DefaultSecretEntropyThreshold
+	}
+	if config.EntropyMinLength == 0 {
+		config.EntropyMinLength = DefaultSecretEntropyMinLength
+	}
+
+	return config, nil
+}
+
+// Path returns the file the configuration was read from
+func (c *SecretsConfig) Path() string {
+	return c.path
+}
+
+// ScanStaged scans the lines added by the staged changes of a repository
+func (c *SecretsConfig) ScanStaged(ctx context.Context, repoName, repoPath string) ([]SecretFinding, error) {
+	diff, err := gitOutput(ctx, repoPath, "diff", "--cached", "--unified=0", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get staged changes of %s", repoName)
+	}
+	findings := c.ScanDiff(diff)
+	for i := range findings {
+		findings[i].Repository = repoName
+	}
+	return findings, nil
+}
+
+// ScanDiff scans the lines added by a unified diff
+func (c *SecretsConfig) ScanDiff(diff string) []SecretFinding {
+	var findings []SecretFinding
+	file, line, skip, header := "", 0, false, false
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			header = true
+		case header && strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			skip = file == "/dev/null" || c.ignored(file)
+			if !skip && isDotenvFile(file) {
+				findings = append(findings, SecretFinding{File: file, Rule: "dotenv-file", Match: "environment file staged"})
+				skip = true
+			}
+		case strings.HasPrefix(text, "@@ "):
+			header = false
+			line = hunkStartLine(text)
+		case !header && strings.HasPrefix(text, "+"):
+			if !skip {
+				findings = append(findings, c.scanLine(file, line, text[1:])...)
+			}
+			line++
+		}
+	}
+	return findings
+}
+
+func (c *SecretsConfig) scanLine(file string, line int, text string) []SecretFinding {
+	for _, re := range c.allow {
+		if re.MatchString(text) {
+			return nil
+		}
+	}
+
+	var findings []SecretFinding
+	for _, rule := range c.Rules {
+		if match := rule.re.FindString(text); match != "" {
+			findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule.Name, Match: redactSecret(match)})
+		}
+	}
+	if len(findings) > 0 || c.EntropyThreshold < 0 || !secretKeywordPattern.MatchString(text) {
+		return findings
+	}
+
+	for _, token := range secretTokenPattern.FindAllString(text, -1) {
+		if len(token) >= c.EntropyMinLength && shannonEntropy(token) >= c.EntropyThreshold {
+			return append(findings, SecretFinding{File: file, Line: line, Rule: "high-entropy-value", Match: redactSecret(token)})
+		}
+	}
+	return findings
+}
+
+func (c *SecretsConfig) ignored(file string) bool {
+	for _, pattern := range append(append([]string{}, defaultSecretIgnorePaths...), c.IgnorePaths...) {
+		if matched, _ := path.Match(pattern, file); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(file)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isDotenvFile reports whether a file is an environment file, except templates
+func isDotenvFile(file string) bool {
+	name := path.Base(file)
+	if name != ".env" && !strings.HasPrefix(name, ".env.") {
+		return false
+	}
+	switch strings.TrimPrefix(name, ".env.") {
+	case "example", "sample", "template", "dist":
+		return false
+	}
+	return true
+}
+
+// hunkStartLine returns the first new-file line of a "@@ -a,b +c,d @@" header
+func hunkStartLine(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redactSecret keeps the first characters of a match so it can be found again
+func redactSecret(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return match[:4] + strings.Repeat("*", min(len(match)-4, 12))
+}
+
+// checkStagedSecrets scans the staged changes of repositories and fails with
+// a SecretsFoundError listing every finding
+func (gops *GitOperations) checkStagedSecrets(ctx context.Context, repoNames []string) error {
+	config, err := LoadSecretsConfig()
+	if err != nil {
+		return err
+	}
+
+	var findings []SecretFinding
+	for _, repoName := range repoNames {
+		repoFindings, err := config.ScanStaged(ctx, repoName, filepath.Join(gops.workspace.Path, repoName))
+		if err != nil {
+			return err
+		}
+		findings = append(findings, repoFindings...)
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Repository < findings[j].Repository
+	})
+	return &SecretsFoundError{Findings: findings}
+}
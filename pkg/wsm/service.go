@@ -0,0 +1,336 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WorkspaceService exposes workspace operations to servers (wsm serve) with
+// request and result types instead of prompts and terminal output. Mutating
+// operations are recorded in the audit log.
+type WorkspaceService struct {
+	// Actor is recorded in audit events when WSM_ACTOR is not set, e.g. "mcp"
+	Actor string
+}
+
+// NewWorkspaceService creates a workspace service recording actor in audit events
+func NewWorkspaceService(actor string) *WorkspaceService {
+	return &WorkspaceService{Actor: actor}
+}
+
+// CreateWorkspaceRequest describes a workspace to create
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+	// Repositories are registered names, @groups, paths to clones or remote
+	// URLs; paths and URLs are registered on the fly
+	Repositories []string `json:"repositories"`
+	// Branch defaults to task/<name>
+	Branch     string `json:"branch,omitempty"`
+	BaseBranch string `json:"base_branch,omitempty"`
+	// Sanitize replaces characters not allowed in the name and branch
+	// instead of rejecting them
+	Sanitize bool `json:"sanitize,omitempty"`
+}
+
+// DeleteWorkspaceRequest describes how to delete a workspace
+type DeleteWorkspaceRequest struct {
+	Name string `json:"name"`
+	// Trash moves the workspace to the trash instead of deleting it
+	Trash          bool `json:"trash,omitempty"`
+	RemoveFiles    bool `json:"remove_files,omitempty"`
+	ForceWorktrees bool `json:"force_worktrees,omitempty"`
+	// Force deletes even when the preflight check finds work that would be lost
+	Force bool `json:"force,omitempty"`
+}
+
+// DeleteWorkspaceResult reports a deletion and the work it put at risk
+type DeleteWorkspaceResult struct {
+	Deleted   bool             `json:"deleted"`
+	Trash     *TrashEntry      `json:"trash,omitempty"`
+	Preflight *DeletePreflight `json:"preflight"`
+}
+
+// ErrWorkAtRisk is returned when deleting a workspace would lose work and Force is not set
+var ErrWorkAtRisk = errors.New("the workspace has unpushed commits, stashes or untracked files, set force to delete anyway")
+
+// CommitRequest commits all changes of a workspace
+type CommitRequest struct {
+	Workspace string `json:"workspace"`
+	Message   string `json:"message"`
+	// Repositories limits the commit to some repositories, empty for all
+	Repositories []string `json:"repositories,omitempty"`
+	Push         bool     `json:"push,omitempty"`
+	// Confirm acknowledges pushes to protected branches
+	Confirm  bool `json:"confirm,omitempty"`
+	ChangeID bool `json:"change_id,omitempty"`
+	// Filter narrows the workspace before Repositories is applied
+	Filter RepositoryFilter `json:"filter,omitempty"`
+	// Groups splits the commit of every repository by paths (see CommitGroup)
+	Groups []CommitGroup `json:"groups,omitempty"`
+}
+
+// CommitResult lists the repositories a commit was made in
+type CommitResult struct {
+	Repositories []string `json:"repositories"`
+	ChangeID     string   `json:"change_id,omitempty"`
+	Pushed       bool     `json:"pushed"`
+}
+
+// DiffRequest selects the diff of a workspace
+type DiffRequest struct {
+	Workspace  string `json:"workspace"`
+	Repository string `json:"repository,omitempty"`
+	Staged     bool   `json:"staged,omitempty"`
+	// SinceCreation diffs against the commit each worktree was created from
+	SinceCreation bool             `json:"since_creation,omitempty"`
+	Filter        RepositoryFilter `json:"filter,omitempty"`
+}
+
+// SyncRequest synchronizes a workspace with its remotes
+type SyncRequest struct {
+	Workspace string           `json:"workspace"`
+	Filter    RepositoryFilter `json:"filter,omitempty"`
+	SyncOptions
+}
+
+// ListWorkspaces returns all saved workspaces
+func (s *WorkspaceService) ListWorkspaces() ([]Workspace, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces, nil
+}
+
+// GetWorkspace returns a saved workspace
+func (s *WorkspaceService) GetWorkspace(name string) (*Workspace, error) {
+	wm, err := NewWorkspaceManager()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workspace manager")
+	}
+	return wm.LoadWorkspace(name)
+}
+
+// FilterWorkspace returns a saved workspace narrowed to the repositories
+// passing filter (see RepositoryFilter.Apply)
+func (s *WorkspaceService) FilterWorkspace(name string, filter RepositoryFilter) (*Workspace, error) {
+	workspace, err := s.GetWorkspace(name)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(workspace)
+}
+
+// DetectWorkspace returns the workspace containing dir
+func (s *WorkspaceService) DetectWorkspace(dir string) (*Workspace, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	return DetectWorkspace(dir, workspaces)
+}
+
+// Status returns the git status of the repositories of a workspace passing filter
+func (s *WorkspaceService) Status(ctx context.Context, name string, filter RepositoryFilter) (*WorkspaceStatus, error) {
+	workspace, err := s.FilterWorkspace(name, filter)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatusChecker().GetWorkspaceStatus(ctx, workspace)
+}
+
+// CreateWorkspace creates a workspace. Nobody can be asked how to handle an
+// existing branch, so the creation is refused when the branch already exists.
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, req CreateWorkspaceRequest) (workspace *Workspace, err error) {
+	defer s.audit("create", req.Name, req, time.Now(), &err)
+
+	if req.Name == "" {
+		return nil, errors.New("workspace name is required")
+	}
+	if len(req.Repositories) == 0 {
+		return nil, errors.New("at least one repository is required")
+	}
+	if req.Branch == "" {
+		if req.Branch, err = GenerateBranchName("task", req.Name, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	wm, err := NewWorkspaceManager()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workspace manager")
+	}
+	wm.OnExistingBranch = ExistingBranchFail
+	wm.OnCheckedOutBranch = CheckedOutBranchFail
+	req.Name, req.Branch, err = wm.prepareWorkspaceNames(req.Name, req.Branch, req.Sanitize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths and remote URLs are registered on the fly
+	req.Repositories, err = wm.Discoverer.RegisterRepositoryLocations(ctx, req.Repositories, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register repositories")
+	}
+	repos, err := wm.FindRepositories(req.Repositories)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find repositories")
+	}
+	for _, repo := range repos {
+		if repo.IsRemoteOnly() {
+			// Cloned by CreateWorkspace, with no local branches yet
+			continue
+		}
+		exists, err := wm.CheckBranchExists(ctx, repo.Path, req.Branch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check branch in %s", repo.Name)
+		}
+		if exists {
+			return nil, errors.Errorf("branch '%s' already exists in repository '%s', choose another branch", req.Branch, repo.Name)
+		}
+	}
+
+	return wm.CreateWorkspace(ctx, CreateOptions{
+		Name:         req.Name,
+		Repositories: req.Repositories,
+		Branch:       req.Branch,
+		BaseBranch:   req.BaseBranch,
+	})
+}
+
+// DeleteWorkspace deletes a workspace, or moves it to the trash. Without Force
+// the deletion is refused with ErrWorkAtRisk when the preflight check finds work
+// that would be lost; the result holds the preflight report in both cases.
+func (s *WorkspaceService) DeleteWorkspace(ctx context.Context, req DeleteWorkspaceRequest) (result *DeleteWorkspaceResult, err error) {
+	defer s.audit("delete", req.Name, req, time.Now(), &err)
+
+	wm, err := NewWorkspaceManager()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workspace manager")
+	}
+	workspace, err := wm.LoadWorkspace(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &DeleteWorkspaceResult{Preflight: wm.CheckDeletePreflight(ctx, workspace)}
+	if !req.Trash && !req.Force && result.Preflight.AtRisk() {
+		return result, ErrWorkAtRisk
+	}
+
+	if req.Trash {
+		if result.Trash, err = wm.TrashWorkspace(ctx, req.Name); err != nil {
+			return result, errors.Wrap(err, "failed to move workspace to the trash")
+		}
+	} else if err := wm.DeleteWorkspace(ctx, req.Name, req.RemoveFiles, req.ForceWorktrees); err != nil {
+		return result, errors.Wrap(err, "failed to delete workspace")
+	}
+	result.Deleted = true
+	return result, nil
+}
+
+// Commit stages and commits all changes of the selected repositories
+func (s *WorkspaceService) Commit(ctx context.Context, req CommitRequest) (result *CommitResult, err error) {
+	defer s.audit("commit", req.Workspace, req, time.Now(), &err)
+
+	if req.Message == "" {
+		return nil, errors.New("commit message is required")
+	}
+	workspace, err := s.FilterWorkspace(req.Workspace, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	gitOps := NewGitOperations(workspace)
+	changes, err := gitOps.GetWorkspaceChanges(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workspace changes")
+	}
+	if len(req.Repositories) > 0 {
+		for repoName := range changes {
+			if !containsValue(req.Repositories, repoName) {
+				delete(changes, repoName)
+			}
+		}
+	}
+
+	result = &CommitResult{Repositories: []string{}}
+	if len(changes) == 0 {
+		return result, nil
+	}
+
+	operation := &CommitOperation{
+		Message: req.Message,
+		Files:   changes,
+		AddAll:  true,
+		Push:    req.Push,
+		Confirm: req.Confirm,
+		Groups:  req.Groups,
+	}
+	if req.ChangeID {
+		if operation.ChangeID, err = NewChangeID(); err != nil {
+			return nil, err
+		}
+	}
+	if err := gitOps.CommitChanges(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	for repoName := range changes {
+		result.Repositories = append(result.Repositories, repoName)
+	}
+	sort.Strings(result.Repositories)
+	result.ChangeID = operation.ChangeID
+	result.Pushed = req.Push
+	return result, nil
+}
+
+// Diff returns the unified diff of a workspace
+func (s *WorkspaceService) Diff(ctx context.Context, req DiffRequest) (string, error) {
+	workspace, err := s.FilterWorkspace(req.Workspace, req.Filter)
+	if err != nil {
+		return "", err
+	}
+	return NewGitOperations(workspace).GetDiff(ctx, req.Staged, req.Repository, req.SinceCreation)
+}
+
+// Sync pulls and pushes the repositories of a workspace
+func (s *WorkspaceService) Sync(ctx context.Context, req SyncRequest) (results []SyncResult, err error) {
+	defer s.audit("sync", req.Workspace, req, time.Now(), &err)
+
+	workspace, err := s.FilterWorkspace(req.Workspace, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	options := req.SyncOptions
+	return NewSyncOperations(workspace).SyncWorkspace(ctx, &options)
+}
+
+// audit records a mutating operation with its request as the only argument
+func (s *WorkspaceService) audit(operation, workspace string, req interface{}, start time.Time, errp *error) {
+	event := AuditEvent{
+		Time:      start,
+		Operation: operation,
+		Workspace: workspace,
+		Duration:  time.Since(start),
+		Success:   *errp == nil,
+		Actor:     os.Getenv(AuditActorEnv),
+	}
+	if event.Actor == "" {
+		event.Actor = s.Actor
+	}
+	if data, err := json.Marshal(req); err == nil {
+		event.Args = []string{string(data)}
+	}
+	if *errp != nil {
+		event.Error = (*errp).Error()
+	}
+	// The audit log must never make an operation fail
+	_ = RecordAuditEvent(event)
+}
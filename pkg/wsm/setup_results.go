@@ -0,0 +1,264 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+const (
+	// setupResultsFile records the last run of every setup script, in .wsm/
+	setupResultsFile = "setup-results.json"
+	// setupLogDir holds the output of every setup script, in .wsm/
+	setupLogDir = "setup-logs"
+)
+
+// SetupResult is the outcome of the last run of a setup script
+type SetupResult struct {
+	Script     string        `json:"script"`
+	WorkingDir string        `json:"working_dir"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	ExitCode   int           `json:"exit_code"`
+	// LogFile holds the output of the script
+	LogFile string `json:"log_file,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the script exited with status 0
+func (r SetupResult) Succeeded() bool {
+	return r.ExitCode == 0 && r.Error == ""
+}
+
+// SetupResults are the setup script results of a workspace, stored in
+// .wsm/setup-results.json
+type SetupResults struct {
+	UpdatedAt time.Time     `json:"updated_at"`
+	Results   []SetupResult `json:"results"`
+}
+
+// LoadSetupResults reads the setup script results of the workspace at path,
+// empty when no script ran yet
+func LoadSetupResults(workspacePath string) (*SetupResults, error) {
+	results := &SetupResults{Results: []SetupResult{}}
+	data, err := os.ReadFile(filepath.Join(workspacePath, ".wsm", setupResultsFile))
+	if os.IsNotExist(err) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read setup results")
+	}
+	if err := json.Unmarshal(data, results); err != nil {
+		return nil, errors.Wrap(err, "failed to parse setup results")
+	}
+	return results, nil
+}
+
+// Failed returns the results of the scripts whose last run failed
+func (r *SetupResults) Failed() []SetupResult {
+	var failed []SetupResult
+	for _, result := range r.Results {
+		if !result.Succeeded() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// record replaces the results of the scripts that ran again and adds the others
+func (r *SetupResults) record(results []SetupResult) {
+	for _, result := range results {
+		replaced := false
+		for i := range r.Results {
+			if r.Results[i].Script == result.Script {
+				r.Results[i] = result
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.Results = append(r.Results, result)
+		}
+	}
+	r.UpdatedAt = time.Now()
+}
+
+// recordSetupResults adds the results of a setup run to .wsm/setup-results.json
+func recordSetupResults(workspacePath string, results []SetupResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	stored, err := LoadSetupResults(workspacePath)
+	if err != nil {
+		// A corrupt file only records previous runs
+		stored = &SetupResults{}
+	}
+	stored.record(results)
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal setup results")
+	}
+	wsmDir := filepath.Join(workspacePath, ".wsm")
+	if err := os.MkdirAll(wsmDir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", wsmDir)
+	}
+	return errors.Wrap(os.WriteFile(filepath.Join(wsmDir, setupResultsFile), data, 0644), "failed to write setup results")
+}
+
+// setupScriptEnv returns the environment of the setup scripts of a workspace
+func setupScriptEnv(workspace *Workspace) []string {
+	env := os.Environ()
+	for key, value := range workspaceEnvironment(workspace) {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// setupScripts returns the setup scripts of a workspace in execution order:
+// .wsm/setup.sh of the workspace root, then the setup.d scripts
+func (wm *WorkspaceManager) setupScripts(workspace *Workspace) ([]SetupScript, error) {
+	var scripts []SetupScript
+	rootSetupScript := filepath.Join(workspace.Path, ".wsm", "setup.sh")
+	if _, err := os.Stat(rootSetupScript); err == nil {
+		scripts = append(scripts, SetupScript{Path: rootSetupScript, WorkingDir: workspace.Path, Name: "setup.sh"})
+	}
+	setupDScripts, err := wm.collectSetupDScripts(workspace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to collect setup.d scripts")
+	}
+	return append(scripts, setupDScripts...), nil
+}
+
+// runSetupScripts runs the trusted setup scripts one after the other,
+// whatever their outcome, and records the results in the workspace
+func (wm *WorkspaceManager) runSetupScripts(ctx context.Context, workspace *Workspace, scripts []SetupScript, env []string) []SetupResult {
+	var results []SetupResult
+	gate := newSetupScriptGate()
+	for _, script := range scripts {
+		if !gate.allows(workspace, script) {
+			continue
+		}
+		result, ok := wm.executeSetupScript(ctx, workspace, script, env)
+		if !ok {
+			continue
+		}
+		if !result.Succeeded() {
+			output.LogWarn(
+				fmt.Sprintf("Setup script %s failed, see %s", script.Path, result.LogFile),
+				"Setup script failed",
+				"script", script.Path,
+				"workingDir", script.WorkingDir,
+				"exitCode", result.ExitCode,
+				"error", result.Error,
+			)
+		}
+		results = append(results, result)
+	}
+
+	if err := recordSetupResults(workspace.Path, results); err != nil {
+		output.PrintWarning("Failed to record setup results: %v", err)
+	}
+	return results
+}
+
+// RerunSetupScripts runs the setup scripts of an existing workspace again,
+// or with onlyFailed the scripts whose last run failed
+func (wm *WorkspaceManager) RerunSetupScripts(ctx context.Context, workspace *Workspace, onlyFailed bool) ([]SetupResult, error) {
+	scripts, err := wm.setupScripts(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if onlyFailed {
+		previous, err := LoadSetupResults(workspace.Path)
+		if err != nil {
+			return nil, err
+		}
+		failed := make(map[string]bool)
+		for _, result := range previous.Failed() {
+			failed[result.Script] = true
+		}
+		var selected []SetupScript
+		for _, script := range scripts {
+			if failed[script.Path] {
+				selected = append(selected, script)
+			}
+		}
+		scripts = selected
+	}
+
+	return wm.runSetupScripts(ctx, workspace, scripts, setupScriptEnv(workspace)), nil
+}
+
+// executeSetupScript runs a setup script with bash, showing its output and
+// writing it to a log file under .wsm/setup-logs. It returns false when the
+// script was not run because it or bash is missing.
+func (wm *WorkspaceManager) executeSetupScript(ctx context.Context, workspace *Workspace, script SetupScript, env []string) (SetupResult, bool) {
+	if _, err := os.Stat(script.Path); os.IsNotExist(err) {
+		return SetupResult{}, false
+	}
+	if err := RequireTool(ctx, "bash"); err != nil {
+		output.PrintWarning("Skipping setup script %s: %v", filepath.Base(script.Path), err)
+		return SetupResult{}, false
+	}
+
+	output.PrintInfo("Executing setup script: %s", filepath.Base(script.Path))
+	result := SetupResult{Script: script.Path, WorkingDir: script.WorkingDir, StartedAt: time.Now()}
+
+	cmd := exec.CommandContext(ctx, "bash", script.Path)
+	cmd.Dir = script.WorkingDir
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	logFile, err := createSetupLog(workspace, script)
+	if err != nil {
+		output.PrintWarning("Not logging setup script %s: %v", filepath.Base(script.Path), err)
+	} else {
+		defer func() { _ = logFile.Close() }()
+		result.LogFile = logFile.Name()
+		cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
+		cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
+	}
+
+	err = cmd.Run()
+	result.Duration = time.Since(result.StartedAt)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+			result.Error = err.Error()
+		}
+		return result, true
+	}
+
+	output.PrintInfo("Setup script completed: %s", filepath.Base(script.Path))
+	return result, true
+}
+
+// createSetupLog creates the log file of a setup script, named after its path
+// in the workspace
+func createSetupLog(workspace *Workspace, script SetupScript) (*os.File, error) {
+	logDir := filepath.Join(workspace.Path, ".wsm", setupLogDir)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, err
+	}
+	name, err := filepath.Rel(workspace.Path, script.Path)
+	if err != nil || strings.HasPrefix(name, "..") {
+		name = filepath.Base(script.Path)
+	}
+	name = strings.ReplaceAll(filepath.ToSlash(name), "/", "_")
+	return os.Create(filepath.Join(logDir, name+".log"))
+}
@@ -0,0 +1,151 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseSparseSpecs parses "repo=dir1,dir2" specifications into sparse checkout
+// directories per repository. Repeating a repository appends to its directories.
+func ParseSparseSpecs(specs []string) (map[string][]string, error) {
+	sparsePaths := make(map[string][]string)
+	for _, spec := range specs {
+		repoName, dirs, ok := strings.Cut(spec, "=")
+		repoName = strings.TrimSpace(repoName)
+		if !ok || repoName == "" {
+			return nil, errors.Errorf("invalid sparse specification '%s' (expected repo=dir1,dir2)", spec)
+		}
+
+		cleaned, err := CleanSparsePaths(strings.Split(dirs, ","))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid sparse specification for '%s'", repoName)
+		}
+		for _, dir := range cleaned {
+			sparsePaths[repoName] = appendUnique(sparsePaths[repoName], dir)
+		}
+	}
+	return sparsePaths, nil
+}
+
+// CleanSparsePaths normalizes cone mode directories: they are relative to the
+// repository root and may not leave it. Empty entries are dropped.
+func CleanSparsePaths(dirs []string) ([]string, error) {
+	var cleaned []string
+	for _, dir := range dirs {
+		dir = strings.Trim(strings.TrimSpace(filepath.ToSlash(dir)), "/")
+		if dir == "" {
+			continue
+		}
+		dir = path.Clean(dir)
+		if dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+			return nil, errors.Errorf("sparse directory '%s' must be inside the repository", dir)
+		}
+		if strings.ContainsAny(dir, "*?[") {
+			return nil, errors.Errorf("sparse directory '%s' must not contain glob patterns (cone mode)", dir)
+		}
+		cleaned = appendUnique(cleaned, dir)
+	}
+	if len(cleaned) == 0 {
+		return nil, errors.New("no sparse directories given")
+	}
+	return cleaned, nil
+}
+
+// worktreeAddCommand builds a `git worktree add` command. Worktrees with a sparse
+// checkout are added without checking out files, so large repositories are never
+// fully materialized.
+func worktreeAddCommand(workspace *Workspace, repoName string, args ...string) []string {
+	command := []string{"git", "worktree", "add"}
+	if len(workspace.SparsePathsFor(repoName)) > 0 {
+		command = append(command, "--no-checkout")
+	}
+	return append(command, args...)
+}
+
+// sparseCheckoutCommands returns the git commands that restrict a worktree added
+// with --no-checkout to its sparse directories and check them out
+func sparseCheckoutCommands(sparsePaths []string) [][]string {
+	set := append([]string{"git", "sparse-checkout", "set", "--cone", "--"}, sparsePaths...)
+	return [][]string{set, {"git", "checkout"}}
+}
+
+// addWorktree runs `git worktree add` for a repository of a workspace, applies
+// the repository's sparse checkout, if any, initializes its submodules and
+// fetches its Git LFS files
+func (wm *WorkspaceManager) addWorktree(ctx context.Context, workspace *Workspace, repo Repository, args ...string) error {
+	if err := wm.ExecuteWorktreeCommand(ctx, repo.Path, worktreeAddCommand(workspace, repo.Name, args...)...); err != nil {
+		return err
+	}
+
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	sparsePaths := workspace.SparsePathsFor(repo.Name)
+	if len(sparsePaths) > 0 {
+		for _, command := range sparseCheckoutCommands(sparsePaths) {
+			if err := wm.ExecuteWorktreeCommand(ctx, worktreePath, command...); err != nil {
+				return errors.Wrapf(err, "failed to apply sparse checkout to %s", repo.Name)
+			}
+		}
+	}
+
+	if err := wm.initSubmodules(ctx, workspace, repo); err != nil {
+		return err
+	}
+	if err := wm.initLFS(ctx, workspace, repo); err != nil {
+		return err
+	}
+	if err := wm.applyGitConfig(ctx, workspace, repo); err != nil {
+		return err
+	}
+	if _, err := wm.deployHooks(ctx, workspace, repo); err != nil {
+		return err
+	}
+	wm.recordDefaultBranch(ctx, workspace, repo)
+	wm.recordOrigin(ctx, workspace, repo)
+	return nil
+}
+
+// planSparseCheckout returns the plan steps applying a repository's sparse checkout
+func planSparseCheckout(workspace *Workspace, repo Repository) []PlanStep {
+	sparsePaths := workspace.SparsePathsFor(repo.Name)
+	if len(sparsePaths) == 0 {
+		return nil
+	}
+
+	commands := sparseCheckoutCommands(sparsePaths)
+	return []PlanStep{
+		{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Restrict %s to %s", repo.Name, strings.Join(sparsePaths, ", ")),
+			Dir:         filepath.Join(workspace.Path, repo.Name),
+			Command:     commands[0],
+		},
+		{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Check out the sparse directories of %s", repo.Name),
+			Dir:         filepath.Join(workspace.Path, repo.Name),
+			Command:     commands[1],
+		},
+	}
+}
+
+// validateSparseRepositories rejects sparse checkouts for repositories that are not part of the workspace
+func validateSparseRepositories(sparsePaths map[string][]string, repos []Repository) error {
+	for repoName := range sparsePaths {
+		found := false
+		for _, repo := range repos {
+			if repo.Name == repoName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("sparse checkout configured for '%s', which is not a workspace repository", repoName)
+		}
+	}
+	return nil
+}
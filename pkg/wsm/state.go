@@ -0,0 +1,533 @@
+package wsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Remote state backends
+const (
+	StateBackendGit  = "git"
+	StateBackendS3   = "s3"
+	StateBackendHTTP = "http"
+)
+
+// Conflict resolution preferences for MergeState
+const (
+	StatePreferLocal  = "local"
+	StatePreferRemote = "remote"
+)
+
+// RemoteStateConfig configures the shared backend holding the registry and
+// workspace definitions of a team. It is read from remote-state.yaml in the
+// workspace-manager config directory:
+//
+//	# a git repository, files are stored under dir/ on branch
+//	backend: git
+//	url: git@github.com:acme/wsm-state.git
+//	branch: main
+//	dir: team
+//
+//	# a single JSON document in S3, accessed with the aws CLI
+//	backend: s3
+//	url: s3://acme-wsm/state.json
+//
+//	# a JSON document read with GET and written with PUT
+//	backend: http
+//	url: https://wsm.acme.dev/state
+//	token-env: WSM_STATE_TOKEN
+type RemoteStateConfig struct {
+	Backend string `yaml:"backend" json:"backend"`
+	URL     string `yaml:"url" json:"url"`
+	// Branch of the git backend, main by default
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// Dir is the directory of the git backend repository holding the state
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// TokenEnv names an environment variable holding a bearer token for the http backend
+	TokenEnv string `yaml:"token-env,omitempty" json:"token_env,omitempty"`
+
+	path string
+}
+
+// StateSnapshot is the shared state: the repository registry and the workspace definitions
+type StateSnapshot struct {
+	Registry   RepositoryRegistry `json:"registry"`
+	Workspaces []Workspace        `json:"workspaces"`
+	UpdatedAt  time.Time          `json:"updated_at,omitempty"`
+	UpdatedBy  string             `json:"updated_by,omitempty"`
+}
+
+// StateChange is a single difference between two snapshots
+type StateChange struct {
+	// Kind is "repository", "group" or "workspace"
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // added, updated, removed
+}
+
+// StateMerge is the result of a three-way merge of the local and remote state
+type StateMerge struct {
+	Result *StateSnapshot `json:"-"`
+	// Incoming are the remote changes applied to the local state
+	Incoming []StateChange `json:"incoming"`
+	// Outgoing are the local changes not yet pushed
+	Outgoing []StateChange `json:"outgoing"`
+	// Conflicts changed on both sides since the last sync
+	Conflicts []StateChange `json:"conflicts"`
+}
+
+func getStateDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager"), nil
+}
+
+// GetRemoteStateConfigPath returns the path of the remote state configuration
+func GetRemoteStateConfigPath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "remote-state.yaml"), nil
+}
+
+// LoadRemoteStateConfig loads the remote state configuration
+func LoadRemoteStateConfig() (*RemoteStateConfig, error) {
+	configPath, err := GetRemoteStateConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("no remote state configured, create %s", configPath)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read remote state configuration")
+	}
+
+	config := &RemoteStateConfig{path: configPath}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", configPath)
+	}
+
+	switch config.Backend {
+	case StateBackendGit, StateBackendS3, StateBackendHTTP:
+	default:
+		return nil, errors.Errorf("unsupported remote state backend '%s' in %s (supported: git, s3, http)", config.Backend, configPath)
+	}
+	if config.URL == "" {
+		return nil, errors.Errorf("remote state url is missing in %s", configPath)
+	}
+	if config.Backend == StateBackendGit && config.Branch == "" {
+		config.Branch = "main"
+	}
+
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *RemoteStateConfig) Path() string {
+	return c.path
+}
+
+// LocalState reads the registry and workspace definitions from the config
+// directory, with portable paths: workspace paths relative to the workspace
+// directory and other paths in the home directory starting with ~/
+func (wm *WorkspaceManager) LocalState() (*StateSnapshot, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := *wm.Discoverer.registry
+	registry.Repositories = append([]Repository(nil), registry.Repositories...)
+	snapshot := &StateSnapshot{
+		Registry:   registry,
+		Workspaces: workspaces,
+	}
+	snapshot.makePortable(wm.workspaceDir)
+	snapshot.normalize()
+	return snapshot, nil
+}
+
+// makePortable replaces the machine-specific paths of a snapshot by portable ones
+func (s *StateSnapshot) makePortable(workspaceDir string) {
+	for i := range s.Registry.Repositories {
+		s.Registry.Repositories[i].Path = homeRelative(s.Registry.Repositories[i].Path)
+	}
+	for i := range s.Workspaces {
+		workspace := &s.Workspaces[i]
+		if rel, err := filepath.Rel(workspaceDir, workspace.Path); err == nil && PathWithin(workspace.Path, workspaceDir) {
+			workspace.Path = filepath.ToSlash(rel)
+		} else {
+			workspace.Path = homeRelative(workspace.Path)
+		}
+		repos := make([]Repository, len(workspace.Repositories))
+		for j, repo := range workspace.Repositories {
+			repo.Path = homeRelative(repo.Path)
+			repos[j] = repo
+		}
+		workspace.Repositories = repos
+	}
+}
+
+// homeRelative writes a path in the home directory as ~/<path>
+func homeRelative(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || !filepath.IsAbs(path) || !PathWithin(path, home) {
+		return path
+	}
+	rel, err := filepath.Rel(home, path)
+	if err != nil || rel == "." {
+		return path
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
+
+// localize checks a snapshot from the shared state and turns its portable
+// paths into paths of this machine. Names must be valid workspace and
+// repository names. Workspaces must resolve inside the workspace directory,
+// unless their local definition already has that path; other absolute
+// workspace paths, from older snapshots of another machine, are resolved by name.
+// Repositories of workspaces take the path of the registry entry of the same name.
+// Their git config is limited as by localizeGitConfig.
+func (wm *WorkspaceManager) localize(snapshot *StateSnapshot, local map[string]Workspace) error {
+	registryPaths := make(map[string]string)
+	for i := range snapshot.Registry.Repositories {
+		repo := &snapshot.Registry.Repositories[i]
+		if err := ValidateRepositoryName(repo.Name); err != nil {
+			return err
+		}
+		repo.Path = expandHome(repo.Path)
+		registryPaths[repo.Name] = repo.Path
+	}
+
+	for i := range snapshot.Workspaces {
+		workspace := &snapshot.Workspaces[i]
+		if err := ValidateWorkspaceName(workspace.Name); err != nil {
+			return err
+		}
+
+		path := expandHome(workspace.Path)
+		existing, known := local[workspace.Name]
+		switch {
+		case path == "":
+			path = filepath.Join(wm.workspaceDir, workspace.Name)
+		case filepath.IsAbs(path) && path == workspace.Path:
+			if !known || existing.Path != path {
+				path = filepath.Join(wm.workspaceDir, workspace.Name)
+			}
+		case !filepath.IsAbs(path):
+			path = filepath.Join(wm.workspaceDir, filepath.FromSlash(path))
+		}
+		inside := PathWithin(path, wm.workspaceDir) && resolvePath(path) != resolvePath(wm.workspaceDir)
+		if !inside && (!known || existing.Path != path) {
+			return errors.Errorf("workspace '%s' of the shared state is at %s, outside the workspace directory %s", workspace.Name, path, wm.workspaceDir)
+		}
+		workspace.Path = path
+		if known {
+			localizeGitConfig(workspace, &existing)
+		} else {
+			localizeGitConfig(workspace, nil)
+		}
+
+		for j := range workspace.Repositories {
+			repo := &workspace.Repositories[j]
+			if err := ValidateRepositoryName(repo.Name); err != nil {
+				return errors.Wrapf(err, "workspace '%s'", workspace.Name)
+			}
+			if registryPath, ok := registryPaths[repo.Name]; ok {
+				repo.Path = registryPath
+			} else {
+				repo.Path = expandHome(repo.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyState writes a merged snapshot to the config directory. Workspace
+// definitions missing from the snapshot are removed; workspace files on disk
+// are never touched. Nothing is written if the snapshot does not pass localize.
+func (wm *WorkspaceManager) ApplyState(snapshot *StateSnapshot) error {
+	existing, err := LoadWorkspaces()
+	if err != nil {
+		return err
+	}
+	local := make(map[string]Workspace, len(existing))
+	for _, workspace := range existing {
+		local[workspace.Name] = workspace
+	}
+	if err := wm.localize(snapshot, local); err != nil {
+		return errors.Wrap(err, "refusing the shared state")
+	}
+
+	registry := snapshot.Registry
+	wm.Discoverer.registry = &registry
+	if err := wm.Discoverer.SaveRegistry(); err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for i := range snapshot.Workspaces {
+		keep[snapshot.Workspaces[i].Name] = true
+		if err := wm.SaveWorkspace(&snapshot.Workspaces[i]); err != nil {
+			return errors.Wrapf(err, "failed to save workspace '%s'", snapshot.Workspaces[i].Name)
+		}
+	}
+
+	workspacesDir := filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces")
+	for _, workspace := range existing {
+		if keep[workspace.Name] {
+			continue
+		}
+		if err := ValidateWorkspaceName(workspace.Name); err != nil {
+			return errors.Wrap(err, "failed to remove a workspace")
+		}
+		if err := os.Remove(filepath.Join(workspacesDir, workspace.Name+".json")); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove workspace '%s'", workspace.Name)
+		}
+	}
+
+	return nil
+}
+
+func getStateBasePath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, "remote-state-base.json"), nil
+}
+
+// LoadStateBase loads the remote state seen at the last pull or push. It is
+// the common ancestor of three-way merges; an empty snapshot is returned
+// before the first sync.
+func LoadStateBase() (*StateSnapshot, error) {
+	basePath, err := getStateBasePath()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StateSnapshot{}
+	data, err := os.ReadFile(basePath)
+	if os.IsNotExist(err) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read remote state base")
+	}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", basePath)
+	}
+	snapshot.normalize()
+	return snapshot, nil
+}
+
+// SaveStateBase records the remote state after a pull or push
+func SaveStateBase(snapshot *StateSnapshot) error {
+	basePath, err := getStateBasePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(basePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal remote state base")
+	}
+	if err := os.WriteFile(basePath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write remote state base")
+	}
+	return nil
+}
+
+// Stamp records when and by whom the snapshot is pushed
+func (s *StateSnapshot) Stamp() {
+	s.UpdatedAt = time.Now()
+	if current, err := user.Current(); err == nil {
+		s.UpdatedBy = current.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		s.UpdatedBy += "@" + host
+	}
+}
+
+// normalize sorts the snapshot so it serializes the same way on every machine
+func (s *StateSnapshot) normalize() {
+	if s.Registry.Repositories == nil {
+		s.Registry.Repositories = []Repository{}
+	}
+	if s.Workspaces == nil {
+		s.Workspaces = []Workspace{}
+	}
+	sort.Slice(s.Registry.Repositories, func(i, j int) bool {
+		return s.Registry.Repositories[i].Name < s.Registry.Repositories[j].Name
+	})
+	sort.Slice(s.Workspaces, func(i, j int) bool {
+		return s.Workspaces[i].Name < s.Workspaces[j].Name
+	})
+}
+
+// stateItems flattens a snapshot into kind -> name -> JSON, the unit of merging
+func (s *StateSnapshot) stateItems() map[string]map[string][]byte {
+	items := map[string]map[string][]byte{
+		"repository": {},
+		"group":      {},
+		"workspace":  {},
+	}
+	for _, repo := range s.Registry.Repositories {
+		items["repository"][repo.Name], _ = json.Marshal(repo)
+	}
+	for name, members := range s.Registry.Groups {
+		items["group"][name], _ = json.Marshal(members)
+	}
+	for _, workspace := range s.Workspaces {
+		items["workspace"][workspace.Name], _ = json.Marshal(workspace)
+	}
+	return items
+}
+
+// snapshotFromItems rebuilds a snapshot from merged items
+func snapshotFromItems(items map[string]map[string][]byte, lastScan time.Time) (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{}
+	snapshot.Registry.LastScan = lastScan
+
+	for name, data := range items["repository"] {
+		var repo Repository
+		if err := json.Unmarshal(data, &repo); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode repository '%s'", name)
+		}
+		snapshot.Registry.Repositories = append(snapshot.Registry.Repositories, repo)
+	}
+	for name, data := range items["group"] {
+		var members []string
+		if err := json.Unmarshal(data, &members); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode group '%s'", name)
+		}
+		if snapshot.Registry.Groups == nil {
+			snapshot.Registry.Groups = map[string][]string{}
+		}
+		snapshot.Registry.Groups[name] = members
+	}
+	for name, data := range items["workspace"] {
+		var workspace Workspace
+		if err := json.Unmarshal(data, &workspace); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode workspace '%s'", name)
+		}
+		snapshot.Workspaces = append(snapshot.Workspaces, workspace)
+	}
+
+	snapshot.normalize()
+	return snapshot, nil
+}
+
+// DiffState lists the changes that turn snapshot from into snapshot to
+func DiffState(from, to *StateSnapshot) []StateChange {
+	fromItems, toItems := from.stateItems(), to.stateItems()
+
+	var changes []StateChange
+	for _, kind := range []string{"repository", "group", "workspace"} {
+		for _, name := range sortedItemNames(fromItems[kind], toItems[kind]) {
+			if action := itemAction(fromItems[kind][name], toItems[kind][name]); action != "" {
+				changes = append(changes, StateChange{Kind: kind, Name: name, Action: action})
+			}
+		}
+	}
+	return changes
+}
+
+// MergeState merges the local and remote state against the state of the last
+// sync. An item changed on one side only takes that side's version; an item
+// changed differently on both sides is a conflict, resolved by prefer
+// (StatePreferLocal or StatePreferRemote) or kept local and reported.
+func MergeState(base, local, remote *StateSnapshot, prefer string) (*StateMerge, error) {
+	baseItems, localItems, remoteItems := base.stateItems(), local.stateItems(), remote.stateItems()
+
+	merge := &StateMerge{}
+	merged := map[string]map[string][]byte{}
+	for _, kind := range []string{"repository", "group", "workspace"} {
+		merged[kind] = map[string][]byte{}
+		for _, name := range sortedItemNames(baseItems[kind], localItems[kind], remoteItems[kind]) {
+			b, l, r := baseItems[kind][name], localItems[kind][name], remoteItems[kind][name]
+
+			result := l
+			switch {
+			case bytes.Equal(l, r):
+			case bytes.Equal(l, b):
+				result = r
+				merge.Incoming = append(merge.Incoming, StateChange{Kind: kind, Name: name, Action: itemAction(l, r)})
+			case bytes.Equal(r, b):
+				merge.Outgoing = append(merge.Outgoing, StateChange{Kind: kind, Name: name, Action: itemAction(b, l)})
+			default:
+				conflict := StateChange{Kind: kind, Name: name, Action: itemAction(b, r)}
+				switch prefer {
+				case StatePreferRemote:
+					result = r
+					merge.Incoming = append(merge.Incoming, conflict)
+				case StatePreferLocal:
+					merge.Outgoing = append(merge.Outgoing, StateChange{Kind: kind, Name: name, Action: itemAction(b, l)})
+				default:
+					merge.Conflicts = append(merge.Conflicts, conflict)
+				}
+			}
+
+			if result != nil {
+				merged[kind][name] = result
+			}
+		}
+	}
+
+	lastScan := local.Registry.LastScan
+	if remote.Registry.LastScan.After(lastScan) {
+		lastScan = remote.Registry.LastScan
+	}
+
+	result, err := snapshotFromItems(merged, lastScan)
+	if err != nil {
+		return nil, err
+	}
+	merge.Result = result
+	return merge, nil
+}
+
+func itemAction(from, to []byte) string {
+	switch {
+	case bytes.Equal(from, to):
+		return ""
+	case from == nil:
+		return "added"
+	case to == nil:
+		return "removed"
+	default:
+		return "updated"
+	}
+}
+
+func sortedItemNames(sets ...map[string][]byte) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, set := range sets {
+		for name := range set {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
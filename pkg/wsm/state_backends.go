@@ -0,0 +1,340 @@
+package wsm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// StateBackend stores a StateSnapshot in a shared location
+type StateBackend interface {
+	// Fetch returns the stored snapshot, or an empty snapshot if nothing has been pushed yet
+	Fetch(ctx context.Context) (*StateSnapshot, error)
+	// Store replaces the stored snapshot
+	Store(ctx context.Context, snapshot *StateSnapshot) error
+	// String describes the backend location
+	String() string
+}
+
+// NewStateBackend creates the backend of a remote state configuration
+func NewStateBackend(config *RemoteStateConfig) (StateBackend, error) {
+	switch config.Backend {
+	case StateBackendGit:
+		stateDir, err := getStateDir()
+		if err != nil {
+			return nil, err
+		}
+		return &gitStateBackend{
+			url:      config.URL,
+			branch:   config.Branch,
+			subdir:   config.Dir,
+			cacheDir: filepath.Join(stateDir, "remote-state-cache"),
+		}, nil
+	case StateBackendS3:
+		if !strings.HasPrefix(config.URL, "s3://") {
+			return nil, errors.Errorf("s3 remote state url must start with s3://, got '%s'", config.URL)
+		}
+		return &s3StateBackend{url: config.URL}, nil
+	case StateBackendHTTP:
+		return &httpStateBackend{url: config.URL, tokenEnv: config.TokenEnv, client: http.DefaultClient}, nil
+	default:
+		return nil, errors.Errorf("unsupported remote state backend '%s'", config.Backend)
+	}
+}
+
+func decodeSnapshot(data []byte, source string) (*StateSnapshot, error) {
+	snapshot := &StateSnapshot{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse remote state from %s", source)
+		}
+	}
+	snapshot.normalize()
+	return snapshot, nil
+}
+
+// gitStateBackend keeps the state as registry.json and workspaces/<name>.json in
+// a git repository, mirroring the local config directory so changes are reviewable
+type gitStateBackend struct {
+	url      string
+	branch   string
+	subdir   string
+	cacheDir string
+}
+
+func (b *gitStateBackend) String() string {
+	return fmt.Sprintf("git %s (branch %s)", b.url, b.branch)
+}
+
+func (b *gitStateBackend) stateDir() string {
+	return filepath.Join(b.cacheDir, b.subdir)
+}
+
+// sync clones or fetches the repository into the cache and checks out the
+// remote branch. It reports whether the branch exists on the remote yet.
+func (b *gitStateBackend) sync(ctx context.Context) (bool, error) {
+	if _, err := os.Stat(filepath.Join(b.cacheDir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(b.cacheDir), 0755); err != nil {
+			return false, errors.Wrap(err, "failed to create remote state cache")
+		}
+		if _, err := gitOutput(ctx, filepath.Dir(b.cacheDir), "clone", "--quiet", b.url, b.cacheDir); err != nil {
+			return false, errors.Wrapf(err, "failed to clone remote state %s", b.url)
+		}
+	} else {
+		if _, err := gitOutput(ctx, b.cacheDir, "remote", "set-url", "origin", b.url); err != nil {
+			return false, err
+		}
+		if _, err := gitOutput(ctx, b.cacheDir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+			return false, errors.Wrapf(err, "failed to fetch remote state %s", b.url)
+		}
+	}
+
+	remoteRef := "origin/" + b.branch
+	if _, err := gitOutput(ctx, b.cacheDir, "rev-parse", "--verify", "--quiet", remoteRef); err != nil {
+		// Empty repository or new branch: start from an empty tree
+		if _, err := gitOutput(ctx, b.cacheDir, "checkout", "--quiet", "--orphan", b.branch); err != nil {
+			if _, err := gitOutput(ctx, b.cacheDir, "checkout", "--quiet", b.branch); err != nil {
+				return false, err
+			}
+		}
+		_, _ = gitOutput(ctx, b.cacheDir, "rm", "-r", "--quiet", "-f", "--ignore-unmatch", ".")
+		return false, nil
+	}
+
+	if _, err := gitOutput(ctx, b.cacheDir, "checkout", "--quiet", "-B", b.branch, remoteRef); err != nil {
+		return false, err
+	}
+	if _, err := gitOutput(ctx, b.cacheDir, "reset", "--quiet", "--hard", remoteRef); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *gitStateBackend) Fetch(ctx context.Context) (*StateSnapshot, error) {
+	exists, err := b.sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StateSnapshot{}
+	if !exists {
+		snapshot.normalize()
+		return snapshot, nil
+	}
+
+	registryPath := filepath.Join(b.stateDir(), "registry.json")
+	if data, err := os.ReadFile(registryPath); err == nil {
+		if err := json.Unmarshal(data, &snapshot.Registry); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", registryPath)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read remote registry")
+	}
+
+	workspacesDir := filepath.Join(b.stateDir(), "workspaces")
+	entries, err := os.ReadDir(workspacesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read remote workspaces")
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		workspacePath := filepath.Join(workspacesDir, entry.Name())
+		data, err := os.ReadFile(workspacePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", workspacePath)
+		}
+		var workspace Workspace
+		if err := json.Unmarshal(data, &workspace); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", workspacePath)
+		}
+		snapshot.Workspaces = append(snapshot.Workspaces, workspace)
+	}
+
+	snapshot.normalize()
+	return snapshot, nil
+}
+
+func (b *gitStateBackend) Store(ctx context.Context, snapshot *StateSnapshot) error {
+	if _, err := b.sync(ctx); err != nil {
+		return err
+	}
+
+	workspacesDir := filepath.Join(b.stateDir(), "workspaces")
+	if err := os.RemoveAll(workspacesDir); err != nil {
+		return errors.Wrap(err, "failed to clear remote workspaces")
+	}
+	if err := os.MkdirAll(workspacesDir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create remote workspaces directory")
+	}
+
+	if err := writeStateJSON(filepath.Join(b.stateDir(), "registry.json"), snapshot.Registry); err != nil {
+		return err
+	}
+	for _, workspace := range snapshot.Workspaces {
+		if err := writeStateJSON(filepath.Join(workspacesDir, workspace.Name+".json"), workspace); err != nil {
+			return err
+		}
+	}
+
+	if _, err := gitOutput(ctx, b.cacheDir, "add", "-A", "--", "."); err != nil {
+		return err
+	}
+	if _, err := gitOutput(ctx, b.cacheDir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	message := "Update workspace-manager state"
+	if snapshot.UpdatedBy != "" {
+		message += " from " + snapshot.UpdatedBy
+	}
+	if _, err := gitOutput(ctx, b.cacheDir, "commit", "--quiet", "-m", message); err != nil {
+		return errors.Wrap(err, "failed to commit remote state")
+	}
+	if _, err := gitOutput(ctx, b.cacheDir, "push", "--quiet", "origin", "HEAD:refs/heads/"+b.branch); err != nil {
+		return errors.Wrapf(err, "failed to push remote state to %s", b.url)
+	}
+	return nil
+}
+
+func writeStateJSON(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal %s", filepath.Base(path))
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	return nil
+}
+
+// s3StateBackend keeps the state as a single JSON object, accessed with the aws CLI
+// so the usual AWS credential configuration applies
+type s3StateBackend struct {
+	url string
+}
+
+func (b *s3StateBackend) String() string {
+	return "s3 " + b.url
+}
+
+func (b *s3StateBackend) Fetch(ctx context.Context) (*StateSnapshot, error) {
+	if err := RequireTool(ctx, "aws"); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "aws", "s3", "cp", "--only-show-errors", b.url, "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := stderr.String()
+		if strings.Contains(msg, "404") || strings.Contains(msg, "Not Found") || strings.Contains(msg, "NoSuchKey") {
+			return decodeSnapshot(nil, b.url)
+		}
+		return nil, errors.Wrapf(err, "failed to download %s: %s", b.url, strings.TrimSpace(msg))
+	}
+	return decodeSnapshot(stdout.Bytes(), b.url)
+}
+
+func (b *s3StateBackend) Store(ctx context.Context, snapshot *StateSnapshot) error {
+	if err := RequireTool(ctx, "aws"); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal remote state")
+	}
+
+	cmd := exec.CommandContext(ctx, "aws", "s3", "cp", "--only-show-errors", "--content-type", "application/json", "-", b.url)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to upload %s: %s", b.url, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// httpStateBackend reads the state with GET and replaces it with PUT
+type httpStateBackend struct {
+	url      string
+	tokenEnv string
+	client   *http.Client
+}
+
+func (b *httpStateBackend) String() string {
+	return "http " + b.url
+}
+
+func (b *httpStateBackend) do(ctx context.Context, method string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.url, body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid remote state url %s", b.url)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.tokenEnv != "" {
+		token := os.Getenv(b.tokenEnv)
+		if token == "" {
+			return nil, errors.Errorf("environment variable %s holding the remote state token is not set", b.tokenEnv)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s failed", method, b.url)
+	}
+	return resp, nil
+}
+
+func (b *httpStateBackend) Fetch(ctx context.Context) (*StateSnapshot, error) {
+	resp, err := b.do(ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return decodeSnapshot(nil, b.url)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", b.url)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("GET %s: %s: %s", b.url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return decodeSnapshot(data, b.url)
+}
+
+func (b *httpStateBackend) Store(ctx context.Context, snapshot *StateSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal remote state")
+	}
+
+	resp, err := b.do(ctx, http.MethodPut, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("PUT %s: %s: %s", b.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
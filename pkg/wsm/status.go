@@ -2,6 +2,7 @@ package wsm
 
 import (
 	"context"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
@@ -22,22 +23,40 @@ func NewStatusChecker() *StatusChecker {
 func (sc *StatusChecker) GetWorkspaceStatus(ctx context.Context, workspace *Workspace) (*WorkspaceStatus, error) {
 	var repoStatuses []RepositoryStatus
 
+	// Freshness comes from the background fetch daemon; status itself never fetches
+	fetchState, err := LoadFetchState()
+	if err != nil {
+		fetchState = &FetchState{}
+	}
+
 	for _, repo := range workspace.Repositories {
 		repoPath := filepath.Join(workspace.Path, repo.Name)
 		status, err := sc.getRepositoryStatus(ctx, repo, repoPath)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get status for repository %s", repo.Name)
 		}
+		if lastFetch, ok := fetchState.LastFetch(repo.Path); ok {
+			status.LastFetch = &lastFetch
+		}
+		if ref := workspace.PinFor(repo.Name); ref != "" {
+			status.Pinned = ref
+			status.PinDrifted = pinDrifted(ctx, repoPath, ref)
+		}
 		repoStatuses = append(repoStatuses, *status)
 	}
 
 	overall := sc.calculateOverallStatus(repoStatuses)
 
-	return &WorkspaceStatus{
+	status := &WorkspaceStatus{
 		Workspace:    *workspace,
 		Repositories: repoStatuses,
 		Overall:      overall,
-	}, nil
+	}
+	// Setup results are informative, an unreadable file only hides them
+	if setup, err := LoadSetupResults(workspace.Path); err == nil {
+		status.Setup = setup.Results
+	}
+	return status, nil
 }
 
 // getRepositoryStatus gets the git status of a single repository
@@ -49,10 +68,15 @@ func (sc *StatusChecker) getRepositoryStatus(ctx context.Context, repo Repositor
 	// Get current branch
 	if branch, err := sc.getCurrentBranch(ctx, repoPath); err == nil {
 		status.CurrentBranch = branch
+		status.Detached = branch == ""
 	}
 
+	// Files matched by .wsmignore do not make the repository dirty
+	ignoreRules := loadIgnoreRulesOrEmpty(repoPath)
+
 	// Get modified files
 	if modifiedFiles, err := sc.getModifiedFiles(ctx, repoPath); err == nil {
+		modifiedFiles = ignoreRules.Filter(modifiedFiles)
 		status.ModifiedFiles = modifiedFiles
 		status.HasChanges = len(modifiedFiles) > 0
 	}
@@ -67,7 +91,7 @@ func (sc *StatusChecker) getRepositoryStatus(ctx context.Context, repo Repositor
 
 	// Get untracked files
 	if untrackedFiles, err := sc.getUntrackedFiles(ctx, repoPath); err == nil {
-		status.UntrackedFiles = untrackedFiles
+		status.UntrackedFiles = ignoreRules.Filter(untrackedFiles)
 	}
 
 	// Get ahead/behind status
@@ -81,13 +105,25 @@ func (sc *StatusChecker) getRepositoryStatus(ctx context.Context, repo Repositor
 		status.HasConflicts = hasConflicts
 	}
 
-	// Check if branch is merged to origin/main
-	if isMerged, err := CheckBranchMerged(ctx, repoPath); err == nil {
+	if operation, err := sc.getInProgressOperation(ctx, repoPath); err == nil {
+		status.InProgress = operation
+	}
+
+	if stashCount, err := sc.getStashCount(ctx, repoPath, status.CurrentBranch); err == nil {
+		status.StashCount = stashCount
+	}
+
+	if tags, err := sc.getUnpushedTags(ctx, repoPath); err == nil {
+		status.UnpushedTags = tags
+	}
+
+	// Check if branch is merged to the default branch of the base remote (origin, or upstream for forks)
+	if isMerged, err := CheckBranchMerged(ctx, repoPath, repo.BaseRemote()); err == nil {
 		status.IsMerged = isMerged
 	}
 
-	// Check if branch needs to be rebased on origin/main
-	if needsRebase, err := CheckBranchNeedsRebase(ctx, repoPath); err == nil {
+	// Check if branch needs to be rebased on the default branch of the base remote
+	if needsRebase, err := CheckBranchNeedsRebase(ctx, repoPath, repo.BaseRemote()); err == nil {
 		status.NeedsRebase = needsRebase
 	}
 
@@ -213,10 +249,91 @@ func (sc *StatusChecker) hasConflicts(ctx context.Context, repoPath string) (boo
 	return false, nil
 }
 
+// inProgressMarkers are the files of a worktree's git directory revealing an
+// unfinished operation, checked in order
+var inProgressMarkers = []struct{ path, operation string }{
+	{"rebase-merge", "rebase"},
+	{"rebase-apply/applying", "am"},
+	{"rebase-apply", "rebase"},
+	{"MERGE_HEAD", "merge"},
+	{"CHERRY_PICK_HEAD", "cherry-pick"},
+	{"REVERT_HEAD", "revert"},
+	{"BISECT_LOG", "bisect"},
+}
+
+// getInProgressOperation returns the merge, rebase... left unfinished in a worktree
+func (sc *StatusChecker) getInProgressOperation(ctx context.Context, repoPath string) (string, error) {
+	gitDir, err := gitOutput(ctx, repoPath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	for _, marker := range inProgressMarkers {
+		if _, err := os.Stat(filepath.Join(gitDir, marker.path)); err == nil {
+			return marker.operation, nil
+		}
+	}
+	return "", nil
+}
+
+// getStashCount counts the stash entries created on a branch. The stash is
+// shared by all worktrees of a repository, so entries of other branches are left out.
+func (sc *StatusChecker) getStashCount(ctx context.Context, repoPath, branch string) (int, error) {
+	out, err := gitOutput(ctx, repoPath, "stash", "list", "--format=%gs")
+	if err != nil {
+		return 0, err
+	}
+	if branch == "" {
+		branch = "(no branch)"
+	}
+	count := 0
+	for _, subject := range splitLines(out) {
+		if strings.HasPrefix(subject, "WIP on "+branch+":") || strings.HasPrefix(subject, "On "+branch+":") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// getUnpushedTags returns the tags on commits of HEAD that no remote-tracking
+// branch contains, so pushing the tags alone would not be enough
+func (sc *StatusChecker) getUnpushedTags(ctx context.Context, repoPath string) ([]string, error) {
+	remotes, err := gitOutput(ctx, repoPath, "remote")
+	if err != nil || remotes == "" {
+		return nil, err
+	}
+	commits, err := gitOutput(ctx, repoPath, "rev-list", "HEAD", "--not", "--remotes")
+	if err != nil || commits == "" {
+		return nil, err
+	}
+	unpushed := make(map[string]bool)
+	for _, commit := range splitLines(commits) {
+		unpushed[commit] = true
+	}
+
+	refs, err := gitOutput(ctx, repoPath, "for-each-ref", "refs/tags", "--format=%(refname:short) %(objectname) %(*objectname)")
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, line := range splitLines(refs) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		// Annotated tags point at a tag object, peeled to the commit
+		commit := fields[len(fields)-1]
+		if unpushed[commit] {
+			tags = append(tags, fields[0])
+		}
+	}
+	return tags, nil
+}
+
 // calculateOverallStatus determines the overall workspace status
 func (sc *StatusChecker) calculateOverallStatus(repoStatuses []RepositoryStatus) string {
 	hasChanges := false
 	hasConflicts := false
+	inProgress := false
 	needsSync := false
 
 	for _, status := range repoStatuses {
@@ -226,7 +343,11 @@ func (sc *StatusChecker) calculateOverallStatus(repoStatuses []RepositoryStatus)
 		if status.HasConflicts {
 			hasConflicts = true
 		}
-		if status.Ahead > 0 || status.Behind > 0 {
+		if status.InProgress != "" {
+			inProgress = true
+		}
+		// Pinned repositories are never synced
+		if status.Pinned == "" && (status.Ahead > 0 || status.Behind > 0) {
 			needsSync = true
 		}
 	}
@@ -234,6 +355,9 @@ func (sc *StatusChecker) calculateOverallStatus(repoStatuses []RepositoryStatus)
 	if hasConflicts {
 		return "conflicts"
 	}
+	if inProgress {
+		return "in-progress"
+	}
 	if hasChanges {
 		return "modified"
 	}
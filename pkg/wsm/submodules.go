@@ -0,0 +1,186 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Submodule modes of a workspace
+const (
+	// SubmodulesInit initializes the submodules of each repository (the default)
+	SubmodulesInit = "init"
+	// SubmodulesRecursive also initializes nested submodules
+	SubmodulesRecursive = "recursive"
+	// SubmodulesNone leaves submodules uninitialized
+	SubmodulesNone = "none"
+)
+
+// SubmoduleModes lists the accepted submodule modes, for flag completion
+var SubmoduleModes = []string{SubmodulesInit, SubmodulesRecursive, SubmodulesNone}
+
+// ValidateSubmoduleMode rejects unknown submodule modes; empty means the default
+func ValidateSubmoduleMode(mode string) error {
+	if mode == "" || containsValue(SubmoduleModes, mode) {
+		return nil
+	}
+	return errors.Errorf("invalid submodule mode '%s' (expected %s)", mode, strings.Join(SubmoduleModes, ", "))
+}
+
+// SubmoduleMode returns how the submodules of the workspace's worktrees are initialized
+func (w *Workspace) SubmoduleMode() string {
+	if w.Submodules == "" {
+		return SubmodulesInit
+	}
+	return w.Submodules
+}
+
+// submoduleUpdateCommand returns the command initializing the submodules of a
+// worktree, nil when the workspace leaves them alone
+func submoduleUpdateCommand(mode string) []string {
+	switch mode {
+	case SubmodulesNone:
+		return nil
+	case SubmodulesRecursive:
+		return []string{"git", "submodule", "update", "--init", "--recursive"}
+	default:
+		return []string{"git", "submodule", "update", "--init"}
+	}
+}
+
+// hasSubmodules reports whether a checkout declares submodules
+func hasSubmodules(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".gitmodules"))
+	return err == nil
+}
+
+// isSubmoduleCheckout reports whether a directory is the checkout of a submodule,
+// whose .git file points into the modules directory of its parent repository
+func isSubmoduleCheckout(path string) bool {
+	data, err := os.ReadFile(filepath.Join(path, ".git"))
+	if err != nil {
+		return false
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+	return strings.Contains(filepath.ToSlash(gitDir), "/modules/")
+}
+
+// initSubmodules initializes the submodules of a freshly added worktree. The
+// worktree's .gitmodules is checked rather than the registry, which may predate
+// the submodules.
+func (wm *WorkspaceManager) initSubmodules(ctx context.Context, workspace *Workspace, repo Repository) error {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	command := submoduleUpdateCommand(workspace.SubmoduleMode())
+	if command == nil || !hasSubmodules(worktreePath) {
+		return nil
+	}
+
+	if err := wm.ExecuteWorktreeCommand(ctx, worktreePath, command...); err != nil {
+		return errors.Wrapf(err, "failed to initialize submodules of %s (use --submodules none to skip them)", repo.Name)
+	}
+	return nil
+}
+
+// deinitSubmodules prepares a worktree with submodules for `git worktree remove`,
+// which refuses to remove them: the submodules are deinitialized and their
+// repositories, kept in the worktree's git directory, are deleted. Without force,
+// submodules with local modifications or commits not on any remote are kept.
+func deinitSubmodules(ctx context.Context, worktreePath string, force bool) error {
+	if !hasSubmodules(worktreePath) {
+		return nil
+	}
+
+	if !force {
+		unpushed, err := gitOutput(ctx, worktreePath, "submodule", "foreach", "--quiet", "--recursive",
+			`git log --oneline HEAD --not --remotes -- | sed "s|^|$displaypath: |"`)
+		if err != nil {
+			return errors.Wrapf(err, "failed to inspect submodules: %s", unpushed)
+		}
+		if unpushed != "" {
+			return errors.Errorf("submodules have commits not on any remote:\n%s", unpushed)
+		}
+	}
+
+	args := []string{"submodule", "deinit", "--all"}
+	if force {
+		args = append(args, "--force")
+	}
+	if out, err := gitOutput(ctx, worktreePath, args...); err != nil {
+		return errors.Wrapf(err, "failed to deinitialize submodules: %s", out)
+	}
+
+	gitDir, err := gitOutput(ctx, worktreePath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return errors.Wrap(err, "failed to locate the worktree's git directory")
+	}
+	if err := os.RemoveAll(filepath.Join(gitDir, "modules")); err != nil {
+		return errors.Wrap(err, "failed to remove submodule repositories")
+	}
+	return nil
+}
+
+// planSubmoduleUpdate returns the plan step initializing a repository's submodules
+func planSubmoduleUpdate(workspace *Workspace, repo Repository) []PlanStep {
+	command := submoduleUpdateCommand(workspace.SubmoduleMode())
+	if command == nil || (len(repo.Submodules) == 0 && !hasSubmodules(repo.Path)) {
+		return nil
+	}
+
+	return []PlanStep{
+		{
+			Action:      PlanActionCommand,
+			Description: fmt.Sprintf("Initialize the submodules of %s", repo.Name),
+			Dir:         filepath.Join(workspace.Path, repo.Name),
+			Command:     command,
+		},
+	}
+}
+
+// getGitSubmodules reads the submodules declared in a repository's .gitmodules
+func (rd *RepositoryDiscoverer) getGitSubmodules(ctx context.Context, path string) ([]Submodule, error) {
+	if !hasSubmodules(path) {
+		return nil, nil
+	}
+
+	out, err := gitOutput(ctx, path, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.(path|url)$`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read .gitmodules")
+	}
+
+	byName := map[string]*Submodule{}
+	for _, line := range splitLines(out) {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		// submodule.<name>.<path|url>, where <name> may contain dots
+		key = strings.TrimPrefix(key, "submodule.")
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			continue
+		}
+		name, field := key[:dot], key[dot+1:]
+		submodule, ok := byName[name]
+		if !ok {
+			submodule = &Submodule{Name: name}
+			byName[name] = submodule
+		}
+		if field == "path" {
+			submodule.Path = value
+		} else {
+			submodule.URL = value
+		}
+	}
+
+	submodules := make([]Submodule, 0, len(byName))
+	for _, submodule := range byName {
+		submodules = append(submodules, *submodule)
+	}
+	sort.Slice(submodules, func(i, j int) bool { return submodules[i].Path < submodules[j].Path })
+	return submodules, nil
+}
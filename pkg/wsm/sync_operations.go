@@ -35,6 +35,8 @@ type SyncResult struct {
 	BehindBefore int    `json:"behind_before"`
 	AheadAfter   int    `json:"ahead_after"`
 	BehindAfter  int    `json:"behind_after"`
+	// Skipped is set for repositories left untouched, such as pinned ones
+	Skipped bool `json:"skipped,omitempty"`
 }
 
 // SyncOptions configures sync operations
@@ -43,6 +45,10 @@ type SyncOptions struct {
 	Push   bool `json:"push"`
 	Rebase bool `json:"rebase"`
 	DryRun bool `json:"dry_run"`
+	// Confirm acknowledges pushes to protected branches
+	Confirm bool `json:"confirm,omitempty"`
+	// IncludeArchived pushes archived (read-only) repositories too
+	IncludeArchived bool `json:"include_archived,omitempty"`
 }
 
 // SyncWorkspace synchronizes all repositories in the workspace
@@ -59,17 +65,38 @@ func (so *SyncOperations) SyncWorkspace(ctx context.Context, options *SyncOption
 		"dry_run", options.DryRun,
 	)
 
-	for _, repo := range so.workspace.Repositories {
+	progress := output.StartProgress("Syncing", len(so.workspace.Repositories))
+	defer progress.Done()
+
+	for i, repo := range so.workspace.Repositories {
+		if err := checkInterrupted(ctx, "sync", syncResultRepositories(results), repositoryNames(so.workspace.Repositories[i:])); err != nil {
+			return results, err
+		}
+		progress.Step(repo.Name)
+		if ref := so.workspace.PinFor(repo.Name); ref != "" {
+			results = append(results, pinnedSyncResult(repo.Name, ref))
+			continue
+		}
 		repoPath := filepath.Join(so.workspace.Path, repo.Name)
-		result := so.syncRepository(ctx, repo.Name, repoPath, options)
+		result := so.syncRepository(ctx, repo, repoPath, options)
 		results = append(results, result)
 	}
 
 	return results, nil
 }
 
+// syncResultRepositories returns the repositories with a result
+func syncResultRepositories(results []SyncResult) []string {
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Repository)
+	}
+	return names
+}
+
 // syncRepository synchronizes a single repository
-func (so *SyncOperations) syncRepository(ctx context.Context, repoName, repoPath string, options *SyncOptions) SyncResult {
+func (so *SyncOperations) syncRepository(ctx context.Context, repo Repository, repoPath string, options *SyncOptions) SyncResult {
+	repoName := repo.Name
 	result := SyncResult{
 		Repository: repoName,
 		Success:    true,
@@ -99,11 +126,37 @@ func (so *SyncOperations) syncRepository(ctx context.Context, repoName, repoPath
 			return result
 		}
 		result.Pulled = true
+
+		// Forks also refresh the remote their base branch comes from, so the
+		// merged and needs-rebase checks compare with it
+		if repo.UpstreamRemote != "" {
+			if err := so.fetchRemote(ctx, repoPath, repo.UpstreamRemote); err != nil {
+				result.Success = false
+				result.Error = fmt.Sprintf("fetch %s failed: %v", repo.UpstreamRemote, err)
+				return result
+			}
+		}
 	}
 
-	// Push changes if requested
-	if options.Push {
-		if err := so.pushRepository(ctx, repoPath); err != nil {
+	// Push changes if requested; archived repositories are only pulled
+	if options.Push && repo.Archived && !options.IncludeArchived {
+		result.Error = "archived, not pushed"
+	} else if options.Push {
+		if err := so.checkPushPolicy(ctx, repoName, repoPath, options.Confirm); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			return result
+		}
+		if err := checkRepositoryLargeFiles(ctx, repoName, repoPath); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			return result
+		}
+		push := func() error { return so.pushRepository(ctx, repoPath, so.workspace.RemoteFor(repo)) }
+		if so.workspace.Fork != nil {
+			push = func() error { return PushToFork(ctx, repo, repoPath, so.workspace.Fork) }
+		}
+		if err := push(); err != nil {
 			result.Success = false
 			result.Error = fmt.Sprintf("push failed: %v", err)
 			return result
@@ -138,6 +191,9 @@ func (so *SyncOperations) syncRepository(ctx context.Context, repoName, repoPath
 
 // pullRepository pulls changes from remote
 func (so *SyncOperations) pullRepository(ctx context.Context, repoPath string, rebase bool) error {
+	ctx, cancel := GitContext(ctx, "pull")
+	defer cancel()
+
 	var cmd *exec.Cmd
 	if rebase {
 		cmd = exec.CommandContext(ctx, "git", "pull", "--rebase")
@@ -148,19 +204,52 @@ func (so *SyncOperations) pullRepository(ctx context.Context, repoPath string, r
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return errors.Wrapf(err, "git pull failed: %s", string(output))
+		return errors.Wrapf(GitError(ctx, err), "git pull failed: %s", string(output))
 	}
 
 	return nil
 }
 
+// fetchRemote fetches a remote of a repository
+func (so *SyncOperations) fetchRemote(ctx context.Context, repoPath, remote string) error {
+	_, err := gitOutput(ctx, repoPath, "fetch", "--quiet", remote)
+	return err
+}
+
+// checkPushPolicy validates that pushing the current branch of a repository is allowed
+func (so *SyncOperations) checkPushPolicy(ctx context.Context, repoName, repoPath string, confirmed bool) error {
+	policy, err := LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	branch, err := getGitCurrentBranch(ctx, repoPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get current branch of %s", repoName)
+	}
+
+	return policy.Check(PolicyCheck{
+		Action:     PolicyActionPush,
+		Repository: repoName,
+		Branch:     branch,
+		Confirmed:  confirmed,
+	})
+}
+
 // pushRepository pushes changes to remote
-func (so *SyncOperations) pushRepository(ctx context.Context, repoPath string) error {
+func (so *SyncOperations) pushRepository(ctx context.Context, repoPath, remote string) error {
+	if err := CheckGoReplacesNotCommitted(ctx, repoPath); err != nil {
+		return err
+	}
+
 	// First, try a simple push
-	cmd := exec.CommandContext(ctx, "git", "push")
+	pushCtx, cancel := GitContext(ctx, "push")
+	defer cancel()
+	cmd := exec.CommandContext(pushCtx, "git", "push")
 	cmd.Dir = repoPath
 
 	cmdOutput, err := cmd.CombinedOutput()
+	err = GitError(pushCtx, err)
 	if err != nil {
 		// Check if the error is due to missing upstream branch
 		outputStr := string(cmdOutput)
@@ -178,16 +267,19 @@ func (so *SyncOperations) pushRepository(ctx context.Context, repoPath string) e
 
 			// Push with --set-upstream
 			output.LogInfo(
-				fmt.Sprintf("Setting upstream for branch '%s' to origin/%s", currentBranch, currentBranch),
+				fmt.Sprintf("Setting upstream for branch '%s' to %s/%s", currentBranch, remote, currentBranch),
 				"Setting upstream branch",
 				"branch", currentBranch,
+				"remote", remote,
 			)
 
-			pushCmd := exec.CommandContext(ctx, "git", "push", "-u", "origin", currentBranch)
+			upstreamCtx, cancelUpstream := GitContext(ctx, "push")
+			defer cancelUpstream()
+			pushCmd := exec.CommandContext(upstreamCtx, "git", "push", "-u", remote, currentBranch)
 			pushCmd.Dir = repoPath
 			pushOutput, pushErr := pushCmd.CombinedOutput()
 			if pushErr != nil {
-				return errors.Wrapf(pushErr, "git push -u failed: %s", string(pushOutput))
+				return errors.Wrapf(GitError(upstreamCtx, pushErr), "git push -u failed: %s", string(pushOutput))
 			}
 
 			return nil
@@ -258,7 +350,14 @@ func (so *SyncOperations) CreateBranch(ctx context.Context, branchName string, t
 		"track", track,
 	)
 
-	for _, repo := range so.workspace.Repositories {
+	for i, repo := range so.workspace.Repositories {
+		if err := checkInterrupted(ctx, "branch creation", syncResultRepositories(results), repositoryNames(so.workspace.Repositories[i:])); err != nil {
+			return results, err
+		}
+		if ref := so.workspace.PinFor(repo.Name); ref != "" {
+			results = append(results, pinnedSyncResult(repo.Name, ref))
+			continue
+		}
 		repoPath := filepath.Join(so.workspace.Path, repo.Name)
 		result := so.createBranchInRepository(ctx, repo.Name, repoPath, branchName, track)
 		results = append(results, result)
@@ -309,7 +408,14 @@ func (so *SyncOperations) SwitchBranch(ctx context.Context, branchName string) (
 		"branch", branchName,
 	)
 
-	for _, repo := range so.workspace.Repositories {
+	for i, repo := range so.workspace.Repositories {
+		if err := checkInterrupted(ctx, "branch switch", syncResultRepositories(results), repositoryNames(so.workspace.Repositories[i:])); err != nil {
+			return results, err
+		}
+		if ref := so.workspace.PinFor(repo.Name); ref != "" {
+			results = append(results, pinnedSyncResult(repo.Name, ref))
+			continue
+		}
 		repoPath := filepath.Join(so.workspace.Path, repo.Name)
 		result := so.switchBranchInRepository(ctx, repo.Name, repoPath, branchName)
 		results = append(results, result)
@@ -344,48 +450,3 @@ func (so *SyncOperations) switchBranchInRepository(ctx context.Context, repoName
 
 	return result
 }
-
-// GetWorkspaceLog gets commit history across workspace
-func (so *SyncOperations) GetWorkspaceLog(ctx context.Context, since string, oneline bool, limit int) (map[string]string, error) {
-	logs := make(map[string]string)
-
-	for _, repo := range so.workspace.Repositories {
-		repoPath := filepath.Join(so.workspace.Path, repo.Name)
-		log, err := so.getRepositoryLog(ctx, repoPath, since, oneline, limit)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get log for %s", repo.Name)
-		}
-		if log != "" {
-			logs[repo.Name] = log
-		}
-	}
-
-	return logs, nil
-}
-
-// getRepositoryLog gets commit history for a single repository
-func (so *SyncOperations) getRepositoryLog(ctx context.Context, repoPath, since string, oneline bool, limit int) (string, error) {
-	args := []string{"log"}
-
-	if since != "" {
-		args = append(args, "--since", since)
-	}
-
-	if oneline {
-		args = append(args, "--oneline")
-	}
-
-	if limit > 0 {
-		args = append(args, fmt.Sprintf("-%d", limit))
-	}
-
-	cmd := exec.CommandContext(ctx, "git", args...)
-	cmd.Dir = repoPath
-
-	output, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-
-	return string(output), nil
-}
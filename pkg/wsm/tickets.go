@@ -0,0 +1,96 @@
+package wsm
+
+import (
+	"net/url"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TicketRef is the ticket a workspace works on
+type TicketRef struct {
+	ID  string `json:"id"`
+	URL string `json:"url,omitempty"`
+}
+
+// ResolveTicket parses the value of --ticket: a ticket ID such as PROJ-123,
+// linked with the ticket-url of branch-naming.yaml, or the URL of the ticket,
+// whose ID is found with the ticket pattern
+func ResolveTicket(value string) (*TicketRef, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, errors.New("ticket is empty")
+	}
+	naming, err := LoadBranchNaming()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Host == "" {
+			return nil, errors.Errorf("invalid ticket URL '%s'", value)
+		}
+		ticket := &TicketRef{ID: naming.Ticket(parsed.Path), URL: value}
+		if ticket.ID == "" {
+			// e.g. https://github.com/acme/api/issues/42 → 42
+			ticket.ID = path.Base(strings.TrimRight(parsed.Path, "/"))
+		}
+		if ticket.ID == "" || ticket.ID == "/" || ticket.ID == "." {
+			return nil, errors.Errorf("no ticket ID found in '%s'", value)
+		}
+		return ticket, nil
+	}
+
+	if strings.ContainsAny(value, " \t/") {
+		return nil, errors.Errorf("invalid ticket ID '%s'", value)
+	}
+	return &TicketRef{ID: value, URL: naming.TicketLink(value)}, nil
+}
+
+// TicketLink returns the URL of a ticket ID, empty without ticket-url
+func (n *BranchNaming) TicketLink(id string) string {
+	if n.TicketURL == "" || id == "" {
+		return ""
+	}
+	return strings.ReplaceAll(n.TicketURL, "{ticket}", url.PathEscape(id))
+}
+
+// workspaceTicket returns the ticket of a new workspace: the one given with
+// --ticket, else the ticket ID found in its name or branch
+func workspaceTicket(ticket *TicketRef, name, branch string) TicketRef {
+	if ticket != nil {
+		return *ticket
+	}
+	// The naming file was validated when the branch was generated
+	naming, err := LoadBranchNaming()
+	if err != nil {
+		return TicketRef{}
+	}
+	id := naming.Ticket(name)
+	if id == "" {
+		id = naming.Ticket(branch)
+	}
+	return TicketRef{ID: id, URL: naming.TicketLink(id)}
+}
+
+// OpenURL opens a URL in the default browser without waiting for it
+func OpenURL(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "failed to open %s", target)
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
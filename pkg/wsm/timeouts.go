@@ -0,0 +1,162 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// GitTimeouts bounds how long git commands may run, by kind of operation. They
+// are read from timeouts.yaml in the workspace-manager config directory:
+//
+//	network: 2m   # fetch, pull, push, clone, ls-remote
+//	worktree: 5m  # worktree, checkout, merge, rebase, commit, submodule
+//	local: 1m     # status, diff, log, rev-parse and other local commands
+//
+// A zero duration disables the timeout of its kind.
+type GitTimeouts struct {
+	Network  time.Duration
+	Worktree time.Duration
+	Local    time.Duration
+}
+
+// DefaultGitTimeouts are used for kinds missing from timeouts.yaml
+var DefaultGitTimeouts = GitTimeouts{
+	Network:  2 * time.Minute,
+	Worktree: 5 * time.Minute,
+	Local:    time.Minute,
+}
+
+var (
+	gitTimeoutsMu sync.RWMutex
+	gitTimeouts   = DefaultGitTimeouts
+)
+
+// GitTimeoutError reports a git command killed because it exceeded its timeout
+type GitTimeoutError struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (e *GitTimeoutError) Error() string {
+	return fmt.Sprintf("git %s timed out after %s", e.Command, e.Timeout)
+}
+
+// GetGitTimeoutsPath returns the path of the git timeouts file
+func GetGitTimeoutsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "timeouts.yaml"), nil
+}
+
+// LoadGitTimeouts returns the default timeouts overridden by timeouts.yaml
+func LoadGitTimeouts() (GitTimeouts, error) {
+	timeouts := DefaultGitTimeouts
+
+	timeoutsPath, err := GetGitTimeoutsPath()
+	if err != nil {
+		return timeouts, err
+	}
+	data, err := os.ReadFile(timeoutsPath)
+	if os.IsNotExist(err) {
+		return timeouts, nil
+	}
+	if err != nil {
+		return timeouts, errors.Wrap(err, "failed to read git timeouts")
+	}
+
+	var configured map[string]string
+	if err := yaml.Unmarshal(data, &configured); err != nil {
+		return timeouts, errors.Wrapf(err, "failed to parse git timeouts %s", timeoutsPath)
+	}
+	for kind, value := range configured {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return timeouts, errors.Wrapf(err, "invalid %s timeout in %s", kind, timeoutsPath)
+		}
+		switch kind {
+		case "network":
+			timeouts.Network = d
+		case "worktree":
+			timeouts.Worktree = d
+		case "local":
+			timeouts.Local = d
+		default:
+			return timeouts, errors.Errorf("unknown timeout kind '%s' in %s (network, worktree, local)", kind, timeoutsPath)
+		}
+	}
+	return timeouts, nil
+}
+
+// SetGitTimeouts replaces the timeouts applied to git commands
+func SetGitTimeouts(timeouts GitTimeouts) {
+	gitTimeoutsMu.Lock()
+	defer gitTimeoutsMu.Unlock()
+	gitTimeouts = timeouts
+}
+
+// GitTimeoutFor returns the timeout of a git command given its arguments
+func GitTimeoutFor(args ...string) time.Duration {
+	gitTimeoutsMu.RLock()
+	defer gitTimeoutsMu.RUnlock()
+
+	switch gitSubcommand(args) {
+	case "fetch", "pull", "push", "clone", "ls-remote", "remote":
+		return gitTimeouts.Network
+	// Commits run hooks and may wait for a signing passphrase
+	case "worktree", "checkout", "switch", "merge", "rebase", "cherry-pick", "revert", "commit", "submodule", "sparse-checkout", "lfs", "gc":
+		return gitTimeouts.Worktree
+	default:
+		return gitTimeouts.Local
+	}
+}
+
+// gitSubcommand skips global options such as -C <dir> or -c key=value
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-C" || args[i] == "-c":
+			i++
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			return args[i]
+		}
+	}
+	return ""
+}
+
+// GitContext derives the context of a git command, cancelled when the command
+// exceeds the timeout of its operation. Pass the resulting error through
+// GitError to report timeouts readably.
+func GitContext(ctx context.Context, args ...string) (context.Context, context.CancelFunc) {
+	timeout := GitTimeoutFor(args...)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout, &GitTimeoutError{
+		Command: strings.Join(args, " "),
+		Timeout: timeout,
+	})
+}
+
+// GitError replaces the error of a command run with a GitContext by a
+// GitTimeoutError when the command was killed for taking too long
+func GitError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var timeoutErr *GitTimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return err
+}
@@ -0,0 +1,329 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const tmuxLayoutFile = "tmux.yaml"
+
+// TmuxLayout declares the windows and panes of a workspace tmux session.
+// It is read from .wsm/tmux.yaml (or .wsm/profiles/PROFILE/tmux.yaml) in the
+// workspace root and in each repository:
+//
+//	windows:
+//	  - name: code
+//	    repo: app
+//	    layout: main-vertical
+//	    panes:
+//	      - commands: [nvim .]
+//	      - repo: lib
+//	        split: horizontal
+//	        size: 30%
+//	        commands: [go test ./...]
+//	  - name: server
+//	    dir: app/cmd/server
+//	    commands: [make run]
+//
+// With per-repo, the session gets a root window and one window per repository
+// (see PerRepoLayout); declared windows replace the generated window of the
+// same name or follow them.
+type TmuxLayout struct {
+	PerRepo bool         `yaml:"per-repo,omitempty" json:"per_repo,omitempty"`
+	Windows []TmuxWindow `yaml:"windows" json:"windows"`
+}
+
+// tmuxRootWindow names the window of the workspace root in per-repo layouts
+const tmuxRootWindow = "root"
+
+// TmuxWindow is a tmux window. Without panes, the window is a single pane
+// described by its own directory and commands.
+type TmuxWindow struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Repo starts the window in a workspace repository
+	Repo string `yaml:"repo,omitempty" json:"repo,omitempty"`
+	// Dir starts the window in a directory relative to the layout file's base directory
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// Layout is a tmux layout applied once all panes exist (e.g. tiled, main-vertical)
+	Layout   string     `yaml:"layout,omitempty" json:"layout,omitempty"`
+	Commands []string   `yaml:"commands,omitempty" json:"commands,omitempty"`
+	Panes    []TmuxPane `yaml:"panes,omitempty" json:"panes,omitempty"`
+
+	// baseDir is the directory of the workspace or repository the layout was read from
+	baseDir string
+}
+
+// TmuxPane is a pane of a window. The first pane is the window itself; the
+// following ones are created by splitting the active pane.
+type TmuxPane struct {
+	Repo string `yaml:"repo,omitempty" json:"repo,omitempty"`
+	Dir  string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	// Split is "vertical" (stacked, the default) or "horizontal" (side by side)
+	Split string `yaml:"split,omitempty" json:"split,omitempty"`
+	// Size of the new pane in lines/columns or percent, e.g. 20 or 30%
+	Size     string   `yaml:"size,omitempty" json:"size,omitempty"`
+	Commands []string `yaml:"commands,omitempty" json:"commands,omitempty"`
+}
+
+// TmuxService renders tmux layouts into tmux commands for a workspace
+type TmuxService struct {
+	workspace *Workspace
+}
+
+// NewTmuxService creates a tmux service for a workspace
+func NewTmuxService(workspace *Workspace) *TmuxService {
+	return &TmuxService{workspace: workspace}
+}
+
+// LayoutPaths returns the layout files for a profile: the workspace root first,
+// then each repository. Files that do not exist are not returned.
+func (ts *TmuxService) LayoutPaths(profile string) []string {
+	relPath := filepath.Join(".wsm", tmuxLayoutFile)
+	if profile != "" {
+		relPath = filepath.Join(".wsm", "profiles", profile, tmuxLayoutFile)
+	}
+
+	dirs := []string{ts.workspace.Path}
+	for _, repo := range ts.workspace.Repositories {
+		dirs = append(dirs, filepath.Join(ts.workspace.Path, repo.Name))
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		layoutPath := filepath.Join(dir, relPath)
+		if _, err := os.Stat(layoutPath); err == nil {
+			paths = append(paths, layoutPath)
+		}
+	}
+	return paths
+}
+
+// LoadLayout reads and combines the layout files of a profile. Windows of the
+// workspace root come first, followed by the windows declared by repositories.
+// It returns nil if no layout file exists.
+func (ts *TmuxService) LoadLayout(profile string) (*TmuxLayout, error) {
+	paths := ts.LayoutPaths(profile)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	combined := &TmuxLayout{}
+	for _, layoutPath := range paths {
+		data, err := os.ReadFile(layoutPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read tmux layout %s", layoutPath)
+		}
+
+		var layout TmuxLayout
+		if err := yaml.Unmarshal(data, &layout); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse tmux layout %s", layoutPath)
+		}
+
+		// .wsm/[profiles/NAME/]tmux.yaml -> the directory containing .wsm
+		baseDir := filepath.Dir(layoutPath)
+		for filepath.Base(baseDir) != ".wsm" {
+			baseDir = filepath.Dir(baseDir)
+		}
+		baseDir = filepath.Dir(baseDir)
+
+		for i := range layout.Windows {
+			layout.Windows[i].baseDir = baseDir
+		}
+		combined.PerRepo = combined.PerRepo || layout.PerRepo
+		combined.Windows = append(combined.Windows, layout.Windows...)
+	}
+
+	if combined.PerRepo {
+		return ts.PerRepoLayout(combined)
+	}
+	if err := ts.validate(combined); err != nil {
+		return nil, err
+	}
+
+	return combined, nil
+}
+
+// PerRepoLayout returns the layout of a session with a root window and one
+// window per repository, named after it and started in its worktree. Windows
+// of declared, if any, replace the generated window of the same name or are
+// added after them.
+func (ts *TmuxService) PerRepoLayout(declared *TmuxLayout) (*TmuxLayout, error) {
+	layout := &TmuxLayout{PerRepo: true}
+	layout.Windows = append(layout.Windows, TmuxWindow{Name: tmuxRootWindow, baseDir: ts.workspace.Path})
+	for _, repo := range ts.workspace.Repositories {
+		layout.Windows = append(layout.Windows, TmuxWindow{Name: repo.Name, Repo: repo.Name, baseDir: ts.workspace.Path})
+	}
+
+	if declared != nil {
+		generated := len(layout.Windows)
+		for _, window := range declared.Windows {
+			name := window.Name
+			if name == "" {
+				name = window.Repo
+			}
+			replaced := false
+			for i := 0; i < generated && name != ""; i++ {
+				if layout.Windows[i].Name == name {
+					window.Name = name
+					layout.Windows[i] = window
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				layout.Windows = append(layout.Windows, window)
+			}
+		}
+	}
+
+	if err := ts.validate(layout); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// validate checks repositories, splits and window names, and fills in default window names
+func (ts *TmuxService) validate(layout *TmuxLayout) error {
+	if len(layout.Windows) == 0 {
+		return errors.New("tmux layout declares no windows")
+	}
+
+	names := make(map[string]bool)
+	for i := range layout.Windows {
+		window := &layout.Windows[i]
+		if window.Name == "" {
+			window.Name = window.Repo
+		}
+		if window.Name == "" {
+			window.Name = fmt.Sprintf("window-%d", i+1)
+		}
+		if names[window.Name] {
+			return errors.Errorf("tmux layout declares window '%s' twice", window.Name)
+		}
+		names[window.Name] = true
+
+		if len(window.Panes) > 0 && len(window.Commands) > 0 {
+			return errors.Errorf("window '%s': commands belong to its panes when panes are declared", window.Name)
+		}
+		if err := ts.checkRepo(window.Repo); err != nil {
+			return errors.Wrapf(err, "window '%s'", window.Name)
+		}
+		for j, pane := range window.Panes {
+			if err := ts.checkRepo(pane.Repo); err != nil {
+				return errors.Wrapf(err, "window '%s' pane %d", window.Name, j+1)
+			}
+			switch pane.Split {
+			case "", "vertical", "horizontal":
+			default:
+				return errors.Errorf("window '%s' pane %d: split must be 'vertical' or 'horizontal', got '%s'", window.Name, j+1, pane.Split)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ts *TmuxService) checkRepo(repoName string) error {
+	if repoName == "" {
+		return nil
+	}
+	for _, repo := range ts.workspace.Repositories {
+		if repo.Name == repoName {
+			return nil
+		}
+	}
+	return errors.Errorf("repository '%s' is not part of workspace '%s'", repoName, ts.workspace.Name)
+}
+
+// resolveDir returns the working directory for a repo or dir setting, falling back to fallback
+func (ts *TmuxService) resolveDir(repoName, dir, baseDir, fallback string) string {
+	switch {
+	case repoName != "":
+		return filepath.Join(ts.workspace.Path, repoName)
+	case dir == "":
+		return fallback
+	case filepath.IsAbs(expandHome(dir)):
+		return expandHome(dir)
+	default:
+		return filepath.Join(baseDir, dir)
+	}
+}
+
+// Commands renders a layout into the tmux commands that create the session.
+// Window targets use exact names (session:=name); a split leaves the new pane
+// active, so startup commands are sent to the pane that was just created.
+func (ts *TmuxService) Commands(session string, layout *TmuxLayout) [][]string {
+	var commands [][]string
+
+	for i, window := range layout.Windows {
+		windowDir := ts.resolveDir(window.Repo, window.Dir, window.baseDir, window.baseDir)
+		target := fmt.Sprintf("%s:=%s", session, window.Name)
+
+		panes := window.Panes
+		if len(panes) == 0 {
+			panes = []TmuxPane{{Commands: window.Commands}}
+		}
+
+		firstDir := ts.resolveDir(panes[0].Repo, panes[0].Dir, window.baseDir, windowDir)
+		if i == 0 {
+			commands = append(commands, []string{"new-session", "-d", "-s", session, "-n", window.Name, "-c", firstDir})
+		} else {
+			commands = append(commands, []string{"new-window", "-t", session + ":", "-n", window.Name, "-c", firstDir})
+		}
+		commands = append(commands, sendKeysCommands(target, panes[0].Commands)...)
+
+		for _, pane := range panes[1:] {
+			split := []string{"split-window", "-t", target}
+			if pane.Split == "horizontal" {
+				split = append(split, "-h")
+			} else {
+				split = append(split, "-v")
+			}
+			if pane.Size != "" {
+				split = append(split, "-l", pane.Size)
+			}
+			split = append(split, "-c", ts.resolveDir(pane.Repo, pane.Dir, window.baseDir, windowDir))
+			commands = append(commands, split)
+			commands = append(commands, sendKeysCommands(target, pane.Commands)...)
+		}
+
+		if window.Layout != "" {
+			commands = append(commands, []string{"select-layout", "-t", target, window.Layout})
+		}
+	}
+
+	commands = append(commands, []string{"select-window", "-t", fmt.Sprintf("%s:=%s", session, layout.Windows[0].Name)})
+
+	return commands
+}
+
+func sendKeysCommands(target string, lines []string) [][]string {
+	var commands [][]string
+	for _, line := range lines {
+		commands = append(commands, []string{"send-keys", "-t", target, line, "Enter"})
+	}
+	return commands
+}
+
+// CreateSession creates a detached tmux session from a layout. If a command
+// fails, the partially created session is killed.
+func (ts *TmuxService) CreateSession(ctx context.Context, session string, layout *TmuxLayout) error {
+	for _, args := range ts.Commands(session, layout) {
+		cmd := exec.CommandContext(ctx, "tmux", args...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if args[0] != "new-session" {
+				_ = exec.CommandContext(ctx, "tmux", "kill-session", "-t", "="+session).Run()
+			}
+			return errors.Wrapf(err, "tmux %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+		}
+		subsystemLogger(logSubsystemTmux).Debug().Str("session", session).Strs("args", args).Msg("Executed tmux command")
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TmuxStatusOption is the session option holding the status segment, for
+	// use in custom status lines as #{@wsm-status}
+	TmuxStatusOption = "@wsm-status"
+	// tmuxStatusInterval is how often tmux refreshes the status line, in seconds
+	tmuxStatusInterval = "15"
+)
+
+// Segment renders the counts of the status for a status line, e.g. "●2 ↑3 ↓1",
+// or "✓" when the workspace is clean and in sync
+func (ps *PromptStatus) Segment() string {
+	var parts []string
+	if ps.Conflicts > 0 {
+		parts = append(parts, fmt.Sprintf("✗%d", ps.Conflicts))
+	}
+	if ps.Dirty > 0 {
+		parts = append(parts, fmt.Sprintf("●%d", ps.Dirty))
+	}
+	if ps.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", ps.Ahead))
+	}
+	if ps.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", ps.Behind))
+	}
+	if len(parts) == 0 {
+		return "✓"
+	}
+	return strings.Join(parts, " ")
+}
+
+// tmuxEscape escapes the format characters of tmux option values
+func tmuxEscape(s string) string {
+	return strings.ReplaceAll(s, "#", "##")
+}
+
+// StatusBarCommands returns the tmux commands putting the workspace status in
+// front of the session's status-right. statusRight is the status-right the
+// session had; refresh is the command line of 'wsm tmux refresh-status',
+// which tmux runs every status interval.
+func (ts *TmuxService) StatusBarCommands(session, statusRight string, refresh []string) [][]string {
+	segment := fmt.Sprintf("#(%s) ", tmuxEscape(ShellJoin(refresh)))
+	return [][]string{
+		{"set-option", "-t", session, "status-interval", tmuxStatusInterval},
+		{"set-option", "-t", session, "status-right-length", "80"},
+		{"set-option", "-t", session, "status-right", segment + statusRight},
+	}
+}
+
+// tmuxWindow is a window of a session and the directory of its active pane
+type tmuxWindow struct {
+	id   string
+	path string
+}
+
+// listTmuxWindows lists the windows of a session
+func listTmuxWindows(ctx context.Context, session string) ([]tmuxWindow, error) {
+	out, err := exec.CommandContext(ctx, "tmux", "list-windows", "-t", "="+session, "-F", "#{window_id}\t#{pane_current_path}").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the windows of tmux session '%s'", session)
+	}
+	var windows []tmuxWindow
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if id, path, ok := strings.Cut(line, "\t"); ok {
+			windows = append(windows, tmuxWindow{id: id, path: path})
+		}
+	}
+	return windows, nil
+}
+
+// repositoryAt returns the repository of the workspace containing path, if any
+func (ts *TmuxService) repositoryAt(path string) string {
+	path = resolvePath(path)
+	for _, repo := range ts.workspace.Repositories {
+		repoPath := resolvePath(filepath.Join(ts.workspace.Path, repo.Name))
+		if path == repoPath || strings.HasPrefix(path, repoPath+string(filepath.Separator)) {
+			return repo.Name
+		}
+	}
+	return ""
+}
+
+// RefreshStatus stores the status segment in the session's @wsm-status option
+// and titles every window after the repository its active pane is in. Titles
+// only show on windows tmux names automatically, not on those named in
+// tmux.yaml. It returns the segment.
+func (ts *TmuxService) RefreshStatus(ctx context.Context, session string, maxAge time.Duration) (string, error) {
+	status, err := GetPromptStatus(ctx, ts.workspace, maxAge)
+	if status == nil {
+		return "", err
+	}
+	segment := status.Segment()
+
+	if out, err := exec.CommandContext(ctx, "tmux", "set-option", "-t", session, TmuxStatusOption, segment).CombinedOutput(); err != nil {
+		return segment, errors.Wrapf(err, "failed to set %s: %s", TmuxStatusOption, strings.TrimSpace(string(out)))
+	}
+
+	windows, err := listTmuxWindows(ctx, session)
+	if err != nil {
+		return segment, err
+	}
+	for _, window := range windows {
+		args := []string{"set-window-option", "-t", window.id, "automatic-rename-format"}
+		if repo := ts.repositoryAt(window.path); repo != "" {
+			args = append(args, tmuxEscape(repo))
+		} else {
+			args = []string{"set-window-option", "-u", "-t", window.id, "automatic-rename-format"}
+		}
+		if out, err := exec.CommandContext(ctx, "tmux", args...).CombinedOutput(); err != nil {
+			return segment, errors.Wrapf(err, "failed to title window %s: %s", window.id, strings.TrimSpace(string(out)))
+		}
+	}
+	return segment, nil
+}
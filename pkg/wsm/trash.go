@@ -0,0 +1,366 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// TrashGracePeriod is how long trashed workspaces can be restored with `wsm undelete`
+const TrashGracePeriod = 7 * 24 * time.Hour
+
+const (
+	trashDirName       = ".trash"
+	trashEntryFile     = "trash.json"
+	trashWorkspaceDir  = "workspace"
+	trashWorktreeLock  = "trashed by workspace-manager"
+	trashTimestampForm = "20060102-150405"
+)
+
+// TrashEntry is a deleted workspace kept for the grace period.
+// The workspace directory is moved to <workspaces>/.trash/<id>/workspace, next to the
+// live workspaces so the move stays on the same filesystem. Its worktrees are locked
+// so `git worktree prune` keeps them registered until the entry is restored or purged.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Workspace Workspace `json:"workspace"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	dir string
+}
+
+// Dir returns the directory holding the trash entry
+func (e *TrashEntry) Dir() string {
+	return e.dir
+}
+
+// Expired reports whether the grace period of the entry is over
+func (e *TrashEntry) Expired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+func (e *TrashEntry) workspacePath() string {
+	return filepath.Join(e.dir, trashWorkspaceDir)
+}
+
+func (wm *WorkspaceManager) trashDir() string {
+	return filepath.Join(filepath.Dir(wm.workspaceDir), trashDirName)
+}
+
+// TrashWorkspace moves a workspace into the trash instead of deleting it.
+// Worktrees and branches stay registered in their repositories, and the workspace
+// can be restored with RestoreWorkspace until the grace period expires.
+func (wm *WorkspaceManager) TrashWorkspace(ctx context.Context, name string) (*TrashEntry, error) {
+	workspace, err := wm.LoadWorkspace(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", name)
+	}
+
+	if _, err := os.Stat(workspace.Path); err != nil {
+		return nil, errors.Wrapf(err, "workspace directory %s is not accessible", workspace.Path)
+	}
+
+	now := time.Now()
+	entry := &TrashEntry{
+		Workspace: *workspace,
+		DeletedAt: now,
+		ExpiresAt: now.Add(TrashGracePeriod),
+	}
+	entry.dir, entry.ID, err = wm.newTrashDir(name, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := entry.save(); err != nil {
+		_ = os.RemoveAll(entry.dir)
+		return nil, err
+	}
+
+	for _, repo := range workspace.Repositories {
+		worktreePath := filepath.Join(workspace.Path, repo.Name)
+		if _, err := os.Stat(worktreePath); err != nil {
+			continue
+		}
+		if _, err := gitOutput(ctx, repo.Path, "worktree", "lock", "--reason", trashWorktreeLock, worktreePath); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to lock worktree %s: %v", worktreePath, err),
+				"Failed to lock worktree",
+				"repo", repo.Name,
+				"worktree", worktreePath,
+				"error", err,
+			)
+		}
+	}
+
+	if err := os.Rename(workspace.Path, entry.workspacePath()); err != nil {
+		wm.unlockWorktrees(ctx, workspace)
+		_ = os.RemoveAll(entry.dir)
+		return nil, errors.Wrapf(err, "failed to move %s to the trash", workspace.Path)
+	}
+
+	configPath := filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces", name+".json")
+	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to remove workspace configuration: %s", configPath)
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Moved workspace '%s' to the trash: %s", name, entry.dir),
+		"Moved workspace to trash",
+		"workspace", name,
+		"trash", entry.dir,
+	)
+
+	return entry, nil
+}
+
+// newTrashDir creates the directory of a new trash entry and returns it with
+// the entry ID: the workspace name and deletion time, followed by a counter
+// when the workspace was already trashed in the same second
+func (wm *WorkspaceManager) newTrashDir(name string, now time.Time) (string, string, error) {
+	if err := os.MkdirAll(wm.trashDir(), 0755); err != nil {
+		return "", "", errors.Wrap(err, "failed to create trash directory")
+	}
+	base := fmt.Sprintf("%s-%s", name, now.Format(trashTimestampForm))
+	id := base
+	for i := 2; ; i++ {
+		dir := filepath.Join(wm.trashDir(), id)
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, id, nil
+		}
+		if !os.IsExist(err) {
+			return "", "", errors.Wrap(err, "failed to create trash directory")
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// ListTrash returns the trashed workspaces, most recently deleted first
+func (wm *WorkspaceManager) ListTrash() ([]*TrashEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(wm.trashDir(), "*", trashEntryFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search the trash")
+	}
+
+	var entries []*TrashEntry
+	for _, match := range matches {
+		entry, err := loadTrashEntry(filepath.Dir(match))
+		if err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Ignoring unreadable trash entry %s: %v", match, err),
+				"Ignoring unreadable trash entry",
+				"path", match,
+				"error", err,
+			)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+
+	return entries, nil
+}
+
+// FindTrashEntry returns the most recently trashed entry matching a workspace name or entry ID
+func (wm *WorkspaceManager) FindTrashEntry(nameOrID string) (*TrashEntry, error) {
+	entries, err := wm.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.ID == nameOrID || entry.Workspace.Name == nameOrID {
+			return entry, nil
+		}
+	}
+
+	return nil, errors.Errorf("no deleted workspace '%s' in the trash", nameOrID)
+}
+
+// RestoreWorkspace moves a trashed workspace back to its original location,
+// re-registers its worktrees and restores its configuration.
+func (wm *WorkspaceManager) RestoreWorkspace(ctx context.Context, entry *TrashEntry) (*Workspace, error) {
+	workspace := entry.Workspace
+
+	if _, err := wm.LoadWorkspace(workspace.Name); err == nil {
+		return nil, errors.Errorf("workspace '%s' already exists", workspace.Name)
+	}
+	if _, err := os.Stat(workspace.Path); err == nil {
+		return nil, errors.Errorf("cannot restore workspace '%s': %s already exists", workspace.Name, workspace.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(workspace.Path), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create workspace parent directory")
+	}
+	if err := os.Rename(entry.workspacePath(), workspace.Path); err != nil {
+		return nil, errors.Wrapf(err, "failed to move workspace back to %s", workspace.Path)
+	}
+
+	for _, repo := range workspace.Repositories {
+		worktreePath := filepath.Join(workspace.Path, repo.Name)
+		if _, err := os.Stat(worktreePath); err != nil {
+			continue
+		}
+		if _, err := gitOutput(ctx, repo.Path, "worktree", "repair", worktreePath); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to repair worktree %s: %v", worktreePath, err),
+				"Failed to repair worktree",
+				"repo", repo.Name,
+				"worktree", worktreePath,
+				"error", err,
+			)
+		}
+	}
+	wm.unlockWorktrees(ctx, &workspace)
+
+	if err := wm.SaveWorkspace(&workspace); err != nil {
+		return nil, errors.Wrap(err, "failed to restore workspace configuration")
+	}
+
+	if err := os.RemoveAll(entry.dir); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to remove trash entry %s: %v", entry.dir, err),
+			"Failed to remove trash entry",
+			"trash", entry.dir,
+			"error", err,
+		)
+	}
+
+	return &workspace, nil
+}
+
+// PurgeTrash permanently deletes trash entries whose grace period is over, or all entries with all set.
+// It returns the purged entries.
+func (wm *WorkspaceManager) PurgeTrash(ctx context.Context, all bool) ([]*TrashEntry, error) {
+	entries, err := wm.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []*TrashEntry
+	var errs []string
+	for _, entry := range entries {
+		if !all && !entry.Expired() {
+			continue
+		}
+		if err := wm.purgeTrashEntry(ctx, entry); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.ID, err))
+			continue
+		}
+		purged = append(purged, entry)
+	}
+
+	if len(errs) > 0 {
+		return purged, errors.Errorf("failed to purge some trash entries: %s", strings.Join(errs, "; "))
+	}
+
+	return purged, nil
+}
+
+// purgeTrashEntry deletes the files of a trash entry and drops its worktrees from the repositories
+func (wm *WorkspaceManager) purgeTrashEntry(ctx context.Context, entry *TrashEntry) error {
+	// The worktree paths no longer match their registration, so unlock them through
+	// the administrative directory referenced by each worktree's .git file.
+	for _, repo := range entry.Workspace.Repositories {
+		gitFile := filepath.Join(entry.workspacePath(), repo.Name, ".git")
+		data, err := os.ReadFile(gitFile)
+		if err != nil {
+			continue
+		}
+		adminDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "gitdir:"))
+		if !filepath.IsAbs(adminDir) {
+			// Relative to where the worktree was before it was trashed
+			adminDir = filepath.Join(entry.Workspace.Path, repo.Name, adminDir)
+		}
+		if err := os.Remove(filepath.Join(adminDir, "locked")); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to unlock worktree of %s", repo.Name)
+		}
+	}
+
+	if err := os.RemoveAll(entry.dir); err != nil {
+		return errors.Wrapf(err, "failed to remove %s", entry.dir)
+	}
+
+	for _, repo := range entry.Workspace.Repositories {
+		if _, err := gitOutput(ctx, repo.Path, "worktree", "prune"); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("git worktree prune failed in %s: %v", repo.Name, err),
+				"git worktree prune failed",
+				"repo", repo.Name,
+				"error", err,
+			)
+		}
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Purged trashed workspace '%s'", entry.Workspace.Name),
+		"Purged trashed workspace",
+		"workspace", entry.Workspace.Name,
+		"trash", entry.dir,
+	)
+
+	return nil
+}
+
+// unlockWorktrees releases the trash locks of a workspace's worktrees
+func (wm *WorkspaceManager) unlockWorktrees(ctx context.Context, workspace *Workspace) {
+	for _, repo := range workspace.Repositories {
+		worktreePath := filepath.Join(workspace.Path, repo.Name)
+		if _, err := os.Stat(worktreePath); err != nil {
+			continue
+		}
+		// Fails harmlessly when the worktree is not locked
+		_, _ = gitOutput(ctx, repo.Path, "worktree", "unlock", worktreePath)
+	}
+}
+
+// trashedWorkspacePaths returns the original paths of trashed workspaces
+func (wm *WorkspaceManager) trashedWorkspacePaths() []string {
+	entries, err := wm.ListTrash()
+	if err != nil {
+		return nil
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		paths = append(paths, entry.Workspace.Path)
+	}
+	return paths
+}
+
+func (e *TrashEntry) save() error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal trash entry")
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, trashEntryFile), data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write trash entry")
+	}
+	return nil
+}
+
+func loadTrashEntry(dir string) (*TrashEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, trashEntryFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read trash entry")
+	}
+
+	var entry TrashEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, errors.Wrap(err, "failed to parse trash entry")
+	}
+	entry.dir = dir
+
+	return &entry, nil
+}
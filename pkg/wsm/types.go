@@ -15,12 +15,48 @@ type Repository struct {
 	LastCommit    string    `json:"last_commit"`
 	LastUpdated   time.Time `json:"last_updated"`
 	Categories    []string  `json:"categories"`
+
+	// User-managed tags survive rediscovery: added ones are merged into Categories,
+	// removed ones are filtered out of the auto-detected categories.
+	UserCategories     []string `json:"user_categories,omitempty"`
+	ExcludedCategories []string `json:"excluded_categories,omitempty"`
+
+	// Submodules declared in the repository's .gitmodules
+	Submodules []Submodule `json:"submodules,omitempty"`
+
+	// LFS is set when the repository's .gitattributes tracks files with Git LFS
+	LFS bool `json:"lfs,omitempty"`
+
+	// Remote is the primary remote branches are pushed to, tracked on and,
+	// without UpstreamRemote, compared with; empty means origin. It survives
+	// rediscovery.
+	Remote string `json:"remote,omitempty"`
+
+	// UpstreamRemote is the remote base branches are compared with and pulled
+	// from, e.g. "upstream" for forks; empty means Remote. It survives rediscovery.
+	UpstreamRemote string `json:"upstream_remote,omitempty"`
+
+	// CloneOptions makes on-demand clones and fetches partial or shallow, for
+	// very large repositories. It survives rediscovery.
+	CloneOptions *CloneOptions `json:"clone_options,omitempty"`
+
+	// Archived marks a read-only repository, set with 'wsm repos archive' or
+	// from GitHub's archive status: commit and push skip it. It survives rediscovery.
+	Archived bool `json:"archived,omitempty"`
+}
+
+// Submodule is a git submodule of a repository
+type Submodule struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	URL  string `json:"url"`
 }
 
 // RepositoryRegistry stores discovered repositories
 type RepositoryRegistry struct {
-	Repositories []Repository `json:"repositories"`
-	LastScan     time.Time    `json:"last_scan"`
+	Repositories []Repository        `json:"repositories"`
+	LastScan     time.Time           `json:"last_scan"`
+	Groups       map[string][]string `json:"groups,omitempty"` // group name -> repository names
 }
 
 // Workspace represents a multi-repository workspace
@@ -33,6 +69,96 @@ type Workspace struct {
 	Created      time.Time    `json:"created"`
 	GoWorkspace  bool         `json:"go_workspace"`
 	AgentMD      string       `json:"agent_md"`
+	// Ticket is the ticket ID given with --ticket or found in the workspace
+	// name or branch (see branch-naming.yaml), available to commit messages
+	// as {ticket}; TicketURL links it to the issue tracker
+	Ticket    string `json:"ticket,omitempty"`
+	TicketURL string `json:"ticket_url,omitempty"`
+	// Ecosystems lists the root workspace manifests maintained besides go.work
+	// (node: pnpm-workspace.yaml or package.json workspaces, rust: Cargo.toml)
+	Ecosystems []string `json:"ecosystems,omitempty"`
+	// GoReplaces maintains replace directives to sibling worktrees in the go.mod
+	// of consumers, for modules that cannot use go.work. They are removed before commits.
+	GoReplaces bool `json:"go_replaces,omitempty"`
+	// Fork enables the fork-based contribution workflow: branches are pushed to
+	// a fork of every repository and pull requests target the original one
+	Fork *ForkConfig `json:"fork,omitempty"`
+	// Remote overrides the primary remote of every repository of the workspace
+	Remote string `json:"remote,omitempty"`
+	// PendingRepositories holds repositories whose worktree could not be created
+	// during a partial workspace creation. They can be retried with `wsm add --retry-pending`.
+	PendingRepositories []PendingRepository `json:"pending_repositories,omitempty"`
+
+	// RepositoryBaseBranches overrides BaseBranch per repository (e.g. from a manifest)
+	RepositoryBaseBranches map[string]string `json:"repository_base_branches,omitempty"`
+
+	// RepositoryDefaultBranches holds the default branch of each repository's
+	// base remote, detected when its worktree was created. Merge, rebase and
+	// push fall back to it when no base branch is set.
+	RepositoryDefaultBranches map[string]string `json:"repository_default_branches,omitempty"`
+
+	// RepositoryBranches overrides Branch for repositories whose worktree got a
+	// branch of its own, because Branch was checked out elsewhere
+	// (--on-checked-out-branch=link)
+	RepositoryBranches map[string]string `json:"repository_branches,omitempty"`
+
+	// RepositorySparsePaths lists the cone mode sparse checkout directories per repository.
+	// Repositories without an entry are checked out in full.
+	RepositorySparsePaths map[string][]string `json:"repository_sparse_paths,omitempty"`
+
+	// RepositoryPins pins repositories to a tag or commit SHA. Pinned repositories are
+	// checked out detached and treated as read-only: sync, commit, push and rebase skip them.
+	RepositoryPins map[string]string `json:"repository_pins,omitempty"`
+
+	// RepositoryOrigins records the commit and branch each worktree was created from
+	RepositoryOrigins map[string]RepositoryOrigin `json:"repository_origins,omitempty"`
+
+	// GitConfig holds git config overrides (e.g. user.email, commit.gpgsign,
+	// core.hooksPath) written to the config of every worktree with
+	// 'git config --worktree'. They are removed with the workspace.
+	GitConfig map[string]string `json:"git_config,omitempty"`
+	// GitConfigUserKeys lists the keys of GitConfig the user passed with
+	// --git-config; only those may make git run a command
+	GitConfigUserKeys []string `json:"git_config_user_keys,omitempty"`
+	// GitConfigExtensions lists the repositories where wsm enabled
+	// extensions.worktreeConfig for GitConfig or hooks, to disable it again on delete
+	GitConfigExtensions []string `json:"git_config_extensions,omitempty"`
+
+	// HookRepositories lists the repositories whose worktrees use the git
+	// hooks deployed to .wsm/githooks (see git_hooks.go)
+	HookRepositories []string `json:"hook_repositories,omitempty"`
+	// NoHooks disables the deployment of git hooks (--no-hooks)
+	NoHooks bool `json:"no_hooks,omitempty"`
+
+	// Submodules controls how submodules of new worktrees are initialized
+	// (init, recursive or none); empty means init.
+	Submodules string `json:"submodules,omitempty"`
+
+	// Releases records the tag sets created by `wsm release`
+	Releases []Release `json:"releases,omitempty"`
+
+	// Scaffold is the scaffold template directory copied into the workspace at creation
+	Scaffold string `json:"scaffold,omitempty"`
+
+	// NixFlake is set when a flake.nix was generated at creation (see nix.yaml)
+	NixFlake bool `json:"nix_flake,omitempty"`
+
+	// MergeStrategy is how 'wsm merge' merges the workspace branch (see
+	// MergeStrategies); empty means a plain merge
+	MergeStrategy string `json:"merge_strategy,omitempty"`
+
+	// AuxiliaryPaths are non-git directories of the workspace, skipped by git operations
+	AuxiliaryPaths []AuxiliaryPath `json:"auxiliary_paths,omitempty"`
+
+	// Filter is set on views narrowed to some repositories by RepositoryFilter.Apply;
+	// such views are never saved
+	Filter *RepositoryFilter `json:"filter,omitempty"`
+}
+
+// PendingRepository is a repository that still needs a worktree in a workspace
+type PendingRepository struct {
+	Repository Repository `json:"repository"`
+	Error      string     `json:"error"`
 }
 
 // WorkspaceConfig holds workspace management configuration
@@ -42,6 +168,32 @@ type WorkspaceConfig struct {
 	RegistryPath string `json:"registry_path"`
 }
 
+// BaseBranchFor returns the base branch a repository's worktree branch is created from
+func (w *Workspace) BaseBranchFor(repoName string) string {
+	if baseBranch, ok := w.RepositoryBaseBranches[repoName]; ok && baseBranch != "" {
+		return baseBranch
+	}
+	return w.BaseBranch
+}
+
+// BranchFor returns the branch of a repository's worktree
+func (w *Workspace) BranchFor(repoName string) string {
+	if branch, ok := w.RepositoryBranches[repoName]; ok && branch != "" {
+		return branch
+	}
+	return w.Branch
+}
+
+// SparsePathsFor returns the sparse checkout directories of a repository, nil for a full checkout
+func (w *Workspace) SparsePathsFor(repoName string) []string {
+	return w.RepositorySparsePaths[repoName]
+}
+
+// PinFor returns the tag or commit a repository is pinned to, empty if it tracks the workspace branch
+func (w *Workspace) PinFor(repoName string) string {
+	return w.RepositoryPins[repoName]
+}
+
 // RepositoryStatus represents the git status of a repository
 type RepositoryStatus struct {
 	Repository     Repository `json:"repository"`
@@ -53,8 +205,15 @@ type RepositoryStatus struct {
 	Behind         int        `json:"behind"`
 	CurrentBranch  string     `json:"current_branch"`
 	HasConflicts   bool       `json:"has_conflicts"`
-	IsMerged       bool       `json:"is_merged"`    // True if branch is merged to origin/main
-	NeedsRebase    bool       `json:"needs_rebase"` // True if branch needs to be rebased on origin/main
+	IsMerged       bool       `json:"is_merged"`               // True if branch is merged to origin/main
+	NeedsRebase    bool       `json:"needs_rebase"`            // True if branch needs to be rebased on origin/main
+	LastFetch      *time.Time `json:"last_fetch,omitempty"`    // Last fetch (wsm daemon or freshness.yaml), nil if never
+	Pinned         string     `json:"pinned,omitempty"`        // Tag or commit the repository is pinned to
+	PinDrifted     bool       `json:"pin_drifted,omitempty"`   // True if HEAD moved away from the pinned commit
+	Detached       bool       `json:"detached,omitempty"`      // True if HEAD is not on a branch
+	InProgress     string     `json:"in_progress,omitempty"`   // Operation in progress: merge, rebase, am, cherry-pick, revert or bisect
+	StashCount     int        `json:"stash_count"`             // Stash entries created on the current branch
+	UnpushedTags   []string   `json:"unpushed_tags,omitempty"` // Tags on commits no remote branch contains
 }
 
 // WorkspaceStatus represents the overall status of a workspace
@@ -62,6 +221,8 @@ type WorkspaceStatus struct {
 	Workspace    Workspace          `json:"workspace"`
 	Repositories []RepositoryStatus `json:"repositories"`
 	Overall      string             `json:"overall"`
+	// Setup holds the last result of every setup script of the workspace
+	Setup []SetupResult `json:"setup,omitempty"`
 }
 
 // WorktreeInfo tracks information about a created worktree for rollback purposes
@@ -0,0 +1,75 @@
+package wsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// UnregisterResult reports what unregistering a workspace left in place
+type UnregisterResult struct {
+	Workspace string `json:"workspace"`
+	Path      string `json:"path"`
+	// Checkouts are the worktrees kept as git worktrees of their repositories
+	Checkouts []string `json:"checkouts"`
+	// Links are worktrees reused from elsewhere whose links were kept
+	Links  []string `json:"links,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// UnregisterWorkspace deletes a workspace from wsm but keeps its worktrees in
+// place, for working on without wsm. The configuration and the .wsm directory
+// are removed and the shared hooks released; the worktrees stay registered in
+// their repositories, their links repaired with 'git worktree repair', so they
+// can later be moved with 'git worktree move' or removed with 'git worktree
+// remove'. Git config applied to the worktrees is kept.
+func (wm *WorkspaceManager) UnregisterWorkspace(ctx context.Context, name string) (*UnregisterResult, error) {
+	workspace, err := wm.LoadWorkspace(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load workspace '%s'", name)
+	}
+
+	result := &UnregisterResult{Workspace: workspace.Name, Path: workspace.Path, Checkouts: []string{}}
+	for _, repo := range workspace.Repositories {
+		worktreePath := filepath.Join(workspace.Path, repo.Name)
+		info, err := os.Lstat(worktreePath)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			result.Links = append(result.Links, worktreePath)
+			continue
+		}
+
+		// The hooks live in .wsm, which is removed
+		wm.releaseHooks(ctx, workspace, repo)
+		if _, err := gitOutput(ctx, repo.Path, "worktree", "repair", worktreePath); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to repair the worktree: %v", repo.Name, err))
+		}
+		result.Checkouts = append(result.Checkouts, worktreePath)
+	}
+
+	metadataDir := filepath.Join(workspace.Path, ".wsm")
+	if err := os.RemoveAll(metadataDir); err != nil {
+		return nil, errors.Wrapf(err, "failed to remove %s", metadataDir)
+	}
+
+	configPath := filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces", name+".json")
+	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to remove workspace configuration: %s", configPath)
+	}
+	forgetWorkspaceUse(name)
+
+	output.LogInfo(
+		fmt.Sprintf("Unregistered workspace '%s', keeping %d worktree(s)", name, len(result.Checkouts)),
+		"Unregistered workspace",
+		"workspace", name,
+		"worktrees", len(result.Checkouts),
+	)
+
+	return result, nil
+}
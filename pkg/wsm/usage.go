@@ -0,0 +1,262 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/pkg/errors"
+)
+
+// usageRecordInterval throttles writes of the usage log, which commands run
+// from prompts and status lines would otherwise rewrite constantly
+const usageRecordInterval = time.Minute
+
+// UsageLog holds when a wsm command last touched each workspace
+type UsageLog struct {
+	Workspaces map[string]time.Time `json:"workspaces"`
+}
+
+func getUsageLogPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspace-manager", "usage.json"), nil
+}
+
+// LoadUsageLog loads the usage log, returning an empty log if none exists
+func LoadUsageLog() (*UsageLog, error) {
+	usage := &UsageLog{Workspaces: map[string]time.Time{}}
+
+	usagePath, err := getUsageLogPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(usagePath)
+	if os.IsNotExist(err) {
+		return usage, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read usage log")
+	}
+
+	if err := json.Unmarshal(data, usage); err != nil {
+		return nil, errors.Wrap(err, "failed to parse usage log")
+	}
+	if usage.Workspaces == nil {
+		usage.Workspaces = map[string]time.Time{}
+	}
+
+	return usage, nil
+}
+
+// Save writes the usage log atomically so concurrent commands never see a partial file
+func (ul *UsageLog) Save() error {
+	usagePath, err := getUsageLogPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(usagePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to create config directory")
+	}
+
+	data, err := json.MarshalIndent(ul, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal usage log")
+	}
+
+	tmpPath := fmt.Sprintf("%s.%d.tmp", usagePath, os.Getpid())
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write usage log")
+	}
+	if err := os.Rename(tmpPath, usagePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "failed to replace usage log")
+	}
+
+	return nil
+}
+
+// RecordWorkspaceUse marks a workspace as used by a wsm command now
+func RecordWorkspaceUse(name string) error {
+	usage, err := LoadUsageLog()
+	if err != nil {
+		return err
+	}
+	if time.Since(usage.Workspaces[name]) < usageRecordInterval {
+		return nil
+	}
+	usage.Workspaces[name] = time.Now()
+	return usage.Save()
+}
+
+// forgetWorkspaceUse removes a deleted workspace from the usage log
+func forgetWorkspaceUse(name string) {
+	usage, err := LoadUsageLog()
+	if err == nil {
+		if _, ok := usage.Workspaces[name]; !ok {
+			return
+		}
+		delete(usage.Workspaces, name)
+		err = usage.Save()
+	}
+	if err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to remove workspace '%s' from the usage log: %v", name, err),
+			"Failed to remove workspace from usage log",
+			"workspace", name,
+			"error", err,
+		)
+	}
+}
+
+// workspaceActivity is what tells how recently a workspace was used
+type workspaceActivity struct {
+	size int64
+	// lastModified is the newest modification of a file outside .git
+	lastModified time.Time
+	// lastCommand is when a wsm command last touched the workspace, zero if never
+	lastCommand time.Time
+	// lastUsed is the later of both, or the creation time if neither is known
+	lastUsed time.Time
+	missing  bool
+}
+
+// measureActivity measures a workspace's disk usage and when it was last used
+func measureActivity(ctx context.Context, workspace *Workspace, usage *UsageLog) (workspaceActivity, error) {
+	activity := workspaceActivity{lastCommand: usage.Workspaces[workspace.Name]}
+	if _, err := os.Stat(workspace.Path); os.IsNotExist(err) {
+		activity.missing = true
+	} else {
+		activity.size, activity.lastModified, err = directorySize(ctx, workspace.Path)
+		if err != nil {
+			return activity, errors.Wrapf(err, "failed to measure workspace %s", workspace.Name)
+		}
+	}
+
+	activity.lastUsed = workspace.Created
+	for _, t := range []time.Time{activity.lastModified, activity.lastCommand} {
+		if t.After(activity.lastUsed) {
+			activity.lastUsed = t
+		}
+	}
+	return activity, nil
+}
+
+// CleanupSuggestion is a workspace unused long enough to be worth deleting
+type CleanupSuggestion struct {
+	Workspace    string    `json:"workspace"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	LastUsed     time.Time `json:"last_used"`
+	LastModified time.Time `json:"last_modified,omitempty"`
+	// LastCommand is when a wsm command last touched the workspace
+	LastCommand *time.Time `json:"last_command,omitempty"`
+	Missing     bool       `json:"missing,omitempty"`
+	// UnmergedWork lists, per repository, the commits not merged into the
+	// default branch of its base remote yet
+	UnmergedWork []string `json:"unmerged_work,omitempty"`
+	// KeptBecause lists the work gc would lose; wsm gc deletes the workspace
+	// when it is empty
+	KeptBecause []string `json:"kept_because,omitempty"`
+}
+
+// CleanupReport lists the workspaces unused for UnusedFor, least recently
+// used first
+type CleanupReport struct {
+	UnusedFor   time.Duration       `json:"unused_for"`
+	Suggestions []CleanupSuggestion `json:"suggestions"`
+	// Collectable is how many suggestions 'wsm gc' would delete, Reclaimable their size
+	Collectable int   `json:"collectable"`
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// SuggestCleanup finds the workspaces neither touched by a wsm command nor
+// changed on disk for unusedFor, with their disk usage and the work that is
+// not merged yet
+func (wm *WorkspaceManager) SuggestCleanup(ctx context.Context, unusedFor time.Duration) (*CleanupReport, error) {
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	usage, err := LoadUsageLog()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CleanupReport{UnusedFor: unusedFor, Suggestions: []CleanupSuggestion{}}
+	for i := range workspaces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		workspace := &workspaces[i]
+
+		activity, err := measureActivity(ctx, workspace, usage)
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(activity.lastUsed) < unusedFor {
+			continue
+		}
+
+		suggestion := CleanupSuggestion{
+			Workspace:    workspace.Name,
+			Path:         workspace.Path,
+			Size:         activity.size,
+			LastUsed:     activity.lastUsed,
+			LastModified: activity.lastModified,
+			Missing:      activity.missing,
+		}
+		if !activity.lastCommand.IsZero() {
+			suggestion.LastCommand = &activity.lastCommand
+		}
+		if !activity.missing {
+			suggestion.UnmergedWork = unmergedWork(ctx, workspace)
+			suggestion.KeptBecause = gcKeepReasons(wm.CheckDeletePreflight(ctx, workspace))
+		}
+		if len(suggestion.KeptBecause) == 0 {
+			report.Collectable++
+			report.Reclaimable += suggestion.Size
+		}
+		report.Suggestions = append(report.Suggestions, suggestion)
+	}
+
+	sort.Slice(report.Suggestions, func(i, j int) bool {
+		return report.Suggestions[i].LastUsed.Before(report.Suggestions[j].LastUsed)
+	})
+	return report, nil
+}
+
+// unmergedWork counts, per repository, the commits of the worktree's branch
+// that are not in the default branch of its base remote. It does not fetch,
+// so it is as current as the last fetch.
+func unmergedWork(ctx context.Context, workspace *Workspace) []string {
+	var work []string
+	for _, repo := range workspace.Repositories {
+		worktreePath := filepath.Join(workspace.Path, repo.Name)
+		if !isDir(worktreePath) {
+			continue
+		}
+		baseRef := workspace.BaseRefFor(ctx, repo.Name, worktreePath)
+		if !refExists(ctx, worktreePath, baseRef) {
+			continue
+		}
+		out, err := gitOutput(ctx, worktreePath, "rev-list", "--count", "HEAD", "--not", baseRef)
+		if err != nil {
+			continue
+		}
+		if count, err := strconv.Atoi(out); err == nil && count > 0 {
+			work = append(work, fmt.Sprintf("%s: %d commit(s) not merged into %s", repo.Name, count, baseRef))
+		}
+	}
+	return work
+}
@@ -3,8 +3,25 @@ package wsm
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+
+	"github.com/go-go-golems/workspace-manager/pkg/output"
+	"github.com/rs/zerolog"
+)
+
+// Log subsystems, selectable with --log-scope
+const (
+	logSubsystemGit      = "git"
+	logSubsystemWorktree = "worktree"
+	logSubsystemTmux     = "tmux"
 )
 
+// subsystemLogger returns the logger of a subsystem (see output.Logger)
+func subsystemLogger(subsystem string) *zerolog.Logger {
+	logger := output.Logger(subsystem)
+	return &logger
+}
+
 // printJSON prints data as formatted JSON
 func PrintJSON(data interface{}) error {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
@@ -34,3 +51,14 @@ func GetStatusSymbol(status string) string {
 		return status
 	}
 }
+
+// IsCancellationError reports whether an error was caused by the user cancelling an operation
+func IsCancellationError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "cancelled by user") ||
+		strings.Contains(errMsg, "creation cancelled") ||
+		strings.Contains(errMsg, "operation cancelled")
+}
@@ -0,0 +1,357 @@
+package wsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Divergences between a saved workspace and the disk found by VerifyWorkspace
+const (
+	VerifyWorkspaceMissing  = "workspace-missing"
+	VerifyWorktreeMissing   = "worktree-missing"
+	VerifyRepositoryMissing = "repository-missing"
+	VerifyWorktreeDrift     = "worktree-path-drift"
+	VerifyBranchMismatch    = "branch-mismatch"
+	VerifyExtraDirectory    = "extra-directory"
+	VerifyGoWorkMissing     = "go-work-missing"
+	VerifyGoWorkOutOfSync   = "go-work-out-of-sync"
+	VerifyMetadataDrift     = "metadata-drift"
+)
+
+// VerifyFinding is a difference between the saved definition of a workspace
+// (Expected) and what is on disk (Actual)
+type VerifyFinding struct {
+	Kind       string `json:"kind"`
+	Repository string `json:"repository,omitempty"`
+	Expected   string `json:"expected"`
+	Actual     string `json:"actual"`
+	// Hint tells how to reconcile findings --repair does not handle
+	Hint        string `json:"hint,omitempty"`
+	Repairable  bool   `json:"repairable"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repair_error,omitempty"`
+
+	repair func(ctx context.Context) error
+}
+
+// VerifyReport holds the findings of VerifyWorkspace
+type VerifyReport struct {
+	Workspace string          `json:"workspace"`
+	Path      string          `json:"path"`
+	Findings  []VerifyFinding `json:"findings"`
+}
+
+// Unresolved counts the findings left after repairs
+func (r *VerifyReport) Unresolved() int {
+	count := 0
+	for _, finding := range r.Findings {
+		if !finding.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// VerifyWorkspace compares the saved definition of a workspace with the disk:
+// worktrees, their repositories and branches, stray repositories in the
+// workspace directory, go.work and .wsm/wsm.json. With repair set, missing
+// worktrees are recreated, branches switched back when the worktree is clean
+// and generated files rewritten. Nothing is deleted.
+func (wm *WorkspaceManager) VerifyWorkspace(ctx context.Context, workspace *Workspace, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{Workspace: workspace.Name, Path: workspace.Path, Findings: []VerifyFinding{}}
+
+	if !isDir(workspace.Path) {
+		report.Findings = append(report.Findings, VerifyFinding{
+			Kind:     VerifyWorkspaceMissing,
+			Expected: workspace.Path,
+			Actual:   "does not exist",
+			Hint:     fmt.Sprintf("wsm delete %s, then create it again", workspace.Name),
+		})
+		return report, nil
+	}
+
+	for _, repo := range workspace.Repositories {
+		report.Findings = append(report.Findings, wm.verifyRepository(ctx, workspace, repo)...)
+	}
+	report.Findings = append(report.Findings, verifyExtraDirectories(workspace)...)
+	report.Findings = append(report.Findings, wm.verifyGoWork(workspace)...)
+	report.Findings = append(report.Findings, wm.verifyMetadata(workspace)...)
+
+	if !repair {
+		return report, nil
+	}
+	repaired := false
+	for i := range report.Findings {
+		finding := &report.Findings[i]
+		if !finding.Repairable || finding.repair == nil {
+			continue
+		}
+		if err := finding.repair(ctx); err != nil {
+			finding.RepairError = err.Error()
+		} else {
+			finding.Repaired = true
+			repaired = true
+		}
+	}
+	// Recreated worktrees may record branches, origins and git config
+	if repaired {
+		if err := wm.SaveWorkspace(workspace); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// verifyRepository checks the worktree of a repository: that it exists, that
+// it belongs to the registered repository and that it is on its branch
+func (wm *WorkspaceManager) verifyRepository(ctx context.Context, workspace *Workspace, repo Repository) []VerifyFinding {
+	worktreePath := filepath.Join(workspace.Path, repo.Name)
+	newFinding := func(kind, expected, actual string) VerifyFinding {
+		return VerifyFinding{Kind: kind, Repository: repo.Name, Expected: expected, Actual: actual}
+	}
+
+	if !isDir(repo.Path) {
+		finding := newFinding(VerifyRepositoryMissing, repo.Path, "does not exist")
+		finding.Hint = fmt.Sprintf("wsm discover the new location of %s, or wsm remove %s %s", repo.Name, workspace.Name, repo.Name)
+		return []VerifyFinding{finding}
+	}
+
+	if _, err := os.Lstat(filepath.Join(worktreePath, ".git")); err != nil {
+		finding := newFinding(VerifyWorktreeMissing, fmt.Sprintf("worktree %s", worktreePath), "does not exist")
+		if entries, err := os.ReadDir(worktreePath); err == nil && len(entries) > 0 {
+			finding.Actual = "a directory that is not a git worktree"
+			finding.Hint = fmt.Sprintf("move %s away, then run wsm verify --repair", worktreePath)
+			return []VerifyFinding{finding}
+		}
+		finding.Repairable = true
+		finding.repair = func(ctx context.Context) error {
+			return wm.recreateWorktree(ctx, workspace, repo)
+		}
+		return []VerifyFinding{finding}
+	}
+
+	// Reused worktrees (links) live elsewhere and may be on any branch
+	if info, err := os.Lstat(worktreePath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+
+	worktreeCommon, err := gitOutput(ctx, worktreePath, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	repoCommon, repoErr := gitOutput(ctx, repo.Path, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil || repoErr != nil || resolvePath(worktreeCommon) != resolvePath(repoCommon) {
+		actual := "a broken link to its repository"
+		if err == nil {
+			actual = fmt.Sprintf("a worktree of %s", filepath.Dir(worktreeCommon))
+		}
+		finding := newFinding(VerifyWorktreeDrift, fmt.Sprintf("a worktree of %s", repo.Path), actual)
+		finding.Repairable = repoErr == nil
+		finding.repair = func(ctx context.Context) error {
+			_, err := gitOutput(ctx, repo.Path, "worktree", "repair", worktreePath)
+			return err
+		}
+		// The branch cannot be checked until the worktree is repaired
+		return []VerifyFinding{finding}
+	}
+
+	expected := workspace.BranchFor(repo.Name)
+	if workspace.PinFor(repo.Name) != "" || expected == "" {
+		return nil
+	}
+	actual, err := gitOutput(ctx, worktreePath, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		actual = "detached HEAD"
+	}
+	if actual == expected {
+		return nil
+	}
+	finding := newFinding(VerifyBranchMismatch, "branch "+expected, actual)
+	if actual != "detached HEAD" {
+		finding.Actual = "branch " + actual
+	}
+	finding.Repairable = true
+	finding.repair = func(ctx context.Context) error {
+		if changes, _ := gitOutput(ctx, worktreePath, "status", "--porcelain"); changes != "" {
+			return errors.New("the worktree has uncommitted changes")
+		}
+		_, err := gitOutput(ctx, worktreePath, "switch", expected)
+		return err
+	}
+	return []VerifyFinding{finding}
+}
+
+// recreateWorktree adds the missing worktree of a repository again, on its
+// branch if that still exists
+func (wm *WorkspaceManager) recreateWorktree(ctx context.Context, workspace *Workspace, repo Repository) error {
+	// Forget the registration of the deleted worktree
+	if _, err := gitOutput(ctx, repo.Path, "worktree", "prune"); err != nil {
+		return err
+	}
+	if workspace.PinFor(repo.Name) != "" {
+		return wm.addPinnedWorktree(ctx, workspace, repo)
+	}
+	branch := workspace.BranchFor(repo.Name)
+	targetPath := filepath.Join(workspace.Path, repo.Name)
+	if branch != "" {
+		exists, err := wm.CheckBranchExists(ctx, repo.Path, branch)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return wm.addWorktree(ctx, workspace, repo, targetPath, branch)
+		}
+	}
+	return wm.createWorktree(ctx, workspace, repo)
+}
+
+// verifyExtraDirectories reports git checkouts in the workspace directory
+// that are not repositories of the workspace
+func verifyExtraDirectories(workspace *Workspace) []VerifyFinding {
+	entries, err := os.ReadDir(workspace.Path)
+	if err != nil {
+		return nil
+	}
+	known := map[string]bool{".wsm": true}
+	for _, repo := range workspace.Repositories {
+		known[repo.Name] = true
+	}
+	for _, aux := range workspace.AuxiliaryPaths {
+		known[aux.Name] = true
+	}
+
+	var findings []VerifyFinding
+	for _, entry := range entries {
+		path := filepath.Join(workspace.Path, entry.Name())
+		if known[entry.Name()] || !isDir(path) {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err != nil {
+			continue
+		}
+		findings = append(findings, VerifyFinding{
+			Kind:       VerifyExtraDirectory,
+			Repository: entry.Name(),
+			Expected:   "not part of the workspace",
+			Actual:     fmt.Sprintf("git checkout %s", path),
+			Hint:       fmt.Sprintf("wsm add %s %s, or remove %s", workspace.Name, entry.Name(), path),
+		})
+	}
+	return findings
+}
+
+// verifyGoWork checks that go.work exists for Go workspaces and uses exactly
+// the repositories with a go.mod
+func (wm *WorkspaceManager) verifyGoWork(workspace *Workspace) []VerifyFinding {
+	if !workspace.GoWorkspace {
+		return nil
+	}
+	goWorkPath := filepath.Join(workspace.Path, "go.work")
+	data, err := os.ReadFile(goWorkPath)
+	if err != nil {
+		return []VerifyFinding{{
+			Kind:       VerifyGoWorkMissing,
+			Expected:   goWorkPath,
+			Actual:     "does not exist",
+			Repairable: true,
+			repair: func(ctx context.Context) error {
+				return wm.CreateGoWorkspace(workspace)
+			},
+		}}
+	}
+
+	used := make(map[string]bool)
+	for _, use := range parseGoWorkUses(string(data)) {
+		used[goWorkUseDir(use)] = true
+	}
+	var expected, stale []string
+	for _, repo := range workspace.Repositories {
+		if _, err := os.Stat(filepath.Join(workspace.Path, repo.Name, "go.mod")); err == nil {
+			expected = append(expected, repo.Name)
+		}
+	}
+	var missing []string
+	for _, name := range expected {
+		if !used[name] {
+			missing = append(missing, name)
+		}
+	}
+	for use := range used {
+		if !containsValue(expected, use) && !isDir(filepath.Join(workspace.Path, use)) {
+			stale = append(stale, use)
+		}
+	}
+	if len(missing) == 0 && len(stale) == 0 {
+		return nil
+	}
+	sort.Strings(stale)
+
+	var actual []string
+	if len(missing) > 0 {
+		actual = append(actual, "does not use "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		actual = append(actual, "uses missing "+strings.Join(stale, ", "))
+	}
+	return []VerifyFinding{{
+		Kind:       VerifyGoWorkOutOfSync,
+		Expected:   "go.work uses " + strings.Join(expected, ", "),
+		Actual:     strings.Join(actual, "; "),
+		Repairable: true,
+		repair: func(ctx context.Context) error {
+			return wm.UpdateGoWorkspace(workspace, stale...)
+		},
+	}}
+}
+
+// verifyMetadata checks that .wsm/wsm.json describes the workspace as saved
+func (wm *WorkspaceManager) verifyMetadata(workspace *Workspace) []VerifyFinding {
+	metadataPath := filepath.Join(workspace.Path, ".wsm", "wsm.json")
+	repoNames := make([]string, 0, len(workspace.Repositories))
+	for _, repo := range workspace.Repositories {
+		repoNames = append(repoNames, repo.Name)
+	}
+	finding := VerifyFinding{
+		Kind:       VerifyMetadataDrift,
+		Expected:   describeMetadata(workspace.Name, workspace.Path, repoNames),
+		Repairable: true,
+		repair: func(ctx context.Context) error {
+			return wm.createWorkspaceMetadata(workspace)
+		},
+	}
+
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		finding.Actual = metadataPath + " does not exist"
+		return []VerifyFinding{finding}
+	}
+	var metadata WorkspaceMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		finding.Actual = fmt.Sprintf("%s is invalid: %v", metadataPath, err)
+		return []VerifyFinding{finding}
+	}
+	names := make([]string, 0, len(metadata.Repositories))
+	drifted := false
+	for _, repo := range metadata.Repositories {
+		names = append(names, repo.Name)
+		if repo.WorktreePath != filepath.Join(workspace.Path, repo.Name) {
+			drifted = true
+		}
+	}
+	finding.Actual = describeMetadata(metadata.Name, metadata.Path, names)
+	if drifted || finding.Actual != finding.Expected {
+		return []VerifyFinding{finding}
+	}
+	return nil
+}
+
+// describeMetadata summarizes the identity of a workspace for VerifyMetadataDrift
+func describeMetadata(name, path string, repos []string) string {
+	sorted := append([]string{}, repos...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%s at %s with %s", name, path, strings.Join(sorted, ", "))
+}
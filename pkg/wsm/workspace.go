@@ -11,9 +11,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/huh"
 	"github.com/go-go-golems/workspace-manager/pkg/output"
 	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
 )
 
 // WorkspaceManager handles workspace creation and management
@@ -21,6 +21,60 @@ type WorkspaceManager struct {
 	config       *WorkspaceConfig
 	Discoverer   *RepositoryDiscoverer
 	workspaceDir string
+	// CloneDir is where repositories registered without a clone are cloned on demand
+	CloneDir string
+	// GoReplaces enables go.mod replace directives in the workspaces it creates
+	GoReplaces bool
+	// OnExistingBranch is how a workspace branch that already exists is handled
+	// (ExistingBranchOverwrite, Use or Fail); empty asks the user
+	OnExistingBranch string
+	// OnCheckedOutBranch is how a workspace branch already checked out in
+	// another worktree is handled (CheckedOutBranchReuse, Link or Fail); empty
+	// asks the user
+	OnCheckedOutBranch string
+	// NoScripts skips the setup scripts of repositories added to a workspace;
+	// new workspaces take it from CreateOptions
+	NoScripts bool
+}
+
+// CreateOptions are the settings of a new workspace
+type CreateOptions struct {
+	Name         string
+	Repositories []string
+	Branch       string
+	// BaseBranch is the branch the workspace branch starts from; empty uses
+	// the current branch of each repository
+	BaseBranch string
+	// AgentSource is the AGENT.md template copied into the workspace
+	AgentSource string
+	SparsePaths map[string][]string
+	Pins        map[string]string
+	Submodules  string
+	// Scaffold is the scaffold template (name or path); empty uses the default
+	// template when there is one, NoScaffold none
+	Scaffold      string
+	MergeStrategy string
+	// Ticket is the ticket of the workspace (--ticket); nil looks for a ticket
+	// ID in the workspace name
+	Ticket *TicketRef
+	// GitConfig is the per-worktree git config (--git-config)
+	GitConfig map[string]string
+	// Nix overrides the enabled switch of nix.yaml
+	Nix *bool
+	// GoReplaces enables go.mod replace directives between the worktrees
+	GoReplaces bool
+	// Sanitize turns invalid workspace and branch names into valid ones
+	// instead of rejecting them
+	Sanitize  bool
+	NoScripts bool
+	NoHooks   bool
+	DryRun    bool
+	// Partial records repositories whose worktree cannot be created as pending
+	// instead of rolling back the whole workspace
+	Partial bool
+	// Journaled records progress under .wsm/, and a failure keeps the created
+	// worktrees so the creation can be finished with ResumeWorkspaceCreation
+	Journaled bool
 }
 
 func getRegistryPath() (string, error) {
@@ -56,51 +110,139 @@ func NewWorkspaceManager() (*WorkspaceManager, error) {
 }
 
 // CreateWorkspace creates a new multi-repository workspace
-func (wm *WorkspaceManager) CreateWorkspace(ctx context.Context, name string, repoNames []string, branch string, baseBranch string, agentSource string, dryRun bool) (*Workspace, error) {
+func (wm *WorkspaceManager) CreateWorkspace(ctx context.Context, opts CreateOptions) (*Workspace, error) {
 	// Validate input
-	if name == "" {
-		return nil, errors.New("workspace name is required")
+	var err error
+	opts.Name, opts.Branch, err = wm.prepareWorkspaceNames(opts.Name, opts.Branch, opts.Sanitize)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find repositories
+	// Find repositories, registering paths and remote URLs on the fly
+	repoNames, err := wm.Discoverer.RegisterRepositoryLocations(ctx, opts.Repositories, opts.DryRun)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register repositories")
+	}
 	repos, err := wm.FindRepositories(repoNames)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to find repositories")
 	}
+	repos, err = wm.EnsureCloned(ctx, repos, opts.DryRun)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone repositories")
+	}
+
+	workspace, err := wm.newWorkspace(repos, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := wm.createNewWorkspace(ctx, workspace, opts); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// newWorkspace checks the settings of a new workspace of repos and builds its
+// configuration without touching disk. opts.Name and opts.Branch went through
+// prepareWorkspaceNames.
+func (wm *WorkspaceManager) newWorkspace(repos []Repository, opts CreateOptions) (*Workspace, error) {
+	if err := validateSparseRepositories(opts.SparsePaths, repos); err != nil {
+		return nil, err
+	}
+	if err := validatePinnedRepositories(opts.Pins, repos); err != nil {
+		return nil, err
+	}
+	if err := ValidateMergeStrategy(opts.MergeStrategy); err != nil {
+		return nil, err
+	}
+	if err := ValidateSubmoduleMode(opts.Submodules); err != nil {
+		return nil, err
+	}
+	scaffold, err := wm.ResolveScaffold(opts.Scaffold)
+	if err != nil {
+		return nil, err
+	}
+	nixFlake, err := useNixFlake(opts.Nix)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create workspace directory path
-	workspacePath := filepath.Join(wm.workspaceDir, name)
+	workspacePath := filepath.Join(wm.workspaceDir, opts.Name)
+	if err := ValidateWorkspacePath(workspacePath, repositoryNames(repos)); err != nil {
+		return nil, err
+	}
+
+	ticket := workspaceTicket(opts.Ticket, opts.Name, opts.Branch)
+	return &Workspace{
+		Name:                  opts.Name,
+		Path:                  workspacePath,
+		Repositories:          repos,
+		Branch:                opts.Branch,
+		BaseBranch:            opts.BaseBranch,
+		Ticket:                ticket.ID,
+		TicketURL:             ticket.URL,
+		Created:               time.Now(),
+		GoWorkspace:           wm.shouldCreateGoWorkspace(repos),
+		Ecosystems:            detectEcosystems(repos),
+		GoReplaces:            opts.GoReplaces,
+		AgentMD:               opts.AgentSource,
+		RepositorySparsePaths: opts.SparsePaths,
+		RepositoryPins:        opts.Pins,
+		Submodules:            opts.Submodules,
+		Scaffold:              scaffold,
+		MergeStrategy:         opts.MergeStrategy,
+		NixFlake:              nixFlake,
+		GitConfig:             opts.GitConfig,
+		GitConfigUserKeys:     sortedKeys(opts.GitConfig),
+		NoHooks:               opts.NoHooks,
+	}, nil
+}
 
-	workspace := &Workspace{
-		Name:         name,
-		Path:         workspacePath,
-		Repositories: repos,
-		Branch:       branch,
-		BaseBranch:   baseBranch,
-		Created:      time.Now(),
-		GoWorkspace:  wm.shouldCreateGoWorkspace(repos),
-		AgentMD:      agentSource,
+// createNewWorkspace creates a workspace built by newWorkspace on disk, unless
+// opts is a dry run
+func (wm *WorkspaceManager) createNewWorkspace(ctx context.Context, workspace *Workspace, opts CreateOptions) error {
+	if opts.DryRun {
+		return nil
 	}
 
-	if dryRun {
-		return workspace, nil
+	var journal *CreateJournal
+	if opts.Journaled {
+		journal = newCreateJournal(workspace, opts.Partial)
 	}
+	return wm.buildWorkspace(ctx, workspace, opts, journal)
+}
 
+// buildWorkspace creates the workspace on disk and saves its configuration.
+// journal is nil for non-journaled creations.
+func (wm *WorkspaceManager) buildWorkspace(ctx context.Context, workspace *Workspace, opts CreateOptions, journal *CreateJournal) error {
 	// Create workspace
-	if err := wm.createWorkspaceStructure(ctx, workspace); err != nil {
-		return nil, errors.Wrap(err, "failed to create workspace structure")
+	if err := wm.createWorkspaceStructure(ctx, workspace, opts, journal); err != nil {
+		return errors.Wrap(err, "failed to create workspace structure")
 	}
 
 	// Save workspace configuration
 	if err := wm.SaveWorkspace(workspace); err != nil {
-		return nil, errors.Wrap(err, "failed to save workspace configuration")
+		if journal != nil {
+			journal.recordFailure(err)
+		}
+		return errors.Wrap(err, "failed to save workspace configuration")
 	}
 
-	return workspace, nil
+	if journal != nil {
+		journal.remove()
+	}
+
+	return nil
 }
 
-// findRepositories finds repositories by name
+// FindRepositories finds repositories by name; "@group" entries expand to the group's repositories
 func (wm *WorkspaceManager) FindRepositories(repoNames []string) ([]Repository, error) {
+	repoNames, err := wm.Discoverer.ExpandGroups(repoNames)
+	if err != nil {
+		return nil, err
+	}
+
 	allRepos := wm.Discoverer.GetRepositories()
 	repoMap := make(map[string]Repository)
 
@@ -139,7 +281,7 @@ func (wm *WorkspaceManager) shouldCreateGoWorkspace(repos []Repository) bool {
 }
 
 // createWorkspaceStructure creates the physical workspace structure
-func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, workspace *Workspace) error {
+func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, workspace *Workspace, opts CreateOptions, journal *CreateJournal) error {
 	output.LogInfo(
 		fmt.Sprintf("Creating workspace structure for '%s'", workspace.Name),
 		"Creating workspace structure",
@@ -151,18 +293,58 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 		return errors.Wrapf(err, "failed to create workspace directory: %s", workspace.Path)
 	}
 
+	if journal != nil {
+		if err := journal.save(); err != nil {
+			return errors.Wrap(err, "failed to write create journal")
+		}
+	}
+
 	// Track successfully created worktrees for rollback
 	var createdWorktrees []WorktreeInfo
+	var createdRepos []Repository
+
+	progress := output.StartProgress("Creating worktrees", len(workspace.Repositories))
+	defer progress.Done()
 
 	// Create worktrees for each repository
-	for _, repo := range workspace.Repositories {
+	for i, repo := range workspace.Repositories {
+		if err := checkInterrupted(ctx, "workspace creation", repositoryNames(createdRepos), repositoryNames(workspace.Repositories[i:])); err != nil {
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees, err)
+		}
+		progress.Step(repo.Name)
 		worktreeInfo := WorktreeInfo{
 			Repository: repo,
 			TargetPath: filepath.Join(workspace.Path, repo.Name),
 			Branch:     workspace.Branch,
 		}
 
+		// When resuming, keep worktrees created by the interrupted run
+		if journal != nil && wm.isResumableWorktree(ctx, worktreeInfo.TargetPath, workspace.Branch) {
+			output.PrintInfo("Worktree for '%s' already exists, skipping", repo.Name)
+			if err := journal.markCompleted(repo.Name); err != nil {
+				return errors.Wrap(err, "failed to update create journal")
+			}
+			createdWorktrees = append(createdWorktrees, worktreeInfo)
+			createdRepos = append(createdRepos, repo)
+			continue
+		}
+
 		if err := wm.createWorktree(ctx, workspace, repo); err != nil {
+			// In partial mode, record the failure and keep going unless the user cancelled
+			if opts.Partial && !IsCancellationError(err) && ctx.Err() == nil {
+				output.LogWarn(
+					fmt.Sprintf("Failed to create worktree for repository '%s', marking it as pending", repo.Name),
+					"Failed to create worktree, marking repository as pending",
+					"repo", repo.Name,
+					"error", err,
+				)
+				workspace.PendingRepositories = append(workspace.PendingRepositories, PendingRepository{
+					Repository: repo,
+					Error:      err.Error(),
+				})
+				continue
+			}
+
 			// Rollback any worktrees created so far
 			output.LogError(
 				fmt.Sprintf("Failed to create worktree for repository '%s'", repo.Name),
@@ -172,13 +354,18 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 				"error", err,
 			)
 
-			wm.rollbackWorktrees(ctx, createdWorktrees)
-			wm.cleanupWorkspaceDirectory(workspace.Path)
-			return errors.Wrapf(err, "failed to create worktree for %s", repo.Name)
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees,
+				errors.Wrapf(err, "failed to create worktree for %s", repo.Name))
 		}
 
 		// Track successful creation
 		createdWorktrees = append(createdWorktrees, worktreeInfo)
+		createdRepos = append(createdRepos, repo)
+		if journal != nil {
+			if err := journal.markCompleted(repo.Name); err != nil {
+				return errors.Wrap(err, "failed to update create journal")
+			}
+		}
 		output.LogInfo(
 			fmt.Sprintf("Successfully created worktree for '%s'", repo.Name),
 			"Successfully created worktree",
@@ -187,17 +374,24 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 		)
 	}
 
-	// Create go.work file if needed
-	if workspace.GoWorkspace {
-		if err := wm.CreateGoWorkspace(workspace); err != nil {
+	if len(workspace.PendingRepositories) > 0 {
+		if len(createdRepos) == 0 {
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, nil,
+				errors.New("failed to create worktrees for all repositories"))
+		}
+		workspace.Repositories = createdRepos
+	}
+
+	// Create go.work and the other workspace manifests if needed
+	if workspace.HasWorkspaceManifests() {
+		if err := wm.UpdateWorkspaceManifests(workspace); err != nil {
 			output.LogError(
-				"Failed to create go.work file",
-				"Failed to create go.work file, rolling back worktrees",
+				"Failed to create workspace manifests",
+				"Failed to create workspace manifests, rolling back worktrees",
 				"error", err,
 			)
-			wm.rollbackWorktrees(ctx, createdWorktrees)
-			wm.cleanupWorkspaceDirectory(workspace.Path)
-			return errors.Wrap(err, "failed to create go.work file")
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees,
+				errors.Wrap(err, "failed to create workspace manifests"))
 		}
 	}
 
@@ -209,9 +403,21 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 				"Failed to copy AGENT.md, rolling back worktrees",
 				"error", err,
 			)
-			wm.rollbackWorktrees(ctx, createdWorktrees)
-			wm.cleanupWorkspaceDirectory(workspace.Path)
-			return errors.Wrap(err, "failed to copy AGENT.md")
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees,
+				errors.Wrap(err, "failed to copy AGENT.md"))
+		}
+	}
+
+	if workspace.Scaffold != "" {
+		if err := wm.applyScaffold(workspace); err != nil {
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees, err)
+		}
+	}
+
+	if workspace.NixFlake {
+		if err := wm.applyNixFlake(ctx, workspace); err != nil {
+			return wm.abortWorkspaceCreation(ctx, workspace, journal, createdWorktrees,
+				errors.Wrap(err, "failed to generate flake.nix"))
 		}
 	}
 
@@ -234,7 +440,7 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 	)
 
 	// Execute setup scripts if they exist
-	if err := wm.executeSetupScripts(ctx, workspace); err != nil {
+	if err := wm.executeSetupScripts(ctx, workspace, opts.NoScripts); err != nil {
 		output.LogWarn(
 			fmt.Sprintf("Failed to execute setup scripts for workspace '%s'", workspace.Name),
 			"Setup scripts failed, workspace created but setup incomplete",
@@ -247,9 +453,24 @@ func (wm *WorkspaceManager) createWorkspaceStructure(ctx context.Context, worksp
 	return nil
 }
 
+// abortWorkspaceCreation rolls back a failed creation, or keeps its progress when journaled
+func (wm *WorkspaceManager) abortWorkspaceCreation(ctx context.Context, workspace *Workspace, journal *CreateJournal, createdWorktrees []WorktreeInfo, err error) error {
+	if journal != nil {
+		journal.recordFailure(err)
+		output.PrintWarning("Kept %d created worktrees; finish with: wsm create %s --resume", len(createdWorktrees), workspace.Name)
+		return err
+	}
+
+	// Roll back even when the creation was interrupted
+	wm.rollbackWorktrees(context.WithoutCancel(ctx), createdWorktrees)
+	wm.cleanupWorkspaceDirectory(workspace.Path)
+	return err
+}
+
 // createWorktree creates a git worktree for a repository
 func (wm *WorkspaceManager) createWorktree(ctx context.Context, workspace *Workspace, repo Repository) error {
 	targetPath := filepath.Join(workspace.Path, repo.Name)
+	baseBranch := workspace.BaseBranchFor(repo.Name)
 
 	output.LogInfo(
 		fmt.Sprintf("Creating worktree for '%s' on branch '%s'", repo.Name, workspace.Branch),
@@ -259,9 +480,13 @@ func (wm *WorkspaceManager) createWorktree(ctx context.Context, workspace *Works
 		"target", targetPath,
 	)
 
+	if workspace.PinFor(repo.Name) != "" {
+		return wm.addPinnedWorktree(ctx, workspace, repo)
+	}
+
 	if workspace.Branch == "" {
 		// No specific branch, create worktree from current branch
-		return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", targetPath)
+		return wm.addWorktree(ctx, workspace, repo, targetPath)
 	}
 
 	// Check if branch exists locally
@@ -271,7 +496,8 @@ func (wm *WorkspaceManager) createWorktree(ctx context.Context, workspace *Works
 	}
 
 	// Check if branch exists remotely
-	remoteBranchExists, err := wm.CheckRemoteBranchExists(ctx, repo.Path, workspace.Branch)
+	remote := workspace.RemoteFor(repo)
+	remoteBranchExists, err := wm.CheckRemoteBranchExists(ctx, repo.Path, remote, workspace.Branch)
 	if err != nil {
 		output.LogWarn(
 			fmt.Sprintf("Could not check if remote branch '%s' exists", workspace.Branch),
@@ -283,54 +509,39 @@ func (wm *WorkspaceManager) createWorktree(ctx context.Context, workspace *Works
 
 	fmt.Printf("\nBranch status for %s:\n", repo.Name)
 	fmt.Printf("  Local branch '%s' exists: %v\n", workspace.Branch, branchExists)
-	fmt.Printf("  Remote branch 'origin/%s' exists: %v\n", workspace.Branch, remoteBranchExists)
+	fmt.Printf("  Remote branch '%s/%s' exists: %v\n", remote, workspace.Branch, remoteBranchExists)
 
 	if branchExists {
-		// Branch exists locally - ask user what to do using huh
-		output.PrintWarning("Branch '%s' already exists in repository '%s'", workspace.Branch, repo.Name)
-
-		var choice string
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewSelect[string]().
-					Title("How would you like to handle the existing branch?").
-					Options(
-						huh.NewOption("Overwrite the existing branch (git worktree add -B)", "overwrite"),
-						huh.NewOption("Use the existing branch as-is (git worktree add)", "use"),
-						huh.NewOption("Cancel workspace creation", "cancel"),
-					).
-					Value(&choice),
-			),
-		)
-
-		err := form.Run()
+		if handled, err := wm.addCheckedOutWorktree(ctx, workspace, repo, workspace.Branch); handled {
+			return err
+		}
+		choice, err := wm.existingBranchPolicy(repo.Name, workspace.Branch)
 		if err != nil {
-			// Check if user cancelled/aborted the form
-			errMsg := strings.ToLower(err.Error())
-			if strings.Contains(errMsg, "user aborted") ||
-				strings.Contains(errMsg, "cancelled") ||
-				strings.Contains(errMsg, "aborted") ||
-				strings.Contains(errMsg, "interrupt") {
-				return errors.New("workspace creation cancelled by user")
+			return err
+		}
+		if choice == ExistingBranchAsk {
+			if choice, err = askExistingBranch(repo.Name, workspace.Branch); err != nil {
+				return err
 			}
-			return errors.Wrap(err, "failed to get user choice")
 		}
 
 		switch choice {
-		case "overwrite":
+		case ExistingBranchOverwrite:
 			output.PrintInfo("Overwriting branch '%s'...", workspace.Branch)
 			if remoteBranchExists {
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", workspace.Branch, targetPath, "origin/"+workspace.Branch)
-			} else if workspace.BaseBranch != "" {
-				output.PrintInfo("Creating new branch '%s' from '%s'...", workspace.Branch, workspace.BaseBranch)
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", workspace.Branch, targetPath, workspace.BaseBranch)
+				return wm.addWorktree(ctx, workspace, repo, "-B", workspace.Branch, targetPath, remote+"/"+workspace.Branch)
+			} else if baseBranch != "" {
+				output.PrintInfo("Creating new branch '%s' from '%s'...", workspace.Branch, baseBranch)
+				return wm.addWorktree(ctx, workspace, repo, "-B", workspace.Branch, targetPath, baseBranch)
 			} else {
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", workspace.Branch, targetPath)
+				return wm.addWorktree(ctx, workspace, repo, "-B", workspace.Branch, targetPath)
 			}
-		case "use":
+		case ExistingBranchUse:
 			output.PrintInfo("Using existing branch '%s'...", workspace.Branch)
-			return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", targetPath, workspace.Branch)
-		case "cancel":
+			return wm.addWorktree(ctx, workspace, repo, targetPath, workspace.Branch)
+		case ExistingBranchFail:
+			return existingBranchError(repo.Name, workspace.Branch)
+		case existingBranchCancel:
 			return errors.New("workspace creation cancelled by user")
 		default:
 			return errors.New("invalid choice, workspace creation cancelled")
@@ -338,15 +549,15 @@ func (wm *WorkspaceManager) createWorktree(ctx context.Context, workspace *Works
 	} else {
 		// Branch doesn't exist locally
 		if remoteBranchExists {
-			output.PrintInfo("Creating worktree from remote branch origin/%s...", workspace.Branch)
-			return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-b", workspace.Branch, targetPath, "origin/"+workspace.Branch)
+			output.PrintInfo("Creating worktree from remote branch %s/%s...", remote, workspace.Branch)
+			return wm.addWorktree(ctx, workspace, repo, "-b", workspace.Branch, targetPath, remote+"/"+workspace.Branch)
 		} else {
-			if workspace.BaseBranch != "" {
-				output.PrintInfo("Creating new branch '%s' from '%s' and worktree...", workspace.Branch, workspace.BaseBranch)
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-b", workspace.Branch, targetPath, workspace.BaseBranch)
+			if baseBranch != "" {
+				output.PrintInfo("Creating new branch '%s' from '%s' and worktree...", workspace.Branch, baseBranch)
+				return wm.addWorktree(ctx, workspace, repo, "-b", workspace.Branch, targetPath, baseBranch)
 			} else {
 				output.PrintInfo("Creating new branch '%s' and worktree...", workspace.Branch)
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-b", workspace.Branch, targetPath)
+				return wm.addWorktree(ctx, workspace, repo, "-b", workspace.Branch, targetPath)
 			}
 		}
 	}
@@ -360,9 +571,9 @@ func (wm *WorkspaceManager) CheckBranchExists(ctx context.Context, repoPath, bra
 	return err == nil, nil
 }
 
-// checkRemoteBranchExists checks if a remote branch exists
-func (wm *WorkspaceManager) CheckRemoteBranchExists(ctx context.Context, repoPath, branch string) (bool, error) {
-	cmd := exec.CommandContext(ctx, "git", "show-ref", "--verify", "--quiet", "refs/remotes/origin/"+branch)
+// checkRemoteBranchExists checks if a branch exists on a remote
+func (wm *WorkspaceManager) CheckRemoteBranchExists(ctx context.Context, repoPath, remote, branch string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch)
 	cmd.Dir = repoPath
 	err := cmd.Run()
 	return err == nil, nil
@@ -370,6 +581,12 @@ func (wm *WorkspaceManager) CheckRemoteBranchExists(ctx context.Context, repoPat
 
 // executeWorktreeCommand executes a git worktree command with proper logging and error handling
 func (wm *WorkspaceManager) ExecuteWorktreeCommand(ctx context.Context, repoPath string, args ...string) error {
+	if args[0] == "git" {
+		var cancel context.CancelFunc
+		ctx, cancel = GitContext(ctx, args[1:]...)
+		defer cancel()
+	}
+
 	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	cmd.Dir = repoPath
 
@@ -383,7 +600,16 @@ func (wm *WorkspaceManager) ExecuteWorktreeCommand(ctx context.Context, repoPath
 		"repoPath", repoPath,
 	)
 
+	start := time.Now()
 	cmdOutput, err := cmd.CombinedOutput()
+	err = GitError(ctx, err)
+	subsystemLogger(logSubsystemWorktree).Debug().
+		Str("command", cmdStr).
+		Str("dir", repoPath).
+		Dur("duration", time.Since(start)).
+		Str("output", string(cmdOutput)).
+		Err(err).
+		Msg("Ran worktree command")
 	if err != nil {
 		fmt.Printf("❌ Command failed: %s\n", cmdStr)
 		fmt.Printf("   Error: %v\n", err)
@@ -446,19 +672,91 @@ func (wm *WorkspaceManager) getGoVersion(ctx context.Context) (string, error) {
 	return fmt.Sprintf("%s.%s", versionParts[0], versionParts[1]), nil
 }
 
-// createGoWorkspace creates a go.work file
+// CreateGoWorkspace creates the go.work file of a workspace, or updates an
+// existing one so it uses every repository with a go.mod
 func (wm *WorkspaceManager) CreateGoWorkspace(workspace *Workspace) error {
+	return wm.UpdateGoWorkspace(workspace)
+}
+
+// UpdateGoWorkspace edits go.work in place: it adds a use directive for each
+// workspace repository with a go.mod and drops the ones of removedRepos. Other
+// directives and comments, such as replace directives added by hand, are kept.
+func (wm *WorkspaceManager) UpdateGoWorkspace(workspace *Workspace, removedRepos ...string) error {
 	goWorkPath := filepath.Join(workspace.Path, "go.work")
+	if _, err := os.Stat(goWorkPath); os.IsNotExist(err) {
+		output.LogInfo(
+			fmt.Sprintf("Creating go.work file at %s", goWorkPath),
+			"Creating go.work file",
+			"path", goWorkPath,
+		)
+	}
 
-	output.LogInfo(
-		fmt.Sprintf("Creating go.work file at %s", goWorkPath),
-		"Creating go.work file",
-		"path", goWorkPath,
-	)
+	content, err := wm.renderGoWork(workspace, func(repo Repository) string {
+		return filepath.Join(workspace.Path, repo.Name)
+	}, removedRepos...)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(goWorkPath, content, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write go.work file")
+	}
+
+	return nil
+}
+
+// renderGoWork returns the workspace's go.work with removedRepos dropped and
+// the repositories whose moduleDir has a go.mod added
+func (wm *WorkspaceManager) renderGoWork(workspace *Workspace, moduleDir func(repo Repository) string, removedRepos ...string) ([]byte, error) {
+	workFile, err := wm.loadGoWork(filepath.Join(workspace.Path, "go.work"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repoName := range removedRepos {
+		for _, use := range append([]*modfile.Use{}, workFile.Use...) {
+			if goWorkUseDir(use.Path) == repoName {
+				if err := workFile.DropUse(use.Path); err != nil {
+					return nil, errors.Wrapf(err, "failed to drop %s from go.work", use.Path)
+				}
+			}
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, use := range workFile.Use {
+		used[goWorkUseDir(use.Path)] = true
+	}
+	for _, repo := range workspace.Repositories {
+		goModPath := filepath.Join(moduleDir(repo), "go.mod")
+		if _, err := os.Stat(goModPath); err != nil || used[repo.Name] {
+			continue
+		}
+		if err := workFile.AddUse("./"+repo.Name, ""); err != nil {
+			return nil, errors.Wrapf(err, "failed to add %s to go.work", repo.Name)
+		}
+	}
+
+	workFile.SortBlocks()
+	workFile.Cleanup()
+	return modfile.Format(workFile.Syntax), nil
+}
+
+// loadGoWork parses an existing go.work, or starts one for the installed Go version
+func (wm *WorkspaceManager) loadGoWork(goWorkPath string) (*modfile.WorkFile, error) {
+	data, err := os.ReadFile(goWorkPath)
+	if err == nil {
+		workFile, err := modfile.ParseWork(goWorkPath, data, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", goWorkPath)
+		}
+		return workFile, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read %s", goWorkPath)
+	}
 
 	// Dynamically detect Go version
-	ctx := context.Background()
-	goVersion, err := wm.getGoVersion(ctx)
+	goVersion, err := wm.getGoVersion(context.Background())
 	if err != nil {
 		output.LogWarn(
 			fmt.Sprintf("Failed to detect Go version, using default 1.23: %v", err),
@@ -468,23 +766,17 @@ func (wm *WorkspaceManager) CreateGoWorkspace(workspace *Workspace) error {
 		goVersion = "1.23" // Safe fallback version
 	}
 
-	content := fmt.Sprintf("go %s\n\nuse (\n", goVersion)
-
-	for _, repo := range workspace.Repositories {
-		// Check if repo has go.mod
-		goModPath := filepath.Join(workspace.Path, repo.Name, "go.mod")
-		if _, err := os.Stat(goModPath); err == nil {
-			content += fmt.Sprintf("\t./%s\n", repo.Name)
-		}
-	}
-
-	content += ")\n"
-
-	if err := os.WriteFile(goWorkPath, []byte(content), 0644); err != nil {
-		return errors.Wrapf(err, "failed to write go.work file")
+	workFile := &modfile.WorkFile{Syntax: &modfile.FileSyntax{}}
+	if err := workFile.AddGoStmt(goVersion); err != nil {
+		return nil, errors.Wrap(err, "failed to set go version in go.work")
 	}
+	return workFile, nil
+}
 
-	return nil
+// goWorkUseDir returns the workspace directory a go.work use path refers to,
+// e.g. "app" for "./app" or "app/"
+func goWorkUseDir(usePath string) string {
+	return filepath.ToSlash(filepath.Clean(usePath))
 }
 
 // copyAgentMD copies AGENT.md file to workspace
@@ -522,6 +814,9 @@ func (wm *WorkspaceManager) copyAgentMD(workspace *Workspace) error {
 
 // saveWorkspace saves workspace configuration
 func (wm *WorkspaceManager) SaveWorkspace(workspace *Workspace) error {
+	if workspace.Filter != nil {
+		return errors.Errorf("refusing to save workspace '%s' narrowed to %s", workspace.Name, workspace.Filter)
+	}
 	workspacesDir := filepath.Join(filepath.Dir(wm.config.RegistryPath), "workspaces")
 	if err := os.MkdirAll(workspacesDir, 0755); err != nil {
 		return errors.Wrap(err, "failed to create workspaces directory")
@@ -605,6 +900,7 @@ func LoadWorkspaces() ([]Workspace, error) {
 				)
 				continue
 			}
+			workspace.applyRemote()
 
 			workspaces = append(workspaces, workspace)
 		}
@@ -614,6 +910,15 @@ func LoadWorkspaces() ([]Workspace, error) {
 }
 
 // LoadWorkspace loads a specific workspace by name
+// WorkspaceNotFoundError is returned when no workspace is saved under a name
+type WorkspaceNotFoundError struct {
+	Name string
+}
+
+func (e *WorkspaceNotFoundError) Error() string {
+	return fmt.Sprintf("workspace '%s' not found", e.Name)
+}
+
 func (wm *WorkspaceManager) LoadWorkspace(name string) (*Workspace, error) {
 	configDir, err := os.UserConfigDir()
 	if err != nil {
@@ -623,7 +928,7 @@ func (wm *WorkspaceManager) LoadWorkspace(name string) (*Workspace, error) {
 	workspacePath := filepath.Join(configDir, "workspace-manager", "workspaces", name+".json")
 
 	if _, err := os.Stat(workspacePath); os.IsNotExist(err) {
-		return nil, errors.Errorf("workspace '%s' not found", name)
+		return nil, &WorkspaceNotFoundError{Name: name}
 	}
 
 	data, err := os.ReadFile(workspacePath)
@@ -635,6 +940,7 @@ func (wm *WorkspaceManager) LoadWorkspace(name string) (*Workspace, error) {
 	if err := json.Unmarshal(data, &workspace); err != nil {
 		return nil, errors.Wrapf(err, "failed to parse workspace file: %s", workspacePath)
 	}
+	workspace.applyRemote()
 
 	return &workspace, nil
 }
@@ -655,10 +961,25 @@ func (wm *WorkspaceManager) DeleteWorkspace(ctx context.Context, name string, re
 		return errors.Wrapf(err, "failed to load workspace '%s'", name)
 	}
 
+	// Local replace directives would make the worktrees look modified
+	if workspace.GoReplaces {
+		if err := RemoveGoReplaces(workspace); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to remove replace directives: %v", err),
+				"Failed to remove replace directives, but continuing",
+				"error", err,
+			)
+		}
+	}
+
 	// Remove worktrees first
 	if err := wm.removeWorktrees(ctx, workspace, forceWorktrees); err != nil {
 		return errors.Wrap(err, "failed to remove worktrees")
 	}
+	for _, repo := range workspace.Repositories {
+		wm.releaseHooks(ctx, workspace, repo)
+		wm.releaseGitConfig(ctx, workspace, repo)
+	}
 
 	// Remove workspace directory and files if requested
 	if removeFiles {
@@ -689,6 +1010,8 @@ func (wm *WorkspaceManager) DeleteWorkspace(ctx context.Context, name string, re
 			)
 		}
 	} else {
+		cleanupAuxiliaryPaths(workspace)
+
 		// If not removing files, still clean up go.work and AGENT.md from workspace directory
 		// as these are workspace-specific files that should be removed with workspace deletion
 		if err := wm.cleanupWorkspaceSpecificFiles(workspace.Path); err != nil {
@@ -710,6 +1033,7 @@ func (wm *WorkspaceManager) DeleteWorkspace(ctx context.Context, name string, re
 	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
 		return errors.Wrapf(err, "failed to remove workspace configuration: %s", configPath)
 	}
+	forgetWorkspaceUse(name)
 
 	output.LogInfo(
 		fmt.Sprintf("Workspace '%s' deleted successfully", name),
@@ -753,6 +1077,13 @@ func (wm *WorkspaceManager) removeWorktrees(ctx context.Context, workspace *Work
 		fmt.Printf("Workspace path: %s\n", workspace.Path)
 		fmt.Printf("Expected worktree path: %s\n", worktreePath)
 
+		if reused, err := removeReusedWorktree(worktreePath); reused {
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
 		// Check if worktree path exists
 		if stat, err := os.Stat(worktreePath); os.IsNotExist(err) {
 			fmt.Printf("⚠️  Worktree directory does not exist, skipping\n")
@@ -764,8 +1095,9 @@ func (wm *WorkspaceManager) removeWorktrees(ctx context.Context, workspace *Work
 			fmt.Printf("✓ Worktree directory exists (type: %s)\n", map[bool]string{true: "directory", false: "file"}[stat.IsDir()])
 		}
 
-		// Check for untracked files that would preclude removal
-		untrackedFiles, err := wm.getUntrackedFiles(ctx, worktreePath)
+		// Check for untracked files that would preclude removal; files matched by
+		// .wsmignore (build artifacts, node_modules) are removed without asking
+		untrackedFiles, ignoredFiles, err := wm.splitUntrackedFiles(ctx, worktreePath)
 		if err != nil {
 			output.LogWarn(
 				fmt.Sprintf("Failed to check for untracked files in %s: %v", repo.Name, err),
@@ -788,11 +1120,7 @@ func (wm *WorkspaceManager) removeWorktrees(ctx context.Context, workspace *Work
 
 			// Even with --force, ask for confirmation
 			fmt.Printf("\nWith --force-worktrees, these untracked files will be permanently deleted.\n")
-			fmt.Printf("Do you want to proceed with %s? (y/N): ", repo.Name)
-
-			var response string
-			_, _ = fmt.Scanln(&response)
-			if response != "y" && response != "Y" && response != "yes" && response != "Yes" {
+			if !confirm(fmt.Sprintf("Do you want to proceed with %s?", repo.Name)) {
 				errs = append(errs, fmt.Errorf("operation cancelled by user for %s", repo.Name))
 				continue
 			}
@@ -800,6 +1128,19 @@ func (wm *WorkspaceManager) removeWorktrees(ctx context.Context, workspace *Work
 			fmt.Printf("Proceeding with forced removal of %s...\n", repo.Name)
 		}
 
+		if len(ignoredFiles) > 0 {
+			fmt.Printf("Removing %d untracked file(s) matched by %s\n", len(ignoredFiles), IgnoreFileName)
+			if err := removeIgnoredFiles(ctx, worktreePath, ignoredFiles); err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed to clean %s", repo.Name))
+				continue
+			}
+		}
+
+		if err := deinitSubmodules(ctx, worktreePath, force); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to remove worktree for %s", repo.Name))
+			continue
+		}
+
 		// Remove worktree using git command
 		var cmd *exec.Cmd
 		var cmdStr string
@@ -913,10 +1254,11 @@ func (wm *WorkspaceManager) logWorkspaceFilesToRemove(workspacePath string) erro
 	return nil
 }
 
-// cleanupWorkspaceSpecificFiles removes workspace-specific files (go.work, AGENT.md)
+// cleanupWorkspaceSpecificFiles removes workspace-specific files (go.work, AGENT.md, workspace manifests)
 // even when not doing a full directory removal
 func (wm *WorkspaceManager) cleanupWorkspaceSpecificFiles(workspacePath string) error {
-	workspaceSpecificFiles := []string{"go.work", "go.work.sum", "AGENT.md"}
+	workspaceSpecificFiles := append([]string{"go.work", "go.work.sum", "AGENT.md", AgentHandoffFile}, WorkspaceManifestFiles...)
+	workspaceSpecificFiles = append(workspaceSpecificFiles, NixWorkspaceFiles...)
 
 	for _, fileName := range workspaceSpecificFiles {
 		filePath := filepath.Join(workspacePath, fileName)
@@ -973,6 +1315,12 @@ func (wm *WorkspaceManager) rollbackWorktrees(ctx context.Context, worktrees []W
 		worktree := worktrees[i]
 
 		fmt.Printf("Rolling back worktree: %s (at %s)\n", worktree.Repository.Name, worktree.TargetPath)
+		if reused, err := removeReusedWorktree(worktree.TargetPath); reused {
+			if err != nil {
+				fmt.Printf("  ⚠️  %v\n", err)
+			}
+			continue
+		}
 
 		output.LogInfo(
 			fmt.Sprintf("Rolling back worktree for %s", worktree.Repository.Name),
@@ -982,6 +1330,10 @@ func (wm *WorkspaceManager) rollbackWorktrees(ctx context.Context, worktrees []W
 			"repoPath", worktree.Repository.Path,
 		)
 
+		if err := deinitSubmodules(ctx, worktree.TargetPath, true); err != nil {
+			fmt.Printf("  ⚠️  %v\n", err)
+		}
+
 		// Use git worktree remove --force for rollback to ensure it works even with uncommitted changes
 		cmd := exec.CommandContext(ctx, "git", "worktree", "remove", "--force", worktree.TargetPath)
 		cmd.Dir = worktree.Repository.Path
@@ -1057,6 +1409,9 @@ func (wm *WorkspaceManager) cleanupWorkspaceDirectory(workspacePath string) {
 		"AGENT.md":   true,
 		".gitignore": true,
 	}
+	for _, fileName := range WorkspaceManifestFiles {
+		expectedFiles[fileName] = true
+	}
 
 	if !isEmpty {
 		for _, entry := range entries {
@@ -1104,7 +1459,9 @@ func (wm *WorkspaceManager) cleanupWorkspaceDirectory(workspacePath string) {
 }
 
 // AddRepositoryToWorkspace adds a repository to an existing workspace
-func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, workspaceName, repoName, branchName string, forceOverwrite bool) error {
+// sparsePaths restricts the new worktree to a cone mode sparse checkout of these directories.
+// pin checks the repository out detached at a tag or commit instead of a branch.
+func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, workspaceName, repoName, branchName string, forceOverwrite bool, sparsePaths []string, pin string) error {
 	output.LogInfo(
 		fmt.Sprintf("Adding repository %s to workspace %s", repoName, workspaceName),
 		"Adding repository to workspace",
@@ -1120,6 +1477,15 @@ func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, worksp
 		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
 	}
 
+	// A path or remote URL is registered on the fly
+	if IsRepositoryLocation(repoName) {
+		names, err := wm.Discoverer.RegisterRepositoryLocations(ctx, []string{repoName}, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to register repository '%s'", repoName)
+		}
+		repoName = names[0]
+	}
+
 	// Check if repository is already in workspace
 	for _, repo := range workspace.Repositories {
 		if repo.Name == repoName {
@@ -1136,6 +1502,10 @@ func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, worksp
 	if len(repos) == 0 {
 		return errors.Errorf("repository '%s' not found in registry", repoName)
 	}
+	repos, err = wm.EnsureCloned(ctx, repos, false)
+	if err != nil {
+		return errors.Wrapf(err, "failed to clone repository '%s'", repoName)
+	}
 
 	repo := repos[0]
 
@@ -1154,25 +1524,56 @@ func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, worksp
 	output.PrintInfo("Target branch: %s", targetBranch)
 	output.PrintInfo("Workspace path: %s", workspace.Path)
 
+	if len(sparsePaths) > 0 {
+		if workspace.RepositorySparsePaths == nil {
+			workspace.RepositorySparsePaths = map[string][]string{}
+		}
+		workspace.RepositorySparsePaths[repo.Name] = sparsePaths
+	}
+	if pin != "" {
+		if workspace.RepositoryPins == nil {
+			workspace.RepositoryPins = map[string]string{}
+		}
+		workspace.RepositoryPins[repo.Name] = pin
+	}
+
 	// Create worktree for the new repository
 	if err := wm.CreateWorktreeForAdd(ctx, workspace, repo, targetBranch, forceOverwrite); err != nil {
 		return errors.Wrapf(err, "failed to create worktree for repository '%s'", repoName)
 	}
 
-	// Add repository to workspace configuration
+	// Add repository to workspace configuration, clearing it from the pending list if needed
 	workspace.Repositories = append(workspace.Repositories, repo)
+	var stillPending []PendingRepository
+	for _, pending := range workspace.PendingRepositories {
+		if pending.Repository.Name != repo.Name {
+			stillPending = append(stillPending, pending)
+		}
+	}
+	workspace.PendingRepositories = stillPending
 
-	// Update go.work file if this is a Go workspace and the new repo has go.mod
-	if workspace.GoWorkspace {
-		if err := wm.CreateGoWorkspace(workspace); err != nil {
+	// Update go.work and the other workspace manifests with the new repository
+	for _, ecosystem := range detectEcosystems([]Repository{repo}) {
+		workspace.Ecosystems = appendUnique(workspace.Ecosystems, ecosystem)
+	}
+	if workspace.HasWorkspaceManifests() {
+		if err := wm.UpdateWorkspaceManifests(workspace); err != nil {
 			output.LogWarn(
-				fmt.Sprintf("Failed to update go.work file: %v", err),
-				"Failed to update go.work file, but continuing",
+				fmt.Sprintf("Failed to update workspace manifests: %v", err),
+				"Failed to update workspace manifests, but continuing",
 				"error", err,
 			)
 		}
 	}
 
+	if err := UpdateEditorProjects(workspace); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to update editor project files: %v", err),
+			"Failed to update editor project files, but continuing",
+			"error", err,
+		)
+	}
+
 	// Save updated workspace configuration
 	if err := wm.SaveWorkspace(workspace); err != nil {
 		return errors.Wrap(err, "failed to save updated workspace configuration")
@@ -1205,6 +1606,101 @@ func (wm *WorkspaceManager) AddRepositoryToWorkspace(ctx context.Context, worksp
 	return nil
 }
 
+// RetryPendingRepositories retries worktree creation for repositories left
+// pending by a partial create. The repositories added before a cancellation
+// are saved; the others stay pending.
+func (wm *WorkspaceManager) RetryPendingRepositories(ctx context.Context, workspaceName string, forceOverwrite bool) error {
+	workspace, err := wm.LoadWorkspace(workspaceName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load workspace '%s'", workspaceName)
+	}
+
+	if len(workspace.PendingRepositories) == 0 {
+		output.PrintInfo("Workspace '%s' has no pending repositories", workspaceName)
+		return nil
+	}
+
+	output.PrintInfo("Retrying %d pending repositories in workspace '%s'", len(workspace.PendingRepositories), workspaceName)
+
+	var stillPending []PendingRepository
+	var added []Repository
+	var cancelErr error
+	for i, pending := range workspace.PendingRepositories {
+		repo := pending.Repository
+		if err := wm.CreateWorktreeForAdd(ctx, workspace, repo, workspace.Branch, forceOverwrite); err != nil {
+			if IsCancellationError(err) {
+				stillPending = append(stillPending, workspace.PendingRepositories[i:]...)
+				cancelErr = err
+				break
+			}
+			output.LogWarn(
+				fmt.Sprintf("Repository '%s' is still pending: %v", repo.Name, err),
+				"Retry of pending repository failed",
+				"repo", repo.Name,
+				"error", err,
+			)
+			stillPending = append(stillPending, PendingRepository{Repository: repo, Error: err.Error()})
+			continue
+		}
+		workspace.Repositories = append(workspace.Repositories, repo)
+		added = append(added, repo)
+	}
+	workspace.PendingRepositories = stillPending
+
+	if len(added) > 0 && workspace.HasWorkspaceManifests() {
+		if err := wm.UpdateWorkspaceManifests(workspace); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to update workspace manifests: %v", err),
+				"Failed to update workspace manifests, but continuing",
+				"error", err,
+			)
+		}
+	}
+
+	if err := UpdateEditorProjects(workspace); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to update editor project files: %v", err),
+			"Failed to update editor project files, but continuing",
+			"error", err,
+		)
+	}
+
+	if err := wm.SaveWorkspace(workspace); err != nil {
+		return errors.Wrap(err, "failed to save updated workspace configuration")
+	}
+
+	if err := wm.createWorkspaceMetadata(workspace); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to update wsm.json metadata file for workspace '%s'", workspace.Name),
+			"Failed to update workspace metadata file",
+			"workspace", workspace.Name,
+			"error", err,
+		)
+	}
+	if cancelErr != nil {
+		return cancelErr
+	}
+
+	for _, repo := range added {
+		if err := wm.executeSetupScriptsForRepo(ctx, workspace, repo); err != nil {
+			output.LogWarn(
+				fmt.Sprintf("Failed to execute setup scripts for repository '%s'", repo.Name),
+				"Setup scripts failed for retried repository",
+				"workspace", workspace.Name,
+				"repo", repo.Name,
+				"error", err,
+			)
+		}
+	}
+
+	if len(stillPending) > 0 {
+		return errors.Errorf("%d repositories are still pending in workspace '%s'", len(stillPending), workspaceName)
+	}
+
+	fmt.Printf("✓ Successfully added all pending repositories to workspace '%s'\n", workspaceName)
+	return nil
+}
+
 // CreateWorktreeForAdd creates a worktree for adding a repository to an existing workspace
 func (wm *WorkspaceManager) CreateWorktreeForAdd(ctx context.Context, workspace *Workspace, repo Repository, branch string, forceOverwrite bool) error {
 	targetPath := filepath.Join(workspace.Path, repo.Name)
@@ -1223,9 +1719,13 @@ func (wm *WorkspaceManager) CreateWorktreeForAdd(ctx context.Context, workspace
 		return errors.Errorf("target path '%s' already exists", targetPath)
 	}
 
+	if workspace.PinFor(repo.Name) != "" {
+		return wm.addPinnedWorktree(ctx, workspace, repo)
+	}
+
 	if branch == "" {
 		// No specific branch, create worktree from current branch
-		return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", targetPath)
+		return wm.addWorktree(ctx, workspace, repo, targetPath)
 	}
 
 	// Check if branch exists locally
@@ -1235,7 +1735,8 @@ func (wm *WorkspaceManager) CreateWorktreeForAdd(ctx context.Context, workspace
 	}
 
 	// Check if branch exists remotely
-	remoteBranchExists, err := wm.CheckRemoteBranchExists(ctx, repo.Path, branch)
+	remote := workspace.RemoteFor(repo)
+	remoteBranchExists, err := wm.CheckRemoteBranchExists(ctx, repo.Path, remote, branch)
 	if err != nil {
 		output.LogWarn(
 			fmt.Sprintf("Could not check remote branch existence for '%s': %v", branch, err),
@@ -1247,56 +1748,47 @@ func (wm *WorkspaceManager) CreateWorktreeForAdd(ctx context.Context, workspace
 
 	fmt.Printf("\nBranch status for %s:\n", repo.Name)
 	fmt.Printf("  Local branch '%s' exists: %v\n", branch, branchExists)
-	fmt.Printf("  Remote branch 'origin/%s' exists: %v\n", branch, remoteBranchExists)
+	fmt.Printf("  Remote branch '%s/%s' exists: %v\n", remote, branch, remoteBranchExists)
 
 	if branchExists {
-		if forceOverwrite {
-			fmt.Printf("Force overwriting branch '%s'...\n", branch)
-			if remoteBranchExists {
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", branch, targetPath, "origin/"+branch)
-			} else {
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", branch, targetPath)
+		if handled, err := wm.addCheckedOutWorktree(ctx, workspace, repo, branch); handled {
+			return err
+		}
+		choice := ExistingBranchOverwrite
+		if !forceOverwrite {
+			if choice, err = wm.existingBranchPolicy(repo.Name, branch); err != nil {
+				return err
 			}
-		} else {
-			// Branch exists locally - ask user what to do unless force is specified
-			fmt.Printf("\n⚠️  Branch '%s' already exists in repository '%s'\n", branch, repo.Name)
-			fmt.Printf("What would you like to do?\n")
-			fmt.Printf("  [o] Overwrite the existing branch (git worktree add -B)\n")
-			fmt.Printf("  [u] Use the existing branch as-is (git worktree add)\n")
-			fmt.Printf("  [c] Cancel operation\n")
-			fmt.Printf("Choice [o/u/c]: ")
-
-			var choice string
-			if _, err := fmt.Scanln(&choice); err != nil {
-				// If input fails, default to cancel to be safe
-				choice = "c"
+		}
+		if choice == ExistingBranchAsk {
+			if choice, err = askExistingBranch(repo.Name, branch); err != nil {
+				return err
 			}
+		}
 
-			switch strings.ToLower(choice) {
-			case "o", "overwrite":
-				fmt.Printf("Overwriting branch '%s'...\n", branch)
-				if remoteBranchExists {
-					return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", branch, targetPath, "origin/"+branch)
-				} else {
-					return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-B", branch, targetPath)
-				}
-			case "u", "use":
-				fmt.Printf("Using existing branch '%s'...\n", branch)
-				return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", targetPath, branch)
-			case "c", "cancel":
-				return errors.New("operation cancelled by user")
-			default:
-				return errors.New("invalid choice, operation cancelled")
+		switch choice {
+		case ExistingBranchOverwrite:
+			fmt.Printf("Overwriting branch '%s'...\n", branch)
+			if remoteBranchExists {
+				return wm.addWorktree(ctx, workspace, repo, "-B", branch, targetPath, remote+"/"+branch)
 			}
+			return wm.addWorktree(ctx, workspace, repo, "-B", branch, targetPath)
+		case ExistingBranchUse:
+			fmt.Printf("Using existing branch '%s'...\n", branch)
+			return wm.addWorktree(ctx, workspace, repo, targetPath, branch)
+		case existingBranchCancel:
+			return errors.New("operation cancelled by user")
+		default:
+			return existingBranchError(repo.Name, branch)
 		}
 	} else {
 		// Branch doesn't exist locally
 		if remoteBranchExists {
-			fmt.Printf("Creating worktree from remote branch origin/%s...\n", branch)
-			return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-b", branch, targetPath, "origin/"+branch)
+			fmt.Printf("Creating worktree from remote branch %s/%s...\n", remote, branch)
+			return wm.addWorktree(ctx, workspace, repo, "-b", branch, targetPath, remote+"/"+branch)
 		} else {
 			fmt.Printf("Creating new branch '%s' and worktree...\n", branch)
-			return wm.ExecuteWorktreeCommand(ctx, repo.Path, "git", "worktree", "add", "-b", branch, targetPath)
+			return wm.addWorktree(ctx, workspace, repo, "-b", branch, targetPath)
 		}
 	}
 }
@@ -1339,9 +1831,21 @@ func (wm *WorkspaceManager) RemoveRepositoryFromWorkspace(ctx context.Context, w
 
 	// Remove the worktree
 	worktreePath := filepath.Join(workspace.Path, repoName)
+	if workspace.GoReplaces {
+		// Local replace directives would make the worktree look modified
+		if _, err := setGoReplaces(filepath.Join(worktreePath, "go.mod"), nil); err != nil && !os.IsNotExist(err) {
+			output.LogWarn(
+				fmt.Sprintf("Failed to remove replace directives from %s: %v", repoName, err),
+				"Failed to remove replace directives, but continuing",
+				"error", err,
+			)
+		}
+	}
 	if err := wm.removeWorktreeForRepo(ctx, targetRepo, worktreePath, force); err != nil {
 		return errors.Wrapf(err, "failed to remove worktree for repository '%s'", repoName)
 	}
+	wm.releaseHooks(ctx, workspace, targetRepo)
+	wm.releaseGitConfig(ctx, workspace, targetRepo)
 
 	// Remove repository directory if requested
 	if removeFiles {
@@ -1356,18 +1860,30 @@ func (wm *WorkspaceManager) RemoveRepositoryFromWorkspace(ctx context.Context, w
 
 	// Remove repository from workspace configuration
 	workspace.Repositories = append(workspace.Repositories[:repoIndex], workspace.Repositories[repoIndex+1:]...)
-
-	// Update go.work file if this is a Go workspace
-	if workspace.GoWorkspace {
-		if err := wm.CreateGoWorkspace(workspace); err != nil {
+	delete(workspace.RepositorySparsePaths, repoName)
+	delete(workspace.RepositoryPins, repoName)
+	delete(workspace.RepositoryOrigins, repoName)
+	delete(workspace.RepositoryDefaultBranches, repoName)
+
+	// Update go.work and the other workspace manifests
+	if workspace.HasWorkspaceManifests() {
+		if err := wm.UpdateWorkspaceManifests(workspace, repoName); err != nil {
 			output.LogWarn(
-				fmt.Sprintf("Failed to update go.work file: %v", err),
-				"Failed to update go.work file, but continuing",
+				fmt.Sprintf("Failed to update workspace manifests: %v", err),
+				"Failed to update workspace manifests, but continuing",
 				"error", err,
 			)
 		}
 	}
 
+	if err := UpdateEditorProjects(workspace); err != nil {
+		output.LogWarn(
+			fmt.Sprintf("Failed to update editor project files: %v", err),
+			"Failed to update editor project files, but continuing",
+			"error", err,
+		)
+	}
+
 	// Save updated workspace configuration
 	if err := wm.SaveWorkspace(workspace); err != nil {
 		return errors.Wrap(err, "failed to save updated workspace configuration")
@@ -1390,6 +1906,10 @@ func (wm *WorkspaceManager) removeWorktreeForRepo(ctx context.Context, repo Repo
 	fmt.Printf("\n--- Removing worktree for %s ---\n", repo.Name)
 	fmt.Printf("Worktree path: %s\n", worktreePath)
 
+	if reused, err := removeReusedWorktree(worktreePath); reused {
+		return err
+	}
+
 	// Check if worktree path exists
 	if stat, err := os.Stat(worktreePath); os.IsNotExist(err) {
 		fmt.Printf("⚠️  Worktree directory does not exist, skipping worktree removal\n")
@@ -1400,8 +1920,9 @@ func (wm *WorkspaceManager) removeWorktreeForRepo(ctx context.Context, repo Repo
 		fmt.Printf("✓ Worktree directory exists (type: %s)\n", map[bool]string{true: "directory", false: "file"}[stat.IsDir()])
 	}
 
-	// Check for untracked files that would preclude removal
-	untrackedFiles, err := wm.getUntrackedFiles(ctx, worktreePath)
+	// Check for untracked files that would preclude removal; files matched by
+	// .wsmignore are removed without asking
+	untrackedFiles, ignoredFiles, err := wm.splitUntrackedFiles(ctx, worktreePath)
 	if err != nil {
 		output.LogWarn(
 			fmt.Sprintf("Failed to check for untracked files: %v", err),
@@ -1422,17 +1943,24 @@ func (wm *WorkspaceManager) removeWorktreeForRepo(ctx context.Context, repo Repo
 
 		// Even with --force, ask for confirmation
 		fmt.Printf("\nWith --force, these untracked files will be permanently deleted.\n")
-		fmt.Printf("Do you want to proceed? (y/N): ")
-
-		var response string
-		_, _ = fmt.Scanln(&response)
-		if response != "y" && response != "Y" && response != "yes" && response != "Yes" {
+		if !confirm("Do you want to proceed?") {
 			return errors.New("operation cancelled by user")
 		}
 
 		fmt.Printf("Proceeding with forced removal...\n")
 	}
 
+	if len(ignoredFiles) > 0 {
+		fmt.Printf("Removing %d untracked file(s) matched by %s\n", len(ignoredFiles), IgnoreFileName)
+		if err := removeIgnoredFiles(ctx, worktreePath, ignoredFiles); err != nil {
+			return err
+		}
+	}
+
+	if err := deinitSubmodules(ctx, worktreePath, force); err != nil {
+		return errors.Wrapf(err, "failed to remove worktree for %s", repo.Name)
+	}
+
 	// First, list current worktrees for debugging
 	fmt.Printf("\nCurrent worktrees for %s:\n", repo.Name)
 	listCmd := exec.CommandContext(ctx, "git", "worktree", "list")
@@ -1526,52 +2054,16 @@ func (wm *WorkspaceManager) getUntrackedFiles(ctx context.Context, repoPath stri
 }
 
 // executeSetupScripts executes setup scripts after workspace creation
-func (wm *WorkspaceManager) executeSetupScripts(ctx context.Context, workspace *Workspace) error {
-	// Prepare environment variables
-	env := os.Environ()
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_NAME=%s", workspace.Name))
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_PATH=%s", workspace.Path))
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_BRANCH=%s", workspace.Branch))
-	if workspace.BaseBranch != "" {
-		env = append(env, fmt.Sprintf("WSM_WORKSPACE_BASE_BRANCH=%s", workspace.BaseBranch))
-	}
-
-	// Add repository names as comma-separated list
-	repoNames := make([]string, len(workspace.Repositories))
-	for i, repo := range workspace.Repositories {
-		repoNames[i] = repo.Name
-	}
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_REPOS=%s", strings.Join(repoNames, ",")))
-
-	// Execute workspace root setup.sh
-	rootSetupScript := filepath.Join(workspace.Path, ".wsm", "setup.sh")
-	if err := wm.executeSetupScript(ctx, rootSetupScript, workspace.Path, env); err != nil {
-		output.LogWarn(
-			fmt.Sprintf("Failed to execute root setup script: %s", rootSetupScript),
-			"Root setup script failed",
-			"script", rootSetupScript,
-			"error", err,
-		)
+func (wm *WorkspaceManager) executeSetupScripts(ctx context.Context, workspace *Workspace, noScripts bool) error {
+	if noScripts {
+		output.PrintInfo("Skipping setup scripts (--no-scripts)")
+		return nil
 	}
-
-	// Collect and execute setup.d scripts in order across all locations
-	setupScripts, err := wm.collectSetupDScripts(workspace)
+	scripts, err := wm.setupScripts(workspace)
 	if err != nil {
-		return errors.Wrap(err, "failed to collect setup.d scripts")
-	}
-
-	for _, script := range setupScripts {
-		if err := wm.executeSetupScript(ctx, script.Path, script.WorkingDir, env); err != nil {
-			output.LogWarn(
-				fmt.Sprintf("Failed to execute setup script: %s", script.Path),
-				"Setup script failed",
-				"script", script.Path,
-				"workingDir", script.WorkingDir,
-				"error", err,
-			)
-		}
+		return err
 	}
-
+	wm.runSetupScripts(ctx, workspace, scripts, setupScriptEnv(workspace))
 	return nil
 }
 
@@ -1664,29 +2156,6 @@ func (wm *WorkspaceManager) getSetupDScripts(setupDir, workingDir string) ([]Set
 	return scripts, nil
 }
 
-// executeSetupScript executes a single setup script
-func (wm *WorkspaceManager) executeSetupScript(ctx context.Context, scriptPath, workingDir string, env []string) error {
-	// Check if script exists
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		return nil // Script doesn't exist, not an error
-	}
-
-	output.PrintInfo("Executing setup script: %s", filepath.Base(scriptPath))
-
-	cmd := exec.CommandContext(ctx, "bash", scriptPath)
-	cmd.Dir = workingDir
-	cmd.Env = env
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return errors.Wrapf(err, "script execution failed: %s", scriptPath)
-	}
-
-	output.PrintInfo("Setup script completed: %s", filepath.Base(scriptPath))
-	return nil
-}
-
 // PreviewSetupScripts shows which setup scripts would be executed in dry-run mode
 func (wm *WorkspaceManager) PreviewSetupScripts(workspace *Workspace, stepNum int) error {
 	fmt.Printf("  %d. Create workspace metadata file:\n", stepNum)
@@ -1767,6 +2236,8 @@ type WorkspaceMetadata struct {
 	AgentMD      string               `json:"agentMD,omitempty"`
 	CreatedAt    time.Time            `json:"createdAt"`
 	Repositories []RepositoryMetadata `json:"repositories"`
+	Pending      []string             `json:"pending,omitempty"`
+	Auxiliary    []AuxiliaryPath      `json:"auxiliary,omitempty"`
 	Environment  map[string]string    `json:"environment"`
 }
 
@@ -1786,6 +2257,28 @@ func (wm *WorkspaceManager) createWorkspaceMetadata(workspace *Workspace) error
 		return errors.Wrapf(err, "failed to create .wsm directory: %s", wsmDir)
 	}
 
+	jsonData, err := buildWorkspaceMetadata(workspace)
+	if err != nil {
+		return err
+	}
+
+	// Write JSON file
+	metadataPath := filepath.Join(wsmDir, "wsm.json")
+	if err := os.WriteFile(metadataPath, jsonData, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write workspace metadata file: %s", metadataPath)
+	}
+
+	output.LogInfo(
+		fmt.Sprintf("Created workspace metadata file: %s", metadataPath),
+		"Created workspace metadata file",
+		"metadataPath", metadataPath,
+	)
+
+	return nil
+}
+
+// buildWorkspaceMetadata renders the wsm.json content for a workspace
+func buildWorkspaceMetadata(workspace *Workspace) ([]byte, error) {
 	// Prepare repository metadata
 	repoMetadata := make([]RepositoryMetadata, len(workspace.Repositories))
 	for i, repo := range workspace.Repositories {
@@ -1814,6 +2307,11 @@ func (wm *WorkspaceManager) createWorkspaceMetadata(workspace *Workspace) error
 		environment["WSM_WORKSPACE_BASE_BRANCH"] = workspace.BaseBranch
 	}
 
+	var pending []string
+	for _, p := range workspace.PendingRepositories {
+		pending = append(pending, p.Repository.Name)
+	}
+
 	// Create metadata structure
 	metadata := WorkspaceMetadata{
 		Name:         workspace.Name,
@@ -1824,47 +2322,26 @@ func (wm *WorkspaceManager) createWorkspaceMetadata(workspace *Workspace) error
 		AgentMD:      workspace.AgentMD,
 		CreatedAt:    time.Now(),
 		Repositories: repoMetadata,
+		Pending:      pending,
+		Auxiliary:    workspace.AuxiliaryPaths,
 		Environment:  environment,
 	}
 
-	// Write JSON file
-	metadataPath := filepath.Join(wsmDir, "wsm.json")
 	jsonData, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal workspace metadata to JSON")
-	}
-
-	if err := os.WriteFile(metadataPath, jsonData, 0644); err != nil {
-		return errors.Wrapf(err, "failed to write workspace metadata file: %s", metadataPath)
+		return nil, errors.Wrap(err, "failed to marshal workspace metadata to JSON")
 	}
 
-	output.LogInfo(
-		fmt.Sprintf("Created workspace metadata file: %s", metadataPath),
-		"Created workspace metadata file",
-		"metadataPath", metadataPath,
-	)
-
-	return nil
+	return jsonData, nil
 }
 
 // executeSetupScriptsForRepo executes setup scripts for a newly added repository
 func (wm *WorkspaceManager) executeSetupScriptsForRepo(ctx context.Context, workspace *Workspace, repo Repository) error {
-	// Prepare environment variables
-	env := os.Environ()
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_NAME=%s", workspace.Name))
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_PATH=%s", workspace.Path))
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_BRANCH=%s", workspace.Branch))
-	if workspace.BaseBranch != "" {
-		env = append(env, fmt.Sprintf("WSM_WORKSPACE_BASE_BRANCH=%s", workspace.BaseBranch))
-	}
-
-	// Add repository names as comma-separated list
-	repoNames := make([]string, len(workspace.Repositories))
-	for i, r := range workspace.Repositories {
-		repoNames[i] = r.Name
+	if wm.NoScripts {
+		output.PrintInfo("Skipping setup scripts of %s (--no-scripts)", repo.Name)
+		return nil
 	}
-	env = append(env, fmt.Sprintf("WSM_WORKSPACE_REPOS=%s", strings.Join(repoNames, ",")))
-	env = append(env, fmt.Sprintf("WSM_ADDED_REPO=%s", repo.Name))
+	env := append(setupScriptEnv(workspace), fmt.Sprintf("WSM_ADDED_REPO=%s", repo.Name))
 
 	// Execute setup scripts from the newly added repository's .wsm/setup.d/
 	repoSetupDir := filepath.Join(workspace.Path, repo.Name, ".wsm", "setup.d")
@@ -1887,18 +2364,7 @@ func (wm *WorkspaceManager) executeSetupScriptsForRepo(ctx context.Context, work
 
 	if len(repoScripts) > 0 {
 		output.PrintInfo("Executing setup scripts for newly added repository: %s", repo.Name)
-
-		for _, script := range repoScripts {
-			if err := wm.executeSetupScript(ctx, script.Path, script.WorkingDir, env); err != nil {
-				output.LogWarn(
-					fmt.Sprintf("Failed to execute setup script: %s", script.Path),
-					"Setup script failed",
-					"script", script.Path,
-					"workingDir", script.WorkingDir,
-					"error", err,
-				)
-			}
-		}
+		wm.runSetupScripts(ctx, workspace, repoScripts, env)
 	}
 
 	return nil
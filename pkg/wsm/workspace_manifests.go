@@ -0,0 +1,355 @@
+package wsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Ecosystems with a root workspace manifest besides go.work
+const (
+	EcosystemNode = "node"
+	EcosystemRust = "rust"
+)
+
+// workspaceManifest generates the root manifest tying the repositories of one
+// ecosystem together, like go.work does for Go modules
+type workspaceManifest struct {
+	// Ecosystem is the repository category that enables the manifest
+	Ecosystem string
+	// MemberFile marks a repository as a member, e.g. package.json
+	MemberFile string
+	// File returns the manifest path in the workspace root
+	File func(workspace *Workspace) string
+	// Render returns the content of a new manifest at path
+	Render func(path string, members []string) string
+	// Update edits an existing manifest, keeping entries it does not manage
+	Update func(data []byte, members, removed []string) ([]byte, error)
+}
+
+var workspaceManifests = []workspaceManifest{
+	{
+		Ecosystem:  EcosystemNode,
+		MemberFile: "package.json",
+		File:       nodeWorkspaceFile,
+		Render:     renderNodeWorkspace,
+		Update:     updateNodeWorkspace,
+	},
+	{
+		Ecosystem:  EcosystemRust,
+		MemberFile: "Cargo.toml",
+		File: func(workspace *Workspace) string {
+			return filepath.Join(workspace.Path, "Cargo.toml")
+		},
+		Render: renderCargoWorkspace,
+		Update: updateCargoWorkspace,
+	},
+}
+
+// WorkspaceManifestFiles are the root files workspace manifests may create
+var WorkspaceManifestFiles = []string{"pnpm-workspace.yaml", "package.json", "Cargo.toml", "Cargo.lock"}
+
+// HasWorkspaceManifests reports whether the workspace maintains go.work,
+// another root workspace manifest or go.mod replace directives
+func (w *Workspace) HasWorkspaceManifests() bool {
+	return w.GoWorkspace || len(w.Ecosystems) > 0 || w.GoReplaces
+}
+
+// detectEcosystems returns the ecosystems of the repositories with a workspace manifest
+func detectEcosystems(repos []Repository) []string {
+	var ecosystems []string
+	for _, manifest := range workspaceManifests {
+		for _, repo := range repos {
+			if containsValue(repo.Categories, manifest.Ecosystem) {
+				ecosystems = append(ecosystems, manifest.Ecosystem)
+				break
+			}
+		}
+	}
+	return ecosystems
+}
+
+// UpdateWorkspaceManifests creates or updates go.work, the workspace manifests
+// of the workspace's ecosystems and the go.mod replace directives.
+// removedRepos are dropped from them.
+func (wm *WorkspaceManager) UpdateWorkspaceManifests(workspace *Workspace, removedRepos ...string) error {
+	if workspace.GoWorkspace {
+		if err := wm.UpdateGoWorkspace(workspace, removedRepos...); err != nil {
+			return errors.Wrap(err, "failed to update go.work")
+		}
+	}
+	if workspace.GoReplaces {
+		if _, err := ApplyGoReplaces(workspace); err != nil {
+			return errors.Wrap(err, "failed to update go.mod replace directives")
+		}
+	}
+
+	for _, manifest := range workspaceManifests {
+		if !containsValue(workspace.Ecosystems, manifest.Ecosystem) {
+			continue
+		}
+		if err := manifest.apply(workspace, removedRepos); err != nil {
+			return errors.Wrapf(err, "failed to update %s", filepath.Base(manifest.File(workspace)))
+		}
+	}
+	return nil
+}
+
+// members returns the workspace repositories containing the member file
+func (m workspaceManifest) members(workspace *Workspace) []string {
+	var members []string
+	for _, repo := range workspace.Repositories {
+		if _, err := os.Stat(filepath.Join(workspace.Path, repo.Name, m.MemberFile)); err == nil {
+			members = append(members, repo.Name)
+		}
+	}
+	return members
+}
+
+func (m workspaceManifest) apply(workspace *Workspace, removed []string) error {
+	path := m.File(workspace)
+	members := m.members(workspace)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if len(members) == 0 {
+			return nil
+		}
+		return os.WriteFile(path, []byte(m.Render(path, members)), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	updated, err := m.Update(data, members, removed)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, 0644)
+}
+
+// mergeMembers keeps the existing entries except removed ones and appends missing members
+func mergeMembers(existing, members, removed []string) []string {
+	var merged []string
+	for _, entry := range existing {
+		if !containsValue(removed, strings.TrimPrefix(entry, "./")) {
+			merged = appendUnique(merged, entry)
+		}
+	}
+	for _, member := range members {
+		if !containsValue(merged, member) && !containsValue(merged, "./"+member) {
+			merged = append(merged, member)
+		}
+	}
+	return merged
+}
+
+// nodeWorkspaceFile returns pnpm-workspace.yaml when the workspace uses pnpm,
+// and the root package.json (npm and yarn workspaces) otherwise
+func nodeWorkspaceFile(workspace *Workspace) string {
+	pnpmPath := filepath.Join(workspace.Path, "pnpm-workspace.yaml")
+	if _, err := os.Stat(pnpmPath); err == nil {
+		return pnpmPath
+	}
+	for _, repo := range workspace.Repositories {
+		if _, err := os.Stat(filepath.Join(workspace.Path, repo.Name, "pnpm-lock.yaml")); err == nil {
+			return pnpmPath
+		}
+	}
+	return filepath.Join(workspace.Path, "package.json")
+}
+
+func isPnpmWorkspace(data []byte) bool {
+	return !strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+func renderNodeWorkspace(path string, members []string) string {
+	if filepath.Base(path) == "pnpm-workspace.yaml" {
+		return renderPnpmWorkspace(members)
+	}
+	data, _ := json.MarshalIndent(map[string]interface{}{
+		"name":       "wsm-workspace",
+		"private":    true,
+		"workspaces": members,
+	}, "", "  ")
+	return string(data) + "\n"
+}
+
+func renderPnpmWorkspace(members []string) string {
+	var b strings.Builder
+	b.WriteString("packages:\n")
+	for _, member := range members {
+		fmt.Fprintf(&b, "  - %s\n", member)
+	}
+	return b.String()
+}
+
+func updateNodeWorkspace(data []byte, members, removed []string) ([]byte, error) {
+	if isPnpmWorkspace(data) {
+		return updatePnpmWorkspace(data, members, removed)
+	}
+
+	// Keys come out sorted, but every key and value of package.json is kept
+	var pkg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, errors.Wrap(err, "failed to parse package.json")
+	}
+
+	var existing []string
+	var workspacesObject map[string]json.RawMessage
+	if raw, ok := pkg["workspaces"]; ok {
+		// Yarn also accepts {"packages": [...], "nohoist": [...]}
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			if err := json.Unmarshal(raw, &workspacesObject); err != nil {
+				return nil, errors.Wrap(err, "unsupported workspaces field in package.json")
+			}
+			if packages, ok := workspacesObject["packages"]; ok {
+				if err := json.Unmarshal(packages, &existing); err != nil {
+					return nil, errors.Wrap(err, "unsupported workspaces.packages field in package.json")
+				}
+			}
+		}
+	}
+
+	merged, err := json.Marshal(mergeMembers(existing, members, removed))
+	if err != nil {
+		return nil, err
+	}
+	if workspacesObject != nil {
+		workspacesObject["packages"] = merged
+		if merged, err = json.Marshal(workspacesObject); err != nil {
+			return nil, err
+		}
+	}
+	pkg["workspaces"] = merged
+
+	out, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal package.json")
+	}
+	return append(out, '\n'), nil
+}
+
+func updatePnpmWorkspace(data []byte, members, removed []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse pnpm-workspace.yaml")
+	}
+	if len(doc.Content) == 0 {
+		return []byte(renderPnpmWorkspace(members)), nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("pnpm-workspace.yaml is not a mapping")
+	}
+
+	// Editing the node tree keeps comments and the other settings
+	var packages *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "packages" {
+			packages = root.Content[i+1]
+		}
+	}
+	if packages == nil {
+		packages = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "packages"}, packages)
+	}
+
+	var existing []string
+	for _, item := range packages.Content {
+		existing = append(existing, item.Value)
+	}
+	kept := packages.Content[:0]
+	for _, item := range packages.Content {
+		if !containsValue(removed, strings.TrimPrefix(item.Value, "./")) {
+			kept = append(kept, item)
+		}
+	}
+	packages.Content = kept
+	for _, entry := range mergeMembers(existing, members, removed) {
+		if !containsValue(existing, entry) {
+			packages.Content = append(packages.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: entry})
+		}
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal pnpm-workspace.yaml")
+	}
+	return out, nil
+}
+
+func renderCargoWorkspace(_ string, members []string) string {
+	return "[workspace]\nresolver = \"2\"\n" + renderCargoMembers(members)
+}
+
+func renderCargoMembers(members []string) string {
+	var b strings.Builder
+	b.WriteString("members = [\n")
+	for _, member := range members {
+		fmt.Fprintf(&b, "    %q,\n", member)
+	}
+	b.WriteString("]\n")
+	return b.String()
+}
+
+var (
+	tomlSectionPattern = regexp.MustCompile(`^\s*\[`)
+	tomlStringPattern  = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// updateCargoWorkspace rewrites the members array of the [workspace] table and
+// leaves every other line, e.g. [patch] sections and comments, untouched
+func updateCargoWorkspace(data []byte, members, removed []string) ([]byte, error) {
+	lines := strings.SplitAfter(string(data), "\n")
+
+	header := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "[workspace]" {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		// A package manifest at the workspace root gains a [workspace] table
+		content := strings.TrimRight(string(data), "\n") + "\n\n" + renderCargoWorkspace("", members)
+		return []byte(content), nil
+	}
+
+	start, end := -1, -1
+	for i := header + 1; i < len(lines); i++ {
+		if tomlSectionPattern.MatchString(lines[i]) && start < 0 {
+			break
+		}
+		trimmed := strings.TrimSpace(lines[i])
+		if start < 0 && strings.HasPrefix(trimmed, "members") && strings.Contains(trimmed, "=") {
+			start = i
+		}
+		if start >= 0 && strings.Contains(lines[i], "]") {
+			end = i
+			break
+		}
+	}
+
+	var existing []string
+	if start >= 0 {
+		if end < 0 {
+			return nil, errors.New("unterminated members array in Cargo.toml")
+		}
+		for _, match := range tomlStringPattern.FindAllStringSubmatch(strings.Join(lines[start:end+1], ""), -1) {
+			existing = append(existing, match[1])
+		}
+	} else {
+		start, end = header+1, header
+	}
+
+	membersBlock := renderCargoMembers(mergeMembers(existing, members, removed))
+	content := strings.Join(lines[:start], "") + membersBlock + strings.Join(lines[end+1:], "")
+	return []byte(content), nil
+}
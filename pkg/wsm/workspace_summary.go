@@ -0,0 +1,174 @@
+package wsm
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sort keys of workspace summaries
+const (
+	SummarySortName     = "name"
+	SummarySortCreated  = "created"
+	SummarySortActivity = "activity"
+	SummarySortRepos    = "repos"
+	SummarySortDirty    = "dirty"
+	SummarySortSize     = "size"
+)
+
+// SummarySortKeys lists the sort keys accepted by SummarizeWorkspaces
+var SummarySortKeys = []string{SummarySortName, SummarySortCreated, SummarySortActivity, SummarySortRepos, SummarySortDirty, SummarySortSize}
+
+// WorkspaceSummary is one row of the workspace list
+type WorkspaceSummary struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	Branch       string    `json:"branch"`
+	Repositories []string  `json:"repositories"`
+	Created      time.Time `json:"created"`
+	// LastActivity is the newest modification of a file in the workspace; it
+	// and Size are only measured with SummaryQuery.Disk
+	LastActivity time.Time `json:"last_activity,omitempty"`
+	Size         int64     `json:"size,omitempty"`
+	// Dirty, Ahead, Behind and Conflicts count repositories and commits, as
+	// in the prompt status
+	Dirty     int  `json:"dirty"`
+	Ahead     int  `json:"ahead"`
+	Behind    int  `json:"behind"`
+	Conflicts int  `json:"conflicts"`
+	Missing   bool `json:"missing,omitempty"`
+	// StatusUpdatedAt is when the status counts were computed
+	StatusUpdatedAt time.Time `json:"status_updated_at"`
+}
+
+// SummaryQuery selects, measures and orders workspace summaries
+type SummaryQuery struct {
+	// MaxStatusAge reuses cached statuses younger than this
+	MaxStatusAge time.Duration
+	// Disk measures the size and last activity of every workspace
+	Disk bool
+	// Repository keeps the workspaces using this repository
+	Repository string
+	// DirtyOnly keeps workspaces with uncommitted changes
+	DirtyOnly bool
+	// IdleFor keeps workspaces without activity for at least this long; it
+	// implies Disk
+	IdleFor time.Duration
+	// Sort is one of SummarySortKeys, created by default. Names sort
+	// ascending, everything else largest or newest first.
+	Sort    string
+	Reverse bool
+}
+
+// SummarizeWorkspaces lists the workspaces with their status counts, read
+// from the status cache shared with shell prompts when it is fresh enough and
+// recomputed and cached otherwise
+func (s *WorkspaceService) SummarizeWorkspaces(ctx context.Context, query SummaryQuery) ([]WorkspaceSummary, error) {
+	if query.Sort == "" {
+		query.Sort = SummarySortCreated
+	}
+	if !containsValue(SummarySortKeys, query.Sort) {
+		return nil, errors.Errorf("invalid sort key '%s' (expected one of %s)", query.Sort, strings.Join(SummarySortKeys, ", "))
+	}
+	if query.IdleFor > 0 {
+		query.Disk = true
+	}
+
+	workspaces, err := LoadWorkspaces()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load workspaces")
+	}
+	cache, err := LoadStatusCache()
+	if err != nil {
+		// A corrupt cache is rebuilt
+		cache = &StatusCache{Workspaces: map[string]PromptStatus{}}
+	}
+
+	summaries := []WorkspaceSummary{}
+	cacheUpdated := false
+	for i := range workspaces {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		workspace := &workspaces[i]
+		if query.Repository != "" && !containsValue(repositoryNames(workspace.Repositories), query.Repository) {
+			continue
+		}
+
+		summary := WorkspaceSummary{
+			Name:         workspace.Name,
+			Path:         workspace.Path,
+			Branch:       workspace.Branch,
+			Repositories: repositoryNames(workspace.Repositories),
+			Created:      workspace.Created,
+		}
+		if _, err := os.Stat(workspace.Path); os.IsNotExist(err) {
+			summary.Missing = true
+		} else {
+			status, ok := cache.Workspaces[workspace.Name]
+			if !ok || time.Since(status.UpdatedAt) >= query.MaxStatusAge || fetchedSince(workspace, status.UpdatedAt) {
+				status = *ComputePromptStatus(ctx, workspace)
+				cache.Workspaces[workspace.Name] = status
+				cacheUpdated = true
+			}
+			summary.Dirty, summary.Ahead, summary.Behind, summary.Conflicts = status.Dirty, status.Ahead, status.Behind, status.Conflicts
+			summary.StatusUpdatedAt = status.UpdatedAt
+
+			if query.Disk {
+				summary.Size, summary.LastActivity, err = directorySize(ctx, workspace.Path)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to measure workspace %s", workspace.Name)
+				}
+			}
+		}
+		// A workspace without files of its own was last changed when it was created
+		if query.Disk && summary.LastActivity.IsZero() {
+			summary.LastActivity = workspace.Created
+		}
+
+		if query.DirtyOnly && summary.Dirty == 0 && summary.Conflicts == 0 {
+			continue
+		}
+		if query.IdleFor > 0 && time.Since(summary.LastActivity) < query.IdleFor {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if cacheUpdated {
+		// The list is still correct without the cache
+		_ = cache.Save()
+	}
+
+	sortWorkspaceSummaries(summaries, query.Sort, query.Reverse)
+	return summaries, nil
+}
+
+func sortWorkspaceSummaries(summaries []WorkspaceSummary, key string, reverse bool) {
+	less := func(a, b WorkspaceSummary) bool {
+		switch key {
+		case SummarySortName:
+			return a.Name < b.Name
+		case SummarySortActivity:
+			return a.LastActivity.After(b.LastActivity)
+		case SummarySortRepos:
+			return len(a.Repositories) > len(b.Repositories)
+		case SummarySortDirty:
+			return a.Dirty+a.Conflicts > b.Dirty+b.Conflicts
+		case SummarySortSize:
+			return a.Size > b.Size
+		default:
+			return a.Created.After(b.Created)
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if reverse {
+			return less(summaries[j], summaries[i])
+		}
+		return less(summaries[i], summaries[j])
+	})
+}